@@ -102,3 +102,29 @@ func AssumeIamRole(iamRoleArn string) (*sts.Credentials, error) {
 
 	return output.Credentials, nil
 }
+
+// The calls to the STS API Terragrunt makes with the credentials it runs with. This is satisfied by the client returned
+// by sts.New, and lets tests use a mock client instead.
+type StsClient interface {
+	GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error)
+}
+
+// Return the id of the AWS account that the credentials of the given STS client belong to
+func GetAccountId(stsClient StsClient) (string, error) {
+	identity, err := stsClient.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	if identity.Account == nil || *identity.Account == "" {
+		return "", errors.WithStackTrace(AccountIdNotReturned{})
+	}
+
+	return *identity.Account, nil
+}
+
+type AccountIdNotReturned struct{}
+
+func (err AccountIdNotReturned) Error() string {
+	return "The STS GetCallerIdentity call did not return an account id"
+}
@@ -5,12 +5,16 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gruntwork-io/terragrunt/config"
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/go-version"
 	"github.com/urfave/cli"
 )
 
@@ -20,6 +24,12 @@ func ParseTerragruntOptions(cliContext *cli.Context) (*options.TerragruntOptions
 	if err != nil {
 		return nil, err
 	}
+
+	// A build without a version, such as one from source, can't be compared to other versions, so it's left unknown
+	if terragruntVersion, err := version.NewVersion(cliContext.App.Version); err == nil {
+		terragruntOptions.TerragruntVersion = terragruntVersion
+	}
+
 	return terragruntOptions, nil
 }
 
@@ -97,6 +107,107 @@ func parseTerragruntOptionsFromArgs(args []string, writer, errWriter io.Writer)
 		return nil, err
 	}
 
+	jsonOut, err := parseStringArg(args, OPT_TERRAGRUNT_JSON_OUT, os.Getenv("TERRAGRUNT_JSON_OUT"))
+	if err != nil {
+		return nil, err
+	}
+
+	statusPortRaw, err := parseStringArg(args, OPT_TERRAGRUNT_STATUS_PORT, os.Getenv("TERRAGRUNT_STATUS_PORT"))
+	if err != nil {
+		return nil, err
+	}
+	statusPort := 0
+	if statusPortRaw != "" {
+		statusPort, err = strconv.Atoi(statusPortRaw)
+		if err != nil || statusPort < 1 || statusPort > 65535 {
+			return nil, errors.WithStackTrace(InvalidStatusPort(statusPortRaw))
+		}
+	}
+
+	statusBindAddress, err := parseStringArg(args, OPT_TERRAGRUNT_STATUS_BIND_ADDRESS, os.Getenv("TERRAGRUNT_STATUS_BIND_ADDRESS"))
+	if err != nil {
+		return nil, err
+	}
+
+	versionCheckUrl, err := parseStringArg(args, OPT_TERRAGRUNT_VERSION_CHECK_URL, os.Getenv("TERRAGRUNT_VERSION_CHECK_URL"))
+	if err != nil {
+		return nil, err
+	}
+
+	staggerRaw, err := parseStringArg(args, OPT_TERRAGRUNT_STAGGER, os.Getenv("TERRAGRUNT_STAGGER"))
+	if err != nil {
+		return nil, err
+	}
+	stagger := time.Duration(0)
+	if staggerRaw != "" {
+		stagger, err = time.ParseDuration(staggerRaw)
+		if err != nil || stagger < 0 {
+			return nil, errors.WithStackTrace(InvalidStagger(staggerRaw))
+		}
+	}
+
+	maxStartsPerMinuteRaw, err := parseStringArg(args, OPT_TERRAGRUNT_MAX_STARTS_PER_MINUTE, os.Getenv("TERRAGRUNT_MAX_STARTS_PER_MINUTE"))
+	if err != nil {
+		return nil, err
+	}
+	maxStartsPerMinute := 0
+	if maxStartsPerMinuteRaw != "" {
+		maxStartsPerMinute, err = strconv.Atoi(maxStartsPerMinuteRaw)
+		if err != nil || maxStartsPerMinute < 1 {
+			return nil, errors.WithStackTrace(InvalidMaxStartsPerMinute(maxStartsPerMinuteRaw))
+		}
+	}
+
+	resume, err := parseStringArg(args, OPT_TERRAGRUNT_RESUME, os.Getenv("TERRAGRUNT_RESUME"))
+	if err != nil {
+		return nil, err
+	}
+
+	httpTimeoutRaw, err := parseStringArg(args, OPT_TERRAGRUNT_HTTP_TIMEOUT, os.Getenv("TERRAGRUNT_HTTP_TIMEOUT"))
+	if err != nil {
+		return nil, err
+	}
+	httpTimeout := options.DEFAULT_HTTP_TIMEOUT
+	if httpTimeoutRaw != "" {
+		httpTimeout, err = time.ParseDuration(httpTimeoutRaw)
+		if err != nil || httpTimeout <= 0 {
+			return nil, errors.WithStackTrace(InvalidHttpTimeout(httpTimeoutRaw))
+		}
+	}
+
+	sensitiveVarPatternsRaw, err := parseMultiStringArg(args, OPT_TERRAGRUNT_SENSITIVE_VAR, parseCommaSeparatedList(os.Getenv("TERRAGRUNT_SENSITIVE_VARS")))
+	if err != nil {
+		return nil, err
+	}
+	sensitiveVarPatterns := []*regexp.Regexp{}
+	for _, pattern := range sensitiveVarPatternsRaw {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.WithStackTrace(InvalidSensitiveVarPattern(pattern))
+		}
+		sensitiveVarPatterns = append(sensitiveVarPatterns, compiled)
+	}
+
+	includeDependenciesDepthRaw, err := parseStringArg(args, OPT_TERRAGRUNT_INCLUDE_DEPENDENCIES_DEPTH, os.Getenv("TERRAGRUNT_INCLUDE_DEPENDENCIES_DEPTH"))
+	if err != nil {
+		return nil, err
+	}
+	includeDependenciesDepth := 0
+	if includeDependenciesDepthRaw != "" {
+		includeDependenciesDepth, err = strconv.Atoi(includeDependenciesDepthRaw)
+		if err != nil || includeDependenciesDepth < 1 {
+			return nil, errors.WithStackTrace(InvalidIncludeDependenciesDepth(includeDependenciesDepthRaw))
+		}
+	}
+
+	disableExtraArgs, disabledExtraArgs := parseOptionalListArg(args, OPT_TERRAGRUNT_DISABLE_EXTRA_ARGS, os.Getenv("TERRAGRUNT_DISABLE_EXTRA_ARGS"))
+	disableHooks, disabledHooks := parseOptionalListArg(args, OPT_TERRAGRUNT_DISABLE_HOOKS, os.Getenv("TERRAGRUNT_DISABLE_HOOKS"))
+
+	stackRunId, err := util.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+
 	opts, err := options.NewTerragruntOptions(filepath.ToSlash(terragruntConfigPath))
 	if err != nil {
 		return nil, err
@@ -109,6 +220,7 @@ func parseTerragruntOptionsFromArgs(args []string, writer, errWriter io.Writer)
 	opts.TerraformCliArgs = filterTerragruntArgs(args)
 	opts.TerraformCommand = util.FirstArg(opts.TerraformCliArgs)
 	opts.WorkingDir = filepath.ToSlash(workingDir)
+	opts.OriginalWorkingDir = opts.WorkingDir
 	opts.DownloadDir = filepath.ToSlash(downloadDir)
 	opts.Logger = util.CreateLoggerWithWriter(errWriter, "")
 	opts.RunTerragrunt = runTerragrunt
@@ -121,73 +233,44 @@ func parseTerragruntOptionsFromArgs(args []string, writer, errWriter io.Writer)
 	opts.IamRole = iamRole
 	opts.ExcludeDirs = excludeDirs
 	opts.IncludeDirs = includeDirs
+	opts.ShowConfigDiff = parseBooleanArg(args, OPT_TERRAGRUNT_SHOW_CONFIG_DIFF, os.Getenv("TERRAGRUNT_SHOW_CONFIG_DIFF") == "true")
+	opts.TrackConfigChanges = opts.ShowConfigDiff || parseBooleanArg(args, OPT_TERRAGRUNT_TRACK_CONFIG_CHANGES, os.Getenv("TERRAGRUNT_TRACK_CONFIG_CHANGES") == "true")
+	opts.JsonOut = jsonOut
+	opts.StatusPort = statusPort
+	opts.StatusBindAddress = statusBindAddress
+	opts.CheckForUpdates = parseBooleanArg(args, OPT_TERRAGRUNT_CHECK_FOR_UPDATES, os.Getenv("TERRAGRUNT_CHECK_FOR_UPDATES") == "true")
+	opts.VersionCheckUrl = versionCheckUrl
+	opts.Stagger = stagger
+	opts.MaxStartsPerMinute = maxStartsPerMinute
+	opts.RootWorkingDir = opts.WorkingDir
+	opts.StackRunId = stackRunId
+	opts.Strict = parseBooleanArg(args, OPT_TERRAGRUNT_STRICT, os.Getenv("TERRAGRUNT_STRICT") == "true")
+	opts.FailOnEmpty = parseBooleanArg(args, OPT_TERRAGRUNT_FAIL_ON_EMPTY, os.Getenv("TERRAGRUNT_FAIL_ON_EMPTY") == "true")
+	opts.IncludeSensitive = parseBooleanArg(args, OPT_TERRAGRUNT_INCLUDE_SENSITIVE, os.Getenv("TERRAGRUNT_INCLUDE_SENSITIVE") == "true")
+	opts.Resume = resume
+	opts.HttpTimeout = httpTimeout
+	opts.SensitiveVarPatterns = sensitiveVarPatterns
+	opts.IncludeDependencies = parseBooleanArg(args, OPT_TERRAGRUNT_INCLUDE_DEPENDENCIES, os.Getenv("TERRAGRUNT_INCLUDE_DEPENDENCIES") == "true")
+	opts.IncludeDependenciesDepth = includeDependenciesDepth
+	opts.LogHelperMetrics = parseBooleanArg(args, OPT_TERRAGRUNT_LOG_HELPER_METRICS, os.Getenv("TERRAGRUNT_LOG_HELPER_METRICS") == "true")
+	opts.DisableExtraArgs = disableExtraArgs
+	opts.DisabledExtraArgs = disabledExtraArgs
+	opts.DisableHooks = disableHooks
+	opts.DisabledHooks = disabledHooks
 
 	return opts, nil
 }
 
-func filterTerraformExtraArgs(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) []string {
-	out := []string{}
-	cmd := util.FirstArg(terragruntOptions.TerraformCliArgs)
-
-	for _, arg := range terragruntConfig.Terraform.ExtraArgs {
-		for _, arg_cmd := range arg.Commands {
-			if cmd == arg_cmd {
-				lastArg := util.LastArg(terragruntOptions.TerraformCliArgs)
-				skipVars := cmd == "apply" && util.IsFile(lastArg)
-
-				// The following is a fix for GH-493.
-				// If the first argument is "apply" and the second argument is a file (plan),
-				// we don't add any -var-file to the command.
-				if skipVars {
-					// If we have to skip vars, we need to iterate over all elements of array...
-					for _, a := range arg.Arguments {
-						if !strings.HasPrefix(a, "-var") {
-							out = append(out, a)
-						}
-					}
-				} else {
-					// ... Otherwise, let's add all the arguments
-					out = append(out, arg.Arguments...)
-				}
-
-				if !skipVars {
-					// If RequiredVarFiles is specified, add -var-file=<file> for each specified files
-					for _, file := range util.RemoveDuplicatesFromListKeepLast(arg.RequiredVarFiles) {
-						out = append(out, fmt.Sprintf("-var-file=%s", file))
-					}
-
-					// If OptionalVarFiles is specified, check for each file if it exists and if so, add -var-file=<file>
-					// It is possible that many files resolve to the same path, so we remove duplicates.
-					for _, file := range util.RemoveDuplicatesFromListKeepLast(arg.OptionalVarFiles) {
-						if util.FileExists(file) {
-							out = append(out, fmt.Sprintf("-var-file=%s", file))
-						} else {
-							terragruntOptions.Logger.Printf("Skipping var-file %s as it does not exist", file)
-						}
-					}
-				}
-			}
+// Parse a comma separated list, such as the regular expressions in the TERRAGRUNT_SENSITIVE_VARS env var. Empty entries
+// are skipped, so a trailing comma does no harm.
+func parseCommaSeparatedList(value string) []string {
+	patterns := []string{}
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
 		}
 	}
-
-	return out
-}
-
-func filterTerraformEnvVarsFromExtraArgs(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) map[string]string {
-	out := map[string]string{}
-	cmd := util.FirstArg(terragruntOptions.TerraformCliArgs)
-
-	for _, arg := range terragruntConfig.Terraform.ExtraArgs {
-		for _, argcmd := range arg.Commands {
-			if cmd == argcmd {
-				for k, v := range arg.EnvVars {
-					out[k] = v
-				}
-			}
-		}
-	}
-
-	return out
+	return patterns
 }
 
 func parseEnvironmentVariables(environment []string) map[string]string {
@@ -225,6 +308,13 @@ func filterTerragruntArgs(args []string) []string {
 			// Just skip the boolean flag
 			continue
 		}
+		if util.ListContainsElement(ALL_TERRAGRUNT_OPTIONAL_LIST_OPTS, argWithoutPrefix) {
+			// Skip the flag, and its list of names if it has one
+			if hasOptionalArgValue(args, i) {
+				i = i + 1
+			}
+			continue
+		}
 
 		out = append(out, arg)
 	}
@@ -278,6 +368,41 @@ func parseMultiStringArg(args []string, argName string, defaultValue []string) (
 	return stringArgs, nil
 }
 
+// Find an argument of the given name that may be passed on its own (e.g. --foo), or followed by a comma separated list
+// of names (e.g. --foo A,B), in the given list of arguments. It may be passed more than once. Return true if it's passed
+// on its own at least once, along with the names in all of its lists. If it isn't present, envValue is used instead:
+// "true" is the same as passing the argument on its own, and anything else is a comma separated list of names.
+func parseOptionalListArg(args []string, argName string, envValue string) (bool, []string) {
+	isPresent := false
+	isPresentWithoutList := false
+	names := []string{}
+
+	for i, arg := range args {
+		if arg == fmt.Sprintf("--%s", argName) {
+			isPresent = true
+			if hasOptionalArgValue(args, i) {
+				names = append(names, parseCommaSeparatedList(args[i+1])...)
+			} else {
+				isPresentWithoutList = true
+			}
+		}
+	}
+	if isPresent {
+		return isPresentWithoutList, names
+	}
+
+	if strings.TrimSpace(envValue) == "true" {
+		return true, names
+	}
+	return false, parseCommaSeparatedList(envValue)
+}
+
+// Return true if the argument at the given index is followed by a value of its own, rather than by another flag or by
+// nothing at all
+func hasOptionalArgValue(args []string, index int) bool {
+	return index+1 < len(args) && !strings.HasPrefix(args[index+1], "-")
+}
+
 // Custom error types
 
 type ArgMissingValue string
@@ -285,3 +410,39 @@ type ArgMissingValue string
 func (err ArgMissingValue) Error() string {
 	return fmt.Sprintf("You must specify a value for the --%s option", string(err))
 }
+
+type InvalidStatusPort string
+
+func (err InvalidStatusPort) Error() string {
+	return fmt.Sprintf("The --%s option must be a port number between 1 and 65535, but got '%s'", OPT_TERRAGRUNT_STATUS_PORT, string(err))
+}
+
+type InvalidStagger string
+
+func (err InvalidStagger) Error() string {
+	return fmt.Sprintf("The --%s option must be a non-negative duration, such as 3s or 500ms, but got '%s'", OPT_TERRAGRUNT_STAGGER, string(err))
+}
+
+type InvalidMaxStartsPerMinute string
+
+func (err InvalidMaxStartsPerMinute) Error() string {
+	return fmt.Sprintf("The --%s option must be a whole number of at least 1, but got '%s'", OPT_TERRAGRUNT_MAX_STARTS_PER_MINUTE, string(err))
+}
+
+type InvalidHttpTimeout string
+
+func (err InvalidHttpTimeout) Error() string {
+	return fmt.Sprintf("The --%s option must be a positive duration, such as 30s or 1m, but got '%s'", OPT_TERRAGRUNT_HTTP_TIMEOUT, string(err))
+}
+
+type InvalidSensitiveVarPattern string
+
+func (err InvalidSensitiveVarPattern) Error() string {
+	return fmt.Sprintf("The --%s option must be a valid regular expression (https://github.com/google/re2/wiki/Syntax), but got '%s'", OPT_TERRAGRUNT_SENSITIVE_VAR, string(err))
+}
+
+type InvalidIncludeDependenciesDepth string
+
+func (err InvalidIncludeDependenciesDepth) Error() string {
+	return fmt.Sprintf("The --%s option must be a whole number of at least 1, but got '%s'", OPT_TERRAGRUNT_INCLUDE_DEPENDENCIES_DEPTH, string(err))
+}
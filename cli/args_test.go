@@ -6,7 +6,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"testing"
+	"time"
 
 	"strings"
 
@@ -93,6 +95,168 @@ func TestParseTerragruntOptionsFromArgs(t *testing.T) {
 			nil,
 		},
 
+		{
+			[]string{"terragrunt-info", "--terragrunt-json-out", "/some/path/info.json"},
+			mockOptionsWithJsonOut(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"terragrunt-info"}, "/some/path/info.json"),
+			nil,
+		},
+
+		{
+			[]string{"apply-all", "--terragrunt-status-port", "8123", "--terragrunt-status-bind-address", "0.0.0.0"},
+			mockOptionsWithStatusPort(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{}, 8123, "0.0.0.0"),
+			nil,
+		},
+
+		{
+			[]string{"plan", "--terragrunt-check-for-updates", "--terragrunt-version-check-url", "https://example.com/latest"},
+			mockOptionsWithVersionCheck(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"plan"}, true, "https://example.com/latest"),
+			nil,
+		},
+
+		{
+			[]string{"--terragrunt-version-check-url"},
+			nil,
+			ArgMissingValue("terragrunt-version-check-url"),
+		},
+
+		{
+			[]string{"plan-all", "--terragrunt-stagger", "3s", "--terragrunt-max-starts-per-minute", "20"},
+			mockOptionsWithStartLimits(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{}, 3*time.Second, 20),
+			nil,
+		},
+
+		{
+			[]string{"plan", "--terragrunt-strict"},
+			mockOptionsWithStrict(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"plan"}, true),
+			nil,
+		},
+
+		{
+			[]string{"plan", "--terragrunt-fail-on-empty"},
+			mockOptionsWithFailOnEmpty(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"plan"}),
+			nil,
+		},
+
+		{
+			[]string{"render-inputs", "--terragrunt-include-sensitive"},
+			mockOptionsWithIncludeSensitive(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"render-inputs"}, true),
+			nil,
+		},
+
+		{
+			[]string{"apply-all", "--terragrunt-resume", "last"},
+			mockOptionsWithResume(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{}, "last"),
+			nil,
+		},
+
+		{
+			[]string{"plan", "--terragrunt-http-timeout", "30s"},
+			mockOptionsWithHttpTimeout(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"plan"}, 30*time.Second),
+			nil,
+		},
+
+		{
+			[]string{"plan", "--terragrunt-http-timeout", "0s"},
+			nil,
+			InvalidHttpTimeout("0s"),
+		},
+
+		{
+			[]string{"render-inputs", "--terragrunt-sensitive-var", "^db_", "--terragrunt-sensitive-var", "_key$"},
+			mockOptionsWithSensitiveVarPatterns(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"render-inputs"}, "^db_", "_key$"),
+			nil,
+		},
+
+		{
+			[]string{"render-inputs", "--terragrunt-sensitive-var", "(unclosed"},
+			nil,
+			InvalidSensitiveVarPattern("(unclosed"),
+		},
+
+		{
+			[]string{"plan-all", "--terragrunt-stagger", "500ms"},
+			mockOptionsWithStartLimits(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{}, 500*time.Millisecond, 0),
+			nil,
+		},
+
+		{
+			[]string{"plan-all", "--terragrunt-stagger", "3"},
+			nil,
+			InvalidStagger("3"),
+		},
+
+		{
+			[]string{"plan-all", "--terragrunt-stagger", "-1s"},
+			nil,
+			InvalidStagger("-1s"),
+		},
+
+		{
+			[]string{"plan-all", "--terragrunt-max-starts-per-minute", "0"},
+			nil,
+			InvalidMaxStartsPerMinute("0"),
+		},
+
+		{
+			[]string{"plan-all", "--terragrunt-max-starts-per-minute", "lots"},
+			nil,
+			InvalidMaxStartsPerMinute("lots"),
+		},
+
+		{
+			[]string{"plan", "--terragrunt-include-dependencies", "--terragrunt-include-dependencies-depth", "2"},
+			mockOptionsWithIncludeDependencies(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"plan"}, true, 2),
+			nil,
+		},
+
+		{
+			[]string{"plan", "--terragrunt-log-helper-metrics"},
+			mockOptionsWithLogHelperMetrics(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"plan"}),
+			nil,
+		},
+
+		{
+			[]string{"plan", "--terragrunt-disable-extra-args", "--terragrunt-disable-hooks"},
+			mockOptionsWithDisabledExtraArgsAndHooks(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"plan"}, true, []string{}, true, []string{}),
+			nil,
+		},
+
+		{
+			[]string{"plan", "--terragrunt-disable-extra-args", "name1,name2"},
+			mockOptionsWithDisabledExtraArgsAndHooks(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"plan"}, false, []string{"name1", "name2"}, false, []string{}),
+			nil,
+		},
+
+		{
+			[]string{"plan", "--terragrunt-disable-extra-args", "vars,retry", "--terragrunt-disable-extra-args", "locking", "--terragrunt-disable-hooks", "fmt", "-out=plan.out"},
+			mockOptionsWithDisabledExtraArgsAndHooks(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"plan", "-out=plan.out"}, false, []string{"vars", "retry", "locking"}, false, []string{"fmt"}),
+			nil,
+		},
+
+		{
+			[]string{"plan", "--terragrunt-disable-extra-args", "vars", "--terragrunt-disable-extra-args", "--terragrunt-disable-hooks", "-input=false"},
+			mockOptionsWithDisabledExtraArgsAndHooks(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{"plan", "-input=false"}, true, []string{"vars"}, true, []string{}),
+			nil,
+		},
+
+		{
+			[]string{"plan", "--terragrunt-include-dependencies", "--terragrunt-include-dependencies-depth", "0"},
+			nil,
+			InvalidIncludeDependenciesDepth("0"),
+		},
+
+		{
+			[]string{"apply-all", "--terragrunt-status-port", "not-a-port"},
+			nil,
+			InvalidStatusPort("not-a-port"),
+		},
+
+		{
+			[]string{"apply-all", "--terragrunt-status-port", "70000"},
+			nil,
+			InvalidStatusPort("70000"),
+		},
+
 		{
 			[]string{"--terragrunt-config", fmt.Sprintf("/some/path/%s", config.DefaultTerragruntConfigPath), "--terragrunt-non-interactive"},
 			mockOptions(t, fmt.Sprintf("/some/path/%s", config.DefaultTerragruntConfigPath), workingDir, []string{}, true, "", false),
@@ -148,6 +312,38 @@ func assertOptionsEqual(t *testing.T, expected options.TerragruntOptions, actual
 	assert.Equal(t, expected.Source, actual.Source, msgAndArgs...)
 	assert.Equal(t, expected.IgnoreDependencyErrors, actual.IgnoreDependencyErrors, msgAndArgs...)
 	assert.Equal(t, expected.IamRole, actual.IamRole, msgAndArgs...)
+	assert.Equal(t, expected.JsonOut, actual.JsonOut, msgAndArgs...)
+	assert.Equal(t, expected.StatusPort, actual.StatusPort, msgAndArgs...)
+	assert.Equal(t, expected.StatusBindAddress, actual.StatusBindAddress, msgAndArgs...)
+	assert.Equal(t, expected.CheckForUpdates, actual.CheckForUpdates, msgAndArgs...)
+	assert.Equal(t, expected.VersionCheckUrl, actual.VersionCheckUrl, msgAndArgs...)
+	assert.Equal(t, expected.Stagger, actual.Stagger, msgAndArgs...)
+	assert.Equal(t, expected.MaxStartsPerMinute, actual.MaxStartsPerMinute, msgAndArgs...)
+	assert.Equal(t, expected.WorkingDir, actual.RootWorkingDir, msgAndArgs...)
+	assert.Equal(t, expected.WorkingDir, actual.OriginalWorkingDir, msgAndArgs...)
+	assert.NotEmpty(t, actual.StackRunId, msgAndArgs...)
+	assert.Equal(t, expected.Strict, actual.Strict, msgAndArgs...)
+	assert.Equal(t, expected.FailOnEmpty, actual.FailOnEmpty, msgAndArgs...)
+	assert.Equal(t, expected.IncludeSensitive, actual.IncludeSensitive, msgAndArgs...)
+	assert.Equal(t, expected.Resume, actual.Resume, msgAndArgs...)
+	assert.Equal(t, expected.HttpTimeout, actual.HttpTimeout, msgAndArgs...)
+	assert.Equal(t, sensitiveVarPatternStrings(expected.SensitiveVarPatterns), sensitiveVarPatternStrings(actual.SensitiveVarPatterns), msgAndArgs...)
+	assert.Equal(t, expected.IncludeDependencies, actual.IncludeDependencies, msgAndArgs...)
+	assert.Equal(t, expected.IncludeDependenciesDepth, actual.IncludeDependenciesDepth, msgAndArgs...)
+	assert.Equal(t, expected.LogHelperMetrics, actual.LogHelperMetrics, msgAndArgs...)
+	assert.Equal(t, expected.DisableExtraArgs, actual.DisableExtraArgs, msgAndArgs...)
+	assert.Equal(t, expected.DisabledExtraArgs, actual.DisabledExtraArgs, msgAndArgs...)
+	assert.Equal(t, expected.DisableHooks, actual.DisableHooks, msgAndArgs...)
+	assert.Equal(t, expected.DisabledHooks, actual.DisabledHooks, msgAndArgs...)
+	assert.NotNil(t, actual.HelperMetrics, msgAndArgs...)
+}
+
+func sensitiveVarPatternStrings(patterns []*regexp.Regexp) []string {
+	out := []string{}
+	for _, pattern := range patterns {
+		out = append(out, pattern.String())
+	}
+	return out
 }
 
 func mockOptions(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, nonInteractive bool, terragruntSource string, ignoreDependencyErrors bool) *options.TerragruntOptions {
@@ -172,6 +368,106 @@ func mockOptionsWithIamRole(t *testing.T, terragruntConfigPath string, workingDi
 	return opts
 }
 
+func mockOptionsWithJsonOut(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, jsonOut string) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.JsonOut = jsonOut
+
+	return opts
+}
+
+func mockOptionsWithStatusPort(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, statusPort int, statusBindAddress string) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.StatusPort = statusPort
+	opts.StatusBindAddress = statusBindAddress
+
+	return opts
+}
+
+func mockOptionsWithVersionCheck(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, checkForUpdates bool, versionCheckUrl string) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.CheckForUpdates = checkForUpdates
+	opts.VersionCheckUrl = versionCheckUrl
+
+	return opts
+}
+
+func mockOptionsWithStrict(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, strict bool) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.Strict = strict
+
+	return opts
+}
+
+func mockOptionsWithFailOnEmpty(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.FailOnEmpty = true
+
+	return opts
+}
+
+func mockOptionsWithIncludeSensitive(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, includeSensitive bool) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.IncludeSensitive = includeSensitive
+
+	return opts
+}
+
+func mockOptionsWithResume(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, resume string) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.Resume = resume
+
+	return opts
+}
+
+func mockOptionsWithHttpTimeout(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, httpTimeout time.Duration) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.HttpTimeout = httpTimeout
+
+	return opts
+}
+
+func mockOptionsWithSensitiveVarPatterns(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, patterns ...string) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	for _, pattern := range patterns {
+		opts.SensitiveVarPatterns = append(opts.SensitiveVarPatterns, regexp.MustCompile(pattern))
+	}
+
+	return opts
+}
+
+func mockOptionsWithStartLimits(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, stagger time.Duration, maxStartsPerMinute int) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.Stagger = stagger
+	opts.MaxStartsPerMinute = maxStartsPerMinute
+
+	return opts
+}
+
+func mockOptionsWithIncludeDependencies(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, includeDependencies bool, includeDependenciesDepth int) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.IncludeDependencies = includeDependencies
+	opts.IncludeDependenciesDepth = includeDependenciesDepth
+
+	return opts
+}
+
+func mockOptionsWithLogHelperMetrics(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.LogHelperMetrics = true
+
+	return opts
+}
+
+func mockOptionsWithDisabledExtraArgsAndHooks(t *testing.T, terragruntConfigPath string, workingDir string, terraformCliArgs []string, disableExtraArgs bool, disabledExtraArgs []string, disableHooks bool, disabledHooks []string) *options.TerragruntOptions {
+	opts := mockOptions(t, terragruntConfigPath, workingDir, terraformCliArgs, false, "", false)
+	opts.DisableExtraArgs = disableExtraArgs
+	opts.DisabledExtraArgs = disabledExtraArgs
+	opts.DisableHooks = disableHooks
+	opts.DisabledHooks = disabledHooks
+
+	return opts
+}
+
 func TestFilterTerragruntArgs(t *testing.T) {
 	t.Parallel()
 
@@ -186,6 +482,9 @@ func TestFilterTerragruntArgs(t *testing.T) {
 		{[]string{"foo", "--terragrunt-non-interactive", "--bar", "--terragrunt-working-dir", "/some/path", "--baz", "--terragrunt-config", fmt.Sprintf("/some/path/%s", config.DefaultTerragruntConfigPath)}, []string{"foo", "--bar", "--baz"}},
 		{[]string{"apply-all", "foo", "bar"}, []string{"foo", "bar"}},
 		{[]string{"foo", "destroy-all", "--foo", "--bar"}, []string{"foo", "--foo", "--bar"}},
+		{[]string{"plan", "--terragrunt-disable-extra-args", "name1,name2"}, []string{"plan"}},
+		{[]string{"plan", "--terragrunt-disable-extra-args", "--terragrunt-disable-hooks", "fmt", "-input=false"}, []string{"plan", "-input=false"}},
+		{[]string{"plan", "--terragrunt-disable-hooks"}, []string{"plan"}},
 	}
 
 	for _, testCase := range testCases {
@@ -194,6 +493,18 @@ func TestFilterTerragruntArgs(t *testing.T) {
 	}
 }
 
+// get_terragrunt_cli_flag can't use the lists of flags in this package, so make sure it knows about exactly the same
+// flags
+func TestGetTerragruntCliFlagKnowsAllFlags(t *testing.T) {
+	t.Parallel()
+
+	allFlags := append(append(append([]string{}, ALL_TERRAGRUNT_BOOLEAN_OPTS...), ALL_TERRAGRUNT_STRING_OPTS...), ALL_TERRAGRUNT_OPTIONAL_LIST_OPTS...)
+	for _, flag := range allFlags {
+		assert.Contains(t, config.TERRAGRUNT_CLI_FLAGS, flag)
+	}
+	assert.Equal(t, len(allFlags), len(config.TERRAGRUNT_CLI_FLAGS))
+}
+
 func TestParseMultiStringArg(t *testing.T) {
 	t.Parallel()
 
@@ -222,6 +533,33 @@ func TestParseMultiStringArg(t *testing.T) {
 	}
 }
 
+func TestParseOptionalListArg(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		args                []string
+		envValue            string
+		expectedWithoutList bool
+		expectedNames       []string
+	}{
+		{[]string{"plan"}, "", false, []string{}},
+		{[]string{"plan", "--foo"}, "", true, []string{}},
+		{[]string{"plan", "--foo", "a,b"}, "", false, []string{"a", "b"}},
+		{[]string{"plan", "--foo", "a,b", "--foo", "c", "--bar"}, "", false, []string{"a", "b", "c"}},
+		{[]string{"plan", "--foo", "--bar", "value"}, "", true, []string{}},
+		{[]string{"plan", "--foo", "a", "--foo"}, "", true, []string{"a"}},
+		{[]string{"plan"}, "true", true, []string{}},
+		{[]string{"plan"}, "a, b,", false, []string{"a", "b"}},
+		{[]string{"plan", "--foo", "c"}, "true", false, []string{"c"}},
+	}
+
+	for _, testCase := range testCases {
+		actualWithoutList, actualNames := parseOptionalListArg(testCase.args, "foo", testCase.envValue)
+		assert.Equal(t, testCase.expectedWithoutList, actualWithoutList, "For args %v and env value %s", testCase.args, testCase.envValue)
+		assert.Equal(t, testCase.expectedNames, actualNames, "For args %v and env value %s", testCase.args, testCase.envValue)
+	}
+}
+
 func TestParseEnvironmentVariables(t *testing.T) {
 	testCases := []struct {
 		environmentVariables []string
@@ -402,17 +740,61 @@ func TestFilterTerraformExtraArgs(t *testing.T) {
 		},
 	}
 	for _, testCase := range testCases {
-		config := config.TerragruntConfig{
+		terragruntConfig := config.TerragruntConfig{
 			Terraform: &config.TerraformConfig{ExtraArgs: []config.TerraformExtraArguments{testCase.extraArgs}},
 		}
 
-		out := filterTerraformExtraArgs(testCase.options, &config)
+		out := config.TerraformExtraArgsForCommand(testCase.options, &terragruntConfig)
 
 		assert.Equal(t, testCase.expectedArgs, out)
 	}
 
 }
 
+func TestFilterTerraformExtraArgsDisabled(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := os.Getwd()
+	assert.Nil(t, err)
+
+	vars := mockExtraArgs([]string{"-lock=false"}, []string{"plan", "apply"}, []string{"required.tfvars"}, []string{})
+	vars.Name = "vars"
+	vars.EnvVars = map[string]string{"TF_VAR_foo": "bar"}
+	retry := mockExtraArgs([]string{"-lock-timeout=20m"}, []string{"plan", "apply"}, []string{}, []string{})
+	retry.Name = "retry"
+	destroy := mockExtraArgs([]string{"-force"}, []string{"destroy"}, []string{}, []string{})
+	destroy.Name = "destroy"
+
+	terragruntConfig := config.TerragruntConfig{
+		Terraform: &config.TerraformConfig{ExtraArgs: []config.TerraformExtraArguments{vars, retry, destroy}},
+	}
+
+	testCases := []struct {
+		disableExtraArgs  bool
+		disabledExtraArgs []string
+		expectedArgs      []string
+		expectedEnvVars   map[string]string
+		expectedDisabled  []string
+	}{
+		{false, []string{}, []string{"-lock=false", "-var-file=required.tfvars", "-lock-timeout=20m"}, map[string]string{"TF_VAR_foo": "bar"}, []string{}},
+		{false, []string{"vars"}, []string{"-lock-timeout=20m"}, map[string]string{}, []string{"vars"}},
+		{false, []string{"retry"}, []string{"-lock=false", "-var-file=required.tfvars"}, map[string]string{"TF_VAR_foo": "bar"}, []string{"retry"}},
+		{false, []string{"destroy", "no-such-block"}, []string{"-lock=false", "-var-file=required.tfvars", "-lock-timeout=20m"}, map[string]string{"TF_VAR_foo": "bar"}, []string{}},
+		{false, []string{"vars", "retry"}, []string{}, map[string]string{}, []string{"vars", "retry"}},
+		{true, []string{}, []string{}, map[string]string{}, []string{"vars", "retry"}},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions := mockCmdOptions(t, workingDir, []string{"plan"})
+		terragruntOptions.DisableExtraArgs = testCase.disableExtraArgs
+		terragruntOptions.DisabledExtraArgs = testCase.disabledExtraArgs
+
+		assert.Equal(t, testCase.expectedArgs, config.TerraformExtraArgsForCommand(terragruntOptions, &terragruntConfig), "For disabled %v", testCase.disabledExtraArgs)
+		assert.Equal(t, testCase.expectedEnvVars, config.TerraformEnvVarsForCommand(terragruntOptions, &terragruntConfig), "For disabled %v", testCase.disabledExtraArgs)
+		assert.Equal(t, testCase.expectedDisabled, config.DisabledExtraArgsForCommand(terragruntOptions, &terragruntConfig), "For disabled %v", testCase.disabledExtraArgs)
+	}
+}
+
 func createTempFile(t *testing.T) string {
 	tmpFile, err := ioutil.TempFile("", "")
 	if err != nil {
@@ -0,0 +1,327 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The manifest, in the root of the download dir, that records which module each cache entry in the download dir
+// belongs to and when it was last used
+const CACHE_MANIFEST_FILE = ".terragrunt-cache-manifest.json"
+
+// The lock file that guards reads and writes of the manifest, so concurrent runs, such as those of apply-all, don't
+// lose each other's updates
+const CACHE_MANIFEST_LOCK_FILE = ".terragrunt-cache-manifest.lock"
+
+// The file, in each cache entry, that records the same information as the manifest does for that entry. If the
+// manifest is missing or corrupt, it's rebuilt from these files.
+const CACHE_ENTRY_FILE = ".terragrunt-cache-entry.json"
+
+// The version of the format of the manifest. A manifest with a different version is rebuilt.
+const CACHE_MANIFEST_VERSION = 1
+
+// How long to wait for other Terragrunt processes to finish updating the manifest
+const CACHE_MANIFEST_LOCK_TIMEOUT = 30 * time.Second
+
+// The options of the clean-cache command
+const CLEAN_CACHE_OPT_ORPHANS = "orphans"
+const CLEAN_CACHE_OPT_UNUSED_FOR = "unused-for"
+const CLEAN_CACHE_OPT_DRY_RUN = "dry-run"
+
+type cacheManifest struct {
+	Version int                           `json:"version"`
+	Entries map[string]cacheManifestEntry `json:"entries"`
+}
+
+// A cache entry is the folder in the download dir that holds all the code downloaded for a single module
+type cacheManifestEntry struct {
+	ConfigPath string    `json:"config_path"`
+	CachePath  string    `json:"cache_path"`
+	LastUsed   time.Time `json:"last_used"`
+}
+
+type cleanCacheOptions struct {
+	// Remove cache entries whose Terragrunt config no longer exists
+	Orphans bool
+
+	// Remove cache entries that haven't been used for longer than this. Zero means don't remove entries based on age.
+	UnusedFor time.Duration
+
+	// Only log which cache entries would be removed
+	DryRun bool
+}
+
+// Record in the manifest of the download dir that the given cache entry was just used by the module of the current
+// Terragrunt config. Problems with the manifest are logged, but never fail the run.
+func recordCacheEntryUse(terragruntOptions *options.TerragruntOptions, cachePath string) {
+	canonicalConfigPath, err := util.CanonicalPath(terragruntOptions.TerragruntConfigPath, "")
+	if err != nil {
+		terragruntOptions.Logger.Printf("WARNING: Unable to record use of cache entry %s: %v", cachePath, err)
+		return
+	}
+
+	entry := cacheManifestEntry{ConfigPath: canonicalConfigPath, CachePath: cachePath, LastUsed: time.Now().UTC()}
+	if err := updateCacheManifest(terragruntOptions, entry); err != nil {
+		terragruntOptions.Logger.Printf("WARNING: Unable to record use of cache entry %s: %v", cachePath, err)
+	}
+}
+
+// Write the given entry to its cache entry file and to the manifest of the download dir
+func updateCacheManifest(terragruntOptions *options.TerragruntOptions, entry cacheManifestEntry) error {
+	if err := os.MkdirAll(entry.CachePath, 0700); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	if err := writeJsonFile(util.JoinPath(entry.CachePath, CACHE_ENTRY_FILE), entry); err != nil {
+		return err
+	}
+
+	return withCacheManifest(terragruntOptions, func(manifest *cacheManifest) (bool, error) {
+		manifest.Entries[entry.CachePath] = entry
+		return true, nil
+	})
+}
+
+// Run the given function with the manifest of the download dir, while holding the lock on it. If the function returns
+// true, the manifest, as modified by the function, is written back to disk.
+func withCacheManifest(terragruntOptions *options.TerragruntOptions, update func(manifest *cacheManifest) (bool, error)) error {
+	downloadDir := terragruntOptions.DownloadDir
+	if err := os.MkdirAll(downloadDir, 0700); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	release, err := util.AcquireLockFile(util.JoinPath(downloadDir, CACHE_MANIFEST_LOCK_FILE), CACHE_MANIFEST_LOCK_TIMEOUT)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	manifest, err := readCacheManifest(downloadDir)
+	if err != nil {
+		terragruntOptions.Logger.Printf("WARNING: Rebuilding the cache manifest in %s from its cache entries: %v", downloadDir, err)
+		manifest = rebuildCacheManifest(downloadDir)
+	}
+
+	changed, err := update(manifest)
+	if err != nil || !changed {
+		return err
+	}
+
+	return writeCacheManifest(downloadDir, manifest)
+}
+
+// Read the manifest of the given download dir. Returns an empty manifest if there is none yet, and an error if it's
+// corrupt or was written using a different version of the manifest format.
+func readCacheManifest(downloadDir string) (*cacheManifest, error) {
+	manifestPath := util.JoinPath(downloadDir, CACHE_MANIFEST_FILE)
+	if !util.FileExists(manifestPath) {
+		return &cacheManifest{Version: CACHE_MANIFEST_VERSION, Entries: map[string]cacheManifestEntry{}}, nil
+	}
+
+	contents, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	manifest := &cacheManifest{}
+	if err := json.Unmarshal(contents, manifest); err != nil {
+		return nil, errors.WithStackTrace(CorruptCacheManifest{Path: manifestPath, Underlying: err})
+	}
+	if manifest.Version != CACHE_MANIFEST_VERSION {
+		return nil, errors.WithStackTrace(CorruptCacheManifest{Path: manifestPath, Underlying: fmt.Errorf("unsupported version %d", manifest.Version)})
+	}
+	if manifest.Entries == nil {
+		manifest.Entries = map[string]cacheManifestEntry{}
+	}
+
+	return manifest, nil
+}
+
+// Build the manifest of the given download dir by scanning it for cache entry files. Cache entry files that can't be
+// read are skipped, so the cache entries they belong to are left alone until they're used again.
+func rebuildCacheManifest(downloadDir string) *cacheManifest {
+	manifest := &cacheManifest{Version: CACHE_MANIFEST_VERSION, Entries: map[string]cacheManifestEntry{}}
+
+	entryFiles, err := filepath.Glob(util.JoinPath(downloadDir, "*", CACHE_ENTRY_FILE))
+	if err != nil {
+		return manifest
+	}
+
+	for _, entryFile := range entryFiles {
+		contents, err := ioutil.ReadFile(entryFile)
+		if err != nil {
+			continue
+		}
+
+		entry := cacheManifestEntry{}
+		if err := json.Unmarshal(contents, &entry); err != nil || entry.ConfigPath == "" {
+			continue
+		}
+
+		entry.CachePath = filepath.ToSlash(filepath.Dir(entryFile))
+		manifest.Entries[entry.CachePath] = entry
+	}
+
+	return manifest
+}
+
+// Write the given manifest to the given download dir. The manifest is written to a temp file first, and then moved into
+// place, so that it's never left half written.
+func writeCacheManifest(downloadDir string, manifest *cacheManifest) error {
+	manifestPath := util.JoinPath(downloadDir, CACHE_MANIFEST_FILE)
+	tmpPath := manifestPath + ".tmp"
+
+	if err := writeJsonFile(tmpPath, manifest); err != nil {
+		return err
+	}
+	return errors.WithStackTrace(os.Rename(tmpPath, manifestPath))
+}
+
+func writeJsonFile(path string, data interface{}) error {
+	contents, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(path, contents, 0600))
+}
+
+// Remove cache entries from the download dir according to the options passed to the clean-cache command
+func cleanCache(terragruntOptions *options.TerragruntOptions) error {
+	cleanOptions, err := parseCleanCacheOptions(util.RemoveElementFromList(terragruntOptions.TerraformCliArgs, CMD_CLEAN_CACHE))
+	if err != nil {
+		return err
+	}
+	return cleanCacheEntries(terragruntOptions, cleanOptions, time.Now())
+}
+
+// Parse the options of the clean-cache command. At least one of --orphans and --unused-for must be specified.
+func parseCleanCacheOptions(args []string) (*cleanCacheOptions, error) {
+	cleanOptions := &cleanCacheOptions{
+		Orphans: parseBooleanArg(args, CLEAN_CACHE_OPT_ORPHANS, false),
+		DryRun:  parseBooleanArg(args, CLEAN_CACHE_OPT_DRY_RUN, false),
+	}
+
+	unusedFor, err := parseStringArg(args, CLEAN_CACHE_OPT_UNUSED_FOR, "")
+	if err != nil {
+		return nil, err
+	}
+	if unusedFor != "" {
+		cleanOptions.UnusedFor, err = parseAge(unusedFor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !cleanOptions.Orphans && cleanOptions.UnusedFor == 0 {
+		return nil, errors.WithStackTrace(NothingToClean{})
+	}
+
+	return cleanOptions, nil
+}
+
+// Parse an age such as 30d, 12h, or 90m. Days aren't supported by time.ParseDuration, so they are handled here.
+func parseAge(age string) (time.Duration, error) {
+	var duration time.Duration
+	var err error
+
+	if strings.HasSuffix(age, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(age, "d"))
+		duration = time.Duration(days) * 24 * time.Hour
+	} else {
+		duration, err = time.ParseDuration(age)
+	}
+
+	if err != nil || duration <= 0 {
+		return 0, errors.WithStackTrace(InvalidCacheAge(age))
+	}
+	return duration, nil
+}
+
+// Remove the cache entries in the manifest of the download dir that match the given options, as of the given time,
+// once the user confirms. The manifest stays locked while waiting for the user, so concurrent runs can't record uses
+// of the entries about to be removed.
+func cleanCacheEntries(terragruntOptions *options.TerragruntOptions, cleanOptions *cleanCacheOptions, now time.Time) error {
+	return withCacheManifest(terragruntOptions, func(manifest *cacheManifest) (bool, error) {
+		cachePaths := []string{}
+		for cachePath := range manifest.Entries {
+			cachePaths = append(cachePaths, cachePath)
+		}
+		sort.Strings(cachePaths)
+
+		toRemove := []string{}
+		changes := []string{}
+		for _, cachePath := range cachePaths {
+			if reason := reasonToCleanCacheEntry(manifest.Entries[cachePath], cleanOptions, now); reason != "" {
+				toRemove = append(toRemove, cachePath)
+				changes = append(changes, fmt.Sprintf("Remove cache entry %s: %s", cachePath, reason))
+			}
+		}
+
+		if len(toRemove) == 0 {
+			terragruntOptions.Logger.Printf("None of the %d cache entries in %s need to be removed", len(cachePaths), terragruntOptions.DownloadDir)
+			return false, nil
+		}
+
+		terragruntOptions.Logger.Printf("%d of %d cache entries in %s will be removed:", len(toRemove), len(cachePaths), terragruntOptions.DownloadDir)
+		shouldRemove, err := shell.ConfirmOrDryRun("Are you sure you want to remove the cache entries above?", changes, cleanOptions.DryRun, terragruntOptions)
+		if err != nil || !shouldRemove {
+			return false, err
+		}
+
+		for _, cachePath := range toRemove {
+			terragruntOptions.Logger.Printf("Removing cache entry %s", cachePath)
+			if err := os.RemoveAll(cachePath); err != nil {
+				return true, errors.WithStackTrace(err)
+			}
+			delete(manifest.Entries, cachePath)
+		}
+
+		return true, nil
+	})
+}
+
+// Return why the given cache entry should be removed, or an empty string if it shouldn't
+func reasonToCleanCacheEntry(entry cacheManifestEntry, cleanOptions *cleanCacheOptions, now time.Time) string {
+	if cleanOptions.Orphans && !util.FileExists(entry.ConfigPath) {
+		return fmt.Sprintf("its Terragrunt config %s no longer exists", entry.ConfigPath)
+	}
+	if cleanOptions.UnusedFor > 0 && now.Sub(entry.LastUsed) > cleanOptions.UnusedFor {
+		return fmt.Sprintf("it hasn't been used since %s", entry.LastUsed.Format(time.RFC3339))
+	}
+	return ""
+}
+
+// Custom error types
+
+type CorruptCacheManifest struct {
+	Path       string
+	Underlying error
+}
+
+func (err CorruptCacheManifest) Error() string {
+	return fmt.Sprintf("Unable to parse cache manifest at %s: %v", err.Path, err.Underlying)
+}
+
+type NothingToClean struct{}
+
+func (err NothingToClean) Error() string {
+	return fmt.Sprintf("The %s command requires --%s, --%s, or both", CMD_CLEAN_CACHE, CLEAN_CACHE_OPT_ORPHANS, CLEAN_CACHE_OPT_UNUSED_FOR)
+}
+
+type InvalidCacheAge string
+
+func (err InvalidCacheAge) Error() string {
+	return fmt.Sprintf("Invalid value '%s' for --%s. Expected a positive age such as 30d, 12h, or 90m.", string(err), CLEAN_CACHE_OPT_UNUSED_FOR)
+}
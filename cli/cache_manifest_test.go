@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The source used by the modules in the clean-cache fixture
+const CLEAN_CACHE_FIXTURE_SOURCE = "github.com/gruntwork-io/terragrunt.git//test/fixture-download/hello-world?ref=v0.9.9"
+
+func TestCleanCacheOrphansAfterDeletingModule(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, downloadDir := copyCleanCacheFixture(t)
+	defer os.RemoveAll(tmpDir)
+
+	appOptions := cacheTestOptions(t, util.JoinPath(tmpDir, "app", config.DefaultTerragruntConfigPath), downloadDir)
+	dbOptions := cacheTestOptions(t, util.JoinPath(tmpDir, "db", config.DefaultTerragruntConfigPath), downloadDir)
+	appCacheDir := recordFixtureCacheEntryUse(t, appOptions)
+	dbCacheDir := recordFixtureCacheEntryUse(t, dbOptions)
+
+	require.NoError(t, os.RemoveAll(util.JoinPath(tmpDir, "db")))
+
+	require.NoError(t, cleanCacheEntries(appOptions, &cleanCacheOptions{Orphans: true, DryRun: true}, time.Now()))
+	assert.True(t, util.IsDir(appCacheDir))
+	assert.True(t, util.IsDir(dbCacheDir))
+	assert.ElementsMatch(t, []string{appCacheDir, dbCacheDir}, cacheManifestPaths(t, downloadDir))
+
+	require.NoError(t, cleanCacheEntries(appOptions, &cleanCacheOptions{Orphans: true}, time.Now()))
+	assert.True(t, util.IsDir(appCacheDir))
+	assert.False(t, util.FileExists(dbCacheDir))
+	assert.Equal(t, []string{appCacheDir}, cacheManifestPaths(t, downloadDir))
+}
+
+func TestCleanCacheUnusedFor(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, downloadDir := copyCleanCacheFixture(t)
+	defer os.RemoveAll(tmpDir)
+
+	appOptions := cacheTestOptions(t, util.JoinPath(tmpDir, "app", config.DefaultTerragruntConfigPath), downloadDir)
+	dbOptions := cacheTestOptions(t, util.JoinPath(tmpDir, "db", config.DefaultTerragruntConfigPath), downloadDir)
+	appCacheDir := recordFixtureCacheEntryUse(t, appOptions)
+	dbCacheDir := recordFixtureCacheEntryUse(t, dbOptions)
+
+	now := time.Now()
+	require.NoError(t, updateCacheManifest(dbOptions, cacheManifestEntry{ConfigPath: dbOptions.TerragruntConfigPath, CachePath: dbCacheDir, LastUsed: now.Add(-31 * 24 * time.Hour)}))
+
+	require.NoError(t, cleanCacheEntries(appOptions, &cleanCacheOptions{UnusedFor: 30 * 24 * time.Hour}, now))
+	assert.True(t, util.IsDir(appCacheDir))
+	assert.False(t, util.FileExists(dbCacheDir))
+	assert.Equal(t, []string{appCacheDir}, cacheManifestPaths(t, downloadDir))
+}
+
+func TestCleanCacheDeclined(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, downloadDir := copyCleanCacheFixture(t)
+	defer os.RemoveAll(tmpDir)
+
+	appOptions := cacheTestOptions(t, util.JoinPath(tmpDir, "app", config.DefaultTerragruntConfigPath), downloadDir)
+	dbOptions := cacheTestOptions(t, util.JoinPath(tmpDir, "db", config.DefaultTerragruntConfigPath), downloadDir)
+	appCacheDir := recordFixtureCacheEntryUse(t, appOptions)
+	dbCacheDir := recordFixtureCacheEntryUse(t, dbOptions)
+
+	require.NoError(t, os.RemoveAll(util.JoinPath(tmpDir, "db")))
+
+	appOptions.NonInteractive = false
+	appOptions.Reader = strings.NewReader("n\n")
+
+	require.NoError(t, cleanCacheEntries(appOptions, &cleanCacheOptions{Orphans: true}, time.Now()))
+	assert.True(t, util.IsDir(appCacheDir))
+	assert.True(t, util.IsDir(dbCacheDir))
+	assert.ElementsMatch(t, []string{appCacheDir, dbCacheDir}, cacheManifestPaths(t, downloadDir))
+
+	appOptions.Reader = strings.NewReader("y\n")
+
+	require.NoError(t, cleanCacheEntries(appOptions, &cleanCacheOptions{Orphans: true}, time.Now()))
+	assert.True(t, util.IsDir(appCacheDir))
+	assert.False(t, util.FileExists(dbCacheDir))
+	assert.Equal(t, []string{appCacheDir}, cacheManifestPaths(t, downloadDir))
+}
+
+func TestCacheManifestRebuiltWhenCorrupt(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, downloadDir := copyCleanCacheFixture(t)
+	defer os.RemoveAll(tmpDir)
+
+	appOptions := cacheTestOptions(t, util.JoinPath(tmpDir, "app", config.DefaultTerragruntConfigPath), downloadDir)
+	dbOptions := cacheTestOptions(t, util.JoinPath(tmpDir, "db", config.DefaultTerragruntConfigPath), downloadDir)
+	appCacheDir := recordFixtureCacheEntryUse(t, appOptions)
+	dbCacheDir := recordFixtureCacheEntryUse(t, dbOptions)
+
+	require.NoError(t, ioutil.WriteFile(util.JoinPath(downloadDir, CACHE_MANIFEST_FILE), []byte(`{"version": 1, "entries": {`), 0600))
+
+	_, err := readCacheManifest(downloadDir)
+	if assert.Error(t, err) {
+		assert.IsType(t, CorruptCacheManifest{}, errors.Unwrap(err))
+	}
+
+	recordCacheEntryUse(appOptions, appCacheDir)
+	assert.ElementsMatch(t, []string{appCacheDir, dbCacheDir}, cacheManifestPaths(t, downloadDir))
+}
+
+func TestRecordCacheEntryUseConcurrently(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, downloadDir := copyCleanCacheFixture(t)
+	defer os.RemoveAll(tmpDir)
+
+	terragruntOptions := cacheTestOptions(t, util.JoinPath(tmpDir, "app", config.DefaultTerragruntConfigPath), downloadDir)
+
+	expected := []string{}
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		cachePath := util.JoinPath(downloadDir, fmt.Sprintf("module-%02d", i))
+		expected = append(expected, cachePath)
+
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			recordCacheEntryUse(terragruntOptions, cachePath)
+		}()
+	}
+	waitGroup.Wait()
+
+	assert.Equal(t, expected, cacheManifestPaths(t, downloadDir))
+}
+
+func TestParseCleanCacheOptions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		args        []string
+		expected    *cleanCacheOptions
+		expectedErr error
+	}{
+		{[]string{"--orphans"}, &cleanCacheOptions{Orphans: true}, nil},
+		{[]string{"--unused-for", "30d", "--dry-run"}, &cleanCacheOptions{UnusedFor: 30 * 24 * time.Hour, DryRun: true}, nil},
+		{[]string{"--orphans", "--unused-for", "12h"}, &cleanCacheOptions{Orphans: true, UnusedFor: 12 * time.Hour}, nil},
+		{[]string{}, nil, NothingToClean{}},
+		{[]string{"--dry-run"}, nil, NothingToClean{}},
+		{[]string{"--unused-for", "a month"}, nil, InvalidCacheAge("")},
+		{[]string{"--unused-for", "-3d"}, nil, InvalidCacheAge("")},
+		{[]string{"--unused-for"}, nil, ArgMissingValue("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := parseCleanCacheOptions(testCase.args)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, err, "For args %v", testCase.args) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(err), "For args %v", testCase.args)
+			}
+		} else {
+			assert.Nil(t, err, "For args %v, unexpected error: %v", testCase.args, err)
+			assert.Equal(t, testCase.expected, actual, "For args %v", testCase.args)
+		}
+	}
+}
+
+// Copy the clean-cache fixture into a temp folder, so modules can be deleted from it, and return the temp folder and a
+// shared download dir within it
+func copyCleanCacheFixture(t *testing.T) (string, string) {
+	tmpDir, err := ioutil.TempDir("", "clean-cache")
+	require.NoError(t, err)
+
+	require.NoError(t, util.CopyFolderContents("../test/fixture-clean-cache", tmpDir))
+
+	canonicalTmpDir, err := util.CanonicalPath(tmpDir, "")
+	require.NoError(t, err)
+
+	return canonicalTmpDir, util.JoinPath(canonicalTmpDir, "download")
+}
+
+func cacheTestOptions(t *testing.T, terragruntConfigPath string, downloadDir string) *options.TerragruntOptions {
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(terragruntConfigPath)
+	require.NoError(t, err)
+	terragruntOptions.DownloadDir = downloadDir
+	return terragruntOptions
+}
+
+// Record use of the cache entry of the fixture module with the given options, just like downloadTerraformSource does,
+// and return the path of the cache entry
+func recordFixtureCacheEntryUse(t *testing.T, terragruntOptions *options.TerragruntOptions) string {
+	terraformSource, err := processTerraformSource(CLEAN_CACHE_FIXTURE_SOURCE, terragruntOptions)
+	require.NoError(t, err)
+
+	recordCacheEntryUse(terragruntOptions, terraformSource.CacheDir)
+	require.True(t, util.IsDir(terraformSource.CacheDir))
+
+	return terraformSource.CacheDir
+}
+
+func cacheManifestPaths(t *testing.T, downloadDir string) []string {
+	manifest, err := readCacheManifest(downloadDir)
+	require.NoError(t, err)
+
+	paths := []string{}
+	for path := range manifest.Entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
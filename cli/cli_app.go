@@ -35,9 +35,32 @@ const OPT_TERRAGRUNT_IAM_ROLE = "terragrunt-iam-role"
 const OPT_TERRAGRUNT_IGNORE_DEPENDENCY_ERRORS = "terragrunt-ignore-dependency-errors"
 const OPT_TERRAGRUNT_EXCLUDE_DIR = "terragrunt-exclude-dir"
 const OPT_TERRAGRUNT_INCLUDE_DIR = "terragrunt-include-dir"
-
-var ALL_TERRAGRUNT_BOOLEAN_OPTS = []string{OPT_NON_INTERACTIVE, OPT_TERRAGRUNT_SOURCE_UPDATE, OPT_TERRAGRUNT_IGNORE_DEPENDENCY_ERRORS, OPT_TERRAGRUNT_NO_AUTO_INIT, OPT_TERRAGRUNT_NO_AUTO_RETRY}
-var ALL_TERRAGRUNT_STRING_OPTS = []string{OPT_TERRAGRUNT_CONFIG, OPT_TERRAGRUNT_TFPATH, OPT_WORKING_DIR, OPT_DOWNLOAD_DIR, OPT_TERRAGRUNT_SOURCE, OPT_TERRAGRUNT_IAM_ROLE, OPT_TERRAGRUNT_EXCLUDE_DIR, OPT_TERRAGRUNT_INCLUDE_DIR}
+const OPT_TERRAGRUNT_TRACK_CONFIG_CHANGES = "terragrunt-track-config-changes"
+const OPT_TERRAGRUNT_SHOW_CONFIG_DIFF = "terragrunt-show-config-diff"
+const OPT_TERRAGRUNT_JSON_OUT = "terragrunt-json-out"
+const OPT_TERRAGRUNT_STATUS_PORT = "terragrunt-status-port"
+const OPT_TERRAGRUNT_STATUS_BIND_ADDRESS = "terragrunt-status-bind-address"
+const OPT_TERRAGRUNT_CHECK_FOR_UPDATES = "terragrunt-check-for-updates"
+const OPT_TERRAGRUNT_VERSION_CHECK_URL = "terragrunt-version-check-url"
+const OPT_TERRAGRUNT_STAGGER = "terragrunt-stagger"
+const OPT_TERRAGRUNT_MAX_STARTS_PER_MINUTE = "terragrunt-max-starts-per-minute"
+const OPT_TERRAGRUNT_STRICT = "terragrunt-strict"
+const OPT_TERRAGRUNT_FAIL_ON_EMPTY = "terragrunt-fail-on-empty"
+const OPT_TERRAGRUNT_INCLUDE_SENSITIVE = "terragrunt-include-sensitive"
+const OPT_TERRAGRUNT_RESUME = "terragrunt-resume"
+const OPT_TERRAGRUNT_HTTP_TIMEOUT = "terragrunt-http-timeout"
+const OPT_TERRAGRUNT_SENSITIVE_VAR = "terragrunt-sensitive-var"
+const OPT_TERRAGRUNT_INCLUDE_DEPENDENCIES = "terragrunt-include-dependencies"
+const OPT_TERRAGRUNT_INCLUDE_DEPENDENCIES_DEPTH = "terragrunt-include-dependencies-depth"
+const OPT_TERRAGRUNT_LOG_HELPER_METRICS = "terragrunt-log-helper-metrics"
+const OPT_TERRAGRUNT_DISABLE_EXTRA_ARGS = "terragrunt-disable-extra-args"
+const OPT_TERRAGRUNT_DISABLE_HOOKS = "terragrunt-disable-hooks"
+
+var ALL_TERRAGRUNT_BOOLEAN_OPTS = []string{OPT_NON_INTERACTIVE, OPT_TERRAGRUNT_SOURCE_UPDATE, OPT_TERRAGRUNT_IGNORE_DEPENDENCY_ERRORS, OPT_TERRAGRUNT_NO_AUTO_INIT, OPT_TERRAGRUNT_NO_AUTO_RETRY, OPT_TERRAGRUNT_TRACK_CONFIG_CHANGES, OPT_TERRAGRUNT_SHOW_CONFIG_DIFF, OPT_TERRAGRUNT_CHECK_FOR_UPDATES, OPT_TERRAGRUNT_STRICT, OPT_TERRAGRUNT_FAIL_ON_EMPTY, OPT_TERRAGRUNT_INCLUDE_SENSITIVE, OPT_TERRAGRUNT_INCLUDE_DEPENDENCIES, OPT_TERRAGRUNT_LOG_HELPER_METRICS}
+var ALL_TERRAGRUNT_STRING_OPTS = []string{OPT_TERRAGRUNT_CONFIG, OPT_TERRAGRUNT_TFPATH, OPT_WORKING_DIR, OPT_DOWNLOAD_DIR, OPT_TERRAGRUNT_SOURCE, OPT_TERRAGRUNT_IAM_ROLE, OPT_TERRAGRUNT_EXCLUDE_DIR, OPT_TERRAGRUNT_INCLUDE_DIR, OPT_TERRAGRUNT_JSON_OUT, OPT_TERRAGRUNT_STATUS_PORT, OPT_TERRAGRUNT_STATUS_BIND_ADDRESS, OPT_TERRAGRUNT_VERSION_CHECK_URL, OPT_TERRAGRUNT_STAGGER, OPT_TERRAGRUNT_MAX_STARTS_PER_MINUTE, OPT_TERRAGRUNT_RESUME, OPT_TERRAGRUNT_HTTP_TIMEOUT, OPT_TERRAGRUNT_SENSITIVE_VAR, OPT_TERRAGRUNT_INCLUDE_DEPENDENCIES_DEPTH}
+
+// Flags that may be passed on their own, like a boolean flag, or followed by a comma separated list of names
+var ALL_TERRAGRUNT_OPTIONAL_LIST_OPTS = []string{OPT_TERRAGRUNT_DISABLE_EXTRA_ARGS, OPT_TERRAGRUNT_DISABLE_HOOKS}
 
 const CMD_PLAN_ALL = "plan-all"
 const CMD_APPLY_ALL = "apply-all"
@@ -48,6 +71,14 @@ const CMD_VALIDATE_ALL = "validate-all"
 const CMD_INIT = "init"
 const CMD_INIT_FROM_MODULE = "init-from-module"
 
+const CMD_TERRAGRUNT_INFO = "terragrunt-info"
+const CMD_GRAPH_DEPENDENCIES = "graph-dependencies"
+const CMD_PROVIDERS_REPORT = "providers-report"
+const CMD_RENDER_INPUTS = "render-inputs"
+const CMD_RENDER_INPUTS_ALL = "render-inputs-all"
+const CMD_CLEAN_CACHE = "clean-cache"
+const CMD_MIGRATE_CONFIG = "migrate-config"
+
 // CMD_SPIN_UP is deprecated.
 const CMD_SPIN_UP = "spin-up"
 
@@ -104,10 +135,17 @@ COMMANDS:
    output-all           Display the outputs of a 'stack' by running 'terragrunt output' in each subfolder
    destroy-all          Destroy a 'stack' by running 'terragrunt destroy' in each subfolder
    validate-all         Validate 'stack' by running 'terragrunt validate' in each subfolder
+   terragrunt-info      Print the paths and settings Terragrunt uses for the current module
+   graph-dependencies   Print the dependencies between the modules of the 'stack' in each subfolder
+   providers-report     Print the provider version constraints of the modules of the 'stack' in each subfolder, highlighting conflicts
+   render-inputs        Print the values of the variables Terraform would receive for the current module
+   render-inputs-all    Print the values of the variables Terraform would receive for each module of the 'stack' in each subfolder
+   clean-cache          Remove cache entries of deleted (--orphans) or unused (--unused-for 30d) modules from the download dir
+   migrate-config       Move the terragrunt block of terraform.tfvars to terragrunt.hcl (--config-name), for every module with --all
    *                    Terragrunt forwards all other commands directly to Terraform
 
 GLOBAL OPTIONS:
-   terragrunt-config                    Path to the Terragrunt config file. Default is terraform.tfvars.
+   terragrunt-config                    Path to the Terragrunt config file. Default is terragrunt.hcl if it exists, or terraform.tfvars otherwise.
    terragrunt-tfpath                    Path to the Terraform binary. Default is terraform (on PATH).
    terragrunt-no-auto-init              Don't automatically run 'terraform init' during other terragrunt commands. You must run 'terragrunt init' manually.
    terragrunt-no-auto-retry             Don't automatically re-run command in case of transient errors.
@@ -120,6 +158,26 @@ GLOBAL OPTIONS:
    terragrunt-ignore-dependency-errors  *-all commands continue processing components even if a dependency fails.
    terragrunt-exclude-dir               Unix-style glob of directories to exclude when running *-all commands
    terragrunt-include-dir               Unix-style glob of directories to include when running *-all commands
+   terragrunt-track-config-changes      Report which settings in the resolved config changed since the previous run.
+   terragrunt-show-config-diff          Print the full diff of the resolved config since the previous run. Implies terragrunt-track-config-changes.
+   terragrunt-json-out                  Write the output of terragrunt-info, graph-dependencies, providers-report, and render-inputs(-all) as JSON to the specified file, or to stdout if set to '-'.
+   terragrunt-status-port               Serve the status of *-all commands as JSON on this port while they run.
+   terragrunt-status-bind-address       The address the status server binds to. Default is 127.0.0.1.
+   terragrunt-check-for-updates         Check, at most once a day, whether a newer version of Terragrunt is available.
+   terragrunt-version-check-url         The URL to check for the latest Terragrunt release. Default is the GitHub releases API.
+   terragrunt-stagger                   The minimum time between starting two modules in *-all commands, e.g. 3s.
+   terragrunt-max-starts-per-minute     The maximum number of modules *-all commands start per minute.
+   terragrunt-strict                    Treat configuration that is almost always a mistake as an error instead of a warning.
+   terragrunt-fail-on-empty             Treat a built-in function call that returns an empty value as an error, unless it's wrapped in allow_empty.
+   terragrunt-include-sensitive         Show the values of variables that look like secrets in the output of render-inputs(-all).
+   terragrunt-resume                    Skip the modules that succeeded in a previous apply-all run and haven't changed since. Pass 'last' or the path of a resume state file.
+   terragrunt-http-timeout              How long helpers such as http_get_json wait for a response, e.g. 30s. Default is 10s.
+   terragrunt-sensitive-var             Regular expression for the names of variables to mask in the output of render-inputs(-all). May be repeated.
+   terragrunt-include-dependencies      Run plan or apply in the dependencies of the current module too, in dependency order.
+   terragrunt-include-dependencies-depth How many levels of dependencies terragrunt-include-dependencies runs plan in. Default is 1.
+   terragrunt-log-helper-metrics        Log how many times each built-in function was called while parsing configs, and how long the calls took.
+   terragrunt-disable-extra-args        Don't add the arguments, var files, or env vars of any extra_arguments block to the Terraform command, or only of the blocks of the comma separated names that follow, e.g. vars,retry.
+   terragrunt-disable-hooks             Don't run any before_hook or after_hook, or only the hooks of the comma separated names that follow.
 
 VERSION:
    {{.Version}}{{if len .Authors}}
@@ -182,6 +240,9 @@ func runApp(cliContext *cli.Context) (finalErr error) {
 		return err
 	}
 
+	updateNotice := startUpdateCheck(cliContext.App.Version, terragruntOptions)
+	defer printUpdateNotice(updateNotice, terragruntOptions)
+
 	if err := PopulateTerraformVersion(terragruntOptions); err != nil {
 		return err
 	}
@@ -211,6 +272,25 @@ func runCommand(command string, terragruntOptions *options.TerragruntOptions) (f
 	if isMultiModuleCommand(command) {
 		return runMultiModuleCommand(command, terragruntOptions)
 	}
+	if terragruntOptions.IncludeDependencies {
+		return runWithDependencies(terragruntOptions)
+	}
+	switch command {
+	case CMD_TERRAGRUNT_INFO:
+		return printTerragruntInfo(terragruntOptions)
+	case CMD_GRAPH_DEPENDENCIES:
+		return printDependencyGraph(terragruntOptions)
+	case CMD_PROVIDERS_REPORT:
+		return printProvidersReport(terragruntOptions)
+	case CMD_RENDER_INPUTS:
+		return printRenderedInputs(terragruntOptions)
+	case CMD_RENDER_INPUTS_ALL:
+		return printRenderedInputsAll(terragruntOptions)
+	case CMD_CLEAN_CACHE:
+		return cleanCache(terragruntOptions)
+	case CMD_MIGRATE_CONFIG:
+		return migrateConfig(terragruntOptions)
+	}
 	return runTerragrunt(terragruntOptions)
 }
 
@@ -223,7 +303,7 @@ func runTerragrunt(terragruntOptions *options.TerragruntOptions) error {
 
 	terragruntConfig, err := config.ReadTerragruntConfig(terragruntOptions)
 	if err != nil {
-		return err
+		return errors.WithClass(err, errors.ErrorClassConfig)
 	}
 
 	if terragruntOptions.IamRole == "" {
@@ -234,7 +314,20 @@ func runTerragrunt(terragruntOptions *options.TerragruntOptions) error {
 		return err
 	}
 
-	if sourceUrl := getTerraformSourceUrl(terragruntOptions, terragruntConfig); sourceUrl != "" {
+	// Only create the download dir if we are going to write to it, so that nothing is written next to the Terragrunt
+	// config unless necessary
+	sourceUrl := getTerraformSourceUrl(terragruntOptions, terragruntConfig)
+	if sourceUrl != "" || terragruntOptions.TrackConfigChanges {
+		if err := ensureDownloadDirIsWritable(terragruntOptions); err != nil {
+			return err
+		}
+	}
+
+	if terragruntOptions.TrackConfigChanges {
+		defer reportConfigChanges(terragruntOptions, terragruntConfig)()
+	}
+
+	if sourceUrl != "" {
 		if err := downloadTerraformSource(sourceUrl, terragruntOptions, terragruntConfig); err != nil {
 			return err
 		}
@@ -271,13 +364,32 @@ func processHooks(hooks []config.Hook, terragruntOptions *options.TerragruntOpti
 
 	terragruntOptions.Logger.Printf("Detected %d Hooks", len(hooks))
 
+	// Hooks write to stderr, even if they run terraform themselves, so stdout only has the output of the Terraform
+	// command, such as the JSON of output -json. Only the writer differs, so a shallow copy is enough.
+	hookOptions := *terragruntOptions
+	hookOptions.Writer = terragruntOptions.ErrWriter
+
 	for _, curHook := range hooks {
+		if terragruntOptions.IsHookDisabled(curHook.Name) {
+			if util.ListContainsElement(curHook.Commands, terragruntOptions.TerraformCommand) {
+				terragruntOptions.Logger.Printf("Skipping hook %s as it is disabled", curHook.Name)
+			}
+			continue
+		}
+
+		if !curHook.WhenIsTrue() {
+			if util.ListContainsElement(curHook.Commands, terragruntOptions.TerraformCommand) {
+				terragruntOptions.Logger.Printf("Skipping hook %s as its when condition is false", curHook.Name)
+			}
+			continue
+		}
+
 		allPreviousErrors := append(previousExecError, errorsOccurred...)
 		if shouldRunHook(curHook, terragruntOptions, allPreviousErrors...) {
 			terragruntOptions.Logger.Printf("Executing hook: %s", curHook.Name)
 			actionToExecute := curHook.Execute[0]
 			actionParams := curHook.Execute[1:]
-			possibleError := shell.RunShellCommand(terragruntOptions, actionToExecute, actionParams...)
+			possibleError := shell.RunShellCommand(&hookOptions, actionToExecute, actionParams...)
 
 			if possibleError != nil {
 				terragruntOptions.Logger.Printf("Error running hook %s with message: %s", curHook.Name, possibleError.Error())
@@ -327,11 +439,20 @@ func assumeRoleIfNecessary(terragruntOptions *options.TerragruntOptions) error {
 // Runs terraform with the given options and CLI args.
 // This will forward all the args and extra_arguments directly to Terraform.
 func runTerragruntWithConfig(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig, allowSourceDownload bool) error {
+	if err := setModuleEnvVars(terragruntOptions); err != nil {
+		return err
+	}
 
 	// Add extra_arguments to the command
 	if terragruntConfig.Terraform != nil && terragruntConfig.Terraform.ExtraArgs != nil && len(terragruntConfig.Terraform.ExtraArgs) > 0 {
-		terragruntOptions.InsertTerraformCliArgs(filterTerraformExtraArgs(terragruntOptions, terragruntConfig)...)
-		for k, v := range filterTerraformEnvVarsFromExtraArgs(terragruntOptions, terragruntConfig) {
+		for _, name := range config.DisabledExtraArgsForCommand(terragruntOptions, terragruntConfig) {
+			terragruntOptions.Logger.Printf("Skipping extra_arguments %s as it is disabled", name)
+		}
+		for _, name := range config.ExtraArgsSkippedByWhenForCommand(terragruntOptions, terragruntConfig) {
+			terragruntOptions.Logger.Printf("Skipping extra_arguments %s as its when condition is false", name)
+		}
+		terragruntOptions.InsertTerraformCliArgs(config.TerraformExtraArgsForCommand(terragruntOptions, terragruntConfig)...)
+		for k, v := range config.TerraformEnvVarsForCommand(terragruntOptions, terragruntConfig) {
 			terragruntOptions.Env[k] = v
 		}
 	}
@@ -350,6 +471,12 @@ func runTerragruntWithConfig(terragruntOptions *options.TerragruntOptions, terra
 		return err
 	}
 
+	if terragruntConfig.RemoteState != nil {
+		if err := terragruntConfig.RemoteState.BackupBeforeCommand(util.FirstArg(terragruntOptions.TerraformCliArgs), terragruntOptions); err != nil {
+			return errors.WithClass(err, errors.ErrorClassRemoteState)
+		}
+	}
+
 	beforeHookErrors := processHooks(terragruntConfig.Terraform.GetBeforeHooks(), terragruntOptions)
 	terraformError := runTerraformCommandIfNoErrors(beforeHookErrors, terragruntOptions)
 	postHookErrors := processHooks(terragruntConfig.Terraform.GetAfterHooks(), terragruntOptions, beforeHookErrors, terraformError)
@@ -422,11 +549,11 @@ func prepareInitCommand(terragruntOptions *options.TerragruntOptions, terragrunt
 		// Initialize the remote state if necessary  (e.g. create S3 bucket and DynamoDB table)
 		remoteStateNeedsInit, err := remoteStateNeedsInit(terragruntConfig.RemoteState, terragruntOptions)
 		if err != nil {
-			return err
+			return errors.WithClass(err, errors.ErrorClassRemoteState)
 		}
 		if remoteStateNeedsInit {
 			if err := terragruntConfig.RemoteState.Initialize(terragruntOptions); err != nil {
-				return err
+				return errors.WithClass(err, errors.ErrorClassRemoteState)
 			}
 		}
 
@@ -689,7 +816,7 @@ func applyAll(terragruntOptions *options.TerragruntOptions) error {
 	}
 
 	terragruntOptions.Logger.Printf("%s", stack.String())
-	shouldApplyAll, err := shell.PromptUserForYesNo("Are you sure you want to run 'terragrunt apply' in each folder of the stack described above?", terragruntOptions)
+	shouldApplyAll, err := shell.ConfirmOrDryRun("Are you sure you want to run 'terragrunt apply' in each folder of the stack described above?", nil, false, terragruntOptions)
 	if err != nil {
 		return err
 	}
@@ -710,7 +837,7 @@ func destroyAll(terragruntOptions *options.TerragruntOptions) error {
 	}
 
 	terragruntOptions.Logger.Printf("%s", stack.String())
-	shouldDestroyAll, err := shell.PromptUserForYesNo("WARNING: Are you sure you want to run `terragrunt destroy` in each folder of the stack described above? There is no undo!", terragruntOptions)
+	shouldDestroyAll, err := shell.ConfirmOrDryRun("WARNING: Are you sure you want to run `terragrunt destroy` in each folder of the stack described above? There is no undo!", nil, false, terragruntOptions)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessHooksSkipsDisabledHooks(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		disableHooks  bool
+		disabledHooks []string
+		expectedRun   []string
+	}{
+		{false, []string{}, []string{"fmt", "lint"}},
+		{false, []string{"fmt"}, []string{"lint"}},
+		{false, []string{"fmt", "lint"}, []string{}},
+		{false, []string{"no-such-hook"}, []string{"fmt", "lint"}},
+		{true, []string{}, []string{}},
+	}
+
+	for _, testCase := range testCases {
+		tmpDir, err := ioutil.TempDir("", "terragrunt-disabled-hooks-test")
+		require.NoError(t, err)
+		defer os.RemoveAll(tmpDir)
+
+		terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath))
+		require.NoError(t, err)
+		terragruntOptions.TerraformCommand = "apply"
+		terragruntOptions.DisableHooks = testCase.disableHooks
+		terragruntOptions.DisabledHooks = testCase.disabledHooks
+		terragruntOptions.Writer = &bytes.Buffer{}
+		terragruntOptions.ErrWriter = &bytes.Buffer{}
+
+		hooks := []config.Hook{
+			{Name: "fmt", Commands: []string{"apply"}, Execute: []string{"touch", filepath.Join(tmpDir, "fmt")}},
+			{Name: "lint", Commands: []string{"apply"}, Execute: []string{"touch", filepath.Join(tmpDir, "lint")}},
+		}
+		require.NoError(t, processHooks(hooks, terragruntOptions))
+
+		for _, hook := range hooks {
+			ran := util.FileExists(filepath.Join(tmpDir, hook.Name))
+			assert.Equal(t, util.ListContainsElement(testCase.expectedRun, hook.Name), ran, "Hook %s with disabled hooks %v", hook.Name, testCase.disabledHooks)
+		}
+	}
+}
+
+func TestProcessHooksSkipsHooksWithFalseWhen(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "terragrunt-when-hooks-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.TerraformCommand = "apply"
+	terragruntOptions.Writer = &bytes.Buffer{}
+	terragruntOptions.ErrWriter = &bytes.Buffer{}
+
+	hooks := []config.Hook{
+		{Name: "always", Commands: []string{"apply"}, Execute: []string{"touch", filepath.Join(tmpDir, "always")}},
+		{Name: "ci", Commands: []string{"apply"}, Execute: []string{"touch", filepath.Join(tmpDir, "ci")}, When: true},
+		{Name: "not-ci", Commands: []string{"apply"}, Execute: []string{"touch", filepath.Join(tmpDir, "not-ci")}, When: false},
+	}
+	require.NoError(t, processHooks(hooks, terragruntOptions))
+
+	assert.True(t, util.FileExists(filepath.Join(tmpDir, "always")))
+	assert.True(t, util.FileExists(filepath.Join(tmpDir, "ci")))
+	assert.False(t, util.FileExists(filepath.Join(tmpDir, "not-ci")))
+}
+
+func TestProcessHooksWritesToStderr(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(config.DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+	terragruntOptions.TerraformPath = "echo"
+	terragruntOptions.TerraformCommand = "output"
+	terragruntOptions.TerraformCliArgs = []string{"output", "-json"}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	terragruntOptions.Writer = stdout
+	terragruntOptions.ErrWriter = stderr
+
+	// A hook that runs terraform itself, with the same args, doesn't add to the output of the Terraform command either
+	hooks := []config.Hook{
+		{Name: "echo", Commands: []string{"output"}, Execute: []string{"echo", "hook output"}},
+		{Name: "terraform", Commands: []string{"output"}, Execute: append([]string{terragruntOptions.TerraformPath}, terragruntOptions.TerraformCliArgs...)},
+	}
+
+	require.NoError(t, processHooks(hooks, terragruntOptions))
+	assert.Empty(t, stdout.String())
+	assert.Equal(t, "hook output\noutput -json\n", stderr.String())
+}
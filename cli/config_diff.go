@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The folder, within the download dir, where config snapshots are stored
+const CONFIG_SNAPSHOTS_DIR = "config-snapshots"
+
+// Compare the given resolved config to the snapshot stored by the previous run and log which settings changed. If
+// --terragrunt-show-config-diff is set, log the full diff as well. Returns a function that stores a snapshot of the
+// given config for the next run to compare against; callers should invoke it once the run has finished. Problems with
+// reading or writing snapshots are logged, but never fail the run.
+func reportConfigChanges(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) func() {
+	noop := func() {}
+
+	snapshotPath, err := configSnapshotPath(terragruntOptions)
+	if err != nil {
+		terragruntOptions.Logger.Printf("WARNING: Unable to determine where to store config snapshot: %v", err)
+		return noop
+	}
+
+	newSnapshot, err := config.NewConfigSnapshot(terragruntConfig, terragruntOptions.SensitiveVarPatterns)
+	if err != nil {
+		terragruntOptions.Logger.Printf("WARNING: Unable to create config snapshot: %v", err)
+		return noop
+	}
+
+	oldSnapshot, err := config.ReadConfigSnapshot(snapshotPath)
+	if err != nil {
+		terragruntOptions.Logger.Printf("WARNING: Ignoring previous config snapshot: %v", err)
+	}
+
+	if oldSnapshot != nil {
+		changes := config.DiffConfigSnapshots(oldSnapshot, newSnapshot)
+		if summary := config.SummarizeConfigChanges(changes, oldSnapshot, newSnapshot); summary != "" {
+			terragruntOptions.Logger.Printf("Config changed since last run: %s", summary)
+			if terragruntOptions.ShowConfigDiff {
+				terragruntOptions.Logger.Printf("Config diff since last run:\n%s", formatConfigChanges(changes))
+			}
+		}
+	}
+
+	return func() {
+		if err := config.WriteConfigSnapshot(newSnapshot, snapshotPath); err != nil {
+			terragruntOptions.Logger.Printf("WARNING: Unable to store config snapshot at %s: %v", snapshotPath, err)
+		}
+	}
+}
+
+// Return the path where the config snapshot for the current module is stored. The download dir may be shared by
+// multiple modules, so the file name is derived from the canonical path of the Terragrunt config file.
+func configSnapshotPath(terragruntOptions *options.TerragruntOptions) (string, error) {
+	canonicalConfigPath, err := util.CanonicalPath(terragruntOptions.TerragruntConfigPath, "")
+	if err != nil {
+		return "", err
+	}
+
+	fileName := fmt.Sprintf("%s.json", util.EncodeBase64Sha1(canonicalConfigPath))
+	return util.JoinPath(filepath.ToSlash(terragruntOptions.DownloadDir), CONFIG_SNAPSHOTS_DIR, fileName), nil
+}
+
+func formatConfigChanges(changes []config.ConfigChange) string {
+	out := ""
+	for _, change := range changes {
+		out += fmt.Sprintf("  %s\n", change.String())
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportConfigChangesRedactsSensitiveVars(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "terragrunt-config-diff-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.DownloadDir = util.JoinPath(tmpDir, options.TerragruntCacheDir)
+	terragruntOptions.ShowConfigDiff = true
+	terragruntOptions.SensitiveVarPatterns = []*regexp.Regexp{regexp.MustCompile(`^license_key$`)}
+
+	var logs bytes.Buffer
+	terragruntOptions.Logger = util.CreateLoggerWithWriter(&logs, "")
+
+	configWithVars := func(licenseKey string, dbPassword string, name string) *config.TerragruntConfig {
+		return &config.TerragruntConfig{
+			Terraform: &config.TerraformConfig{
+				ExtraArgs: []config.TerraformExtraArguments{
+					{
+						Name:      "vars",
+						Arguments: []string{"-var", "license_key=" + licenseKey, "-var=db_password=" + dbPassword, "-var", "name=" + name},
+						EnvVars:   map[string]string{"TF_VAR_license_key": licenseKey},
+					},
+				},
+			},
+		}
+	}
+
+	reportConfigChanges(terragruntOptions, configWithVars("old-license", "old-password", "app"))()
+	reportConfigChanges(terragruntOptions, configWithVars("new-license", "new-password", "app2"))()
+
+	output := logs.String()
+	assert.Contains(t, output, `"license_key=<redacted>"`)
+	assert.Contains(t, output, `"-var=db_password=<redacted>"`)
+	assert.Contains(t, output, `"name=app2"`)
+	for _, secret := range []string{"old-license", "new-license", "old-password", "new-password"} {
+		assert.NotContains(t, output, secret)
+	}
+
+	snapshotPath, err := configSnapshotPath(terragruntOptions)
+	require.NoError(t, err)
+	snapshot, err := ioutil.ReadFile(snapshotPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(snapshot), "new-license")
+	assert.NotContains(t, string(snapshot), "new-password")
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The file, in the download dir of a module, that records which files were copied into it from the module's own
+// folder, and for which Terragrunt config, so files left over from a previous run can be removed before the next one
+const COPIED_FILES_FILE = ".terragrunt-copied-files.json"
+
+type copiedFilesRecord struct {
+	// Identifies the Terragrunt config the files were copied for (see configSignature)
+	ConfigSignature string `json:"config_signature"`
+
+	// The paths of the copied files, relative to the download dir, with forward slashes
+	Files []string `json:"files"`
+}
+
+// Copy the files in the module's own folder, the working dir in the given options, into the working dir of the given
+// TerraformSource, and record which files were copied, so removeStaleCopiedFiles can clean them up in later runs
+func copyModuleFiles(terraformSource *TerraformSource, terragruntOptions *options.TerragruntOptions) error {
+	files, err := copiedFilePaths(terraformSource, terragruntOptions.WorkingDir)
+	if err != nil {
+		return err
+	}
+
+	terragruntOptions.Logger.Printf("Copying files from %s into %s", terragruntOptions.WorkingDir, terraformSource.WorkingDir)
+	if err := util.CopyFolderContents(terragruntOptions.WorkingDir, terraformSource.WorkingDir); err != nil {
+		return err
+	}
+
+	signature, err := configSignature(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	return writeJsonFile(terraformSource.CopiedFilesFile, copiedFilesRecord{ConfigSignature: signature, Files: files})
+}
+
+// Remove the files a previous run copied into the download dir of the given TerraformSource that the next copy won't
+// overwrite: all of them if they were copied for a different Terragrunt config, and otherwise those that are no longer
+// in the module's own folder. As those files may have overwritten files of the source itself, the source is then
+// downloaded again. If the record of the copied files can't be read, the whole download dir is removed instead.
+func removeStaleCopiedFiles(terraformSource *TerraformSource, terragruntOptions *options.TerragruntOptions) error {
+	if !util.FileExists(terraformSource.CopiedFilesFile) {
+		return nil
+	}
+
+	record, err := readCopiedFilesRecord(terraformSource.CopiedFilesFile)
+	if err != nil {
+		terragruntOptions.Logger.Printf("WARNING: Unable to read the record of copied files in %s, so deleting the folder to start from a clean copy: %v", terraformSource.DownloadDir, err)
+		return errors.WithStackTrace(os.RemoveAll(terraformSource.DownloadDir))
+	}
+
+	signature, err := configSignature(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	moduleFiles, err := copiedFilePaths(terraformSource, terragruntOptions.WorkingDir)
+	if err != nil {
+		return err
+	}
+
+	staleFiles := []string{}
+	for _, file := range record.Files {
+		if record.ConfigSignature != signature || !util.ListContainsElement(moduleFiles, file) {
+			staleFiles = append(staleFiles, file)
+		}
+	}
+	if len(staleFiles) == 0 {
+		return nil
+	}
+
+	terragruntOptions.Logger.Printf("Removing %d files copied into %s by a previous run that are no longer in %s, and downloading the source again", len(staleFiles), terraformSource.DownloadDir, terragruntOptions.WorkingDir)
+	for _, file := range append(staleFiles, COPIED_FILES_FILE) {
+		if err := os.Remove(util.JoinPath(terraformSource.DownloadDir, file)); err != nil && !os.IsNotExist(err) {
+			return errors.WithStackTrace(err)
+		}
+	}
+
+	if err := os.Remove(terraformSource.VersionFile); err != nil && !os.IsNotExist(err) {
+		return errors.WithStackTrace(err)
+	}
+	return nil
+}
+
+// Return the paths, relative to the download dir of the given TerraformSource, that the files in the given module
+// folder are copied to. Like util.CopyFolderContents, this skips hidden files and folders.
+func copiedFilePaths(terraformSource *TerraformSource, moduleDir string) ([]string, error) {
+	modulePath, err := util.GetPathRelativeTo(terraformSource.WorkingDir, terraformSource.DownloadDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	err = filepath.Walk(moduleDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == moduleDir {
+			return nil
+		}
+		if util.PathContainsHiddenFileOrFolder(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(moduleDir, filePath)
+		if err != nil {
+			return err
+		}
+		files = append(files, path.Join(filepath.ToSlash(modulePath), filepath.ToSlash(relativePath)))
+		return nil
+	})
+
+	return files, errors.WithStackTrace(err)
+}
+
+func readCopiedFilesRecord(recordPath string) (*copiedFilesRecord, error) {
+	contents, err := ioutil.ReadFile(recordPath)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	record := &copiedFilesRecord{}
+	if err := json.Unmarshal(contents, record); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return record, nil
+}
+
+// Identify the Terragrunt config in the given options, so files copied for one config are never mistaken for files
+// copied for another
+func configSignature(terragruntOptions *options.TerragruntOptions) (string, error) {
+	canonicalConfigPath, err := util.CanonicalPath(terragruntOptions.TerragruntConfigPath, "")
+	if err != nil {
+		return "", err
+	}
+	return util.EncodeBase64Sha1(canonicalConfigPath), nil
+}
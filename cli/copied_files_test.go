@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Two modules that share a source, but have different files in their own folders
+const COPIED_FILES_FIXTURE = "../test/fixture-copied-files"
+
+func TestCopyModuleFilesTwoModulesSharingSource(t *testing.T) {
+	t.Parallel()
+
+	fixtureDir := copyCopiedFilesFixture(t)
+	downloadDir := tmpDir(t)
+	defer os.RemoveAll(downloadDir)
+
+	sourceA := runCopyModuleFiles(t, util.JoinPath(fixtureDir, "live/a", config.DefaultTerragruntConfigPath), downloadDir)
+	sourceB := runCopyModuleFiles(t, util.JoinPath(fixtureDir, "live/b", config.DefaultTerragruntConfigPath), downloadDir)
+
+	assert.NotEqual(t, sourceA.CacheDir, sourceB.CacheDir)
+	assertFilesInDir(t, sourceA.WorkingDir, []string{"a.tf", "backend.tf", "main.tf", "terraform.tfvars"})
+	assertFilesInDir(t, sourceB.WorkingDir, []string{"b.tf", "main.tf", "terraform.tfvars"})
+
+	// Running them again, in the other order, doesn't change anything
+	sourceB = runCopyModuleFiles(t, util.JoinPath(fixtureDir, "live/b", config.DefaultTerragruntConfigPath), downloadDir)
+	sourceA = runCopyModuleFiles(t, util.JoinPath(fixtureDir, "live/a", config.DefaultTerragruntConfigPath), downloadDir)
+
+	assertFilesInDir(t, sourceA.WorkingDir, []string{"a.tf", "backend.tf", "main.tf", "terraform.tfvars"})
+	assertFilesInDir(t, sourceB.WorkingDir, []string{"b.tf", "main.tf", "terraform.tfvars"})
+}
+
+func TestProcessTerraformSourceDifferentConfigsInSameFolder(t *testing.T) {
+	t.Parallel()
+
+	moduleDir := absPath(t, COPIED_FILES_FIXTURE+"/live/a")
+
+	var cacheDirs []string
+	for _, configPath := range []string{config.DefaultTerragruntConfigPath, "other.tfvars"} {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(moduleDir, configPath))
+		require.NoError(t, err)
+
+		terraformSource, err := processTerraformSource("../../modules//app", terragruntOptions)
+		require.NoError(t, err)
+		cacheDirs = append(cacheDirs, terraformSource.CacheDir)
+	}
+
+	assert.NotEqual(t, cacheDirs[0], cacheDirs[1])
+}
+
+func TestRemoveStaleCopiedFilesRemovedFromModule(t *testing.T) {
+	t.Parallel()
+
+	fixtureDir := copyCopiedFilesFixture(t)
+	downloadDir := tmpDir(t)
+	defer os.RemoveAll(downloadDir)
+
+	configPath := util.JoinPath(fixtureDir, "live/a", config.DefaultTerragruntConfigPath)
+	runCopyModuleFiles(t, configPath, downloadDir)
+
+	require.NoError(t, os.Remove(util.JoinPath(fixtureDir, "live/a/a.tf")))
+
+	terraformSource := runCopyModuleFiles(t, configPath, downloadDir)
+	assertFilesInDir(t, terraformSource.WorkingDir, []string{"backend.tf", "main.tf", "terraform.tfvars"})
+}
+
+func TestRemoveStaleCopiedFilesDifferentConfig(t *testing.T) {
+	t.Parallel()
+
+	fixtureDir := copyCopiedFilesFixture(t)
+	downloadDir := tmpDir(t)
+	defer os.RemoveAll(downloadDir)
+
+	terraformSource, terragruntOptions := copiedFilesTerraformSource(t, util.JoinPath(fixtureDir, "live/b", config.DefaultTerragruntConfigPath), downloadDir)
+	simulateDownload(t, terraformSource)
+
+	// Files copied for another config, even one that is still in the module's folder, are all removed, along with the
+	// version file, so the source is downloaded again
+	copyFolder(t, util.JoinPath(fixtureDir, "live/a"), terraformSource.WorkingDir)
+	record := copiedFilesRecord{ConfigSignature: "some-other-config", Files: []string{"app/a.tf", "app/backend.tf", "app/terraform.tfvars"}}
+	require.NoError(t, writeJsonFile(terraformSource.CopiedFilesFile, record))
+
+	require.NoError(t, removeStaleCopiedFiles(terraformSource, terragruntOptions))
+	assertFilesInDir(t, terraformSource.WorkingDir, []string{"main.tf"})
+	assert.False(t, util.FileExists(terraformSource.VersionFile))
+	assert.False(t, util.FileExists(terraformSource.CopiedFilesFile))
+}
+
+func TestRemoveStaleCopiedFilesNothingStale(t *testing.T) {
+	t.Parallel()
+
+	fixtureDir := copyCopiedFilesFixture(t)
+	downloadDir := tmpDir(t)
+	defer os.RemoveAll(downloadDir)
+
+	configPath := util.JoinPath(fixtureDir, "live/a", config.DefaultTerragruntConfigPath)
+	runCopyModuleFiles(t, configPath, downloadDir)
+
+	terraformSource, terragruntOptions := copiedFilesTerraformSource(t, configPath, downloadDir)
+	require.NoError(t, removeStaleCopiedFiles(terraformSource, terragruntOptions))
+
+	// Nothing is removed, so the source doesn't need to be downloaded again
+	assertFilesInDir(t, terraformSource.WorkingDir, []string{"a.tf", "backend.tf", "main.tf", "terraform.tfvars"})
+	assert.True(t, util.FileExists(terraformSource.VersionFile))
+}
+
+func TestRemoveStaleCopiedFilesCorruptRecord(t *testing.T) {
+	t.Parallel()
+
+	fixtureDir := copyCopiedFilesFixture(t)
+	downloadDir := tmpDir(t)
+	defer os.RemoveAll(downloadDir)
+
+	terraformSource, terragruntOptions := copiedFilesTerraformSource(t, util.JoinPath(fixtureDir, "live/a", config.DefaultTerragruntConfigPath), downloadDir)
+	simulateDownload(t, terraformSource)
+	require.NoError(t, ioutil.WriteFile(terraformSource.CopiedFilesFile, []byte("not json"), 0600))
+
+	require.NoError(t, removeStaleCopiedFiles(terraformSource, terragruntOptions))
+	assert.False(t, util.FileExists(terraformSource.DownloadDir))
+}
+
+func TestOptionalVarFilesInDownloadedSource(t *testing.T) {
+	t.Parallel()
+
+	fixtureDir := copyCopiedFilesFixture(t)
+	moduleDir := util.JoinPath(fixtureDir, "live/a")
+	require.NoError(t, ioutil.WriteFile(util.JoinPath(moduleDir, "env.tfvars"), []byte("env = \"stage\"\n"), 0644))
+
+	downloadDir := tmpDir(t)
+	defer os.RemoveAll(downloadDir)
+
+	terraformSource := runCopyModuleFiles(t, util.JoinPath(moduleDir, config.DefaultTerragruntConfigPath), downloadDir)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(moduleDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{"plan"}
+	// Terraform runs in the download dir, as it does after downloadTerraformSource, so that's what relative var files
+	// are resolved against, even though the process itself never changes dir
+	terragruntOptions.WorkingDir = terraformSource.WorkingDir
+
+	terragruntConfig := &config.TerragruntConfig{
+		Terraform: &config.TerraformConfig{
+			ExtraArgs: []config.TerraformExtraArguments{
+				{Name: "vars", Commands: []string{"plan"}, OptionalVarFiles: []string{"env.tfvars", "missing.tfvars"}},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"-var-file=env.tfvars"}, config.TerraformExtraArgsForCommand(terragruntOptions, terragruntConfig))
+
+	env, err := moduleEnvVars(terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.ToSlash(moduleDir), env[ENV_TERRAGRUNT_ORIGINAL_WORKING_DIR])
+}
+
+// Copy the fixture into a temp folder, so tests can change it
+func copyCopiedFilesFixture(t *testing.T) string {
+	fixtureDir := tmpDir(t)
+	copyFolder(t, COPIED_FILES_FIXTURE, fixtureDir)
+	return fixtureDir
+}
+
+func copiedFilesTerraformSource(t *testing.T, configPath string, downloadDir string) (*TerraformSource, *options.TerragruntOptions) {
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(configPath)
+	require.NoError(t, err)
+	terragruntOptions.DownloadDir = downloadDir
+
+	terraformSource, err := processTerraformSource("../../modules//app", terragruntOptions)
+	require.NoError(t, err)
+
+	return terraformSource, terragruntOptions
+}
+
+// Put the source module into the download dir, as terraform init would, unless it's already there
+func simulateDownload(t *testing.T, terraformSource *TerraformSource) {
+	if util.FileExists(terraformSource.VersionFile) {
+		return
+	}
+	require.NoError(t, os.MkdirAll(terraformSource.DownloadDir, 0700))
+	copyFolder(t, terraformSource.CanonicalSourceURL.Path, terraformSource.DownloadDir)
+	require.NoError(t, writeVersionFile(terraformSource))
+}
+
+// Do what downloadTerraformSource does for the module with the given config, without running terraform init
+func runCopyModuleFiles(t *testing.T, configPath string, downloadDir string) *TerraformSource {
+	terraformSource, terragruntOptions := copiedFilesTerraformSource(t, configPath, downloadDir)
+
+	require.NoError(t, removeStaleCopiedFiles(terraformSource, terragruntOptions))
+	simulateDownload(t, terraformSource)
+	require.NoError(t, copyModuleFiles(terraformSource, terragruntOptions))
+
+	return terraformSource
+}
+
+func assertFilesInDir(t *testing.T, dir string, expected []string) {
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+
+	actual := []string{}
+	for _, file := range files {
+		actual = append(actual, file.Name())
+	}
+	assert.Equal(t, expected, actual, "For dir %s", dir)
+}
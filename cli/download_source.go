@@ -23,6 +23,10 @@ type TerraformSource struct {
 	// A canonical version of RawSource, in URL format
 	CanonicalSourceURL *url.URL
 
+	// The folder, within the download dir, that holds all the code downloaded for the current module. This is the
+	// unit tracked by the cache manifest (see cache_manifest.go).
+	CacheDir string
+
 	// The folder where we should download the source to
 	DownloadDir string
 
@@ -31,10 +35,14 @@ type TerraformSource struct {
 
 	// The path to a file in DownloadDir that stores the version number of the code
 	VersionFile string
+
+	// The path to a file in DownloadDir that records which files were copied into it from the module's own folder (see
+	// copied_files.go)
+	CopiedFilesFile string
 }
 
 func (src *TerraformSource) String() string {
-	return fmt.Sprintf("TerraformSource{CanonicalSourceURL = %v, DownloadDir = %v, WorkingDir = %v, VersionFile = %v}", src.CanonicalSourceURL, src.DownloadDir, src.WorkingDir, src.VersionFile)
+	return fmt.Sprintf("TerraformSource{CanonicalSourceURL = %v, CacheDir = %v, DownloadDir = %v, WorkingDir = %v, VersionFile = %v, CopiedFilesFile = %v}", src.CanonicalSourceURL, src.CacheDir, src.DownloadDir, src.WorkingDir, src.VersionFile, src.CopiedFilesFile)
 }
 
 var forcedRegexp = regexp.MustCompile(`^([A-Za-z0-9]+)::(.+)$`)
@@ -51,12 +59,17 @@ func downloadTerraformSource(source string, terragruntOptions *options.Terragrun
 		return err
 	}
 
+	recordCacheEntryUse(terragruntOptions, terraformSource.CacheDir)
+
+	if err := removeStaleCopiedFiles(terraformSource, terragruntOptions); err != nil {
+		return err
+	}
+
 	if err := downloadTerraformSourceIfNecessary(terraformSource, terragruntOptions, terragruntConfig); err != nil {
 		return err
 	}
 
-	terragruntOptions.Logger.Printf("Copying files from %s into %s", terragruntOptions.WorkingDir, terraformSource.WorkingDir)
-	if err := util.CopyFolderContents(terragruntOptions.WorkingDir, terraformSource.WorkingDir); err != nil {
+	if err := copyModuleFiles(terraformSource, terragruntOptions); err != nil {
 		return err
 	}
 
@@ -152,16 +165,17 @@ func writeVersionFile(terraformSource *TerraformSource) error {
 // Otherwise, for every Terragrunt command, you'd have to wait for Terragrunt to download your Terraform code, download
 // that code's dependencies (terraform get), and configure remote state (terraform remote config), which is very slow.
 //
-// To maximize reuse, given a working directory w and a source URL s, we download code from S into the folder /T/W/H
-// where:
+// To maximize reuse, given a working directory w, a Terragrunt config path c, and a source URL s, we download code from
+// S into the folder /T/W/H where:
 //
 // 1. S is the part of s before the double-slash (//). This typically represents the root of the repo (e.g.
 //    github.com/foo/infrastructure-modules). We download the entire repo so that relative paths to other files in that
 //    repo resolve correctly. If no double-slash is specified, all of s is used.
 // 1. T is the OS temp dir (e.g. /tmp).
-// 2. W is the base 64 encoded sha1 hash of w. This ensures that if you are running Terragrunt concurrently in
+// 2. W is the base 64 encoded sha1 hash of w and c. This ensures that if you are running Terragrunt concurrently in
 //    multiple folders (e.g. during automated tests), then even if those folders are using the same source URL s, they
-//    do not overwrite each other.
+//    do not overwrite each other. Including c means two configs in the same folder (e.g. via --terragrunt-config)
+//    don't share a folder either, as the files copied from their folder (see copied_files.go) may differ.
 // 3. H is the base 64 encoded sha1 of S without its query string. For remote source URLs (e.g. Git
 //    URLs), this is based on the assumption that the scheme/host/path of the URL (e.g. git::github.com/foo/bar)
 //    identifies the repo, and we always want to download the same repo into the same folder (see the encodeSourceName
@@ -206,16 +220,25 @@ func processTerraformSource(source string, terragruntOptions *options.Terragrunt
 		return nil, err
 	}
 
-	encodedWorkingDir := util.EncodeBase64Sha1(canonicalWorkingDir)
-	downloadDir := util.JoinPath(terragruntOptions.DownloadDir, encodedWorkingDir, rootPath)
+	canonicalConfigPath, err := util.CanonicalPath(terragruntOptions.TerragruntConfigPath, "")
+	if err != nil {
+		return nil, err
+	}
+
+	encodedWorkingDir := util.EncodeBase64Sha1(canonicalWorkingDir + "|" + canonicalConfigPath)
+	cacheDir := util.JoinPath(terragruntOptions.DownloadDir, encodedWorkingDir)
+	downloadDir := util.JoinPath(cacheDir, rootPath)
 	workingDir := util.JoinPath(downloadDir, modulePath)
 	versionFile := util.JoinPath(downloadDir, ".terragrunt-source-version")
+	copiedFilesFile := util.JoinPath(downloadDir, COPIED_FILES_FILE)
 
 	return &TerraformSource{
 		CanonicalSourceURL: rootSourceUrl,
+		CacheDir:           cacheDir,
 		DownloadDir:        downloadDir,
 		WorkingDir:         workingDir,
 		VersionFile:        versionFile,
+		CopiedFilesFile:    copiedFilesFile,
 	}, nil
 }
 
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/shell"
+)
+
+// The exit codes Terragrunt exits with, one for each class of failure (see errors.ErrorClass), so scripts and CI can
+// tell the failures apart. Codes 0 to 2 are left to Terraform, which uses 2 for plan -detailed-exitcode.
+const (
+	EXIT_CODE_SUCCESS                = 0
+	EXIT_CODE_FAILURE                = 1
+	EXIT_CODE_CONFIG_ERROR           = 3
+	EXIT_CODE_DEPENDENCY_GRAPH_ERROR = 4
+	EXIT_CODE_REMOTE_STATE_ERROR     = 5
+	EXIT_CODE_LOCK_ERROR             = 6
+)
+
+// The exit code for each class of failure other than ErrorClassUnknown
+var EXIT_CODES_BY_ERROR_CLASS = map[errors.ErrorClass]int{
+	errors.ErrorClassConfig:          EXIT_CODE_CONFIG_ERROR,
+	errors.ErrorClassDependencyGraph: EXIT_CODE_DEPENDENCY_GRAPH_ERROR,
+	errors.ErrorClassRemoteState:     EXIT_CODE_REMOTE_STATE_ERROR,
+	errors.ErrorClassLock:            EXIT_CODE_LOCK_ERROR,
+}
+
+// Return the code Terragrunt should exit with after running into the given error, if any. Errors of a known class of
+// failure get the exit code of that class. Otherwise, if the error came from a command that exited with a code, such
+// as Terraform, or the modules of an xxx-all command, Terragrunt exits with that same code, so that e.g. the 2 of
+// plan -detailed-exitcode is passed on. Any other error is a general failure.
+func ExitCode(err error) int {
+	if err == nil {
+		return EXIT_CODE_SUCCESS
+	}
+
+	if exitCode, hasClass := EXIT_CODES_BY_ERROR_CLASS[errors.ClassOf(err)]; hasClass {
+		return exitCode
+	}
+
+	if exitCode, err := shell.GetExitCode(err); err == nil {
+		return exitCode
+	}
+
+	return EXIT_CODE_FAILURE
+}
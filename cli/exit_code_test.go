@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	planWithChanges := exec.Command("sh", "-c", "exit 2").Run()
+	require.Error(t, planWithChanges)
+
+	app := &configstack.TerraformModule{Path: "app"}
+	vpc := &configstack.TerraformModule{Path: "vpc", Dependencies: []*configstack.TerraformModule{app}}
+	app.Dependencies = []*configstack.TerraformModule{vpc}
+	cycle := configstack.CheckForCycles([]*configstack.TerraformModule{app, vpc})
+	require.Error(t, cycle)
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"no error", nil, EXIT_CODE_SUCCESS},
+		{"error without a class", errors.WithStackTrace(fmt.Errorf("something went wrong")), EXIT_CODE_FAILURE},
+		{"terraform exit code", errors.WithStackTrace(planWithChanges), 2},
+		{"terraform exit code after hooks", errors.NewMultiError(nil, planWithChanges, nil), 2},
+		{"module of an xxx-all command", configstack.MultiError{Errors: []error{planWithChanges}}, 2},
+		{"config", errors.WithClass(errors.WithStackTrace(config.ErrorParsingTerragruntConfig{ConfigPath: "terraform.tfvars", Underlying: fmt.Errorf("bad HCL")}), errors.ErrorClassConfig), EXIT_CODE_CONFIG_ERROR},
+		{"config of a module in a stack", errors.WithStackTrace(configstack.ErrorProcessingModule{ModulePath: "app", UnderlyingError: fmt.Errorf("bad HCL")}), EXIT_CODE_CONFIG_ERROR},
+		{"dependency cycle", cycle, EXIT_CODE_DEPENDENCY_GRAPH_ERROR},
+		{"unrecognized dependency", errors.WithStackTrace(configstack.UnrecognizedDependency{ModulePath: "app", DependencyPath: "../vpc"}), EXIT_CODE_DEPENDENCY_GRAPH_ERROR},
+		{"remote state", errors.WithClass(errors.WithStackTrace(remote.MissingRequiredS3RemoteStateConfig("bucket")), errors.ErrorClassRemoteState), EXIT_CODE_REMOTE_STATE_ERROR},
+		{"lock timeout during remote state initialization", errors.WithClass(errors.WithStackTrace(remote.MaxRetriesWaitingForS3BucketExceeded("my-bucket")), errors.ErrorClassRemoteState), EXIT_CODE_LOCK_ERROR},
+		{"lock file timeout", errors.WithStackTrace(util.LockFileTimeout{Path: "/tmp/.terragrunt.lock"}), EXIT_CODE_LOCK_ERROR},
+		{"class wins over exit code", errors.NewMultiError(planWithChanges, errors.WithClass(fmt.Errorf("no such bucket"), errors.ErrorClassRemoteState)), EXIT_CODE_REMOTE_STATE_ERROR},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, ExitCode(testCase.err), "For %s", testCase.name)
+	}
+}
+
+func TestWithClassKeepsExistingClass(t *testing.T) {
+	t.Parallel()
+
+	lockTimeout := errors.WithStackTrace(remote.MaxRetriesWaitingForS3BucketExceeded("my-bucket"))
+	assert.Equal(t, lockTimeout, errors.WithClass(lockTimeout, errors.ErrorClassRemoteState), "An error that already has a class should be returned unchanged")
+
+	remoteStateErr := errors.WithClass(fmt.Errorf("no such bucket"), errors.ErrorClassRemoteState)
+	assert.Equal(t, remoteStateErr, errors.WithClass(remoteStateErr, errors.ErrorClassConfig), "An error that was already given a class should be returned unchanged")
+
+	unclassifiedErr := errors.WithStackTrace(fmt.Errorf("something went wrong"))
+	classifiedErr := errors.WithClass(unclassifiedErr, errors.ErrorClassConfig)
+	assert.Equal(t, errors.ErrorClassConfig, errors.ClassOf(classifiedErr))
+	assert.Equal(t, errors.Unwrap(unclassifiedErr), errors.Unwrap(classifiedErr))
+}
+
+func TestExitCodeOfInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "terragrunt-exit-code-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	configPath := util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath)
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`terragrunt = { iam_role = "${not_a_helper()}" }`), 0644))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(configPath)
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{"plan"}
+
+	err = runTerragrunt(terragruntOptions)
+	if assert.Error(t, err) {
+		assert.Equal(t, EXIT_CODE_CONFIG_ERROR, ExitCode(err))
+		// The class doesn't get in the way of checking the type of the error
+		assert.IsType(t, config.UnknownHelperFunction(""), errors.Unwrap(err))
+	}
+}
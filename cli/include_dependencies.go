@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The commands that can be run with --terragrunt-include-dependencies
+var INCLUDE_DEPENDENCIES_COMMANDS = []string{"plan", "apply"}
+
+// How many levels of dependencies plan runs in with --terragrunt-include-dependencies, unless
+// --terragrunt-include-dependencies-depth says otherwise: only the direct dependencies, to see their pending changes
+const DEFAULT_INCLUDE_DEPENDENCIES_PLAN_DEPTH = 1
+
+// Run plan or apply in the current module and in its dependencies, in dependency order, the same way plan-all and
+// apply-all run them in a stack. Plan runs in the dependencies up to --terragrunt-include-dependencies-depth levels
+// away, while apply runs in all of them, as the module can only be applied once everything it depends on has been.
+func runWithDependencies(terragruntOptions *options.TerragruntOptions) error {
+	command := util.FirstArg(terragruntOptions.TerraformCliArgs)
+	maxDepth, err := includeDependenciesDepth(command, terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	// Each module gets the Terraform args the user passed, after the command, which the stack adds itself
+	stackOptions := terragruntOptions.Clone(terragruntOptions.TerragruntConfigPath)
+	stackOptions.WorkingDir = terragruntOptions.WorkingDir
+	stackOptions.TerraformCliArgs = terragruntOptions.TerraformCliArgs[1:]
+
+	stack, err := configstack.FindStackForModuleWithDependencies(stackOptions, maxDepth)
+	if err != nil {
+		return err
+	}
+
+	terragruntOptions.Logger.Printf("%s", stack.String())
+	if command == "plan" {
+		return stack.Plan(terragruntOptions)
+	}
+
+	shouldApply, err := shell.ConfirmOrDryRun("Are you sure you want to run 'terragrunt apply' in the module and each of its dependencies described above?", nil, false, terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	if shouldApply {
+		return stack.Apply(terragruntOptions)
+	}
+
+	return nil
+}
+
+// Return how many levels of dependencies the given command runs in with --terragrunt-include-dependencies, or 0 for all
+// of them, or an error if the command or its args can't be run in the dependencies of a module
+func includeDependenciesDepth(command string, terragruntOptions *options.TerragruntOptions) (int, error) {
+	if !util.ListContainsElement(INCLUDE_DEPENDENCIES_COMMANDS, command) {
+		return 0, errors.WithStackTrace(IncludeDependenciesNotSupported(command))
+	}
+
+	// Resource addresses are specific to a module, so a -target can't apply to the dependencies too
+	for _, arg := range terragruntOptions.TerraformCliArgs[1:] {
+		name := strings.TrimLeft(arg, "-")
+		if strings.HasPrefix(arg, "-") && (name == "target" || strings.HasPrefix(name, "target=")) {
+			return 0, errors.WithStackTrace(IncludeDependenciesWithTarget(arg))
+		}
+	}
+
+	if command == "apply" {
+		if terragruntOptions.IncludeDependenciesDepth > 0 {
+			return 0, errors.WithStackTrace(IncludeDependenciesDepthNotSupported(command))
+		}
+		return 0, nil
+	}
+
+	if terragruntOptions.IncludeDependenciesDepth > 0 {
+		return terragruntOptions.IncludeDependenciesDepth, nil
+	}
+	return DEFAULT_INCLUDE_DEPENDENCIES_PLAN_DEPTH, nil
+}
+
+// Custom error types
+
+type IncludeDependenciesNotSupported string
+
+func (command IncludeDependenciesNotSupported) Error() string {
+	return fmt.Sprintf("The --%s option only works with the %s commands, not with '%s'", OPT_TERRAGRUNT_INCLUDE_DEPENDENCIES, strings.Join(INCLUDE_DEPENDENCIES_COMMANDS, " and "), string(command))
+}
+
+type IncludeDependenciesWithTarget string
+
+func (arg IncludeDependenciesWithTarget) Error() string {
+	return fmt.Sprintf("The --%s option can't be combined with %s, as resource addresses only apply to a single module", OPT_TERRAGRUNT_INCLUDE_DEPENDENCIES, string(arg))
+}
+
+type IncludeDependenciesDepthNotSupported string
+
+func (command IncludeDependenciesDepthNotSupported) Error() string {
+	return fmt.Sprintf("The --%s option only works with plan: %s always runs in all the dependencies of the module", OPT_TERRAGRUNT_INCLUDE_DEPENDENCIES_DEPTH, string(command))
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncludeDependenciesDepth(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		args          []string
+		depth         int
+		expectedDepth int
+		expectedErr   error
+	}{
+		{[]string{"plan"}, 0, DEFAULT_INCLUDE_DEPENDENCIES_PLAN_DEPTH, nil},
+		{[]string{"plan", "-lock=false"}, 3, 3, nil},
+		{[]string{"apply"}, 0, 0, nil},
+		{[]string{"apply", "-input=false"}, 2, 0, IncludeDependenciesDepthNotSupported("")},
+		{[]string{"plan", "-target=aws_instance.app"}, 0, 0, IncludeDependenciesWithTarget("")},
+		{[]string{"apply", "-target", "aws_instance.app"}, 0, 0, IncludeDependenciesWithTarget("")},
+		{[]string{"apply", "--target=aws_instance.app"}, 0, 0, IncludeDependenciesWithTarget("")},
+		{[]string{"destroy"}, 0, 0, IncludeDependenciesNotSupported("")},
+		{[]string{}, 0, 0, IncludeDependenciesNotSupported("")},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest("/root/app/terraform.tfvars")
+		require.NoError(t, err)
+		terragruntOptions.TerraformCliArgs = testCase.args
+		terragruntOptions.IncludeDependencies = true
+		terragruntOptions.IncludeDependenciesDepth = testCase.depth
+
+		command := ""
+		if len(testCase.args) > 0 {
+			command = testCase.args[0]
+		}
+
+		actualDepth, actualErr := includeDependenciesDepth(command, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For args %v", testCase.args) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For args %v", testCase.args)
+			}
+		} else {
+			assert.NoError(t, actualErr, "For args %v", testCase.args)
+			assert.Equal(t, testCase.expectedDepth, actualDepth, "For args %v", testCase.args)
+		}
+	}
+}
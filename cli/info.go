@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The data printed by the terragrunt-info command
+type terragruntInfo struct {
+	ConfigPath       string   `json:"config_path"`
+	WorkingDir       string   `json:"working_dir"`
+	DownloadDir      string   `json:"download_dir"`
+	TerraformBinary  string   `json:"terraform_binary"`
+	TerraformVersion string   `json:"terraform_version"`
+	TerraformSource  string   `json:"terraform_source"`
+	IamRole          string   `json:"iam_role"`
+	Dependencies     []string `json:"dependencies"`
+
+	// How many times each helper function was called while parsing the config, and how long the calls took
+	HelperMetrics []util.CallMetric `json:"helper_metrics"`
+}
+
+// The data printed by the graph-dependencies command
+type dependencyGraph struct {
+	Path    string                  `json:"path"`
+	Modules []dependencyGraphModule `json:"modules"`
+}
+
+type dependencyGraphModule struct {
+	Path             string   `json:"path"`
+	Dependencies     []string `json:"dependencies"`
+	ConcurrencyGroup string   `json:"concurrency_group"`
+}
+
+// Print information about the current module, such as the paths Terragrunt will use, to help with debugging and
+// tooling
+func printTerragruntInfo(terragruntOptions *options.TerragruntOptions) error {
+	terragruntConfig, err := config.ReadTerragruntConfig(terragruntOptions)
+	if err != nil {
+		return errors.WithClass(err, errors.ErrorClassConfig)
+	}
+
+	info := newTerragruntInfo(terragruntOptions, terragruntConfig)
+
+	return writeStructuredOutput(terragruntOptions, info, func(writer io.Writer) {
+		fmt.Fprintf(writer, "Config path:       %s\n", info.ConfigPath)
+		fmt.Fprintf(writer, "Working dir:       %s\n", info.WorkingDir)
+		fmt.Fprintf(writer, "Download dir:      %s\n", info.DownloadDir)
+		fmt.Fprintf(writer, "Terraform binary:  %s\n", info.TerraformBinary)
+		fmt.Fprintf(writer, "Terraform version: %s\n", info.TerraformVersion)
+		fmt.Fprintf(writer, "Terraform source:  %s\n", info.TerraformSource)
+		fmt.Fprintf(writer, "IAM role:          %s\n", info.IamRole)
+		fmt.Fprintf(writer, "Dependencies:      %v\n", info.Dependencies)
+		fmt.Fprintf(writer, "Helper functions:\n")
+		for _, metric := range info.HelperMetrics {
+			fmt.Fprintf(writer, "  %s: %d calls in %v\n", metric.Name, metric.Calls, metric.Duration)
+		}
+	})
+}
+
+func newTerragruntInfo(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) terragruntInfo {
+	info := terragruntInfo{
+		ConfigPath:      terragruntOptions.TerragruntConfigPath,
+		WorkingDir:      terragruntOptions.WorkingDir,
+		DownloadDir:     terragruntOptions.DownloadDir,
+		TerraformBinary: terragruntOptions.TerraformPath,
+		TerraformSource: getTerraformSourceUrl(terragruntOptions, terragruntConfig),
+		IamRole:         terragruntOptions.IamRole,
+		Dependencies:    []string{},
+		HelperMetrics:   terragruntOptions.HelperMetrics.Snapshot(),
+	}
+
+	if terragruntOptions.TerraformVersion != nil {
+		info.TerraformVersion = terragruntOptions.TerraformVersion.String()
+	}
+
+	if info.IamRole == "" {
+		info.IamRole = terragruntConfig.IamRole
+	}
+
+	if terragruntConfig.Dependencies != nil {
+		info.Dependencies = append(info.Dependencies, terragruntConfig.Dependencies.Paths...)
+	}
+
+	return info
+}
+
+// Print the dependencies between all the modules in the stack in the working dir
+func printDependencyGraph(terragruntOptions *options.TerragruntOptions) error {
+	stack, err := configstack.FindStackInSubfolders(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	graph := newDependencyGraph(stack)
+
+	return writeStructuredOutput(terragruntOptions, graph, func(writer io.Writer) {
+		fmt.Fprintf(writer, "Dependencies of the modules in %s:\n", graph.Path)
+		for _, module := range graph.Modules {
+			if module.ConcurrencyGroup != "" {
+				fmt.Fprintf(writer, "  %s (concurrency group: %s)\n", module.Path, module.ConcurrencyGroup)
+			} else {
+				fmt.Fprintf(writer, "  %s\n", module.Path)
+			}
+			for _, dependency := range module.Dependencies {
+				fmt.Fprintf(writer, "    => %s\n", dependency)
+			}
+		}
+	})
+}
+
+func newDependencyGraph(stack *configstack.Stack) dependencyGraph {
+	graph := dependencyGraph{Path: stack.Path, Modules: []dependencyGraphModule{}}
+
+	for _, module := range stack.Modules {
+		graphModule := dependencyGraphModule{Path: module.Path, Dependencies: []string{}, ConcurrencyGroup: module.ConcurrencyGroup()}
+		for _, dependency := range module.Dependencies {
+			graphModule.Dependencies = append(graphModule.Dependencies, dependency.Path)
+		}
+		sort.Strings(graphModule.Dependencies)
+		graph.Modules = append(graph.Modules, graphModule)
+	}
+
+	sort.Slice(graph.Modules, func(i, j int) bool { return graph.Modules[i].Path < graph.Modules[j].Path })
+
+	return graph
+}
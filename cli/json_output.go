@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The version of the JSON written by commands that produce structured data, such as terragrunt-info and
+// graph-dependencies. Within a major version, changes must be additive only: new fields may be added, but existing
+// fields must never be removed, renamed, or change type. Any other change requires bumping this version.
+const JSON_OUTPUT_SCHEMA_VERSION = 1
+
+// The value of --terragrunt-json-out that means "write the JSON to stdout"
+const JSON_OUT_STDOUT = "-"
+
+// The names of the fields the encoder adds to the top level of the JSON output of every command
+const (
+	JSON_OUTPUT_SCHEMA_VERSION_KEY = "schema_version"
+	JSON_OUTPUT_GENERATED_AT_KEY   = "generated_at"
+)
+
+// Write the given structured data produced by a command. If --terragrunt-json-out is not set, the data is written to
+// stdout in human-readable form using the given function. If it's set to a file, the human-readable form is still
+// written to stdout and the data is also written to that file as JSON. If it's set to "-", only the JSON is written to
+// stdout.
+func writeStructuredOutput(terragruntOptions *options.TerragruntOptions, data interface{}, writeHumanReadable func(io.Writer)) error {
+	if terragruntOptions.JsonOut != JSON_OUT_STDOUT {
+		writeHumanReadable(terragruntOptions.Writer)
+	}
+
+	if terragruntOptions.JsonOut == "" {
+		return nil
+	}
+
+	encoded, err := encodeJsonOutput(data, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if terragruntOptions.JsonOut == JSON_OUT_STDOUT {
+		_, err := fmt.Fprintln(terragruntOptions.Writer, string(encoded))
+		return errors.WithStackTrace(err)
+	}
+
+	if err := ioutil.WriteFile(terragruntOptions.JsonOut, encoded, 0644); err != nil {
+		if util.IsReadOnlyError(err) {
+			return errors.WithStackTrace(PathNotWritable{Path: terragruntOptions.JsonOut, Hint: fmt.Sprintf("Point --%s at a writable path, or set it to '%s' to write to stdout.", OPT_TERRAGRUNT_JSON_OUT, JSON_OUT_STDOUT), Underlying: err})
+		}
+		return errors.WithStackTrace(err)
+	}
+	return nil
+}
+
+// Encode the given data, which must encode to a JSON object, as JSON, adding the schema version and the given
+// generation time as top-level fields. All commands that produce structured data must go through this method so their
+// JSON output is consistent.
+func encodeJsonOutput(data interface{}, generatedAt time.Time) ([]byte, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, errors.WithStackTrace(JsonOutputNotAnObject{Underlying: err})
+	}
+
+	for _, key := range []string{JSON_OUTPUT_SCHEMA_VERSION_KEY, JSON_OUTPUT_GENERATED_AT_KEY} {
+		if _, alreadyDefined := fields[key]; alreadyDefined {
+			return nil, errors.WithStackTrace(ReservedJsonOutputField(key))
+		}
+	}
+
+	fields[JSON_OUTPUT_SCHEMA_VERSION_KEY] = JSON_OUTPUT_SCHEMA_VERSION
+	fields[JSON_OUTPUT_GENERATED_AT_KEY] = generatedAt.UTC().Format(time.RFC3339)
+
+	out, err := json.MarshalIndent(fields, "", "  ")
+	return out, errors.WithStackTrace(err)
+}
+
+// Custom error types
+
+type JsonOutputNotAnObject struct {
+	Underlying error
+}
+
+func (err JsonOutputNotAnObject) Error() string {
+	return fmt.Sprintf("The structured output of a command must be a JSON object: %v", err.Underlying)
+}
+
+type ReservedJsonOutputField string
+
+func (key ReservedJsonOutputField) Error() string {
+	return fmt.Sprintf("The structured output of a command may not define the field %s, as it is reserved for the JSON encoder", string(key))
+}
@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeJsonOutput(t *testing.T) {
+	t.Parallel()
+
+	generatedAt := time.Date(2018, 3, 14, 15, 9, 26, 0, time.FixedZone("PDT", -7*60*60))
+	encoded, err := encodeJsonOutput(dependencyGraphModule{Path: "/stack/app", Dependencies: []string{"/stack/vpc"}}, generatedAt)
+	require.NoError(t, err)
+
+	actual := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(encoded, &actual))
+
+	expected := map[string]interface{}{
+		"schema_version":    float64(JSON_OUTPUT_SCHEMA_VERSION),
+		"generated_at":      "2018-03-14T22:09:26Z",
+		"path":              "/stack/app",
+		"dependencies":      []interface{}{"/stack/vpc"},
+		"concurrency_group": "",
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestEncodeJsonOutputErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		data        interface{}
+		expectedErr error
+	}{
+		{[]string{"not", "an", "object"}, JsonOutputNotAnObject{}},
+		{"foo", JsonOutputNotAnObject{}},
+		{map[string]string{"schema_version": "2"}, ReservedJsonOutputField("")},
+		{map[string]string{"generated_at": "now"}, ReservedJsonOutputField("")},
+	}
+
+	for _, testCase := range testCases {
+		_, err := encodeJsonOutput(testCase.data, time.Now())
+		if assert.Error(t, err, "For data %v", testCase.data) {
+			assert.IsType(t, testCase.expectedErr, errors.Unwrap(err), "For data %v", testCase.data)
+		}
+	}
+}
+
+func TestWriteStructuredOutput(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "json-out")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	jsonOutPath := filepath.Join(tmpDir, "out.json")
+	data := dependencyGraphModule{Path: "/stack/app", Dependencies: []string{}}
+	writeHumanReadable := func(writer io.Writer) { fmt.Fprintln(writer, "human readable") }
+
+	testCases := []struct {
+		jsonOut           string
+		expectHumanOutput bool
+		expectStdoutJson  bool
+		expectJsonFileOut bool
+	}{
+		{"", true, false, false},
+		{JSON_OUT_STDOUT, false, true, false},
+		{jsonOutPath, true, false, true},
+	}
+
+	for _, testCase := range testCases {
+		stdout := &bytes.Buffer{}
+		terragruntOptions, err := options.NewTerragruntOptionsForTest("")
+		require.NoError(t, err)
+		terragruntOptions.Writer = stdout
+		terragruntOptions.JsonOut = testCase.jsonOut
+
+		require.NoError(t, writeStructuredOutput(terragruntOptions, data, writeHumanReadable))
+
+		assert.Equal(t, testCase.expectHumanOutput, bytes.Contains(stdout.Bytes(), []byte("human readable")), "For json out %s", testCase.jsonOut)
+		assert.Equal(t, testCase.expectStdoutJson, bytes.Contains(stdout.Bytes(), []byte(`"schema_version"`)), "For json out %s", testCase.jsonOut)
+
+		if testCase.expectJsonFileOut {
+			contents, err := ioutil.ReadFile(jsonOutPath)
+			require.NoError(t, err)
+			assert.Contains(t, string(contents), `"schema_version"`)
+		}
+	}
+}
+
+// These tests enforce the schema bump policy described in JSON_OUTPUT_SCHEMA_VERSION: the fields in each command's JSON
+// output may only be added to within a schema version. If one of these tests fails because you removed, renamed, or
+// changed the type of a field, you must bump JSON_OUTPUT_SCHEMA_VERSION and update the snapshots below. If you only
+// added fields, add them to the snapshots below.
+
+func TestTerragruntInfoJsonShape(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("/stack/app/" + config.DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+	terragruntConfig := &config.TerragruntConfig{
+		Terraform:    &config.TerraformConfig{Source: "git::git@github.com:foo/modules.git//app?ref=v0.0.1"},
+		Dependencies: &config.ModuleDependencies{Paths: []string{"../vpc"}},
+	}
+
+	expectedShape := []string{
+		"config_path:string",
+		"dependencies:array",
+		"download_dir:string",
+		"generated_at:string",
+		"helper_metrics:array",
+		"iam_role:string",
+		"schema_version:number",
+		"terraform_binary:string",
+		"terraform_source:string",
+		"terraform_version:string",
+		"working_dir:string",
+	}
+
+	assertJsonShape(t, expectedShape, newTerragruntInfo(terragruntOptions, terragruntConfig))
+}
+
+func TestDependencyGraphJsonShape(t *testing.T) {
+	t.Parallel()
+
+	vpc := &configstack.TerraformModule{Path: "/stack/vpc"}
+	app := &configstack.TerraformModule{
+		Path:         "/stack/app",
+		Dependencies: []*configstack.TerraformModule{vpc},
+		Config:       config.TerragruntConfig{Terraform: &config.TerraformConfig{ConcurrencyGroup: "pagerduty"}},
+	}
+	stack := &configstack.Stack{Path: "/stack", Modules: []*configstack.TerraformModule{app, vpc}}
+
+	graph := newDependencyGraph(stack)
+	assert.Equal(t, []dependencyGraphModule{
+		{Path: "/stack/app", Dependencies: []string{"/stack/vpc"}, ConcurrencyGroup: "pagerduty"},
+		{Path: "/stack/vpc", Dependencies: []string{}},
+	}, graph.Modules)
+
+	expectedShape := []string{
+		"generated_at:string",
+		"modules:array",
+		"modules[].concurrency_group:string",
+		"modules[].dependencies:array",
+		"modules[].path:string",
+		"path:string",
+		"schema_version:number",
+	}
+
+	assertJsonShape(t, expectedShape, graph)
+}
+
+// Assert that the JSON output for the given data contains every field in the given snapshot of its shape, with the
+// same type. New fields are allowed, as adding fields is backwards compatible.
+func assertJsonShape(t *testing.T, expectedShape []string, data interface{}) {
+	encoded, err := encodeJsonOutput(data, time.Now())
+	require.NoError(t, err)
+
+	decoded := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+
+	actualShape := []string{}
+	collectJsonShape("", decoded, &actualShape)
+	sort.Strings(actualShape)
+
+	for _, field := range expectedShape {
+		assert.Contains(t, actualShape, field, "Field %s was removed or changed type without bumping JSON_OUTPUT_SCHEMA_VERSION", field)
+	}
+}
+
+func collectJsonShape(prefix string, value interface{}, shape *[]string) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typedValue {
+			name := key
+			if prefix != "" {
+				name = prefix + "." + key
+			}
+			*shape = append(*shape, fmt.Sprintf("%s:%s", name, jsonType(child)))
+			collectJsonShape(name, child, shape)
+		}
+	case []interface{}:
+		for _, child := range typedValue {
+			collectJsonShape(prefix+"[]", child, shape)
+		}
+	}
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	default:
+		return "null"
+	}
+}
@@ -0,0 +1,423 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/printer"
+)
+
+// The options of the migrate-config command
+const MIGRATE_CONFIG_OPT_ALL = "all"
+const MIGRATE_CONFIG_OPT_CONFIG_NAME = "config-name"
+const MIGRATE_CONFIG_OPT_DRY_RUN = "dry-run"
+
+// The variable files Terraform loads without being asked to. The terragrunt block must not be moved to a file that
+// matches one of these, or Terraform would read it as a variable.
+var TERRAFORM_AUTO_LOADED_VAR_FILE_REGEX = regexp.MustCompile(`^terraform\.tfvars(\.json)?$|\.auto\.tfvars(\.json)?$`)
+
+// Matches calls to find_in_parent_folders in an include path that look for the default config file
+var FIND_IN_PARENT_FOLDERS_DEFAULT_REGEX = regexp.MustCompile(`find_in_parent_folders\(\s*\)`)
+
+type migrateConfigOptions struct {
+	// Migrate every module in the working dir and its subfolders instead of just the current one
+	All bool
+
+	// The name of the file, in the folder of each module, to move the terragrunt block to
+	ConfigName string
+
+	// Only log which files would change
+	DryRun bool
+}
+
+// The changes migrate-config makes to the config of a single module
+type configMigration struct {
+	// The file the terragrunt block is moved out of
+	ConfigPath string
+
+	// The contents the file is left with, which are only variables. Empty if there are none, in which case the file is
+	// removed.
+	RemainingContents string
+
+	// The dedicated file the terragrunt block is moved to
+	NewConfigPath string
+
+	// The contents of the dedicated file, which are the terragrunt block, including its comments
+	NewConfigContents string
+
+	// The include path of the terragrunt block before and after the migration, if it had to be updated because the
+	// config it includes was migrated too
+	OldIncludePath string
+	NewIncludePath string
+}
+
+// Move the terragrunt block of the terraform.tfvars file of the current module, or, with --all, of every module in the
+// working dir and its subfolders, to a dedicated file, so terraform.tfvars is left with only variables
+func migrateConfig(terragruntOptions *options.TerragruntOptions) error {
+	migrateOptions, err := parseMigrateConfigOptions(util.RemoveElementFromList(terragruntOptions.TerraformCliArgs, CMD_MIGRATE_CONFIG))
+	if err != nil {
+		return err
+	}
+
+	configPaths := []string{terragruntOptions.TerragruntConfigPath}
+	if migrateOptions.All {
+		configPaths, err = migratableConfigPaths(terragruntOptions, migrateOptions)
+		if err != nil {
+			return err
+		}
+	}
+
+	migrations, err := planConfigMigrations(configPaths, migrateOptions, terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	if len(migrations) == 0 {
+		terragruntOptions.Logger.Printf("None of the Terragrunt config files in %s need to be migrated", terragruntOptions.WorkingDir)
+		return nil
+	}
+
+	terragruntOptions.Logger.Printf("The terragrunt blocks of %d Terragrunt config file(s) will be moved to %s:", len(migrations), migrateOptions.ConfigName)
+	shouldMigrate, err := shell.ConfirmOrDryRun("Are you sure you want to migrate the config files above?", describeConfigMigrations(migrations), migrateOptions.DryRun, terragruntOptions)
+	if err != nil || !shouldMigrate {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if err := applyConfigMigration(migration, terragruntOptions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Parse the options of the migrate-config command
+func parseMigrateConfigOptions(args []string) (*migrateConfigOptions, error) {
+	configName, err := parseStringArg(args, MIGRATE_CONFIG_OPT_CONFIG_NAME, config.DedicatedTerragruntConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	isPlainFileName := configName != "" && configName != "." && configName != ".." && !strings.ContainsAny(configName, `/\`)
+	if !isPlainFileName || TERRAFORM_AUTO_LOADED_VAR_FILE_REGEX.MatchString(configName) || strings.HasSuffix(configName, config.OldTerragruntConfigPath) {
+		return nil, errors.WithStackTrace(InvalidMigratedConfigName(configName))
+	}
+
+	return &migrateConfigOptions{
+		All:        parseBooleanArg(args, MIGRATE_CONFIG_OPT_ALL, false),
+		ConfigName: configName,
+		DryRun:     parseBooleanArg(args, MIGRATE_CONFIG_OPT_DRY_RUN, false),
+	}, nil
+}
+
+// Return the paths of the Terragrunt config files in the working dir and its subfolders that have a terragrunt block
+// to migrate. Those in the old .terragrunt format, and those that were already migrated, are skipped.
+func migratableConfigPaths(terragruntOptions *options.TerragruntOptions, migrateOptions *migrateConfigOptions) ([]string, error) {
+	configPaths, err := config.FindConfigFilesInPath(terragruntOptions.WorkingDir, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	migratable := []string{}
+	for _, configPath := range configPaths {
+		name := filepath.Base(configPath)
+		if name == config.OldTerragruntConfigPath {
+			terragruntOptions.Logger.Printf("Skipping %s, as config files in the deprecated %s format have no variables to separate from", configPath, config.OldTerragruntConfigPath)
+			continue
+		}
+		if name == migrateOptions.ConfigName || name == config.DedicatedTerragruntConfigPath {
+			continue
+		}
+		migratable = append(migratable, configPath)
+	}
+	return migratable, nil
+}
+
+// Plan the migration of each of the given config files. An include path is only updated if the config it points to is
+// one of the given config files, as otherwise, that config stays where it is.
+func planConfigMigrations(configPaths []string, migrateOptions *migrateConfigOptions, terragruntOptions *options.TerragruntOptions) ([]*configMigration, error) {
+	migratedConfigPaths := map[string]string{}
+	for _, configPath := range configPaths {
+		canonicalPath, err := util.CanonicalPath(configPath, "")
+		if err != nil {
+			return nil, err
+		}
+		migratedConfigPaths[canonicalPath] = util.JoinPath(filepath.Dir(canonicalPath), migrateOptions.ConfigName)
+	}
+
+	migrations := []*configMigration{}
+	for _, configPath := range configPaths {
+		migration, err := planConfigMigration(configPath, migrateOptions.ConfigName, migratedConfigPaths, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// Plan moving the terragrunt block of the given config file, along with its comments, to a file with the given name in
+// the same folder. The given map has the path each migrated config is moved to, keyed by its canonical current path,
+// and is used to update the include path of the terragrunt block.
+func planConfigMigration(configPath string, configName string, migratedConfigPaths map[string]string, terragruntOptions *options.TerragruntOptions) (*configMigration, error) {
+	if filepath.Base(configPath) == config.OldTerragruntConfigPath {
+		return nil, errors.WithStackTrace(CannotMigrateOldConfigFormat(configPath))
+	}
+
+	if filepath.Base(configPath) == configName {
+		return nil, errors.WithStackTrace(ConfigAlreadyMigrated(configPath))
+	}
+
+	newConfigPath := util.JoinPath(filepath.Dir(configPath), configName)
+	if util.FileExists(newConfigPath) {
+		return nil, errors.WithStackTrace(MigratedConfigAlreadyExists{ConfigPath: configPath, NewConfigPath: newConfigPath})
+	}
+
+	contents, err := util.ReadFileAsString(configPath)
+	if err != nil {
+		return nil, err
+	}
+	// Normalize the contents first, as the parser would, so the offsets of the nodes it returns line up with them
+	contents = util.NormalizeLineEndings(util.StripUTF8BOM(contents))
+
+	file, err := hcl.ParseString(contents)
+	if err != nil {
+		return nil, errors.WithStackTrace(config.ErrorParsingTerragruntConfig{ConfigPath: configPath, Underlying: err})
+	}
+
+	block := findTerragruntBlock(file)
+	if block == nil {
+		return nil, errors.WithStackTrace(NoTerragruntBlockToMigrate(configPath))
+	}
+
+	start, end := terragruntBlockOffsets(contents, block)
+	blockContents := contents[start:end]
+
+	migration := &configMigration{ConfigPath: configPath, NewConfigPath: newConfigPath}
+
+	if includePath := terragruntBlockIncludePath(block); includePath != nil {
+		newIncludePath := migratedIncludePath(includePath, configPath, configName, migratedConfigPaths, terragruntOptions)
+		if newIncludePath != includePath.Token.Text {
+			offset := includePath.Token.Pos.Offset - start
+			blockContents = blockContents[:offset] + newIncludePath + blockContents[offset+len(includePath.Token.Text):]
+			migration.OldIncludePath = includePath.Token.Text
+			migration.NewIncludePath = newIncludePath
+		}
+	}
+
+	migration.NewConfigContents = renderMigratedConfig(blockContents)
+	migration.RemainingContents = remainingVariables(contents[:start], contents[end:])
+
+	return migration, nil
+}
+
+// Return the terragrunt = { ... } item of the given file, or nil if it has none
+func findTerragruntBlock(file *ast.File) *ast.ObjectItem {
+	root, isObjectList := file.Node.(*ast.ObjectList)
+	if !isObjectList {
+		return nil
+	}
+
+	for _, item := range root.Items {
+		if len(item.Keys) != 1 || hclKeyName(item.Keys[0]) != "terragrunt" {
+			continue
+		}
+		if _, isObject := item.Val.(*ast.ObjectType); isObject {
+			return item
+		}
+	}
+	return nil
+}
+
+// Return the offsets in the given contents of the start and the end of the given terragrunt block. These cover whole
+// lines, from the comments right above the block to the comment, if any, after its closing brace.
+func terragruntBlockOffsets(contents string, block *ast.ObjectItem) (int, int) {
+	start := block.Keys[0].Pos().Offset
+	if block.LeadComment != nil && len(block.LeadComment.List) > 0 {
+		start = block.LeadComment.List[0].Start.Offset
+	}
+
+	end := block.Val.(*ast.ObjectType).Rbrace.Offset + 1
+	if block.LineComment != nil {
+		for _, comment := range block.LineComment.List {
+			if commentEnd := comment.Start.Offset + len(comment.Text); commentEnd > end {
+				end = commentEnd
+			}
+		}
+	}
+
+	start = strings.LastIndex(contents[:start], "\n") + 1
+	if newline := strings.Index(contents[end:], "\n"); newline >= 0 {
+		end += newline + 1
+	} else {
+		end = len(contents)
+	}
+
+	return start, end
+}
+
+// Return the value of the path setting in the include block of the given terragrunt block, or nil if there is none
+func terragruntBlockIncludePath(block *ast.ObjectItem) *ast.LiteralType {
+	for _, include := range block.Val.(*ast.ObjectType).List.Filter("include").Items {
+		includeBlock, isObject := include.Val.(*ast.ObjectType)
+		if !isObject {
+			continue
+		}
+		for _, path := range includeBlock.List.Filter("path").Items {
+			if literal, isLiteral := path.Val.(*ast.LiteralType); isLiteral {
+				return literal
+			}
+		}
+	}
+	return nil
+}
+
+// Return the include path, as it's written in the config file, quotes included, that points to where the config the
+// given include path points to is migrated to. If that config isn't migrated, or the include path can't be updated
+// automatically, it's returned unchanged.
+func migratedIncludePath(includePath *ast.LiteralType, configPath string, configName string, migratedConfigPaths map[string]string, terragruntOptions *options.TerragruntOptions) string {
+	text := includePath.Token.Text
+	value, isString := includePath.Token.Value().(string)
+	if !isString {
+		return text
+	}
+
+	resolvedPath, err := config.ResolveTerragruntConfigString(value, nil, terragruntOptions.Clone(configPath))
+	if err != nil {
+		terragruntOptions.Logger.Printf("WARNING: Unable to resolve the include path %s in %s, so it won't be updated: %v", value, configPath, err)
+		return text
+	}
+	if !filepath.IsAbs(resolvedPath) {
+		resolvedPath = util.JoinPath(filepath.Dir(configPath), resolvedPath)
+	}
+	canonicalPath, err := util.CanonicalPath(resolvedPath, "")
+	if err != nil {
+		return text
+	}
+
+	newIncludedConfigPath, isMigrated := migratedConfigPaths[canonicalPath]
+	if !isMigrated {
+		return text
+	}
+
+	oldName := filepath.Base(canonicalPath)
+	switch {
+	case FIND_IN_PARENT_FOLDERS_DEFAULT_REGEX.MatchString(text):
+		// find_in_parent_folders() finds the dedicated config file without being told to
+		if configName == config.DedicatedTerragruntConfigPath {
+			return text
+		}
+		return FIND_IN_PARENT_FOLDERS_DEFAULT_REGEX.ReplaceAllLiteralString(text, fmt.Sprintf(`find_in_parent_folders("%s")`, configName))
+	case strings.Contains(text, fmt.Sprintf(`find_in_parent_folders("%s"`, oldName)):
+		return strings.Replace(text, fmt.Sprintf(`find_in_parent_folders("%s"`, oldName), fmt.Sprintf(`find_in_parent_folders("%s"`, configName), -1)
+	case !strings.Contains(value, "${") && strings.HasSuffix(text, oldName+`"`):
+		return strings.TrimSuffix(text, oldName+`"`) + configName + `"`
+	}
+
+	terragruntOptions.Logger.Printf("WARNING: Unable to update the include path %s in %s automatically. Update it to point to %s by hand.", value, configPath, newIncludedConfigPath)
+	return text
+}
+
+// Format the given terragrunt block for its own file. Comments are kept. If the block can't be formatted, it's used as
+// is, which still parses the same way.
+func renderMigratedConfig(blockContents string) string {
+	formatted, err := printer.Format([]byte(blockContents))
+	if err != nil {
+		return blockContents
+	}
+	return strings.TrimRight(string(formatted), "\n") + "\n"
+}
+
+// Return what's left of a config file once its terragrunt block, which was between the given contents, is removed, or
+// an empty string if nothing but whitespace is left
+func remainingVariables(before string, after string) string {
+	parts := []string{}
+	for _, part := range []string{before, after} {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, strings.Trim(part, "\n"))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+// Return a description of each file operation the given migrations perform
+func describeConfigMigrations(migrations []*configMigration) []string {
+	changes := []string{}
+	for _, migration := range migrations {
+		changes = append(changes, fmt.Sprintf("Move the terragrunt block of %s to %s", migration.ConfigPath, migration.NewConfigPath))
+		if migration.NewIncludePath != "" {
+			changes = append(changes, fmt.Sprintf("Update the include path in %s from %s to %s", migration.NewConfigPath, migration.OldIncludePath, migration.NewIncludePath))
+		}
+		if migration.RemainingContents == "" {
+			changes = append(changes, fmt.Sprintf("Remove %s, as it has no variables left", migration.ConfigPath))
+		} else {
+			changes = append(changes, fmt.Sprintf("Leave only the variables in %s", migration.ConfigPath))
+		}
+	}
+	return changes
+}
+
+// Write the dedicated config file of the given migration, and then remove the terragrunt block from the original
+// config file, or the whole file if it has no variables left
+func applyConfigMigration(migration *configMigration, terragruntOptions *options.TerragruntOptions) error {
+	terragruntOptions.Logger.Printf("Moving the terragrunt block of %s to %s", migration.ConfigPath, migration.NewConfigPath)
+
+	if err := ioutil.WriteFile(migration.NewConfigPath, []byte(migration.NewConfigContents), 0644); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	if migration.RemainingContents == "" {
+		return errors.WithStackTrace(os.Remove(migration.ConfigPath))
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(migration.ConfigPath, []byte(migration.RemainingContents), 0644))
+}
+
+// Custom error types
+
+type InvalidMigratedConfigName string
+
+func (name InvalidMigratedConfigName) Error() string {
+	return fmt.Sprintf("Invalid value '%s' for --%s. It must be a file name without a folder, and not the name of a variable file Terraform loads automatically or of a %s file.", string(name), MIGRATE_CONFIG_OPT_CONFIG_NAME, config.OldTerragruntConfigPath)
+}
+
+type NoTerragruntBlockToMigrate string
+
+func (configPath NoTerragruntBlockToMigrate) Error() string {
+	return fmt.Sprintf("Cannot migrate %s, as it has no terragrunt = { ... } block", string(configPath))
+}
+
+type CannotMigrateOldConfigFormat string
+
+func (configPath CannotMigrateOldConfigFormat) Error() string {
+	return fmt.Sprintf("Cannot migrate %s, as it's in the deprecated %s format, which has no variables to separate the config from. Rename it to %s and wrap its contents in a terragrunt = { ... } block instead.", string(configPath), config.OldTerragruntConfigPath, config.DedicatedTerragruntConfigPath)
+}
+
+type ConfigAlreadyMigrated string
+
+func (configPath ConfigAlreadyMigrated) Error() string {
+	return fmt.Sprintf("Cannot migrate %s, as it's already a dedicated Terragrunt config file", string(configPath))
+}
+
+type MigratedConfigAlreadyExists struct {
+	ConfigPath    string
+	NewConfigPath string
+}
+
+func (err MigratedConfigAlreadyExists) Error() string {
+	return fmt.Sprintf("Cannot migrate %s, as %s already exists", err.ConfigPath, err.NewConfigPath)
+}
@@ -0,0 +1,288 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateConfigRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name              string
+		contents          string
+		expectedComments  []string
+		expectedRemaining string
+	}{
+		{
+			"block only",
+			`terragrunt = {
+  terraform {
+    source = "../modules//app"
+  }
+}
+`,
+			[]string{},
+			"",
+		},
+		{
+			"variables and comments",
+			`# The name of the app
+name = "app"
+
+# Where the Terraform code of the app lives
+terragrunt = {
+  terraform {
+    # Pinned to a local copy
+    source = "../modules//app"
+
+    extra_arguments "retry_lock" {
+      commands  = ["${get_terraform_commands_that_need_locking()}"]
+      arguments = ["-lock-timeout=20m"]
+    }
+  }
+
+  prevent_destroy = true
+} # end of the terragrunt block
+
+instance_count = 3
+tags = {
+  team = "platform"
+}
+`,
+			[]string{"# Where the Terraform code of the app lives", "# Pinned to a local copy", "# end of the terragrunt block"},
+			`# The name of the app
+name = "app"
+
+instance_count = 3
+tags = {
+  team = "platform"
+}
+`,
+		},
+		{
+			"windows line endings",
+			"name = \"app\"\r\nterragrunt = {\r\n  iam_role = \"arn:aws:iam::123456789012:role/app\"\r\n}\r\n",
+			[]string{},
+			"name = \"app\"\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tmpDir := writeMigrateConfigTestFiles(t, map[string]string{"app/terraform.tfvars": testCase.contents})
+		defer os.RemoveAll(tmpDir)
+
+		configPath := util.JoinPath(tmpDir, "app", config.DefaultTerragruntConfigPath)
+		before := resolveMigrateConfigTestModule(t, configPath)
+
+		require.NoError(t, migrateConfig(migrateConfigTestOptions(t, configPath)), "For %s", testCase.name)
+
+		newConfigPath := util.JoinPath(tmpDir, "app", config.DedicatedTerragruntConfigPath)
+		newContents, err := util.ReadFileAsString(newConfigPath)
+		require.NoError(t, err, "For %s", testCase.name)
+		for _, comment := range testCase.expectedComments {
+			assert.Contains(t, newContents, comment, "For %s", testCase.name)
+		}
+
+		if testCase.expectedRemaining == "" {
+			assert.False(t, util.FileExists(configPath), "For %s", testCase.name)
+		} else {
+			remaining, err := util.ReadFileAsString(configPath)
+			require.NoError(t, err, "For %s", testCase.name)
+			assert.Equal(t, testCase.expectedRemaining, remaining, "For %s", testCase.name)
+
+			variables := map[string]interface{}{}
+			require.NoError(t, hcl.Decode(&variables, remaining), "For %s", testCase.name)
+			assert.NotContains(t, variables, "terragrunt", "For %s", testCase.name)
+		}
+
+		assert.Equal(t, newConfigPath, config.DefaultConfigPath(util.JoinPath(tmpDir, "app")), "For %s", testCase.name)
+		assert.Equal(t, before, resolveMigrateConfigTestModule(t, newConfigPath), "For %s", testCase.name)
+	}
+}
+
+func TestMigrateConfigAllUpdatesIncludePaths(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"terraform.tfvars": `terragrunt = {
+  remote_state {
+    backend = "s3"
+    config {
+      bucket = "my-bucket"
+      key    = "${path_relative_to_include()}/terraform.tfstate"
+      region = "us-east-1"
+    }
+  }
+}
+`,
+		"default/terraform.tfvars": `terragrunt = {
+  include {
+    path = "${find_in_parent_folders()}"
+  }
+}
+
+name = "default"
+`,
+		"named/terraform.tfvars": `terragrunt = {
+  include {
+    path = "${find_in_parent_folders("terraform.tfvars")}"
+  }
+}
+`,
+		"relative/terraform.tfvars": `terragrunt = {
+  include {
+    path = "../terraform.tfvars"
+  }
+  dependencies {
+    paths = ["../default"]
+  }
+}
+`,
+	}
+	modules := []string{"default", "named", "relative"}
+
+	testCases := []struct {
+		configName           string
+		expectedIncludePaths map[string]string
+	}{
+		{
+			config.DedicatedTerragruntConfigPath,
+			map[string]string{
+				"default":  `"${find_in_parent_folders()}"`,
+				"named":    `"${find_in_parent_folders("terragrunt.hcl")}"`,
+				"relative": `"../terragrunt.hcl"`,
+			},
+		},
+		{
+			"tg.hcl",
+			map[string]string{
+				"default":  `"${find_in_parent_folders("tg.hcl")}"`,
+				"named":    `"${find_in_parent_folders("tg.hcl")}"`,
+				"relative": `"../tg.hcl"`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tmpDir := writeMigrateConfigTestFiles(t, files)
+		defer os.RemoveAll(tmpDir)
+
+		before := map[string]resolvedMigrateConfigTestModule{}
+		for _, module := range modules {
+			before[module] = resolveMigrateConfigTestModule(t, util.JoinPath(tmpDir, module, config.DefaultTerragruntConfigPath))
+		}
+
+		terragruntOptions := migrateConfigTestOptions(t, util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath), "--all", "--config-name", testCase.configName)
+		require.NoError(t, migrateConfig(terragruntOptions), "For %s", testCase.configName)
+
+		assert.True(t, util.FileExists(util.JoinPath(tmpDir, testCase.configName)), "For %s", testCase.configName)
+		assert.False(t, util.FileExists(util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath)), "For %s", testCase.configName)
+
+		for _, module := range modules {
+			newConfigPath := util.JoinPath(tmpDir, module, testCase.configName)
+			newContents, err := util.ReadFileAsString(newConfigPath)
+			require.NoError(t, err, "For %s in %s", testCase.configName, module)
+			assert.Contains(t, newContents, "path = "+testCase.expectedIncludePaths[module], "For %s in %s", testCase.configName, module)
+			assert.Equal(t, before[module], resolveMigrateConfigTestModule(t, newConfigPath), "For %s in %s", testCase.configName, module)
+		}
+	}
+}
+
+func TestMigrateConfigDryRun(t *testing.T) {
+	t.Parallel()
+
+	contents := "terragrunt = {\n  prevent_destroy = true\n}\n\nname = \"app\"\n"
+	tmpDir := writeMigrateConfigTestFiles(t, map[string]string{"app/terraform.tfvars": contents})
+	defer os.RemoveAll(tmpDir)
+
+	configPath := util.JoinPath(tmpDir, "app", config.DefaultTerragruntConfigPath)
+	require.NoError(t, migrateConfig(migrateConfigTestOptions(t, configPath, "--dry-run")))
+
+	actual, err := util.ReadFileAsString(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, contents, actual)
+	assert.False(t, util.FileExists(util.JoinPath(tmpDir, "app", config.DedicatedTerragruntConfigPath)))
+}
+
+func TestMigrateConfigErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		files         map[string]string
+		args          []string
+		expectedError error
+	}{
+		{"no terragrunt block", map[string]string{"terraform.tfvars": "name = \"app\"\n"}, []string{}, NoTerragruntBlockToMigrate("")},
+		{"already exists", map[string]string{"terraform.tfvars": "terragrunt = {}\n", "terragrunt.hcl": "terragrunt = {}\n"}, []string{}, MigratedConfigAlreadyExists{}},
+		{"tfvars name", map[string]string{"terraform.tfvars": "terragrunt = {}\n"}, []string{"--config-name", "terraform.tfvars"}, InvalidMigratedConfigName("")},
+		{"auto tfvars name", map[string]string{"terraform.tfvars": "terragrunt = {}\n"}, []string{"--config-name", "config.auto.tfvars"}, InvalidMigratedConfigName("")},
+		{"old format name", map[string]string{"terraform.tfvars": "terragrunt = {}\n"}, []string{"--config-name", ".terragrunt"}, InvalidMigratedConfigName("")},
+		{"name with folder", map[string]string{"terraform.tfvars": "terragrunt = {}\n"}, []string{"--config-name", "config/terragrunt.hcl"}, InvalidMigratedConfigName("")},
+	}
+
+	for _, testCase := range testCases {
+		tmpDir := writeMigrateConfigTestFiles(t, testCase.files)
+		defer os.RemoveAll(tmpDir)
+
+		err := migrateConfig(migrateConfigTestOptions(t, util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath), testCase.args...))
+		if assert.Error(t, err, "For %s", testCase.name) {
+			assert.IsType(t, testCase.expectedError, errors.Unwrap(err), "For %s", testCase.name)
+		}
+	}
+}
+
+// Write each of the given files, keyed by path, to a new temp folder, and return the path of that folder
+func writeMigrateConfigTestFiles(t *testing.T, files map[string]string) string {
+	tmpDir, err := ioutil.TempDir("", "terragrunt-migrate-config-test")
+	require.NoError(t, err)
+
+	for path, contents := range files {
+		fullPath := util.JoinPath(tmpDir, path)
+		require.NoError(t, os.MkdirAll(util.JoinPath(fullPath, ".."), 0755))
+		require.NoError(t, ioutil.WriteFile(fullPath, []byte(contents), 0644))
+	}
+	return tmpDir
+}
+
+func migrateConfigTestOptions(t *testing.T, configPath string, args ...string) *options.TerragruntOptions {
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(configPath)
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = append([]string{CMD_MIGRATE_CONFIG}, args...)
+	terragruntOptions.NonInteractive = true
+	return terragruntOptions
+}
+
+// The resolved config of a module and the variables Terraform receives in its folder, which must be the same before and
+// after migrating its config
+type resolvedMigrateConfigTestModule struct {
+	Config map[string]string
+	Inputs map[string]interface{}
+}
+
+func resolveMigrateConfigTestModule(t *testing.T, configPath string) resolvedMigrateConfigTestModule {
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(configPath)
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{"plan"}
+
+	terragruntConfig, err := config.ParseConfigFile(configPath, terragruntOptions, nil)
+	require.NoError(t, err)
+
+	snapshot, err := config.NewConfigSnapshot(terragruntConfig, terragruntOptions.SensitiveVarPatterns)
+	require.NoError(t, err)
+
+	inputs, err := config.ResolvedInputs(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+
+	return resolvedMigrateConfigTestModule{Config: snapshot.Config, Inputs: inputs}
+}
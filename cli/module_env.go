@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"path/filepath"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The env vars Terragrunt sets for hooks and Terraform, so they know which module and command they're running for
+const (
+	// The path of the module's folder, relative to the working dir of the Terragrunt command the user ran, e.g.
+	// "live/app" for the live/app module of an xxx-all command, or "." for a single module
+	ENV_TERRAGRUNT_MODULE_PATH = "TERRAGRUNT_MODULE_PATH"
+
+	// The absolute path of the module's Terragrunt config file
+	ENV_TERRAGRUNT_CONFIG_PATH = "TERRAGRUNT_CONFIG_PATH"
+
+	// The absolute path of the module's working dir before any Terraform code was downloaded. Hooks and Terraform
+	// themselves run in the download dir in that case.
+	ENV_TERRAGRUNT_ORIGINAL_WORKING_DIR = "TERRAGRUNT_ORIGINAL_WORKING_DIR"
+
+	// The Terraform command being run, e.g. "plan", even if the user ran plan-all
+	ENV_TERRAGRUNT_COMMAND = "TERRAGRUNT_COMMAND"
+
+	// The folder Terraform code is downloaded into
+	ENV_TERRAGRUNT_DOWNLOAD_DIR = "TERRAGRUNT_DOWNLOAD_DIR"
+
+	// A UUID that is unique to the Terragrunt command the user ran, shared by all modules of an xxx-all command
+	ENV_TERRAGRUNT_STACK_RUN_ID = "TERRAGRUNT_STACK_RUN_ID"
+)
+
+// Set the env vars that describe the module and command Terragrunt is running in the env of the given options, so
+// they're passed to hooks and Terraform. They're set the same way whether the module is run on its own or as part of
+// an xxx-all command.
+func setModuleEnvVars(terragruntOptions *options.TerragruntOptions) error {
+	envVars, err := moduleEnvVars(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range envVars {
+		terragruntOptions.Env[key] = value
+	}
+	return nil
+}
+
+// Return the env vars that describe the module and command Terragrunt is running. See setModuleEnvVars.
+func moduleEnvVars(terragruntOptions *options.TerragruntOptions) (map[string]string, error) {
+	configPath, err := filepath.Abs(terragruntOptions.TerragruntConfigPath)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	moduleDir := filepath.Dir(configPath)
+
+	// Options not created from the command line, such as in tests, may not have a root working dir
+	rootWorkingDir := terragruntOptions.RootWorkingDir
+	if rootWorkingDir == "" {
+		rootWorkingDir = moduleDir
+	}
+
+	modulePath, err := util.GetPathRelativeTo(moduleDir, rootWorkingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Options not created from the command line or cloned for a module, such as in tests, may not have one either
+	originalWorkingDir := terragruntOptions.OriginalWorkingDir
+	if originalWorkingDir == "" {
+		originalWorkingDir = terragruntOptions.WorkingDir
+	}
+	originalWorkingDir, err = filepath.Abs(originalWorkingDir)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	return map[string]string{
+		ENV_TERRAGRUNT_MODULE_PATH:          modulePath,
+		ENV_TERRAGRUNT_CONFIG_PATH:          filepath.ToSlash(configPath),
+		ENV_TERRAGRUNT_ORIGINAL_WORKING_DIR: filepath.ToSlash(originalWorkingDir),
+		ENV_TERRAGRUNT_COMMAND:              terragruntOptions.TerraformCommand,
+		ENV_TERRAGRUNT_DOWNLOAD_DIR:         filepath.ToSlash(terragruntOptions.DownloadDir),
+		ENV_TERRAGRUNT_STACK_RUN_ID:         terragruntOptions.StackRunId,
+	}, nil
+}
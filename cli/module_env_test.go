@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleEnvVars(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		configPath         string
+		rootWorkingDir     string
+		expectedModulePath string
+	}{
+		{"/root/live/app/" + config.DefaultTerragruntConfigPath, "/root/live/app", "."},
+		{"/root/live/app/" + config.DefaultTerragruntConfigPath, "/root/live", "app"},
+		{"/root/live/app/db/" + config.DefaultTerragruntConfigPath, "/root/live", "app/db"},
+		{"/root/live/app/" + config.DefaultTerragruntConfigPath, "", "."},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest(testCase.configPath)
+		require.NoError(t, err)
+		terragruntOptions.RootWorkingDir = testCase.rootWorkingDir
+		terragruntOptions.TerraformCommand = "plan"
+		terragruntOptions.DownloadDir = "/tmp/download"
+		terragruntOptions.StackRunId = "0b5c8c4e-9e0c-4f4e-8d6a-2f1c3e7a9b10"
+
+		expected := map[string]string{
+			ENV_TERRAGRUNT_MODULE_PATH:          testCase.expectedModulePath,
+			ENV_TERRAGRUNT_CONFIG_PATH:          testCase.configPath,
+			ENV_TERRAGRUNT_ORIGINAL_WORKING_DIR: filepath.Dir(testCase.configPath),
+			ENV_TERRAGRUNT_COMMAND:              "plan",
+			ENV_TERRAGRUNT_DOWNLOAD_DIR:         "/tmp/download",
+			ENV_TERRAGRUNT_STACK_RUN_ID:         "0b5c8c4e-9e0c-4f4e-8d6a-2f1c3e7a9b10",
+		}
+
+		actual, err := moduleEnvVars(terragruntOptions)
+		assert.NoError(t, err, "For config path %s and root working dir %s", testCase.configPath, testCase.rootWorkingDir)
+		assert.Equal(t, expected, actual, "For config path %s and root working dir %s", testCase.configPath, testCase.rootWorkingDir)
+	}
+}
+
+func TestModuleEnvVarsArePassedToHooks(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "module-env")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	// A hook script that dumps its env to the file passed as its only argument
+	dumpEnvScript := filepath.Join(tmpDir, "dump-env.sh")
+	require.NoError(t, ioutil.WriteFile(dumpEnvScript, []byte("#!/bin/sh\nenv > \"$1\"\n"), 0755))
+
+	stackRunId, err := util.NewUUID()
+	require.NoError(t, err)
+
+	// Mimic an xxx-all command, which clones the options of the command the user ran for each module
+	rootOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	rootOptions.RootWorkingDir = rootOptions.WorkingDir
+	rootOptions.StackRunId = stackRunId
+	rootOptions.TerraformCommand = "apply"
+	rootOptions.Env = map[string]string{"PATH": os.Getenv("PATH")}
+	rootOptions.Writer = &bytes.Buffer{}
+	rootOptions.ErrWriter = &bytes.Buffer{}
+
+	for _, modulePath := range []string{"app", "data/db"} {
+		configPath := util.JoinPath(tmpDir, modulePath, config.DefaultTerragruntConfigPath)
+		require.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0755))
+
+		terragruntOptions := rootOptions.Clone(configPath)
+		require.NoError(t, setModuleEnvVars(terragruntOptions))
+
+		envFile := util.JoinPath(tmpDir, strings.Replace(modulePath, "/", "-", -1)+".env")
+		hooks := []config.Hook{{Name: "dump_env", Commands: []string{"apply"}, Execute: []string{dumpEnvScript, envFile}}}
+		require.NoError(t, processHooks(hooks, terragruntOptions))
+
+		env := readEnvFile(t, envFile)
+		assert.Equal(t, modulePath, env[ENV_TERRAGRUNT_MODULE_PATH])
+		assert.Equal(t, filepath.ToSlash(configPath), env[ENV_TERRAGRUNT_CONFIG_PATH])
+		assert.Equal(t, filepath.ToSlash(filepath.Dir(configPath)), env[ENV_TERRAGRUNT_ORIGINAL_WORKING_DIR])
+		assert.Equal(t, "apply", env[ENV_TERRAGRUNT_COMMAND])
+		assert.Equal(t, terragruntOptions.DownloadDir, env[ENV_TERRAGRUNT_DOWNLOAD_DIR])
+		assert.Equal(t, stackRunId, env[ENV_TERRAGRUNT_STACK_RUN_ID])
+	}
+}
+
+// Parse a file written by the env command into a map of env var names to values
+func readEnvFile(t *testing.T, path string) map[string]string {
+	contents, err := util.ReadFileAsString(path)
+	require.NoError(t, err)
+
+	env := map[string]string{}
+	for _, line := range strings.Split(contents, "\n") {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	return env
+}
@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+// How a provider that is used without any version constraint shows up in the providers-report
+const UNCONSTRAINED_PROVIDER_VERSION = "(none)"
+
+// The data printed by the providers-report command
+type providersReport struct {
+	Path           string                   `json:"path"`
+	Providers      []providerReport         `json:"providers"`
+	SkippedModules []providersReportSkipped `json:"skipped_modules"`
+}
+
+// The version constraints used for a single provider across the stack. If the modules don't all use the same
+// constraint, the provider has a conflict.
+type providerReport struct {
+	Name        string                      `json:"name"`
+	Conflict    bool                        `json:"conflict"`
+	Constraints []providerVersionConstraint `json:"constraints"`
+}
+
+type providerVersionConstraint struct {
+	Constraint string   `json:"constraint"`
+	Modules    []string `json:"modules"`
+}
+
+// A module whose Terraform code couldn't be read, such as one whose remote source hasn't been downloaded yet
+type providersReportSkipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Print the version constraints of the providers used by the modules in the stack in the working dir, highlighting
+// providers whose constraints differ between modules. The constraints are read from the .tf files of each module, after
+// resolving its source, so no Terraform commands are run.
+func printProvidersReport(terragruntOptions *options.TerragruntOptions) error {
+	stack, err := configstack.FindStackInSubfolders(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	report, err := newProvidersReport(stack)
+	if err != nil {
+		return err
+	}
+
+	return writeStructuredOutput(terragruntOptions, report, func(writer io.Writer) {
+		fmt.Fprintf(writer, "Provider version constraints of the modules in %s:\n", report.Path)
+		for _, provider := range report.Providers {
+			if provider.Conflict {
+				fmt.Fprintf(writer, "  %s (CONFLICT: %d different constraints)\n", provider.Name, len(provider.Constraints))
+			} else {
+				fmt.Fprintf(writer, "  %s\n", provider.Name)
+			}
+			for _, constraint := range provider.Constraints {
+				fmt.Fprintf(writer, "    %s\n", constraint.Constraint)
+				for _, module := range constraint.Modules {
+					fmt.Fprintf(writer, "      %s\n", module)
+				}
+			}
+		}
+		if len(report.SkippedModules) > 0 {
+			fmt.Fprintf(writer, "Skipped modules:\n")
+			for _, skipped := range report.SkippedModules {
+				fmt.Fprintf(writer, "  %s: %s\n", skipped.Path, skipped.Reason)
+			}
+		}
+	})
+}
+
+func newProvidersReport(stack *configstack.Stack) (providersReport, error) {
+	report := providersReport{Path: stack.Path, Providers: []providerReport{}, SkippedModules: []providersReportSkipped{}}
+
+	// provider name -> constraint -> module paths
+	constraintsByProvider := map[string]map[string][]string{}
+
+	for _, module := range stack.Modules {
+		codeDir, err := terraformCodeDir(module.TerragruntOptions, &module.Config)
+		if err != nil {
+			return report, err
+		}
+		if !util.IsDir(codeDir) {
+			report.SkippedModules = append(report.SkippedModules, providersReportSkipped{
+				Path:   module.Path,
+				Reason: fmt.Sprintf("the folder with its Terraform code, %s, does not exist. If its source is remote, run 'terragrunt init' in it first to download it.", codeDir),
+			})
+			continue
+		}
+
+		constraints, err := readProviderVersionConstraints(codeDir)
+		if invalidFile, isInvalidFile := errors.Unwrap(err).(InvalidTerraformFile); isInvalidFile {
+			report.SkippedModules = append(report.SkippedModules, providersReportSkipped{Path: module.Path, Reason: invalidFile.Error()})
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		for provider, providerConstraints := range constraints {
+			if constraintsByProvider[provider] == nil {
+				constraintsByProvider[provider] = map[string][]string{}
+			}
+			for _, constraint := range providerConstraints {
+				constraintsByProvider[provider][constraint] = append(constraintsByProvider[provider][constraint], module.Path)
+			}
+		}
+	}
+
+	for provider, modulesByConstraint := range constraintsByProvider {
+		providerReport := providerReport{Name: provider, Conflict: len(modulesByConstraint) > 1, Constraints: []providerVersionConstraint{}}
+		for constraint, modules := range modulesByConstraint {
+			sort.Strings(modules)
+			providerReport.Constraints = append(providerReport.Constraints, providerVersionConstraint{Constraint: constraint, Modules: modules})
+		}
+		sort.Slice(providerReport.Constraints, func(i, j int) bool {
+			return providerReport.Constraints[i].Constraint < providerReport.Constraints[j].Constraint
+		})
+		report.Providers = append(report.Providers, providerReport)
+	}
+
+	sort.Slice(report.Providers, func(i, j int) bool { return report.Providers[i].Name < report.Providers[j].Name })
+	sort.Slice(report.SkippedModules, func(i, j int) bool { return report.SkippedModules[i].Path < report.SkippedModules[j].Path })
+
+	return report, nil
+}
+
+// Return the folder with the Terraform code of the module with the given options and config, without downloading
+// anything. That's the module's own folder if it has no source, the source folder itself if the source, which takes
+// --terragrunt-source into account, is a local path, and the folder in the download dir the source is downloaded to
+// otherwise.
+func terraformCodeDir(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) (string, error) {
+	sourceUrl := getTerraformSourceUrl(terragruntOptions, terragruntConfig)
+	if sourceUrl == "" {
+		return terragruntOptions.WorkingDir, nil
+	}
+
+	terraformSource, err := processTerraformSource(sourceUrl, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	if !isLocalSource(terraformSource.CanonicalSourceURL) {
+		return terraformSource.WorkingDir, nil
+	}
+
+	modulePath, err := util.GetPathRelativeTo(terraformSource.WorkingDir, terraformSource.DownloadDir)
+	if err != nil {
+		return "", err
+	}
+	return util.JoinPath(terraformSource.CanonicalSourceURL.Path, modulePath), nil
+}
+
+// Read the version constraints of the providers configured in the .tf files in the given folder, from both the version
+// field of provider blocks and the required_providers block of terraform blocks. Returns a map of provider name to the
+// constraints used for it. Providers that are configured without a version constraint get
+// UNCONSTRAINED_PROVIDER_VERSION, so they show up in the report too.
+func readProviderVersionConstraints(codeDir string) (map[string][]string, error) {
+	paths, err := filepath.Glob(filepath.Join(codeDir, "*.tf"))
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	sort.Strings(paths)
+
+	constraints := map[string][]string{}
+	addConstraint := func(provider string, constraint string) {
+		if constraint == "" {
+			constraint = UNCONSTRAINED_PROVIDER_VERSION
+		}
+		if !util.ListContainsElement(constraints[provider], constraint) {
+			constraints[provider] = append(constraints[provider], constraint)
+		}
+	}
+
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+
+		file, err := hcl.ParseBytes(contents)
+		if err != nil {
+			return nil, errors.WithStackTrace(InvalidTerraformFile{Path: path, Underlying: err})
+		}
+
+		root, isObjectList := file.Node.(*ast.ObjectList)
+		if !isObjectList {
+			continue
+		}
+
+		for _, provider := range root.Filter("provider").Items {
+			if len(provider.Keys) == 0 {
+				continue
+			}
+			addConstraint(hclKeyName(provider.Keys[0]), hclStringField(provider.Val, "version"))
+		}
+
+		for _, terraform := range root.Filter("terraform").Items {
+			terraformBlock, isObject := terraform.Val.(*ast.ObjectType)
+			if !isObject {
+				continue
+			}
+			for _, requiredProviders := range terraformBlock.List.Filter("required_providers").Items {
+				requiredProvidersBlock, isObject := requiredProviders.Val.(*ast.ObjectType)
+				if !isObject {
+					continue
+				}
+				for _, provider := range requiredProvidersBlock.List.Items {
+					if len(provider.Keys) == 0 {
+						continue
+					}
+					// Either aws = "~> 2.0" or aws = { version = "~> 2.0" }
+					constraint := hclStringValue(provider.Val)
+					if constraint == "" {
+						constraint = hclStringField(provider.Val, "version")
+					}
+					addConstraint(hclKeyName(provider.Keys[0]), constraint)
+				}
+			}
+		}
+	}
+
+	return constraints, nil
+}
+
+// Return the name of the given HCL key, without quotes
+func hclKeyName(key *ast.ObjectKey) string {
+	name := key.Token.Text
+	if unquoted, err := strconv.Unquote(name); err == nil {
+		return unquoted
+	}
+	return name
+}
+
+// Return the given node as a string, or an empty string if it isn't a string literal
+func hclStringValue(node ast.Node) string {
+	literal, isLiteral := node.(*ast.LiteralType)
+	if !isLiteral {
+		return ""
+	}
+	if value, isString := literal.Token.Value().(string); isString {
+		return strings.TrimSpace(value)
+	}
+	return ""
+}
+
+// Return the string field with the given name of the given HCL block, or an empty string if there is none
+func hclStringField(node ast.Node, name string) string {
+	block, isObject := node.(*ast.ObjectType)
+	if !isObject {
+		return ""
+	}
+	for _, item := range block.List.Filter(name).Items {
+		if value := hclStringValue(item.Val); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Custom error types
+
+type InvalidTerraformFile struct {
+	Path       string
+	Underlying error
+}
+
+func (err InvalidTerraformFile) Error() string {
+	return fmt.Sprintf("Unable to parse the Terraform file %s: %v", err.Path, err.Underlying)
+}
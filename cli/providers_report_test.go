@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The stack in the providers-report fixture
+const PROVIDERS_REPORT_FIXTURE = "../test/fixture-providers-report"
+
+func TestProvidersReport(t *testing.T) {
+	t.Parallel()
+
+	report, liveDir := providersReportForFixture(t, "")
+
+	assert.Equal(t, []providerReport{
+		{Name: "aws", Conflict: true, Constraints: []providerVersionConstraint{
+			{Constraint: "~> 1.30", Modules: []string{liveDir + "/app", liveDir + "/vpc"}},
+			{Constraint: "~> 1.40", Modules: []string{liveDir + "/db"}},
+		}},
+		{Name: "null", Constraints: []providerVersionConstraint{
+			{Constraint: UNCONSTRAINED_PROVIDER_VERSION, Modules: []string{liveDir + "/vpc"}},
+		}},
+		{Name: "random", Constraints: []providerVersionConstraint{
+			{Constraint: "~> 2.0", Modules: []string{liveDir + "/db"}},
+		}},
+		{Name: "template", Constraints: []providerVersionConstraint{
+			{Constraint: "~> 1.0", Modules: []string{liveDir + "/app"}},
+		}},
+	}, report.Providers)
+
+	if assert.Len(t, report.SkippedModules, 1) {
+		assert.Equal(t, liveDir+"/remote", report.SkippedModules[0].Path)
+		assert.Contains(t, report.SkippedModules[0].Reason, "terragrunt init")
+	}
+}
+
+func TestProvidersReportWithTerragruntSource(t *testing.T) {
+	t.Parallel()
+
+	altModulesDir, err := util.CanonicalPath(PROVIDERS_REPORT_FIXTURE+"/alt-modules", ".")
+	require.NoError(t, err)
+
+	report, liveDir := providersReportForFixture(t, altModulesDir)
+
+	// The local copy of the db module pins the same AWS provider version as the other modules
+	assert.Equal(t, []providerReport{
+		{Name: "aws", Constraints: []providerVersionConstraint{
+			{Constraint: "~> 1.30", Modules: []string{liveDir + "/app", liveDir + "/db", liveDir + "/vpc"}},
+		}},
+		{Name: "null", Constraints: []providerVersionConstraint{
+			{Constraint: UNCONSTRAINED_PROVIDER_VERSION, Modules: []string{liveDir + "/vpc"}},
+		}},
+		{Name: "random", Constraints: []providerVersionConstraint{
+			{Constraint: "~> 2.0", Modules: []string{liveDir + "/db"}},
+		}},
+		{Name: "template", Constraints: []providerVersionConstraint{
+			{Constraint: "~> 1.0", Modules: []string{liveDir + "/app"}},
+		}},
+	}, report.Providers)
+
+	if assert.Len(t, report.SkippedModules, 1) {
+		assert.Equal(t, liveDir+"/remote", report.SkippedModules[0].Path)
+		assert.Contains(t, report.SkippedModules[0].Reason, altModulesDir+"/remote")
+	}
+}
+
+func TestReadProviderVersionConstraints(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		codeDir  string
+		expected map[string][]string
+	}{
+		{PROVIDERS_REPORT_FIXTURE + "/live/app", map[string][]string{"aws": {"~> 1.30"}, "template": {"~> 1.0"}}},
+		{PROVIDERS_REPORT_FIXTURE + "/live/vpc", map[string][]string{"aws": {"~> 1.30"}, "null": {UNCONSTRAINED_PROVIDER_VERSION}}},
+		{PROVIDERS_REPORT_FIXTURE + "/modules/db", map[string][]string{"aws": {"~> 1.40"}, "random": {"~> 2.0"}}},
+		{PROVIDERS_REPORT_FIXTURE + "/alt-modules/db", map[string][]string{"aws": {"~> 1.30"}, "random": {"~> 2.0"}}},
+		{PROVIDERS_REPORT_FIXTURE + "/live/remote", map[string][]string{}},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := readProviderVersionConstraints(testCase.codeDir)
+		if assert.NoError(t, err, "For dir %s", testCase.codeDir) {
+			assert.Equal(t, testCase.expected, actual, "For dir %s", testCase.codeDir)
+		}
+	}
+}
+
+func TestProvidersReportJsonShape(t *testing.T) {
+	t.Parallel()
+
+	report, _ := providersReportForFixture(t, "")
+
+	expectedShape := []string{
+		"generated_at:string",
+		"path:string",
+		"providers:array",
+		"providers[].conflict:bool",
+		"providers[].constraints:array",
+		"providers[].constraints[].constraint:string",
+		"providers[].constraints[].modules:array",
+		"providers[].name:string",
+		"schema_version:number",
+		"skipped_modules:array",
+		"skipped_modules[].path:string",
+		"skipped_modules[].reason:string",
+	}
+
+	assertJsonShape(t, expectedShape, report)
+}
+
+// Create the providers-report for the live folder of the fixture, with the given --terragrunt-source, and return it
+// along with the canonical path of the live folder
+func providersReportForFixture(t *testing.T, terragruntSource string) (providersReport, string) {
+	liveDir, err := util.CanonicalPath(PROVIDERS_REPORT_FIXTURE+"/live", ".")
+	require.NoError(t, err)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(liveDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.Source = terragruntSource
+
+	stack, err := configstack.FindStackInSubfolders(terragruntOptions)
+	require.NoError(t, err)
+
+	report, err := newProvidersReport(stack)
+	require.NoError(t, err)
+
+	return report, liveDir
+}
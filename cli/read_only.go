@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// Make sure the download dir exists and can be written to. Terragrunt writes everything it needs to store, such as
+// downloaded Terraform code and config snapshots, into the download dir. By default, that's a folder next to the
+// Terragrunt config, but when running with a read-only file system (e.g. in a locked down container), we can't create
+// it there. In that case, fall back to a folder in the system temp dir, so Terragrunt works without any extra
+// configuration. If the user explicitly set the download dir, respect their choice and return an error instead.
+func ensureDownloadDirIsWritable(terragruntOptions *options.TerragruntOptions) error {
+	err := os.MkdirAll(terragruntOptions.DownloadDir, 0700)
+	if err == nil {
+		return nil
+	}
+	if !util.IsReadOnlyError(err) {
+		return errors.WithStackTrace(err)
+	}
+
+	_, defaultDownloadDir, defaultErr := options.DefaultWorkingAndDownloadDirs(terragruntOptions.TerragruntConfigPath)
+	if defaultErr != nil {
+		return defaultErr
+	}
+	if filepath.ToSlash(terragruntOptions.DownloadDir) != filepath.ToSlash(defaultDownloadDir) {
+		return errors.WithStackTrace(PathNotWritable{Path: terragruntOptions.DownloadDir, Hint: fmt.Sprintf("Point --%s or the TERRAGRUNT_DOWNLOAD environment variable at a writable folder.", OPT_DOWNLOAD_DIR), Underlying: err})
+	}
+
+	scratchDownloadDir, err := options.ScratchDownloadDir(terragruntOptions.TerragruntConfigPath)
+	if err != nil {
+		return err
+	}
+
+	terragruntOptions.Logger.Printf("Download dir %s is read-only, so using %s instead. Set --%s to choose another folder.", terragruntOptions.DownloadDir, scratchDownloadDir, OPT_DOWNLOAD_DIR)
+	if err := os.MkdirAll(scratchDownloadDir, 0700); err != nil {
+		return errors.WithStackTrace(PathNotWritable{Path: scratchDownloadDir, Hint: fmt.Sprintf("Point --%s or the TERRAGRUNT_DOWNLOAD environment variable at a writable folder.", OPT_DOWNLOAD_DIR), Underlying: err})
+	}
+
+	terragruntOptions.DownloadDir = filepath.ToSlash(scratchDownloadDir)
+	return nil
+}
+
+// Custom error types
+
+type PathNotWritable struct {
+	Path       string
+	Hint       string
+	Underlying error
+}
+
+func (err PathNotWritable) Error() string {
+	return fmt.Sprintf("Terragrunt needs to write to %s, but it is read-only (%v). %s", err.Path, err.Underlying, err.Hint)
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureDownloadDirIsWritableCreatesDefaultDownloadDir(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "download-dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	defaultDownloadDir := terragruntOptions.DownloadDir
+
+	require.NoError(t, ensureDownloadDirIsWritable(terragruntOptions))
+	assert.Equal(t, defaultDownloadDir, terragruntOptions.DownloadDir)
+	assert.True(t, util.IsDir(defaultDownloadDir))
+}
+
+func TestEnsureDownloadDirIsWritableReadOnlyConfigDir(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := createReadOnlyDir(t)
+	defer os.RemoveAll(tmpDir)
+	defer os.Chmod(tmpDir, 0755)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+
+	scratchDownloadDir, err := options.ScratchDownloadDir(terragruntOptions.TerragruntConfigPath)
+	require.NoError(t, err)
+	defer os.RemoveAll(scratchDownloadDir)
+
+	require.NoError(t, ensureDownloadDirIsWritable(terragruntOptions))
+	assert.Equal(t, filepath.ToSlash(scratchDownloadDir), terragruntOptions.DownloadDir)
+	assert.True(t, util.IsDir(scratchDownloadDir))
+	assert.False(t, util.FileExists(util.JoinPath(tmpDir, options.TerragruntCacheDir)))
+}
+
+func TestEnsureDownloadDirIsWritableReadOnlyCustomDownloadDir(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := createReadOnlyDir(t)
+	defer os.RemoveAll(tmpDir)
+	defer os.Chmod(tmpDir, 0755)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(tmpDir, "live", config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.DownloadDir = util.JoinPath(tmpDir, "cache")
+
+	err = ensureDownloadDirIsWritable(terragruntOptions)
+	if assert.Error(t, err) {
+		assert.IsType(t, PathNotWritable{}, errors.Unwrap(err))
+	}
+}
+
+// Create a temp dir and make it read-only. Skip the test if that's not possible, such as when running as root, which
+// can write to read-only folders.
+func createReadOnlyDir(t *testing.T) string {
+	tmpDir, err := ioutil.TempDir("", "read-only")
+	require.NoError(t, err)
+	require.NoError(t, os.Chmod(tmpDir, 0555))
+
+	if probe, err := ioutil.TempFile(tmpDir, "probe"); err == nil {
+		probe.Close()
+		os.Remove(probe.Name())
+		os.RemoveAll(tmpDir)
+		t.Skip("Unable to make a folder read-only, probably because the tests are running as root")
+	}
+
+	return tmpDir
+}
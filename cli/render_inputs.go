@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The Terraform command render-inputs shows the variables for, unless another one is passed to it, as in
+// "terragrunt render-inputs apply". It matters because extra_arguments only apply to the commands they list.
+const DEFAULT_RENDER_INPUTS_COMMAND = "plan"
+
+// The data printed by the render-inputs command
+type renderedInputs struct {
+	ConfigPath string                 `json:"config_path"`
+	Command    string                 `json:"command"`
+	Inputs     map[string]interface{} `json:"inputs"`
+}
+
+// The data printed by the render-inputs-all command
+type renderedInputsAll struct {
+	Path    string                 `json:"path"`
+	Command string                 `json:"command"`
+	Modules []renderedModuleInputs `json:"modules"`
+}
+
+type renderedModuleInputs struct {
+	Path   string                 `json:"path"`
+	Inputs map[string]interface{} `json:"inputs"`
+}
+
+// Print the values of the variables Terraform would receive for the current module, such as for a policy check. The
+// values of variables that look like secrets are masked, unless --terragrunt-include-sensitive is set.
+func printRenderedInputs(terragruntOptions *options.TerragruntOptions) error {
+	command, err := renderInputsCommand(terragruntOptions, CMD_RENDER_INPUTS)
+	if err != nil {
+		return err
+	}
+
+	terragruntConfig, err := config.ReadTerragruntConfig(terragruntOptions)
+	if err != nil {
+		return errors.WithClass(err, errors.ErrorClassConfig)
+	}
+
+	inputs, err := renderInputs(terragruntOptions, terragruntConfig, command)
+	if err != nil {
+		return err
+	}
+
+	rendered := renderedInputs{ConfigPath: terragruntOptions.TerragruntConfigPath, Command: command, Inputs: inputs}
+
+	return writeStructuredOutput(terragruntOptions, rendered, func(writer io.Writer) {
+		fmt.Fprintf(writer, "Inputs of %s for terraform %s:\n", rendered.ConfigPath, rendered.Command)
+		writeInputs(writer, rendered.Inputs, "  ")
+	})
+}
+
+// Print the values of the variables Terraform would receive for each module in the stack in the working dir. See
+// printRenderedInputs.
+func printRenderedInputsAll(terragruntOptions *options.TerragruntOptions) error {
+	command, err := renderInputsCommand(terragruntOptions, CMD_RENDER_INPUTS_ALL)
+	if err != nil {
+		return err
+	}
+
+	stack, err := configstack.FindStackInSubfolders(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	rendered, err := newRenderedInputsAll(stack, command)
+	if err != nil {
+		return err
+	}
+
+	return writeStructuredOutput(terragruntOptions, rendered, func(writer io.Writer) {
+		fmt.Fprintf(writer, "Inputs of the modules in %s for terraform %s:\n", rendered.Path, rendered.Command)
+		for _, module := range rendered.Modules {
+			fmt.Fprintf(writer, "  %s\n", module.Path)
+			writeInputs(writer, module.Inputs, "    ")
+		}
+	})
+}
+
+func newRenderedInputsAll(stack *configstack.Stack, command string) (renderedInputsAll, error) {
+	rendered := renderedInputsAll{Path: stack.Path, Command: command, Modules: []renderedModuleInputs{}}
+
+	for _, module := range stack.Modules {
+		inputs, err := renderInputs(module.TerragruntOptions, &module.Config, command)
+		if err != nil {
+			return rendered, err
+		}
+		rendered.Modules = append(rendered.Modules, renderedModuleInputs{Path: module.Path, Inputs: inputs})
+	}
+
+	sort.Slice(rendered.Modules, func(i, j int) bool { return rendered.Modules[i].Path < rendered.Modules[j].Path })
+
+	return rendered, nil
+}
+
+// Return the variables Terraform would receive when running the given command with the given options and config, with
+// secrets masked unless --terragrunt-include-sensitive is set
+func renderInputs(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig, command string) (map[string]interface{}, error) {
+	// Only the command differs from the options Terragrunt would run Terraform with, so a shallow copy is enough
+	commandOptions := *terragruntOptions
+	commandOptions.TerraformCliArgs = []string{command}
+
+	inputs, err := config.ResolvedInputs(&commandOptions, terragruntConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if terragruntOptions.IncludeSensitive {
+		return inputs, nil
+	}
+	return config.RedactSensitiveInputs(inputs, terragruntOptions.SensitiveVarPatterns), nil
+}
+
+// Return the Terraform command passed to the given render-inputs command, if any, or DEFAULT_RENDER_INPUTS_COMMAND
+func renderInputsCommand(terragruntOptions *options.TerragruntOptions, renderCommand string) (string, error) {
+	args := util.RemoveElementFromList(terragruntOptions.TerraformCliArgs, renderCommand)
+	switch len(args) {
+	case 0:
+		return DEFAULT_RENDER_INPUTS_COMMAND, nil
+	case 1:
+		return args[0], nil
+	default:
+		return "", errors.WithStackTrace(InvalidRenderInputsArgs{Command: renderCommand, Args: args})
+	}
+}
+
+// Write each of the given inputs on its own line, sorted by name, with its value encoded as JSON
+func writeInputs(writer io.Writer, inputs map[string]interface{}, indent string) {
+	names := []string{}
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// Unlike json.Marshal, an Encoder can leave characters such as < and > alone, which keeps values readable
+		var value bytes.Buffer
+		encoder := json.NewEncoder(&value)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(inputs[name]); err != nil {
+			value.Reset()
+			fmt.Fprintf(&value, "%v", inputs[name])
+		}
+		fmt.Fprintf(writer, "%s%s = %s\n", indent, name, strings.TrimSuffix(value.String(), "\n"))
+	}
+}
+
+// Custom error types
+
+type InvalidRenderInputsArgs struct {
+	Command string
+	Args    []string
+}
+
+func (err InvalidRenderInputsArgs) Error() string {
+	return fmt.Sprintf("%s takes at most one argument, the Terraform command to show the inputs for, such as 'apply', but got %v", err.Command, err.Args)
+}
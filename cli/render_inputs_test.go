@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A stack with interpolations, includes, and env-derived values. The expected folder has the inputs of its modules.
+const RENDER_INPUTS_FIXTURE = "../test/fixture-render-inputs"
+
+func TestRenderInputsAll(t *testing.T) {
+	t.Parallel()
+
+	rendered, liveDir := renderedInputsAllForFixture(t, false)
+
+	assert.Equal(t, "apply", rendered.Command)
+	if assert.Len(t, rendered.Modules, 2) {
+		assert.Equal(t, liveDir+"/app", rendered.Modules[0].Path)
+		assertInputsEqualGolden(t, "app-plan.json", true, rendered.Modules[0].Inputs)
+		assert.Equal(t, config.RedactedValue, rendered.Modules[0].Inputs["api_token"])
+
+		assert.Equal(t, liveDir+"/db", rendered.Modules[1].Path)
+		assertInputsEqualGolden(t, "db-apply.json", true, rendered.Modules[1].Inputs)
+		assert.Equal(t, config.RedactedValue, rendered.Modules[1].Inputs["db_password"])
+	}
+}
+
+func TestRenderInputsAllIncludeSensitive(t *testing.T) {
+	t.Parallel()
+
+	rendered, _ := renderedInputsAllForFixture(t, true)
+
+	if assert.Len(t, rendered.Modules, 2) {
+		assertInputsEqualGolden(t, "app-plan.json", false, rendered.Modules[0].Inputs)
+		assertInputsEqualGolden(t, "db-apply.json", false, rendered.Modules[1].Inputs)
+	}
+}
+
+func TestPrintRenderedInputs(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := renderInputsOptionsForTest(t, RENDER_INPUTS_FIXTURE+"/live/app/"+config.DefaultTerragruntConfigPath)
+	terragruntOptions.TerraformCliArgs = []string{CMD_RENDER_INPUTS, "output"}
+
+	var out bytes.Buffer
+	terragruntOptions.Writer = &out
+
+	require.NoError(t, printRenderedInputs(terragruntOptions))
+
+	expected := "Inputs of " + terragruntOptions.TerragruntConfigPath + " for terraform output:\n" +
+		"  api_token = \"<redacted>\"\n" +
+		"  environment = \"dev\"\n" +
+		"  instance_type = \"t3.small\"\n" +
+		"  name = \"app\"\n" +
+		"  owner = \"alice\"\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestRenderInputsCommand(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		args          []string
+		expected      string
+		expectedError error
+	}{
+		{[]string{CMD_RENDER_INPUTS}, DEFAULT_RENDER_INPUTS_COMMAND, nil},
+		{[]string{CMD_RENDER_INPUTS, "apply"}, "apply", nil},
+		{[]string{"destroy", CMD_RENDER_INPUTS}, "destroy", nil},
+		{[]string{CMD_RENDER_INPUTS, "apply", "plan"}, "", InvalidRenderInputsArgs{}},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+		require.NoError(t, err)
+		terragruntOptions.TerraformCliArgs = testCase.args
+
+		actual, err := renderInputsCommand(terragruntOptions, CMD_RENDER_INPUTS)
+		if testCase.expectedError != nil {
+			if assert.Error(t, err, "For args %v", testCase.args) {
+				assert.IsType(t, testCase.expectedError, errors.Unwrap(err), "For args %v", testCase.args)
+			}
+		} else {
+			assert.NoError(t, err, "For args %v", testCase.args)
+			assert.Equal(t, testCase.expected, actual, "For args %v", testCase.args)
+		}
+	}
+}
+
+func TestRenderInputsAllJsonShape(t *testing.T) {
+	t.Parallel()
+
+	rendered, _ := renderedInputsAllForFixture(t, false)
+
+	expectedShape := []string{
+		"command:string",
+		"generated_at:string",
+		"modules:array",
+		"modules[].inputs:object",
+		"modules[].path:string",
+		"path:string",
+		"schema_version:number",
+	}
+
+	assertJsonShape(t, expectedShape, rendered)
+}
+
+// Render the inputs of the modules in the live folder of the fixture for terraform apply, and return them along with
+// the canonical path of the live folder
+func renderedInputsAllForFixture(t *testing.T, includeSensitive bool) (renderedInputsAll, string) {
+	liveDir, err := util.CanonicalPath(RENDER_INPUTS_FIXTURE+"/live", ".")
+	require.NoError(t, err)
+
+	terragruntOptions := renderInputsOptionsForTest(t, util.JoinPath(liveDir, config.DefaultTerragruntConfigPath))
+	terragruntOptions.IncludeSensitive = includeSensitive
+
+	stack, err := configstack.FindStackInSubfolders(terragruntOptions)
+	require.NoError(t, err)
+
+	rendered, err := newRenderedInputsAll(stack, "apply")
+	require.NoError(t, err)
+
+	return rendered, liveDir
+}
+
+// Create options for the given config path with the env the golden files in the fixture were rendered with
+func renderInputsOptionsForTest(t *testing.T, configPath string) *options.TerragruntOptions {
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(configPath)
+	require.NoError(t, err)
+
+	terragruntOptions.Env = map[string]string{
+		"TG_RENDER_INPUTS_REGION": "eu-west-1",
+		"TF_VAR_owner":            "alice",
+		"TF_VAR_name":             "from-env",
+	}
+	return terragruntOptions
+}
+
+// Assert that the given inputs match the given golden file in the fixture, with its secrets masked if redacted is set
+func assertInputsEqualGolden(t *testing.T, goldenFile string, redacted bool, actual map[string]interface{}) {
+	golden := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(readFile(t, RENDER_INPUTS_FIXTURE+"/expected/"+goldenFile)), &golden))
+	if redacted {
+		golden = config.RedactSensitiveInputs(golden, nil)
+	}
+
+	expected, err := json.Marshal(golden)
+	require.NoError(t, err)
+	actualJson, err := json.Marshal(actual)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, string(expected), string(actualJson), "For golden file %s", goldenFile)
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/hashicorp/go-version"
+)
+
+// The URL queried for the latest Terragrunt release if --terragrunt-version-check-url isn't set
+const DEFAULT_VERSION_CHECK_URL = "https://api.github.com/repos/gruntwork-io/terragrunt/releases/latest"
+
+// Where to send users to download a newer release if the release info doesn't include a link
+const DEFAULT_RELEASES_URL = "https://github.com/gruntwork-io/terragrunt/releases"
+
+// Check for a newer release at most this often, no matter how many times Terragrunt runs in the meantime
+const UPDATE_CHECK_INTERVAL = 24 * time.Hour
+
+// Give up on the update check after this long, so a slow or unreachable server never holds up Terragrunt
+const UPDATE_CHECK_TIMEOUT = 2 * time.Second
+
+// The file, in the user's cache dir, that records when we last checked for a newer release
+const UPDATE_CHECK_TIMESTAMP_FILE = "last-update-check"
+
+// The subset of the GitHub release info we care about
+type latestRelease struct {
+	TagName string `json:"tag_name"`
+	HtmlUrl string `json:"html_url"`
+}
+
+// If the user opted in with --terragrunt-check-for-updates, check in the background whether a newer version of
+// Terragrunt has been released. Returns a channel that receives a notice to show the user, or an empty string if
+// there's nothing to show, or nil if the check is disabled. The check only sends the server a plain GET request, and
+// never fails the run: any error is logged only if TERRAGRUNT_DEBUG is set.
+func startUpdateCheck(currentVersion string, terragruntOptions *options.TerragruntOptions) <-chan string {
+	if !terragruntOptions.CheckForUpdates {
+		return nil
+	}
+
+	notices := make(chan string, 1)
+
+	go func() {
+		defer close(notices)
+
+		timestampPath, err := updateCheckTimestampPath()
+		if err != nil {
+			logUpdateCheckError(err, terragruntOptions)
+			return
+		}
+
+		notice, err := checkForUpdate(currentVersion, versionCheckUrl(terragruntOptions), timestampPath, time.Now())
+		if err != nil {
+			logUpdateCheckError(err, terragruntOptions)
+			return
+		}
+		notices <- notice
+	}()
+
+	return notices
+}
+
+// Wait for the update check started by startUpdateCheck, which takes at most UPDATE_CHECK_TIMEOUT, and print its
+// notice, if any
+func printUpdateNotice(notices <-chan string, terragruntOptions *options.TerragruntOptions) {
+	if notices == nil {
+		return
+	}
+
+	if notice := <-notices; notice != "" {
+		terragruntOptions.Logger.Println(notice)
+	}
+}
+
+// Return the URL to query for the latest release
+func versionCheckUrl(terragruntOptions *options.TerragruntOptions) string {
+	if terragruntOptions.VersionCheckUrl != "" {
+		return terragruntOptions.VersionCheckUrl
+	}
+	return DEFAULT_VERSION_CHECK_URL
+}
+
+// Return the path of the file that records when we last checked for a newer release
+func updateCheckTimestampPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	return filepath.Join(cacheDir, "terragrunt", UPDATE_CHECK_TIMESTAMP_FILE), nil
+}
+
+// Query the given URL for the latest release and return a one line notice if it's newer than the current version. If
+// the last check, as recorded in the file at timestampPath, was less than UPDATE_CHECK_INTERVAL ago, don't query the
+// URL at all and return an empty string.
+func checkForUpdate(currentVersion string, url string, timestampPath string, now time.Time) (string, error) {
+	current, err := version.NewVersion(currentVersion)
+	if err != nil {
+		return "", errors.WithStackTrace(InvalidTerragruntVersion(currentVersion))
+	}
+
+	if !isUpdateCheckDue(timestampPath, now) {
+		return "", nil
+	}
+
+	// Record the check before making it, so an unreachable server is also only tried once per interval
+	if err := writeUpdateCheckTimestamp(timestampPath, now); err != nil {
+		return "", err
+	}
+
+	release, err := fetchLatestRelease(url)
+	if err != nil {
+		return "", err
+	}
+
+	latest, err := version.NewVersion(release.TagName)
+	if err != nil {
+		return "", errors.WithStackTrace(InvalidLatestRelease{Url: url, TagName: release.TagName})
+	}
+
+	if !latest.GreaterThan(current) {
+		return "", nil
+	}
+
+	releaseUrl := release.HtmlUrl
+	if releaseUrl == "" {
+		releaseUrl = DEFAULT_RELEASES_URL
+	}
+
+	return fmt.Sprintf("A newer version of Terragrunt is available: %s (you are running %s). See %s", release.TagName, currentVersion, releaseUrl), nil
+}
+
+// Returns true if there's no record of a previous update check, or the last one was at least UPDATE_CHECK_INTERVAL
+// before the given time. A timestamp that can't be read or is in the future, e.g. after the clock was changed, is
+// treated as no record at all.
+func isUpdateCheckDue(timestampPath string, now time.Time) bool {
+	contents, err := ioutil.ReadFile(timestampPath)
+	if err != nil {
+		return true
+	}
+
+	lastCheck, err := time.Parse(time.RFC3339, strings.TrimSpace(string(contents)))
+	if err != nil || lastCheck.After(now) {
+		return true
+	}
+
+	return now.Sub(lastCheck) >= UPDATE_CHECK_INTERVAL
+}
+
+func writeUpdateCheckTimestamp(timestampPath string, now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(timestampPath), 0700); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(timestampPath, []byte(now.UTC().Format(time.RFC3339)), 0600))
+}
+
+func fetchLatestRelease(url string) (*latestRelease, error) {
+	client := http.Client{Timeout: UPDATE_CHECK_TIMEOUT}
+
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.WithStackTrace(UpdateCheckFailed{Url: url, StatusCode: response.StatusCode})
+	}
+
+	release := &latestRelease{}
+	if err := json.NewDecoder(response.Body).Decode(release); err != nil {
+		return nil, errors.WithStackTrace(InvalidLatestRelease{Url: url})
+	}
+
+	return release, nil
+}
+
+func logUpdateCheckError(err error, terragruntOptions *options.TerragruntOptions) {
+	if terragruntOptions.Env["TERRAGRUNT_DEBUG"] != "" {
+		terragruntOptions.Logger.Printf("Unable to check for a newer version of Terragrunt: %v", err)
+	}
+}
+
+// Custom error types
+
+type InvalidTerragruntVersion string
+
+func (err InvalidTerragruntVersion) Error() string {
+	return fmt.Sprintf("The version of this Terragrunt binary, '%s', is not a valid version, so it can't be compared to the latest release. This is normal for development builds.", string(err))
+}
+
+type UpdateCheckFailed struct {
+	Url        string
+	StatusCode int
+}
+
+func (err UpdateCheckFailed) Error() string {
+	return fmt.Sprintf("Got status code %d from %s", err.StatusCode, err.Url)
+}
+
+type InvalidLatestRelease struct {
+	Url     string
+	TagName string
+}
+
+func (err InvalidLatestRelease) Error() string {
+	return fmt.Sprintf("The release info returned by %s does not contain a valid version in its tag_name field: '%s'", err.Url, err.TagName)
+}
@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsUpdateCheckDue(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "update-check")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	now := time.Date(2018, 3, 14, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		timestamp string
+		expected  bool
+	}{
+		{"missing", "", true},
+		{"corrupt", "not a timestamp", true},
+		{"just-checked", now.Format(time.RFC3339), false},
+		{"checked-an-hour-ago", now.Add(-1 * time.Hour).Format(time.RFC3339), false},
+		{"checked-almost-a-day-ago", now.Add(-UPDATE_CHECK_INTERVAL + time.Minute).Format(time.RFC3339), false},
+		{"checked-a-day-ago", now.Add(-UPDATE_CHECK_INTERVAL).Format(time.RFC3339), true},
+		{"checked-a-week-ago", now.Add(-7 * UPDATE_CHECK_INTERVAL).Format(time.RFC3339), true},
+		{"checked-in-the-future", now.Add(time.Hour).Format(time.RFC3339), true},
+	}
+
+	for _, testCase := range testCases {
+		timestampPath := filepath.Join(tmpDir, testCase.name)
+		if testCase.timestamp != "" {
+			require.NoError(t, ioutil.WriteFile(timestampPath, []byte(testCase.timestamp+"\n"), 0600))
+		}
+
+		assert.Equal(t, testCase.expected, isUpdateCheckDue(timestampPath, now), "For case %s", testCase.name)
+	}
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	t.Parallel()
+
+	server, requests := mockReleaseServer(t, http.StatusOK, `{"tag_name": "v0.14.2", "html_url": "https://example.com/releases/v0.14.2"}`)
+	defer server.Close()
+
+	testCases := []struct {
+		currentVersion string
+		expected       string
+	}{
+		{"v0.14.0", "A newer version of Terragrunt is available: v0.14.2 (you are running v0.14.0). See https://example.com/releases/v0.14.2"},
+		{"0.13.25", "A newer version of Terragrunt is available: v0.14.2 (you are running 0.13.25). See https://example.com/releases/v0.14.2"},
+		{"v0.14.2", ""},
+		{"v0.15.0", ""},
+	}
+
+	for _, testCase := range testCases {
+		tmpDir, err := ioutil.TempDir("", "update-check")
+		require.NoError(t, err)
+		defer os.RemoveAll(tmpDir)
+
+		timestampPath := filepath.Join(tmpDir, "nested", UPDATE_CHECK_TIMESTAMP_FILE)
+
+		notice, err := checkForUpdate(testCase.currentVersion, server.URL, timestampPath, time.Now())
+		assert.NoError(t, err, "For version %s", testCase.currentVersion)
+		assert.Equal(t, testCase.expected, notice, "For version %s", testCase.currentVersion)
+		assert.True(t, util.FileExists(timestampPath), "For version %s", testCase.currentVersion)
+	}
+
+	assert.Equal(t, int32(len(testCases)), atomic.LoadInt32(requests))
+}
+
+func TestCheckForUpdateAtMostOncePerInterval(t *testing.T) {
+	t.Parallel()
+
+	server, requests := mockReleaseServer(t, http.StatusOK, `{"tag_name": "v0.14.2"}`)
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "update-check")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	timestampPath := filepath.Join(tmpDir, UPDATE_CHECK_TIMESTAMP_FILE)
+	now := time.Now()
+
+	notice, err := checkForUpdate("v0.14.0", server.URL, timestampPath, now)
+	assert.NoError(t, err)
+	assert.Contains(t, notice, DEFAULT_RELEASES_URL)
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+
+	notice, err = checkForUpdate("v0.14.0", server.URL, timestampPath, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, "", notice)
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+
+	notice, err = checkForUpdate("v0.14.0", server.URL, timestampPath, now.Add(UPDATE_CHECK_INTERVAL))
+	assert.NoError(t, err)
+	assert.Contains(t, notice, "v0.14.2")
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+}
+
+func TestCheckForUpdateErrors(t *testing.T) {
+	t.Parallel()
+
+	serverError, _ := mockReleaseServer(t, http.StatusInternalServerError, `{"message": "oops"}`)
+	defer serverError.Close()
+
+	notJson, _ := mockReleaseServer(t, http.StatusOK, `<html></html>`)
+	defer notJson.Close()
+
+	invalidTag, _ := mockReleaseServer(t, http.StatusOK, `{"tag_name": "latest"}`)
+	defer invalidTag.Close()
+
+	testCases := []struct {
+		currentVersion string
+		url            string
+		expectedErr    error
+	}{
+		{"", serverError.URL, InvalidTerragruntVersion("")},
+		{"v0.14.0", serverError.URL, UpdateCheckFailed{}},
+		{"v0.14.0", notJson.URL, InvalidLatestRelease{}},
+		{"v0.14.0", invalidTag.URL, InvalidLatestRelease{}},
+	}
+
+	for _, testCase := range testCases {
+		tmpDir, err := ioutil.TempDir("", "update-check")
+		require.NoError(t, err)
+		defer os.RemoveAll(tmpDir)
+
+		notice, err := checkForUpdate(testCase.currentVersion, testCase.url, filepath.Join(tmpDir, UPDATE_CHECK_TIMESTAMP_FILE), time.Now())
+		assert.Equal(t, "", notice, "For url %s", testCase.url)
+		if assert.Error(t, err, "For url %s", testCase.url) {
+			assert.IsType(t, testCase.expectedErr, errors.Unwrap(err), "For url %s", testCase.url)
+		}
+	}
+}
+
+func TestCheckForUpdateUnreachableServer(t *testing.T) {
+	t.Parallel()
+
+	server, _ := mockReleaseServer(t, http.StatusOK, `{"tag_name": "v0.14.2"}`)
+	url := server.URL
+	server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "update-check")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	timestampPath := filepath.Join(tmpDir, UPDATE_CHECK_TIMESTAMP_FILE)
+
+	notice, err := checkForUpdate("v0.14.0", url, timestampPath, time.Now())
+	assert.Error(t, err)
+	assert.Equal(t, "", notice)
+
+	// A failed check still counts, so we don't retry an unreachable server on every run
+	assert.False(t, isUpdateCheckDue(timestampPath, time.Now()))
+}
+
+func TestStartUpdateCheckDisabledByDefault(t *testing.T) {
+	t.Parallel()
+
+	server, requests := mockReleaseServer(t, http.StatusOK, `{"tag_name": "v0.14.2"}`)
+	defer server.Close()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("update-check-test")
+	require.NoError(t, err)
+	terragruntOptions.VersionCheckUrl = server.URL
+
+	var logs bytes.Buffer
+	terragruntOptions.Logger = util.CreateLoggerWithWriter(&logs, "")
+
+	notices := startUpdateCheck("v0.14.0", terragruntOptions)
+	assert.Nil(t, notices)
+
+	printUpdateNotice(notices, terragruntOptions)
+	assert.Equal(t, "", logs.String())
+	assert.Equal(t, int32(0), atomic.LoadInt32(requests))
+}
+
+func TestVersionCheckUrl(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("update-check-test")
+	require.NoError(t, err)
+	assert.Equal(t, DEFAULT_VERSION_CHECK_URL, versionCheckUrl(terragruntOptions))
+
+	terragruntOptions.VersionCheckUrl = "https://example.com/latest"
+	assert.Equal(t, "https://example.com/latest", versionCheckUrl(terragruntOptions))
+}
+
+// Start an HTTP server that responds to every request with the given status code and body. Returns the server and a
+// counter of the requests it received.
+func mockReleaseServer(t *testing.T, statusCode int, body string) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		writer.WriteHeader(statusCode)
+		fmt.Fprint(writer, body)
+	}))
+	return server, &requests
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// The Terragrunt CLI flags get_terragrunt_cli_flag knows about, each with a function that returns the value of the flag
+// as resolved in the TerragruntOptions, so env var fallbacks and defaults are taken into account. Boolean flags are
+// returned as "true" or "false", and string flags verbatim, or as an empty string if they're unset. Flags that take an
+// optional list of names are returned as "true" if they're passed on their own, and otherwise as the names, comma
+// separated. The names must match the ALL_TERRAGRUNT_BOOLEAN_OPTS, ALL_TERRAGRUNT_STRING_OPTS, and
+// ALL_TERRAGRUNT_OPTIONAL_LIST_OPTS of the cli package, which this package can't import.
+var TERRAGRUNT_CLI_FLAGS = map[string]func(terragruntOptions *options.TerragruntOptions) string{
+	"terragrunt-config":                     func(opts *options.TerragruntOptions) string { return opts.TerragruntConfigPath },
+	"terragrunt-tfpath":                     func(opts *options.TerragruntOptions) string { return opts.TerraformPath },
+	"terragrunt-no-auto-init":               func(opts *options.TerragruntOptions) string { return strconv.FormatBool(!opts.AutoInit) },
+	"terragrunt-no-auto-retry":              func(opts *options.TerragruntOptions) string { return strconv.FormatBool(!opts.AutoRetry) },
+	"terragrunt-non-interactive":            func(opts *options.TerragruntOptions) string { return strconv.FormatBool(opts.NonInteractive) },
+	"terragrunt-working-dir":                rootWorkingDir,
+	"terragrunt-download-dir":               func(opts *options.TerragruntOptions) string { return opts.DownloadDir },
+	"terragrunt-source":                     func(opts *options.TerragruntOptions) string { return opts.Source },
+	"terragrunt-source-update":              func(opts *options.TerragruntOptions) string { return strconv.FormatBool(opts.SourceUpdate) },
+	"terragrunt-iam-role":                   func(opts *options.TerragruntOptions) string { return opts.IamRole },
+	"terragrunt-ignore-dependency-errors":   func(opts *options.TerragruntOptions) string { return strconv.FormatBool(opts.IgnoreDependencyErrors) },
+	"terragrunt-exclude-dir":                func(opts *options.TerragruntOptions) string { return strings.Join(opts.ExcludeDirs, ",") },
+	"terragrunt-include-dir":                func(opts *options.TerragruntOptions) string { return strings.Join(opts.IncludeDirs, ",") },
+	"terragrunt-track-config-changes":       func(opts *options.TerragruntOptions) string { return strconv.FormatBool(opts.TrackConfigChanges) },
+	"terragrunt-show-config-diff":           func(opts *options.TerragruntOptions) string { return strconv.FormatBool(opts.ShowConfigDiff) },
+	"terragrunt-json-out":                   func(opts *options.TerragruntOptions) string { return opts.JsonOut },
+	"terragrunt-status-port":                statusPort,
+	"terragrunt-status-bind-address":        func(opts *options.TerragruntOptions) string { return opts.StatusBindAddress },
+	"terragrunt-check-for-updates":          func(opts *options.TerragruntOptions) string { return strconv.FormatBool(opts.CheckForUpdates) },
+	"terragrunt-version-check-url":          func(opts *options.TerragruntOptions) string { return opts.VersionCheckUrl },
+	"terragrunt-stagger":                    stagger,
+	"terragrunt-max-starts-per-minute":      maxStartsPerMinute,
+	"terragrunt-strict":                     func(opts *options.TerragruntOptions) string { return strconv.FormatBool(opts.Strict) },
+	"terragrunt-fail-on-empty":              func(opts *options.TerragruntOptions) string { return strconv.FormatBool(opts.FailOnEmpty) },
+	"terragrunt-include-sensitive":          func(opts *options.TerragruntOptions) string { return strconv.FormatBool(opts.IncludeSensitive) },
+	"terragrunt-resume":                     func(opts *options.TerragruntOptions) string { return opts.Resume },
+	"terragrunt-http-timeout":               func(opts *options.TerragruntOptions) string { return opts.HttpTimeout.String() },
+	"terragrunt-sensitive-var":              sensitiveVarPatterns,
+	"terragrunt-include-dependencies":       func(opts *options.TerragruntOptions) string { return strconv.FormatBool(opts.IncludeDependencies) },
+	"terragrunt-include-dependencies-depth": includeDependenciesDepth,
+	"terragrunt-log-helper-metrics":         func(opts *options.TerragruntOptions) string { return strconv.FormatBool(opts.LogHelperMetrics) },
+	"terragrunt-disable-extra-args": func(opts *options.TerragruntOptions) string {
+		return optionalList(opts.DisableExtraArgs, opts.DisabledExtraArgs)
+	},
+	"terragrunt-disable-hooks": func(opts *options.TerragruntOptions) string {
+		return optionalList(opts.DisableHooks, opts.DisabledHooks)
+	},
+}
+
+// Return the value of the given Terragrunt CLI flag, such as terragrunt-source-update
+func getTerragruntCliFlag(parameters string, terragruntOptions *options.TerragruntOptions) (string, error) {
+	name, err := parseOneQuotedParam(parameters)
+	if err != nil {
+		return "", err
+	}
+
+	// Allow the flag to be passed as it's written on the command line, too
+	name = strings.TrimPrefix(name, "--")
+
+	flagValue, isKnownFlag := TERRAGRUNT_CLI_FLAGS[name]
+	if !isKnownFlag {
+		return "", errors.WithStackTrace(UnknownTerragruntCliFlag(name))
+	}
+
+	return flagValue(terragruntOptions), nil
+}
+
+// The working dir of the xxx-all command the user ran, rather than that of the current module
+func rootWorkingDir(terragruntOptions *options.TerragruntOptions) string {
+	if terragruntOptions.RootWorkingDir != "" {
+		return terragruntOptions.RootWorkingDir
+	}
+	return terragruntOptions.WorkingDir
+}
+
+func statusPort(terragruntOptions *options.TerragruntOptions) string {
+	if terragruntOptions.StatusPort == 0 {
+		return ""
+	}
+	return strconv.Itoa(terragruntOptions.StatusPort)
+}
+
+func stagger(terragruntOptions *options.TerragruntOptions) string {
+	if terragruntOptions.Stagger == 0 {
+		return ""
+	}
+	return terragruntOptions.Stagger.String()
+}
+
+func maxStartsPerMinute(terragruntOptions *options.TerragruntOptions) string {
+	if terragruntOptions.MaxStartsPerMinute == 0 {
+		return ""
+	}
+	return strconv.Itoa(terragruntOptions.MaxStartsPerMinute)
+}
+
+func includeDependenciesDepth(terragruntOptions *options.TerragruntOptions) string {
+	if terragruntOptions.IncludeDependenciesDepth == 0 {
+		return ""
+	}
+	return strconv.Itoa(terragruntOptions.IncludeDependenciesDepth)
+}
+
+// The value of a flag that may be passed on its own or followed by a list of names, such as terragrunt-disable-hooks
+func optionalList(isPassedOnItsOwn bool, names []string) string {
+	if isPassedOnItsOwn {
+		return "true"
+	}
+	return strings.Join(names, ",")
+}
+
+// Return the names of all the flags get_terragrunt_cli_flag knows about, sorted
+func terragruntCliFlagNames() []string {
+	names := []string{}
+	for name := range TERRAGRUNT_CLI_FLAGS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Custom error types
+
+type UnknownTerragruntCliFlag string
+
+func (err UnknownTerragruntCliFlag) Error() string {
+	return fmt.Sprintf("Unknown Terragrunt CLI flag '%s' passed to get_terragrunt_cli_flag. Valid flags are: %s", string(err), strings.Join(terragruntCliFlagNames(), ", "))
+}
+
+func sensitiveVarPatterns(terragruntOptions *options.TerragruntOptions) string {
+	patterns := []string{}
+	for _, pattern := range terragruntOptions.SensitiveVarPatterns {
+		patterns = append(patterns, pattern.String())
+	}
+	return strings.Join(patterns, ",")
+}
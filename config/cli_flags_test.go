@@ -0,0 +1,71 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTerragruntCliFlag(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("/root/live/app/" + DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+	terragruntOptions.SourceUpdate = true
+	terragruntOptions.Source = "../modules//app"
+	terragruntOptions.IncludeDirs = []string{"app", "db"}
+	terragruntOptions.Stagger = 3 * time.Second
+	terragruntOptions.RootWorkingDir = "/root/live"
+	terragruntOptions.WorkingDir = "/root/live/app"
+
+	testCases := []struct {
+		params        string
+		expectedValue string
+		expectedErr   error
+	}{
+		{`"terragrunt-source-update"`, "true", nil},
+		{`"--terragrunt-source-update"`, "true", nil},
+		{`"terragrunt-non-interactive"`, "true", nil},
+		{`"terragrunt-ignore-dependency-errors"`, "false", nil},
+		{`"terragrunt-no-auto-init"`, "false", nil},
+		{`"terragrunt-source"`, "../modules//app", nil},
+		{`"terragrunt-include-dir"`, "app,db", nil},
+		{`"terragrunt-working-dir"`, "/root/live", nil},
+		{`"terragrunt-stagger"`, "3s", nil},
+		{`"terragrunt-iam-role"`, "", nil},
+		{`"terragrunt-max-starts-per-minute"`, "", nil},
+		{`"terragrunt-status-port"`, "", nil},
+		{`"terragrunt-source-updates"`, "", UnknownTerragruntCliFlag("")},
+		{`"source-update"`, "", UnknownTerragruntCliFlag("")},
+		{`""`, "", UnknownTerragruntCliFlag("")},
+		{``, "", InvalidStringParams("")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.params, func(t *testing.T) {
+			actualValue, actualErr := getTerragruntCliFlag(testCase.params, terragruntOptions)
+			if testCase.expectedErr != nil {
+				if assert.Error(t, actualErr) {
+					assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr))
+				}
+			} else {
+				assert.Nil(t, actualErr)
+				assert.Equal(t, testCase.expectedValue, actualValue)
+			}
+		})
+	}
+}
+
+func TestUnknownTerragruntCliFlagListsValidFlags(t *testing.T) {
+	t.Parallel()
+
+	message := UnknownTerragruntCliFlag("terragrunt-nope").Error()
+	assert.Contains(t, message, "terragrunt-nope")
+	for name := range TERRAGRUNT_CLI_FLAGS {
+		assert.Contains(t, message, name)
+	}
+}
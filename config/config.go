@@ -16,6 +16,10 @@ import (
 const DefaultTerragruntConfigPath = "terraform.tfvars"
 const OldTerragruntConfigPath = ".terragrunt"
 
+// The dedicated file the migrate-config command moves the terragrunt = { ... } block of terraform.tfvars to by default,
+// so that terraform.tfvars only contains variables
+const DedicatedTerragruntConfigPath = "terragrunt.hcl"
+
 // TerragruntConfig represents a parsed and expanded configuration
 type TerragruntConfig struct {
 	Terraform      *TerraformConfig
@@ -23,6 +27,10 @@ type TerragruntConfig struct {
 	Dependencies   *ModuleDependencies
 	PreventDestroy bool
 	IamRole        string
+	Skip           bool
+
+	// The path of the config included via the include block, if any, with calls to helper functions resolved
+	IncludedConfigPath string
 }
 
 func (conf *TerragruntConfig) String() string {
@@ -39,11 +47,13 @@ type terragruntConfigFile struct {
 	Dependencies   *ModuleDependencies `hcl:"dependencies,omitempty"`
 	PreventDestroy bool                `hcl:"prevent_destroy,omitempty"`
 	IamRole        string              `hcl:"iam_role"`
+	Skip           bool                `hcl:"skip,omitempty"`
 }
 
 // Older versions of Terraform did not support locking, so Terragrunt offered locking as a feature. As of version 0.9.0,
 // Terraform supports locking natively, so this feature was removed from Terragrunt. However, we keep around the
-// LockConfig so we can log a warning for Terragrunt users who are still trying to use it.
+// LockConfig so we can tell Terragrunt users who are still trying to use it that it's deprecated (see
+// DEPRECATED_SETTINGS).
 type LockConfig map[interface{}]interface{}
 
 // tfvarsFileWithTerragruntConfig represents a .tfvars file that contains a terragrunt = { ... } block
@@ -51,6 +61,19 @@ type tfvarsFileWithTerragruntConfig struct {
 	Terragrunt *terragruntConfigFile `hcl:"terragrunt,omitempty"`
 }
 
+// The subset of a Terragrunt config file needed to read terraform.source before any calls to helper functions in the
+// config are resolved. Other settings are left out, as they may not have their final type until calls in them are
+// resolved; e.g., env_vars = "${makemap(...)}" is a string, not a map.
+type terragruntConfigFileSource struct {
+	Terraform *struct {
+		Source string `hcl:"source"`
+	} `hcl:"terraform,omitempty"`
+}
+
+type tfvarsFileWithTerragruntConfigSource struct {
+	Terragrunt *terragruntConfigFileSource `hcl:"terragrunt,omitempty"`
+}
+
 // IncludeConfig represents the configuration settings for a parent Terragrunt configuration file that you can
 // "include" in a child Terragrunt configuration file
 type IncludeConfig struct {
@@ -73,18 +96,35 @@ type Hook struct {
 	Commands   []string `hcl:"commands,omitempty"`
 	Execute    []string `hcl:"execute,omitempty"`
 	RunOnError bool     `hcl:"run_on_error,omitempty"`
+
+	// If set, the hook only runs if this is true. It's usually a call to a helper function that returns a bool, such as
+	// eq or truthy, and must be a bool once resolved (see ValidateWhen).
+	When interface{} `hcl:"when,omitempty"`
 }
 
 func (conf *Hook) String() string {
 	return fmt.Sprintf("Hook{Name = %s, Commands = %v}", conf.Name, len(conf.Commands))
 }
 
+// Returns true if the hook has no when condition, or if its condition is true
+func (conf *Hook) WhenIsTrue() bool {
+	return whenIsTrue(conf.When)
+}
+
 // TerraformConfig specifies where to find the Terraform configuration files
 type TerraformConfig struct {
 	ExtraArgs   []TerraformExtraArguments `hcl:"extra_arguments"`
 	Source      string                    `hcl:"source"`
 	BeforeHooks []Hook                    `hcl:"before_hook"`
 	AfterHooks  []Hook                    `hcl:"after_hook"`
+
+	// Modules in the same concurrency group never run at the same time during xxx-all commands, even if they don't
+	// depend on each other
+	ConcurrencyGroup string `hcl:"concurrency_group"`
+
+	// Exit codes, other than 0, that don't count as a failure of the module during xxx-all commands, so the modules that
+	// depend on it still run
+	AllowedExitCodes []int `hcl:"allowed_exit_codes"`
 }
 
 func (conf *TerraformConfig) String() string {
@@ -119,6 +159,44 @@ func (conf *TerraformConfig) ValidateHooks() error {
 	return nil
 }
 
+// Make sure the when condition of each hook and extra_arguments block, if set, resolved to a bool
+func (conf *TerraformConfig) ValidateWhen() error {
+	if conf == nil {
+		return nil
+	}
+
+	for _, curHook := range conf.BeforeHooks {
+		if err := validateWhen(fmt.Sprintf("before_hook %s", curHook.Name), curHook.When); err != nil {
+			return err
+		}
+	}
+	for _, curHook := range conf.AfterHooks {
+		if err := validateWhen(fmt.Sprintf("after_hook %s", curHook.Name), curHook.When); err != nil {
+			return err
+		}
+	}
+	for _, extraArgs := range conf.ExtraArgs {
+		if err := validateWhen(fmt.Sprintf("extra_arguments %s", extraArgs.Name), extraArgs.When); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Return an error naming the given block if the given when condition is set, but isn't a bool
+func validateWhen(block string, when interface{}) error {
+	if _, isBool := when.(bool); when != nil && !isBool {
+		return errors.WithStackTrace(InvalidWhenValue{Block: block, Value: when})
+	}
+	return nil
+}
+
+// Returns true if the given when condition isn't set, or is true
+func whenIsTrue(when interface{}) bool {
+	return when == nil || when == true
+}
+
 // TerraformExtraArguments sets a list of arguments to pass to Terraform if command fits any in the `Commands` list
 type TerraformExtraArguments struct {
 	Name             string            `hcl:",key"`
@@ -127,6 +205,9 @@ type TerraformExtraArguments struct {
 	OptionalVarFiles []string          `hcl:"optional_var_files,omitempty"`
 	Commands         []string          `hcl:"commands,omitempty"`
 	EnvVars          map[string]string `hcl:"env_vars,omitempty"`
+
+	// If set, the arguments are only passed to Terraform if this is true, like the when condition of a Hook
+	When interface{} `hcl:"when,omitempty"`
 }
 
 func (conf *TerraformExtraArguments) String() string {
@@ -138,13 +219,22 @@ func (conf *TerraformExtraArguments) String() string {
 		conf.EnvVars)
 }
 
+// Returns true if the extra_arguments block has no when condition, or if its condition is true
+func (conf *TerraformExtraArguments) WhenIsTrue() bool {
+	return whenIsTrue(conf.When)
+}
+
 // Return the default path to use for the Terragrunt configuration file. The reason this is a method rather than a
-// constant is that older versions of Terragrunt stored configuration in a different file. This method returns the
-// path to the old configuration format if such a file exists and the new format otherwise.
+// constant is that older versions of Terragrunt stored configuration in a different file, and that the configuration
+// may have been moved out of terraform.tfvars into a dedicated file by the migrate-config command. This method returns
+// the path to the old configuration format if such a file exists, the dedicated file if that exists, and
+// terraform.tfvars otherwise.
 func DefaultConfigPath(workingDir string) string {
-	path := util.JoinPath(workingDir, OldTerragruntConfigPath)
-	if util.FileExists(path) {
-		return path
+	for _, name := range []string{OldTerragruntConfigPath, DedicatedTerragruntConfigPath} {
+		path := util.JoinPath(workingDir, name)
+		if util.FileExists(path) {
+			return path
+		}
 	}
 	return util.JoinPath(workingDir, DefaultTerragruntConfigPath)
 }
@@ -176,8 +266,8 @@ func FindConfigFilesInPath(rootPath string, terragruntOptions *options.Terragrun
 }
 
 // Returns true if the given path with the given FileInfo contains a Terragrunt module and false otherwise. A path
-// contains a Terragrunt module if it contains a Terragrunt configuration file (terraform.tfvars) and is not a cache
-// or download dir.
+// contains a Terragrunt module if it contains a Terragrunt configuration file (e.g. terraform.tfvars) and is not a
+// cache or download dir.
 func containsTerragruntModule(path string, info os.FileInfo, terragruntOptions *options.TerragruntOptions) (bool, error) {
 	if !info.IsDir() {
 		return false, nil
@@ -247,25 +337,34 @@ func isNewTerragruntConfig(path string) (bool, error) {
 // Returns true if the given string contains valid HCL with a terragrunt = { ... } block
 func containsTerragruntBlock(configString string) (bool, error) {
 	terragruntConfig := &tfvarsFileWithTerragruntConfig{}
-	if err := hcl.Decode(terragruntConfig, configString); err != nil {
+	if err := hcl.Decode(terragruntConfig, normalizeConfigString(configString)); err != nil {
 		return false, errors.WithStackTrace(err)
 	}
 	return terragruntConfig.Terragrunt != nil, nil
 }
 
+// Config files edited on Windows often start with a UTF-8 byte order mark and use CRLF line endings. Remove the former
+// and convert the latter to LF before parsing, so neither trips up the parser or ends up in the parsed values. This only
+// affects the copy of the config in memory; the file itself is left untouched.
+func normalizeConfigString(configString string) string {
+	return util.NormalizeLineEndings(util.StripUTF8BOM(configString))
+}
+
 // Read the Terragrunt config file from its default location
 func ReadTerragruntConfig(terragruntOptions *options.TerragruntOptions) (*TerragruntConfig, error) {
 	terragruntOptions.Logger.Printf("Reading Terragrunt config file at %s", terragruntOptions.TerragruntConfigPath)
-	return ParseConfigFile(terragruntOptions.TerragruntConfigPath, terragruntOptions, nil)
+	config, err := ParseConfigFile(terragruntOptions.TerragruntConfigPath, terragruntOptions, nil)
+
+	if terragruntOptions.LogHelperMetrics {
+		logHelperMetrics(terragruntOptions)
+	}
+
+	return config, err
 }
 
 // Parse the Terragrunt config file at the given path. If the include parameter is not nil, then treat this as a config
 // included in some other config file when resolving relative paths.
 func ParseConfigFile(configPath string, terragruntOptions *options.TerragruntOptions, include *IncludeConfig) (*TerragruntConfig, error) {
-	if isOldTerragruntConfig(configPath) {
-		terragruntOptions.Logger.Printf("DEPRECATION WARNING: Found deprecated config file format %s. This old config format will not be supported in the future. Please move your config files into a %s file.", configPath, DefaultTerragruntConfigPath)
-	}
-
 	configString, err := util.ReadFileAsString(configPath)
 	if err != nil {
 		return nil, err
@@ -281,6 +380,8 @@ func ParseConfigFile(configPath string, terragruntOptions *options.TerragruntOpt
 
 // Parse the Terragrunt config contained in the given string.
 func parseConfigString(configString string, terragruntOptions *options.TerragruntOptions, include *IncludeConfig, configPath string) (*TerragruntConfig, error) {
+	configString = normalizeConfigString(configString)
+
 	resolvedConfigString, err := ResolveTerragruntConfigString(configString, include, terragruntOptions)
 	if err != nil {
 		return nil, err
@@ -294,11 +395,35 @@ func parseConfigString(configString string, terragruntOptions *options.Terragrun
 		return nil, errors.WithStackTrace(CouldNotResolveTerragruntConfigInFile(configPath))
 	}
 
+	if err := checkDeprecatedSettings(terragruntConfigFile, configPath, terragruntOptions); err != nil {
+		return nil, err
+	}
+
 	config, err := convertToTerragruntConfig(terragruntConfigFile, terragruntOptions)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := resolveTerraformSourcePhase(config, configString, configPath, include, terragruntOptions); err != nil {
+		return nil, err
+	}
+
+	// Checked after the source phase, so a call to a late helper left in place in a when condition is reported as such,
+	// rather than as a value that isn't a bool
+	if err := config.Terraform.ValidateWhen(); err != nil {
+		return nil, err
+	}
+
+	// When parsing an included config, the dependency paths in it were written relative to the included config's
+	// folder, so rebase them onto the folder of the config that included it
+	if include != nil && config.Dependencies != nil {
+		rebasedPaths, err := rebaseDependencyPaths(config.Dependencies.Paths, configPath, terragruntOptions.TerragruntConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		config.Dependencies.Paths = rebasedPaths
+	}
+
 	if include != nil && terragruntConfigFile.Include != nil {
 		return nil, errors.WithStackTrace(TooManyLevelsOfInheritance{
 			ConfigPath:             terragruntOptions.TerragruntConfigPath,
@@ -307,12 +432,87 @@ func parseConfigString(configString string, terragruntOptions *options.Terragrun
 		})
 	}
 
-	includedConfig, err := parseIncludedConfig(terragruntConfigFile.Include, terragruntOptions)
+	includedConfig, includedConfigPath, err := parseIncludedConfig(terragruntConfigFile.Include, terragruntOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	return mergeConfigWithIncludedConfig(config, includedConfig, terragruntOptions)
+	if includedConfig != nil {
+		if err := checkRemoteStateBackendOverride(config, configPath, includedConfig, includedConfigPath, terragruntOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	mergedConfig, err := mergeConfigWithIncludedConfig(config, includedConfig, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+	if includedConfig != nil {
+		mergedConfig.IncludedConfigPath = includedConfigPath
+	}
+	return mergedConfig, nil
+}
+
+// A child config that uses a different remote state backend than the parent config it includes, e.g. local instead of
+// s3, is almost always a mistake that silently leaves the module's state somewhere unexpected. Log a warning about it,
+// or return an error in strict mode, unless the child acknowledges the override with override_parent_backend = true.
+func checkRemoteStateBackendOverride(config *TerragruntConfig, configPath string, includedConfig *TerragruntConfig, includedConfigPath string, terragruntOptions *options.TerragruntOptions) error {
+	if config.RemoteState == nil || includedConfig.RemoteState == nil {
+		return nil
+	}
+	if config.RemoteState.Backend == includedConfig.RemoteState.Backend || config.RemoteState.OverrideParentBackend {
+		return nil
+	}
+
+	err := RemoteStateBackendOverride{
+		ConfigPath:       configPath,
+		Backend:          config.RemoteState.Backend,
+		ParentConfigPath: includedConfigPath,
+		ParentBackend:    includedConfig.RemoteState.Backend,
+	}
+	if terragruntOptions.Strict {
+		return errors.WithStackTrace(err)
+	}
+
+	terragruntOptions.Logger.Printf("WARNING: %s", err.Error())
+	return nil
+}
+
+// Resolve terraform.source in its own phase, once the rest of the config has been parsed. The source is taken from the
+// config string before any calls to helper functions in it were resolved, so ResolveTerraformSource can report calls to
+// helpers that can't be used there. Helpers in the late phase can't be used anywhere else, so calls to them left in
+// place in any other setting are an error.
+func resolveTerraformSourcePhase(config *TerragruntConfig, configString string, configPath string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) error {
+	if config.Terraform != nil && config.Terraform.Source != "" {
+		unresolvedConfigFile, err := parseConfigStringAsTerragruntConfigFileSource(configString, configPath)
+		if err != nil {
+			return err
+		}
+
+		if unresolvedConfigFile != nil && unresolvedConfigFile.Terraform != nil {
+			source, err := ResolveTerraformSource(unresolvedConfigFile.Terraform.Source, include, terragruntOptions)
+			if err != nil {
+				return err
+			}
+			config.Terraform.Source = source
+		}
+	}
+
+	settings, err := flattenTerragruntConfig(config)
+	if err != nil {
+		return err
+	}
+
+	for _, setting := range sortedKeys(settings) {
+		if setting == "terraform.source" {
+			continue
+		}
+		if functionName := findLateHelperFunctionCall(settings[setting]); functionName != "" {
+			return errors.WithStackTrace(LateHelperFunctionOutsideSource{FunctionName: functionName, Setting: setting})
+		}
+	}
+
+	return nil
 }
 
 // Parse the given config string, read from the given config file, as a terragruntConfigFile struct. This method solely
@@ -334,8 +534,28 @@ func parseConfigStringAsTerragruntConfigFile(configString string, configPath str
 	}
 }
 
+// Parse terraform.source from the given config string, read from the given config file, without processing any
+// interpolations
+func parseConfigStringAsTerragruntConfigFileSource(configString string, configPath string) (*terragruntConfigFileSource, error) {
+	if isOldTerragruntConfig(configPath) {
+		terragruntConfig := &terragruntConfigFileSource{}
+		if err := hcl.Decode(terragruntConfig, configString); err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		return terragruntConfig, nil
+	} else {
+		tfvarsConfig := &tfvarsFileWithTerragruntConfigSource{}
+		if err := hcl.Decode(tfvarsConfig, configString); err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+
+		return tfvarsConfig.Terragrunt, nil
+	}
+}
+
 // Merge the given config with an included config. Anything specified in the current config will override the contents
-// of the included config. If the included config is nil, just return the current config.
+// of the included config, except for dependencies, which are combined (see mergeDependencies). If the included config is
+// nil, just return the current config.
 func mergeConfigWithIncludedConfig(config *TerragruntConfig, includedConfig *TerragruntConfig, terragruntOptions *options.TerragruntOptions) (*TerragruntConfig, error) {
 	if includedConfig == nil {
 		return config, nil
@@ -347,6 +567,9 @@ func mergeConfigWithIncludedConfig(config *TerragruntConfig, includedConfig *Ter
 	if config.PreventDestroy {
 		includedConfig.PreventDestroy = config.PreventDestroy
 	}
+	// Unlike the other settings, skip only applies to the config that sets it, so a parent config can skip itself
+	// without skipping all the configs that include it
+	includedConfig.Skip = config.Skip
 
 	if config.Terraform != nil {
 		if includedConfig.Terraform == nil {
@@ -355,6 +578,12 @@ func mergeConfigWithIncludedConfig(config *TerragruntConfig, includedConfig *Ter
 			if config.Terraform.Source != "" {
 				includedConfig.Terraform.Source = config.Terraform.Source
 			}
+			if config.Terraform.ConcurrencyGroup != "" {
+				includedConfig.Terraform.ConcurrencyGroup = config.Terraform.ConcurrencyGroup
+			}
+			if config.Terraform.AllowedExitCodes != nil {
+				includedConfig.Terraform.AllowedExitCodes = config.Terraform.AllowedExitCodes
+			}
 			mergeExtraArgs(terragruntOptions, config.Terraform.ExtraArgs, &includedConfig.Terraform.ExtraArgs)
 
 			mergeHooks(terragruntOptions, config.Terraform.BeforeHooks, &includedConfig.Terraform.BeforeHooks)
@@ -363,7 +592,11 @@ func mergeConfigWithIncludedConfig(config *TerragruntConfig, includedConfig *Ter
 	}
 
 	if config.Dependencies != nil {
-		includedConfig.Dependencies = config.Dependencies
+		if includedConfig.Dependencies == nil {
+			includedConfig.Dependencies = config.Dependencies
+		} else {
+			mergeDependencies(config.Dependencies.Paths, &includedConfig.Dependencies.Paths)
+		}
 	}
 
 	if config.IamRole != "" {
@@ -373,6 +606,55 @@ func mergeConfigWithIncludedConfig(config *TerragruntConfig, includedConfig *Ter
 	return includedConfig, nil
 }
 
+// Merge the dependencies.
+//
+// The merged dependency paths are the union of the parent's and the child's paths, with the parent's paths first. A
+// child's path that points to the same folder as one of the parent's paths is ignored. By the time this method is
+// called, the parent's paths have already been rebased to be relative to the child's folder (see
+// rebaseDependencyPaths), so the paths can be compared after normalizing them.
+func mergeDependencies(childPaths []string, parentPaths *[]string) {
+	result := *parentPaths
+	for _, child := range childPaths {
+		if getIndexOfDependencyPath(result, child) == -1 {
+			result = append(result, child)
+		}
+	}
+	*parentPaths = result
+}
+
+// Returns the index of the dependency path that points to the same folder as the given path,
+// or -1 if no dependency path does.
+func getIndexOfDependencyPath(paths []string, path string) int {
+	for i, existing := range paths {
+		if util.CleanPath(existing) == util.CleanPath(path) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Dependency paths are written relative to the folder of the config file that declares them. Convert the given
+// relative paths, declared in the config at fromConfigPath, so they are relative to the folder of the config at
+// toConfigPath instead. Absolute paths are returned unchanged.
+func rebaseDependencyPaths(paths []string, fromConfigPath string, toConfigPath string) ([]string, error) {
+	rebasedPaths := []string{}
+
+	for _, path := range paths {
+		if filepath.IsAbs(path) {
+			rebasedPaths = append(rebasedPaths, path)
+			continue
+		}
+
+		rebasedPath, err := util.GetPathRelativeTo(util.JoinPath(filepath.Dir(fromConfigPath), path), filepath.Dir(toConfigPath))
+		if err != nil {
+			return nil, err
+		}
+		rebasedPaths = append(rebasedPaths, rebasedPath)
+	}
+
+	return rebasedPaths, nil
+}
+
 // Merge the hooks (before_hook and after_hook).
 //
 // If a child's hook (before_hook or after_hook) has the same name a parent's hook,
@@ -449,35 +731,32 @@ func getIndexOfExtraArgsWithName(extraArgs []TerraformExtraArguments, name strin
 	return -1
 }
 
-// Parse the config of the given include, if one is specified
-func parseIncludedConfig(includedConfig *IncludeConfig, terragruntOptions *options.TerragruntOptions) (*TerragruntConfig, error) {
+// Parse the config of the given include, if one is specified, and return it along with the path it was read from
+func parseIncludedConfig(includedConfig *IncludeConfig, terragruntOptions *options.TerragruntOptions) (*TerragruntConfig, string, error) {
 	if includedConfig == nil {
-		return nil, nil
+		return nil, "", nil
 	}
 	if includedConfig.Path == "" {
-		return nil, errors.WithStackTrace(IncludedConfigMissingPath(terragruntOptions.TerragruntConfigPath))
+		return nil, "", errors.WithStackTrace(IncludedConfigMissingPath(terragruntOptions.TerragruntConfigPath))
 	}
 
 	resolvedIncludePath, err := ResolveTerragruntConfigString(includedConfig.Path, nil, terragruntOptions)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	if !filepath.IsAbs(resolvedIncludePath) {
 		resolvedIncludePath = util.JoinPath(filepath.Dir(terragruntOptions.TerragruntConfigPath), resolvedIncludePath)
 	}
 
-	return ParseConfigFile(resolvedIncludePath, terragruntOptions, includedConfig)
+	config, err := ParseConfigFile(resolvedIncludePath, terragruntOptions, includedConfig)
+	return config, resolvedIncludePath, err
 }
 
 // Convert the contents of a fully resolved Terragrunt configuration to a TerragruntConfig object
 func convertToTerragruntConfig(terragruntConfigFromFile *terragruntConfigFile, terragruntOptions *options.TerragruntOptions) (*TerragruntConfig, error) {
 	terragruntConfig := &TerragruntConfig{}
 
-	if terragruntConfigFromFile.Lock != nil {
-		terragruntOptions.Logger.Printf("WARNING: Found a lock configuration in the Terraform configuration at %s. Terraform added native support for locking as of version 0.9.0, so this feature has been removed from Terragrunt and will have no effect. See your Terraform backend docs for how to configure locking: https://www.terraform.io/docs/backends/types/index.html.", terragruntOptions.TerragruntConfigPath)
-	}
-
 	if terragruntConfigFromFile.RemoteState != nil {
 		terragruntConfigFromFile.RemoteState.FillDefaults()
 		if err := terragruntConfigFromFile.RemoteState.Validate(); err != nil {
@@ -495,6 +774,7 @@ func convertToTerragruntConfig(terragruntConfigFromFile *terragruntConfigFile, t
 	terragruntConfig.Dependencies = terragruntConfigFromFile.Dependencies
 	terragruntConfig.PreventDestroy = terragruntConfigFromFile.PreventDestroy
 	terragruntConfig.IamRole = terragruntConfigFromFile.IamRole
+	terragruntConfig.Skip = terragruntConfigFromFile.Skip
 
 	return terragruntConfig, nil
 }
@@ -507,6 +787,15 @@ func (e InvalidArgError) Error() string {
 	return string(e)
 }
 
+type InvalidWhenValue struct {
+	Block string
+	Value interface{}
+}
+
+func (err InvalidWhenValue) Error() string {
+	return fmt.Sprintf("The when condition of %s must be true or false, such as the result of a call to eq or truthy, but got '%v'", err.Block, err.Value)
+}
+
 type IncludedConfigMissingPath string
 
 func (err IncludedConfigMissingPath) Error() string {
@@ -523,6 +812,17 @@ func (err TooManyLevelsOfInheritance) Error() string {
 	return fmt.Sprintf("%s includes %s, which itself includes %s. Only one level of includes is allowed.", err.ConfigPath, err.FirstLevelIncludePath, err.SecondLevelIncludePath)
 }
 
+type RemoteStateBackendOverride struct {
+	ConfigPath       string
+	Backend          string
+	ParentConfigPath string
+	ParentBackend    string
+}
+
+func (err RemoteStateBackendOverride) Error() string {
+	return fmt.Sprintf("The remote_state block in %s uses the %s backend, but the config it includes, %s, uses the %s backend. This is almost always a mistake. If it's intentional, set override_parent_backend = true in the remote_state block in %s.", err.ConfigPath, err.Backend, err.ParentConfigPath, err.ParentBackend, err.ConfigPath)
+}
+
 type CouldNotResolveTerragruntConfigInFile string
 
 func (err CouldNotResolveTerragruntConfigInFile) Error() string {
@@ -537,3 +837,7 @@ type ErrorParsingTerragruntConfig struct {
 func (err ErrorParsingTerragruntConfig) Error() string {
 	return fmt.Sprintf("Error parsing Terragrunt config at %s: %v", err.ConfigPath, err.Underlying)
 }
+
+func (err ErrorParsingTerragruntConfig) Class() errors.ErrorClass {
+	return errors.ErrorClassConfig
+}
@@ -1,25 +1,63 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/gruntwork-io/terragrunt/aws_helper"
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
 	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl"
 )
 
-var INTERPOLATION_PARAMETERS = `(\s*"[^"]*?"\s*,?\s*)*`
+// How deep calls to helper functions can be nested in the parameters of other calls, such as the call to eq in
+// ${cond("${eq("a", "b")}", "yes", "no")}, which is nested two deep
+const MAX_NESTED_HELPER_FUNCTION_CALLS = 4
+
+var INTERPOLATION_PARAMETERS = fmt.Sprintf(`(\s*"%s"\s*,?\s*)*`, quotedParamSyntax(MAX_NESTED_HELPER_FUNCTION_CALLS-1))
 var INTERPOLATION_SYNTAX_REGEX = regexp.MustCompile(fmt.Sprintf(`\$\{\s*\w+\(%s\)\s*\}`, INTERPOLATION_PARAMETERS))
 var INTERPOLATION_SYNTAX_REGEX_SINGLE = regexp.MustCompile(fmt.Sprintf(`"(%s)"`, INTERPOLATION_SYNTAX_REGEX))
 var INTERPOLATION_SYNTAX_REGEX_REMAINING = regexp.MustCompile(`\$\{.*?\}`)
+var MAP_KEY_END_SYNTAX_REGEX = regexp.MustCompile(`^[ \t]*[={]`)
 var HELPER_FUNCTION_SYNTAX_REGEX = regexp.MustCompile(`^\$\{\s*(.*?)\((.*?)\)\s*\}$`)
-var HELPER_FUNCTION_GET_ENV_PARAMETERS_SYNTAX_REGEX = regexp.MustCompile(`^\s*"(?P<env>[^=]+?)"\s*\,\s*"(?P<default>.*?)"\s*$`)
+var HELPER_FUNCTION_CALL_LIKE_REGEX = regexp.MustCompile(`^\$\{\s*\w+\s*\(`)
+
+// Matches a call whose function name isn't a bare identifier, such as ${${fn}()} or ${get-env("FOO")}, and captures the
+// name
+var INVALID_HELPER_FUNCTION_NAME_REGEX = regexp.MustCompile(`\$\{\s*(\$\{[^{}]*\}|[^\s(){}"$]*[^\w\s(){}"$][^\s(){}"$]*)\s*\(`)
+var HELPER_FUNCTION_GET_ENV_PARAMETERS_SYNTAX_REGEX = regexp.MustCompile(`^\s*"(?P<env>[^="]+?)"\s*(?P<hasDefault>\,\s*"(?P<default>(?:\\.|[^"\\])*)"\s*)?$`)
+
+// Matches an ANSI SGR escape sequence, such as the \x1b[31m that makes text red, either as the escape character itself,
+// as in the value of an environment variable, or as the HCL escape sequence \u001b, as in a literal value of the config
+var ANSI_SGR_SEQUENCE_REGEX = regexp.MustCompile(`(\x1b|\\u001[bB])\[[0-9;]*m`)
 
 // List of terraform commands that accept -lock-timeout
 var TERRAFORM_COMMANDS_NEED_LOCKING = []string{
@@ -54,14 +92,162 @@ var TERRAFORM_COMMANDS_NEED_INPUT = []string{
 	"refresh",
 }
 
+// List of terraform commands that accept -parallelism=
+var TERRAFORM_COMMANDS_NEED_PARALLELISM = []string{
+	"apply",
+	"destroy",
+	"plan",
+	"refresh",
+}
+
+// The phase in which calls to a helper function are resolved
+type HelperFunctionPhase string
+
+const (
+	// Calls are resolved while parsing the config, before anything else happens
+	HelperPhaseParse HelperFunctionPhase = "parse"
+
+	// Calls are left in place while parsing the config, and only resolved in terraform.source once the rest of the
+	// config has been parsed, as the value they return may depend on it. Using them anywhere else is an error.
+	HelperPhaseLate HelperFunctionPhase = "late"
+)
+
+// The attributes of a helper function that determine when and where calls to it can be resolved
+type HelperFunction struct {
+	Phase HelperFunctionPhase
+
+	// Whether the helper function can be used in terraform.source. Helpers that return something other than a string,
+	// such as a list, can't be part of a source URL.
+	AllowedInSource bool
+
+	// Whether the parameters of the helper function may be calls to other helper functions, such as the
+	// "${get_env("ZIP", "")}" in ${string("${get_env("ZIP", "")}")}, which the helper function resolves itself (see
+	// resolveDeferredParam). Calls nested in the parameters of any other helper function are an error, as they would
+	// otherwise be passed to it as is.
+	ResolvesNestedCalls bool
+}
+
+// The helper functions supported by executeTerragruntHelperFunction
+var HELPER_FUNCTIONS = map[string]HelperFunction{
+	"find_in_parent_folders":                {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"collect_parent_files":                  {Phase: HelperPhaseParse, AllowedInSource: false},
+	"path_relative_to_include":              {Phase: HelperPhaseParse, AllowedInSource: true},
+	"path_relative_from_include":            {Phase: HelperPhaseParse, AllowedInSource: true},
+	"get_env":                               {Phase: HelperPhaseParse, AllowedInSource: true},
+	"get_dotenv":                            {Phase: HelperPhaseParse, AllowedInSource: true},
+	"filebase64":                            {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"filesha256":                            {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"subdirs":                               {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"get_tfvars_dir":                        {Phase: HelperPhaseParse, AllowedInSource: true},
+	"get_original_terragrunt_dir":           {Phase: HelperPhaseParse, AllowedInSource: true},
+	"get_module_download_dir":               {Phase: HelperPhaseParse, AllowedInSource: true},
+	"get_parent_tfvars_dir":                 {Phase: HelperPhaseParse, AllowedInSource: true},
+	"get_include_path":                      {Phase: HelperPhaseParse, AllowedInSource: true},
+	"get_terragrunt_dir":                    {Phase: HelperPhaseParse, AllowedInSource: true},
+	"get_parent_terragrunt_dir":             {Phase: HelperPhaseParse, AllowedInSource: true},
+	"get_aws_account_id":                    {Phase: HelperPhaseParse, AllowedInSource: true},
+	"build_arn":                             {Phase: HelperPhaseParse, AllowedInSource: true},
+	"region_value":                          {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"standard_tags":                         {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"color_for":                             {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"hash_bucket":                           {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"seeded_int":                            {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"range_list":                            {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"truncate":                              {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"dns_label":                             {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"env_from_path":                         {Phase: HelperPhaseParse, AllowedInSource: true},
+	"when_flag":                             {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"makemap":                               {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"get_terraform_workspace":               {Phase: HelperPhaseLate, AllowedInSource: true},
+	"get_num_cpus":                          {Phase: HelperPhaseParse, AllowedInSource: false},
+	"get_git_describe":                      {Phase: HelperPhaseParse, AllowedInSource: true},
+	"get_terragrunt_cli_flag":               {Phase: HelperPhaseParse, AllowedInSource: true},
+	"fingerprint":                           {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"eq":                                    {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"ne":                                    {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"cond":                                  {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"truthy":                                {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"string":                                {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"squash_whitespace":                     {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"strip_ansi":                            {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"longest":                               {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"shortest":                              {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"assert_unique":                         {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"assert_oneof":                          {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"map_to_entries":                        {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"sortmap_by_value":                      {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"merge":                                 {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"quote_join":                            {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"concat":                                {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"element":                               {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"clamp":                                 {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"allow_empty":                           {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"http_get_json":                         {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"read_tfvars_file":                      {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"detect_cloud":                          {Phase: HelperPhaseParse, AllowedInSource: true},
+	"get_config_mtime":                      {Phase: HelperPhaseParse, AllowedInSource: false, ResolvesNestedCalls: true},
+	"run_cmd":                               {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"retry":                                 {Phase: HelperPhaseParse, AllowedInSource: true, ResolvesNestedCalls: true},
+	"get_terraform_commands_that_need_vars": {Phase: HelperPhaseParse, AllowedInSource: false},
+	"command_needs_vars":                    {Phase: HelperPhaseParse, AllowedInSource: false},
+	"get_terraform_commands_that_need_locking":     {Phase: HelperPhaseParse, AllowedInSource: false},
+	"get_terraform_commands_that_need_input":       {Phase: HelperPhaseParse, AllowedInSource: false},
+	"get_terraform_commands_that_need_parallelism": {Phase: HelperPhaseParse, AllowedInSource: false},
+}
+
+// The git binary get_git_describe runs
+const GIT_COMMAND = "git"
+
+// The name of the workspace Terraform uses if no other workspace has been selected
+const DEFAULT_TERRAFORM_WORKSPACE = "default"
+
+// What detect_cloud returns if it finds credentials for none of CLOUD_PROVIDERS
+const UNKNOWN_CLOUD = "unknown"
+
+// A cloud provider detect_cloud can detect, along with the env vars and the files, relative to the home dir, whose
+// presence means credentials for it are available
+type CloudProvider struct {
+	Name            string
+	EnvVars         []string
+	CredentialFiles []string
+}
+
+// The cloud providers detect_cloud can detect, in the order it checks them in. The env vars of every provider are
+// checked before any of their credential files, so credentials set up for the current run take precedence over those
+// lying around in the home dir.
+var CLOUD_PROVIDERS = []CloudProvider{
+	{
+		Name:            "aws",
+		EnvVars:         []string{"AWS_ACCESS_KEY_ID", "AWS_PROFILE", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI"},
+		CredentialFiles: []string{".aws/credentials"},
+	},
+	{
+		Name:            "gcp",
+		EnvVars:         []string{"GOOGLE_APPLICATION_CREDENTIALS", "GOOGLE_CREDENTIALS", "GOOGLE_CLOUD_KEYFILE_JSON", "GCE_METADATA_HOST"},
+		CredentialFiles: []string{".config/gcloud/application_default_credentials.json"},
+	},
+	{
+		Name:            "azure",
+		EnvVars:         []string{"ARM_CLIENT_ID", "ARM_USE_MSI", "AZURE_CLIENT_ID", "MSI_ENDPOINT", "IDENTITY_ENDPOINT"},
+		CredentialFiles: []string{".azure/accessTokens.json", ".azure/msal_token_cache.json"},
+	},
+}
+
 type EnvVar struct {
 	Name         string
 	DefaultValue string
+	HasDefault   bool
 }
 
 // Given a string value from a Terragrunt configuration, parse the string, resolve any calls to helper functions using
 // the syntax ${...}, and return the final value.
 func ResolveTerragruntConfigString(terragruntConfigString string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	// The name of a function is never resolved, so a call such as ${${fn}()} would otherwise be left as is, or be
+	// reported as whatever is left of it once the calls nested in it have been resolved
+	if matches := INVALID_HELPER_FUNCTION_NAME_REGEX.FindStringSubmatch(terragruntConfigString); matches != nil {
+		return terragruntConfigString, errors.WithStackTrace(InvalidHelperFunctionName{Call: matches[0], Name: matches[1]})
+	}
+
 	// First, we replace all single interpolation syntax (i.e. function directly enclosed within quotes "${function()}")
 	terragruntConfigString, err := processSingleInterpolationInString(terragruntConfigString, include, terragruntOptions)
 	if err != nil {
@@ -71,29 +257,173 @@ func ResolveTerragruntConfigString(terragruntConfigString string, include *Inclu
 	return processMultipleInterpolationsInString(terragruntConfigString, include, terragruntOptions)
 }
 
-// Execute a single Terragrunt helper function and return the result
+// Execute a single Terragrunt helper function and return the result, recording the call in the HelperMetrics of the
+// given options. The time recorded for a call includes the time taken by calls to other helper functions in its
+// parameters.
 func executeTerragruntHelperFunction(functionName string, parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	if _, isKnown := HELPER_FUNCTIONS[functionName]; isKnown {
+		defer recordHelperFunctionCall(functionName, time.Now(), terragruntOptions)
+	}
+	return runTerragruntHelperFunction(functionName, parameters, include, terragruntOptions)
+}
+
+func recordHelperFunctionCall(functionName string, start time.Time, terragruntOptions *options.TerragruntOptions) {
+	terragruntOptions.HelperMetrics.Record(functionName, time.Since(start))
+}
+
+// Log how many times each helper function was called so far in this run, and how long the calls took
+func logHelperMetrics(terragruntOptions *options.TerragruntOptions) {
+	for _, metric := range terragruntOptions.HelperMetrics.Snapshot() {
+		terragruntOptions.Logger.Printf("Helper function %s: %d calls in %v", metric.Name, metric.Calls, metric.Duration)
+	}
+}
+
+// Run the helper function with the given name, without recording the call
+func runTerragruntHelperFunction(functionName string, parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	// Calls nested in the parameters of another call are left alone when the config is resolved, so a helper function
+	// that doesn't resolve them itself would get the text of the call instead of its value
+	if helperFunction, isKnown := HELPER_FUNCTIONS[functionName]; isKnown && !helperFunction.ResolvesNestedCalls {
+		if nestedCall := INTERPOLATION_SYNTAX_REGEX.FindString(parameters); nestedCall != "" {
+			return "", errors.WithStackTrace(NestedHelperFunctionCallNotSupported{Function: functionName, Call: nestedCall})
+		}
+	}
+
 	switch functionName {
-	case "find_in_parent_folders":
-		return findInParentFolders(parameters, terragruntOptions)
+	case "collect_parent_files":
+		return collectParentFiles(parameters, include, terragruntOptions)
 	case "path_relative_to_include":
 		return pathRelativeToInclude(include, terragruntOptions)
 	case "path_relative_from_include":
 		return pathRelativeFromInclude(include, terragruntOptions)
 	case "get_env":
 		return getEnvironmentVariable(parameters, terragruntOptions)
+	case "get_dotenv":
+		return getDotEnv(parameters, terragruntOptions)
 	case "get_tfvars_dir":
 		return getTfVarsDir(terragruntOptions)
+	case "get_original_terragrunt_dir":
+		return getOriginalTerragruntDir(terragruntOptions)
+	case "get_module_download_dir":
+		return getModuleDownloadDir(terragruntOptions)
 	case "get_parent_tfvars_dir":
 		return getParentTfVarsDir(include, terragruntOptions)
+	case "get_include_path":
+		return getIncludePath(include, terragruntOptions)
+	case "get_terragrunt_dir":
+		return getTerragruntDir(terragruntOptions)
+	case "get_parent_terragrunt_dir":
+		return getParentTerragruntDir(include, terragruntOptions)
 	case "get_aws_account_id":
 		return getAWSAccountID(terragruntOptions)
+	case "build_arn":
+		return buildArn(parameters, terragruntOptions)
+	case "env_from_path":
+		return envFromPath(parameters, include, terragruntOptions)
+	case "get_terraform_workspace":
+		return getTerraformWorkspace(terragruntOptions)
+	case "get_num_cpus":
+		return runtime.NumCPU(), nil
+	case "detect_cloud":
+		return detectCloud(terragruntOptions), nil
+	case "get_git_describe":
+		return getGitDescribe(terragruntOptions)
+	case "get_terragrunt_cli_flag":
+		return getTerragruntCliFlag(parameters, terragruntOptions)
 	case "get_terraform_commands_that_need_vars":
 		return TERRAFORM_COMMANDS_NEED_VARS, nil
+	case "command_needs_vars":
+		return commandNeedsVars(terragruntOptions), nil
 	case "get_terraform_commands_that_need_locking":
 		return TERRAFORM_COMMANDS_NEED_LOCKING, nil
 	case "get_terraform_commands_that_need_input":
 		return TERRAFORM_COMMANDS_NEED_INPUT, nil
+	case "get_terraform_commands_that_need_parallelism":
+		return TERRAFORM_COMMANDS_NEED_PARALLELISM, nil
+	// The parameters of the helper functions below may themselves be calls to helper functions, such as the
+	// "${get_env("ZIP", "")}" in ${string("${get_env("ZIP", "")}")} (see HelperFunction.ResolvesNestedCalls). Calls
+	// nested in the parameters of another call are left alone when the config is resolved, so each of these functions
+	// resolves them itself, and only once it needs them: when_flag and cond only resolve the value they return,
+	// region_value only resolves its default if it returns it, and retry may resolve the call passed to it more than
+	// once.
+	case "find_in_parent_folders":
+		return findInParentFolders(parameters, include, terragruntOptions)
+	case "region_value":
+		return regionValue(parameters, include, terragruntOptions)
+	case "standard_tags":
+		return standardTags(parameters, include, terragruntOptions)
+	case "seeded_int":
+		return seededInt(parameters, include, terragruntOptions)
+	case "dns_label":
+		return dnsLabel(parameters, include, terragruntOptions)
+	case "when_flag":
+		return whenFlag(parameters, include, terragruntOptions)
+	case "run_cmd":
+		return runCmd(parameters, include, terragruntOptions)
+	case "retry":
+		return retry(parameters, include, terragruntOptions)
+	case "fingerprint":
+		return fingerprint(parameters, include, terragruntOptions)
+	case "eq":
+		return equals(parameters, include, terragruntOptions)
+	case "ne":
+		equal, err := equals(parameters, include, terragruntOptions)
+		return !equal, err
+	case "cond":
+		return cond(parameters, include, terragruntOptions)
+	case "truthy":
+		return truthy(parameters, include, terragruntOptions)
+	case "allow_empty":
+		return allowEmpty(parameters, include, terragruntOptions)
+	case "string":
+		return stringify(parameters, include, terragruntOptions)
+	case "squash_whitespace":
+		return squashWhitespace(parameters, include, terragruntOptions)
+	case "strip_ansi":
+		return stripAnsi(parameters, include, terragruntOptions)
+	case "color_for":
+		return colorFor(parameters, include, terragruntOptions)
+	case "range_list":
+		return rangeList(parameters, include, terragruntOptions)
+	case "truncate":
+		return truncate(parameters, include, terragruntOptions)
+	case "makemap":
+		return makeMap(parameters, include, terragruntOptions)
+	case "hash_bucket":
+		return hashBucket(parameters, include, terragruntOptions)
+	case "filebase64":
+		return fileBase64(parameters, include, terragruntOptions)
+	case "filesha256":
+		return fileSha256(parameters, include, terragruntOptions)
+	case "subdirs":
+		return subdirs(parameters, include, terragruntOptions)
+	case "get_config_mtime":
+		return getConfigMtime(parameters, include, terragruntOptions)
+	case "longest":
+		return stringByLength(functionName, parameters, include, terragruntOptions, true)
+	case "shortest":
+		return stringByLength(functionName, parameters, include, terragruntOptions, false)
+	case "assert_unique":
+		return assertUnique(parameters, include, terragruntOptions)
+	case "assert_oneof":
+		return assertOneOf(parameters, include, terragruntOptions)
+	case "map_to_entries":
+		return mapToEntries(parameters, include, terragruntOptions)
+	case "sortmap_by_value":
+		return sortMapByValue(parameters, include, terragruntOptions)
+	case "merge":
+		return merge(parameters, include, terragruntOptions)
+	case "quote_join":
+		return quoteJoin(parameters, include, terragruntOptions)
+	case "concat":
+		return concat(parameters, include, terragruntOptions)
+	case "element":
+		return element(parameters, include, terragruntOptions)
+	case "clamp":
+		return clamp(parameters, include, terragruntOptions)
+	case "http_get_json":
+		return httpGetJson(parameters, include, terragruntOptions)
+	case "read_tfvars_file":
+		return readTfVarsFile(parameters, include, terragruntOptions)
 	default:
 		return "", errors.WithStackTrace(UnknownHelperFunction(functionName))
 	}
@@ -103,35 +433,135 @@ func executeTerragruntHelperFunction(functionName string, parameters string, inc
 // functions that return a non-string value we have to get rid of the surrounding quotes and convert the output to HCL syntax. For example,
 // for an array, we need to return "v1", "v2", "v3".
 func processSingleInterpolationInString(terragruntConfigString string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (resolved string, finalErr error) {
-	// The function we pass to ReplaceAllStringFunc cannot return an error, so we have to use named error parameters to capture such errors.
-	resolved = INTERPOLATION_SYNTAX_REGEX_SINGLE.ReplaceAllStringFunc(terragruntConfigString, func(str string) string {
-		matches := INTERPOLATION_SYNTAX_REGEX_SINGLE.FindStringSubmatch(str)
+	// Calls passed as a parameter to another call, such as the "${get_aws_account_id()}" in
+	// ${when_flag("FLAG", "${get_aws_account_id()}", "none")}, are left for the outer helper function to resolve, if at all
+	outerCalls := INTERPOLATION_SYNTAX_REGEX.FindAllStringIndex(terragruntConfigString, -1)
+
+	resolved = ""
+	lastEnd := 0
+	for _, match := range INTERPOLATION_SYNTAX_REGEX_SINGLE.FindAllStringSubmatchIndex(terragruntConfigString, -1) {
+		start, end := match[0], match[1]
+		str := terragruntConfigString[start:end]
+		resolved += terragruntConfigString[lastEnd:start]
+		lastEnd = end
+
+		call := terragruntConfigString[match[2]:match[3]]
+		if isNestedInterpolation(match[2], outerCalls) || isLateHelperFunctionCall(call) {
+			resolved += str
+			continue
+		}
 
-		out, err := resolveTerragruntInterpolation(matches[1], include, terragruntOptions)
+		out, err := resolveTerragruntInterpolation(call, include, terragruntOptions)
+		if err == nil {
+			err = checkNotEmpty(call, out, terragruntOptions)
+		}
 		if err != nil {
 			finalErr = err
-			return str
+			resolved += str
+			continue
+		}
+
+		if isMapKey(terragruntConfigString[end:]) {
+			key, err := interpolatedMapKey(call, out)
+			if err != nil {
+				finalErr = err
+				resolved += str
+				continue
+			}
+			resolved += key
+			continue
 		}
 
 		switch out := out.(type) {
 		case string:
-			return fmt.Sprintf(`"%s"`, out)
+			resolved += fmt.Sprintf(`"%s"`, out)
 		case []string:
-			return util.CommaSeparatedStrings(out)
+			resolved += util.CommaSeparatedStrings(out)
+		case []int:
+			resolved += util.CommaSeparatedInts(out)
+		case map[string]string:
+			resolved += util.HclMapOfStrings(out)
+		case []map[string]interface{}:
+			resolved += util.CommaSeparatedMaps(out)
 		default:
-			return fmt.Sprintf("%v", out)
+			resolved += fmt.Sprintf("%v", out)
 		}
-	})
+	}
+	resolved += terragruntConfigString[lastEnd:]
 	return
 }
 
+// Return true if a quoted string followed by the given text is the key of a map entry, such as "${get_env("ENV", "")}" in
+// { "${get_env("ENV", "")}" = "x" }, or the name of a block, such as extra_arguments "${get_env("NAME", "")}" { ... }
+func isMapKey(textAfter string) bool {
+	return MAP_KEY_END_SYNTAX_REGEX.MatchString(textAfter)
+}
+
+// Render the value returned by the given call to a helper function as the quoted key of a map entry. Strings, numbers and
+// booleans are quoted, so that { "${eq("a", "b")}" = "x" } has the key "false", but lists and maps can't be keys.
+func interpolatedMapKey(call string, out interface{}) (string, error) {
+	switch out := out.(type) {
+	case string, bool, int:
+		return fmt.Sprintf(`"%v"`, out), nil
+	default:
+		return "", errors.WithStackTrace(InterpolatedMapKeyNotAScalar{Call: call, Value: out})
+	}
+}
+
+// If the FailOnEmpty option is set, return an error if the value returned by the given call to a helper function is
+// empty, which is a blank string, or a list or map without any elements. Calls to allow_empty are never an error, and
+// neither are the calls nested in the parameters of another call, as only the value the outer call returns ends up in
+// the config.
+func checkNotEmpty(call string, out interface{}, terragruntOptions *options.TerragruntOptions) error {
+	if !terragruntOptions.FailOnEmpty || helperFunctionName(call) == "allow_empty" || !isEmptyValue(out) {
+		return nil
+	}
+	return errors.WithStackTrace(EmptyHelperFunctionResult(call))
+}
+
+// Return true if the given value returned by a helper function is a blank string, or a list or map without any elements
+func isEmptyValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	if str, isString := value.(string); isString {
+		return strings.TrimSpace(str) == ""
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Slice, reflect.Map:
+		return reflectValue.Len() == 0
+	}
+	return false
+}
+
+// Return true if a call to an interpolation function that starts at the given index is within the parameters of one of
+// the given outer calls, where each outer call is the start and end index of the call
+func isNestedInterpolation(start int, outerCalls [][]int) bool {
+	for _, outerCall := range outerCalls {
+		if outerCall[0] < start && start < outerCall[1] {
+			return true
+		}
+	}
+	return false
+}
+
 // For all interpolation functions that are called using the syntax "${function_a()}-${function_b()}" (i.e. multiple interpolation function
 // within the same string) or "Some text ${function_name()}" (i.e. string composition), we just replace the interpolation function call
 // by the string representation of its return.
 func processMultipleInterpolationsInString(terragruntConfigString string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (resolved string, finalErr error) {
 	// The function we pass to ReplaceAllStringFunc cannot return an error, so we have to use named error parameters to capture such errors.
 	resolved = INTERPOLATION_SYNTAX_REGEX.ReplaceAllStringFunc(terragruntConfigString, func(str string) string {
+		if isLateHelperFunctionCall(str) {
+			return str
+		}
+
 		out, err := resolveTerragruntInterpolation(str, include, terragruntOptions)
+		if err == nil {
+			err = checkNotEmpty(str, out, terragruntOptions)
+		}
 		if err != nil {
 			finalErr = err
 			return str
@@ -142,8 +572,20 @@ func processMultipleInterpolationsInString(terragruntConfigString string, includ
 
 	if finalErr == nil {
 		// If there is no error, we check if there are remaining look-a-like interpolation strings
-		// that have not been considered. If so, they are certainly malformed.
-		remaining := INTERPOLATION_SYNTAX_REGEX_REMAINING.FindAllString(resolved, -1)
+		// that have not been considered. If they look like a call to a function, they are certainly malformed. Calls to
+		// helper functions that are resolved late are left in place on purpose, as are Terraform interpolations, such as
+		// ${var.region}, unless the Strict option says they're likely a typo.
+		remaining := []string{}
+		for _, str := range INTERPOLATION_SYNTAX_REGEX_REMAINING.FindAllString(resolved, -1) {
+			if isLateHelperFunctionCall(str) {
+				continue
+			}
+			if !terragruntOptions.Strict && !HELPER_FUNCTION_CALL_LIKE_REGEX.MatchString(str) {
+				terragruntOptions.Logger.Printf("Leaving %s as is, as it isn't a call to a Terragrunt helper function", str)
+				continue
+			}
+			remaining = append(remaining, str)
+		}
 		if len(remaining) > 0 {
 			finalErr = InvalidInterpolationSyntax(strings.Join(remaining, ", "))
 		}
@@ -152,7 +594,71 @@ func processMultipleInterpolationsInString(terragruntConfigString string, includ
 	return
 }
 
-// Given a string value from a Terragrunt configuration, parse the string, resolve any calls to helper functions using
+// Resolve the calls to helper functions in the given terraform.source value. The given value must come straight from the
+// config file, before any calls in it were resolved, so calls to helpers that can't be used in terraform.source are
+// still there to be reported. Calls to helpers in the parse phase are resolved first, followed by calls to helpers in
+// the late phase.
+func ResolveTerraformSource(source string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	for _, call := range INTERPOLATION_SYNTAX_REGEX.FindAllString(source, -1) {
+		functionName := helperFunctionName(call)
+		if helperFunction, isKnown := HELPER_FUNCTIONS[functionName]; isKnown && !helperFunction.AllowedInSource {
+			return "", errors.WithStackTrace(HelperFunctionNotAllowedInSource(functionName))
+		}
+	}
+
+	resolved, err := ResolveTerragruntConfigString(source, include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	return resolveLateHelperFunctionCalls(resolved, include, terragruntOptions)
+}
+
+// Resolve the calls to helper functions in the late phase that were left in place while parsing the config
+func resolveLateHelperFunctionCalls(str string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (resolved string, finalErr error) {
+	// The function we pass to ReplaceAllStringFunc cannot return an error, so we have to use named error parameters to capture such errors.
+	resolved = INTERPOLATION_SYNTAX_REGEX.ReplaceAllStringFunc(str, func(call string) string {
+		if !isLateHelperFunctionCall(call) {
+			return call
+		}
+
+		out, err := resolveTerragruntInterpolation(call, include, terragruntOptions)
+		if err != nil {
+			finalErr = err
+			return call
+		}
+
+		return fmt.Sprintf("%v", out)
+	})
+	return
+}
+
+// Return the name of the first call to a helper function in the given string that is resolved late, or an empty string
+// if there is no such call
+func findLateHelperFunctionCall(str string) string {
+	for _, call := range INTERPOLATION_SYNTAX_REGEX.FindAllString(str, -1) {
+		if isLateHelperFunctionCall(call) {
+			return helperFunctionName(call)
+		}
+	}
+	return ""
+}
+
+// Return true if the given call to a helper function, such as ${get_terraform_workspace()}, is resolved late
+func isLateHelperFunctionCall(call string) bool {
+	helperFunction, isKnown := HELPER_FUNCTIONS[helperFunctionName(call)]
+	return isKnown && helperFunction.Phase == HelperPhaseLate
+}
+
+// Return the name of the helper function in the given call, such as get_env for ${get_env("FOO", "bar")}
+func helperFunctionName(call string) string {
+	matches := HELPER_FUNCTION_SYNTAX_REGEX.FindStringSubmatch(strings.TrimSpace(call))
+	if len(matches) != 3 {
+		return ""
+	}
+	return strings.TrimSpace(matches[1])
+}
+
 // Resolve a single call to an interpolation function of the format ${some_function()} in a Terragrunt configuration
 func resolveTerragruntInterpolation(str string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
 	matches := HELPER_FUNCTION_SYNTAX_REGEX.FindStringSubmatch(str)
@@ -163,7 +669,8 @@ func resolveTerragruntInterpolation(str string, include *IncludeConfig, terragru
 	}
 }
 
-// Return the directory where the Terragrunt configuration file lives
+// Return the directory where the Terragrunt configuration file lives. Helpers that take a relative path, such as
+// filebase64 and subdirs, resolve it relative to this directory, which doesn't change when Terraform code is downloaded.
 func getTfVarsDir(terragruntOptions *options.TerragruntOptions) (string, error) {
 	terragruntConfigFileAbsPath, err := filepath.Abs(terragruntOptions.TerragruntConfigPath)
 	if err != nil {
@@ -173,235 +680,2956 @@ func getTfVarsDir(terragruntOptions *options.TerragruntOptions) (string, error)
 	return filepath.ToSlash(filepath.Dir(terragruntConfigFileAbsPath)), nil
 }
 
-// Return the parent directory where the Terragrunt configuration file lives
-func getParentTfVarsDir(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
-	parentPath, err := pathRelativeFromInclude(include, terragruntOptions)
-	if err != nil {
-		return "", errors.WithStackTrace(err)
+// Return the working directory of the module before any Terraform code was downloaded into a temporary folder: the
+// folder the user ran Terragrunt in (or passed to --terragrunt-working-dir), or the module's folder for the modules of
+// an xxx-all command
+func getOriginalTerragruntDir(terragruntOptions *options.TerragruntOptions) (string, error) {
+	// Options not created using one of the constructors, such as in tests, may not have an original working dir
+	originalWorkingDir := terragruntOptions.OriginalWorkingDir
+	if originalWorkingDir == "" {
+		originalWorkingDir = terragruntOptions.WorkingDir
 	}
 
-	currentPath := filepath.Dir(terragruntOptions.TerragruntConfigPath)
-	parentPath, err = filepath.Abs(filepath.Join(currentPath, parentPath))
+	originalWorkingDirAbsPath, err := filepath.Abs(originalWorkingDir)
 	if err != nil {
 		return "", errors.WithStackTrace(err)
 	}
 
-	return filepath.ToSlash(parentPath), nil
+	return filepath.ToSlash(originalWorkingDirAbsPath), nil
 }
 
-func parseGetEnvParameters(parameters string) (EnvVar, error) {
-	envVariable := EnvVar{}
-	matches := HELPER_FUNCTION_GET_ENV_PARAMETERS_SYNTAX_REGEX.FindStringSubmatch(parameters)
-	if len(matches) < 2 {
-		return envVariable, errors.WithStackTrace(InvalidGetEnvParams(parameters))
+// Return the name of the current Terraform workspace: the value of the TF_WORKSPACE env var, if set, or the workspace
+// last selected in the working dir using "terraform workspace select", or the default workspace otherwise. Unlike the
+// other helpers, this reads the working dir Terraform runs in, which is the download dir if the code was downloaded.
+func getTerraformWorkspace(terragruntOptions *options.TerragruntOptions) (string, error) {
+	if workspace := terragruntOptions.Env["TF_WORKSPACE"]; workspace != "" {
+		return workspace, nil
 	}
 
-	for index, name := range HELPER_FUNCTION_GET_ENV_PARAMETERS_SYNTAX_REGEX.SubexpNames() {
-		if name == "env" {
-			envVariable.Name = strings.TrimSpace(matches[index])
+	environmentFile := util.JoinPath(terragruntOptions.WorkingDir, ".terraform", "environment")
+	if util.FileExists(environmentFile) {
+		workspace, err := util.ReadFileAsString(environmentFile)
+		if err != nil {
+			return "", err
 		}
-		if name == "default" {
-			envVariable.DefaultValue = strings.TrimSpace(matches[index])
+		if workspace = strings.TrimSpace(workspace); workspace != "" {
+			return workspace, nil
 		}
 	}
 
-	return envVariable, nil
+	return DEFAULT_TERRAFORM_WORKSPACE, nil
 }
 
-func getEnvironmentVariable(parameters string, terragruntOptions *options.TerragruntOptions) (string, error) {
-	parameterMap, err := parseGetEnvParameters(parameters)
+// Return true if the Terraform command Terragrunt runs, such as apply or plan, is one of TERRAFORM_COMMANDS_NEED_VARS,
+// which read var files, and false for commands that don't, such as output or state. For the xxx-all commands, each
+// module's config is resolved again with the command that runs in it, so it's false while the stack is being resolved.
+func commandNeedsVars(terragruntOptions *options.TerragruntOptions) bool {
+	return util.ListContainsElement(TERRAFORM_COMMANDS_NEED_VARS, terragruntOptions.TerraformCommand)
+}
 
-	if err != nil {
-		return "", errors.WithStackTrace(err)
+// Return the name of the first of CLOUD_PROVIDERS whose credentials are available, or UNKNOWN_CLOUD if there are none.
+// Credentials are available if one of the provider's env vars is set, or, failing that for every provider, if one of
+// its credential files exists in the home dir. Instance metadata services are never queried, as that would slow down
+// parsing everywhere else, but the env vars they're exposed through, such as MSI_ENDPOINT, count.
+func detectCloud(terragruntOptions *options.TerragruntOptions) string {
+	for _, provider := range CLOUD_PROVIDERS {
+		for _, envVar := range provider.EnvVars {
+			if terragruntOptions.Env[envVar] != "" {
+				return provider.Name
+			}
+		}
 	}
-	envValue, exists := terragruntOptions.Env[parameterMap.Name]
 
-	if !exists {
-		envValue = parameterMap.DefaultValue
+	homeDir := terragruntOptions.Env["HOME"]
+	if homeDir == "" {
+		homeDir = terragruntOptions.Env["USERPROFILE"]
+	}
+	if homeDir == "" {
+		return UNKNOWN_CLOUD
 	}
 
-	return envValue, nil
+	for _, provider := range CLOUD_PROVIDERS {
+		for _, credentialFile := range provider.CredentialFiles {
+			if util.FileExists(util.JoinPath(homeDir, credentialFile)) {
+				return provider.Name
+			}
+		}
+	}
+
+	return UNKNOWN_CLOUD
 }
 
-// Find a parent Terragrunt configuration file in the parent folders above the current Terragrunt configuration file
-// and return its path
-func findInParentFolders(parameters string, terragruntOptions *options.TerragruntOptions) (string, error) {
-	fileToFindParam, fallbackParam, numParams, err := parseOptionalQuotedParam(parameters)
+// Return the output of "git describe --tags --always" for the repo the Terragrunt configuration file lives in: the most
+// recent tag, with the number of commits since that tag and the short SHA appended if the current commit isn't tagged,
+// or just the short SHA if the repo has no tags at all
+func getGitDescribe(terragruntOptions *options.TerragruntOptions) (string, error) {
+	terragruntConfigDir, err := getTfVarsDir(terragruntOptions)
 	if err != nil {
 		return "", err
 	}
-	if numParams > 0 && fileToFindParam == "" {
-		return "", errors.WithStackTrace(EmptyStringNotAllowed("parameter to the find_in_parent_folders_function"))
+	return gitDescribe(GIT_COMMAND, terragruntConfigDir)
+}
+
+func gitDescribe(gitCommand string, dir string) (string, error) {
+	if _, err := exec.LookPath(gitCommand); err != nil {
+		return "", errors.WithStackTrace(GitNotAvailable{Command: gitCommand, Underlying: err})
 	}
 
-	previousDir, err := filepath.Abs(filepath.Dir(terragruntOptions.TerragruntConfigPath))
-	previousDir = filepath.ToSlash(previousDir)
+	cmd := exec.Command(gitCommand, "describe", "--tags", "--always")
+	cmd.Dir = dir
 
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", errors.WithStackTrace(err)
+		return "", errors.WithStackTrace(GitDescribeFailed{Dir: dir, Output: strings.TrimSpace(string(output)), Underlying: err})
 	}
 
-	fileToFindStr := fmt.Sprintf("%s or %s", DefaultTerragruntConfigPath, OldTerragruntConfigPath)
-	if fileToFindParam != "" {
-		fileToFindStr = fileToFindParam
-	}
+	return strings.TrimSpace(string(output)), nil
+}
 
-	// To avoid getting into an accidental infinite loop (e.g. do to cyclical symlinks), set a max on the number of
-	// parent folders we'll check
-	for i := 0; i < terragruntOptions.MaxFoldersToCheck; i++ {
-		currentDir := filepath.ToSlash(filepath.Dir(previousDir))
-		if currentDir == previousDir {
-			if numParams == 2 {
-				return fallbackParam, nil
-			}
-			return "", errors.WithStackTrace(ParentFileNotFound{Path: terragruntOptions.TerragruntConfigPath, File: fileToFindStr, Cause: "Traversed all the way to the root"})
-		}
+// The calls of run_cmd, keyed by the working dir, command, and args they were run with, so the same command is only run
+// once, even if it's called in several places of a config or, at the same time, in the configs of an xxx-all run.
+// Calls that failed are removed once they're done, so that retry can run the command again.
+var runCmdCache = map[string]*runCmdCall{}
+var runCmdCacheLock sync.Mutex
+
+// A call of run_cmd. The output and error are only set once done is closed, so callers of the same command wait on done
+// rather than on runCmdCacheLock, which is only held while the cache itself is read or written.
+type runCmdCall struct {
+	done   chan struct{}
+	output string
+	err    error
+}
 
-		fileToFind := DefaultConfigPath(currentDir)
-		if fileToFindParam != "" {
-			fileToFind = util.JoinPath(currentDir, fileToFindParam)
-		}
+// Run the command passed as the first parameter, with the rest of the parameters as its args, in the working dir of the
+// given options, and return its stdout, without the trailing newline. Any of the parameters may be calls to helper
+// functions, such as "${get_env("SECRETS_PATH", "")}". For example:
+//
+// run_cmd("git", "rev-parse", "HEAD") -> "3a1b2c4d..."
+//
+// Stdout is only returned, not written to the terminal, as it may be a secret, while stderr is written to the terminal.
+func runCmd(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) == 0 {
+		return "", errors.WithStackTrace(InvalidRunCmdParams(parameters))
+	}
 
-		if util.FileExists(fileToFind) {
-			return util.GetPathRelativeTo(fileToFind, filepath.Dir(terragruntOptions.TerragruntConfigPath))
+	resolvedParams := []string{}
+	for _, param := range params {
+		value, err := resolveDeferredParam(param, include, terragruntOptions)
+		if err != nil {
+			return "", err
 		}
-
-		previousDir = currentDir
+		resolvedParams = append(resolvedParams, fmt.Sprintf("%v", value))
 	}
+	command, args := resolvedParams[0], resolvedParams[1:]
 
-	return "", errors.WithStackTrace(ParentFileNotFound{Path: terragruntOptions.TerragruntConfigPath, File: fileToFindStr, Cause: fmt.Sprintf("Exceeded maximum folders to check (%d)", terragruntOptions.MaxFoldersToCheck)})
-}
+	cacheKey := strings.Join(append([]string{terragruntOptions.WorkingDir}, resolvedParams...), "\x00")
 
-var oneQuotedParamRegex = regexp.MustCompile(`^"([^"]*?)"$`)
-var twoQuotedParamsRegex = regexp.MustCompile(`^"([^"]*?)"\s*,\s*"([^"]*?)"$`)
+	runCmdCacheLock.Lock()
+	call, isCached := runCmdCache[cacheKey]
+	if !isCached {
+		call = &runCmdCall{done: make(chan struct{})}
+		runCmdCache[cacheKey] = call
+	}
+	runCmdCacheLock.Unlock()
 
-// Parse two optional parameters, wrapped in quotes, passed to a function, and return the parameter values and how many
-// of the parameters were actually set. For example, if you have a function foo(bar, baz), where bar and baz are
-// optional string parameters, this function will behave as follows:
-//
-// foo() -> return "", "", 0, nil
-// foo("a") -> return "a", "", 1, nil
-// foo("a", "b") -> return "a", "b", 2, nil
-//
-func parseOptionalQuotedParam(parameters string) (string, string, int, error) {
-	trimmedParameters := strings.TrimSpace(parameters)
-	if trimmedParameters == "" {
-		return "", "", 0, nil
+	if isCached {
+		<-call.done
+		return call.output, call.err
 	}
 
-	matches := oneQuotedParamRegex.FindStringSubmatch(trimmedParameters)
-	if len(matches) == 2 {
-		return matches[1], "", 1, nil
+	call.output, call.err = runCmdUncached(command, args, terragruntOptions)
+	if call.err != nil {
+		runCmdCacheLock.Lock()
+		delete(runCmdCache, cacheKey)
+		runCmdCacheLock.Unlock()
 	}
+	close(call.done)
 
-	matches = twoQuotedParamsRegex.FindStringSubmatch(trimmedParameters)
-	if len(matches) == 3 {
-		return matches[1], matches[2], 2, nil
+	return call.output, call.err
+}
+
+// Run the given command with the given args, in the working dir of the given options, and return its stdout, without
+// the trailing newline
+func runCmdUncached(command string, args []string, terragruntOptions *options.TerragruntOptions) (string, error) {
+	cmdOutput, err := shell.RunShellCommandAndCaptureOutput(terragruntOptions, command, args...)
+	if err != nil {
+		exitCode, exitCodeErr := shell.GetExitCode(err)
+		if exitCodeErr != nil {
+			exitCode = -1
+		}
+		return "", errors.WithStackTrace(RunCmdFailed{Command: command, Args: args, ExitCode: exitCode, Underlying: err})
 	}
 
-	return "", "", 0, errors.WithStackTrace(InvalidStringParams(parameters))
+	return strings.TrimSuffix(strings.TrimSuffix(cmdOutput.Stdout, "\n"), "\r"), nil
 }
 
-// Return the relative path between the included Terragrunt configuration file and the current Terragrunt configuration
-// file
-func pathRelativeToInclude(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
-	if include == nil {
-		return ".", nil
+// The errors of helper functions that retry tries again, on top of the RetryableErrors in the options, which are about
+// Terraform. They're the errors of side-effecting helpers that are usually transient, such as a request that timed out
+// or a server that was briefly unavailable.
+var RETRYABLE_HELPER_ERRORS = []string{
+	// http_get_json couldn't connect or timed out
+	"(?s)^Unable to fetch .*",
+	// http_get_json got a response that may well be different next time
+	"(?s)^Fetching .* failed with status code (408|429|5\\d\\d)$",
+	// The command run_cmd ran failed
+	"(?s)^Running .* with run_cmd failed with exit code .*",
+	// AWS API calls, such as the one get_aws_account_id makes, that were throttled or didn't get through
+	"(?is).*(throttl|rate exceeded|RequestError|timed out|timeout|connection reset|connection refused).*",
+}
+
+// Resolve the call to a helper function passed as the first parameter, such as "${http_get_json(...)}", up to as many
+// times as the second parameter says, waiting as many seconds as the third parameter says after each failed attempt.
+// Returns the value of the first attempt that succeeds, or the error of the last attempt. Only errors that match
+// RETRYABLE_HELPER_ERRORS or the RetryableErrors in the given options are retried; any other error, such as a typo in
+// the call, is returned right away. For example:
+//
+// retry("${http_get_json("https://config.example.com/vpc.json", "cidr")}", "3", "2") -> "10.0.0.0/16"
+//
+// The number of attempts and the delay may themselves be calls to helper functions, such as "${get_env("RETRIES", "3")}".
+func retry(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 3 {
+		return "", errors.WithStackTrace(InvalidRetryParams(parameters))
 	}
 
-	includedConfigPath, err := ResolveTerragruntConfigString(include.Path, include, terragruntOptions)
+	attemptsValue, err := resolveDeferredParam(params[1], include, terragruntOptions)
 	if err != nil {
-		return "", errors.WithStackTrace(err)
+		return "", err
+	}
+	attempts, isWhole, isNumber := parseNumber(attemptsValue)
+	if !isNumber || !isWhole || attempts < 1 {
+		return "", errors.WithStackTrace(InvalidRetryParams(parameters))
 	}
 
-	includePath := filepath.Dir(includedConfigPath)
-	currentPath := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+	delayValue, err := resolveDeferredParam(params[2], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+	delaySeconds, _, isNumber := parseNumber(delayValue)
+	if !isNumber || delaySeconds < 0 {
+		return "", errors.WithStackTrace(InvalidRetryParams(parameters))
+	}
+	delay := time.Duration(delaySeconds * float64(time.Second))
 
-	if !filepath.IsAbs(includePath) {
-		includePath = util.JoinPath(currentPath, includePath)
+	expression := strings.TrimSpace(params[0])
+	for attempt := 1; ; attempt++ {
+		value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+		if err == nil {
+			return value, nil
+		}
+		if attempt >= int(attempts) || !isRetryableHelperError(err, terragruntOptions) {
+			return "", err
+		}
+
+		terragruntOptions.Logger.Printf("Attempt %d of %d to resolve %s failed, retrying in %v: %v", attempt, int(attempts), expression, delay, err)
+		time.Sleep(delay)
 	}
+}
 
-	return util.GetPathRelativeTo(currentPath, includePath)
+// Returns true if the given error of a helper function is worth trying again, as described in retry
+func isRetryableHelperError(err error, terragruntOptions *options.TerragruntOptions) bool {
+	return util.MatchesAny(RETRYABLE_HELPER_ERRORS, err.Error()) || util.MatchesAny(terragruntOptions.RetryableErrors, err.Error())
 }
 
-// Return the relative path from the current Terragrunt configuration to the included Terragrunt configuration file
-func pathRelativeFromInclude(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
-	if include == nil {
-		return ".", nil
+// Return the time the current Terragrunt config file was last modified, in UTC, formatted with the Go time layout passed
+// to get_config_mtime, such as "2006-01-02", or as RFC3339 if none is passed. The modification time is read from the
+// file system on every call, so it's never stale. The layout may contain calls to helper functions.
+func getConfigMtime(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) > 1 {
+		return "", errors.WithStackTrace(InvalidStringParams(parameters))
 	}
 
-	includedConfigPath, err := ResolveTerragruntConfigString(include.Path, include, terragruntOptions)
-	if err != nil {
-		return "", errors.WithStackTrace(err)
+	layout := ""
+	if len(params) == 1 {
+		layout, err = resolveDeferredStringParam(params[0], include, terragruntOptions)
+		if err != nil {
+			return "", err
+		}
+	}
+	if layout == "" {
+		layout = time.RFC3339
 	}
 
-	includePath := filepath.Dir(includedConfigPath)
-	currentPath := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+	if terragruntOptions.TerragruntConfigPath == "" {
+		return "", errors.WithStackTrace(ConfigPathUnknown("get_config_mtime"))
+	}
 
-	if !filepath.IsAbs(includePath) {
-		includePath = util.JoinPath(currentPath, includePath)
+	info, err := os.Stat(terragruntOptions.TerragruntConfigPath)
+	if err != nil {
+		return "", errors.WithStackTrace(err)
 	}
 
-	return util.GetPathRelativeTo(includePath, currentPath)
+	return info.ModTime().UTC().Format(layout), nil
 }
 
-// Return the AWS account id associated to the current set of credentials
-func getAWSAccountID(terragruntOptions *options.TerragruntOptions) (string, error) {
-	sess, err := session.NewSession()
+// Return the absolute path of the directory Terragrunt downloads the Terraform code in the source parameter of the
+// current config to. It's the configured directory, so it's returned even before anything has been downloaded into it.
+func getModuleDownloadDir(terragruntOptions *options.TerragruntOptions) (string, error) {
+	downloadDirAbsPath, err := filepath.Abs(terragruntOptions.DownloadDir)
 	if err != nil {
 		return "", errors.WithStackTrace(err)
 	}
 
-	if terragruntOptions.IamRole != "" {
-		sess.Config.Credentials = stscreds.NewCredentials(sess, terragruntOptions.IamRole)
-	}
+	return filepath.ToSlash(downloadDirAbsPath), nil
+}
 
-	identity, err := sts.New(sess).GetCallerIdentity(nil)
+// Return the parent directory where the Terragrunt configuration file lives
+func getParentTfVarsDir(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	parentPath, err := pathRelativeFromInclude(include, terragruntOptions)
 	if err != nil {
 		return "", errors.WithStackTrace(err)
 	}
 
-	return *identity.Account, nil
-}
+	currentPath := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+	parentPath, err = filepath.Abs(filepath.Join(currentPath, parentPath))
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	return filepath.ToSlash(parentPath), nil
+}
+
+// Return the path of the included Terragrunt configuration file as the including config declared it in its include
+// block, which may be relative to the including config's folder. Unlike get_parent_tfvars_dir, the path isn't made
+// absolute; only calls to helper functions in it, such as find_in_parent_folders, are resolved.
+func getIncludePath(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	if include == nil {
+		return "", errors.WithStackTrace(NoIncludeConfig{FunctionName: "get_include_path", ConfigPath: terragruntOptions.TerragruntConfigPath})
+	}
+	return ResolveTerragruntConfigString(include.Path, include, terragruntOptions)
+}
+
+// Return the absolute path of the folder the Terragrunt configuration file being processed lives in. Unlike
+// get_tfvars_dir, any symlinks in the path are resolved, so the same folder always gets the same path, no matter how it
+// was reached.
+func getTerragruntDir(terragruntOptions *options.TerragruntOptions) (string, error) {
+	terragruntDir, err := util.CanonicalPathWithSymlinks(filepath.Dir(terragruntOptions.TerragruntConfigPath), ".")
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	return terragruntDir, nil
+}
+
+// Return the absolute path of the folder the included Terragrunt configuration file lives in, with any symlinks in it
+// resolved. Unlike get_parent_tfvars_dir, which falls back to the current folder, using it in a config that isn't
+// included by another config is an error.
+func getParentTerragruntDir(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	if include == nil {
+		return "", errors.WithStackTrace(NoIncludeConfig{FunctionName: "get_parent_terragrunt_dir", ConfigPath: terragruntOptions.TerragruntConfigPath})
+	}
+
+	parentTfVarsDir, err := getParentTfVarsDir(include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	parentTerragruntDir, err := util.CanonicalPathWithSymlinks(parentTfVarsDir, ".")
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	return parentTerragruntDir, nil
+}
+
+func parseGetEnvParameters(parameters string) (EnvVar, error) {
+	envVariable := EnvVar{}
+	matches := HELPER_FUNCTION_GET_ENV_PARAMETERS_SYNTAX_REGEX.FindStringSubmatch(parameters)
+	if len(matches) < 2 {
+		return envVariable, errors.WithStackTrace(InvalidGetEnvParams(parameters))
+	}
+
+	for index, name := range HELPER_FUNCTION_GET_ENV_PARAMETERS_SYNTAX_REGEX.SubexpNames() {
+		if name == "env" {
+			envVariable.Name = strings.TrimSpace(matches[index])
+		}
+		if name == "default" {
+			envVariable.DefaultValue = strings.TrimSpace(matches[index])
+		}
+		if name == "hasDefault" {
+			envVariable.HasDefault = matches[index] != ""
+		}
+	}
+
+	return envVariable, nil
+}
+
+// Return the value of the given environment variable, which may be set to an empty string, or the default value, if
+// one was given and the variable isn't set. Without a default value, the variable must be set.
+func getEnvironmentVariable(parameters string, terragruntOptions *options.TerragruntOptions) (string, error) {
+	parameterMap, err := parseGetEnvParameters(parameters)
+
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	envValue, exists := terragruntOptions.Env[parameterMap.Name]
+
+	if !exists {
+		if !parameterMap.HasDefault {
+			return "", errors.WithStackTrace(EnvVarNotFound(parameterMap.Name))
+		}
+		envValue = parameterMap.DefaultValue
+	}
+
+	return envValue, nil
+}
+
+// Return the value of the given key in the given dotenv file (e.g. .env). Relative paths are resolved relative to the
+// directory of the current Terragrunt configuration file.
+func getDotEnv(parameters string, terragruntOptions *options.TerragruntOptions) (string, error) {
+	path, key, numParams, err := parseOptionalQuotedParam(parameters)
+	if err != nil {
+		return "", err
+	}
+	if numParams != 2 {
+		return "", errors.WithStackTrace(InvalidGetDotEnvParams(parameters))
+	}
+	if path == "" {
+		return "", errors.WithStackTrace(EmptyStringNotAllowed("path parameter to the get_dotenv function"))
+	}
+	if key == "" {
+		return "", errors.WithStackTrace(EmptyStringNotAllowed("key parameter to the get_dotenv function"))
+	}
+
+	if !filepath.IsAbs(path) {
+		path = util.JoinPath(filepath.Dir(terragruntOptions.TerragruntConfigPath), path)
+	}
+
+	contents, err := util.ReadFileAsString(path)
+	if err != nil {
+		return "", err
+	}
+
+	value, exists := util.ParseDotEnv(contents)[key]
+	if !exists {
+		return "", errors.WithStackTrace(DotEnvKeyNotFound{Path: path, Key: key})
+	}
+
+	return value, nil
+}
+
+// Return the contents of the given file, base64 encoded, as Terraform's filebase64 function does. The contents are
+// encoded exactly as they are on disk, so this works for binary files such as certificates and keys.
+func fileBase64(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	path, err := parseFilePathParam("filebase64", parameters, include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(contents), nil
+}
+
+// Return the lowercase hex SHA256 digest of the contents of the given file, as Terraform's filesha256 function does.
+// The file is streamed through the hash, so large files aren't loaded into memory.
+func fileSha256(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	path, err := parseFilePathParam("filesha256", parameters, include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Parse the single path parameter of a helper function that reads a file, such as filebase64, and return the path
+// of the file, after resolving any calls to helper functions in it, such as "${get_terragrunt_dir()}/cert.pem".
+// Relative paths are resolved relative to the directory of the current Terragrunt configuration file.
+func parseFilePathParam(functionName string, parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return "", errors.WithStackTrace(InvalidStringParams(parameters))
+	}
+
+	path, err := resolveDeferredStringParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", errors.WithStackTrace(EmptyStringNotAllowed(fmt.Sprintf("parameter to the %s function", functionName)))
+	}
+
+	if !filepath.IsAbs(path) {
+		path = util.JoinPath(filepath.Dir(terragruntOptions.TerragruntConfigPath), path)
+	}
+
+	if !util.FileExists(path) {
+		return "", errors.WithStackTrace(FileNotFound(path))
+	}
+
+	return path, nil
+}
+
+// Return the sorted names of the immediate subdirectories of the given directory. Relative paths are resolved relative
+// to the directory of the current Terragrunt configuration file. Hidden directories, whose names start with a dot, are
+// only included if the optional second parameter is "true". Either parameter may contain calls to helper functions.
+func subdirs(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) ([]string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) < 1 || len(params) > 2 {
+		return nil, errors.WithStackTrace(InvalidSubdirsParams(parameters))
+	}
+
+	path, err := resolveDeferredStringParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, errors.WithStackTrace(EmptyStringNotAllowed("path parameter to the subdirs function"))
+	}
+
+	includeHidden := false
+	if len(params) == 2 {
+		includeHiddenParam, err := resolveDeferredStringParam(params[1], include, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(strings.TrimSpace(includeHiddenParam)) {
+		case "true":
+			includeHidden = true
+		case "false":
+			includeHidden = false
+		default:
+			return nil, errors.WithStackTrace(InvalidSubdirsParams(parameters))
+		}
+	}
+
+	if !filepath.IsAbs(path) {
+		path = util.JoinPath(filepath.Dir(terragruntOptions.TerragruntConfigPath), path)
+	}
+
+	if !util.IsDir(path) {
+		return nil, errors.WithStackTrace(NotADirectory(path))
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	// ReadDir returns the entries sorted by name. Check each with IsDir, rather than the mode ReadDir returns, so
+	// symlinks to directories count as directories.
+	names := []string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") && !includeHidden {
+			continue
+		}
+		if util.IsDir(filepath.Join(path, entry.Name())) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// Find a parent Terragrunt configuration file in the parent folders above the current Terragrunt configuration file
+// and return its path. The first parameter, if any, is the name of the file to find instead, and the second one, if
+// any, is returned when there's no such file, instead of a ParentFileNotFound error. The parameters may be calls to
+// other helper functions, such as "${get_env("CONFIG_NAME")}".
+func findInParentFolders(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	fileToFindParam, fallbackParam, numParams, err := resolveOptionalQuotedParams(parameters, include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+	if numParams > 0 && fileToFindParam == "" {
+		return "", errors.WithStackTrace(EmptyStringNotAllowed("parameter to the find_in_parent_folders_function"))
+	}
+
+	previousDir, err := filepath.Abs(filepath.Dir(terragruntOptions.TerragruntConfigPath))
+	previousDir = filepath.ToSlash(previousDir)
+
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	fileToFindStr := fmt.Sprintf("%s, %s, or %s", DefaultTerragruntConfigPath, DedicatedTerragruntConfigPath, OldTerragruntConfigPath)
+	if fileToFindParam != "" {
+		fileToFindStr = fileToFindParam
+	}
+
+	// To avoid getting into an accidental infinite loop (e.g. do to cyclical symlinks), set a max on the number of
+	// parent folders we'll check
+	for i := 0; i < terragruntOptions.MaxFoldersToCheck; i++ {
+		currentDir := filepath.ToSlash(filepath.Dir(previousDir))
+		if currentDir == previousDir {
+			return parentFileNotFound(fallbackParam, numParams, ParentFileNotFound{Path: terragruntOptions.TerragruntConfigPath, File: fileToFindStr, Cause: "Traversed all the way to the root"})
+		}
+
+		fileToFind := DefaultConfigPath(currentDir)
+		if fileToFindParam != "" {
+			fileToFind = util.JoinPath(currentDir, fileToFindParam)
+		}
+
+		if util.FileExists(fileToFind) {
+			return util.GetPathRelativeTo(fileToFind, filepath.Dir(terragruntOptions.TerragruntConfigPath))
+		}
+
+		previousDir = currentDir
+	}
+
+	return parentFileNotFound(fallbackParam, numParams, ParentFileNotFound{Path: terragruntOptions.TerragruntConfigPath, File: fileToFindStr, Cause: fmt.Sprintf("Exceeded maximum folders to check (%d)", terragruntOptions.MaxFoldersToCheck)})
+}
+
+// Return the fallback passed to find_in_parent_folders as its second parameter, if any, or the given error otherwise,
+// for when the search for the file failed
+func parentFileNotFound(fallbackParam string, numParams int, err ParentFileNotFound) (string, error) {
+	if numParams == 2 {
+		return fallbackParam, nil
+	}
+	return "", errors.WithStackTrace(err)
+}
+
+// Walk from the directory of the current Terragrunt configuration file up to the directory of the included
+// configuration file (or the root of the file system if there is no include), collecting every file that matches the
+// given glob along the way. The files are returned as absolute paths, ordered from the root to the leaf, so that when
+// they are passed to Terraform as var files, the ones closest to the current configuration take precedence.
+func collectParentFiles(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) ([]string, error) {
+	glob, err := parseOneQuotedParam(parameters)
+	if err != nil {
+		return nil, err
+	}
+	if glob == "" {
+		return nil, errors.WithStackTrace(EmptyStringNotAllowed("parameter to the collect_parent_files function"))
+	}
+
+	currentDir, err := filepath.Abs(filepath.Dir(terragruntOptions.TerragruntConfigPath))
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	stopDir := ""
+	if include != nil {
+		stopDir, err = getParentTfVarsDir(include, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+		stopDir = filepath.Clean(stopDir)
+	}
+
+	// Collect the matches from the leaf up and reverse them at the end so that the root comes first
+	matchesPerFolder := [][]string{}
+
+	// To avoid getting into an accidental infinite loop (e.g. do to cyclical symlinks), set a max on the number of
+	// parent folders we'll check
+	for i := 0; i < terragruntOptions.MaxFoldersToCheck; i++ {
+		matches, err := filepath.Glob(filepath.Join(currentDir, glob))
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+
+		files := []string{}
+		for _, match := range matches {
+			if util.IsFile(match) {
+				files = append(files, filepath.ToSlash(match))
+			}
+		}
+		matchesPerFolder = append(matchesPerFolder, files)
+
+		parentDir := filepath.Dir(currentDir)
+		if currentDir == stopDir || parentDir == currentDir {
+			out := []string{}
+			for j := len(matchesPerFolder) - 1; j >= 0; j-- {
+				out = append(out, matchesPerFolder[j]...)
+			}
+			return out, nil
+		}
+
+		currentDir = parentDir
+	}
+
+	return nil, errors.WithStackTrace(ParentFileNotFound{Path: terragruntOptions.TerragruntConfigPath, File: glob, Cause: fmt.Sprintf("Exceeded maximum folders to check (%d)", terragruntOptions.MaxFoldersToCheck)})
+}
+
+var oneQuotedParamRegex = regexp.MustCompile(`^"([^"]*?)"$`)
+var twoQuotedParamsRegex = regexp.MustCompile(`^"([^"]*?)"\s*,\s*"([^"]*?)"$`)
+var nextQuotedParamRegex = regexp.MustCompile(fmt.Sprintf(`^"(%s)"\s*(,|$)`, quotedParamSyntax(MAX_NESTED_HELPER_FUNCTION_CALLS-1)))
+
+// Return the regex syntax for what's between the quotes of a parameter to a helper function: any text without quotes,
+// plus, up to the given depth, calls to helper functions, whose own parameters may contain quotes
+func quotedParamSyntax(depth int) string {
+	if depth == 0 {
+		return `[^"]*?`
+	}
+	return fmt.Sprintf(`(?:%s|[^"])*`, helperFunctionCallSyntax(depth-1))
+}
+
+// Return the regex syntax for a call to a helper function, such as ${foo("a", "b")}, whose parameters may contain
+// calls to other helper functions up to the given depth
+func helperFunctionCallSyntax(depth int) string {
+	return fmt.Sprintf(`\$\{\s*\w+\((?:\s*"%s"\s*,?\s*)*\)\s*\}`, quotedParamSyntax(depth))
+}
+
+// Parse two optional parameters, wrapped in quotes, passed to a function, and return the parameter values and how many
+// of the parameters were actually set. For example, if you have a function foo(bar, baz), where bar and baz are
+// optional string parameters, this function will behave as follows:
+//
+// foo() -> return "", "", 0, nil
+// foo("a") -> return "a", "", 1, nil
+// foo("a", "b") -> return "a", "b", 2, nil
+//
+func parseOptionalQuotedParam(parameters string) (string, string, int, error) {
+	trimmedParameters := strings.TrimSpace(parameters)
+	if trimmedParameters == "" {
+		return "", "", 0, nil
+	}
+
+	matches := oneQuotedParamRegex.FindStringSubmatch(trimmedParameters)
+	if len(matches) == 2 {
+		return matches[1], "", 1, nil
+	}
+
+	matches = twoQuotedParamsRegex.FindStringSubmatch(trimmedParameters)
+	if len(matches) == 3 {
+		return matches[1], matches[2], 2, nil
+	}
+
+	return "", "", 0, errors.WithStackTrace(InvalidStringParams(parameters))
+}
+
+// Like parseOptionalQuotedParam, but the parameters may also be calls to helper functions, such as "${get_env("A")}",
+// which are resolved and formatted as strings
+func resolveOptionalQuotedParams(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, string, int, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) > 2 {
+		return "", "", 0, errors.WithStackTrace(InvalidStringParams(parameters))
+	}
+
+	values := []string{"", ""}
+	for index, param := range params {
+		value, err := resolveDeferredParam(param, include, terragruntOptions)
+		if err != nil {
+			return "", "", 0, err
+		}
+		values[index] = fmt.Sprintf("%v", value)
+	}
+	return values[0], values[1], len(params), nil
+}
+
+// Parse exactly one parameter, wrapped in quotes, passed to a function, and return its value. For example:
+//
+// foo("a") -> return "a", nil
+// foo() -> return "", InvalidStringParams
+// foo("a", "b") -> return "", InvalidStringParams
+func parseOneQuotedParam(parameters string) (string, error) {
+	param, _, numParams, err := parseOptionalQuotedParam(parameters)
+	if err != nil {
+		return "", err
+	}
+	if numParams != 1 {
+		return "", errors.WithStackTrace(InvalidStringParams(parameters))
+	}
+	return param, nil
+}
+
+// Parse any number of parameters, each wrapped in quotes, passed to a function, and return their values. For example:
+//
+// foo() -> return [], nil
+// foo("a", "b", "c") -> return ["a", "b", "c"], nil
+// foo(a) -> return nil, InvalidStringParams
+func parseQuotedParams(parameters string) ([]string, error) {
+	params := []string{}
+
+	remaining := strings.TrimSpace(parameters)
+	for remaining != "" {
+		matches := nextQuotedParamRegex.FindStringSubmatch(remaining)
+		if len(matches) != 3 {
+			return nil, errors.WithStackTrace(InvalidStringParams(parameters))
+		}
+		params = append(params, matches[1])
+		remaining = strings.TrimSpace(remaining[len(matches[0]):])
+	}
+
+	return params, nil
+}
+
+// Return the relative path between the included Terragrunt configuration file and the current Terragrunt configuration
+// file
+func pathRelativeToInclude(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	if include == nil {
+		return ".", nil
+	}
+
+	includedConfigPath, err := ResolveTerragruntConfigString(include.Path, include, terragruntOptions)
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	includePath := filepath.Dir(includedConfigPath)
+	currentPath := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+
+	if !filepath.IsAbs(includePath) {
+		includePath = util.JoinPath(currentPath, includePath)
+	}
+
+	return util.GetPathRelativeTo(currentPath, includePath)
+}
+
+// Return the relative path from the current Terragrunt configuration to the included Terragrunt configuration file
+func pathRelativeFromInclude(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	if include == nil {
+		return ".", nil
+	}
+
+	includedConfigPath, err := ResolveTerragruntConfigString(include.Path, include, terragruntOptions)
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	includePath := filepath.Dir(includedConfigPath)
+	currentPath := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+
+	if !filepath.IsAbs(includePath) {
+		includePath = util.JoinPath(currentPath, includePath)
+	}
+
+	return util.GetPathRelativeTo(includePath, currentPath)
+}
+
+// Return the folder at the given (zero-based) position in the path from the included Terragrunt configuration file, the
+// root of the folder layout, to the current Terragrunt configuration file. For example, if the root config is in live/
+// and the current config is in live/prod/us-east-1/app/, position 0 is "prod" and position 1 is "us-east-1".
+func envFromPath(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	param, err := parseOneQuotedParam(parameters)
+	if err != nil {
+		return "", errors.WithStackTrace(InvalidEnvFromPathParams(parameters))
+	}
+
+	position, err := strconv.Atoi(strings.TrimSpace(param))
+	if err != nil {
+		return "", errors.WithStackTrace(InvalidEnvFromPathParams(parameters))
+	}
+
+	relativePath, err := pathRelativeToInclude(include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	segments := []string{}
+	for _, segment := range strings.Split(util.CleanPath(relativePath), "/") {
+		if segment != "" && segment != "." {
+			segments = append(segments, segment)
+		}
+	}
+
+	if position < 0 || position >= len(segments) {
+		return "", errors.WithStackTrace(PathSegmentOutOfRange{Position: position, Path: relativePath, NumSegments: len(segments)})
+	}
+
+	return segments[position], nil
+}
+
+// Return a map built from the given parameters, which alternate between keys and values. For example:
+//
+// makemap("Name", "vpc", "Env", "prod") -> {"Name" = "vpc", "Env" = "prod"}
+//
+// Keys must not be empty and must not be repeated. Any of the keys and values may be calls to helper functions, such as
+// "${get_env("ENV", "")}".
+func makeMap(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (map[string]string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil {
+		return nil, errors.WithStackTrace(InvalidMakeMapParams(parameters))
+	}
+	if len(params)%2 != 0 {
+		return nil, errors.WithStackTrace(OddNumberOfMakeMapParams(len(params)))
+	}
+
+	values := []string{}
+	for _, param := range params {
+		value, err := resolveDeferredStringParam(param, include, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	out := map[string]string{}
+	for i := 0; i < len(values); i += 2 {
+		key, value := values[i], values[i+1]
+		if key == "" {
+			return nil, errors.WithStackTrace(EmptyMakeMapKey(i))
+		}
+		if _, alreadyExists := out[key]; alreadyExists {
+			return nil, errors.WithStackTrace(DuplicateMakeMapKey(key))
+		}
+		out[key] = value
+	}
+
+	return out, nil
+}
+
+// Values of a feature flag env var that are considered to turn the flag on. Comparison is case insensitive.
+var TRUTHY_FLAG_VALUES = []string{"1", "true", "yes", "on"}
+
+// Return the given value if the feature flag env var with the given name is on (see TRUTHY_FLAG_VALUES), or the given
+// default otherwise. Either one may be a call to a helper function without parameters, such as
+// "${get_aws_account_id()}", in which case only the one that is returned is resolved, so the side effects of resolving
+// the other one never happen. For example:
+//
+// when_flag("USE_NEW_VPC", "${get_aws_account_id()}", "none") -> "none", without calling AWS, if USE_NEW_VPC is not set
+func whenFlag(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 3 || strings.TrimSpace(params[0]) == "" {
+		return "", errors.WithStackTrace(InvalidWhenFlagParams(parameters))
+	}
+
+	flagName, value, defaultValue := strings.TrimSpace(params[0]), params[1], params[2]
+
+	if isFlagOn(terragruntOptions.Env[flagName]) {
+		return resolveDeferredParam(value, include, terragruntOptions)
+	}
+	return resolveDeferredParam(defaultValue, include, terragruntOptions)
+}
+
+// Return true if the two given values, after resolving any calls to helper functions in them, are equal. The bools
+// returned by nested calls to eq and ne are equal to the strings "true" and "false", and numbers, such as those
+// returned by hash_bucket, are equal to the strings with their digits.
+func equals(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 2 {
+		return false, errors.WithStackTrace(InvalidEqualityParams(parameters))
+	}
+
+	values := []interface{}{}
+	for _, param := range params {
+		value, err := resolveDeferredParam(param, include, terragruntOptions)
+		if err != nil {
+			return false, err
+		}
+		values = append(values, comparableValue(value))
+	}
+
+	return reflect.DeepEqual(values[0], values[1]), nil
+}
+
+// Return the given value in the form eq and ne compare it in: bools and ints as the strings they're written as, and
+// any other value as is
+func comparableValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case bool:
+		return strconv.FormatBool(typedValue)
+	case int:
+		return strconv.Itoa(typedValue)
+	}
+	return value
+}
+
+// Return the resolved value of the second parameter if the first one is true, or of the third parameter otherwise.
+// The first parameter is typically a call to eq or ne, but may be any call that returns a bool, or the string "true"
+// or "false". Only the branch that is returned is resolved, so the other one may contain calls that would fail.
+func cond(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 3 {
+		return "", errors.WithStackTrace(InvalidCondParams(parameters))
+	}
+
+	condition, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	isTrue, err := parseCondition(condition)
+	if err != nil {
+		return "", err
+	}
+
+	if isTrue {
+		return resolveDeferredParam(params[1], include, terragruntOptions)
+	}
+	return resolveDeferredParam(params[2], include, terragruntOptions)
+}
+
+// Convert the given condition passed to cond to a bool
+func parseCondition(condition interface{}) (bool, error) {
+	switch condition := condition.(type) {
+	case bool:
+		return condition, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(condition)) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, errors.WithStackTrace(InvalidCondCondition(fmt.Sprintf("%v", condition)))
+}
+
+// Strings that truthy considers false, once trimmed and lowercased. Any other string is true.
+var FALSY_VALUES = []string{"", "0", "false", "no", "off"}
+
+// Return whether the given value, after resolving it if it's a call to a helper function, such as
+// "${get_env("DEBUG", "")}", is truthy. Unlike the condition of cond, which must be true or false, any value is
+// accepted, so flags set via env vars as "1", "Yes", "on", etc. all behave the same:
+//
+// - A string is false if it's one of FALSY_VALUES, ignoring case and surrounding whitespace, and true otherwise.
+// - A bool is itself, and a number is false if it's zero.
+// - A list or map is false if it's empty.
+// - A missing value (nil) is false.
+func truthy(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return false, errors.WithStackTrace(InvalidTruthyParams(parameters))
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return false, err
+	}
+
+	return isTruthy(value), nil
+}
+
+// Return whether the given resolved value is truthy, as described in truthy
+func isTruthy(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+
+	switch typedValue := value.(type) {
+	case string:
+		return !util.ListContainsElement(FALSY_VALUES, strings.ToLower(strings.TrimSpace(typedValue)))
+	case bool:
+		return typedValue
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflectValue.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflectValue.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return reflectValue.Float() != 0
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return reflectValue.Len() > 0
+	}
+	return true
+}
+
+// Return the given value, after resolving it if it's a call to a helper function, as a string. This pins the type of the
+// value: a call to string that is the only thing between quotes always results in a quoted string, even if the value is
+// a number, a bool, or a list, each of which would otherwise be written out unquoted. Values that aren't strings are
+// formatted the same way as when a call that returns them is part of a longer string.
+func stringify(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return "", errors.WithStackTrace(InvalidStringHelperParams(parameters))
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%v", value), nil
+}
+
+// Return the given value as is, after resolving it if it's a call to a helper function. With the FailOnEmpty option, a
+// call to a helper function that returns an empty value is an error, unless it's wrapped in allow_empty, such as
+// ${allow_empty("${get_env("OPTIONAL_SUFFIX", "")}")}.
+func allowEmpty(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return "", errors.WithStackTrace(InvalidAllowEmptyParams(parameters))
+	}
+
+	return resolveDeferredParam(params[0], include, terragruntOptions)
+}
+
+// Return the given value, after resolving it if it's a call to a helper function, with every run of whitespace collapsed
+// into a single space and the whitespace at either end removed. The value is still HCL source at this point, so the
+// escape sequences \n, \r, and \t count as whitespace too.
+func squashWhitespace(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return "", errors.WithStackTrace(InvalidSquashWhitespaceParams(parameters))
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(strings.Fields(replaceWhitespaceEscapes(fmt.Sprintf("%v", value))), " "), nil
+}
+
+// Replace the HCL escape sequences for whitespace in the given string with spaces, leaving other escape sequences, such
+// as an escaped backslash followed by an n, alone
+func replaceWhitespaceEscapes(str string) string {
+	var out strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' && i+1 < len(str) {
+			switch str[i+1] {
+			case 'n', 'r', 't':
+				out.WriteByte(' ')
+			default:
+				out.WriteByte(str[i])
+				out.WriteByte(str[i+1])
+			}
+			i++
+			continue
+		}
+		out.WriteByte(str[i])
+	}
+	return out.String()
+}
+
+// Return the given value, after resolving it if it's a call to a helper function, with all the ANSI SGR escape sequences,
+// which set the color and style of text in a terminal, removed. For example, the output of a tool that colors its
+// output, such as ${strip_ansi("${get_env("TOOL_OUTPUT", "")}")}, becomes plain text.
+func stripAnsi(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return "", errors.WithStackTrace(InvalidStripAnsiParams(parameters))
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	return ANSI_SGR_SEQUENCE_REGEX.ReplaceAllString(fmt.Sprintf("%v", value), ""), nil
+}
+
+// Return the longest string, if longest is true, or the shortest string otherwise, in the list returned by the call to a
+// helper function passed in the given parameters, such as "${get_terraform_commands_that_need_vars()}". Lengths are
+// counted in runes rather than bytes, and if several strings are equally long, the first one wins. The given function
+// name is only used in error messages.
+func stringByLength(functionName string, parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions, longest bool) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return "", errors.WithStackTrace(InvalidStringListParams{Function: functionName, Params: parameters})
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	list, isStringList := value.([]string)
+	if !isStringList {
+		if reflect.ValueOf(value).Kind() == reflect.Slice {
+			return "", errors.WithStackTrace(NotAListOfStrings{Function: functionName, Value: value})
+		}
+		return "", errors.WithStackTrace(NotAList{Function: functionName, Value: value})
+	}
+	if len(list) == 0 {
+		return "", errors.WithStackTrace(EmptyList(functionName))
+	}
+
+	return selectStringByLength(list, longest), nil
+}
+
+// Return the longest string, if longest is true, or the shortest string otherwise, in the given non-empty list. Lengths
+// are counted in runes, and if several strings are equally long, the first one wins.
+func selectStringByLength(list []string, longest bool) string {
+	result := list[0]
+	for _, str := range list[1:] {
+		length, resultLength := utf8.RuneCountInString(str), utf8.RuneCountInString(result)
+		if (longest && length > resultLength) || (!longest && length < resultLength) {
+			result = str
+		}
+	}
+	return result
+}
+
+// Return the strings in the list returned by the call to a helper function passed in the given parameters, such as
+// "${get_terraform_commands_that_need_vars()}", each within double quotes and separated by a comma and a space, the way
+// they would be written in an HCL list. As the result is a string, its quotes and backslashes are escaped, so it can be
+// interpolated into a string in the config, such as an argument for Terraform.
+func quoteJoin(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return "", errors.WithStackTrace(InvalidStringListParams{Function: "quote_join", Params: parameters})
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	list, isStringList := value.([]string)
+	if !isStringList {
+		if reflect.ValueOf(value).Kind() == reflect.Slice {
+			return "", errors.WithStackTrace(NotAListOfStrings{Function: "quote_join", Value: value})
+		}
+		return "", errors.WithStackTrace(NotAList{Function: "quote_join", Value: value})
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return escaper.Replace(util.CommaSeparatedStrings(list)), nil
+}
+
+// Return a single list with the elements of all the lists returned by the calls to helper functions passed as
+// parameters, such as "${get_terraform_commands_that_need_vars()}", in order. For example:
+//
+// concat("${range_list("2")}", "${range_list("5", "7")}") -> [0, 1, 5, 6]
+//
+// If all the lists are lists of numbers, such as the ones range_list returns, so is the result. Otherwise, the result
+// is a list of strings, and any numbers are turned into strings. Like Terraform's concat function, duplicate elements
+// are kept.
+func concat(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) == 0 {
+		return nil, errors.WithStackTrace(InvalidConcatParams(parameters))
+	}
+
+	strs := []string{}
+	ints := []int{}
+	allInts := true
+	for _, param := range params {
+		value, err := resolveDeferredParam(param, include, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		switch list := value.(type) {
+		case []string:
+			strs = append(strs, list...)
+			allInts = false
+		case []int:
+			for _, number := range list {
+				strs = append(strs, strconv.Itoa(number))
+			}
+			ints = append(ints, list...)
+		default:
+			if reflect.ValueOf(value).Kind() == reflect.Slice {
+				return nil, errors.WithStackTrace(NotAListOfStrings{Function: "concat", Value: value})
+			}
+			return nil, errors.WithStackTrace(NotAList{Function: "concat", Value: value})
+		}
+	}
+
+	if allInts {
+		return ints, nil
+	}
+	return strs, nil
+}
+
+// Return the element at the given zero-based index of the list returned by the call to a helper function passed as the
+// first parameter, such as "${subdirs(".")}", after resolving the index too if it's a call to a helper function, such
+// as "${hash_bucket("payments-api", "3")}". Like Terraform's element function, an index past the end of the list wraps
+// around to the start:
+//
+// element("${range_list("10", "13")}", "4") -> 11
+func element(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 2 {
+		return "", errors.WithStackTrace(InvalidElementParams(parameters))
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice {
+		return "", errors.WithStackTrace(NotAList{Function: "element", Value: value})
+	}
+
+	indexValue, err := resolveDeferredParam(params[1], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	number, isInt, isNumber := parseNumber(indexValue)
+	if !isNumber || !isInt {
+		return "", errors.WithStackTrace(NotAWholeNumber{Function: "element", Value: indexValue})
+	}
+
+	index := int(number)
+	if index < 0 || list.Len() == 0 {
+		return "", errors.WithStackTrace(ElementIndexOutOfRange{Index: index, Length: list.Len()})
+	}
+
+	return list.Index(index % list.Len()).Interface(), nil
+}
+
+// Return the list returned by the call to a helper function passed as the first parameter, such as
+// "${subdirs(".")}", if none of its elements appear more than once. Otherwise, return an error with the message passed
+// as the second parameter and the first duplicate element. This works for lists of any of the scalar types helper
+// functions return, such as strings or numbers.
+func assertUnique(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 2 {
+		return "", errors.WithStackTrace(InvalidAssertUniqueParams(parameters))
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice {
+		return "", errors.WithStackTrace(NotAList{Function: "assert_unique", Value: value})
+	}
+
+	if duplicate, hasDuplicate := findDuplicateElement(list); hasDuplicate {
+		return "", errors.WithStackTrace(DuplicateListElement{Message: params[1], Value: duplicate})
+	}
+
+	return value, nil
+}
+
+// Return the value passed as the first parameter, after resolving it if it's a call to a helper function, such as
+// "${get_env("ENV", "dev")}", if it's equal to an element of the list returned by the call to a helper function passed
+// as the second parameter, such as "${subdirs("../envs")}". Otherwise, return an error with the message passed as the
+// third parameter and the allowed values. Values are compared the way eq compares them, so the number 2 is one of the
+// strings "1" and "2", and vice versa.
+func assertOneOf(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 3 {
+		return "", errors.WithStackTrace(InvalidAssertOneOfParams(parameters))
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	allowed, err := resolveDeferredParam(params[1], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	list := reflect.ValueOf(allowed)
+	if list.Kind() != reflect.Slice {
+		return "", errors.WithStackTrace(NotAList{Function: "assert_oneof", Value: allowed})
+	}
+
+	for i := 0; i < list.Len(); i++ {
+		if reflect.DeepEqual(comparableValue(value), comparableValue(list.Index(i).Interface())) {
+			return value, nil
+		}
+	}
+
+	return "", errors.WithStackTrace(ValueNotAllowed{Message: params[2], Value: value, Allowed: allowed})
+}
+
+// Return the first element of the given list that is equal to an element before it, if any. The elements must be
+// comparable, as scalars are.
+func findDuplicateElement(list reflect.Value) (interface{}, bool) {
+	seen := map[interface{}]bool{}
+	for i := 0; i < list.Len(); i++ {
+		element := list.Index(i).Interface()
+		if seen[element] {
+			return element, true
+		}
+		seen[element] = true
+	}
+	return nil, false
+}
+
+// Return a list with an entry for each key of the map returned by the call to a helper function passed as the only
+// parameter, such as "${makemap("Name", "vpc")}", sorted by key. Each entry is a map with the key under "key" and the
+// value, of whatever type it is, under "value". For example:
+//
+// map_to_entries("${makemap("Name", "vpc", "Env", "prod")}") -> [{key = "Env", value = "prod"}, {key = "Name", value = "vpc"}]
+func mapToEntries(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return "", errors.WithStackTrace(InvalidMapToEntriesParams(parameters))
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	entries, isMap := mapEntries(value)
+	if !isMap {
+		return "", errors.WithStackTrace(NotAMap{Function: "map_to_entries", Value: value})
+	}
+	return entries, nil
+}
+
+// Return the entries of the given value, sorted by key, if it's a map with string keys
+func mapEntries(value interface{}) ([]map[string]interface{}, bool) {
+	mapValue := reflect.ValueOf(value)
+	if mapValue.Kind() != reflect.Map || mapValue.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	keys := []string{}
+	for _, key := range mapValue.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+
+	entries := []map[string]interface{}{}
+	for _, key := range keys {
+		entryValue := mapValue.MapIndex(reflect.ValueOf(key).Convert(mapValue.Type().Key())).Interface()
+		entries = append(entries, map[string]interface{}{"key": key, "value": entryValue})
+	}
+	return entries, true
+}
+
+// Return the keys of the map returned by the call to a helper function passed as the only parameter, such as
+// "${makemap("web", "2", "db", "1")}", sorted by their values. If all the values are numbers, or strings that contain
+// one, they're sorted numerically, and otherwise lexicographically. Keys with equal values are sorted by key, so the
+// order is always the same. For example:
+//
+// sortmap_by_value("${makemap("web", "b", "db", "a", "cache", "b")}") -> ["db", "cache", "web"]
+// sortmap_by_value("${makemap("web", "10", "db", "9")}") -> ["db", "web"]
+//
+// It's an error if some of the values are numbers and others aren't, or if any value isn't a string or a number.
+func sortMapByValue(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return "", errors.WithStackTrace(InvalidSortMapByValueParams(parameters))
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	// The entries are sorted by key, so a stable sort by value leaves keys with equal values sorted by key
+	entries, isMap := mapEntries(value)
+	if !isMap {
+		return "", errors.WithStackTrace(NotAMap{Function: "sortmap_by_value", Value: value})
+	}
+
+	isNumeric, isComparable := comparableEntryValues(entries)
+	if !isComparable {
+		return "", errors.WithStackTrace(NonComparableMapValues{Function: "sortmap_by_value", Value: value})
+	}
+	sort.SliceStable(entries, func(i int, j int) bool {
+		if isNumeric {
+			first, _, _ := parseNumber(entries[i]["value"])
+			second, _, _ := parseNumber(entries[j]["value"])
+			return first < second
+		}
+		return entries[i]["value"].(string) < entries[j]["value"].(string)
+	})
+
+	keys := []string{}
+	for _, entry := range entries {
+		keys = append(keys, entry["key"].(string))
+	}
+	return keys, nil
+}
+
+// An empty map literal, which can be passed to merge as is, rather than as a call to makemap without parameters
+const EMPTY_MAP_LITERAL = "{}"
+
+// Return a single map with the keys and values of all the maps passed as parameters, each of which must be a call to a
+// helper function that returns a map, such as makemap, or an empty map literal, {}. If a key is in more than one of
+// the maps, the value in the last one wins. For example:
+//
+// merge("${makemap("Env", "prod", "Team", "core")}", "${makemap("Team", "infra")}") -> {"Env" = "prod", "Team" = "infra"}
+//
+// Like Terraform's merge function, the maps are merged shallowly. Values that are maps or lists themselves can't be
+// represented in the config, so they're an error rather than merged (see mergeMapParam).
+func merge(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (map[string]string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) == 0 {
+		return nil, errors.WithStackTrace(InvalidMergeParams(parameters))
+	}
+
+	out := map[string]string{}
+	for position, param := range params {
+		if strings.TrimSpace(param) == EMPTY_MAP_LITERAL {
+			continue
+		}
+
+		value, err := resolveDeferredParam(param, include, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := mergeMapParam(out, position, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// Add the keys and values of the given value, the resolved parameter of merge at the given position, to the given map.
+// The value must be a map with string keys. Its values may be strings or other scalars, such as numbers, which are
+// formatted as strings, but not maps or lists, as HCL1 interpolations can't return nested maps.
+func mergeMapParam(out map[string]string, position int, value interface{}) error {
+	entries, isMap := mapEntries(value)
+	if !isMap {
+		return errors.WithStackTrace(MergeParamNotAMap{Position: position, Value: value})
+	}
+
+	for _, entry := range entries {
+		key := entry["key"].(string)
+		switch reflect.ValueOf(entry["value"]).Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array:
+			return errors.WithStackTrace(MergeNestedValueNotSupported{Position: position, Key: key, Value: entry["value"]})
+		}
+		out[key] = fmt.Sprintf("%v", entry["value"])
+	}
+
+	return nil
+}
+
+// Return whether the values of the given map entries, as returned by mapEntries, are all numbers or strings that
+// contain one, and whether they can be compared to each other at all, which is only if they're all numbers or all
+// other strings
+func comparableEntryValues(entries []map[string]interface{}) (bool, bool) {
+	hasStrings, hasNumbers := false, false
+	for _, entry := range entries {
+		if _, _, isNumber := parseNumber(entry["value"]); isNumber {
+			hasNumbers = true
+		} else if _, isString := entry["value"].(string); isString {
+			hasStrings = true
+		} else {
+			return false, false
+		}
+	}
+	return hasNumbers, !(hasStrings && hasNumbers)
+}
+
+// Return the given value constrained to the range from min to max, inclusive, after resolving any of the parameters
+// that are calls to helper functions, such as "${get_num_cpus()}":
+//
+// clamp("12", "1", "8") -> 8
+// clamp("0.5", "1", "8") -> 1
+// clamp("2.5", "1", "8") -> 2.5
+//
+// The result is a whole number if all three parameters are, and a decimal number otherwise.
+func clamp(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 3 {
+		return "", errors.WithStackTrace(InvalidClampParams(parameters))
+	}
+
+	numbers := []float64{}
+	allInts := true
+	for _, param := range params {
+		value, err := resolveDeferredParam(param, include, terragruntOptions)
+		if err != nil {
+			return "", err
+		}
+
+		number, isInt, isNumber := parseNumber(value)
+		if !isNumber {
+			return "", errors.WithStackTrace(NotANumber{Function: "clamp", Value: value})
+		}
+		numbers = append(numbers, number)
+		allInts = allInts && isInt
+	}
+
+	value, min, max := numbers[0], numbers[1], numbers[2]
+	if min > max {
+		return "", errors.WithStackTrace(InvalidClampRange{Min: params[1], Max: params[2]})
+	}
+
+	clamped := math.Min(math.Max(value, min), max)
+	if allInts {
+		return int(clamped), nil
+	}
+	return clamped, nil
+}
+
+// Return the given value as a number, and whether it's a whole number, if it's a number or a string that contains one.
+// The last return value is false if it isn't a number at all.
+func parseNumber(value interface{}) (float64, bool, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true, true
+	case float64:
+		return value, value == math.Trunc(value), !math.IsNaN(value) && !math.IsInf(value, 0)
+	case string:
+		trimmed := strings.TrimSpace(value)
+		if number, err := strconv.Atoi(trimmed); err == nil {
+			return float64(number), true, true
+		}
+		if number, err := strconv.ParseFloat(trimmed, 64); err == nil && !math.IsNaN(number) && !math.IsInf(number, 0) {
+			return number, false, true
+		}
+	}
+	return 0, false, false
+}
+
+// Fetch the JSON document at the given http or https URL and return the value at the given dotted path in it, after
+// resolving any of the parameters that are calls to helper functions, such as "${get_env("CONFIG_URL", "")}". Each
+// segment of the path is the key of an object or, in a list, a zero-based index. An empty path returns the whole
+// document. For example, if https://config.example.com/vpc.json returns {"subnets": [{"cidr": "10.0.1.0/24"}]}:
+//
+// http_get_json("https://config.example.com/vpc.json", "subnets.0.cidr") -> "10.0.1.0/24"
+//
+// The request gives up after the HttpTimeout in the given options.
+func httpGetJson(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 2 {
+		return "", errors.WithStackTrace(InvalidHttpGetJsonParams(parameters))
+	}
+
+	resolvedParams := []string{}
+	for _, param := range params {
+		value, err := resolveDeferredParam(param, include, terragruntOptions)
+		if err != nil {
+			return "", err
+		}
+		resolvedParam, isString := value.(string)
+		if !isString {
+			return "", errors.WithStackTrace(InvalidHttpGetJsonParams(parameters))
+		}
+		resolvedParams = append(resolvedParams, resolvedParam)
+	}
+	rawUrl, path := resolvedParams[0], resolvedParams[1]
+
+	document, err := fetchJson(rawUrl, terragruntOptions.HttpTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	value, missingSegment, found := lookupJsonPath(document, path)
+	if !found {
+		return "", errors.WithStackTrace(JsonPathNotFound{Url: rawUrl, Path: path, Segment: missingSegment})
+	}
+
+	return helperValueFromJson(value, path)
+}
+
+// Send a GET request to the given http or https URL and return its body parsed as JSON. Numbers are parsed as
+// json.Number, so whole numbers don't lose precision.
+func fetchJson(rawUrl string, timeout time.Duration) (interface{}, error) {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return nil, errors.WithStackTrace(UnsupportedUrlScheme(rawUrl))
+	}
+
+	client := http.Client{Timeout: timeout}
+
+	response, err := client.Get(rawUrl)
+	if err != nil {
+		return nil, errors.WithStackTrace(HttpGetFailed{Url: rawUrl, Underlying: err})
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, errors.WithStackTrace(HttpGetUnexpectedStatus{Url: rawUrl, StatusCode: response.StatusCode})
+	}
+
+	var document interface{}
+	decoder := json.NewDecoder(response.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, errors.WithStackTrace(InvalidJsonResponse{Url: rawUrl, Underlying: err})
+	}
+
+	return document, nil
+}
+
+// Return the value at the given dotted path in the given parsed JSON document. If there's no such value, return the
+// first segment of the path that couldn't be found, and false.
+func lookupJsonPath(document interface{}, path string) (interface{}, string, bool) {
+	if path == "" {
+		return document, "", true
+	}
+
+	value := document
+	for _, segment := range strings.Split(path, ".") {
+		switch current := value.(type) {
+		case map[string]interface{}:
+			next, hasKey := current[segment]
+			if !hasKey {
+				return nil, segment, false
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, segment, false
+			}
+			value = current[index]
+		default:
+			return nil, segment, false
+		}
+	}
+
+	return value, "", true
+}
+
+// Convert the given value from a parsed JSON document into one that can be interpolated into the config: strings,
+// numbers, and booleans as is, lists of strings as a []string, and objects whose values are all strings as a
+// map[string]string. Other values, such as null or a list of objects, can't be represented in the config.
+func helperValueFromJson(value interface{}, path string) (interface{}, error) {
+	switch value := value.(type) {
+	case string, bool:
+		return value, nil
+	case json.Number:
+		if number, err := value.Int64(); err == nil {
+			return int(number), nil
+		}
+		number, err := value.Float64()
+		if err != nil {
+			return "", errors.WithStackTrace(UnsupportedJsonValue{Path: path, Value: value})
+		}
+		return number, nil
+	case []interface{}:
+		list := []string{}
+		for _, element := range value {
+			str, isString := element.(string)
+			if !isString {
+				return "", errors.WithStackTrace(UnsupportedJsonValue{Path: path, Value: value})
+			}
+			list = append(list, str)
+		}
+		return list, nil
+	case map[string]interface{}:
+		stringMap := map[string]string{}
+		for key, element := range value {
+			str, isString := element.(string)
+			if !isString {
+				return "", errors.WithStackTrace(UnsupportedJsonValue{Path: path, Value: value})
+			}
+			stringMap[key] = str
+		}
+		return stringMap, nil
+	default:
+		return "", errors.WithStackTrace(UnsupportedJsonValue{Path: path, Value: value})
+	}
+}
+
+// Return true if the given value of a feature flag env var turns the flag on
+func isFlagOn(value string) bool {
+	return util.ListContainsElement(TRUTHY_FLAG_VALUES, strings.ToLower(strings.TrimSpace(value)))
+}
+
+// If the given parameter is a call to a helper function, such as "${get_tfvars_dir()}", resolve it. Otherwise, return
+// it as is.
+func resolveDeferredParam(param string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	trimmedParam := strings.TrimSpace(param)
+	if HELPER_FUNCTION_SYNTAX_REGEX.MatchString(trimmedParam) {
+		return resolveTerragruntInterpolation(trimmedParam, include, terragruntOptions)
+	}
+	return param, nil
+}
+
+// Like resolveDeferredParam, but the given parameter may also contain calls to helper functions as part of a longer
+// string, such as "${get_terragrunt_dir()}/cert.pem", and the result is always formatted as a string
+func resolveDeferredStringParam(param string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	if call := INTERPOLATION_SYNTAX_REGEX.FindString(param); call != "" && call != strings.TrimSpace(param) {
+		return processMultipleInterpolationsInString(param, include, terragruntOptions)
+	}
+
+	value, err := resolveDeferredParam(param, include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v", value), nil
+}
+
+// Return a sha256 hex digest of the given values, after resolving any of them that are calls to helper functions, such
+// as "${get_tfvars_dir()}". The same values always result in the same fingerprint, and changing any value changes it,
+// which makes it handy for detecting when the effective inputs of a config change.
+func fingerprint(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) == 0 {
+		return "", errors.WithStackTrace(InvalidFingerprintParams(parameters))
+	}
+
+	values := []interface{}{}
+	for _, param := range params {
+		value, err := resolveDeferredParam(param, include, terragruntOptions)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, value)
+	}
+
+	return fingerprintValues(values)
+}
+
+// Serialize the given values canonically and return the sha256 hex digest of the result. JSON encoding is canonical for
+// our purposes: the values keep their order, as do the items of any list, while the keys of any map are sorted, so
+// the order in which a map was built never affects the fingerprint. Encoding each value as JSON, rather than just
+// joining them, also means e.g. the values "a" and "b" have a different fingerprint than the single value "a,b".
+func fingerprintValues(values []interface{}) (string, error) {
+	serialized, err := json.Marshal(values)
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	hash := sha256.Sum256(serialized)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// Create an AWS session with the given session options using the default credentials chain, assuming the IAM role in
+// the given Terragrunt options, if any
+func createAWSSession(sessionOptions session.Options, terragruntOptions *options.TerragruntOptions) (*session.Session, error) {
+	sess, err := session.NewSessionWithOptions(sessionOptions)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	if terragruntOptions.IamRole != "" {
+		sess.Config.Credentials = stscreds.NewCredentials(sess, terragruntOptions.IamRole)
+	}
+
+	return sess, nil
+}
+
+// Deterministically map the given string, after resolving any calls to helper functions in it, such as
+// "${get_env("ENV", "")}", to a hex color of the form #rrggbb by hashing it. The same string always results in the same
+// color, which is useful for e.g. color coding environments in dashboards.
+func colorFor(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return "", errors.WithStackTrace(InvalidStringParams(parameters))
+	}
+
+	str, err := resolveDeferredStringParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256([]byte(str))
+	return fmt.Sprintf("#%02x%02x%02x", hash[0], hash[1], hash[2]), nil
+}
+
+// Deterministically map the given key to one of the given number of buckets, numbered from 0, by hashing it, after
+// resolving any calls to helper functions in the parameters, such as "${path_relative_to_include()}". The same key and
+// number of buckets always result in the same bucket, which is useful for e.g. picking a canary variant.
+func hashBucket(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (int, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 2 {
+		return 0, errors.WithStackTrace(InvalidHashBucketParams(parameters))
+	}
+
+	values := []string{}
+	for _, param := range params {
+		value, err := resolveDeferredStringParam(param, include, terragruntOptions)
+		if err != nil {
+			return 0, err
+		}
+		values = append(values, value)
+	}
+
+	buckets, err := strconv.Atoi(strings.TrimSpace(values[1]))
+	if err != nil {
+		return 0, errors.WithStackTrace(InvalidHashBucketParams(parameters))
+	}
+	if buckets <= 0 {
+		return 0, errors.WithStackTrace(InvalidHashBucketCount(buckets))
+	}
+
+	hash := sha256.Sum256([]byte(values[0]))
+	return int(binary.BigEndian.Uint64(hash[:8]) % uint64(buckets)), nil
+}
+
+// Deterministically map the given seed to a whole number from min to max, inclusive, by hashing it, after resolving any
+// of the parameters that are calls to helper functions, such as "${path_relative_to_include()}". The same seed and
+// range always result in the same number, and different seeds are spread evenly across the range, which is useful for
+// e.g. staggering the minute of a cron schedule per service:
+//
+// seeded_int("payments-api", "0", "59") -> 24
+func seededInt(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (int, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 3 {
+		return 0, errors.WithStackTrace(InvalidSeededIntParams(parameters))
+	}
+
+	values := []interface{}{}
+	for _, param := range params {
+		value, err := resolveDeferredParam(param, include, terragruntOptions)
+		if err != nil {
+			return 0, err
+		}
+		values = append(values, value)
+	}
+
+	bounds := []int{}
+	for _, value := range values[1:] {
+		number, isInt, isNumber := parseNumber(value)
+		if !isNumber || !isInt {
+			return 0, errors.WithStackTrace(NotAWholeNumber{Function: "seeded_int", Value: value})
+		}
+		bounds = append(bounds, int(number))
+	}
+
+	min, max := bounds[0], bounds[1]
+	if min > max {
+		return 0, errors.WithStackTrace(InvalidSeededIntRange{Min: min, Max: max})
+	}
+
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%v", values[0])))
+	span := uint64(max-min) + 1
+	return min + int(binary.BigEndian.Uint64(hash[:8])%span), nil
+}
+
+// Return a list of numbers, mirroring Terraform's range function:
+//
+// range_list("3") -> [0, 1, 2]
+// range_list("1", "4") -> [1, 2, 3]
+// range_list("0", "10", "5") -> [0, 5]
+// range_list("3", "0") -> [3, 2, 1]
+//
+// With one or two parameters, the step is 1, or -1 if the end is less than the start. The end is never included. Any of
+// the parameters may be calls to helper functions, such as "${get_env("COUNT", "")}".
+func rangeList(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) ([]int, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil {
+		return nil, errors.WithStackTrace(InvalidRangeListParams(parameters))
+	}
+
+	numbers := []int{}
+	for _, param := range params {
+		value, err := resolveDeferredStringParam(param, include, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+		number, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.WithStackTrace(InvalidRangeListParams(parameters))
+		}
+		numbers = append(numbers, number)
+	}
+
+	start, end, step := 0, 0, 1
+	switch len(numbers) {
+	case 1:
+		end = numbers[0]
+	case 2:
+		start, end = numbers[0], numbers[1]
+	case 3:
+		start, end, step = numbers[0], numbers[1], numbers[2]
+	default:
+		return nil, errors.WithStackTrace(InvalidRangeListParams(parameters))
+	}
+
+	if len(numbers) < 3 && end < start {
+		step = -1
+	}
+
+	if step == 0 || (end > start && step < 0) || (end < start && step > 0) {
+		return nil, errors.WithStackTrace(InvalidRangeListStep{Start: start, End: end, Step: step})
+	}
+
+	out := []int{}
+	for i := start; (step > 0 && i < end) || (step < 0 && i > end); i += step {
+		out = append(out, i)
+	}
+	return out, nil
+}
+
+// The suffix truncate appends to truncated strings, unless another suffix is specified
+const DEFAULT_TRUNCATE_SUFFIX = "…"
+
+// Truncate a string so it's at most the given number of characters (runes, not bytes) long, appending a suffix to show
+// the string was truncated. The suffix counts towards the length. If the max length is too short to fit the suffix, the
+// string is truncated without one:
+//
+// truncate("my-long-name", "8") -> "my-long…"
+// truncate("my-long-name", "8", "...") -> "my-lo..."
+// truncate("short", "8") -> "short"
+//
+// Any of the parameters may be calls to helper functions, such as "${get_env("NAME", "")}".
+func truncate(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) < 2 || len(params) > 3 {
+		return "", errors.WithStackTrace(InvalidTruncateParams(parameters))
+	}
+
+	values := []string{}
+	for _, param := range params {
+		value, err := resolveDeferredStringParam(param, include, terragruntOptions)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, value)
+	}
+
+	str := []rune(values[0])
+	suffix := []rune(DEFAULT_TRUNCATE_SUFFIX)
+	if len(values) == 3 {
+		suffix = []rune(values[2])
+	}
+
+	maxLen, err := strconv.Atoi(strings.TrimSpace(values[1]))
+	if err != nil {
+		return "", errors.WithStackTrace(InvalidTruncateParams(parameters))
+	}
+	if maxLen < 0 {
+		return "", errors.WithStackTrace(NegativeTruncateLength(maxLen))
+	}
+
+	if len(str) <= maxLen {
+		return string(str), nil
+	}
+	if maxLen <= len(suffix) {
+		return string(str[:maxLen]), nil
+	}
+	return string(str[:maxLen-len(suffix)]) + string(suffix), nil
+}
+
+// The options of the session get_aws_account_id looks up the account id with. These are the defaults of the AWS SDK,
+// so the shared config file, such as ~/.aws/config, is only read if AWS_SDK_LOAD_CONFIG is set.
+var AWS_ACCOUNT_ID_SESSION_OPTIONS = session.Options{}
+
+// The options of the session build_arn and region_value look up the region with. The region is often only set in the
+// shared config file, so it's read even if AWS_SDK_LOAD_CONFIG isn't set.
+var AWS_REGION_SESSION_OPTIONS = session.Options{SharedConfigState: session.SharedConfigEnable}
+
+// The AWS account ids get_aws_account_id looked up, keyed by the IAM role it assumed, if any, so STS is only called once
+// per role for the life of the process, even in the many configs of an xxx-all run
+var awsAccountIdCache = map[string]string{}
+var awsAccountIdCacheLock sync.Mutex
+
+// The maximum length of a DNS label, as defined in RFC 1123
+const MAX_DNS_LABEL_LENGTH = 63
+
+var invalidDnsLabelCharsRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Turn the given value, after resolving it if it's a call to a helper function, such as "${get_env("APP", "")}", into
+// a valid RFC 1123 DNS label, as used for the names of Kubernetes resources: it's lowercased, every run of characters
+// other than letters and digits is replaced with a single hyphen, and it's cut short at 63 characters, without leading
+// or trailing hyphens. For example:
+//
+// dns_label("My_App (Staging)") -> "my-app-staging"
+//
+// It's an error if nothing valid is left, such as for "--".
+func dnsLabel(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return "", errors.WithStackTrace(InvalidDnsLabelParams(parameters))
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+	str := fmt.Sprintf("%v", value)
+
+	label := strings.Trim(invalidDnsLabelCharsRegex.ReplaceAllString(strings.ToLower(str), "-"), "-")
+	if len(label) > MAX_DNS_LABEL_LENGTH {
+		label = strings.TrimRight(label[:MAX_DNS_LABEL_LENGTH], "-")
+	}
+
+	if label == "" {
+		return "", errors.WithStackTrace(EmptyDnsLabel(str))
+	}
+
+	return label, nil
+}
+
+// Return the AWS account id associated to the current set of credentials, after assuming the IAM role in the given
+// options, if any
+func getAWSAccountID(terragruntOptions *options.TerragruntOptions) (string, error) {
+	return cachedAWSAccountID(terragruntOptions.IamRole, func() (aws_helper.StsClient, error) {
+		sess, err := createAWSSession(AWS_ACCOUNT_ID_SESSION_OPTIONS, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+		return sts.New(sess), nil
+	})
+}
+
+// Return the AWS account id cached for the given IAM role, or, if there is none, look it up with the STS client returned
+// by the given function and cache it. The STS client is only created if the account id isn't cached yet.
+func cachedAWSAccountID(iamRole string, newStsClient func() (aws_helper.StsClient, error)) (string, error) {
+	awsAccountIdCacheLock.Lock()
+	defer awsAccountIdCacheLock.Unlock()
+
+	if accountId, isCached := awsAccountIdCache[iamRole]; isCached {
+		return accountId, nil
+	}
+
+	stsClient, err := newStsClient()
+	if err != nil {
+		return "", err
+	}
+
+	accountId, err := aws_helper.GetAccountId(stsClient)
+	if err != nil {
+		return "", errors.WithStackTrace(AWSAccountIdNotFound{IamRole: iamRole, Underlying: errors.Unwrap(err)})
+	}
+
+	awsAccountIdCache[iamRole] = accountId
+	return accountId, nil
+}
+
+// Return the AWS region configured for the current session (e.g. via AWS_REGION or the shared config file)
+func getAWSRegion(terragruntOptions *options.TerragruntOptions) (string, error) {
+	sess, err := createAWSSession(AWS_REGION_SESSION_OPTIONS, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	if sess.Config.Region == nil || *sess.Config.Region == "" {
+		return "", errors.WithStackTrace(AWSRegionNotFound{})
+	}
+
+	return *sess.Config.Region, nil
+}
+
+// Return the AWS partition (e.g. aws, aws-cn, aws-us-gov) that contains the given region
+func getAWSPartition(region string) (string, error) {
+	partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region)
+	if !ok {
+		return "", errors.WithStackTrace(ArnComponentNotFound(fmt.Sprintf("partition for region %s", region)))
+	}
+
+	return partition.ID(), nil
+}
+
+// Build a normalized ARN for the given service and resource, filling in the partition, region, and account id using
+// the current set of AWS credentials. Example:
+//
+// build_arn("sqs", "my-queue") -> arn:aws:sqs:us-east-1:123456789012:my-queue
+func buildArn(parameters string, terragruntOptions *options.TerragruntOptions) (string, error) {
+	service, resource, numParams, err := parseOptionalQuotedParam(parameters)
+	if err != nil {
+		return "", err
+	}
+	if numParams != 2 {
+		return "", errors.WithStackTrace(InvalidBuildArnParams(parameters))
+	}
+
+	region, err := getAWSRegion(terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	partition, err := getAWSPartition(region)
+	if err != nil {
+		return "", err
+	}
+
+	accountID, err := getAWSAccountID(terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	return formatArn(partition, service, region, accountID, resource)
+}
+
+// Return the value for the current AWS region in the map passed as the first parameter, which must be a call to a
+// helper function that returns a map, such as makemap, or the second parameter if the region isn't one of its keys.
+// The region is looked up the same way as in build_arn. Like when_flag, the default is only resolved if it's returned.
+// Example:
+//
+// region_value("${makemap("us-east-1", "t3.large", "eu-west-1", "m5.large")}", "t3.small") -> "t3.small" in us-west-2
+func regionValue(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 2 {
+		return "", errors.WithStackTrace(InvalidRegionValueParams(parameters))
+	}
+
+	value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	valuesByRegion, isMap := value.(map[string]string)
+	if !isMap {
+		return "", errors.WithStackTrace(NotAMap{Function: "region_value", Value: value})
+	}
+
+	region, err := getAWSRegion(terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	return selectRegionValue(valuesByRegion, region, params[1], include, terragruntOptions)
+}
+
+// Return the value for the given region in the given map, or the given default, after resolving it if it's a call to a
+// helper function, if the region isn't one of its keys
+func selectRegionValue(valuesByRegion map[string]string, region string, defaultValue string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	if value, hasRegion := valuesByRegion[region]; hasRegion {
+		return value, nil
+	}
+	return resolveDeferredParam(defaultValue, include, terragruntOptions)
+}
+
+// The name of the file standard_tags reads default tags from, in the parent folders of the current Terragrunt
+// configuration file
+const DEFAULT_TAGS_FILE = "default_tags.hcl"
+
+// Return the tags every module gets, merged from three layers, where each layer wins over the ones before it:
+//
+//  1. The default tags in the closest default_tags.hcl file in the parent folders of the current Terragrunt
+//     configuration file, if there is one. The file sets each tag as a top-level attribute, such as Team = "platform".
+//  2. A Region tag with the current AWS region, which is looked up the same way as in build_arn.
+//  3. The module tags in the map passed as the only parameter, which must be a call to a helper function that returns
+//     a map, such as makemap, or an empty map literal, {}.
+//
+// For example, with Team = "platform" in default_tags.hcl, in us-east-1:
+//
+// standard_tags("${makemap("Name", "vpc")}") -> {"Name" = "vpc", "Region" = "us-east-1", "Team" = "platform"}
+func standardTags(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (map[string]string, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 1 {
+		return nil, errors.WithStackTrace(InvalidStandardTagsParams(parameters))
+	}
+
+	moduleTags := map[string]string{}
+	if strings.TrimSpace(params[0]) != EMPTY_MAP_LITERAL {
+		value, err := resolveDeferredParam(params[0], include, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		valueMap, isMap := value.(map[string]string)
+		if !isMap {
+			return nil, errors.WithStackTrace(NotAMap{Function: "standard_tags", Value: value})
+		}
+		moduleTags = valueMap
+	}
+
+	defaultTags, err := readDefaultTags(include, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	region, err := getAWSRegion(terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return composeStandardTags(defaultTags, region, moduleTags), nil
+}
+
+// Merge the given default tags, a Region tag with the given region, and the given module tags, in that order, so the
+// module tags win
+func composeStandardTags(defaultTags map[string]string, region string, moduleTags map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, tags := range []map[string]string{defaultTags, {"Region": region}, moduleTags} {
+		for key, value := range tags {
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// Return the tags in the closest default_tags.hcl file in the parent folders of the current Terragrunt configuration
+// file, found the same way as by find_in_parent_folders, or no tags if there's no such file
+func readDefaultTags(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (map[string]string, error) {
+	path, err := findInParentFolders(fmt.Sprintf(`"%s", ""`, DEFAULT_TAGS_FILE), include, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return map[string]string{}, nil
+	}
+
+	if !filepath.IsAbs(path) {
+		path = util.JoinPath(filepath.Dir(terragruntOptions.TerragruntConfigPath), path)
+	}
+
+	contents, err := util.ReadFileAsString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	if err := hcl.Decode(&values, contents); err != nil {
+		return nil, errors.WithStackTrace(ErrorParsingDefaultTagsFile{Path: path, Underlying: err})
+	}
+
+	tags := map[string]string{}
+	for key, value := range values {
+		switch value.(type) {
+		case string, int, float64, bool:
+			tags[key] = fmt.Sprintf("%v", value)
+		default:
+			return nil, errors.WithStackTrace(DefaultTagNotAScalar{Path: path, Key: key, Value: value})
+		}
+	}
+
+	return tags, nil
+}
+
+// Combine the given components into an ARN of the form arn:partition:service:region:account-id:resource, returning an
+// error if any of the components is empty
+func formatArn(partition string, service string, region string, accountID string, resource string) (string, error) {
+	components := []struct {
+		name  string
+		value string
+	}{
+		{"partition", partition},
+		{"service", service},
+		{"region", region},
+		{"account id", accountID},
+		{"resource", resource},
+	}
+
+	for _, component := range components {
+		if strings.TrimSpace(component.value) == "" {
+			return "", errors.WithStackTrace(ArnComponentNotFound(component.name))
+		}
+	}
+
+	service = strings.ToLower(strings.TrimSpace(service))
+	resource = strings.TrimPrefix(strings.TrimSpace(resource), "/")
+
+	return fmt.Sprintf("arn:%s:%s:%s:%s:%s", partition, service, region, accountID, resource), nil
+}
+
+// Custom error types
+
+type InvalidInterpolationSyntax string
+
+func (err InvalidInterpolationSyntax) Error() string {
+	return fmt.Sprintf("Invalid interpolation syntax. Expected syntax of the form '${function_name()}', but got '%s'", string(err))
+}
+
+type InvalidHelperFunctionName struct {
+	Call string
+	Name string
+}
+
+func (err InvalidHelperFunctionName) Error() string {
+	return fmt.Sprintf("Invalid interpolation syntax in the call starting with '%s': the function name '%s' must be a bare name, such as get_env. The name of a function can't be an interpolation or contain other characters than letters, digits, and underscores.", err.Call, err.Name)
+}
+
+type NestedHelperFunctionCallNotSupported struct {
+	Function string
+	Call     string
+}
+
+func (err NestedHelperFunctionCallNotSupported) Error() string {
+	return fmt.Sprintf("Nested calls to helper functions are not supported in the parameters of %s(), but got %s. The value would be the text of the call rather than the value it returns.", err.Function, err.Call)
+}
+
+type UnknownHelperFunction string
+
+func (err UnknownHelperFunction) Error() string {
+	return fmt.Sprintf("Unknown helper function: %s", string(err))
+}
+
+type ParentFileNotFound struct {
+	Path  string
+	File  string
+	Cause string
+}
+
+func (err ParentFileNotFound) Error() string {
+	return fmt.Sprintf("Could not find a %s in any of the parent folders of %s. Cause: %s.", err.File, err.Path, err.Cause)
+}
+
+type InvalidGetEnvParams string
+
+func (err InvalidGetEnvParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${get_env(\"env\")}' or '${get_env(\"env\", \"default\")}', but got '%s'", string(err))
+}
+
+type EnvVarNotFound string
+
+func (err EnvVarNotFound) Error() string {
+	return fmt.Sprintf("The environment variable %s is not set, and no default value was given to get_env", string(err))
+}
+
+type InvalidStringParams string
+
+func (err InvalidStringParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected one string parameter (e.g., ${foo(\"xxx\")}), two string parameters (e.g. ${foo(\"xxx\", \"yyy\")}), or no parameters (e.g., ${foo()}) but got '%s'.", string(err))
+}
+
+type EmptyStringNotAllowed string
+
+func (err EmptyStringNotAllowed) Error() string {
+	return fmt.Sprintf("Empty string value is not allowed for %s", string(err))
+}
+
+type InvalidBuildArnParams string
+
+func (err InvalidBuildArnParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${build_arn(\"service\", \"resource\")}', but got '%s'", string(err))
+}
+
+type ArnComponentNotFound string
+
+func (err ArnComponentNotFound) Error() string {
+	return fmt.Sprintf("Unable to determine the %s needed to build an ARN", string(err))
+}
+
+type AWSRegionNotFound struct{}
+
+func (err AWSRegionNotFound) Error() string {
+	return "Unable to determine the current AWS region. Set it via the AWS_REGION environment variable or your AWS config file."
+}
+
+type AWSAccountIdNotFound struct {
+	IamRole    string
+	Underlying error
+}
+
+func (err AWSAccountIdNotFound) Error() string {
+	// Only show the code and message of errors returned by the AWS SDK, not the errors they wrap, which are rarely
+	// helpful, such as the list of every credential provider that was tried
+	cause := err.Underlying.Error()
+	if awsErr, isAwsErr := err.Underlying.(awserr.Error); isAwsErr {
+		cause = fmt.Sprintf("%s: %s", awsErr.Code(), awsErr.Message())
+	}
+
+	if err.IamRole != "" {
+		return fmt.Sprintf("Unable to determine the current AWS account id after assuming IAM role %s (%s). Make sure AWS credentials are available, e.g. via the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables or an AWS profile, and that they can assume the role.", err.IamRole, cause)
+	}
+	return fmt.Sprintf("Unable to determine the current AWS account id (%s). Make sure AWS credentials are available, e.g. via the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables or an AWS profile.", cause)
+}
+
+type InvalidRegionValueParams string
 
-// Custom error types
+func (err InvalidRegionValueParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${region_value(\"${makemap(\"us-east-1\", \"value\", ...)}\", \"default\")}', but got '%s'", string(err))
+}
 
-type InvalidInterpolationSyntax string
+type InvalidStandardTagsParams string
 
-func (err InvalidInterpolationSyntax) Error() string {
-	return fmt.Sprintf("Invalid interpolation syntax. Expected syntax of the form '${function_name()}', but got '%s'", string(err))
+func (err InvalidStandardTagsParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${standard_tags(\"${makemap(\"key\", \"value\", ...)}\")}', where the only parameter is a call to a function that returns a map, but got '%s'", string(err))
 }
 
-type UnknownHelperFunction string
+type ErrorParsingDefaultTagsFile struct {
+	Path       string
+	Underlying error
+}
 
-func (err UnknownHelperFunction) Error() string {
-	return fmt.Sprintf("Unknown helper function: %s", string(err))
+func (err ErrorParsingDefaultTagsFile) Error() string {
+	return fmt.Sprintf("Unable to parse the default tags file %s: %v", err.Path, err.Underlying)
 }
 
-type ParentFileNotFound struct {
+type DefaultTagNotAScalar struct {
 	Path  string
-	File  string
-	Cause string
+	Key   string
+	Value interface{}
 }
 
-func (err ParentFileNotFound) Error() string {
-	return fmt.Sprintf("Could not find a %s in any of the parent folders of %s. Cause: %s.", err.File, err.Path, err.Cause)
+func (err DefaultTagNotAScalar) Error() string {
+	return fmt.Sprintf("The default tag %s in %s must be a string, number, or bool, but got '%v'", err.Key, err.Path, err.Value)
 }
 
-type InvalidGetEnvParams string
+type NotAMap struct {
+	Function string
+	Value    interface{}
+}
 
-func (err InvalidGetEnvParams) Error() string {
-	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${get_env(\"env\", \"default\")}', but got '%s'", string(err))
+func (err NotAMap) Error() string {
+	return fmt.Sprintf("The first parameter of %s must be a map, such as the result of a call to makemap, but got '%v'", err.Function, err.Value)
 }
 
-type InvalidStringParams string
+type InvalidGetDotEnvParams string
 
-func (err InvalidStringParams) Error() string {
-	return fmt.Sprintf("Invalid parameters. Expected one string parameter (e.g., ${foo(\"xxx\")}), two string parameters (e.g. ${foo(\"xxx\", \"yyy\")}), or no parameters (e.g., ${foo()}) but got '%s'.", string(err))
+func (err InvalidGetDotEnvParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${get_dotenv(\"path\", \"key\")}', but got '%s'", string(err))
 }
 
-type EmptyStringNotAllowed string
+type DotEnvKeyNotFound struct {
+	Path string
+	Key  string
+}
 
-func (err EmptyStringNotAllowed) Error() string {
-	return fmt.Sprintf("Empty string value is not allowed for %s", string(err))
+func (err DotEnvKeyNotFound) Error() string {
+	return fmt.Sprintf("Could not find key %s in dotenv file %s", err.Key, err.Path)
+}
+
+type FileNotFound string
+
+func (err FileNotFound) Error() string {
+	return fmt.Sprintf("Could not find file %s", string(err))
+}
+
+type NotADirectory string
+
+func (err NotADirectory) Error() string {
+	return fmt.Sprintf("%s is not a directory", string(err))
+}
+
+type InvalidSubdirsParams string
+
+func (err InvalidSubdirsParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${subdirs(\"path\")}' or '${subdirs(\"path\", \"true\")}', but got '%s'", string(err))
+}
+
+type InvalidRangeListParams string
+
+func (err InvalidRangeListParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected one to three integer parameters of the form '${range_list(\"start\", \"end\", \"step\")}', but got '%s'", string(err))
+}
+
+type InvalidRangeListStep struct {
+	Start int
+	End   int
+	Step  int
+}
+
+func (err InvalidRangeListStep) Error() string {
+	return fmt.Sprintf("Invalid step %d for range_list from %d to %d. The step must be non-zero and move from the start towards the end.", err.Step, err.Start, err.End)
+}
+
+type InvalidTruncateParams string
+
+func (err InvalidTruncateParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${truncate(\"string\", \"max_length\")}' or '${truncate(\"string\", \"max_length\", \"suffix\")}', but got '%s'", string(err))
+}
+
+type NegativeTruncateLength int
+
+func (err NegativeTruncateLength) Error() string {
+	return fmt.Sprintf("The max length passed to truncate must not be negative, but got %d", int(err))
+}
+
+type InvalidDnsLabelParams string
+
+func (err InvalidDnsLabelParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${dns_label(\"value\")}', but got '%s'", string(err))
+}
+
+type EmptyDnsLabel string
+
+func (value EmptyDnsLabel) Error() string {
+	return fmt.Sprintf("Cannot turn '%s' into a DNS label with dns_label, as it doesn't contain any letters or digits", string(value))
+}
+
+type InvalidEnvFromPathParams string
+
+func (err InvalidEnvFromPathParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${env_from_path(\"position\")}', where position is an integer, but got '%s'", string(err))
+}
+
+type PathSegmentOutOfRange struct {
+	Position    int
+	Path        string
+	NumSegments int
+}
+
+func (err PathSegmentOutOfRange) Error() string {
+	return fmt.Sprintf("Position %d is out of range for path '%s' relative to the included config, which has %d folder(s)", err.Position, err.Path, err.NumSegments)
+}
+
+type InvalidWhenFlagParams string
+
+func (err InvalidWhenFlagParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${when_flag(\"flagName\", \"value\", \"default\")}', where flagName is not empty, but got '%s'", string(err))
+}
+
+type InvalidEqualityParams string
+
+func (err InvalidEqualityParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${eq(\"a\", \"b\")}' or '${ne(\"a\", \"b\")}', but got '%s'", string(err))
+}
+
+type InvalidCondParams string
+
+func (err InvalidCondParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${cond(\"condition\", \"value if true\", \"value if false\")}', but got '%s'", string(err))
+}
+
+type InvalidCondCondition string
+
+func (err InvalidCondCondition) Error() string {
+	return fmt.Sprintf("The condition passed to cond must be true or false, such as the result of a call to eq or ne, but got '%s'", string(err))
+}
+
+type InvalidTruthyParams string
+
+func (err InvalidTruthyParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${truthy(\"value\")}', but got '%s'", string(err))
+}
+
+type InvalidStringHelperParams string
+
+func (err InvalidStringHelperParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${string(\"value\")}', but got '%s'", string(err))
+}
+
+type InvalidSquashWhitespaceParams string
+
+func (err InvalidSquashWhitespaceParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${squash_whitespace(\"value\")}', but got '%s'", string(err))
+}
+
+type InvalidStripAnsiParams string
+
+func (err InvalidStripAnsiParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${strip_ansi(\"value\")}', but got '%s'", string(err))
+}
+
+type InvalidStringListParams struct {
+	Function string
+	Params   string
+}
+
+func (err InvalidStringListParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${%s(\"${get_terraform_commands_that_need_vars()}\")}', where the only parameter is a call to a function that returns a list of strings, but got '%s'", err.Function, err.Params)
+}
+
+type NotAList struct {
+	Function string
+	Value    interface{}
+}
+
+func (err NotAList) Error() string {
+	return fmt.Sprintf("The parameter of %s must be a list, such as the result of a call to get_terraform_commands_that_need_vars, but got '%v'", err.Function, err.Value)
+}
+
+type NotAListOfStrings struct {
+	Function string
+	Value    interface{}
+}
+
+func (err NotAListOfStrings) Error() string {
+	return fmt.Sprintf("The parameter of %s must be a list of strings, but got a list with other elements: '%v'", err.Function, err.Value)
+}
+
+type InvalidConcatParams string
+
+func (err InvalidConcatParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${concat(\"${get_terraform_commands_that_need_vars()}\", \"${get_terraform_commands_that_need_input()}\", ...)}', where each parameter is a call to a function that returns a list, but got '%s'", string(err))
+}
+
+type InvalidElementParams string
+
+func (err InvalidElementParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${element(\"${subdirs(\".\")}\", \"index\")}', where the first parameter is a call to a function that returns a list, but got '%s'", string(err))
+}
+
+type ElementIndexOutOfRange struct {
+	Index  int
+	Length int
+}
+
+func (err ElementIndexOutOfRange) Error() string {
+	if err.Length == 0 {
+		return fmt.Sprintf("The list passed to element must not be empty, but got an empty list and index %d", err.Index)
+	}
+	return fmt.Sprintf("The index passed to element must not be negative, but got %d", err.Index)
+}
+
+type EmptyList string
+
+func (err EmptyList) Error() string {
+	return fmt.Sprintf("The list passed to %s must not be empty", string(err))
+}
+
+type InvalidAssertUniqueParams string
+
+func (err InvalidAssertUniqueParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${assert_unique(\"${subdirs(\".\")}\", \"message\")}', where the first parameter is a call to a function that returns a list, but got '%s'", string(err))
+}
+
+type DuplicateListElement struct {
+	Message string
+	Value   interface{}
+}
+
+func (err DuplicateListElement) Error() string {
+	return fmt.Sprintf("%s: '%v' appears more than once", err.Message, err.Value)
+}
+
+type InvalidAssertOneOfParams string
+
+func (err InvalidAssertOneOfParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${assert_oneof(\"${get_env(\"ENV\", \"dev\")}\", \"${subdirs(\"../envs\")}\", \"message\")}', where the second parameter is a call to a function that returns a list, but got '%s'", string(err))
+}
+
+type ValueNotAllowed struct {
+	Message string
+	Value   interface{}
+	Allowed interface{}
+}
+
+func (err ValueNotAllowed) Error() string {
+	return fmt.Sprintf("%s: '%v' is not one of %v", err.Message, err.Value, err.Allowed)
+}
+
+type InvalidMapToEntriesParams string
+
+func (err InvalidMapToEntriesParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${map_to_entries(\"${makemap(\"key\", \"value\", ...)}\")}', where the only parameter is a call to a function that returns a map, but got '%s'", string(err))
+}
+
+type InvalidSortMapByValueParams string
+
+func (err InvalidSortMapByValueParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${sortmap_by_value(\"${makemap(\"key\", \"value\", ...)}\")}', where the only parameter is a call to a function that returns a map, but got '%s'", string(err))
+}
+
+type InvalidMergeParams string
+
+func (err InvalidMergeParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${merge(\"${makemap(\"key\", \"value\", ...)}\", \"${makemap(\"key\", \"value\", ...)}\", ...)}', where each parameter is a call to a function that returns a map, but got '%s'", string(err))
+}
+
+type MergeParamNotAMap struct {
+	Position int
+	Value    interface{}
+}
+
+func (err MergeParamNotAMap) Error() string {
+	return fmt.Sprintf("The parameter passed to merge at position %d must be a map, such as the result of a call to makemap, or {}, but got '%v'", err.Position, err.Value)
+}
+
+type MergeNestedValueNotSupported struct {
+	Position int
+	Key      string
+	Value    interface{}
+}
+
+func (err MergeNestedValueNotSupported) Error() string {
+	return fmt.Sprintf("The value of the key '%s' in the map passed to merge at position %d is a map or list, '%v', but merge only supports maps of strings, as nested maps and lists can't be represented in the config", err.Key, err.Position, err.Value)
+}
+
+type NonComparableMapValues struct {
+	Function string
+	Value    interface{}
+}
+
+func (err NonComparableMapValues) Error() string {
+	return fmt.Sprintf("The values of the map passed to %s must be all numbers or all other strings, but got '%v'", err.Function, err.Value)
+}
+
+type InvalidClampParams string
+
+func (err InvalidClampParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${clamp(\"value\", \"min\", \"max\")}', but got '%s'", string(err))
+}
+
+type NotANumber struct {
+	Function string
+	Value    interface{}
+}
+
+func (err NotANumber) Error() string {
+	return fmt.Sprintf("The parameters of %s must be numbers, or calls to functions that return a number, but got '%v'", err.Function, err.Value)
+}
+
+type InvalidClampRange struct {
+	Min string
+	Max string
+}
+
+func (err InvalidClampRange) Error() string {
+	return fmt.Sprintf("The min passed to clamp must not be greater than the max, but got min %s and max %s", err.Min, err.Max)
+}
+
+type InvalidHttpGetJsonParams string
+
+func (err InvalidHttpGetJsonParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${http_get_json(\"url\", \"path\")}', where both parameters are strings, but got '%s'", string(err))
+}
+
+type UnsupportedUrlScheme string
+
+func (err UnsupportedUrlScheme) Error() string {
+	return fmt.Sprintf("http_get_json only supports http and https URLs, but got '%s'", string(err))
+}
+
+type HttpGetFailed struct {
+	Url        string
+	Underlying error
+}
+
+func (err HttpGetFailed) Error() string {
+	return fmt.Sprintf("Unable to fetch %s: %v", err.Url, err.Underlying)
+}
+
+type HttpGetUnexpectedStatus struct {
+	Url        string
+	StatusCode int
+}
+
+func (err HttpGetUnexpectedStatus) Error() string {
+	return fmt.Sprintf("Fetching %s failed with status code %d", err.Url, err.StatusCode)
+}
+
+type InvalidJsonResponse struct {
+	Url        string
+	Underlying error
+}
+
+func (err InvalidJsonResponse) Error() string {
+	return fmt.Sprintf("The response from %s is not valid JSON: %v", err.Url, err.Underlying)
+}
+
+type JsonPathNotFound struct {
+	Url     string
+	Path    string
+	Segment string
+}
+
+func (err JsonPathNotFound) Error() string {
+	return fmt.Sprintf("The JSON document at %s has no value at path '%s': '%s' not found", err.Url, err.Path, err.Segment)
+}
+
+type UnsupportedJsonValue struct {
+	Path  string
+	Value interface{}
+}
+
+func (err UnsupportedJsonValue) Error() string {
+	return fmt.Sprintf("The value at path '%s' must be a string, number, boolean, list of strings, or object whose values are all strings, but got '%v'", err.Path, err.Value)
+}
+
+type InvalidHashBucketParams string
+
+func (err InvalidHashBucketParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${hash_bucket(\"key\", \"buckets\")}', where buckets is a number, but got '%s'", string(err))
+}
+
+type InvalidSeededIntParams string
+
+func (err InvalidSeededIntParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${seeded_int(\"seed\", \"min\", \"max\")}', but got '%s'", string(err))
+}
+
+type NotAWholeNumber struct {
+	Function string
+	Value    interface{}
+}
+
+func (err NotAWholeNumber) Error() string {
+	return fmt.Sprintf("The numbers passed to %s must be whole numbers, or calls to functions that return one, but got '%v'", err.Function, err.Value)
+}
+
+type InvalidSeededIntRange struct {
+	Min int
+	Max int
+}
+
+func (err InvalidSeededIntRange) Error() string {
+	return fmt.Sprintf("The min passed to seeded_int must not be greater than the max, but got min %d and max %d", err.Min, err.Max)
+}
+
+type InvalidHashBucketCount int
+
+func (err InvalidHashBucketCount) Error() string {
+	return fmt.Sprintf("The number of buckets passed to hash_bucket must be at least 1, but got %d", int(err))
+}
+
+type NoIncludeConfig struct {
+	FunctionName string
+	ConfigPath   string
+}
+
+func (err NoIncludeConfig) Error() string {
+	return fmt.Sprintf("%s() can only be used in a config that is included by another config, but %s is not being included", err.FunctionName, err.ConfigPath)
+}
+
+type InvalidFingerprintParams string
+
+func (err InvalidFingerprintParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${fingerprint(\"value\", ...)}', with at least one value, but got '%s'", string(err))
+}
+
+type HelperFunctionNotAllowedInSource string
+
+func (err HelperFunctionNotAllowedInSource) Error() string {
+	return fmt.Sprintf("Helper function %s cannot be used in terraform.source", string(err))
+}
+
+type LateHelperFunctionOutsideSource struct {
+	FunctionName string
+	Setting      string
+}
+
+func (err LateHelperFunctionOutsideSource) Error() string {
+	return fmt.Sprintf("Helper function %s can only be used in terraform.source, but it was used in %s", err.FunctionName, err.Setting)
+}
+
+type InvalidMakeMapParams string
+
+func (err InvalidMakeMapParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${makemap(\"key1\", \"value1\", \"key2\", \"value2\", ...)}', but got '%s'", string(err))
+}
+
+type OddNumberOfMakeMapParams int
+
+func (err OddNumberOfMakeMapParams) Error() string {
+	return fmt.Sprintf("makemap expects an even number of parameters, alternating between keys and values, but got %d", int(err))
+}
+
+type EmptyMakeMapKey int
+
+func (err EmptyMakeMapKey) Error() string {
+	return fmt.Sprintf("The key passed to makemap at position %d is empty. Keys must be non-empty strings.", int(err))
+}
+
+type DuplicateMakeMapKey string
+
+func (err DuplicateMakeMapKey) Error() string {
+	return fmt.Sprintf("The key '%s' was passed to makemap more than once", string(err))
+}
+
+type GitNotAvailable struct {
+	Command    string
+	Underlying error
+}
+
+func (err GitNotAvailable) Error() string {
+	return fmt.Sprintf("get_git_describe() requires git, but the %s command could not be found: %v", err.Command, err.Underlying)
+}
+
+type GitDescribeFailed struct {
+	Dir        string
+	Output     string
+	Underlying error
+}
+
+func (err GitDescribeFailed) Error() string {
+	return fmt.Sprintf("Running git describe in %s failed: %v. Output: %s", err.Dir, err.Underlying, err.Output)
+}
+
+type ConfigPathUnknown string
+
+func (functionName ConfigPathUnknown) Error() string {
+	return fmt.Sprintf("Cannot call %s(), as the path of the current Terragrunt config file isn't known", string(functionName))
+}
+
+type InvalidRetryParams string
+
+func (err InvalidRetryParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${retry(\"${call()}\", \"attempts\", \"delay in seconds\")}', where attempts is a whole number of at least 1 and the delay is not negative, but got '%s'", string(err))
+}
+
+type InvalidRunCmdParams string
+
+func (err InvalidRunCmdParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${run_cmd(\"command\", \"arg1\", \"arg2\", ...)}', but got '%s'", string(err))
+}
+
+type RunCmdFailed struct {
+	Command    string
+	Args       []string
+	ExitCode   int
+	Underlying error
+}
+
+func (err RunCmdFailed) Error() string {
+	return fmt.Sprintf("Running %s %s with run_cmd failed with exit code %d: %v", err.Command, strings.Join(err.Args, " "), err.ExitCode, err.Underlying)
+}
+
+type InvalidAllowEmptyParams string
+
+func (err InvalidAllowEmptyParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${allow_empty(\"value\")}', but got '%s'", string(err))
+}
+
+type EmptyHelperFunctionResult string
+
+func (err EmptyHelperFunctionResult) Error() string {
+	return fmt.Sprintf("%s returned an empty value, which is an error with --terragrunt-fail-on-empty. Wrap it in allow_empty if the value may be empty, such as ${allow_empty(\"%s\")}.", string(err), string(err))
+}
+
+type InterpolatedMapKeyNotAScalar struct {
+	Call  string
+	Value interface{}
+}
+
+func (err InterpolatedMapKeyNotAScalar) Error() string {
+	return fmt.Sprintf("%s is used as a map key, but returned %v, which is not a string, number or boolean.", err.Call, err.Value)
 }
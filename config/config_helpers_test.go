@@ -1,15 +1,32 @@
 package config
 
 import (
+	"bytes"
+	goerrors "errors"
 	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/gruntwork-io/terragrunt/aws_helper"
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/gruntwork-io/terragrunt/test/helpers"
 	"github.com/gruntwork-io/terragrunt/util"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestPathRelativeToInclude(t *testing.T) {
@@ -195,11 +212,47 @@ func TestFindInParentFolders(t *testing.T) {
 			"fallback.txt",
 			nil,
 		},
+		{
+			`"foo.txt"`,
+			terragruntOptionsForTest(t, "../test/fixture-parent-folders/other-file-names/child/sub-child/sub-sub-child/sub-sub-sub-child/"+DefaultTerragruntConfigPath),
+			"../../../../foo.txt",
+			nil,
+		},
+		{
+			`"foo.txt", "fallback.txt"`,
+			terragruntOptionsForTest(t, "../test/fixture-parent-folders/other-file-names/child/sub-child/sub-sub-child/sub-sub-sub-child/"+DefaultTerragruntConfigPath),
+			"../../../../foo.txt",
+			nil,
+		},
+		{
+			`"custom-name.tfvars"`,
+			terragruntOptionsForTest(t, "../test/fixture-parent-folders/other-file-names/child/sub-child/sub-sub-child/sub-sub-sub-child/"+DefaultTerragruntConfigPath),
+			"",
+			ParentFileNotFound{},
+		},
+		{
+			`"custom-name.tfvars", "fallback-value"`,
+			terragruntOptionsForTest(t, "../test/fixture-parent-folders/other-file-names/child/sub-child/sub-sub-child/sub-sub-sub-child/"+DefaultTerragruntConfigPath),
+			"fallback-value",
+			nil,
+		},
+		{
+			`"custom-name.tfvars", ""`,
+			terragruntOptionsForTest(t, "/"),
+			"",
+			nil,
+		},
+		{
+			`"foo.txt", "fallback.txt"`,
+			terragruntOptionsForTestWithMaxFolders(t, "../test/fixture-parent-folders/other-file-names/child/sub-child/sub-sub-child/sub-sub-sub-child/"+DefaultTerragruntConfigPath, 2),
+			"fallback.txt",
+			nil,
+		},
 	}
 
 	for _, testCase := range testCases {
 		t.Run(testCase.terragruntOptions.TerragruntConfigPath, func(t *testing.T) {
-			actualPath, actualErr := findInParentFolders(testCase.params, testCase.terragruntOptions)
+			actualPath, actualErr := findInParentFolders(testCase.params, nil, testCase.terragruntOptions)
 			if testCase.expectedErr != nil {
 				if assert.Error(t, actualErr) {
 					assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr))
@@ -299,6 +352,20 @@ func TestResolveTerragruntInterpolation(t *testing.T) {
 			"child/sub-child",
 			nil,
 		},
+		{
+			"${get_include_path()}",
+			&IncludeConfig{Path: "${find_in_parent_folders()}"},
+			terragruntOptionsForTest(t, "../test/fixture-parent-folders/terragrunt-in-root/child/sub-child/"+DefaultTerragruntConfigPath),
+			"../../" + DefaultTerragruntConfigPath,
+			nil,
+		},
+		{
+			"${get_include_path()}",
+			nil,
+			terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath),
+			"",
+			NoIncludeConfig{},
+		},
 		{
 			"${find_in_parent_folders()}",
 			nil,
@@ -431,6 +498,34 @@ func TestResolveTerragruntConfigString(t *testing.T) {
 			"foo/child/bar/child",
 			nil,
 		},
+		{
+			"${path_relative_from_include()}",
+			nil,
+			terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath),
+			".",
+			nil,
+		},
+		{
+			"${path_relative_from_include()}",
+			&IncludeConfig{Path: "../../../" + DefaultTerragruntConfigPath},
+			terragruntOptionsForTest(t, "/root/child/sub-child/sub-sub-child/"+DefaultTerragruntConfigPath),
+			"../../..",
+			nil,
+		},
+		{
+			"${path_relative_from_include()}/modules//${path_relative_to_include()}",
+			&IncludeConfig{Path: "/root/" + DefaultTerragruntConfigPath},
+			terragruntOptionsForTest(t, "/root/child/sub-child/sub-sub-child/"+DefaultTerragruntConfigPath),
+			"../../../modules//child/sub-child/sub-sub-child",
+			nil,
+		},
+		{
+			"${path_relative_from_include()}/modules//${path_relative_to_include()}",
+			&IncludeConfig{Path: "${find_in_parent_folders()}"},
+			terragruntOptionsForTest(t, "../test/fixture-parent-folders/terragrunt-in-root/child/sub-child/"+DefaultTerragruntConfigPath),
+			"../../modules//child/sub-child",
+			nil,
+		},
 		{
 			"${find_in_parent_folders()}",
 			nil,
@@ -469,10 +564,59 @@ func TestResolveTerragruntConfigString(t *testing.T) {
 		{
 			"foo/${unknown}/bar",
 			nil,
-			terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath),
+			terragruntOptionsForTestStrict(t, "/root/child/"+DefaultTerragruntConfigPath),
 			"",
 			InvalidInterpolationSyntax("${unknown}"),
 		},
+		{
+			"foo/${unknown}/bar",
+			nil,
+			terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath),
+			"foo/${unknown}/bar",
+			nil,
+		},
+		{
+			`"${var.region}"`,
+			nil,
+			terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath),
+			`"${var.region}"`,
+			nil,
+		},
+		{
+			`"${module.vpc.id}-${get_env("TEST_ENV_TERRAGRUNT_MISSING", "dev")}"`,
+			nil,
+			terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath),
+			`"${module.vpc.id}-dev"`,
+			nil,
+		},
+		{
+			`"${var.region}"`,
+			nil,
+			terragruntOptionsForTestStrict(t, "/root/child/"+DefaultTerragruntConfigPath),
+			"",
+			InvalidInterpolationSyntax("${var.region}"),
+		},
+		{
+			`"${module.vpc.id}"`,
+			nil,
+			terragruntOptionsForTestStrict(t, "/root/child/"+DefaultTerragruntConfigPath),
+			"",
+			InvalidInterpolationSyntax("${module.vpc.id}"),
+		},
+		{
+			`"${lookup(var.regions, "dev")}"`,
+			nil,
+			terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath),
+			"",
+			InvalidInterpolationSyntax(`${lookup(var.regions, "dev")}`),
+		},
+		{
+			`"${get_tfvars_dir ()}"`,
+			nil,
+			terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath),
+			"",
+			InvalidInterpolationSyntax("${get_tfvars_dir ()}"),
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -602,6 +746,63 @@ TERRAGRUNT_HIT","")}`),
 			`foo/${get_env("TEST_ENV_TERRAGRUNT_HIT/bar`,
 			nil,
 		},
+		{
+			`foo/${get_env("TEST_ENV_TERRAGRUNT_HIT")}/bar`,
+			nil,
+			terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"TEST_ENV_TERRAGRUNT_HIT": "HIT"}),
+			"foo/HIT/bar",
+			nil,
+		},
+		{
+			// Set to an empty string, which is not the same as unset
+			`foo/${get_env("TEST_ENV_TERRAGRUNT_HIT")}/bar`,
+			nil,
+			terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"TEST_ENV_TERRAGRUNT_HIT": ""}),
+			"foo//bar",
+			nil,
+		},
+		{
+			`foo/${get_env("TEST_ENV_TERRAGRUNT_HIT", "default")}/bar`,
+			nil,
+			terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"TEST_ENV_TERRAGRUNT_HIT": ""}),
+			"foo//bar",
+			nil,
+		},
+		{
+			`foo/${get_env("TEST_ENV_TERRAGRUNT_HIT")}/bar`,
+			nil,
+			terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"TEST_ENV_TERRAGRUNT_OTHER": "SOMETHING"}),
+			"",
+			EnvVarNotFound("TEST_ENV_TERRAGRUNT_HIT"),
+		},
+		{
+			`foo/${get_env("")}/bar`,
+			nil,
+			terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath),
+			"",
+			InvalidGetEnvParams(`""`),
+		},
+		{
+			`foo/${get_env("TEST_ENV_TERRAGRUNT_HIT", "a", "b")}/bar`,
+			nil,
+			terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath),
+			"",
+			InvalidGetEnvParams(`"TEST_ENV_TERRAGRUNT_HIT", "a", "b"`),
+		},
+		{
+			`${find_in_parent_folders("${get_env("TEST_ENV_TERRAGRUNT_CONFIG_NAME")}")}`,
+			nil,
+			terragruntOptionsForTestWithEnv(t, "../test/fixture-parent-folders/other-file-names/child/"+DefaultTerragruntConfigPath, map[string]string{"TEST_ENV_TERRAGRUNT_CONFIG_NAME": "foo.txt"}),
+			"../foo.txt",
+			nil,
+		},
+		{
+			`${find_in_parent_folders("${get_env("TEST_ENV_TERRAGRUNT_CONFIG_NAME")}")}`,
+			nil,
+			terragruntOptionsForTest(t, "../test/fixture-parent-folders/other-file-names/child/"+DefaultTerragruntConfigPath),
+			"",
+			EnvVarNotFound("TEST_ENV_TERRAGRUNT_CONFIG_NAME"),
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -646,182 +847,3455 @@ func TestResolveCommandsInterpolationConfigString(t *testing.T) {
 			fmt.Sprintf(`commands = "test-%v"`, TERRAFORM_COMMANDS_NEED_VARS),
 			nil,
 		},
-	}
-
-	for _, testCase := range testCases {
-		actualOut, actualErr := ResolveTerragruntConfigString(testCase.str, testCase.include, testCase.terragruntOptions)
-		if testCase.expectedErr != nil {
-			assert.True(t, errors.IsError(actualErr, testCase.expectedErr), "For string '%s' include %v and options %v, expected error %v but got error %v and output %v", testCase.str, testCase.include, testCase.terragruntOptions, testCase.expectedErr, actualErr, actualOut)
-		} else {
-			assert.Nil(t, actualErr, "For string '%s' include %v and options %v, unexpected error: %v", testCase.str, testCase.include, testCase.terragruntOptions, actualErr)
-			assert.Equal(t, testCase.expectedOut, actualOut, "For string '%s' include %v and options %v", testCase.str, testCase.include, testCase.terragruntOptions)
-		}
-	}
-}
-
-func TestResolveMultipleInterpolationsConfigString(t *testing.T) {
-	t.Parallel()
-
-	testCases := []struct {
-		str               string
-		include           *IncludeConfig
-		terragruntOptions *options.TerragruntOptions
-		expectedOut       string
-		expectedErr       error
-	}{
 		{
-			`${get_env("NON_EXISTING_VAR1", "default1")}-${get_env("NON_EXISTING_VAR2", "default2")}`,
+			`azs = ["${range_list("1", "4")}"]`,
 			nil,
 			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
-			fmt.Sprintf("default1-default2"),
+			`azs = [1, 2, 3]`,
 			nil,
 		},
 		{
-			// Included within quotes
-			`"${get_env("NON_EXISTING_VAR1", "default1")}-${get_env("NON_EXISTING_VAR2", "default2")}"`,
+			`commands = ["${when_flag("USE_INPUT_COMMANDS", "${get_terraform_commands_that_need_input()}", "apply")}"]`,
 			nil,
-			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
-			`"default1-default2"`,
+			terragruntOptionsForTestWithEnv(t, DefaultTerragruntConfigPath, map[string]string{"USE_INPUT_COMMANDS": "1"}),
+			fmt.Sprintf("commands = [%s]", util.CommaSeparatedStrings(TERRAFORM_COMMANDS_NEED_INPUT)),
 			nil,
 		},
 		{
-			// Malformed parameters
-			`${get_env("NON_EXISTING_VAR1", "default"-${get_terraform_commands_that_need_vars()}`,
+			`commands = ["${when_flag("USE_INPUT_COMMANDS", "${get_terraform_commands_that_need_input()}", "apply")}"]`,
 			nil,
 			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
-			fmt.Sprintf(`${get_env("NON_EXISTING_VAR1", "default"-%v`, TERRAFORM_COMMANDS_NEED_VARS),
+			`commands = ["apply"]`,
 			nil,
 		},
 		{
-			`test1 = "${get_env("NON_EXISTING_VAR1", "default")}" test2 = ["${get_terraform_commands_that_need_vars()}"]`,
+			// The call passed to when_flag is left for when_flag to resolve, even within a longer string
+			`arguments = ["-var", "account_id=${when_flag("USE_ACCOUNT_ID", "${not_a_helper()}", "none")}"]`,
 			nil,
 			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
-			fmt.Sprintf(`test1 = "default" test2 = [%v]`, util.CommaSeparatedStrings(TERRAFORM_COMMANDS_NEED_VARS)),
+			`arguments = ["-var", "account_id=none"]`,
 			nil,
 		},
 		{
-			`${get_env("NON_EXISTING_VAR1", "default")}-${get_terraform_commands_that_need_vars()}`,
+			`size = "${cond("${eq("${get_env("ENV", "")}", "prod")}", "m5.large", "t3.small")}"`,
 			nil,
-			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
-			fmt.Sprintf("default-%v", TERRAFORM_COMMANDS_NEED_VARS),
+			terragruntOptionsForTestWithEnv(t, DefaultTerragruntConfigPath, map[string]string{"ENV": "prod"}),
+			`size = "m5.large"`,
 			nil,
 		},
-	}
-
-	for _, testCase := range testCases {
-		actualOut, actualErr := ResolveTerragruntConfigString(testCase.str, testCase.include, testCase.terragruntOptions)
-		if testCase.expectedErr != nil {
-			assert.True(t, errors.IsError(actualErr, testCase.expectedErr), "For string '%s' include %v and options %v, expected error %v but got error %v and output %v", testCase.str, testCase.include, testCase.terragruntOptions, testCase.expectedErr, actualErr, actualOut)
-		} else {
-			assert.Nil(t, actualErr, "For string '%s' include %v and options %v, unexpected error: %v", testCase.str, testCase.include, testCase.terragruntOptions, actualErr)
-			assert.Equal(t, testCase.expectedOut, actualOut, "For string '%s' include %v and options %v", testCase.str, testCase.include, testCase.terragruntOptions)
-		}
-	}
-}
-
-func TestGetTfVarsDirAbsPath(t *testing.T) {
-	t.Parallel()
-	workingDir, err := os.Getwd()
-	assert.Nil(t, err, "Could not get current working dir: %v", err)
-	testGetTfVarsDir(t, "/foo/bar/terraform.tfvars", fmt.Sprintf("%s/foo/bar", filepath.VolumeName(workingDir)))
-}
-
-func TestGetTfVarsDirRelPath(t *testing.T) {
-	t.Parallel()
-	workingDir, err := os.Getwd()
-	assert.Nil(t, err, "Could not get current working dir: %v", err)
-	workingDir = filepath.ToSlash(workingDir)
-
-	testGetTfVarsDir(t, "foo/bar/terraform.tfvars", fmt.Sprintf("%s/foo/bar", workingDir))
-}
-
-func testGetTfVarsDir(t *testing.T, configPath string, expectedPath string) {
-	terragruntOptions, err := options.NewTerragruntOptionsForTest(configPath)
-	assert.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
-
-	actualPath, err := getTfVarsDir(terragruntOptions)
-
-	assert.Nil(t, err, "Unexpected error: %v", err)
-	assert.Equal(t, expectedPath, actualPath)
-}
-
-func terragruntOptionsForTest(t *testing.T, configPath string) *options.TerragruntOptions {
-	opts, err := options.NewTerragruntOptionsForTest(configPath)
-	if err != nil {
-		t.Fatalf("Failed to create TerragruntOptions: %v", err)
-	}
-	return opts
-}
-
-func terragruntOptionsForTestWithMaxFolders(t *testing.T, configPath string, maxFoldersToCheck int) *options.TerragruntOptions {
-	opts := terragruntOptionsForTest(t, configPath)
-	opts.MaxFoldersToCheck = maxFoldersToCheck
-	return opts
-}
-
-func terragruntOptionsForTestWithEnv(t *testing.T, configPath string, env map[string]string) *options.TerragruntOptions {
-	opts := terragruntOptionsForTest(t, configPath)
-	opts.Env = env
-	return opts
-}
-
-func TestGetParentTfVarsDir(t *testing.T) {
-	t.Parallel()
-
-	currentDir, err := os.Getwd()
-	assert.Nil(t, err, "Could not get current working dir: %v", err)
-	parentDir := filepath.ToSlash(filepath.Dir(currentDir))
-
-	testCases := []struct {
-		include           *IncludeConfig
-		terragruntOptions *options.TerragruntOptions
-		expectedPath      string
-	}{
 		{
+			`size = "${cond("${eq("${get_env("ENV", "")}", "prod")}", "m5.large", "t3.small")}"`,
+			nil,
+			terragruntOptionsForTestWithEnv(t, DefaultTerragruntConfigPath, map[string]string{"ENV": "stage"}),
+			`size = "t3.small"`,
 			nil,
-			terragruntOptionsForTest(t, helpers.RootFolder+"child/"+DefaultTerragruntConfigPath),
-			helpers.RootFolder + "child",
 		},
 		{
-			&IncludeConfig{Path: "../" + DefaultTerragruntConfigPath},
-			terragruntOptionsForTest(t, helpers.RootFolder+"child/"+DefaultTerragruntConfigPath),
-			helpers.RootFolder,
+			`arguments = ["-var", "size=${cond("${ne("${get_env("ENV", "")}", "prod")}", "t3.small", "m5.large")}"]`,
+			nil,
+			terragruntOptionsForTestWithEnv(t, DefaultTerragruntConfigPath, map[string]string{"ENV": "prod"}),
+			`arguments = ["-var", "size=m5.large"]`,
+			nil,
 		},
 		{
-			&IncludeConfig{Path: helpers.RootFolder + DefaultTerragruntConfigPath},
-			terragruntOptionsForTest(t, helpers.RootFolder+"child/"+DefaultTerragruntConfigPath),
-			helpers.RootFolder,
+			// The branch cond returns keeps its type, so a list stays a list
+			`commands = ["${cond("${eq("${get_env("ENV", "")}", "prod")}", "${get_terraform_commands_that_need_vars()}", "plan")}"]`,
+			nil,
+			terragruntOptionsForTestWithEnv(t, DefaultTerragruntConfigPath, map[string]string{"ENV": "prod"}),
+			fmt.Sprintf("commands = [%s]", util.CommaSeparatedStrings(TERRAFORM_COMMANDS_NEED_VARS)),
+			nil,
 		},
 		{
-			&IncludeConfig{Path: "../../../" + DefaultTerragruntConfigPath},
-			terragruntOptionsForTest(t, helpers.RootFolder+"child/sub-child/sub-sub-child/"+DefaultTerragruntConfigPath),
-			helpers.RootFolder,
+			`width = "${longest("${get_terraform_commands_that_need_input()}")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`width = "refresh"`,
+			nil,
 		},
 		{
-			&IncludeConfig{Path: helpers.RootFolder + DefaultTerragruntConfigPath},
-			terragruntOptionsForTest(t, helpers.RootFolder+"child/sub-child/sub-sub-child/"+DefaultTerragruntConfigPath),
-			helpers.RootFolder,
+			`narrow = "${shortest("${get_terraform_commands_that_need_input()}")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`narrow = "init"`,
+			nil,
 		},
 		{
-			&IncludeConfig{Path: "../../other-child/" + DefaultTerragruntConfigPath},
-			terragruntOptionsForTest(t, helpers.RootFolder+"child/sub-child/"+DefaultTerragruntConfigPath),
-			fmt.Sprintf("%s/other-child", filepath.VolumeName(parentDir)),
+			`ca_bundle = "${filebase64("cert.der")}"`,
+			nil,
+			terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath),
+			`ca_bundle = "77u/YmluYXJ5AP8NCg=="`,
+			nil,
 		},
 		{
-			&IncludeConfig{Path: "../../" + DefaultTerragruntConfigPath},
-			terragruntOptionsForTest(t, "../child/sub-child/"+DefaultTerragruntConfigPath),
-			parentDir,
+			`assets_hash = "${filesha256("hello.txt")}"`,
+			nil,
+			terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath),
+			`assets_hash = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"`,
+			nil,
 		},
 		{
-			&IncludeConfig{Path: "${find_in_parent_folders()}"},
-			terragruntOptionsForTest(t, "../test/fixture-parent-folders/terragrunt-in-root/child/sub-child/"+DefaultTerragruntConfigPath),
-			fmt.Sprintf("%s/test/fixture-parent-folders/terragrunt-in-root", parentDir),
+			`commands = ["${cond("${get_terragrunt_cli_flag("terragrunt-source-update")}", "skip", "validate")}"]`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`commands = ["validate"]`,
+			nil,
+		},
+		{
+			`modules = ["${subdirs(".")}"]`,
+			nil,
+			terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath),
+			`modules = ["app", "db"]`,
+			nil,
+		},
+		{
+			`description = "${squash_whitespace("  managed by\n   terragrunt  ")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`description = "managed by terragrunt"`,
+			nil,
+		},
+		{
+			`zip = "${string("02134")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`zip = "02134"`,
+			nil,
+		},
+		{
+			`canary = "${string("${hash_bucket("app-1", "10")}")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`canary = "5"`,
+			nil,
+		},
+		{
+			`protected = "${string("${eq("a", "a")}")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`protected = "true"`,
+			nil,
+		},
+		{
+			`canary = "${hash_bucket("app-1", "10")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`canary = 5`,
+			nil,
+		},
+		{
+			`tags = ["${map_to_entries("${makemap("Name", "vpc", "Env", "prod")}")}"]`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`tags = [{"key" = "Env", "value" = "prod"}, {"key" = "Name", "value" = "vpc"}]`,
+			nil,
+		},
+		{
+			`tiers = ["${sortmap_by_value("${makemap("web", "3", "db", "1", "cache", "2")}")}"]`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`tiers = ["db", "cache", "web"]`,
+			nil,
+		},
+		{
+			`component = "${assert_oneof("${get_env("TEST_ENV_TERRAGRUNT_COMPONENT", "app")}", "${subdirs(".")}", "Unknown component")}"`,
+			nil,
+			terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath),
+			`component = "app"`,
+			nil,
+		},
+		{
+			`commands = "[${quote_join("${get_terraform_commands_that_need_input()}")}]"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`commands = "[\"apply\", \"import\", \"init\", \"plan\", \"refresh\"]"`,
+			nil,
+		},
+		{
+			`parallelism = "${get_num_cpus()}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			fmt.Sprintf(`parallelism = %d`, runtime.NumCPU()),
+			nil,
+		},
+		{
+			`parallelism = "${string("${get_num_cpus()}")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			fmt.Sprintf(`parallelism = "%d"`, runtime.NumCPU()),
+			nil,
+		},
+		{
+			`parallelism = "${clamp("${get_num_cpus()}", "1", "1")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`parallelism = 1`,
+			nil,
+		},
+		{
+			`replicas = "${clamp("${get_env("TEST_ENV_TERRAGRUNT_REPLICAS", "3")}", "2", "5")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`replicas = 3`,
+			nil,
+		},
+		{
+			`variant = "${cond("${eq("${hash_bucket("app-1", "10")}", "5")}", "canary", "stable")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`variant = "canary"`,
+			nil,
+		},
+		{
+			`protected = "${eq("${get_env("ENV", "")}", "prod")}"`,
+			nil,
+			terragruntOptionsForTestWithEnv(t, DefaultTerragruntConfigPath, map[string]string{"ENV": "prod"}),
+			`protected = true`,
+			nil,
+		},
+		{
+			`tags = "${merge("${makemap("Env", "prod", "Team", "core")}", "${makemap("Team", "infra")}")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`tags = {"Env" = "prod", "Team" = "infra"}`,
+			nil,
+		},
+		{
+			`tags = "${merge("${makemap("Env", "prod")}", "{}")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`tags = {"Env" = "prod"}`,
+			nil,
+		},
+		{
+			`commands = ["${concat("${get_terraform_commands_that_need_parallelism()}", "${get_terraform_commands_that_need_locking()}")}"]`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`commands = ["apply", "destroy", "plan", "refresh", "apply", "destroy", "import", "init", "plan", "refresh", "taint", "untaint"]`,
+			nil,
+		},
+		{
+			`ports = ["${concat("${range_list("2")}", "${range_list("8080", "8082")}")}"]`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`ports = [0, 1, 8080, 8081]`,
+			nil,
+		},
+		{
+			`command = "${element("${get_terraform_commands_that_need_parallelism()}", "4")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`command = "apply"`,
+			nil,
+		},
+		{
+			`arguments = ["-var", "dir=${when_flag("USE_DIR", "${get_tfvars_dir()}", "none")}"]`,
+			nil,
+			terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"USE_DIR": "yes"}),
+			`arguments = ["-var", "dir=/root/child"]`,
+			nil,
+		},
+		{
+			`arguments = ["-var", "inputs=${fingerprint("vpc", "${get_tfvars_dir()}")}"]`,
+			nil,
+			terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath),
+			`arguments = ["-var", "inputs=25a8c4fd8409f9ac4b88becace42b726a785bae25b0a314e0c139e82114480f2"]`,
+			nil,
+		},
+		{
+			`env_vars = "${makemap("TF_VAR_name", "vpc", "TF_VAR_env", "prod")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`env_vars = {"TF_VAR_env" = "prod", "TF_VAR_name" = "vpc"}`,
+			nil,
 		},
 	}
 
 	for _, testCase := range testCases {
-		actualPath, actualErr := getParentTfVarsDir(testCase.include, testCase.terragruntOptions)
-		assert.Nil(t, actualErr, "For include %v and options %v, unexpected error: %v", testCase.include, testCase.terragruntOptions, actualErr)
-		assert.Equal(t, testCase.expectedPath, actualPath, "For include %v and options %v", testCase.include, testCase.terragruntOptions)
+		actualOut, actualErr := ResolveTerragruntConfigString(testCase.str, testCase.include, testCase.terragruntOptions)
+		if testCase.expectedErr != nil {
+			assert.True(t, errors.IsError(actualErr, testCase.expectedErr), "For string '%s' include %v and options %v, expected error %v but got error %v and output %v", testCase.str, testCase.include, testCase.terragruntOptions, testCase.expectedErr, actualErr, actualOut)
+		} else {
+			assert.Nil(t, actualErr, "For string '%s' include %v and options %v, unexpected error: %v", testCase.str, testCase.include, testCase.terragruntOptions, actualErr)
+			assert.Equal(t, testCase.expectedOut, actualOut, "For string '%s' include %v and options %v", testCase.str, testCase.include, testCase.terragruntOptions)
+		}
+	}
+}
+
+func TestResolveMultipleInterpolationsConfigString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		str               string
+		include           *IncludeConfig
+		terragruntOptions *options.TerragruntOptions
+		expectedOut       string
+		expectedErr       error
+	}{
+		{
+			`${get_env("NON_EXISTING_VAR1", "default1")}-${get_env("NON_EXISTING_VAR2", "default2")}`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			fmt.Sprintf("default1-default2"),
+			nil,
+		},
+		{
+			// Included within quotes
+			`"${get_env("NON_EXISTING_VAR1", "default1")}-${get_env("NON_EXISTING_VAR2", "default2")}"`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			`"default1-default2"`,
+			nil,
+		},
+		{
+			// Malformed parameters
+			`${get_env("NON_EXISTING_VAR1", "default"-${get_terraform_commands_that_need_vars()}`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			fmt.Sprintf(`${get_env("NON_EXISTING_VAR1", "default"-%v`, TERRAFORM_COMMANDS_NEED_VARS),
+			nil,
+		},
+		{
+			`test1 = "${get_env("NON_EXISTING_VAR1", "default")}" test2 = ["${get_terraform_commands_that_need_vars()}"]`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			fmt.Sprintf(`test1 = "default" test2 = [%v]`, util.CommaSeparatedStrings(TERRAFORM_COMMANDS_NEED_VARS)),
+			nil,
+		},
+		{
+			`${get_env("NON_EXISTING_VAR1", "default")}-${get_terraform_commands_that_need_vars()}`,
+			nil,
+			terragruntOptionsForTest(t, DefaultTerragruntConfigPath),
+			fmt.Sprintf("default-%v", TERRAFORM_COMMANDS_NEED_VARS),
+			nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		actualOut, actualErr := ResolveTerragruntConfigString(testCase.str, testCase.include, testCase.terragruntOptions)
+		if testCase.expectedErr != nil {
+			assert.True(t, errors.IsError(actualErr, testCase.expectedErr), "For string '%s' include %v and options %v, expected error %v but got error %v and output %v", testCase.str, testCase.include, testCase.terragruntOptions, testCase.expectedErr, actualErr, actualOut)
+		} else {
+			assert.Nil(t, actualErr, "For string '%s' include %v and options %v, unexpected error: %v", testCase.str, testCase.include, testCase.terragruntOptions, actualErr)
+			assert.Equal(t, testCase.expectedOut, actualOut, "For string '%s' include %v and options %v", testCase.str, testCase.include, testCase.terragruntOptions)
+		}
+	}
+}
+
+func TestResolveInterpolatedMapKeysConfigString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		str         string
+		env         map[string]string
+		expectedOut string
+		expectedErr error
+	}{
+		{
+			`tags = { "${get_env("TAG_KEY", "")}" = "x" }`,
+			map[string]string{"TAG_KEY": "team"},
+			`tags = { "team" = "x" }`,
+			nil,
+		},
+		{
+			`tags = { "${get_env("TAG_KEY", "")}"="${get_env("TAG_VALUE", "")}" }`,
+			map[string]string{"TAG_KEY": "team", "TAG_VALUE": "infra"},
+			`tags = { "team"="infra" }`,
+			nil,
+		},
+		{
+			"tags = {\n  name = \"app\"\n  \"${get_env(\"TAG_KEY\", \"\")}\" = \"x\"\n  \"env\" = \"prod\"\n}",
+			map[string]string{"TAG_KEY": "team"},
+			"tags = {\n  name = \"app\"\n  \"team\" = \"x\"\n  \"env\" = \"prod\"\n}",
+			nil,
+		},
+		{
+			`tags = { "${get_env("TAG_KEY", "")}-${get_env("TAG_SUFFIX", "")}" = "x" }`,
+			map[string]string{"TAG_KEY": "team", "TAG_SUFFIX": "name"},
+			`tags = { "team-name" = "x" }`,
+			nil,
+		},
+		{
+			`tags = { "${eq("a", "b")}" = "x", "${truthy("yes")}" = "y" }`,
+			nil,
+			`tags = { "false" = "x", "true" = "y" }`,
+			nil,
+		},
+		{
+			`tags = { "${clamp("15", "0", "10")}" = "x" }`,
+			nil,
+			`tags = { "10" = "x" }`,
+			nil,
+		},
+		{
+			`extra_arguments "${get_env("TAG_KEY", "")}" { }`,
+			map[string]string{"TAG_KEY": "team"},
+			`extra_arguments "team" { }`,
+			nil,
+		},
+		{
+			`tags = { "${eq("a", "b")}" }`,
+			nil,
+			`tags = { false }`,
+			nil,
+		},
+		{
+			`tags = { "${get_terraform_commands_that_need_vars()}" = "x" }`,
+			nil,
+			"",
+			InterpolatedMapKeyNotAScalar{Call: "${get_terraform_commands_that_need_vars()}", Value: TERRAFORM_COMMANDS_NEED_VARS},
+		},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions := terragruntOptionsForTestWithEnv(t, DefaultTerragruntConfigPath, testCase.env)
+		actualOut, actualErr := ResolveTerragruntConfigString(testCase.str, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For string '%s'", testCase.str) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For string '%s'", testCase.str)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For string '%s', unexpected error: %v", testCase.str, actualErr)
+			assert.Equal(t, testCase.expectedOut, actualOut, "For string '%s'", testCase.str)
+		}
+	}
+}
+
+func TestResolveInvalidHelperFunctionNameConfigString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		str          string
+		expectedName string
+	}{
+		{`"${${fn}()}"`, "${fn}"},
+		{`foo = "${ ${fn} ("a")}"`, "${fn}"},
+		{`foo = "${${get_env("FN", "eq")}("a", "b")}"`, `${get_env("FN", "eq")}`},
+		{`foo = "prefix-${get-env("FOO", "")}"`, "get-env"},
+		{`foo = "${cond("true", "${fn.name()}", "no")}"`, "fn.name"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := ResolveTerragruntConfigString(testCase.str, nil, terragruntOptionsForTest(t, DefaultTerragruntConfigPath))
+		if assert.Error(t, err, "For string '%s'", testCase.str) {
+			actualErr, isInvalidName := errors.Unwrap(err).(InvalidHelperFunctionName)
+			if assert.True(t, isInvalidName, "For string '%s', unexpected error %v", testCase.str, err) {
+				assert.Equal(t, testCase.expectedName, actualErr.Name, "For string '%s'", testCase.str)
+			}
+		}
+	}
+
+	_, err := ResolveTerragruntConfigString(`foo = "${${fn}()}"`, nil, terragruntOptionsForTest(t, DefaultTerragruntConfigPath))
+	if assert.Error(t, err) {
+		assert.Equal(t, "Invalid interpolation syntax in the call starting with '${${fn}(': the function name '${fn}' must be a bare name, such as get_env. The name of a function can't be an interpolation or contain other characters than letters, digits, and underscores.", errors.Unwrap(err).Error())
+	}
+
+	// Terraform interpolations that aren't calls, and calls with a bare name, aren't affected
+	actualOut, err := ResolveTerragruntConfigString(`foo = "${var.region}-${get_env("FOO", "bar")}"`, nil, terragruntOptionsForTest(t, DefaultTerragruntConfigPath))
+	assert.NoError(t, err)
+	assert.Equal(t, `foo = "${var.region}-bar"`, actualOut)
+}
+
+func TestGetTfVarsDirAbsPath(t *testing.T) {
+	t.Parallel()
+	workingDir, err := os.Getwd()
+	assert.Nil(t, err, "Could not get current working dir: %v", err)
+	testGetTfVarsDir(t, "/foo/bar/terraform.tfvars", fmt.Sprintf("%s/foo/bar", filepath.VolumeName(workingDir)))
+}
+
+func TestGetTfVarsDirRelPath(t *testing.T) {
+	t.Parallel()
+	workingDir, err := os.Getwd()
+	assert.Nil(t, err, "Could not get current working dir: %v", err)
+	workingDir = filepath.ToSlash(workingDir)
+
+	testGetTfVarsDir(t, "foo/bar/terraform.tfvars", fmt.Sprintf("%s/foo/bar", workingDir))
+}
+
+func testGetTfVarsDir(t *testing.T, configPath string, expectedPath string) {
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(configPath)
+	assert.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	actualPath, err := getTfVarsDir(terragruntOptions)
+
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	assert.Equal(t, expectedPath, actualPath)
+}
+
+func TestGetOriginalTerragruntDir(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := os.Getwd()
+	require.NoError(t, err)
+	workingDir = filepath.ToSlash(workingDir)
+
+	testCases := []struct {
+		configPath         string
+		workingDir         string
+		originalWorkingDir string
+		expected           string
+	}{
+		// Downloading the Terraform code changes the working dir, but not the original one
+		{"/root/live/app/" + DefaultTerragruntConfigPath, "/root/live/app/.terragrunt-cache/abc/vpc", "/root/live/app", "/root/live/app"},
+		{"/root/live/app/" + DefaultTerragruntConfigPath, "/root/live/app", "/root/live/app", "/root/live/app"},
+		// --terragrunt-working-dir and --terragrunt-config can point at different folders
+		{"/root/common/" + DefaultTerragruntConfigPath, "/root/live/app", "/root/live/app", "/root/live/app"},
+		{"live/app/" + DefaultTerragruntConfigPath, "live/app", "live/app", workingDir + "/live/app"},
+		{"/root/live/app/" + DefaultTerragruntConfigPath, "/root/live/app", "", "/root/live/app"},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions := terragruntOptionsForTest(t, testCase.configPath)
+		terragruntOptions.WorkingDir = testCase.workingDir
+		terragruntOptions.OriginalWorkingDir = testCase.originalWorkingDir
+
+		actual, err := getOriginalTerragruntDir(terragruntOptions)
+		assert.NoError(t, err, "For working dir %s and original working dir %s", testCase.workingDir, testCase.originalWorkingDir)
+		assert.Equal(t, testCase.expected, actual, "For working dir %s and original working dir %s", testCase.workingDir, testCase.originalWorkingDir)
+	}
+}
+
+func TestGetModuleDownloadDir(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := os.Getwd()
+	require.NoError(t, err)
+	workingDir = filepath.ToSlash(workingDir)
+
+	testCases := []struct {
+		configPath  string
+		downloadDir string
+		expected    string
+	}{
+		// The default download dir doesn't have to exist yet
+		{"/root/live/app/" + DefaultTerragruntConfigPath, "", "/root/live/app/" + options.TerragruntCacheDir},
+		{"live/app/" + DefaultTerragruntConfigPath, "", workingDir + "/live/app/" + options.TerragruntCacheDir},
+		// Set with --terragrunt-download-dir
+		{"/root/live/app/" + DefaultTerragruntConfigPath, "/tmp/terragrunt-cache", "/tmp/terragrunt-cache"},
+		{"/root/live/app/" + DefaultTerragruntConfigPath, "cache", workingDir + "/cache"},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions := terragruntOptionsForTest(t, testCase.configPath)
+		if testCase.downloadDir != "" {
+			terragruntOptions.DownloadDir = testCase.downloadDir
+		}
+
+		actual, err := getModuleDownloadDir(terragruntOptions)
+		assert.NoError(t, err, "For config path %s and download dir %s", testCase.configPath, testCase.downloadDir)
+		assert.Equal(t, testCase.expected, actual, "For config path %s and download dir %s", testCase.configPath, testCase.downloadDir)
+	}
+}
+
+// Once the Terraform code is downloaded, the working dir is the download dir, but the helpers that take a relative path
+// must still resolve it relative to the folder of the Terragrunt config. Only get_terraform_workspace reads the working
+// dir Terraform runs in.
+func TestHelpersAfterSourceIsDownloaded(t *testing.T) {
+	t.Parallel()
+
+	downloadDir, err := ioutil.TempDir("", "terragrunt-download-dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(downloadDir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(downloadDir, ".terraform"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(downloadDir, ".terraform", "environment"), []byte("stage\n"), 0600))
+
+	configDir, err := filepath.Abs("../test/fixture-file-helpers")
+	require.NoError(t, err)
+	configDir = filepath.ToSlash(configDir)
+
+	terragruntOptions := terragruntOptionsForTest(t, util.JoinPath(configDir, DefaultTerragruntConfigPath))
+	terragruntOptions.WorkingDir = downloadDir
+
+	testCases := []struct {
+		function string
+		params   string
+		expected interface{}
+	}{
+		{"get_tfvars_dir", "", configDir},
+		{"get_original_terragrunt_dir", "", configDir},
+		{"filesha256", `"hello.txt"`, "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+		{"subdirs", `".."`, []string{"fixture-download-source", "fixture-dotenv", "fixture-file-helpers"}},
+		{"find_in_parent_folders", `"fixture-render-inputs"`, "../fixture-render-inputs"},
+		{"get_terraform_workspace", "", "stage"},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := executeTerragruntHelperFunction(testCase.function, testCase.params, nil, terragruntOptions)
+		if !assert.NoError(t, err, "For %s(%s)", testCase.function, testCase.params) {
+			continue
+		}
+
+		// The test folder has many more subfolders, so only check that the ones we expect are there
+		if names, isList := actual.([]string); isList {
+			assert.Subset(t, names, testCase.expected, "For %s(%s)", testCase.function, testCase.params)
+		} else {
+			assert.Equal(t, testCase.expected, actual, "For %s(%s)", testCase.function, testCase.params)
+		}
+	}
+}
+
+func terragruntOptionsForTest(t *testing.T, configPath string) *options.TerragruntOptions {
+	opts, err := options.NewTerragruntOptionsForTest(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create TerragruntOptions: %v", err)
+	}
+	return opts
+}
+
+func terragruntOptionsForTestWithMaxFolders(t *testing.T, configPath string, maxFoldersToCheck int) *options.TerragruntOptions {
+	opts := terragruntOptionsForTest(t, configPath)
+	opts.MaxFoldersToCheck = maxFoldersToCheck
+	return opts
+}
+
+func terragruntOptionsForTestStrict(t *testing.T, configPath string) *options.TerragruntOptions {
+	opts := terragruntOptionsForTest(t, configPath)
+	opts.Strict = true
+	return opts
+}
+
+func terragruntOptionsForTestWithEnv(t *testing.T, configPath string, env map[string]string) *options.TerragruntOptions {
+	opts := terragruntOptionsForTest(t, configPath)
+	opts.Env = env
+	return opts
+}
+
+func TestGetIncludePath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		include      *IncludeConfig
+		expectedPath string
+		expectedErr  error
+	}{
+		{&IncludeConfig{Path: "../" + DefaultTerragruntConfigPath}, "../" + DefaultTerragruntConfigPath, nil},
+		{&IncludeConfig{Path: "../../other-child/" + DefaultTerragruntConfigPath}, "../../other-child/" + DefaultTerragruntConfigPath, nil},
+		{&IncludeConfig{Path: helpers.RootFolder + DefaultTerragruntConfigPath}, helpers.RootFolder + DefaultTerragruntConfigPath, nil},
+		{nil, "", NoIncludeConfig{}},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions := terragruntOptionsForTest(t, helpers.RootFolder+"child/"+DefaultTerragruntConfigPath)
+		actualPath, actualErr := getIncludePath(testCase.include, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For include %v", testCase.include) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For include %v", testCase.include)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For include %v, unexpected error: %v", testCase.include, actualErr)
+			assert.Equal(t, testCase.expectedPath, actualPath, "For include %v", testCase.include)
+		}
+	}
+}
+
+func TestGetParentTfVarsDir(t *testing.T) {
+	t.Parallel()
+
+	currentDir, err := os.Getwd()
+	assert.Nil(t, err, "Could not get current working dir: %v", err)
+	parentDir := filepath.ToSlash(filepath.Dir(currentDir))
+
+	testCases := []struct {
+		include           *IncludeConfig
+		terragruntOptions *options.TerragruntOptions
+		expectedPath      string
+	}{
+		{
+			nil,
+			terragruntOptionsForTest(t, helpers.RootFolder+"child/"+DefaultTerragruntConfigPath),
+			helpers.RootFolder + "child",
+		},
+		{
+			&IncludeConfig{Path: "../" + DefaultTerragruntConfigPath},
+			terragruntOptionsForTest(t, helpers.RootFolder+"child/"+DefaultTerragruntConfigPath),
+			helpers.RootFolder,
+		},
+		{
+			&IncludeConfig{Path: helpers.RootFolder + DefaultTerragruntConfigPath},
+			terragruntOptionsForTest(t, helpers.RootFolder+"child/"+DefaultTerragruntConfigPath),
+			helpers.RootFolder,
+		},
+		{
+			&IncludeConfig{Path: "../../../" + DefaultTerragruntConfigPath},
+			terragruntOptionsForTest(t, helpers.RootFolder+"child/sub-child/sub-sub-child/"+DefaultTerragruntConfigPath),
+			helpers.RootFolder,
+		},
+		{
+			&IncludeConfig{Path: helpers.RootFolder + DefaultTerragruntConfigPath},
+			terragruntOptionsForTest(t, helpers.RootFolder+"child/sub-child/sub-sub-child/"+DefaultTerragruntConfigPath),
+			helpers.RootFolder,
+		},
+		{
+			&IncludeConfig{Path: "../../other-child/" + DefaultTerragruntConfigPath},
+			terragruntOptionsForTest(t, helpers.RootFolder+"child/sub-child/"+DefaultTerragruntConfigPath),
+			fmt.Sprintf("%s/other-child", filepath.VolumeName(parentDir)),
+		},
+		{
+			&IncludeConfig{Path: "../../" + DefaultTerragruntConfigPath},
+			terragruntOptionsForTest(t, "../child/sub-child/"+DefaultTerragruntConfigPath),
+			parentDir,
+		},
+		{
+			&IncludeConfig{Path: "${find_in_parent_folders()}"},
+			terragruntOptionsForTest(t, "../test/fixture-parent-folders/terragrunt-in-root/child/sub-child/"+DefaultTerragruntConfigPath),
+			fmt.Sprintf("%s/test/fixture-parent-folders/terragrunt-in-root", parentDir),
+		},
+	}
+
+	for _, testCase := range testCases {
+		actualPath, actualErr := getParentTfVarsDir(testCase.include, testCase.terragruntOptions)
+		assert.Nil(t, actualErr, "For include %v and options %v, unexpected error: %v", testCase.include, testCase.terragruntOptions, actualErr)
+		assert.Equal(t, testCase.expectedPath, actualPath, "For include %v and options %v", testCase.include, testCase.terragruntOptions)
+	}
+}
+
+func TestGetTerragruntDir(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "terragrunt-dir-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	// The temp folder may itself be behind a symlink, such as /tmp on macOS
+	tmpDir, err = filepath.EvalSymlinks(tmpDir)
+	require.NoError(t, err)
+	tmpDir = filepath.ToSlash(tmpDir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "live", "app"), 0700))
+	require.NoError(t, os.Symlink(filepath.Join(tmpDir, "live"), filepath.Join(tmpDir, "link")))
+
+	testCases := []struct {
+		terragruntConfigPath string
+		expectedPath         string
+	}{
+		{tmpDir + "/live/app/" + DefaultTerragruntConfigPath, tmpDir + "/live/app"},
+		{tmpDir + "/link/app/" + DefaultTerragruntConfigPath, tmpDir + "/live/app"},
+		{tmpDir + "/link/../live/app/" + DefaultTerragruntConfigPath, tmpDir + "/live/app"},
+		{helpers.RootFolder + "does-not-exist/child/" + DefaultTerragruntConfigPath, helpers.RootFolder + "does-not-exist/child"},
+	}
+
+	for _, testCase := range testCases {
+		actualPath, actualErr := ResolveTerragruntConfigString("${get_terragrunt_dir()}", nil, terragruntOptionsForTest(t, testCase.terragruntConfigPath))
+		assert.Nil(t, actualErr, "For config path %s, unexpected error: %v", testCase.terragruntConfigPath, actualErr)
+		assert.Equal(t, testCase.expectedPath, actualPath, "For config path %s", testCase.terragruntConfigPath)
+	}
+}
+
+func TestGetParentTerragruntDir(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "terragrunt-parent-dir-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	tmpDir, err = filepath.EvalSymlinks(tmpDir)
+	require.NoError(t, err)
+	tmpDir = filepath.ToSlash(tmpDir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "live", "app"), 0700))
+	require.NoError(t, os.Symlink(filepath.Join(tmpDir, "live"), filepath.Join(tmpDir, "link")))
+
+	testCases := []struct {
+		include              *IncludeConfig
+		terragruntConfigPath string
+		expectedPath         string
+		expectedErr          error
+	}{
+		{&IncludeConfig{Path: "../" + DefaultTerragruntConfigPath}, tmpDir + "/live/app/" + DefaultTerragruntConfigPath, tmpDir + "/live", nil},
+		{&IncludeConfig{Path: "../" + DefaultTerragruntConfigPath}, tmpDir + "/link/app/" + DefaultTerragruntConfigPath, tmpDir + "/live", nil},
+		{&IncludeConfig{Path: tmpDir + "/link/" + DefaultTerragruntConfigPath}, tmpDir + "/live/app/" + DefaultTerragruntConfigPath, tmpDir + "/live", nil},
+		{&IncludeConfig{Path: "../" + DefaultTerragruntConfigPath}, helpers.RootFolder + "does-not-exist/child/" + DefaultTerragruntConfigPath, helpers.RootFolder + "does-not-exist", nil},
+		{nil, tmpDir + "/live/app/" + DefaultTerragruntConfigPath, "", NoIncludeConfig{}},
+	}
+
+	for _, testCase := range testCases {
+		actualPath, actualErr := ResolveTerragruntConfigString("${get_parent_terragrunt_dir()}", testCase.include, terragruntOptionsForTest(t, testCase.terragruntConfigPath))
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For include %v and config path %s", testCase.include, testCase.terragruntConfigPath) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For include %v and config path %s", testCase.include, testCase.terragruntConfigPath)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For include %v and config path %s, unexpected error: %v", testCase.include, testCase.terragruntConfigPath, actualErr)
+			assert.Equal(t, testCase.expectedPath, actualPath, "For include %v and config path %s", testCase.include, testCase.terragruntConfigPath)
+		}
+	}
+}
+
+func TestFormatArn(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		partition   string
+		service     string
+		region      string
+		accountID   string
+		resource    string
+		expectedArn string
+		expectedErr error
+	}{
+		{"aws", "sqs", "us-east-1", "123456789012", "my-queue", "arn:aws:sqs:us-east-1:123456789012:my-queue", nil},
+		{"aws-cn", "SNS", "cn-north-1", "123456789012", " my-topic ", "arn:aws-cn:sns:cn-north-1:123456789012:my-topic", nil},
+		{"aws", "lambda", "eu-west-1", "123456789012", "/function:my-function", "arn:aws:lambda:eu-west-1:123456789012:function:my-function", nil},
+		{"", "sqs", "us-east-1", "123456789012", "my-queue", "", ArnComponentNotFound("partition")},
+		{"aws", "sqs", "", "123456789012", "my-queue", "", ArnComponentNotFound("region")},
+		{"aws", "sqs", "us-east-1", "", "my-queue", "", ArnComponentNotFound("account id")},
+		{"aws", "sqs", "us-east-1", "123456789012", "  ", "", ArnComponentNotFound("resource")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.expectedArn, func(t *testing.T) {
+			actualArn, actualErr := formatArn(testCase.partition, testCase.service, testCase.region, testCase.accountID, testCase.resource)
+			if testCase.expectedErr != nil {
+				if assert.Error(t, actualErr) {
+					assert.Equal(t, testCase.expectedErr, errors.Unwrap(actualErr))
+				}
+			} else {
+				assert.Nil(t, actualErr)
+				assert.Equal(t, testCase.expectedArn, actualArn)
+			}
+		})
+	}
+}
+
+func TestBuildArnInvalidParams(t *testing.T) {
+	t.Parallel()
+
+	for _, params := range []string{``, `"sqs"`} {
+		_, err := buildArn(params, terragruntOptionsForTest(t, DefaultTerragruntConfigPath))
+		if assert.Error(t, err, "For params %s", params) {
+			assert.IsType(t, InvalidBuildArnParams(""), errors.Unwrap(err))
+		}
+	}
+}
+
+func TestGetAWSPartition(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		region            string
+		expectedPartition string
+	}{
+		{"us-east-1", "aws"},
+		{"cn-north-1", "aws-cn"},
+		{"us-gov-west-1", "aws-us-gov"},
+	}
+
+	for _, testCase := range testCases {
+		actualPartition, err := getAWSPartition(testCase.region)
+		assert.Nil(t, err, "For region %s", testCase.region)
+		assert.Equal(t, testCase.expectedPartition, actualPartition, "For region %s", testCase.region)
+	}
+}
+
+func TestRegionValueInvalidParams(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+
+	testCases := []struct {
+		params      string
+		expectedErr error
+	}{
+		{``, InvalidRegionValueParams("")},
+		{`"${makemap("us-east-1", "a")}"`, InvalidRegionValueParams("")},
+		{`"${makemap("us-east-1", "a")}", "b", "c"`, InvalidRegionValueParams("")},
+		{`"us-east-1", "default"`, NotAMap{}},
+		{`"${get_terraform_commands_that_need_vars()}", "default"`, NotAMap{}},
+		{`"${not_a_helper()}", "default"`, UnknownHelperFunction("")},
+	}
+
+	// All of these fail before the region is looked up, so they don't depend on the AWS configuration
+	for _, testCase := range testCases {
+		_, err := regionValue(testCase.params, nil, terragruntOptions)
+		if assert.Error(t, err, "For params %s", testCase.params) {
+			assert.IsType(t, testCase.expectedErr, errors.Unwrap(err), "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestStandardTagsInvalidParams(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "../test/fixture-standard-tags/app/"+DefaultTerragruntConfigPath)
+
+	testCases := []struct {
+		params      string
+		expectedErr error
+	}{
+		{``, InvalidStandardTagsParams("")},
+		{`"${makemap("Name", "vpc")}", "${makemap("Env", "prod")}"`, InvalidStandardTagsParams("")},
+		{`"Name=vpc"`, NotAMap{}},
+		{`"${get_terraform_commands_that_need_vars()}"`, NotAMap{}},
+		{`"${not_a_helper()}"`, UnknownHelperFunction("")},
+	}
+
+	// All of these fail before the region is looked up, so they don't depend on the AWS configuration
+	for _, testCase := range testCases {
+		_, err := standardTags(testCase.params, nil, terragruntOptions)
+		if assert.Error(t, err, "For params %s", testCase.params) {
+			assert.IsType(t, testCase.expectedErr, errors.Unwrap(err), "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestReadDefaultTags(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		configPath  string
+		expected    map[string]string
+		expectedErr error
+	}{
+		{"../test/fixture-standard-tags/app/" + DefaultTerragruntConfigPath, map[string]string{"Team": "platform", "ManagedBy": "terragrunt", "CostCenter": "1234"}, nil},
+		{"../test/fixture-standard-tags/envs/prod/app/" + DefaultTerragruntConfigPath, map[string]string{"Team": "platform", "ManagedBy": "terragrunt", "Environment": "prod"}, nil},
+		{"/root/child/" + DefaultTerragruntConfigPath, map[string]string{}, nil},
+		{"../test/fixture-standard-tags/invalid/app/" + DefaultTerragruntConfigPath, nil, DefaultTagNotAScalar{}},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := readDefaultTags(nil, terragruntOptionsForTest(t, testCase.configPath))
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For config %s", testCase.configPath) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For config %s", testCase.configPath)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For config %s, unexpected error: %v", testCase.configPath, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For config %s", testCase.configPath)
+		}
+	}
+}
+
+func TestComposeStandardTags(t *testing.T) {
+	t.Parallel()
+
+	defaultTags := map[string]string{"Team": "platform", "Region": "from-defaults", "ManagedBy": "terragrunt"}
+
+	assert.Equal(t, map[string]string{"Team": "platform", "Region": "us-east-1", "ManagedBy": "terragrunt"}, composeStandardTags(defaultTags, "us-east-1", map[string]string{}))
+	assert.Equal(t, map[string]string{"Team": "payments", "Region": "eu-west-1", "ManagedBy": "terragrunt", "Name": "api"}, composeStandardTags(defaultTags, "eu-west-1", map[string]string{"Team": "payments", "Name": "api"}))
+	assert.Equal(t, map[string]string{"Region": "us-west-2"}, composeStandardTags(map[string]string{}, "us-east-1", map[string]string{"Region": "us-west-2"}))
+}
+
+func TestMapToEntries(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+
+	testCases := []struct {
+		params      string
+		expected    interface{}
+		expectedErr error
+	}{
+		{`"${makemap("Name", "vpc", "Env", "prod")}"`, []map[string]interface{}{{"key": "Env", "value": "prod"}, {"key": "Name", "value": "vpc"}}, nil},
+		{`"${makemap("b", "2", "a", "", "c", "3")}"`, []map[string]interface{}{{"key": "a", "value": ""}, {"key": "b", "value": "2"}, {"key": "c", "value": "3"}}, nil},
+		{`"${makemap()}"`, []map[string]interface{}{}, nil},
+		{``, nil, InvalidMapToEntriesParams("")},
+		{`"${makemap("a", "b")}", "extra"`, nil, InvalidMapToEntriesParams("")},
+		{`"a=b"`, nil, NotAMap{}},
+		{`"${get_terraform_commands_that_need_vars()}"`, nil, NotAMap{}},
+		{`"${not_a_helper()}"`, nil, UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := mapToEntries(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestSortMapByValue(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+
+	testCases := []struct {
+		params      string
+		expected    interface{}
+		expectedErr error
+	}{
+		{`"${makemap("web", "b", "db", "a", "cache", "b")}"`, []string{"db", "cache", "web"}, nil},
+		{`"${makemap("web", "10", "db", "9", "cache", "9.5", "queue", "9")}"`, []string{"db", "queue", "cache", "web"}, nil},
+		{`"${makemap("web", "b10", "db", "b9")}"`, []string{"web", "db"}, nil},
+		{`"${makemap("web", "+2", "db", "-3.5", "cache", "-3", "queue", "0")}"`, []string{"db", "cache", "queue", "web"}, nil},
+		{`"${makemap("web", "", "db", "a")}"`, []string{"web", "db"}, nil},
+		{`"${makemap()}"`, []string{}, nil},
+		{``, nil, InvalidSortMapByValueParams("")},
+		{`"${makemap("a", "b")}", "extra"`, nil, InvalidSortMapByValueParams("")},
+		{`"a=b"`, nil, NotAMap{}},
+		{`"${get_terraform_commands_that_need_vars()}"`, nil, NotAMap{}},
+		{`"${makemap("web", "10", "db", "a")}"`, nil, NonComparableMapValues{}},
+		{`"${not_a_helper()}"`, nil, UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := sortMapByValue(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"TEAM": "infra"})
+
+	testCases := []struct {
+		params      string
+		expected    map[string]string
+		expectedErr error
+	}{
+		{`"${makemap("Env", "prod")}"`, map[string]string{"Env": "prod"}, nil},
+		{`"${makemap("Env", "prod")}", "${makemap("Team", "core")}"`, map[string]string{"Env": "prod", "Team": "core"}, nil},
+		{`"${makemap("Env", "prod", "Team", "core")}", "${makemap("Team", "infra")}"`, map[string]string{"Env": "prod", "Team": "infra"}, nil},
+		{`"${makemap("Team", "infra")}", "${makemap("Env", "prod", "Team", "core")}"`, map[string]string{"Env": "prod", "Team": "core"}, nil},
+		{`"${makemap("Env", "prod")}", "{}"`, map[string]string{"Env": "prod"}, nil},
+		{`"{}", "${makemap("Env", "prod")}", " {} "`, map[string]string{"Env": "prod"}, nil},
+		{`"{}"`, map[string]string{}, nil},
+		{`"${makemap()}", "${makemap("Env", "prod")}"`, map[string]string{"Env": "prod"}, nil},
+		{`"${merge("${makemap("Env", "prod")}", "${makemap("Team", "core")}")}", "${makemap("Team", "infra", "Owner", "ops")}"`, map[string]string{"Env": "prod", "Team": "infra", "Owner": "ops"}, nil},
+		{`"${makemap("Env", "prod")}", "${merge("{}", "${makemap("Env", "stage")}")}"`, map[string]string{"Env": "stage"}, nil},
+		{``, nil, InvalidMergeParams("")},
+		{`a, b`, nil, InvalidMergeParams("")},
+		{`"${makemap("Env", "prod")}", "Team=core"`, nil, MergeParamNotAMap{}},
+		{`"${makemap("Env", "prod")}", "${get_env("TEAM", "")}"`, nil, MergeParamNotAMap{}},
+		{`"${get_terraform_commands_that_need_vars()}"`, nil, MergeParamNotAMap{}},
+		{`"${not_a_helper()}"`, nil, UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := merge(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestMergeErrorNamesParamPosition(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+
+	_, err := merge(`"${makemap("Env", "prod")}", "{}", "not-a-map"`, nil, terragruntOptions)
+	if assert.Error(t, err) {
+		assert.Equal(t, MergeParamNotAMap{Position: 2, Value: "not-a-map"}, errors.Unwrap(err))
+	}
+}
+
+func TestMergeMapParam(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value       interface{}
+		expected    map[string]string
+		expectedErr error
+	}{
+		{map[string]string{"Team": "infra"}, map[string]string{"Env": "prod", "Team": "infra"}, nil},
+		{map[string]string{}, map[string]string{"Env": "prod", "Team": "core"}, nil},
+		{map[string]interface{}{"Env": "stage", "Replicas": 3, "Public": true}, map[string]string{"Env": "stage", "Team": "core", "Replicas": "3", "Public": "true"}, nil},
+		{map[string]interface{}{"Tags": map[string]string{"Owner": "ops"}}, nil, MergeNestedValueNotSupported{}},
+		{map[string]interface{}{"Tags": map[string]interface{}{"Owner": map[string]string{"Name": "ops"}}}, nil, MergeNestedValueNotSupported{}},
+		{map[string]interface{}{"Env": "stage", "Zones": []string{"a", "b"}}, nil, MergeNestedValueNotSupported{}},
+		{[]map[string]interface{}{{"key": "Env", "value": "prod"}}, nil, MergeParamNotAMap{}},
+		{"Team=infra", nil, MergeParamNotAMap{}},
+	}
+
+	for _, testCase := range testCases {
+		out := map[string]string{"Env": "prod", "Team": "core"}
+		actualErr := mergeMapParam(out, 1, testCase.value)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For value %v", testCase.value) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For value %v", testCase.value)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For value %v, unexpected error: %v", testCase.value, actualErr)
+			assert.Equal(t, testCase.expected, out, "For value %v", testCase.value)
+		}
+	}
+}
+
+func TestMergeErrorNamesKeyOfNestedValue(t *testing.T) {
+	t.Parallel()
+
+	nested := map[string]string{"Owner": "ops"}
+	err := mergeMapParam(map[string]string{}, 2, map[string]interface{}{"Env": "prod", "Tags": nested})
+	if assert.Error(t, err) {
+		assert.Equal(t, MergeNestedValueNotSupported{Position: 2, Key: "Tags", Value: nested}, errors.Unwrap(err))
+	}
+}
+
+func TestClamp(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+
+	testCases := []struct {
+		params      string
+		expected    interface{}
+		expectedErr error
+	}{
+		{`"12", "1", "8"`, 8, nil},
+		{`"0", "1", "8"`, 1, nil},
+		{`"5", "1", "8"`, 5, nil},
+		{`"-3", "-5", "-1"`, -3, nil},
+		{`"-12", "-5", "+8"`, -5, nil},
+		{`"+5", "1", "8"`, 5, nil},
+		{`"-3.14", "-5", "8"`, -3.14, nil},
+		{`"+2.5", "-1.5", "+8"`, 2.5, nil},
+		{`"2E-3", "0", "1"`, 0.002, nil},
+		{`"1e5", "1", "1.5e4"`, 1.5e4, nil},
+		{`"4", "4", "4"`, 4, nil},
+		{`"2.5", "1", "8"`, 2.5, nil},
+		{`"0.5", "1", "8"`, 1.0, nil},
+		{`"12", "1", "7.5"`, 7.5, nil},
+		{` "3" , "1" , "8" `, 3, nil},
+		{`"${get_env("TEST_ENV_TERRAGRUNT_MISSING", "20")}", "1", "8"`, 8, nil},
+		{`"${range_list("3")}", "1", "8"`, nil, NotANumber{}},
+		{`"abc", "1", "8"`, nil, NotANumber{}},
+		{`"5", "", "8"`, nil, NotANumber{}},
+		{`"5", "1", "NaN"`, nil, NotANumber{}},
+		{`"5", "8", "1"`, nil, InvalidClampRange{}},
+		{`"5", "1.5", "1.2"`, nil, InvalidClampRange{}},
+		{`"5", "1"`, nil, InvalidClampParams("")},
+		{``, nil, InvalidClampParams("")},
+		{`"${not_a_helper()}", "1", "8"`, nil, UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := clamp(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value          interface{}
+		expectedNumber float64
+		expectedIsInt  bool
+		expectedIsNum  bool
+	}{
+		{"5", 5, true, true},
+		{"-5", -5, true, true},
+		{"+5", 5, true, true},
+		{" -42 ", -42, true, true},
+		{"3.14", 3.14, false, true},
+		{"-3.14", -3.14, false, true},
+		{"+3.14", 3.14, false, true},
+		{"1.5e10", 1.5e10, false, true},
+		{"1.5E10", 1.5e10, false, true},
+		{"2E-3", 0.002, false, true},
+		{"2e+3", 2000, false, true},
+		{"-2.5e-3", -0.0025, false, true},
+		{"1e5", 100000, false, true},
+		{1.5e10, 1.5e10, true, true},
+		{"3.4.3", 0, false, false},
+		{"1e", 0, false, false},
+		{"1e+", 0, false, false},
+		{"e5", 0, false, false},
+		{"1.5e10.5", 0, false, false},
+		{-7, -7, true, true},
+		{-2.5, -2.5, false, true},
+		{-2.0, -2, true, true},
+		{"-", 0, false, false},
+		{"+", 0, false, false},
+		{"--5", 0, false, false},
+		{"+-5", 0, false, false},
+		{"5-", 0, false, false},
+		{"- 5", 0, false, false},
+		{"", 0, false, false},
+		{[]string{"-5"}, 0, false, false},
+	}
+
+	for _, testCase := range testCases {
+		number, isInt, isNumber := parseNumber(testCase.value)
+		assert.Equal(t, testCase.expectedIsNum, isNumber, "For value %v", testCase.value)
+		if testCase.expectedIsNum {
+			assert.Equal(t, testCase.expectedNumber, number, "For value %v", testCase.value)
+			assert.Equal(t, testCase.expectedIsInt, isInt, "For value %v", testCase.value)
+		}
+	}
+}
+
+func TestHttpGetJson(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/config.json":
+			fmt.Fprint(writer, `{"name": "vpc", "count": 3, "ratio": 0.5, "enabled": true, "nothing": null, "zones": ["a", "b"], "tags": {"Env": "prod"}, "subnets": [{"cidr": "10.0.1.0/24"}]}`)
+		case "/invalid.json":
+			fmt.Fprint(writer, "not json")
+		case "/slow.json":
+			time.Sleep(500 * time.Millisecond)
+			fmt.Fprint(writer, `{}`)
+		default:
+			http.NotFound(writer, request)
+		}
+	}))
+	defer server.Close()
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+	terragruntOptions.HttpTimeout = 100 * time.Millisecond
+	terragruntOptions.Env = map[string]string{"CONFIG_URL": server.URL + "/config.json"}
+
+	testCases := []struct {
+		params      string
+		expected    interface{}
+		expectedErr error
+	}{
+		{fmt.Sprintf(`"%s/config.json", "name"`, server.URL), "vpc", nil},
+		{fmt.Sprintf(`"%s/config.json", "count"`, server.URL), 3, nil},
+		{fmt.Sprintf(`"%s/config.json", "ratio"`, server.URL), 0.5, nil},
+		{fmt.Sprintf(`"%s/config.json", "enabled"`, server.URL), true, nil},
+		{fmt.Sprintf(`"%s/config.json", "zones"`, server.URL), []string{"a", "b"}, nil},
+		{fmt.Sprintf(`"%s/config.json", "zones.1"`, server.URL), "b", nil},
+		{fmt.Sprintf(`"%s/config.json", "tags"`, server.URL), map[string]string{"Env": "prod"}, nil},
+		{fmt.Sprintf(`"%s/config.json", "subnets.0.cidr"`, server.URL), "10.0.1.0/24", nil},
+		{`"${get_env("CONFIG_URL", "")}", "name"`, "vpc", nil},
+		{fmt.Sprintf(`"%s/config.json", "missing"`, server.URL), nil, JsonPathNotFound{}},
+		{fmt.Sprintf(`"%s/config.json", "zones.2"`, server.URL), nil, JsonPathNotFound{}},
+		{fmt.Sprintf(`"%s/config.json", "name.first"`, server.URL), nil, JsonPathNotFound{}},
+		{fmt.Sprintf(`"%s/config.json", "nothing"`, server.URL), nil, UnsupportedJsonValue{}},
+		{fmt.Sprintf(`"%s/config.json", "subnets"`, server.URL), nil, UnsupportedJsonValue{}},
+		{fmt.Sprintf(`"%s/config.json", ""`, server.URL), nil, UnsupportedJsonValue{}},
+		{fmt.Sprintf(`"%s/missing.json", "name"`, server.URL), nil, HttpGetUnexpectedStatus{}},
+		{fmt.Sprintf(`"%s/invalid.json", "name"`, server.URL), nil, InvalidJsonResponse{}},
+		{fmt.Sprintf(`"%s/slow.json", "name"`, server.URL), nil, HttpGetFailed{}},
+		{`"file:///etc/passwd", "name"`, nil, UnsupportedUrlScheme("")},
+		{`"ftp://example.com/config.json", "name"`, nil, UnsupportedUrlScheme("")},
+		{`"config.json", "name"`, nil, UnsupportedUrlScheme("")},
+		{`"${range_list("3")}", "name"`, nil, InvalidHttpGetJsonParams("")},
+		{fmt.Sprintf(`"%s/config.json"`, server.URL), nil, InvalidHttpGetJsonParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := httpGetJson(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestMapEntries(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value         interface{}
+		expected      []map[string]interface{}
+		expectedIsMap bool
+	}{
+		{map[string]string{"b": "2", "a": "1"}, []map[string]interface{}{{"key": "a", "value": "1"}, {"key": "b", "value": "2"}}, true},
+		{map[string]int{"b": 2, "a": 1}, []map[string]interface{}{{"key": "a", "value": 1}, {"key": "b", "value": 2}}, true},
+		{map[string]interface{}{"list": []string{"x"}, "flag": true}, []map[string]interface{}{{"key": "flag", "value": true}, {"key": "list", "value": []string{"x"}}}, true},
+		{map[string]string{}, []map[string]interface{}{}, true},
+		{map[int]string{1: "a"}, nil, false},
+		{[]string{"a"}, nil, false},
+		{"a", nil, false},
+		{nil, nil, false},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualIsMap := mapEntries(testCase.value)
+		assert.Equal(t, testCase.expectedIsMap, actualIsMap, "For value %v", testCase.value)
+		assert.Equal(t, testCase.expected, actual, "For value %v", testCase.value)
+	}
+}
+
+func TestSelectRegionValue(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+	valuesByRegion := map[string]string{"us-east-1": "t3.large", "eu-west-1": "m5.large", "ap-south-1": ""}
+
+	testCases := []struct {
+		region       string
+		defaultValue string
+		expected     interface{}
+	}{
+		{"us-east-1", "t3.small", "t3.large"},
+		{"eu-west-1", "t3.small", "m5.large"},
+		{"ap-south-1", "t3.small", ""},
+		{"us-west-2", "t3.small", "t3.small"},
+		{"us-west-2", "${get_tfvars_dir()}", "/root/child"},
+		// The default is only resolved if it's returned
+		{"us-east-1", "${not_a_helper()}", "t3.large"},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := selectRegionValue(valuesByRegion, testCase.region, testCase.defaultValue, nil, terragruntOptions)
+		assert.Nil(t, err, "For region %s, unexpected error: %v", testCase.region, err)
+		assert.Equal(t, testCase.expected, actual, "For region %s", testCase.region)
+	}
+}
+
+func TestCollectParentFiles(t *testing.T) {
+	t.Parallel()
+
+	fixtureDir, err := filepath.Abs("../test/fixture-parent-folders/collect-parent-files")
+	assert.Nil(t, err)
+	fixtureDir = filepath.ToSlash(fixtureDir)
+
+	testCases := []struct {
+		params            string
+		include           *IncludeConfig
+		terragruntOptions *options.TerragruntOptions
+		expectedFiles     []string
+		expectedErr       error
+	}{
+		{
+			`"*.auto.tfvars"`,
+			&IncludeConfig{Path: "${find_in_parent_folders()}"},
+			terragruntOptionsForTest(t, fixtureDir+"/us-east-1/stage/app/"+DefaultTerragruntConfigPath),
+			[]string{fixtureDir + "/account.auto.tfvars", fixtureDir + "/us-east-1/region.auto.tfvars", fixtureDir + "/us-east-1/stage/app/app.auto.tfvars"},
+			nil,
+		},
+		{
+			`"*.auto.tfvars"`,
+			nil,
+			terragruntOptionsForTest(t, fixtureDir+"/us-east-1/stage/app/"+DefaultTerragruntConfigPath),
+			[]string{fixtureDir + "/account.auto.tfvars", fixtureDir + "/us-east-1/region.auto.tfvars", fixtureDir + "/us-east-1/stage/app/app.auto.tfvars"},
+			nil,
+		},
+		{
+			`"*.auto.tfvars"`,
+			&IncludeConfig{Path: "../../" + DefaultTerragruntConfigPath},
+			terragruntOptionsForTest(t, fixtureDir+"/us-east-1/stage/app/"+DefaultTerragruntConfigPath),
+			[]string{fixtureDir + "/us-east-1/region.auto.tfvars", fixtureDir + "/us-east-1/stage/app/app.auto.tfvars"},
+			nil,
+		},
+		{
+			`"region.auto.tfvars"`,
+			&IncludeConfig{Path: "${find_in_parent_folders()}"},
+			terragruntOptionsForTest(t, fixtureDir+"/us-east-1/stage/app/"+DefaultTerragruntConfigPath),
+			[]string{fixtureDir + "/us-east-1/region.auto.tfvars"},
+			nil,
+		},
+		{
+			`"*.does-not-exist"`,
+			&IncludeConfig{Path: "${find_in_parent_folders()}"},
+			terragruntOptionsForTest(t, fixtureDir+"/us-east-1/stage/app/"+DefaultTerragruntConfigPath),
+			[]string{},
+			nil,
+		},
+		{
+			`"*.auto.tfvars"`,
+			nil,
+			terragruntOptionsForTestWithMaxFolders(t, fixtureDir+"/us-east-1/stage/app/"+DefaultTerragruntConfigPath, 2),
+			nil,
+			ParentFileNotFound{},
+		},
+		{
+			``,
+			nil,
+			terragruntOptionsForTest(t, fixtureDir+"/us-east-1/stage/app/"+DefaultTerragruntConfigPath),
+			nil,
+			InvalidStringParams(""),
+		},
+		{
+			`""`,
+			nil,
+			terragruntOptionsForTest(t, fixtureDir+"/us-east-1/stage/app/"+DefaultTerragruntConfigPath),
+			nil,
+			EmptyStringNotAllowed(""),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.params, func(t *testing.T) {
+			actualFiles, actualErr := collectParentFiles(testCase.params, testCase.include, testCase.terragruntOptions)
+			if testCase.expectedErr != nil {
+				if assert.Error(t, actualErr) {
+					assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr))
+				}
+			} else {
+				assert.Nil(t, actualErr)
+				assert.Equal(t, testCase.expectedFiles, actualFiles)
+			}
+		})
+	}
+}
+
+func TestGetDotEnv(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		params            string
+		terragruntOptions *options.TerragruntOptions
+		expectedValue     string
+		expectedErr       error
+	}{
+		{`"../.env", "BUCKET_NAME"`, terragruntOptionsForTest(t, "../test/fixture-dotenv/child/"+DefaultTerragruntConfigPath), "my-dev-bucket", nil},
+		{`"../.env", "AWS_REGION"`, terragruntOptionsForTest(t, "../test/fixture-dotenv/child/"+DefaultTerragruntConfigPath), "us-west-2", nil},
+		{`".env", "GREETING"`, terragruntOptionsForTest(t, "../test/fixture-dotenv/"+DefaultTerragruntConfigPath), "hello world", nil},
+		{`"windows.env", "BUCKET_NAME"`, terragruntOptionsForTest(t, "../test/fixture-dotenv/"+DefaultTerragruntConfigPath), "my-windows-bucket", nil},
+		{`"windows.env", "AWS_REGION"`, terragruntOptionsForTest(t, "../test/fixture-dotenv/"+DefaultTerragruntConfigPath), "eu-west-1", nil},
+		{`"../.env", "MISSING"`, terragruntOptionsForTest(t, "../test/fixture-dotenv/child/"+DefaultTerragruntConfigPath), "", DotEnvKeyNotFound{}},
+		{`"../.env"`, terragruntOptionsForTest(t, "../test/fixture-dotenv/child/"+DefaultTerragruntConfigPath), "", InvalidGetDotEnvParams("")},
+		{`"", "BUCKET_NAME"`, terragruntOptionsForTest(t, "../test/fixture-dotenv/child/"+DefaultTerragruntConfigPath), "", EmptyStringNotAllowed("")},
+		{`"../.env", ""`, terragruntOptionsForTest(t, "../test/fixture-dotenv/child/"+DefaultTerragruntConfigPath), "", EmptyStringNotAllowed("")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.params, func(t *testing.T) {
+			actualValue, actualErr := getDotEnv(testCase.params, testCase.terragruntOptions)
+			if testCase.expectedErr != nil {
+				if assert.Error(t, actualErr) {
+					assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr))
+				}
+			} else {
+				assert.Nil(t, actualErr)
+				assert.Equal(t, testCase.expectedValue, actualValue)
+			}
+		})
+	}
+}
+
+func TestFileBase64(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		params            string
+		terragruntOptions *options.TerragruntOptions
+		expectedValue     string
+		expectedErr       error
+	}{
+		{`"hello.txt"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "aGVsbG8K", nil},
+		{`"../hello.txt"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/child/"+DefaultTerragruntConfigPath), "aGVsbG8K", nil},
+		// A byte order mark, a NUL byte, and a trailing CRLF must all survive as is
+		{`"cert.der"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "77u/YmluYXJ5AP8NCg==", nil},
+		{`"missing.pem"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "", FileNotFound("")},
+		{`""`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "", EmptyStringNotAllowed("")},
+		{``, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "", InvalidStringParams("")},
+		{`"hello.txt", "cert.der"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "", InvalidStringParams("")},
+		{`"${get_terragrunt_dir()}/hello.txt"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "aGVsbG8K", nil},
+		{`"${get_env("CERT", "")}"`, terragruntOptionsForTestWithEnv(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath, map[string]string{"CERT": "cert.der"}), "77u/YmluYXJ5AP8NCg==", nil},
+		{`"${get_env("NOT_SET", "")}"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "", EmptyStringNotAllowed("")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.params, func(t *testing.T) {
+			actualValue, actualErr := fileBase64(testCase.params, nil, testCase.terragruntOptions)
+			if testCase.expectedErr != nil {
+				if assert.Error(t, actualErr) {
+					assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr))
+				}
+			} else {
+				assert.Nil(t, actualErr)
+				assert.Equal(t, testCase.expectedValue, actualValue)
+			}
+		})
+	}
+}
+
+func TestFileSha256(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		params            string
+		terragruntOptions *options.TerragruntOptions
+		expectedValue     string
+		expectedErr       error
+	}{
+		{`"hello.txt"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", nil},
+		{`"../hello.txt"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/child/"+DefaultTerragruntConfigPath), "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", nil},
+		{`"cert.der"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "58a79c364871a1c5e204ca9b311444b76548939d821fb89385b71d191cdc649c", nil},
+		{`"missing.zip"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "", FileNotFound("")},
+		{`""`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "", EmptyStringNotAllowed("")},
+		{`"hello.txt", "cert.der"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "", InvalidStringParams("")},
+		{`"${get_terragrunt_dir()}/hello.txt"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", nil},
+		{`"${get_env("BUNDLE", "")}"`, terragruntOptionsForTestWithEnv(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath, map[string]string{"BUNDLE": "cert.der"}), "58a79c364871a1c5e204ca9b311444b76548939d821fb89385b71d191cdc649c", nil},
+		{`"${get_terragrunt_dir()}/missing.zip"`, terragruntOptionsForTest(t, "../test/fixture-file-helpers/"+DefaultTerragruntConfigPath), "", FileNotFound("")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.params, func(t *testing.T) {
+			actualValue, actualErr := fileSha256(testCase.params, nil, testCase.terragruntOptions)
+			if testCase.expectedErr != nil {
+				if assert.Error(t, actualErr) {
+					assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr))
+				}
+			} else {
+				assert.Nil(t, actualErr)
+				assert.Equal(t, testCase.expectedValue, actualValue)
+			}
+		})
+	}
+}
+
+func TestSubdirs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		params            string
+		terragruntOptions *options.TerragruntOptions
+		expectedValue     []string
+		expectedErr       error
+	}{
+		{`"."`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), []string{"app", "db"}, nil},
+		{`".", "false"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), []string{"app", "db"}, nil},
+		{`".", "true"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), []string{".hidden", "app", "db"}, nil},
+		{`"../fixture-subdirs"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), []string{"app", "db"}, nil},
+		{`"app"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), []string{}, nil},
+		{`"notes.txt"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), nil, NotADirectory("")},
+		{`"missing"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), nil, NotADirectory("")},
+		{`""`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), nil, EmptyStringNotAllowed("")},
+		{`".", "yes"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), nil, InvalidSubdirsParams("")},
+		{``, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), nil, InvalidSubdirsParams("")},
+		{`".", "true", "true"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), nil, InvalidSubdirsParams("")},
+		{`"${get_terragrunt_dir()}"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), []string{"app", "db"}, nil},
+		{`"${get_terragrunt_dir()}/app"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), []string{}, nil},
+		{`"${get_terragrunt_dir()}/notes.txt"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), nil, NotADirectory("")},
+		{`".", "${get_env("INCLUDE_HIDDEN", "false")}"`, terragruntOptionsForTestWithEnv(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath, map[string]string{"INCLUDE_HIDDEN": "true"}), []string{".hidden", "app", "db"}, nil},
+		{`".", "${get_env("INCLUDE_HIDDEN", "yes")}"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), nil, InvalidSubdirsParams("")},
+		{`"${not_a_helper()}"`, terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath), nil, UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.params, func(t *testing.T) {
+			actualValue, actualErr := subdirs(testCase.params, nil, testCase.terragruntOptions)
+			if testCase.expectedErr != nil {
+				if assert.Error(t, actualErr) {
+					assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr))
+				}
+			} else {
+				assert.Nil(t, actualErr)
+				assert.Equal(t, testCase.expectedValue, actualValue)
+			}
+		})
+	}
+}
+
+func TestColorFor(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"ENV": "prod"})
+
+	testCases := []struct {
+		params        string
+		expectedColor string
+		expectedErr   error
+	}{
+		{`"prod"`, "#6754af", nil},
+		{`"stage"`, "#c7ff6d", nil},
+		{`""`, "#e3b0c4", nil},
+		{``, "", InvalidStringParams("")},
+		{`"prod", "stage"`, "", InvalidStringParams("")},
+		{`"${get_env("ENV", "")}"`, "#6754af", nil},
+		{`"${not_a_helper()}"`, "", UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.params, func(t *testing.T) {
+			actualColor, actualErr := colorFor(testCase.params, nil, terragruntOptions)
+			if testCase.expectedErr != nil {
+				if assert.Error(t, actualErr) {
+					assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr))
+				}
+			} else {
+				assert.Nil(t, actualErr)
+				assert.Equal(t, testCase.expectedColor, actualColor)
+				assert.Regexp(t, `^#[0-9a-f]{6}$`, actualColor)
+
+				// Same input, same color
+				colorAgain, _ := colorFor(testCase.params, nil, terragruntOptions)
+				assert.Equal(t, actualColor, colorAgain)
+			}
+		})
+	}
+
+	// A call may also be part of the string
+	actualColor, err := colorFor(`"${get_env("ENV", "")}-db"`, nil, terragruntOptions)
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	expectedColor, _ := colorFor(`"prod-db"`, nil, terragruntOptions)
+	assert.Equal(t, expectedColor, actualColor)
+}
+
+func TestRangeList(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"COUNT": "3"})
+
+	testCases := []struct {
+		params      string
+		expected    []int
+		expectedErr error
+	}{
+		{`"3"`, []int{0, 1, 2}, nil},
+		{`"0"`, []int{}, nil},
+		{`"1", "4"`, []int{1, 2, 3}, nil},
+		{`"3", "0"`, []int{3, 2, 1}, nil},
+		{`"-2", "2"`, []int{-2, -1, 0, 1}, nil},
+		{`"2", "2"`, []int{}, nil},
+		{`"0", "10", "5"`, []int{0, 5}, nil},
+		{`"0", "9", "3"`, []int{0, 3, 6}, nil},
+		{`"10", "0", "-4"`, []int{10, 6, 2}, nil},
+		{`"-3"`, []int{0, -1, -2}, nil},
+		{``, nil, InvalidRangeListParams("")},
+		{`"a"`, nil, InvalidRangeListParams("")},
+		{`"1.5"`, nil, InvalidRangeListParams("")},
+		{`"1", "2", "3", "4"`, nil, InvalidRangeListParams("")},
+		{`"0", "10", "0"`, nil, InvalidRangeListStep{}},
+		{`"0", "10", "-1"`, nil, InvalidRangeListStep{}},
+		{`"10", "0", "1"`, nil, InvalidRangeListStep{}},
+		{`"${get_env("COUNT", "")}"`, []int{0, 1, 2}, nil},
+		{`"1", "${get_env("COUNT", "")}"`, []int{1, 2}, nil},
+		{`"${get_env("NOT_SET", "")}"`, nil, InvalidRangeListParams("")},
+		{`"${not_a_helper()}"`, nil, UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := rangeList(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestParseQuotedParams(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		params      string
+		expected    []string
+		expectedErr error
+	}{
+		{``, []string{}, nil},
+		{`  `, []string{}, nil},
+		{`"a"`, []string{"a"}, nil},
+		{`"a", "b" ,"c"`, []string{"a", "b", "c"}, nil},
+		{`"", " b "`, []string{"", " b "}, nil},
+		{`a`, nil, InvalidStringParams("")},
+		{`"a" "b"`, nil, InvalidStringParams("")},
+		{`"a", b`, nil, InvalidStringParams("")},
+		{`"${get_env("ENV", "dev")}", "b"`, []string{`${get_env("ENV", "dev")}`, "b"}, nil},
+		{`"${eq("${get_env("ENV", "")}", "prod")}", "x-${foo("a")}-y"`, []string{`${eq("${get_env("ENV", "")}", "prod")}`, `x-${foo("a")}-y`}, nil},
+		{`"${get_env("ENV")}" "b"`, nil, InvalidStringParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := parseQuotedParams(testCase.params)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestDnsLabel(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, DefaultTerragruntConfigPath, map[string]string{"APP": "Billing_API"})
+
+	testCases := []struct {
+		params      string
+		expected    string
+		expectedErr error
+	}{
+		{`"my-app"`, "my-app", nil},
+		{`"My_App (Staging)"`, "my-app-staging", nil},
+		{`"feature/JIRA-123.fix"`, "feature-jira-123-fix", nil},
+		{`"--leading-and-trailing--"`, "leading-and-trailing", nil},
+		{`"a--b"`, "a-b", nil},
+		{`"héllo wörld"`, "h-llo-w-rld", nil},
+		{`"123"`, "123", nil},
+		{`"` + strings.Repeat("a", 70) + `"`, strings.Repeat("a", 63), nil},
+		{`"` + strings.Repeat("a", 62) + `_b"`, strings.Repeat("a", 62), nil},
+		{`"${get_env("APP", "")}"`, "billing-api", nil},
+		{`"--"`, "", EmptyDnsLabel("")},
+		{`""`, "", EmptyDnsLabel("")},
+		{`"日本語"`, "", EmptyDnsLabel("")},
+		{``, "", InvalidDnsLabelParams("")},
+		{`"a", "b"`, "", InvalidDnsLabelParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := dnsLabel(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"NAME": "my-long-name", "MAX_LENGTH": "8"})
+
+	testCases := []struct {
+		params      string
+		expected    string
+		expectedErr error
+	}{
+		{`"short", "8"`, "short", nil},
+		{`"exactly8", "8"`, "exactly8", nil},
+		{`"my-long-name", "8"`, "my-long…", nil},
+		{`"my-long-name", "8", "..."`, "my-lo...", nil},
+		{`"my-long-name", "8", ""`, "my-long-", nil},
+		{`"héllo-wörld", "6"`, "héllo…", nil},
+		{`"日本語のテキスト", "4"`, "日本語…", nil},
+		{`"my-long-name", "2", "..."`, "my", nil},
+		{`"my-long-name", "0"`, "", nil},
+		{`"", "0"`, "", nil},
+		{`"my-long-name", "-1"`, "", NegativeTruncateLength(0)},
+		{`"my-long-name", "eight"`, "", InvalidTruncateParams("")},
+		{`"my-long-name"`, "", InvalidTruncateParams("")},
+		{``, "", InvalidTruncateParams("")},
+		{`"a", "1", "b", "c"`, "", InvalidTruncateParams("")},
+		{`"${get_env("NAME", "")}", "8"`, "my-long…", nil},
+		{`"${get_env("NAME", "")}-suffix", "${get_env("MAX_LENGTH", "")}", "..."`, "my-lo...", nil},
+		{`"${get_env("NAME", "")}", "${get_env("NOT_SET", "")}"`, "", InvalidTruncateParams("")},
+		{`"${not_a_helper()}", "8"`, "", UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := truncate(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestEnvFromPath(t *testing.T) {
+	t.Parallel()
+
+	include := &IncludeConfig{Path: "../../../" + DefaultTerragruntConfigPath}
+	opts := terragruntOptionsForTest(t, helpers.RootFolder+"prod/us-east-1/app/"+DefaultTerragruntConfigPath)
+
+	testCases := []struct {
+		params      string
+		include     *IncludeConfig
+		expected    string
+		expectedErr error
+	}{
+		{`"0"`, include, "prod", nil},
+		{`"1"`, include, "us-east-1", nil},
+		{`" 2 "`, include, "app", nil},
+		{`"3"`, include, "", PathSegmentOutOfRange{}},
+		{`"-1"`, include, "", PathSegmentOutOfRange{}},
+		{`"0"`, nil, "", PathSegmentOutOfRange{}},
+		{`"prod"`, include, "", InvalidEnvFromPathParams("")},
+		{``, include, "", InvalidEnvFromPathParams("")},
+		{`"0", "1"`, include, "", InvalidEnvFromPathParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := envFromPath(testCase.params, testCase.include, opts)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestWhenFlag(t *testing.T) {
+	t.Parallel()
+
+	flagOn := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"USE_NEW_VPC": "True"})
+	flagOff := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"USE_NEW_VPC": "0"})
+	flagUnset := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{})
+
+	testCases := []struct {
+		params            string
+		terragruntOptions *options.TerragruntOptions
+		expected          interface{}
+		expectedErr       error
+	}{
+		{`"USE_NEW_VPC", "new", "old"`, flagOn, "new", nil},
+		{`"USE_NEW_VPC", "new", "old"`, flagOff, "old", nil},
+		{`"USE_NEW_VPC", "new", "old"`, flagUnset, "old", nil},
+		{`"USE_NEW_VPC", "${get_tfvars_dir()}", "old"`, flagOn, "/root/child", nil},
+		{`"USE_NEW_VPC", "${get_terraform_commands_that_need_input()}", ""`, flagOn, TERRAFORM_COMMANDS_NEED_INPUT, nil},
+		// The value is only resolved if the flag is on, so a call that would fail is fine while the flag is off
+		{`"USE_NEW_VPC", "${not_a_helper()}", "old"`, flagOff, "old", nil},
+		{`"USE_NEW_VPC", "${not_a_helper()}", "old"`, flagOn, nil, UnknownHelperFunction("")},
+		{`"USE_NEW_VPC", "new", "${not_a_helper()}"`, flagOn, "new", nil},
+		{`"USE_NEW_VPC", "new"`, flagOn, nil, InvalidWhenFlagParams("")},
+		{`"", "new", "old"`, flagOn, nil, InvalidWhenFlagParams("")},
+		{`USE_NEW_VPC, "new", "old"`, flagOn, nil, InvalidWhenFlagParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := whenFlag(testCase.params, nil, testCase.terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	opts := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+
+	testCases := []struct {
+		params      string
+		expected    string
+		expectedErr error
+	}{
+		// The sha256 of the JSON encoded list of values, ["foo"]
+		{`"foo"`, "94edc6173bb7fdb250660a8d91b5ff93cba1bf90e618c7893dd78660cc909bda", nil},
+		{``, "", InvalidFingerprintParams("")},
+		{`foo`, "", InvalidFingerprintParams("")},
+		{`"${not_a_helper()}"`, "", UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := fingerprint(testCase.params, nil, opts)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestFingerprintChangesWithAnyValue(t *testing.T) {
+	t.Parallel()
+
+	opts := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+
+	fingerprintOf := func(params string) string {
+		actual, err := fingerprint(params, nil, opts)
+		if err != nil {
+			t.Fatalf("For params %s, unexpected error: %v", params, err)
+		}
+		return actual
+	}
+
+	base := fingerprintOf(`"vpc", "us-east-1", "10.0.0.0/16"`)
+	assert.Regexp(t, `^[0-9a-f]{64}$`, base)
+	assert.Equal(t, base, fingerprintOf(`"vpc",  "us-east-1",   "10.0.0.0/16"`))
+
+	for _, params := range []string{
+		`"vpc", "us-east-2", "10.0.0.0/16"`,
+		`"us-east-1", "vpc", "10.0.0.0/16"`,
+		`"vpc", "us-east-1"`,
+		`"vpc", "us-east-1", "10.0.0.0/16", ""`,
+		`"vpc,us-east-1,10.0.0.0/16"`,
+	} {
+		assert.NotEqual(t, base, fingerprintOf(params), "For params %s", params)
+	}
+
+	// Calls to helper functions are resolved before fingerprinting, so they fingerprint the same as their result
+	assert.Equal(t, fingerprintOf(`"/root/child"`), fingerprintOf(`"${get_tfvars_dir()}"`))
+	assert.NotEqual(t, fingerprintOf(`"apply"`), fingerprintOf(`"${get_terraform_commands_that_need_input()}"`))
+}
+
+func TestFingerprintValuesIsCanonical(t *testing.T) {
+	t.Parallel()
+
+	mapInOneOrder := map[string]interface{}{}
+	mapInOneOrder["region"] = "us-east-1"
+	mapInOneOrder["name"] = "vpc"
+	mapInOneOrder["tags"] = map[string]string{"team": "platform", "env": "prod"}
+
+	mapInAnotherOrder := map[string]interface{}{}
+	mapInAnotherOrder["tags"] = map[string]string{"env": "prod", "team": "platform"}
+	mapInAnotherOrder["name"] = "vpc"
+	mapInAnotherOrder["region"] = "us-east-1"
+
+	first, err := fingerprintValues([]interface{}{mapInOneOrder, []string{"a", "b"}})
+	assert.Nil(t, err)
+	second, err := fingerprintValues([]interface{}{mapInAnotherOrder, []string{"a", "b"}})
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	mapInAnotherOrder["tags"] = map[string]string{"env": "stage", "team": "platform"}
+	changedValue, err := fingerprintValues([]interface{}{mapInAnotherOrder, []string{"a", "b"}})
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, changedValue)
+
+	reorderedList, err := fingerprintValues([]interface{}{mapInOneOrder, []string{"b", "a"}})
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, reorderedList)
+}
+
+func TestHashBucket(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"SERVICE": "app-1", "BUCKETS": "10"})
+
+	testCases := []struct {
+		params         string
+		expectedBucket int
+		expectedErr    error
+	}{
+		{`"app-1", "10"`, 5, nil},
+		{`"app-2", "10"`, 0, nil},
+		{`"app-1", "100"`, 55, nil},
+		{`"prod", " 10 "`, 4, nil},
+		{`"", "10"`, 2, nil},
+		{`"app-1", "1"`, 0, nil},
+		{`"app-1", "0"`, 0, InvalidHashBucketCount(0)},
+		{`"app-1", "-3"`, 0, InvalidHashBucketCount(0)},
+		{`"app-1", "ten"`, 0, InvalidHashBucketParams("")},
+		{`"app-1"`, 0, InvalidHashBucketParams("")},
+		{`"app-1", "10", "20"`, 0, InvalidHashBucketParams("")},
+		{``, 0, InvalidHashBucketParams("")},
+		{`"${get_env("SERVICE", "")}", "10"`, 5, nil},
+		{`"app-${get_env("NOT_SET", "2")}", "${get_env("BUCKETS", "")}"`, 0, nil},
+		{`"app-1", "${get_env("NOT_SET", "")}"`, 0, InvalidHashBucketParams("")},
+		{`"${not_a_helper()}", "10"`, 0, UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actualBucket, actualErr := hashBucket(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expectedBucket, actualBucket, "For params %s", testCase.params)
+		}
+	}
+
+	// Each module is hashed by its own path, rather than by the text of the call, which is the same in every module
+	include := &IncludeConfig{Path: "../../" + DefaultTerragruntConfigPath}
+	for _, modulePath := range []string{"prod/app", "prod/db", "stage/app"} {
+		moduleOptions := terragruntOptionsForTest(t, helpers.RootFolder+modulePath+"/"+DefaultTerragruntConfigPath)
+
+		actualBucket, err := hashBucket(`"${path_relative_to_include()}", "4"`, include, moduleOptions)
+		assert.Nil(t, err, "For module %s, unexpected error: %v", modulePath, err)
+
+		expectedBucket, _ := hashBucket(fmt.Sprintf(`"%s", "4"`, modulePath), nil, moduleOptions)
+		assert.Equal(t, expectedBucket, actualBucket, "For module %s", modulePath)
+	}
+}
+
+func TestSeededInt(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"SERVICE": "payments-api"})
+
+	testCases := []struct {
+		params      string
+		expected    int
+		expectedErr error
+	}{
+		{`"payments-api", "0", "59"`, 24, nil},
+		{`"orders-api", "0", "59"`, 41, nil},
+		{`"envs/prod/vpc", "0", "59"`, 31, nil},
+		{`"", "0", "59"`, 52, nil},
+		{`"payments-api", "1", "10"`, 5, nil},
+		{`"payments-api", "-5", "5"`, 1, nil},
+		{`"payments-api", "7", "7"`, 7, nil},
+		{` "payments-api" , " 0 " , "+59" `, 24, nil},
+		{`"app-1", "0", "9"`, 5, nil},
+		{`"${get_env("SERVICE", "")}", "0", "59"`, 24, nil},
+		{`"payments-api", "0", "${get_env("MAX_MINUTE", "59")}"`, 24, nil},
+		{`"payments-api", "10", "1"`, 0, InvalidSeededIntRange{}},
+		{`"payments-api", "0", "5.5"`, 0, NotAWholeNumber{}},
+		{`"payments-api", "zero", "59"`, 0, NotAWholeNumber{}},
+		{`"payments-api", "0", ""`, 0, NotAWholeNumber{}},
+		{`"payments-api", "0"`, 0, InvalidSeededIntParams("")},
+		{``, 0, InvalidSeededIntParams("")},
+		{`"${not_a_helper()}", "0", "59"`, 0, UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := seededInt(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestSeededIntIsInRange(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+
+	for _, bounds := range [][]int{{0, 0}, {0, 1}, {-3, 3}, {0, 59}, {1000, 1999}} {
+		for i := 0; i < 50; i++ {
+			params := fmt.Sprintf(`"key-%d", "%d", "%d"`, i, bounds[0], bounds[1])
+			actual, err := seededInt(params, nil, terragruntOptions)
+			assert.Nil(t, err, "For params %s, unexpected error: %v", params, err)
+			assert.True(t, actual >= bounds[0] && actual <= bounds[1], "For params %s, %d is out of range", params, actual)
+
+			actualAgain, _ := seededInt(params, nil, terragruntOptions)
+			assert.Equal(t, actual, actualAgain, "For params %s", params)
+		}
+	}
+}
+
+func TestHashBucketIsInRange(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+
+	for _, buckets := range []int{1, 2, 3, 7, 100} {
+		for i := 0; i < 50; i++ {
+			params := fmt.Sprintf(`"key-%d", "%d"`, i, buckets)
+			bucket, err := hashBucket(params, nil, terragruntOptions)
+			assert.Nil(t, err, "For params %s, unexpected error: %v", params, err)
+			assert.True(t, bucket >= 0 && bucket < buckets, "For params %s, bucket %d is out of range", params, bucket)
+
+			bucketAgain, _ := hashBucket(params, nil, terragruntOptions)
+			assert.Equal(t, bucket, bucketAgain, "For params %s", params)
+		}
+	}
+}
+
+func TestEquals(t *testing.T) {
+	t.Parallel()
+
+	opts := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"ENV": "prod"})
+
+	testCases := []struct {
+		params      string
+		expected    bool
+		expectedErr error
+	}{
+		{`"a", "a"`, true, nil},
+		{`"a", "b"`, false, nil},
+		{`"", ""`, true, nil},
+		{`"a", "A"`, false, nil},
+		{`"${get_env("ENV", "")}", "prod"`, true, nil},
+		{`"${get_env("ENV", "")}", "stage"`, false, nil},
+		{`"${get_tfvars_dir()}", "/root/child"`, true, nil},
+		{`"${eq("a", "a")}", "true"`, true, nil},
+		{`"${hash_bucket("app-1", "10")}", "5"`, true, nil},
+		{`"${hash_bucket("app-1", "10")}", "4"`, false, nil},
+		{`"${get_terraform_commands_that_need_input()}", "${get_terraform_commands_that_need_input()}"`, true, nil},
+		{`"${get_terraform_commands_that_need_input()}", "apply"`, false, nil},
+		{`"a"`, false, InvalidEqualityParams("")},
+		{`"a", "b", "c"`, false, InvalidEqualityParams("")},
+		{`a, b`, false, InvalidEqualityParams("")},
+		{`"${not_a_helper()}", "a"`, false, UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := equals(testCase.params, nil, opts)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestCond(t *testing.T) {
+	t.Parallel()
+
+	prod := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"ENV": "prod"})
+	stage := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"ENV": "stage"})
+	dev := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"ENV": "dev"})
+
+	nestedConds := `"${eq("${get_env("ENV", "")}", "prod")}", "m5.large", "${cond("${eq("${get_env("ENV", "")}", "stage")}", "t3.medium", "t3.small")}"`
+
+	testCases := []struct {
+		params            string
+		terragruntOptions *options.TerragruntOptions
+		expected          interface{}
+		expectedErr       error
+	}{
+		{`"true", "then", "else"`, prod, "then", nil},
+		{`"false", "then", "else"`, prod, "else", nil},
+		{` "TRUE" , "then", "else"`, prod, "then", nil},
+		{`"${eq("${get_env("ENV", "")}", "prod")}", "m5.large", "t3.small"`, prod, "m5.large", nil},
+		{`"${eq("${get_env("ENV", "")}", "prod")}", "m5.large", "t3.small"`, stage, "t3.small", nil},
+		{`"${ne("${get_env("ENV", "")}", "prod")}", "t3.small", "m5.large"`, prod, "m5.large", nil},
+		{nestedConds, prod, "m5.large", nil},
+		{nestedConds, stage, "t3.medium", nil},
+		{nestedConds, dev, "t3.small", nil},
+		{`"true", "${get_terraform_commands_that_need_input()}", "apply"`, prod, TERRAFORM_COMMANDS_NEED_INPUT, nil},
+		{`"true", "${get_tfvars_dir()}", "none"`, prod, "/root/child", nil},
+		{`"${truthy("${get_env("ENV", "")}")}", "then", "else"`, prod, "then", nil},
+		{`"${truthy("${get_env("NOT_SET", "")}")}", "then", "else"`, prod, "else", nil},
+		// Only the branch that is returned is resolved, so the other one may contain calls that would fail
+		{`"true", "then", "${not_a_helper()}"`, prod, "then", nil},
+		{`"false", "${not_a_helper()}", "else"`, prod, "else", nil},
+		{`"true", "${not_a_helper()}", "else"`, prod, nil, UnknownHelperFunction("")},
+		{`"yes", "then", "else"`, prod, nil, InvalidCondCondition("")},
+		{`"${get_tfvars_dir()}", "then", "else"`, prod, nil, InvalidCondCondition("")},
+		{`"true", "then"`, prod, nil, InvalidCondParams("")},
+		{`true, "then", "else"`, prod, nil, InvalidCondParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := cond(testCase.params, nil, testCase.terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestNestedCallsInHelperFunctionsThatDontResolveThem(t *testing.T) {
+	t.Parallel()
+
+	opts := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"ENV": "prod"})
+
+	testCases := []string{
+		`foo = "${get_env("NOT_SET", "${get_tfvars_dir()}")}"`,
+		`foo = "prefix-${get_env("NOT_SET", "${get_tfvars_dir()}")}"`,
+		`foo = "${string("${get_env("NOT_SET", "${get_tfvars_dir()}")}")}"`,
+	}
+
+	for _, testCase := range testCases {
+		_, err := ResolveTerragruntConfigString(testCase, nil, opts)
+		if assert.Error(t, err, "For string %s", testCase) {
+			actualErr, isNotSupported := errors.Unwrap(err).(NestedHelperFunctionCallNotSupported)
+			if assert.True(t, isNotSupported, "For string %s, unexpected error: %v", testCase, err) {
+				assert.Equal(t, "get_env", actualErr.Function, "For string %s", testCase)
+				assert.Equal(t, `${get_tfvars_dir()}`, actualErr.Call, "For string %s", testCase)
+			}
+		}
+	}
+}
+
+func TestTruthy(t *testing.T) {
+	t.Parallel()
+
+	opts := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"DEBUG": "Yes", "DRY_RUN": "0"})
+
+	testCases := []struct {
+		params      string
+		expected    bool
+		expectedErr error
+	}{
+		{`"1"`, true, nil},
+		{`"true"`, true, nil},
+		{`"TRUE"`, true, nil},
+		{`"yes"`, true, nil},
+		{`" On "`, true, nil},
+		{`"anything"`, true, nil},
+		{`""`, false, nil},
+		{`"  "`, false, nil},
+		{`"0"`, false, nil},
+		{`"false"`, false, nil},
+		{`"False"`, false, nil},
+		{`"no"`, false, nil},
+		{`"OFF"`, false, nil},
+		{`"${get_env("DEBUG", "")}"`, true, nil},
+		{`"${get_env("DRY_RUN", "")}"`, false, nil},
+		{`"${get_env("NOT_SET", "")}"`, false, nil},
+		{`"${eq("a", "a")}"`, true, nil},
+		{`"${ne("a", "a")}"`, false, nil},
+		{`"${hash_bucket("app-1", "10")}"`, true, nil},
+		{`"${get_terraform_commands_that_need_input()}"`, true, nil},
+		{`"${collect_parent_files("*.does-not-exist")}"`, false, nil},
+		{`"${not_a_helper()}"`, false, UnknownHelperFunction("")},
+		{``, false, InvalidTruthyParams("")},
+		{`"a", "b"`, false, InvalidTruthyParams("")},
+		{`true`, false, InvalidTruthyParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := truthy(testCase.params, nil, opts)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestIsTruthy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    interface{}
+		expected bool
+	}{
+		{nil, false},
+		{true, true},
+		{false, false},
+		{0, false},
+		{5, true},
+		{0.0, false},
+		{0.5, true},
+		{[]string{}, false},
+		{[]string{"a"}, true},
+		{map[string]interface{}{}, false},
+		{map[string]interface{}{"a": "b"}, true},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, isTruthy(testCase.value), "For value %v", testCase.value)
+	}
+}
+
+func TestStringByLength(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+
+	testCases := []struct {
+		functionName string
+		params       string
+		expected     string
+		expectedErr  error
+	}{
+		{"longest", `"${get_terraform_commands_that_need_vars()}"`, "validate", nil},
+		{"shortest", `"${get_terraform_commands_that_need_vars()}"`, "plan", nil},
+		{"longest", `"${get_terraform_commands_that_need_locking()}"`, "destroy", nil},
+		{"shortest", `"${get_terraform_commands_that_need_locking()}"`, "init", nil},
+		{"longest", `"${cond("true", "${get_terraform_commands_that_need_input()}", "none")}"`, "refresh", nil},
+		{"longest", `"${collect_parent_files("*.does-not-exist")}"`, "", EmptyList("")},
+		{"shortest", `"${range_list("3")}"`, "", NotAListOfStrings{}},
+		{"longest", `"${makemap("a", "b")}"`, "", NotAList{}},
+		{"longest", `"${get_tfvars_dir()}"`, "", NotAList{}},
+		{"shortest", `"a, bb, ccc"`, "", NotAList{}},
+		{"longest", `"${not_a_helper()}"`, "", UnknownHelperFunction("")},
+		{"longest", ``, "", InvalidStringListParams{}},
+		{"shortest", `"${get_terraform_commands_that_need_vars()}", "${get_terraform_commands_that_need_input()}"`, "", InvalidStringListParams{}},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := stringByLength(testCase.functionName, testCase.params, nil, terragruntOptions, testCase.functionName == "longest")
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For %s(%s)", testCase.functionName, testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For %s(%s)", testCase.functionName, testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For %s(%s), unexpected error: %v", testCase.functionName, testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For %s(%s)", testCase.functionName, testCase.params)
+		}
+	}
+}
+
+func TestQuoteJoin(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath)
+
+	testCases := []struct {
+		params      string
+		expected    string
+		expectedErr error
+	}{
+		{`"${get_terraform_commands_that_need_input()}"`, `\"apply\", \"import\", \"init\", \"plan\", \"refresh\"`, nil},
+		{`"${subdirs(".")}"`, `\"app\", \"db\"`, nil},
+		{`"${cond("true", "${get_terraform_commands_that_need_input()}", "none")}"`, `\"apply\", \"import\", \"init\", \"plan\", \"refresh\"`, nil},
+		{`"${collect_parent_files("*.does-not-exist")}"`, ``, nil},
+		{`"${range_list("3")}"`, "", NotAListOfStrings{}},
+		{`"${makemap("a", "b")}"`, "", NotAList{}},
+		{`"${get_tfvars_dir()}"`, "", NotAList{}},
+		{`"a, b"`, "", NotAList{}},
+		{`"${not_a_helper()}"`, "", UnknownHelperFunction("")},
+		{``, "", InvalidStringListParams{}},
+		{`"${get_terraform_commands_that_need_vars()}", "${get_terraform_commands_that_need_input()}"`, "", InvalidStringListParams{}},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := quoteJoin(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestConcat(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath)
+
+	testCases := []struct {
+		params      string
+		expected    interface{}
+		expectedErr error
+	}{
+		{`"${subdirs(".")}"`, []string{"app", "db"}, nil},
+		{`"${subdirs(".")}", "${get_terraform_commands_that_need_parallelism()}"`, []string{"app", "db", "apply", "destroy", "plan", "refresh"}, nil},
+		{`"${subdirs(".")}", "${subdirs(".")}"`, []string{"app", "db", "app", "db"}, nil},
+		{`"${range_list("2")}", "${range_list("5", "7")}"`, []int{0, 1, 5, 6}, nil},
+		{`"${range_list("2")}", "${subdirs(".")}"`, []string{"0", "1", "app", "db"}, nil},
+		{`"${collect_parent_files("*.does-not-exist")}", "${subdirs(".")}"`, []string{"app", "db"}, nil},
+		{`"${collect_parent_files("*.does-not-exist")}"`, []string{}, nil},
+		{`"${concat("${subdirs(".")}", "${range_list("1")}")}", "${subdirs(".")}"`, []string{"app", "db", "0", "app", "db"}, nil},
+		{`"${subdirs(".")}", "${makemap("a", "b")}"`, nil, NotAList{}},
+		{`"${subdirs(".")}", "a, b"`, nil, NotAList{}},
+		{`"${map_to_entries("${makemap("a", "b")}")}"`, nil, NotAListOfStrings{}},
+		{`"${not_a_helper()}"`, nil, UnknownHelperFunction("")},
+		{``, nil, InvalidConcatParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := concat(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestElement(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath, map[string]string{"INDEX": "3"})
+
+	testCases := []struct {
+		params      string
+		expected    interface{}
+		expectedErr error
+	}{
+		{`"${subdirs(".")}", "0"`, "app", nil},
+		{`"${subdirs(".")}", "1"`, "db", nil},
+		{`"${subdirs(".")}", "2"`, "app", nil},
+		{`"${subdirs(".")}", "${get_env("INDEX", "0")}"`, "db", nil},
+		{`"${range_list("10", "13")}", "4"`, 11, nil},
+		{`"${concat("${subdirs(".")}", "${get_terraform_commands_that_need_parallelism()}")}", "4"`, "plan", nil},
+		{`"${collect_parent_files("*.does-not-exist")}", "0"`, "", ElementIndexOutOfRange{}},
+		{`"${subdirs(".")}", "-1"`, "", ElementIndexOutOfRange{}},
+		{`"${subdirs(".")}", "1.5"`, "", NotAWholeNumber{}},
+		{`"${subdirs(".")}", "first"`, "", NotAWholeNumber{}},
+		{`"${makemap("a", "b")}", "0"`, "", NotAList{}},
+		{`"a, b", "0"`, "", NotAList{}},
+		{`"${not_a_helper()}", "0"`, "", UnknownHelperFunction("")},
+		{`"${subdirs(".")}"`, "", InvalidElementParams("")},
+		{``, "", InvalidElementParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := element(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestAssertUnique(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath)
+
+	testCases := []struct {
+		params      string
+		expected    interface{}
+		expectedErr error
+	}{
+		{`"${get_terraform_commands_that_need_vars()}", "Duplicate command"`, TERRAFORM_COMMANDS_NEED_VARS, nil},
+		{`"${range_list("3")}", "Duplicate number"`, []int{0, 1, 2}, nil},
+		{`"${subdirs(".")}", "Duplicate folder"`, []string{"app", "db"}, nil},
+		{`"${collect_parent_files("*.does-not-exist")}", "Duplicate file"`, []string{}, nil},
+		{`"${makemap("a", "b")}", "Duplicate key"`, "", NotAList{}},
+		{`"${get_tfvars_dir()}", "Duplicate folder"`, "", NotAList{}},
+		{`"a, b, a", "Duplicate value"`, "", NotAList{}},
+		{`"${not_a_helper()}", "Duplicate value"`, "", UnknownHelperFunction("")},
+		{`"${get_terraform_commands_that_need_vars()}"`, "", InvalidAssertUniqueParams("")},
+		{``, "", InvalidAssertUniqueParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := assertUnique(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestAssertOneOf(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath, map[string]string{"TEST_ENV_TERRAGRUNT_COMPONENT": "db"})
+
+	testCases := []struct {
+		params      string
+		expected    interface{}
+		expectedErr error
+	}{
+		{`"db", "${subdirs(".")}", "Unknown component"`, "db", nil},
+		{`"${get_env("TEST_ENV_TERRAGRUNT_COMPONENT", "app")}", "${subdirs(".")}", "Unknown component"`, "db", nil},
+		{`"${get_env("TEST_ENV_TERRAGRUNT_MISSING", "app")}", "${subdirs(".")}", "Unknown component"`, "app", nil},
+		{`"plan", "${get_terraform_commands_that_need_vars()}", "Unknown command"`, "plan", nil},
+		{`"2", "${range_list("3")}", "Unknown index"`, "2", nil},
+		{`"${hash_bucket("key", "1")}", "${range_list("3")}", "Unknown index"`, 0, nil},
+		{`"${eq("a", "a")}", "${subdirs(".")}", "Unknown component"`, nil, ValueNotAllowed{}},
+		{`"cache", "${subdirs(".")}", "Unknown component"`, nil, ValueNotAllowed{}},
+		{`"Db", "${subdirs(".")}", "Unknown component"`, nil, ValueNotAllowed{}},
+		{`"3", "${range_list("3")}", "Unknown index"`, nil, ValueNotAllowed{}},
+		{`"db", "${collect_parent_files("*.does-not-exist")}", "Unknown component"`, nil, ValueNotAllowed{}},
+		{`"db", "app, db", "Unknown component"`, nil, NotAList{}},
+		{`"db", "${makemap("db", "app")}", "Unknown component"`, nil, NotAList{}},
+		{`"${not_a_helper()}", "${subdirs(".")}", "Unknown component"`, nil, UnknownHelperFunction("")},
+		{`"db", "${subdirs(".")}"`, nil, InvalidAssertOneOfParams("")},
+		{``, nil, InvalidAssertOneOfParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := assertOneOf(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestValueNotAllowedMessage(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "../test/fixture-subdirs/"+DefaultTerragruntConfigPath)
+
+	_, err := assertOneOf(`"cache", "${subdirs(".")}", "Unknown component"`, nil, terragruntOptions)
+	if assert.Error(t, err) {
+		assert.Equal(t, "Unknown component: 'cache' is not one of [app db]", errors.Unwrap(err).Error())
+	}
+}
+
+func TestFindDuplicateElement(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		list              interface{}
+		expectedDuplicate interface{}
+		expectedFound     bool
+	}{
+		{[]string{}, nil, false},
+		{[]string{"a", "b", "c"}, nil, false},
+		{[]string{"a", "b", "a", "b"}, "a", true},
+		{[]string{"a", "A"}, nil, false},
+		{[]int{1, 2, 3, 2}, 2, true},
+		{[]int{1, 2, 3}, nil, false},
+		{[]bool{true, false, true}, true, true},
+		{[]interface{}{"1", 1}, nil, false},
+	}
+
+	for _, testCase := range testCases {
+		duplicate, found := findDuplicateElement(reflect.ValueOf(testCase.list))
+		assert.Equal(t, testCase.expectedFound, found, "For list %v", testCase.list)
+		assert.Equal(t, testCase.expectedDuplicate, duplicate, "For list %v", testCase.list)
+	}
+}
+
+func TestDuplicateListElementMessage(t *testing.T) {
+	t.Parallel()
+
+	err := DuplicateListElement{Message: "Each environment must have its own folder", Value: "prod"}
+	assert.Equal(t, "Each environment must have its own folder: 'prod' appears more than once", err.Error())
+}
+
+func TestSelectStringByLength(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		list             []string
+		expectedLongest  string
+		expectedShortest string
+	}{
+		{[]string{"a"}, "a", "a"},
+		{[]string{""}, "", ""},
+		{[]string{"bb", "a", "ccc"}, "ccc", "a"},
+		{[]string{"ab", "cd", "e", "f"}, "ab", "e"},
+		// Lengths are counted in runes, not bytes: "ñññ" is 3 runes, but 6 bytes
+		{[]string{"ñññ", "abcd", "xy"}, "abcd", "xy"},
+		{[]string{"日本", "abc"}, "abc", "日本"},
+		{[]string{"", "a", ""}, "a", ""},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expectedLongest, selectStringByLength(testCase.list, true), "For list %v", testCase.list)
+		assert.Equal(t, testCase.expectedShortest, selectStringByLength(testCase.list, false), "For list %v", testCase.list)
+	}
+}
+
+func TestStringify(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"ZIP": "02134"})
+
+	testCases := []struct {
+		params      string
+		expected    string
+		expectedErr error
+	}{
+		{`"02134"`, "02134", nil},
+		{`"1e3"`, "1e3", nil},
+		{`""`, "", nil},
+		{`"${get_env("ZIP", "")}"`, "02134", nil},
+		{`"${hash_bucket("app-1", "10")}"`, "5", nil},
+		{`"${eq("a", "a")}"`, "true", nil},
+		{`"${range_list("3")}"`, "[0 1 2]", nil},
+		{`"${cond("true", "${hash_bucket("app-1", "10")}", "none")}"`, "5", nil},
+		{`"${not_a_helper()}"`, "", UnknownHelperFunction("")},
+		{``, "", InvalidStringHelperParams("")},
+		{`"a", "b"`, "", InvalidStringHelperParams("")},
+		{`02134`, "", InvalidStringHelperParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := stringify(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestAllowEmpty(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"ZIP": "02134"})
+
+	testCases := []struct {
+		params      string
+		expected    interface{}
+		expectedErr error
+	}{
+		{`"02134"`, "02134", nil},
+		{`""`, "", nil},
+		{`"${get_env("ZIP", "")}"`, "02134", nil},
+		{`"${get_env("BLANK", "")}"`, "", nil},
+		{`"${range_list("0")}"`, []int{}, nil},
+		{`"${not_a_helper()}"`, nil, UnknownHelperFunction("")},
+		{``, nil, InvalidAllowEmptyParams("")},
+		{`"a", "b"`, nil, InvalidAllowEmptyParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := allowEmpty(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestResolveConfigStringFailOnEmpty(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		str         string
+		failOnEmpty bool
+		expectedOut string
+		expectedErr error
+	}{
+		{`name = "${get_env("NAME", "")}"`, false, `name = ""`, nil},
+		{`name = "${get_env("NAME", "")}"`, true, "", EmptyHelperFunctionResult("")},
+		{`name = "${get_env("BLANK", "")}"`, true, "", EmptyHelperFunctionResult("")},
+		{`name = "app-${get_env("NAME", "")}"`, true, "", EmptyHelperFunctionResult("")},
+		{`names = ["${range_list("0")}"]`, true, "", EmptyHelperFunctionResult("")},
+		{`name = "${get_env("NAME", "app")}"`, true, `name = "app"`, nil},
+		{`name = "${eq("a", "b")}"`, true, `name = false`, nil},
+		{`name = "${allow_empty("${get_env("NAME", "")}")}"`, true, `name = ""`, nil},
+		{`name = "app${allow_empty("${get_env("NAME", "")}")}"`, true, `name = "app"`, nil},
+		{`names = ["${allow_empty("${range_list("0")}")}"]`, true, `names = []`, nil},
+		{`name = "${cond("${eq("${get_env("NAME", "")}", "")}", "yes", "no")}"`, true, `name = "yes"`, nil},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"BLANK": "  "})
+		terragruntOptions.FailOnEmpty = testCase.failOnEmpty
+
+		actualOut, actualErr := ResolveTerragruntConfigString(testCase.str, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For string '%s'", testCase.str) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For string '%s'", testCase.str)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For string '%s', unexpected error: %v", testCase.str, actualErr)
+			assert.Equal(t, testCase.expectedOut, actualOut, "For string '%s'", testCase.str)
+		}
+	}
+}
+
+func TestSquashWhitespace(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"OUTPUT": "  line one\n\tline two  \r\n\nline three\n"})
+
+	testCases := []struct {
+		params      string
+		expected    string
+		expectedErr error
+	}{
+		{`"a   b"`, "a b", nil},
+		{`"  padded  "`, "padded", nil},
+		{`"line one\nline two\r\n\tline three"`, "line one line two line three", nil},
+		{`"C:\\new"`, `C:\\new`, nil},
+		{`"tidy"`, "tidy", nil},
+		{`"   "`, "", nil},
+		{`""`, "", nil},
+		{`"${get_env("OUTPUT", "")}"`, "line one line two line three", nil},
+		{`"${not_a_helper()}"`, "", UnknownHelperFunction("")},
+		{``, "", InvalidSquashWhitespaceParams("")},
+		{`"a", "b"`, "", InvalidSquashWhitespaceParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := squashWhitespace(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestStripAnsi(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"OUTPUT": "\x1b[1;32mok\x1b[0m: \x1b[33m3\x1b[m changes"})
+
+	testCases := []struct {
+		params      string
+		expected    string
+		expectedErr error
+	}{
+		{`"plain"`, "plain", nil},
+		{`""`, "", nil},
+		{`"\u001b[31mred\u001b[0m"`, "red", nil},
+		{`"\u001B[1;4mbold\u001B[22m"`, "bold", nil},
+		{"\"\x1b[38;5;208morange\x1b[0m\"", "orange", nil},
+		{`"[31m not an escape"`, "[31m not an escape", nil},
+		{`"${get_env("OUTPUT", "")}"`, "ok: 3 changes", nil},
+		{`"${string("${get_env("OUTPUT", "")}")}"`, "ok: 3 changes", nil},
+		{`"${not_a_helper()}"`, "", UnknownHelperFunction("")},
+		{``, "", InvalidStripAnsiParams("")},
+		{`"a", "b"`, "", InvalidStripAnsiParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := stripAnsi(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestIsFlagOn(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    string
+		expected bool
+	}{
+		{"1", true},
+		{"true", true},
+		{"TRUE", true},
+		{" yes ", true},
+		{"on", true},
+		{"", false},
+		{"0", false},
+		{"false", false},
+		{"off", false},
+		{"enabled", false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, isFlagOn(testCase.value), "For value '%s'", testCase.value)
+	}
+}
+
+func TestCommandNeedsVars(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		terraformCommand string
+		expected         string
+	}{
+		{"apply", "true"},
+		{"plan", "true"},
+		{"destroy", "true"},
+		{"validate", "true"},
+		{"output", "false"},
+		{"state", "false"},
+		{"init", "false"},
+		{"apply-all", "false"},
+		{"", "false"},
+	}
+
+	for _, testCase := range testCases {
+		opts := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+		opts.TerraformCommand = testCase.terraformCommand
+
+		actual, err := ResolveTerragruntConfigString(`needs_vars = "${command_needs_vars()}"`, nil, opts)
+		assert.Nil(t, err, "For command %s, unexpected error: %v", testCase.terraformCommand, err)
+		assert.Equal(t, "needs_vars = "+testCase.expected, actual, "For command %s", testCase.terraformCommand)
+
+		actual, err = ResolveTerragruntConfigString(`region = "${cond("${command_needs_vars()}", "${get_env("REGION", "us-east-1")}", "none")}"`, nil, opts)
+		assert.Nil(t, err, "For command %s, unexpected error: %v", testCase.terraformCommand, err)
+		if testCase.expected == "true" {
+			assert.Equal(t, `region = "us-east-1"`, actual, "For command %s", testCase.terraformCommand)
+		} else {
+			assert.Equal(t, `region = "none"`, actual, "For command %s", testCase.terraformCommand)
+		}
+	}
+}
+
+func TestGetTerraformWorkspace(t *testing.T) {
+	t.Parallel()
+
+	workingDirWithWorkspace, err := ioutil.TempDir("", "terraform-workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workingDirWithWorkspace)
+
+	if err := os.MkdirAll(filepath.Join(workingDirWithWorkspace, ".terraform"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(workingDirWithWorkspace, ".terraform", "environment"), []byte("prod\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		workingDir string
+		env        map[string]string
+		expected   string
+	}{
+		{"/tmp/does-not-exist", map[string]string{}, DEFAULT_TERRAFORM_WORKSPACE},
+		{"/tmp/does-not-exist", map[string]string{"TF_WORKSPACE": "stage"}, "stage"},
+		{workingDirWithWorkspace, map[string]string{}, "prod"},
+		{workingDirWithWorkspace, map[string]string{"TF_WORKSPACE": "stage"}, "stage"},
+	}
+
+	for _, testCase := range testCases {
+		opts := terragruntOptionsForTestWithEnv(t, DefaultTerragruntConfigPath, testCase.env)
+		opts.WorkingDir = testCase.workingDir
+
+		actual, err := getTerraformWorkspace(opts)
+		assert.Nil(t, err, "For working dir %s and env %v, unexpected error: %v", testCase.workingDir, testCase.env, err)
+		assert.Equal(t, testCase.expected, actual, "For working dir %s and env %v", testCase.workingDir, testCase.env)
+	}
+}
+
+func TestDetectCloud(t *testing.T) {
+	t.Parallel()
+
+	homeDir, err := ioutil.TempDir("", "detect-cloud")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homeDir)
+
+	if err := os.MkdirAll(filepath.Join(homeDir, ".config", "gcloud"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(homeDir, ".config", "gcloud", "application_default_credentials.json"), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		env      map[string]string
+		expected string
+	}{
+		{map[string]string{}, UNKNOWN_CLOUD},
+		{map[string]string{"HOME": "/tmp/does-not-exist"}, UNKNOWN_CLOUD},
+		{map[string]string{"AWS_ACCESS_KEY_ID": "AKIA"}, "aws"},
+		{map[string]string{"AWS_PROFILE": ""}, UNKNOWN_CLOUD},
+		{map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": "/creds.json"}, "gcp"},
+		{map[string]string{"ARM_CLIENT_ID": "id"}, "azure"},
+		{map[string]string{"MSI_ENDPOINT": "http://localhost"}, "azure"},
+		{map[string]string{"ARM_CLIENT_ID": "id", "GOOGLE_CREDENTIALS": "{}", "AWS_PROFILE": "prod"}, "aws"},
+		{map[string]string{"ARM_CLIENT_ID": "id", "GOOGLE_CREDENTIALS": "{}"}, "gcp"},
+		{map[string]string{"HOME": homeDir}, "gcp"},
+		{map[string]string{"USERPROFILE": homeDir}, "gcp"},
+		{map[string]string{"HOME": homeDir, "ARM_USE_MSI": "true"}, "azure"},
+	}
+
+	for _, testCase := range testCases {
+		opts := terragruntOptionsForTestWithEnv(t, DefaultTerragruntConfigPath, testCase.env)
+
+		actual, err := ResolveTerragruntConfigString("${detect_cloud()}", nil, opts)
+		assert.Nil(t, err, "For env %v, unexpected error: %v", testCase.env, err)
+		assert.Equal(t, testCase.expected, actual, "For env %v", testCase.env)
+	}
+}
+
+func TestGetConfigMtime(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "get-config-mtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, DefaultTerragruntConfigPath)
+	if err := ioutil.WriteFile(configPath, []byte("terragrunt = {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	setMtime := func(mtime time.Time) {
+		if err := os.Chtimes(configPath, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setMtime(time.Date(2018, 3, 14, 15, 9, 26, 0, time.FixedZone("UTC+2", 2*60*60)))
+
+	testCases := []struct {
+		str      string
+		expected string
+	}{
+		{"${get_config_mtime()}", "2018-03-14T13:09:26Z"},
+		{`${get_config_mtime("")}`, "2018-03-14T13:09:26Z"},
+		{`${get_config_mtime("2006-01-02")}`, "2018-03-14"},
+		{`mtime-${get_config_mtime("20060102150405")}`, "mtime-20180314130926"},
+		{`${get_config_mtime("${get_env("MTIME_LAYOUT", "")}")}`, "2018-03"},
+		{`${get_config_mtime("${get_env("MTIME_LAYOUT_NOT_SET", "")}")}`, "2018-03-14T13:09:26Z"},
+		{`${get_config_mtime("${get_env("MTIME_LAYOUT", "")}-02")}`, "2018-03-02"},
+	}
+
+	opts := terragruntOptionsForTestWithEnv(t, configPath, map[string]string{"MTIME_LAYOUT": "2006-01"})
+	for _, testCase := range testCases {
+		actual, err := ResolveTerragruntConfigString(testCase.str, nil, opts)
+		assert.Nil(t, err, "For string '%s', unexpected error: %v", testCase.str, err)
+		assert.Equal(t, testCase.expected, actual, "For string '%s'", testCase.str)
+	}
+
+	// The modification time is read again on every call
+	setMtime(time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC))
+	actual, err := ResolveTerragruntConfigString("${get_config_mtime()}", nil, opts)
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	assert.Equal(t, "2019-01-02T03:04:05Z", actual)
+
+	_, err = ResolveTerragruntConfigString(`${get_config_mtime("2006", "01")}`, nil, opts)
+	assert.IsType(t, InvalidStringParams(""), errors.Unwrap(err))
+
+	_, err = ResolveTerragruntConfigString(`${get_config_mtime("${not_a_helper()}")}`, nil, opts)
+	assert.IsType(t, UnknownHelperFunction(""), errors.Unwrap(err))
+
+	_, err = ResolveTerragruntConfigString("${get_config_mtime()}", nil, terragruntOptionsForTest(t, ""))
+	assert.IsType(t, ConfigPathUnknown(""), errors.Unwrap(err))
+
+	_, err = ResolveTerragruntConfigString("${get_config_mtime()}", nil, terragruntOptionsForTest(t, filepath.Join(tmpDir, "does-not-exist", DefaultTerragruntConfigPath)))
+	assert.True(t, os.IsNotExist(errors.Unwrap(err)), "Expected a file not found error, but got: %v", err)
+}
+
+func TestGetGitDescribe(t *testing.T) {
+	t.Parallel()
+
+	repoDir, err := ioutil.TempDir("", "git-describe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repoDir)
+
+	runGit := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Terragrunt", "-c", "user.email=terragrunt@example.com"}, args...)...)
+		cmd.Dir = repoDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	runGit("init", "-q")
+	runGit("commit", "-q", "--allow-empty", "-m", "first")
+
+	opts := terragruntOptionsForTest(t, filepath.Join(repoDir, DefaultTerragruntConfigPath))
+
+	// With no tags, git describe falls back to the short SHA
+	actual, err := getGitDescribe(opts)
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{7,}$`), actual)
+
+	runGit("tag", "v0.1.0")
+	actual, err = getGitDescribe(opts)
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	assert.Equal(t, "v0.1.0", actual)
+
+	runGit("commit", "-q", "--allow-empty", "-m", "second")
+	actual, err = getGitDescribe(opts)
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	assert.Regexp(t, regexp.MustCompile(`^v0\.1\.0-1-g[0-9a-f]{7,}$`), actual)
+}
+
+func TestGetGitDescribeErrors(t *testing.T) {
+	t.Parallel()
+
+	notARepoDir, err := ioutil.TempDir("", "git-describe-not-a-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(notARepoDir)
+
+	testCases := []struct {
+		gitCommand  string
+		dir         string
+		expectedErr error
+	}{
+		{"git-command-that-does-not-exist", notARepoDir, GitNotAvailable{}},
+		{GIT_COMMAND, notARepoDir, GitDescribeFailed{}},
+	}
+
+	for _, testCase := range testCases {
+		_, err := gitDescribe(testCase.gitCommand, testCase.dir)
+		if assert.Error(t, err, "For git command %s", testCase.gitCommand) {
+			assert.IsType(t, testCase.expectedErr, errors.Unwrap(err), "For git command %s", testCase.gitCommand)
+		}
+	}
+}
+
+type mockStsClient struct {
+	account string
+	err     error
+	calls   int
+}
+
+func (client *mockStsClient) GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	client.calls++
+	if client.err != nil {
+		return nil, client.err
+	}
+	return &sts.GetCallerIdentityOutput{Account: aws.String(client.account)}, nil
+}
+
+func TestCachedAWSAccountID(t *testing.T) {
+	t.Parallel()
+
+	// Use IAM roles no other test uses, as the cache is shared by the whole process
+	iamRole := "arn:aws:iam::123456789012:role/test-cached-aws-account-id"
+	client := &mockStsClient{account: "123456789012"}
+	newStsClient := func() (aws_helper.StsClient, error) {
+		return client, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		actual, err := cachedAWSAccountID(iamRole, newStsClient)
+		assert.NoError(t, err)
+		assert.Equal(t, "123456789012", actual)
+	}
+	assert.Equal(t, 1, client.calls, "STS should only be called once")
+
+	otherClient := &mockStsClient{account: "210987654321"}
+	actual, err := cachedAWSAccountID(iamRole+"-other", func() (aws_helper.StsClient, error) {
+		return otherClient, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "210987654321", actual, "Each IAM role should have its own account id")
+}
+
+func TestCachedAWSAccountIDErrors(t *testing.T) {
+	t.Parallel()
+
+	noCredentials := awserr.New("NoCredentialProviders", "no valid providers in chain", goerrors.New("EnvAccessKeyNotFound: failed to find credentials in the environment"))
+
+	testCases := []struct {
+		iamRole         string
+		client          *mockStsClient
+		expectedMessage string
+	}{
+		{"test-cached-aws-account-id-errors-1", &mockStsClient{err: noCredentials}, "(NoCredentialProviders: no valid providers in chain)"},
+		{"test-cached-aws-account-id-errors-2", &mockStsClient{err: goerrors.New("connection refused")}, "(connection refused)"},
+		{"test-cached-aws-account-id-errors-3", &mockStsClient{account: ""}, "(The STS GetCallerIdentity call did not return an account id)"},
+	}
+
+	for _, testCase := range testCases {
+		client := testCase.client
+		_, err := cachedAWSAccountID(testCase.iamRole, func() (aws_helper.StsClient, error) {
+			return client, nil
+		})
+		if assert.Error(t, err, "For IAM role %s", testCase.iamRole) {
+			assert.IsType(t, AWSAccountIdNotFound{}, errors.Unwrap(err), "For IAM role %s", testCase.iamRole)
+			assert.Contains(t, err.Error(), testCase.expectedMessage, "For IAM role %s", testCase.iamRole)
+			assert.Contains(t, err.Error(), testCase.iamRole, "For IAM role %s", testCase.iamRole)
+			assert.NotContains(t, err.Error(), "EnvAccessKeyNotFound", "For IAM role %s", testCase.iamRole)
+		}
+
+		// Errors are not cached
+		_, err = cachedAWSAccountID(testCase.iamRole, func() (aws_helper.StsClient, error) {
+			return client, nil
+		})
+		assert.Error(t, err, "For IAM role %s", testCase.iamRole)
+		assert.Equal(t, 2, client.calls, "For IAM role %s", testCase.iamRole)
+	}
+}
+
+// Not parallel, as the AWS SDK reads the shared config file and the region from environment variables
+func TestCreateAWSSessionSharedConfig(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "terragrunt-aws-config-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	configFile := filepath.Join(tmpDir, "config")
+	require.NoError(t, ioutil.WriteFile(configFile, []byte("[default]\nregion = eu-west-3\n"), 0644))
+
+	env := map[string]string{
+		"AWS_CONFIG_FILE":     configFile,
+		"AWS_SDK_LOAD_CONFIG": "",
+		"AWS_REGION":          "",
+		"AWS_DEFAULT_REGION":  "",
+		"AWS_PROFILE":         "",
+	}
+	for name, value := range env {
+		oldValue, wasSet := os.LookupEnv(name)
+		if value == "" {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, value)
+		}
+		if wasSet {
+			defer os.Setenv(name, oldValue)
+		} else {
+			defer os.Unsetenv(name)
+		}
+	}
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+
+	// get_aws_account_id keeps the defaults of the AWS SDK, so it doesn't read the shared config file unless
+	// AWS_SDK_LOAD_CONFIG is set
+	sess, err := createAWSSession(AWS_ACCOUNT_ID_SESSION_OPTIONS, terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "", aws.StringValue(sess.Config.Region))
+
+	region, err := getAWSRegion(terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-3", region)
+}
+
+func TestHelperMetrics(t *testing.T) {
+	t.Parallel()
+
+	opts := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"ENV": "prod"})
+
+	config := `
+terragrunt = {
+  terraform {
+    extra_arguments "vars" {
+      commands = ["${get_terraform_commands_that_need_vars()}"]
+      arguments = ["-var", "env=${get_env("ENV", "dev")}", "-var", "dir=${get_tfvars_dir()}"]
+    }
+  }
+  prevent_destroy = "${eq("${get_env("ENV", "dev")}", "prod")}"
+}
+`
+
+	_, err := ResolveTerragruntConfigString(config, nil, opts)
+	require.NoError(t, err)
+
+	calls := map[string]int64{}
+	for _, metric := range opts.HelperMetrics.Snapshot() {
+		calls[metric.Name] = metric.Calls
+		assert.True(t, metric.Duration >= 0, "Unexpected duration %v for %s", metric.Duration, metric.Name)
+	}
+
+	// The get_env in the parameters of eq is counted too
+	expected := map[string]int64{
+		"get_terraform_commands_that_need_vars": 1,
+		"get_env":                               2,
+		"get_tfvars_dir":                        1,
+		"eq":                                    1,
+	}
+	assert.Equal(t, expected, calls)
+
+	// Calls to unknown helper functions are not counted
+	_, err = ResolveTerragruntConfigString(`"${not_a_helper()}"`, nil, opts)
+	assert.Error(t, err)
+	assert.Len(t, opts.HelperMetrics.Snapshot(), 4)
+}
+
+func TestRunCmd(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := ioutil.TempDir("", "run-cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workingDir)
+
+	opts := terragruntOptionsForTest(t, filepath.Join(workingDir, DefaultTerragruntConfigPath))
+
+	testCases := []struct {
+		parameters  string
+		expected    string
+		expectedErr error
+	}{
+		{`"echo", "hello"`, "hello", nil},
+		{`"sh", "-c", "printf 'a\nb\n\n'"`, "a\nb\n", nil},
+		{`"sh", "-c", "pwd"`, workingDir, nil},
+		{`"echo", "${get_tfvars_dir()}"`, workingDir, nil},
+		{`"sh", "-c", "echo oops 1>&2; exit 3"`, "", RunCmdFailed{}},
+		{`"command-that-does-not-exist"`, "", RunCmdFailed{}},
+		{``, "", InvalidRunCmdParams("")},
+		{`echo`, "", InvalidRunCmdParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := runCmd(testCase.parameters, nil, opts)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, err, "For parameters %s", testCase.parameters) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(err), "For parameters %s", testCase.parameters)
+			}
+		} else {
+			assert.Nil(t, err, "For parameters %s: unexpected error: %v", testCase.parameters, err)
+			assert.Equal(t, testCase.expected, actual, "For parameters %s", testCase.parameters)
+		}
+	}
+}
+
+func TestRunCmdExitCode(t *testing.T) {
+	t.Parallel()
+
+	opts := terragruntOptionsForTest(t, DefaultTerragruntConfigPath)
+
+	_, err := runCmd(`"sh", "-c", "exit 3"`, nil, opts)
+	if assert.Error(t, err) {
+		runCmdErr, isRunCmdFailed := errors.Unwrap(err).(RunCmdFailed)
+		if assert.True(t, isRunCmdFailed, "Unexpected error type: %v", err) {
+			assert.Equal(t, 3, runCmdErr.ExitCode)
+		}
+	}
+}
+
+func TestRunCmdRunsEachCommandOnce(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := ioutil.TempDir("", "run-cmd-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workingDir)
+
+	opts := terragruntOptionsForTest(t, filepath.Join(workingDir, DefaultTerragruntConfigPath))
+
+	config := `
+foo = "${run_cmd("sh", "-c", "echo run >> runs.txt; echo value")}"
+bar = "${run_cmd("sh", "-c", "echo run >> runs.txt; echo value")}"
+baz = "${string("${run_cmd("sh", "-c", "echo run >> runs.txt; echo value")}")}"
+`
+	expected := `
+foo = "value"
+bar = "value"
+baz = "value"
+`
+
+	actual, err := ResolveTerragruntConfigString(config, nil, opts)
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	assert.Equal(t, expected, actual)
+
+	runs, err := ioutil.ReadFile(filepath.Join(workingDir, "runs.txt"))
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	assert.Equal(t, "run\n", string(runs), "The command should only run once")
+}
+
+func TestRunCmdConcurrentCalls(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := ioutil.TempDir("", "run-cmd-concurrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workingDir)
+
+	opts := terragruntOptionsForTest(t, filepath.Join(workingDir, DefaultTerragruntConfigPath))
+
+	slowCmd := `"sh", "-c", "echo run >> runs.txt; sleep 1; echo slow"`
+	slowStarted := time.Now()
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			actual, err := runCmd(slowCmd, nil, opts)
+			assert.Nil(t, err, "Unexpected error: %v", err)
+			assert.Equal(t, "slow", actual)
+		}()
+	}
+
+	// Give the slow calls time to start, then check that they don't hold up a different command
+	time.Sleep(200 * time.Millisecond)
+	actual, err := runCmd(`"echo", "fast"`, nil, opts)
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	assert.Equal(t, "fast", actual)
+	assert.True(t, time.Since(slowStarted) < 900*time.Millisecond, "A different command should not wait for the slow one")
+
+	waitGroup.Wait()
+
+	runs, err := ioutil.ReadFile(filepath.Join(workingDir, "runs.txt"))
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	assert.Equal(t, "run\n", string(runs), "Concurrent calls of the same command should only run it once")
+}
+
+// A script that fails until the attempt given as its only argument, counting the attempts in a file in the working dir
+const FLAKY_SCRIPT = `#!/bin/sh
+attempt=$(cat attempts 2>/dev/null || echo 0)
+attempt=$((attempt + 1))
+echo $attempt > attempts
+if [ $attempt -lt $1 ]; then
+  echo "attempt $attempt failed" 1>&2
+  exit 1
+fi
+echo "succeeded on attempt $attempt"
+`
+
+func TestRetry(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		parameters       string
+		expected         interface{}
+		expectedErr      error
+		expectedAttempts string
+	}{
+		{`"${run_cmd("sh", "flaky.sh", "1")}", "3", "0"`, "succeeded on attempt 1", nil, "1"},
+		{`"${run_cmd("sh", "flaky.sh", "3")}", "3", "0"`, "succeeded on attempt 3", nil, "3"},
+		{`"${run_cmd("sh", "flaky.sh", "4")}", "3", "0"`, nil, RunCmdFailed{}, "3"},
+		{`"${run_cmd("sh", "flaky.sh", "2")}", "1", "0"`, nil, RunCmdFailed{}, "1"},
+		{`"${run_cmd("sh", "flaky.sh", "2")}", "${get_env("RETRY_ATTEMPTS", "2")}", "0.01"`, "succeeded on attempt 2", nil, "2"},
+		// Errors that retrying can't fix are returned right away
+		{`"${not_a_helper()}", "3", "0"`, nil, UnknownHelperFunction(""), ""},
+		{`"${get_terraform_commands_that_need_input()}", "3", "0"`, TERRAFORM_COMMANDS_NEED_INPUT, nil, ""},
+		{`"not a call", "3", "0"`, "not a call", nil, ""},
+		{`"${run_cmd("sh", "flaky.sh", "1")}", "3"`, nil, InvalidRetryParams(""), ""},
+		{`"${run_cmd("sh", "flaky.sh", "1")}", "0", "0"`, nil, InvalidRetryParams(""), ""},
+		{`"${run_cmd("sh", "flaky.sh", "1")}", "1.5", "0"`, nil, InvalidRetryParams(""), ""},
+		{`"${run_cmd("sh", "flaky.sh", "1")}", "three", "0"`, nil, InvalidRetryParams(""), ""},
+		{`"${run_cmd("sh", "flaky.sh", "1")}", "3", "-1"`, nil, InvalidRetryParams(""), ""},
+	}
+
+	for _, testCase := range testCases {
+		workingDir, err := ioutil.TempDir("", "retry")
+		require.NoError(t, err)
+		defer os.RemoveAll(workingDir)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(workingDir, "flaky.sh"), []byte(FLAKY_SCRIPT), 0755))
+
+		opts := terragruntOptionsForTest(t, filepath.Join(workingDir, DefaultTerragruntConfigPath))
+		opts.Writer = &bytes.Buffer{}
+		opts.ErrWriter = &bytes.Buffer{}
+
+		actual, err := retry(testCase.parameters, nil, opts)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, err, "For parameters %s", testCase.parameters) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(err), "For parameters %s", testCase.parameters)
+			}
+		} else {
+			assert.Nil(t, err, "For parameters %s: unexpected error: %v", testCase.parameters, err)
+			assert.Equal(t, testCase.expected, actual, "For parameters %s", testCase.parameters)
+		}
+
+		attempts, _ := ioutil.ReadFile(filepath.Join(workingDir, "attempts"))
+		assert.Equal(t, testCase.expectedAttempts, strings.TrimSpace(string(attempts)), "For parameters %s", testCase.parameters)
+	}
+}
+
+func TestRetryWaitsBetweenAttempts(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"cidr": "10.0.0.0/16"}`)
+	}))
+	defer server.Close()
+
+	logs := &bytes.Buffer{}
+	opts := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+	opts.Logger = util.CreateLoggerWithWriter(logs, "")
+
+	start := time.Now()
+	actual, err := retry(fmt.Sprintf(`"${http_get_json("%s", "cidr")}", "3", "0.05"`, server.URL), nil, opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, "10.0.0.0/16", actual)
+	assert.Equal(t, 3, requests)
+	assert.True(t, time.Since(start) >= 100*time.Millisecond, "Expected two delays of 50ms, but took %v", time.Since(start))
+	assert.Equal(t, 2, strings.Count(logs.String(), "retrying in 50ms"))
+}
+
+func TestIsRetryableHelperError(t *testing.T) {
+	t.Parallel()
+
+	opts := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+	opts.RetryableErrors = []string{"(?s).*quota exceeded.*"}
+
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{HttpGetFailed{Url: "https://example.com", Underlying: goerrors.New("dial tcp: connection refused")}, true},
+		{HttpGetUnexpectedStatus{Url: "https://example.com", StatusCode: 503}, true},
+		{HttpGetUnexpectedStatus{Url: "https://example.com", StatusCode: 429}, true},
+		{HttpGetUnexpectedStatus{Url: "https://example.com", StatusCode: 404}, false},
+		{HttpGetUnexpectedStatus{Url: "https://example.com", StatusCode: 5000}, false},
+		{RunCmdFailed{Command: "sh", Args: []string{"flaky.sh"}, ExitCode: 1, Underlying: goerrors.New("exit status 1")}, true},
+		{AWSAccountIdNotFound{Underlying: awserr.New("Throttling", "Rate exceeded", nil)}, true},
+		{AWSAccountIdNotFound{Underlying: awserr.New("AccessDenied", "Not authorized", nil)}, false},
+		{goerrors.New("Daily quota exceeded"), true},
+		{UnknownHelperFunction("not_a_helper"), false},
+		{InvalidHttpGetJsonParams(""), false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, isRetryableHelperError(errors.WithStackTrace(testCase.err), opts), "For error %v", testCase.err)
+	}
+}
+
+func TestResolveTerraformSource(t *testing.T) {
+	t.Parallel()
+
+	opts := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"TF_WORKSPACE": "stage"})
+
+	testCases := []struct {
+		source      string
+		expected    string
+		expectedErr error
+	}{
+		{"../modules//vpc", "../modules//vpc", nil},
+		{`${get_tfvars_dir()}/../modules//vpc-${get_terraform_workspace()}`, "/root/child/../modules//vpc-stage", nil},
+		{`../modules//vpc?ref=${get_env("MODULE_REF", "v1.4.0")}`, "../modules//vpc?ref=v1.4.0", nil},
+		{`../modules//vpc-${range_list("3")}`, "", HelperFunctionNotAllowedInSource("")},
+		{`${collect_parent_files("*.tfvars")}`, "", HelperFunctionNotAllowedInSource("")},
+		{`../modules//vpc-${not_a_helper()}`, "", UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := ResolveTerraformSource(testCase.source, nil, opts)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, err, "For source %s", testCase.source) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(err), "For source %s", testCase.source)
+			}
+		} else {
+			assert.Nil(t, err, "For source %s, unexpected error: %v", testCase.source, err)
+			assert.Equal(t, testCase.expected, actual, "For source %s", testCase.source)
+		}
+	}
+}
+
+func TestMakeMap(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTestWithEnv(t, "/root/child/"+DefaultTerragruntConfigPath, map[string]string{"ENV": "prod"})
+
+	testCases := []struct {
+		params      string
+		expected    map[string]string
+		expectedErr error
+	}{
+		{``, map[string]string{}, nil},
+		{`"Name", "vpc"`, map[string]string{"Name": "vpc"}, nil},
+		{`"Name", "vpc", "Env", ""`, map[string]string{"Name": "vpc", "Env": ""}, nil},
+		{`"Name"`, nil, OddNumberOfMakeMapParams(0)},
+		{`"Name", "vpc", "Env"`, nil, OddNumberOfMakeMapParams(0)},
+		{`"", "vpc"`, nil, EmptyMakeMapKey(0)},
+		{`"Name", "vpc", "Name", "app"`, nil, DuplicateMakeMapKey("")},
+		{`Name, "vpc"`, nil, InvalidMakeMapParams("")},
+		{`"Env", "${get_env("ENV", "")}", "Name", "vpc-${get_env("ENV", "")}"`, map[string]string{"Env": "prod", "Name": "vpc-prod"}, nil},
+		{`"${get_env("ENV", "")}", "vpc"`, map[string]string{"prod": "vpc"}, nil},
+		{`"${get_env("NOT_SET", "")}", "vpc"`, nil, EmptyMakeMapKey(0)},
+		{`"Name", "${not_a_helper()}"`, nil, UnknownHelperFunction("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := makeMap(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
 	}
 }
@@ -0,0 +1,326 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The version of the format used to store config snapshots on disk. Bump this whenever the format of ConfigSnapshot
+// changes in a backwards incompatible way; snapshots with a different version are ignored.
+const ConfigSnapshotVersion = 1
+
+// The value stored in a config snapshot in place of any setting that looks like it contains a secret
+const RedactedValue = util.RedactedValue
+
+// ConfigSnapshot is a record of a fully resolved Terragrunt configuration. It is stored in the module's cache dir after
+// each run so that the next run can tell the user what changed in the config in the meantime.
+type ConfigSnapshot struct {
+	// The version of the snapshot format
+	Version int `json:"version"`
+
+	// A hash of the full, unredacted config, so changes in secrets are detected even though their values aren't stored
+	Hash string `json:"hash"`
+
+	// The config, flattened into a map of setting names (e.g. remote_state.config.key) to their JSON encoded values,
+	// with secrets redacted
+	Config map[string]string `json:"config"`
+}
+
+// The possible kinds of changes between two config snapshots
+const (
+	ConfigSettingAdded   = "added"
+	ConfigSettingRemoved = "removed"
+	ConfigSettingChanged = "changed"
+)
+
+// ConfigChange represents a single setting that differs between two config snapshots
+type ConfigChange struct {
+	Key      string
+	Kind     string
+	OldValue string
+	NewValue string
+}
+
+func (change ConfigChange) String() string {
+	switch change.Kind {
+	case ConfigSettingAdded:
+		return fmt.Sprintf("+ %s = %s", change.Key, change.NewValue)
+	case ConfigSettingRemoved:
+		return fmt.Sprintf("- %s = %s", change.Key, change.OldValue)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", change.Key, change.OldValue, change.NewValue)
+	}
+}
+
+// Create a snapshot of the given fully resolved Terragrunt config. The values of settings whose name looks like it
+// refers to a secret or matches one of the given patterns (see TerragruntOptions.SensitiveVarPatterns), and of the -var
+// arguments that set such variables, are redacted.
+func NewConfigSnapshot(config *TerragruntConfig, sensitiveVarPatterns []*regexp.Regexp) (*ConfigSnapshot, error) {
+	flattened, err := flattenTerragruntConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hash the settings in sorted order so the hash doesn't depend on map iteration order
+	hash := sha256.New()
+	for _, key := range sortedKeys(flattened) {
+		fmt.Fprintf(hash, "%s=%s\n", key, flattened[key])
+	}
+
+	redacted := map[string]string{}
+	for key, value := range flattened {
+		switch {
+		case isSensitiveConfigKey(key, sensitiveVarPatterns):
+			redacted[key] = RedactedValue
+		case strings.HasSuffix(key, ".arguments"):
+			redacted[key] = redactSensitiveVarArgs(value, sensitiveVarPatterns)
+		default:
+			redacted[key] = value
+		}
+	}
+
+	return &ConfigSnapshot{
+		Version: ConfigSnapshotVersion,
+		Hash:    hex.EncodeToString(hash.Sum(nil)),
+		Config:  redacted,
+	}, nil
+}
+
+// Returns true if the last part of the given dotted setting name (e.g. DB_PASSWORD in
+// terraform.extra_arguments.foo.env_vars.DB_PASSWORD) looks like it refers to a secret or matches one of the given
+// patterns
+func isSensitiveConfigKey(key string, sensitiveVarPatterns []*regexp.Regexp) bool {
+	parts := strings.Split(key, ".")
+	name := parts[len(parts)-1]
+	// An env var such as TF_VAR_license_key sets the license_key variable, which the patterns may refer to
+	return util.IsSensitiveName(name, sensitiveVarPatterns) || util.IsSensitiveName(strings.TrimPrefix(name, TERRAFORM_VAR_ENV_PREFIX), sensitiveVarPatterns)
+}
+
+// Redact the -var arguments that set sensitive variables in the given JSON encoded list of Terraform arguments, such as
+// the arguments of extra_arguments (see util.RedactSensitiveVarArgs). If there are none, the encoded list is returned
+// as is, so the snapshot is the same as one taken before these arguments were redacted.
+func redactSensitiveVarArgs(encodedArgs string, sensitiveVarPatterns []*regexp.Regexp) string {
+	args := []string{}
+	if err := json.Unmarshal([]byte(encodedArgs), &args); err != nil || len(args) == 0 {
+		return encodedArgs
+	}
+
+	redactedArgs := util.RedactSensitiveVarArgs(args, sensitiveVarPatterns)
+	if reflect.DeepEqual(args, redactedArgs) {
+		return encodedArgs
+	}
+
+	// Unlike json.Marshal, don't escape the < and > of RedactedValue, so it reads the same as elsewhere in the snapshot
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(redactedArgs); err != nil {
+		return encodedArgs
+	}
+	return strings.TrimSuffix(encoded.String(), "\n")
+}
+
+// Flatten the given config into a map of dotted setting names to JSON encoded values
+func flattenTerragruntConfig(config *TerragruntConfig) (map[string]string, error) {
+	settings := map[string]interface{}{}
+
+	if config.Terraform != nil {
+		settings["terraform.source"] = config.Terraform.Source
+		// Only recorded when set, so snapshots taken before this setting existed don't show it as added
+		if config.Terraform.ConcurrencyGroup != "" {
+			settings["terraform.concurrency_group"] = config.Terraform.ConcurrencyGroup
+		}
+		// Only recorded when set, like concurrency_group
+		if len(config.Terraform.AllowedExitCodes) > 0 {
+			settings["terraform.allowed_exit_codes"] = config.Terraform.AllowedExitCodes
+		}
+
+		for _, extraArgs := range config.Terraform.ExtraArgs {
+			prefix := fmt.Sprintf("terraform.extra_arguments.%s", extraArgs.Name)
+			settings[prefix+".arguments"] = extraArgs.Arguments
+			settings[prefix+".commands"] = extraArgs.Commands
+			settings[prefix+".required_var_files"] = extraArgs.RequiredVarFiles
+			settings[prefix+".optional_var_files"] = extraArgs.OptionalVarFiles
+			for key, value := range extraArgs.EnvVars {
+				settings[fmt.Sprintf("%s.env_vars.%s", prefix, key)] = value
+			}
+			// Only recorded when set, like allowed_exit_codes
+			if extraArgs.When != nil {
+				settings[prefix+".when"] = extraArgs.When
+			}
+		}
+
+		flattenHooks("terraform.before_hook", config.Terraform.BeforeHooks, settings)
+		flattenHooks("terraform.after_hook", config.Terraform.AfterHooks, settings)
+	}
+
+	if config.RemoteState != nil {
+		settings["remote_state.backend"] = config.RemoteState.Backend
+		if config.RemoteState.BackupBeforeApply {
+			settings["remote_state.backup_before_apply"] = config.RemoteState.BackupBeforeApply
+			settings["remote_state.backup_retention"] = config.RemoteState.BackupRetention
+		}
+		for key, value := range config.RemoteState.Config {
+			settings[fmt.Sprintf("remote_state.config.%s", key)] = value
+		}
+	}
+
+	if config.Dependencies != nil {
+		settings["dependencies.paths"] = config.Dependencies.Paths
+	}
+
+	settings["prevent_destroy"] = config.PreventDestroy
+	settings["iam_role"] = config.IamRole
+	if config.Skip {
+		settings["skip"] = config.Skip
+	}
+
+	out := map[string]string{}
+	for key, value := range settings {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		out[key] = string(encoded)
+	}
+
+	return out, nil
+}
+
+func flattenHooks(prefix string, hooks []Hook, settings map[string]interface{}) {
+	for _, hook := range hooks {
+		settings[fmt.Sprintf("%s.%s.commands", prefix, hook.Name)] = hook.Commands
+		settings[fmt.Sprintf("%s.%s.execute", prefix, hook.Name)] = hook.Execute
+		settings[fmt.Sprintf("%s.%s.run_on_error", prefix, hook.Name)] = hook.RunOnError
+		if hook.When != nil {
+			settings[fmt.Sprintf("%s.%s.when", prefix, hook.Name)] = hook.When
+		}
+	}
+}
+
+// Return the settings that were added, removed, or changed between the old and new snapshot, sorted by setting name
+func DiffConfigSnapshots(oldSnapshot *ConfigSnapshot, newSnapshot *ConfigSnapshot) []ConfigChange {
+	changes := []ConfigChange{}
+
+	for _, key := range sortedKeys(newSnapshot.Config) {
+		newValue := newSnapshot.Config[key]
+		oldValue, existed := oldSnapshot.Config[key]
+		if !existed {
+			changes = append(changes, ConfigChange{Key: key, Kind: ConfigSettingAdded, NewValue: newValue})
+		} else if oldValue != newValue {
+			changes = append(changes, ConfigChange{Key: key, Kind: ConfigSettingChanged, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	for _, key := range sortedKeys(oldSnapshot.Config) {
+		if _, exists := newSnapshot.Config[key]; !exists {
+			changes = append(changes, ConfigChange{Key: key, Kind: ConfigSettingRemoved, OldValue: oldSnapshot.Config[key]})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+
+	return changes
+}
+
+// Return a one line summary of the given changes, such as "terraform.source, remote_state.config.key". If the hashes
+// of the snapshots differ, but no visible setting changed, a secret must have changed.
+func SummarizeConfigChanges(changes []ConfigChange, oldSnapshot *ConfigSnapshot, newSnapshot *ConfigSnapshot) string {
+	if len(changes) == 0 {
+		if oldSnapshot.Hash != newSnapshot.Hash {
+			return "one or more sensitive settings"
+		}
+		return ""
+	}
+
+	keys := []string{}
+	for _, change := range changes {
+		keys = append(keys, change.Key)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// Read the config snapshot stored at the given path. Returns nil, with no error, if there is no snapshot at that path.
+// If the snapshot can't be parsed or was written using a different version of the snapshot format, return an error so
+// the caller can log it and carry on as if there was no snapshot at all.
+func ReadConfigSnapshot(path string) (*ConfigSnapshot, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	snapshot := &ConfigSnapshot{}
+	if err := json.Unmarshal(contents, snapshot); err != nil {
+		return nil, errors.WithStackTrace(CorruptConfigSnapshot{Path: path, Underlying: err})
+	}
+
+	if snapshot.Version != ConfigSnapshotVersion {
+		return nil, errors.WithStackTrace(UnsupportedConfigSnapshotVersion{Path: path, Version: snapshot.Version})
+	}
+
+	if snapshot.Config == nil {
+		snapshot.Config = map[string]string{}
+	}
+
+	return snapshot, nil
+}
+
+// Write the given config snapshot to the given path, creating any parent folders as necessary
+func WriteConfigSnapshot(snapshot *ConfigSnapshot, path string) error {
+	contents, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	return errors.WithStackTrace(ioutil.WriteFile(path, contents, 0600))
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Custom error types
+
+type CorruptConfigSnapshot struct {
+	Path       string
+	Underlying error
+}
+
+func (err CorruptConfigSnapshot) Error() string {
+	return fmt.Sprintf("Unable to parse config snapshot at %s: %v", err.Path, err.Underlying)
+}
+
+type UnsupportedConfigSnapshotVersion struct {
+	Path    string
+	Version int
+}
+
+func (err UnsupportedConfigSnapshotVersion) Error() string {
+	return fmt.Sprintf("Config snapshot at %s uses format version %d, but this version of Terragrunt only supports version %d", err.Path, err.Version, ConfigSnapshotVersion)
+}
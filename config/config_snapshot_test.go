@@ -0,0 +1,159 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffConfigSnapshots(t *testing.T) {
+	t.Parallel()
+
+	oldConfig := &TerragruntConfig{
+		Terraform: &TerraformConfig{Source: "git::git@github.com:foo/modules.git//app?ref=v0.0.1"},
+		RemoteState: &remote.RemoteState{
+			Backend: "s3",
+			Config:  map[string]interface{}{"bucket": "my-bucket", "key": "app/terraform.tfstate", "encrypt": true},
+		},
+		Dependencies: &ModuleDependencies{Paths: []string{"../vpc"}},
+	}
+
+	newConfig := &TerragruntConfig{
+		Terraform: &TerraformConfig{Source: "git::git@github.com:foo/modules.git//app?ref=v0.0.2"},
+		RemoteState: &remote.RemoteState{
+			Backend: "s3",
+			Config:  map[string]interface{}{"bucket": "my-bucket", "key": "app/terraform.tfstate", "region": "us-east-1"},
+		},
+		Dependencies: &ModuleDependencies{Paths: []string{"../vpc"}},
+	}
+
+	oldSnapshot, err := NewConfigSnapshot(oldConfig, nil)
+	require.NoError(t, err)
+	newSnapshot, err := NewConfigSnapshot(newConfig, nil)
+	require.NoError(t, err)
+
+	changes := DiffConfigSnapshots(oldSnapshot, newSnapshot)
+
+	expected := []ConfigChange{
+		{Key: "remote_state.config.encrypt", Kind: ConfigSettingRemoved, OldValue: "true"},
+		{Key: "remote_state.config.region", Kind: ConfigSettingAdded, NewValue: `"us-east-1"`},
+		{Key: "terraform.source", Kind: ConfigSettingChanged, OldValue: `"git::git@github.com:foo/modules.git//app?ref=v0.0.1"`, NewValue: `"git::git@github.com:foo/modules.git//app?ref=v0.0.2"`},
+	}
+	assert.Equal(t, expected, changes)
+	assert.Equal(t, "remote_state.config.encrypt, remote_state.config.region, terraform.source", SummarizeConfigChanges(changes, oldSnapshot, newSnapshot))
+}
+
+func TestDiffConfigSnapshotsNoChanges(t *testing.T) {
+	t.Parallel()
+
+	config := &TerragruntConfig{Terraform: &TerraformConfig{Source: "../modules/app"}, IamRole: "arn:aws:iam::123456789012:role/foo"}
+
+	oldSnapshot, err := NewConfigSnapshot(config, nil)
+	require.NoError(t, err)
+	newSnapshot, err := NewConfigSnapshot(config, nil)
+	require.NoError(t, err)
+
+	changes := DiffConfigSnapshots(oldSnapshot, newSnapshot)
+	assert.Empty(t, changes)
+	assert.Equal(t, oldSnapshot.Hash, newSnapshot.Hash)
+	assert.Equal(t, "", SummarizeConfigChanges(changes, oldSnapshot, newSnapshot))
+}
+
+func TestConfigSnapshotRedactsSecrets(t *testing.T) {
+	t.Parallel()
+
+	configWithSecret := func(secret string) *TerragruntConfig {
+		return &TerragruntConfig{
+			Terraform: &TerraformConfig{
+				ExtraArgs: []TerraformExtraArguments{
+					{Name: "secrets", EnvVars: map[string]string{"DB_PASSWORD": secret, "REGION": "us-east-1"}},
+				},
+			},
+		}
+	}
+
+	oldSnapshot, err := NewConfigSnapshot(configWithSecret("hunter2"), nil)
+	require.NoError(t, err)
+	newSnapshot, err := NewConfigSnapshot(configWithSecret("correct-horse"), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, RedactedValue, newSnapshot.Config["terraform.extra_arguments.secrets.env_vars.DB_PASSWORD"])
+	assert.Equal(t, `"us-east-1"`, newSnapshot.Config["terraform.extra_arguments.secrets.env_vars.REGION"])
+
+	changes := DiffConfigSnapshots(oldSnapshot, newSnapshot)
+	assert.Empty(t, changes)
+	assert.NotEqual(t, oldSnapshot.Hash, newSnapshot.Hash)
+	assert.Equal(t, "one or more sensitive settings", SummarizeConfigChanges(changes, oldSnapshot, newSnapshot))
+}
+
+func TestConfigSnapshotOnlyRecordsConcurrencyGroupWhenSet(t *testing.T) {
+	t.Parallel()
+
+	withoutGroup, err := NewConfigSnapshot(&TerragruntConfig{Terraform: &TerraformConfig{Source: "../modules/app"}}, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, withoutGroup.Config, "terraform.concurrency_group")
+
+	withGroup, err := NewConfigSnapshot(&TerragruntConfig{Terraform: &TerraformConfig{Source: "../modules/app", ConcurrencyGroup: "pagerduty"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `"pagerduty"`, withGroup.Config["terraform.concurrency_group"])
+
+	expected := []ConfigChange{{Key: "terraform.concurrency_group", Kind: ConfigSettingAdded, NewValue: `"pagerduty"`}}
+	assert.Equal(t, expected, DiffConfigSnapshots(withoutGroup, withGroup))
+}
+
+func TestReadWriteConfigSnapshot(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "config-snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	snapshotPath := filepath.Join(tmpDir, "nested", "snapshot.json")
+
+	missing, err := ReadConfigSnapshot(snapshotPath)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	snapshot, err := NewConfigSnapshot(&TerragruntConfig{Terraform: &TerraformConfig{Source: "../modules/app"}}, nil)
+	require.NoError(t, err)
+	require.NoError(t, WriteConfigSnapshot(snapshot, snapshotPath))
+
+	actual, err := ReadConfigSnapshot(snapshotPath)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot, actual)
+}
+
+func TestReadConfigSnapshotCorruptOrUnsupported(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "config-snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	testCases := []struct {
+		contents    string
+		expectedErr error
+	}{
+		{`{"version": 1, "config": {`, CorruptConfigSnapshot{}},
+		{`not json at all`, CorruptConfigSnapshot{}},
+		{`{"version": 999, "hash": "abc", "config": {}}`, UnsupportedConfigSnapshotVersion{}},
+		{`{}`, UnsupportedConfigSnapshotVersion{}},
+	}
+
+	for _, testCase := range testCases {
+		snapshotPath := filepath.Join(tmpDir, "snapshot.json")
+		require.NoError(t, ioutil.WriteFile(snapshotPath, []byte(testCase.contents), 0600))
+
+		snapshot, err := ReadConfigSnapshot(snapshotPath)
+		assert.Nil(t, snapshot, "For contents %s", testCase.contents)
+		if assert.Error(t, err, "For contents %s", testCase.contents) {
+			assert.IsType(t, testCase.expectedErr, errors.Unwrap(err), "For contents %s", testCase.contents)
+		}
+	}
+}
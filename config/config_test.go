@@ -1,8 +1,12 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/gruntwork-io/terragrunt/errors"
@@ -89,6 +93,76 @@ terragrunt = {
 	}
 }
 
+func TestParseTerragruntConfigRemoteStateWithInterpolatedKeys(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  remote_state {
+    backend = "s3"
+    config {
+      bucket = "my-bucket"
+      "${get_env("KEY_NAME", "key")}" = "terraform.tfstate"
+      "${eq("a", "a")}" = "yes"
+      "region" = "us-east-1"
+    }
+  }
+}
+`
+
+	opts := mockOptionsForTest(t)
+	opts.Env = map[string]string{"KEY_NAME": "workspace_key_prefix"}
+
+	terragruntConfig, err := parseConfigString(config, opts, nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.NotNil(t, terragruntConfig.RemoteState) {
+		assert.Equal(t, map[string]interface{}{
+			"bucket":               "my-bucket",
+			"workspace_key_prefix": "terraform.tfstate",
+			"true":                 "yes",
+			"region":               "us-east-1",
+		}, terragruntConfig.RemoteState.Config)
+	}
+}
+
+func TestParseTerragruntConfigRemoteStateWithBackup(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  remote_state {
+    backend             = "s3"
+    backup_before_apply = true
+    backup_retention    = 10
+    config {
+      bucket = "my-bucket"
+      key    = "terraform.tfstate"
+      region = "us-east-1"
+    }
+  }
+}
+`
+
+	terragruntConfig, err := parseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.NotNil(t, terragruntConfig.RemoteState) {
+		assert.True(t, terragruntConfig.RemoteState.BackupBeforeApply)
+		assert.Equal(t, 10, terragruntConfig.RemoteState.BackupRetention)
+		assert.NotContains(t, terragruntConfig.RemoteState.Config, "backup_before_apply")
+	}
+
+	_, err = parseConfigString(strings.Replace(config, `"s3"`, `"gcs"`, 1), mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if assert.Error(t, err) {
+		assert.IsType(t, remote.StateBackupNotSupported(""), errors.Unwrap(err))
+	}
+}
+
 func TestParseIamRole(t *testing.T) {
 	t.Parallel()
 
@@ -362,6 +436,117 @@ terragrunt = {
 
 }
 
+func TestParseTerragruntConfigRemoteStateInitArgsAreDeterministic(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  remote_state {
+    backend = "s3"
+    config {
+      encrypt = true
+      bucket = "my-bucket"
+      key = "${path_relative_to_include()}/terraform.tfstate"
+      region = "us-east-1"
+      dynamodb_table = "my-lock-table"
+      profile = "prod"
+    }
+  }
+}
+`
+
+	opts := mockOptionsForTest(t)
+
+	var previousArgs []string
+	for i := 0; i < 10; i++ {
+		terragruntConfig, err := parseConfigString(config, opts, nil, opts.TerragruntConfigPath)
+		require.NoError(t, err)
+
+		args := terragruntConfig.RemoteState.ToTerraformInitArgs()
+		if previousArgs != nil {
+			assert.Equal(t, previousArgs, args)
+		}
+		previousArgs = args
+	}
+}
+
+func TestParseTerragruntConfigRemoteStateBackendOverride(t *testing.T) {
+	t.Parallel()
+
+	childPath := "../test/fixture-parent-folders/terragrunt-in-root/child/sub-child/sub-sub-child/" + DefaultTerragruntConfigPath
+	parentPath := "../test/fixture-parent-folders/terragrunt-in-root/" + DefaultTerragruntConfigPath
+
+	testCases := []struct {
+		name            string
+		include         bool
+		backend         string
+		override        bool
+		strict          bool
+		expectedWarning bool
+		expectedErr     error
+	}{
+		{"match", true, "s3", false, false, false, nil},
+		{"mismatch", true, "local", false, false, true, nil},
+		{"mismatch-strict", true, "local", false, true, false, RemoteStateBackendOverride{}},
+		{"mismatch-suppressed", true, "local", true, false, false, nil},
+		{"mismatch-suppressed-strict", true, "local", true, true, false, nil},
+		{"no-parent", false, "local", false, true, false, nil},
+	}
+
+	for _, testCase := range testCases {
+		include := ""
+		if testCase.include {
+			include = fmt.Sprintf(`
+  include {
+    path = "../../../%s"
+  }
+`, DefaultTerragruntConfigPath)
+		}
+
+		config := fmt.Sprintf(`
+terragrunt = {
+%s
+  remote_state {
+    backend = "%s"
+    override_parent_backend = %t
+    config {
+      path = "terraform.tfstate"
+    }
+  }
+}
+`, include, testCase.backend, testCase.override)
+
+		opts := mockOptionsForTestWithConfigPath(t, childPath)
+		opts.Strict = testCase.strict
+
+		var logs bytes.Buffer
+		opts.Logger = util.CreateLoggerWithWriter(&logs, "")
+
+		terragruntConfig, err := parseConfigString(config, opts, nil, opts.TerragruntConfigPath)
+
+		if testCase.expectedErr != nil {
+			if assert.Error(t, err, "For case %s", testCase.name) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(err), "For case %s", testCase.name)
+			}
+			continue
+		}
+
+		if assert.NoError(t, err, "For case %s", testCase.name) && assert.NotNil(t, terragruntConfig.RemoteState, "For case %s", testCase.name) {
+			assert.Equal(t, testCase.backend, terragruntConfig.RemoteState.Backend, "For case %s", testCase.name)
+		}
+
+		if testCase.expectedWarning {
+			assert.Contains(t, logs.String(), "WARNING", "For case %s", testCase.name)
+			assert.Contains(t, logs.String(), childPath, "For case %s", testCase.name)
+			assert.Contains(t, logs.String(), parentPath, "For case %s", testCase.name)
+			assert.Contains(t, logs.String(), "local backend", "For case %s", testCase.name)
+			assert.Contains(t, logs.String(), "s3 backend", "For case %s", testCase.name)
+		} else {
+			assert.NotContains(t, logs.String(), "WARNING", "For case %s", testCase.name)
+		}
+	}
+}
+
 func TestParseTerragruntConfigIncludeOverrideAll(t *testing.T) {
 	t.Parallel()
 
@@ -415,6 +600,46 @@ terragrunt = {
 
 }
 
+func TestParseTerragruntConfigIncludeDependencies(t *testing.T) {
+	t.Parallel()
+
+	configPath := "../test/fixture-include-dependencies/app/" + DefaultTerragruntConfigPath
+	opts := mockOptionsForTestWithConfigPath(t, configPath)
+
+	terragruntConfig, err := ParseConfigFile(configPath, opts, nil)
+	if assert.Nil(t, err, "Unexpected error: %v", errors.PrintErrorWithStackTrace(err)) {
+		// The parent's paths are relative to the parent's folder, so they are rebased onto the child's folder, and the
+		// child's "../vpc/" is the same folder as the parent's "vpc", so it only shows up once
+		if assert.NotNil(t, terragruntConfig.Dependencies) {
+			assert.Equal(t, []string{"../vpc", "../mysql", "../redis"}, terragruntConfig.Dependencies.Paths)
+		}
+	}
+}
+
+func TestRebaseDependencyPaths(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		paths          []string
+		fromConfigPath string
+		toConfigPath   string
+		expected       []string
+	}{
+		{[]string{}, "/root/terraform.tfvars", "/root/child/terraform.tfvars", []string{}},
+		{[]string{"vpc"}, "/root/terraform.tfvars", "/root/child/terraform.tfvars", []string{"../vpc"}},
+		{[]string{"vpc", "./mysql/"}, "/root/terraform.tfvars", "/root/a/b/terraform.tfvars", []string{"../../vpc", "../../mysql"}},
+		{[]string{"../shared/vpc"}, "/root/env/terraform.tfvars", "/root/env/app/terraform.tfvars", []string{"../../shared/vpc"}},
+		{[]string{"/abs/vpc"}, "/root/terraform.tfvars", "/root/child/terraform.tfvars", []string{"/abs/vpc"}},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := rebaseDependencyPaths(testCase.paths, testCase.fromConfigPath, testCase.toConfigPath)
+		if assert.Nil(t, err, "Unexpected error for paths %v: %v", testCase.paths, err) {
+			assert.Equal(t, testCase.expected, actual, "For paths %v", testCase.paths)
+		}
+	}
+}
+
 func TestParseTerragruntConfigTwoLevels(t *testing.T) {
 	t.Parallel()
 
@@ -517,6 +742,36 @@ func TestMergeConfigIntoIncludedConfig(t *testing.T) {
 			&TerragruntConfig{RemoteState: &remote.RemoteState{Backend: "bar"}, Terraform: &TerraformConfig{Source: "bar"}},
 			&TerragruntConfig{RemoteState: &remote.RemoteState{Backend: "foo"}, Terraform: &TerraformConfig{Source: "foo"}},
 		},
+		{
+			&TerragruntConfig{Terraform: &TerraformConfig{Source: "foo"}},
+			&TerragruntConfig{Terraform: &TerraformConfig{ConcurrencyGroup: "pagerduty"}},
+			&TerragruntConfig{Terraform: &TerraformConfig{Source: "foo", ConcurrencyGroup: "pagerduty"}},
+		},
+		{
+			&TerragruntConfig{Terraform: &TerraformConfig{ConcurrencyGroup: "github"}},
+			&TerragruntConfig{Terraform: &TerraformConfig{ConcurrencyGroup: "pagerduty"}},
+			&TerragruntConfig{Terraform: &TerraformConfig{ConcurrencyGroup: "github"}},
+		},
+		{
+			&TerragruntConfig{Terraform: &TerraformConfig{AllowedExitCodes: []int{0, 2}}},
+			&TerragruntConfig{Terraform: &TerraformConfig{Source: "foo", AllowedExitCodes: []int{3}}},
+			&TerragruntConfig{Terraform: &TerraformConfig{Source: "foo", AllowedExitCodes: []int{0, 2}}},
+		},
+		{
+			&TerragruntConfig{Terraform: &TerraformConfig{Source: "foo"}},
+			&TerragruntConfig{Terraform: &TerraformConfig{AllowedExitCodes: []int{3}}},
+			&TerragruntConfig{Terraform: &TerraformConfig{Source: "foo", AllowedExitCodes: []int{3}}},
+		},
+		{
+			&TerragruntConfig{},
+			&TerragruntConfig{Skip: true},
+			&TerragruntConfig{},
+		},
+		{
+			&TerragruntConfig{Skip: true},
+			&TerragruntConfig{},
+			&TerragruntConfig{Skip: true},
+		},
 		{
 			&TerragruntConfig{Terraform: &TerraformConfig{Source: "foo"}},
 			&TerragruntConfig{RemoteState: &remote.RemoteState{Backend: "bar"}, Terraform: &TerraformConfig{Source: "bar"}},
@@ -602,6 +857,21 @@ func TestMergeConfigIntoIncludedConfig(t *testing.T) {
 			&TerragruntConfig{IamRole: "role1"},
 			&TerragruntConfig{IamRole: "role2"},
 		},
+		{
+			&TerragruntConfig{},
+			&TerragruntConfig{Dependencies: &ModuleDependencies{Paths: []string{"../vpc"}}},
+			&TerragruntConfig{Dependencies: &ModuleDependencies{Paths: []string{"../vpc"}}},
+		},
+		{
+			&TerragruntConfig{Dependencies: &ModuleDependencies{Paths: []string{"../mysql"}}},
+			&TerragruntConfig{Dependencies: &ModuleDependencies{Paths: []string{"../vpc"}}},
+			&TerragruntConfig{Dependencies: &ModuleDependencies{Paths: []string{"../vpc", "../mysql"}}},
+		},
+		{
+			&TerragruntConfig{Dependencies: &ModuleDependencies{Paths: []string{"./../vpc/", "../mysql"}}},
+			&TerragruntConfig{Dependencies: &ModuleDependencies{Paths: []string{"../vpc"}}},
+			&TerragruntConfig{Dependencies: &ModuleDependencies{Paths: []string{"../vpc", "../mysql"}}},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -659,6 +929,560 @@ terragrunt = {
 	}
 }
 
+func TestParseTerragruntConfigTerraformWithConcurrencyGroup(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  terraform {
+    source = "foo"
+    concurrency_group = "pagerduty"
+  }
+}
+`
+
+	terragruntConfig, err := parseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.NotNil(t, terragruntConfig.Terraform) {
+		assert.Equal(t, "foo", terragruntConfig.Terraform.Source)
+		assert.Equal(t, "pagerduty", terragruntConfig.Terraform.ConcurrencyGroup)
+	}
+}
+
+func TestParseTerragruntConfigTerraformWithAllowedExitCodes(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  terraform {
+    source = "foo"
+    allowed_exit_codes = [0, 2]
+  }
+}
+`
+
+	terragruntConfig, err := parseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.NotNil(t, terragruntConfig.Terraform) {
+		assert.Equal(t, []int{0, 2}, terragruntConfig.Terraform.AllowedExitCodes)
+	}
+}
+
+func TestParseTerragruntConfigTerraformWithNegativeAllowedExitCodes(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  terraform {
+    allowed_exit_codes = [-1, 0, -255]
+  }
+}
+`
+
+	terragruntConfig, err := parseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+
+	if assert.NotNil(t, terragruntConfig.Terraform) {
+		assert.Equal(t, []int{-1, 0, -255}, terragruntConfig.Terraform.AllowedExitCodes)
+	}
+}
+
+func TestParseTerragruntConfigWithCond(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  terraform {
+    source = "git::git@github.com:foo/modules.git//app?ref=${cond("${eq("${get_env("ENV", "")}", "prod")}", "v0.0.3", "master")}"
+
+    extra_arguments "size" {
+      commands = ["${cond("${eq("${get_env("ENV", "")}", "prod")}", "${get_terraform_commands_that_need_vars()}", "plan")}"]
+      arguments = ["-var", "size=${cond("${eq("${get_env("ENV", "")}", "prod")}", "m5.large", "t3.small")}"]
+    }
+  }
+
+  prevent_destroy = "${eq("${get_env("ENV", "")}", "prod")}"
+}
+`
+
+	testCases := []struct {
+		env                    string
+		expectedSource         string
+		expectedCommands       []string
+		expectedArguments      []string
+		expectedPreventDestroy bool
+	}{
+		{"prod", "git::git@github.com:foo/modules.git//app?ref=v0.0.3", TERRAFORM_COMMANDS_NEED_VARS, []string{"-var", "size=m5.large"}, true},
+		{"stage", "git::git@github.com:foo/modules.git//app?ref=master", []string{"plan"}, []string{"-var", "size=t3.small"}, false},
+	}
+
+	for _, testCase := range testCases {
+		opts := mockOptionsForTest(t)
+		opts.Env = map[string]string{"ENV": testCase.env}
+
+		terragruntConfig, err := parseConfigString(config, opts, nil, DefaultTerragruntConfigPath)
+		if !assert.NoError(t, err, "For env %s", testCase.env) {
+			continue
+		}
+
+		assert.Equal(t, testCase.expectedPreventDestroy, terragruntConfig.PreventDestroy, "For env %s", testCase.env)
+		if assert.NotNil(t, terragruntConfig.Terraform, "For env %s", testCase.env) {
+			assert.Equal(t, testCase.expectedSource, terragruntConfig.Terraform.Source, "For env %s", testCase.env)
+			if assert.Len(t, terragruntConfig.Terraform.ExtraArgs, 1, "For env %s", testCase.env) {
+				assert.Equal(t, testCase.expectedCommands, terragruntConfig.Terraform.ExtraArgs[0].Commands, "For env %s", testCase.env)
+				assert.Equal(t, testCase.expectedArguments, terragruntConfig.Terraform.ExtraArgs[0].Arguments, "For env %s", testCase.env)
+			}
+		}
+	}
+}
+
+func TestParseTerragruntConfigWithWhen(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  terraform {
+    extra_arguments "prod" {
+      commands  = ["plan"]
+      arguments = ["-var-file=prod.tfvars"]
+      when      = "${eq("${get_env("ENV", "")}", "prod")}"
+    }
+
+    extra_arguments "always" {
+      commands  = ["plan"]
+      arguments = ["-lock-timeout=20m"]
+    }
+
+    before_hook "ci_only" {
+      commands = ["plan"]
+      execute  = ["echo", "ci"]
+      when     = "${truthy("${get_env("CI", "")}")}"
+    }
+
+    after_hook "never" {
+      commands = ["plan"]
+      execute  = ["echo", "never"]
+      when     = false
+    }
+  }
+}
+`
+
+	testCases := []struct {
+		env               map[string]string
+		expectedProd      bool
+		expectedCiOnly    bool
+		expectedExtraArgs []string
+	}{
+		{map[string]string{"ENV": "prod", "CI": "true"}, true, true, []string{"-var-file=prod.tfvars", "-lock-timeout=20m"}},
+		{map[string]string{"ENV": "stage", "CI": "true"}, false, true, []string{"-lock-timeout=20m"}},
+		{map[string]string{"ENV": "prod"}, true, false, []string{"-var-file=prod.tfvars", "-lock-timeout=20m"}},
+		{map[string]string{}, false, false, []string{"-lock-timeout=20m"}},
+	}
+
+	for _, testCase := range testCases {
+		opts := mockOptionsForTest(t)
+		opts.Env = testCase.env
+		opts.TerraformCliArgs = []string{"plan"}
+
+		terragruntConfig, err := parseConfigString(config, opts, nil, DefaultTerragruntConfigPath)
+		if !assert.NoError(t, err, "For env %v", testCase.env) {
+			continue
+		}
+
+		if assert.NotNil(t, terragruntConfig.Terraform, "For env %v", testCase.env) {
+			assert.Equal(t, testCase.expectedProd, terragruntConfig.Terraform.ExtraArgs[0].WhenIsTrue(), "For env %v", testCase.env)
+			assert.Nil(t, terragruntConfig.Terraform.ExtraArgs[1].When, "For env %v", testCase.env)
+			assert.True(t, terragruntConfig.Terraform.ExtraArgs[1].WhenIsTrue(), "For env %v", testCase.env)
+			assert.Equal(t, testCase.expectedCiOnly, terragruntConfig.Terraform.BeforeHooks[0].WhenIsTrue(), "For env %v", testCase.env)
+			assert.False(t, terragruntConfig.Terraform.AfterHooks[0].WhenIsTrue(), "For env %v", testCase.env)
+		}
+
+		assert.Equal(t, testCase.expectedExtraArgs, TerraformExtraArgsForCommand(opts, terragruntConfig), "For env %v", testCase.env)
+		assert.Equal(t, !testCase.expectedProd, len(ExtraArgsSkippedByWhenForCommand(opts, terragruntConfig)) == 1, "For env %v", testCase.env)
+	}
+}
+
+func TestParseTerragruntConfigWithInvalidWhen(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		block       string
+		listKey     string
+		when        string
+		expectedErr error
+	}{
+		{`before_hook "lint"`, "execute", `"${get_env("CI", "true")}"`, InvalidWhenValue{Block: "before_hook lint", Value: "true"}},
+		{`after_hook "notify"`, "execute", `"yes"`, InvalidWhenValue{Block: "after_hook notify", Value: "yes"}},
+		{`extra_arguments "prod"`, "arguments", `1`, InvalidWhenValue{Block: "extra_arguments prod", Value: 1}},
+		{`extra_arguments "prod"`, "arguments", `"${not_a_helper()}"`, UnknownHelperFunction("not_a_helper")},
+		{`before_hook "lint"`, "execute", `"${get_terraform_workspace()}"`, LateHelperFunctionOutsideSource{FunctionName: "get_terraform_workspace", Setting: "terraform.before_hook.lint.when"}},
+	}
+
+	for _, testCase := range testCases {
+		config := fmt.Sprintf(`
+terragrunt = {
+  terraform {
+    %s {
+      commands = ["plan"]
+      %s = ["echo"]
+      when = %s
+    }
+  }
+}
+`, testCase.block, testCase.listKey, testCase.when)
+
+		_, err := parseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+		if assert.Error(t, err, "For config %s", config) {
+			assert.Equal(t, testCase.expectedErr, errors.Unwrap(err), "For config %s", config)
+		}
+	}
+}
+
+func TestParseTerragruntConfigExtraArgumentsEnvVarsWithMakeMap(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  terraform {
+    source = "../modules//vpc"
+
+    extra_arguments "vars" {
+      commands = ["plan"]
+      env_vars = "${makemap("TF_VAR_name", "vpc", "TF_VAR_env", "prod")}"
+    }
+  }
+}
+`
+
+	terragruntConfig, err := parseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+
+	if assert.NotNil(t, terragruntConfig.Terraform) && assert.Len(t, terragruntConfig.Terraform.ExtraArgs, 1) {
+		assert.Equal(t, map[string]string{"TF_VAR_name": "vpc", "TF_VAR_env": "prod"}, terragruntConfig.Terraform.ExtraArgs[0].EnvVars)
+	}
+}
+
+func TestParseTerragruntConfigWithTerraformInterpolations(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  terraform {
+    extra_arguments "vars" {
+      commands = ["plan"]
+      arguments = ["-var", "vpc_id=${module.vpc.id}", "-var", "region=${var.region}-${get_env("ENV", "dev")}"]
+    }
+  }
+}
+
+name = "${var.name}"
+`
+
+	terragruntConfig, err := parseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+
+	if assert.NotNil(t, terragruntConfig.Terraform) && assert.Len(t, terragruntConfig.Terraform.ExtraArgs, 1) {
+		assert.Equal(t, []string{"-var", "vpc_id=${module.vpc.id}", "-var", "region=${var.region}-dev"}, terragruntConfig.Terraform.ExtraArgs[0].Arguments)
+	}
+
+	strictOpts := mockOptionsForTest(t)
+	strictOpts.Strict = true
+
+	_, err = parseConfigString(config, strictOpts, nil, DefaultTerragruntConfigPath)
+	if assert.Error(t, err) {
+		assert.IsType(t, InvalidInterpolationSyntax(""), errors.Unwrap(err))
+	}
+}
+
+func TestParseTerragruntConfigWithCommentsInListsAndMaps(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  terraform {
+    extra_arguments "vars" {
+      commands = [
+        # Commands that read variables
+        "plan", // Preview first
+        "apply",
+        // Only when rolling back
+        "destroy", # Be careful
+      ]
+      arguments = ["-var", "env=${get_env("ENV", "dev")}" /* inline */, "-lock=true"]
+      env_vars = {
+        # The name of the app
+        TF_VAR_name = "vpc" // Shared by all envs
+        TF_VAR_env  = "${get_env("ENV", "dev")}" # Resolved by Terragrunt
+      }
+    }
+  }
+
+  remote_state {
+    backend = "s3"
+    config {
+      bucket = "my-bucket" # Created by Terragrunt
+      // Relative to the root of the repo
+      key    = "vpc/terraform.tfstate"
+      region = "us-east-1"
+    }
+  }
+}
+`
+
+	terragruntConfig, err := parseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+
+	if assert.NotNil(t, terragruntConfig.Terraform) && assert.Len(t, terragruntConfig.Terraform.ExtraArgs, 1) {
+		extraArgs := terragruntConfig.Terraform.ExtraArgs[0]
+		assert.Equal(t, []string{"plan", "apply", "destroy"}, extraArgs.Commands)
+		assert.Equal(t, []string{"-var", "env=dev", "-lock=true"}, extraArgs.Arguments)
+		assert.Equal(t, map[string]string{"TF_VAR_name": "vpc", "TF_VAR_env": "dev"}, extraArgs.EnvVars)
+	}
+
+	if assert.NotNil(t, terragruntConfig.RemoteState) {
+		assert.Len(t, terragruntConfig.RemoteState.Config, 3)
+		assert.Equal(t, "my-bucket", terragruntConfig.RemoteState.Config["bucket"])
+		assert.Equal(t, "vpc/terraform.tfstate", terragruntConfig.RemoteState.Config["key"])
+		assert.Equal(t, "us-east-1", terragruntConfig.RemoteState.Config["region"])
+	}
+}
+
+func TestParseTerragruntConfigWithTerraformCommandLists(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  terraform {
+    extra_arguments "vars" {
+      commands = ["${get_terraform_commands_that_need_vars()}"]
+    }
+    extra_arguments "locking" {
+      commands = ["${get_terraform_commands_that_need_locking()}"]
+    }
+    extra_arguments "input" {
+      commands = ["${get_terraform_commands_that_need_input()}"]
+    }
+    extra_arguments "parallelism" {
+      commands = ["${get_terraform_commands_that_need_parallelism()}"]
+      arguments = ["-parallelism=4"]
+    }
+  }
+}
+`
+
+	terragruntConfig, err := parseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+
+	if assert.NotNil(t, terragruntConfig.Terraform) && assert.Len(t, terragruntConfig.Terraform.ExtraArgs, 4) {
+		assert.Equal(t, TERRAFORM_COMMANDS_NEED_VARS, terragruntConfig.Terraform.ExtraArgs[0].Commands)
+		assert.Equal(t, TERRAFORM_COMMANDS_NEED_LOCKING, terragruntConfig.Terraform.ExtraArgs[1].Commands)
+		assert.Equal(t, TERRAFORM_COMMANDS_NEED_INPUT, terragruntConfig.Terraform.ExtraArgs[2].Commands)
+		assert.Equal(t, []string{"apply", "destroy", "plan", "refresh"}, terragruntConfig.Terraform.ExtraArgs[3].Commands)
+	}
+}
+
+func TestParseTerragruntConfigTerraformSourceWithHelpers(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  terraform {
+    source = "git::git@github.com:foo/modules.git//vpc?ref=${get_env("MODULE_REF", "v1.4.0")}"
+  }
+}
+`
+
+	withDefaultRef := mockOptionsForTest(t)
+	withDefaultRef.Env = map[string]string{}
+	withRefFromEnv := mockOptionsForTest(t)
+	withRefFromEnv.Env = map[string]string{"MODULE_REF": "v1.5.0"}
+
+	testCases := []struct {
+		terragruntOptions *options.TerragruntOptions
+		expectedSource    string
+	}{
+		{withDefaultRef, "git::git@github.com:foo/modules.git//vpc?ref=v1.4.0"},
+		{withRefFromEnv, "git::git@github.com:foo/modules.git//vpc?ref=v1.5.0"},
+	}
+
+	for _, testCase := range testCases {
+		terragruntConfig, err := parseConfigString(config, testCase.terragruntOptions, nil, DefaultTerragruntConfigPath)
+		require.NoError(t, err)
+		if assert.NotNil(t, terragruntConfig.Terraform) {
+			assert.Equal(t, testCase.expectedSource, terragruntConfig.Terraform.Source)
+		}
+	}
+}
+
+func TestParseTerragruntConfigTerraformSourceWithLateHelper(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  terraform {
+    source = "git::git@github.com:foo/modules.git//vpc-${get_terraform_workspace()}?ref=${get_env("MODULE_REF", "v1.4.0")}"
+  }
+}
+`
+
+	opts := mockOptionsForTest(t)
+	opts.Env = map[string]string{"TF_WORKSPACE": "stage"}
+
+	terragruntConfig, err := parseConfigString(config, opts, nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+	if assert.NotNil(t, terragruntConfig.Terraform) {
+		assert.Equal(t, "git::git@github.com:foo/modules.git//vpc-stage?ref=v1.4.0", terragruntConfig.Terraform.Source)
+	}
+}
+
+func TestParseTerragruntConfigTerraformSourceInvalidHelpers(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		config      string
+		expectedErr error
+	}{
+		{
+			`
+terragrunt = {
+  terraform {
+    source = "git::git@github.com:foo/modules.git//vpc?ref=${get_terraform_commands_that_need_vars()}"
+  }
+}
+`,
+			HelperFunctionNotAllowedInSource("get_terraform_commands_that_need_vars"),
+		},
+		{
+			`
+terragrunt = {
+  terraform {
+    source = "git::git@github.com:foo/modules.git//vpc"
+
+    extra_arguments "workspace" {
+      commands  = ["plan"]
+      arguments = ["-var", "workspace=${get_terraform_workspace()}"]
+    }
+  }
+}
+`,
+			LateHelperFunctionOutsideSource{FunctionName: "get_terraform_workspace", Setting: "terraform.extra_arguments.workspace.arguments"},
+		},
+		{
+			`
+terragrunt = {
+  iam_role = "arn:aws:iam::123456789012:role/${get_terraform_workspace()}"
+}
+`,
+			LateHelperFunctionOutsideSource{FunctionName: "get_terraform_workspace", Setting: "iam_role"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, err := parseConfigString(testCase.config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+		if assert.Error(t, err, "For config %s", testCase.config) {
+			assert.Equal(t, testCase.expectedErr, errors.Unwrap(err), "For config %s", testCase.config)
+		}
+	}
+}
+
+func TestParseConfigFileWithUTF8BOM(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "config-with-bom")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	configPath := util.JoinPath(tmpDir, DefaultTerragruntConfigPath)
+	config := util.UTF8_BOM + `
+terragrunt = {
+  terraform {
+    source = "foo"
+  }
+}
+`
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(config), 0644))
+
+	terragruntConfig, err := ParseConfigFile(configPath, mockOptionsForTestWithConfigPath(t, configPath), nil)
+	require.NoError(t, err)
+	if assert.NotNil(t, terragruntConfig.Terraform) {
+		assert.Equal(t, "foo", terragruntConfig.Terraform.Source)
+	}
+}
+
+func TestParseConfigFileWithWindowsLineEndings(t *testing.T) {
+	t.Parallel()
+
+	configPath := "../test/fixture-windows-line-endings/" + DefaultTerragruntConfigPath
+	originalContents, err := ioutil.ReadFile(configPath)
+	require.NoError(t, err)
+
+	terragruntConfig, err := ParseConfigFile(configPath, mockOptionsForTestWithConfigPath(t, configPath), nil)
+	require.NoError(t, err)
+
+	if assert.NotNil(t, terragruntConfig.Terraform) {
+		assert.Equal(t, "git::git@github.com:foo/modules.git//app?ref=v0.0.3", terragruntConfig.Terraform.Source)
+		if assert.Len(t, terragruntConfig.Terraform.ExtraArgs, 1) {
+			extraArgs := terragruntConfig.Terraform.ExtraArgs[0]
+			assert.Equal(t, "env", extraArgs.Name)
+			assert.Equal(t, []string{"apply", "plan"}, extraArgs.Commands)
+			assert.Equal(t, []string{"-var", "env=prod"}, extraArgs.Arguments)
+			assert.Equal(t, map[string]string{"TF_VAR_region": "us-east-1", "TF_VAR_team": "platform"}, extraArgs.EnvVars)
+		}
+	}
+
+	if assert.NotNil(t, terragruntConfig.RemoteState) {
+		assert.Equal(t, "s3", terragruntConfig.RemoteState.Backend)
+		assert.Equal(t, map[string]interface{}{"bucket": "my-bucket", "key": "app/terraform.tfstate", "encrypt": true}, terragruntConfig.RemoteState.Config)
+	}
+
+	if assert.NotNil(t, terragruntConfig.Dependencies) {
+		assert.Equal(t, []string{"../vpc", "../mysql"}, terragruntConfig.Dependencies.Paths)
+	}
+
+	settings, err := flattenTerragruntConfig(terragruntConfig)
+	require.NoError(t, err)
+	for key, value := range settings {
+		assert.NotContains(t, value, `\r`, "Setting %s contains a carriage return", key)
+	}
+
+	// The file itself must be left as it was
+	contentsAfterParsing, err := ioutil.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, originalContents, contentsAfterParsing)
+}
+
+func TestParseConfigStringWithBOMAndCRLF(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		config         string
+		expectedSource string
+	}{
+		{util.UTF8_BOM + "terragrunt = {\r\n  terraform {\r\n    source = \"foo\"\r\n  }\r\n}\r\n", "foo"},
+		{"# comment\r\nterragrunt = {\r\n  // comment\r\n  terraform {\r\n    /* multi\r\n       line */\r\n    source = \"foo\"\r\n  }\r\n}\r\n", "foo"},
+		{"terragrunt = {\r  terraform {\r    source = \"foo\"\r  }\r}\r", "foo"},
+		{util.UTF8_BOM + "terragrunt = {\r\n  terraform {\r\n    source = \"${get_env(\"TF_VAR_does_not_exist\", \"bar\")}\"\r\n  }\r\n}\r\n", "bar"},
+	}
+
+	for _, testCase := range testCases {
+		containsBlock, err := containsTerragruntBlock(testCase.config)
+		assert.NoError(t, err, "For config %q", testCase.config)
+		assert.True(t, containsBlock, "For config %q", testCase.config)
+
+		terragruntConfig, err := parseConfigString(testCase.config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+		if assert.NoError(t, err, "For config %q", testCase.config) && assert.NotNil(t, terragruntConfig.Terraform, "For config %q", testCase.config) {
+			assert.Equal(t, testCase.expectedSource, terragruntConfig.Terraform.Source, "For config %q", testCase.config)
+		}
+	}
+}
+
 func TestParseTerragruntConfigTerraformWithExtraArguments(t *testing.T) {
 	t.Parallel()
 
@@ -705,6 +1529,42 @@ terragrunt = {
 	}
 }
 
+func TestParseTerragruntConfigHeredocs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		heredoc  string
+		expected string
+	}{
+		{"empty", "<<EOF\nEOF", ""},
+		{"interpolation", "<<EOF\nregion=${get_env(\"TEST_HEREDOC_REGION\", \"us-east-1\")}\nowner=${get_env(\"TEST_HEREDOC_OWNER\", \"nobody\")}\nEOF", "region=eu-west-1\nowner=nobody\n"},
+		{"indented", "<<-EOF\n        first\n          second\n        EOF", "first\n  second\n"},
+	}
+
+	for _, testCase := range testCases {
+		config := fmt.Sprintf(`
+terragrunt = {
+  terraform {
+    extra_arguments "heredoc" {
+      commands  = ["apply"]
+      arguments = [%s
+      ]
+    }
+  }
+}
+`, testCase.heredoc)
+
+		terragruntOptions := terragruntOptionsForTestWithEnv(t, DefaultTerragruntConfigPath, map[string]string{"TEST_HEREDOC_REGION": "eu-west-1"})
+		terragruntConfig, err := parseConfigString(config, terragruntOptions, nil, DefaultTerragruntConfigPath)
+		require.NoError(t, err, "For %s", testCase.name)
+
+		if assert.NotNil(t, terragruntConfig.Terraform, "For %s", testCase.name) {
+			assert.Equal(t, []string{testCase.expected}, terragruntConfig.Terraform.ExtraArgs[0].Arguments, "For %s", testCase.name)
+		}
+	}
+}
+
 func TestParseTerragruntConfigTerraformWithMultipleExtraArguments(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/hashicorp/go-version"
+)
+
+// A config setting, or config file format, that still works, but is on its way out
+type DeprecatedSetting struct {
+	// A short, stable identifier of the deprecation, to search the docs and logs for
+	Code string
+
+	// The setting as it's written in the config, such as remote_state.config.lock_table
+	Setting string
+
+	// What to do instead, as a sentence
+	Replacement string
+
+	// The version of Terragrunt in which the setting stops working. From this version on, using it is an error.
+	RemovalVersion string
+
+	// Returns true if the given config file, read from the given path, uses the setting
+	isUsed func(configFile *terragruntConfigFile, configPath string) bool
+}
+
+// The deprecated config settings Terragrunt checks every config it parses for
+var DEPRECATED_SETTINGS = []DeprecatedSetting{
+	{
+		Code:           "TG_DEPRECATED_TERRAGRUNT_FILE",
+		Setting:        OldTerragruntConfigPath + " file",
+		Replacement:    fmt.Sprintf("Move the config into a terragrunt = { ... } block in a %s file.", DefaultTerragruntConfigPath),
+		RemovalVersion: "v1.0.0",
+		isUsed: func(configFile *terragruntConfigFile, configPath string) bool {
+			return isOldTerragruntConfig(configPath)
+		},
+	},
+	{
+		Code:           "TG_DEPRECATED_LOCK",
+		Setting:        "lock",
+		Replacement:    "Terraform locks the state natively as of version 0.9.0, so the lock block has no effect. Remove it, and configure locking in your backend instead, such as with dynamodb_table for s3.",
+		RemovalVersion: "v1.0.0",
+		isUsed: func(configFile *terragruntConfigFile, configPath string) bool {
+			return configFile.Lock != nil
+		},
+	},
+	{
+		Code:           "TG_DEPRECATED_LOCK_TABLE",
+		Setting:        "remote_state.config.lock_table",
+		Replacement:    "Rename it to dynamodb_table.",
+		RemovalVersion: "v1.0.0",
+		isUsed: func(configFile *terragruntConfigFile, configPath string) bool {
+			if configFile.RemoteState == nil || configFile.RemoteState.Backend != "s3" {
+				return false
+			}
+			_, hasLockTable := configFile.RemoteState.Config["lock_table"]
+			return hasLockTable
+		},
+	},
+}
+
+// Check the given config file, read from the given path, for the settings in DEPRECATED_SETTINGS. Using one is an error
+// in strict mode, or once the running version of Terragrunt is at or past the version the setting is removed in.
+// Otherwise, log a warning about it, once per run for each setting and config file, as a config included by every
+// module of an xxx-all command would otherwise repeat it for each module.
+func checkDeprecatedSettings(configFile *terragruntConfigFile, configPath string, terragruntOptions *options.TerragruntOptions) error {
+	for _, setting := range DEPRECATED_SETTINGS {
+		if !setting.isUsed(configFile, configPath) {
+			continue
+		}
+
+		err := DeprecatedSettingUsed{Setting: setting, ConfigPath: configPath, Removed: isRemoved(setting, terragruntOptions)}
+		if err.Removed || terragruntOptions.Strict {
+			return errors.WithStackTrace(err)
+		}
+
+		if terragruntOptions.LoggedWarnings.Add(setting.Code + " " + configPath) {
+			terragruntOptions.Logger.Printf("DEPRECATION WARNING: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Returns true if the running version of Terragrunt is at or past the version the given setting is removed in. If the
+// running version is unknown, such as in a build from source, it's not.
+func isRemoved(setting DeprecatedSetting, terragruntOptions *options.TerragruntOptions) bool {
+	if terragruntOptions.TerragruntVersion == nil {
+		return false
+	}
+	return !terragruntOptions.TerragruntVersion.LessThan(version.Must(version.NewVersion(setting.RemovalVersion)))
+}
+
+// Custom error types
+
+type DeprecatedSettingUsed struct {
+	Setting    DeprecatedSetting
+	ConfigPath string
+	Removed    bool
+}
+
+func (err DeprecatedSettingUsed) Error() string {
+	if err.Removed {
+		return fmt.Sprintf("[%s] %s uses %s, which was removed in Terragrunt %s. %s", err.Setting.Code, err.ConfigPath, err.Setting.Setting, err.Setting.RemovalVersion, err.Setting.Replacement)
+	}
+	return fmt.Sprintf("[%s] %s uses %s, which is deprecated and will stop working in Terragrunt %s. %s", err.Setting.Code, err.ConfigPath, err.Setting.Setting, err.Setting.RemovalVersion, err.Setting.Replacement)
+}
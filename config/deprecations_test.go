@@ -0,0 +1,158 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDeprecatedSettings(t *testing.T) {
+	t.Parallel()
+
+	lockTable := &remote.RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "lock_table": "locks"}}
+	dynamoDBTable := &remote.RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "dynamodb_table": "locks"}}
+
+	testCases := []struct {
+		configFile    *terragruntConfigFile
+		configPath    string
+		expectedCodes []string
+	}{
+		{&terragruntConfigFile{}, DefaultTerragruntConfigPath, []string{}},
+		{&terragruntConfigFile{RemoteState: dynamoDBTable}, DefaultTerragruntConfigPath, []string{}},
+		{&terragruntConfigFile{RemoteState: lockTable}, DefaultTerragruntConfigPath, []string{"TG_DEPRECATED_LOCK_TABLE"}},
+		{&terragruntConfigFile{Lock: &LockConfig{}}, DefaultTerragruntConfigPath, []string{"TG_DEPRECATED_LOCK"}},
+		{&terragruntConfigFile{}, "/root/child/" + OldTerragruntConfigPath, []string{"TG_DEPRECATED_TERRAGRUNT_FILE"}},
+		{&terragruntConfigFile{Lock: &LockConfig{}, RemoteState: lockTable}, "/root/child/" + OldTerragruntConfigPath, []string{"TG_DEPRECATED_TERRAGRUNT_FILE", "TG_DEPRECATED_LOCK", "TG_DEPRECATED_LOCK_TABLE"}},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, logs := terragruntOptionsForDeprecationTest(t)
+
+		require.NoError(t, checkDeprecatedSettings(testCase.configFile, testCase.configPath, terragruntOptions))
+		assert.Equal(t, testCase.expectedCodes, loggedDeprecationCodes(logs.String()), "For config %v at %s", testCase.configFile, testCase.configPath)
+	}
+}
+
+func TestCheckDeprecatedSettingsWarnsOncePerRun(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, logs := terragruntOptionsForDeprecationTest(t)
+	configFile := &terragruntConfigFile{Lock: &LockConfig{}}
+
+	// A parent config included by every module of an xxx-all command is checked once per module, with clones of the
+	// same options
+	for _, modulePath := range []string{"app", "db", "vpc"} {
+		moduleOptions := terragruntOptions.Clone(util.JoinPath("/root", modulePath, DefaultTerragruntConfigPath))
+		require.NoError(t, checkDeprecatedSettings(configFile, "/root/"+DefaultTerragruntConfigPath, moduleOptions))
+	}
+	assert.Equal(t, []string{"TG_DEPRECATED_LOCK"}, loggedDeprecationCodes(logs.String()))
+
+	// The same setting in another config is logged again, so every config that needs updating shows up
+	require.NoError(t, checkDeprecatedSettings(configFile, "/other/"+DefaultTerragruntConfigPath, terragruntOptions))
+	assert.Equal(t, []string{"TG_DEPRECATED_LOCK", "TG_DEPRECATED_LOCK"}, loggedDeprecationCodes(logs.String()))
+}
+
+func TestCheckDeprecatedSettingsErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		strict            bool
+		terragruntVersion string
+		expectedErr       bool
+		expectedRemoved   bool
+	}{
+		{false, "", false, false},
+		{false, "v0.19.2", false, false},
+		{true, "", true, false},
+		{true, "v0.19.2", true, false},
+		{false, "v1.0.0", true, true},
+		{false, "v1.2.3", true, true},
+		{true, "v1.0.0", true, true},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, logs := terragruntOptionsForDeprecationTest(t)
+		terragruntOptions.Strict = testCase.strict
+		if testCase.terragruntVersion != "" {
+			terragruntOptions.TerragruntVersion = version.Must(version.NewVersion(testCase.terragruntVersion))
+		}
+
+		err := checkDeprecatedSettings(&terragruntConfigFile{Lock: &LockConfig{}}, DefaultTerragruntConfigPath, terragruntOptions)
+		if !testCase.expectedErr {
+			assert.NoError(t, err, "For strict %v and version %s", testCase.strict, testCase.terragruntVersion)
+			continue
+		}
+
+		if assert.Error(t, err, "For strict %v and version %s", testCase.strict, testCase.terragruntVersion) {
+			deprecatedSettingUsed, isDeprecatedSettingUsed := errors.Unwrap(err).(DeprecatedSettingUsed)
+			if assert.True(t, isDeprecatedSettingUsed, "For strict %v and version %s", testCase.strict, testCase.terragruntVersion) {
+				assert.Equal(t, "TG_DEPRECATED_LOCK", deprecatedSettingUsed.Setting.Code)
+				assert.Equal(t, testCase.expectedRemoved, deprecatedSettingUsed.Removed, "For strict %v and version %s", testCase.strict, testCase.terragruntVersion)
+			}
+		}
+		assert.Empty(t, loggedDeprecationCodes(logs.String()), "For strict %v and version %s", testCase.strict, testCase.terragruntVersion)
+	}
+}
+
+func TestParseTerragruntConfigWithDeprecatedLockTable(t *testing.T) {
+	t.Parallel()
+
+	config := `
+terragrunt = {
+  remote_state {
+    backend = "s3"
+    config {
+      bucket     = "my-bucket"
+      key        = "terraform.tfstate"
+      region     = "us-east-1"
+      lock_table = "my-lock-table"
+    }
+  }
+}
+`
+
+	terragruntOptions, logs := terragruntOptionsForDeprecationTest(t)
+
+	terragruntConfig, err := parseConfigString(config, terragruntOptions, nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+	if assert.NotNil(t, terragruntConfig.RemoteState) {
+		assert.Equal(t, "my-lock-table", terragruntConfig.RemoteState.Config["lock_table"])
+	}
+	assert.Equal(t, []string{"TG_DEPRECATED_LOCK_TABLE"}, loggedDeprecationCodes(logs.String()))
+
+	terragruntOptions.Strict = true
+	_, err = parseConfigString(config, terragruntOptions, nil, DefaultTerragruntConfigPath)
+	if assert.Error(t, err) {
+		assert.IsType(t, DeprecatedSettingUsed{}, errors.Unwrap(err))
+	}
+}
+
+func terragruntOptionsForDeprecationTest(t *testing.T) (*options.TerragruntOptions, *bytes.Buffer) {
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("/root/" + DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+
+	logs := &bytes.Buffer{}
+	terragruntOptions.Logger = util.CreateLoggerWithWriter(logs, "")
+
+	return terragruntOptions, logs
+}
+
+// Return the codes of the deprecation warnings in the given logs, in the order they were logged
+func loggedDeprecationCodes(logs string) []string {
+	codes := []string{}
+	for _, line := range strings.Split(logs, "\n") {
+		if index := strings.Index(line, "DEPRECATION WARNING: ["); index >= 0 {
+			code := line[index+len("DEPRECATION WARNING: ["):]
+			codes = append(codes, code[:strings.Index(code, "]")])
+		}
+	}
+	return codes
+}
@@ -0,0 +1,381 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl"
+)
+
+// The prefix of the env vars Terraform reads variables from
+const TERRAFORM_VAR_ENV_PREFIX = "TF_VAR_"
+
+// The variable files Terraform loads from its working dir without being asked to, before any *.auto.tfvars files
+var TERRAFORM_DEFAULT_VAR_FILES = []string{"terraform.tfvars", "terraform.tfvars.json"}
+
+// Return the extra_arguments of the given config that apply to the Terraform command in the given options, including
+// a -var-file argument for each of their required_var_files and existing optional_var_files. Relative var files are
+// passed on as is, so Terraform resolves them relative to the working dir it runs in, which is the download dir if the
+// Terraform code was downloaded. That's why optional_var_files are checked for relative to that dir too.
+func TerraformExtraArgsForCommand(terragruntOptions *options.TerragruntOptions, terragruntConfig *TerragruntConfig) []string {
+	out := []string{}
+	if terragruntConfig.Terraform == nil {
+		return out
+	}
+
+	cmd := util.FirstArg(terragruntOptions.TerraformCliArgs)
+
+	for _, arg := range terragruntConfig.Terraform.ExtraArgs {
+		if terragruntOptions.IsExtraArgsDisabled(arg.Name) || !arg.WhenIsTrue() {
+			continue
+		}
+		for _, arg_cmd := range arg.Commands {
+			if cmd == arg_cmd {
+				lastArg := util.LastArg(terragruntOptions.TerraformCliArgs)
+				skipVars := cmd == "apply" && util.IsFile(lastArg)
+
+				// The following is a fix for GH-493.
+				// If the first argument is "apply" and the second argument is a file (plan),
+				// we don't add any -var-file to the command.
+				if skipVars {
+					// If we have to skip vars, we need to iterate over all elements of array...
+					for _, a := range arg.Arguments {
+						if !strings.HasPrefix(a, "-var") {
+							out = append(out, a)
+						}
+					}
+				} else {
+					// ... Otherwise, let's add all the arguments
+					out = append(out, arg.Arguments...)
+				}
+
+				if !skipVars {
+					// If RequiredVarFiles is specified, add -var-file=<file> for each specified files
+					for _, file := range util.RemoveDuplicatesFromListKeepLast(arg.RequiredVarFiles) {
+						out = append(out, fmt.Sprintf("-var-file=%s", file))
+					}
+
+					// If OptionalVarFiles is specified, check for each file if it exists and if so, add -var-file=<file>
+					// It is possible that many files resolve to the same path, so we remove duplicates.
+					for _, file := range util.RemoveDuplicatesFromListKeepLast(arg.OptionalVarFiles) {
+						if util.FileExists(varFilePath(terragruntOptions, file)) {
+							out = append(out, fmt.Sprintf("-var-file=%s", file))
+						} else {
+							terragruntOptions.Logger.Printf("Skipping var-file %s as it does not exist", file)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+// Return the env_vars of the extra_arguments of the given config that apply to the Terraform command in the given
+// options
+func TerraformEnvVarsForCommand(terragruntOptions *options.TerragruntOptions, terragruntConfig *TerragruntConfig) map[string]string {
+	out := map[string]string{}
+	if terragruntConfig.Terraform == nil {
+		return out
+	}
+
+	cmd := util.FirstArg(terragruntOptions.TerraformCliArgs)
+
+	for _, arg := range terragruntConfig.Terraform.ExtraArgs {
+		if terragruntOptions.IsExtraArgsDisabled(arg.Name) || !arg.WhenIsTrue() {
+			continue
+		}
+		for _, argcmd := range arg.Commands {
+			if cmd == argcmd {
+				for k, v := range arg.EnvVars {
+					out[k] = v
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+// Return the names of the extra_arguments of the given config that would apply to the Terraform command in the given
+// options, but are disabled in those options, so TerraformExtraArgsForCommand and TerraformEnvVarsForCommand skip them
+func DisabledExtraArgsForCommand(terragruntOptions *options.TerragruntOptions, terragruntConfig *TerragruntConfig) []string {
+	out := []string{}
+	if terragruntConfig.Terraform == nil {
+		return out
+	}
+
+	cmd := util.FirstArg(terragruntOptions.TerraformCliArgs)
+
+	for _, arg := range terragruntConfig.Terraform.ExtraArgs {
+		if terragruntOptions.IsExtraArgsDisabled(arg.Name) && util.ListContainsElement(arg.Commands, cmd) {
+			out = append(out, arg.Name)
+		}
+	}
+
+	return out
+}
+
+// Return the names of the extra_arguments of the given config that would apply to the Terraform command in the given
+// options, but are skipped because their when condition is false
+func ExtraArgsSkippedByWhenForCommand(terragruntOptions *options.TerragruntOptions, terragruntConfig *TerragruntConfig) []string {
+	out := []string{}
+	if terragruntConfig.Terraform == nil {
+		return out
+	}
+
+	cmd := util.FirstArg(terragruntOptions.TerraformCliArgs)
+
+	for _, arg := range terragruntConfig.Terraform.ExtraArgs {
+		if !terragruntOptions.IsExtraArgsDisabled(arg.Name) && !arg.WhenIsTrue() && util.ListContainsElement(arg.Commands, cmd) {
+			out = append(out, arg.Name)
+		}
+	}
+
+	return out
+}
+
+// Return the path of the given var file as Terraform would resolve it: relative to the working dir it runs in
+func varFilePath(terragruntOptions *options.TerragruntOptions, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return util.JoinPath(terragruntOptions.WorkingDir, file)
+}
+
+// Return the values of the variables Terraform receives when Terragrunt runs the Terraform command in the given options
+// with the given config, keyed by variable name. The values come from the same places, and take precedence the same
+// way, as they do in Terraform, from lowest to highest:
+//
+// 1. TF_VAR_xxx env vars, including those set via env_vars in extra_arguments.
+// 2. terraform.tfvars, terraform.tfvars.json, and *.auto.tfvars(.json) in the working dir in the given options.
+// 3. -var and -var-file arguments, in the order they're passed, as computed by TerraformExtraArgsForCommand.
+//
+// Like Terraform, a map set in several places is merged rather than replaced. The terragrunt block of variable files
+// is not a variable, so it's left out. Values are never redacted; see RedactSensitiveInputs.
+func ResolvedInputs(terragruntOptions *options.TerragruntOptions, terragruntConfig *TerragruntConfig) (map[string]interface{}, error) {
+	inputs := map[string]interface{}{}
+
+	envVars := map[string]string{}
+	for key, value := range terragruntOptions.Env {
+		envVars[key] = value
+	}
+	for key, value := range TerraformEnvVarsForCommand(terragruntOptions, terragruntConfig) {
+		envVars[key] = value
+	}
+	for _, key := range sortedKeys(envVars) {
+		if strings.HasPrefix(key, TERRAFORM_VAR_ENV_PREFIX) && len(key) > len(TERRAFORM_VAR_ENV_PREFIX) {
+			setInput(inputs, strings.TrimPrefix(key, TERRAFORM_VAR_ENV_PREFIX), parseVarValue(envVars[key]))
+		}
+	}
+
+	varFiles, err := defaultVarFiles(terragruntOptions.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, varFile := range varFiles {
+		if err := setInputsFromVarFile(inputs, varFile); err != nil {
+			return nil, err
+		}
+	}
+
+	args := TerraformExtraArgsForCommand(terragruntOptions, terragruntConfig)
+	for i := 0; i < len(args); i++ {
+		flag, value, hasValue := splitVarFlag(args[i])
+		if flag != "var" && flag != "var-file" {
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				continue
+			}
+			i++
+			value = args[i]
+		}
+
+		if flag == "var-file" {
+			if err := setInputsFromVarFile(inputs, varFilePath(terragruntOptions, value)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.WithStackTrace(InvalidVarArgument(value))
+		}
+		setInput(inputs, parts[0], parseVarValue(parts[1]))
+	}
+
+	return inputs, nil
+}
+
+// Return a copy of the given inputs, as returned by ResolvedInputs, with the value of each variable whose name looks
+// like it refers to a secret, or matches one of the given patterns (see TerragruntOptions.SensitiveVarPatterns),
+// replaced by RedactedValue
+func RedactSensitiveInputs(inputs map[string]interface{}, sensitiveVarPatterns []*regexp.Regexp) map[string]interface{} {
+	redacted := map[string]interface{}{}
+	for name, value := range inputs {
+		if util.IsSensitiveName(name, sensitiveVarPatterns) {
+			redacted[name] = RedactedValue
+		} else {
+			redacted[name] = value
+		}
+	}
+	return redacted
+}
+
+// Return the variable files Terraform loads from the given working dir by default, in the order it loads them
+func defaultVarFiles(workingDir string) ([]string, error) {
+	varFiles := []string{}
+	for _, name := range TERRAFORM_DEFAULT_VAR_FILES {
+		if path := util.JoinPath(workingDir, name); util.FileExists(path) {
+			varFiles = append(varFiles, path)
+		}
+	}
+
+	autoVarFiles := []string{}
+	for _, pattern := range []string{"*.auto.tfvars", "*.auto.tfvars.json"} {
+		matches, err := filepath.Glob(filepath.Join(workingDir, pattern))
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		autoVarFiles = append(autoVarFiles, matches...)
+	}
+	sort.Strings(autoVarFiles)
+
+	return append(varFiles, autoVarFiles...), nil
+}
+
+// Set the variables in the given variable file, which may be in HCL or JSON, in the given inputs
+func setInputsFromVarFile(inputs map[string]interface{}, path string) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	values := map[string]interface{}{}
+	if err := hcl.Decode(&values, string(contents)); err != nil {
+		return errors.WithStackTrace(ErrorParsingVarFile{Path: path, Underlying: err})
+	}
+
+	for _, name := range sortedInterfaceKeys(values) {
+		if name == "terragrunt" {
+			continue
+		}
+		setInput(inputs, name, normalizeHclValue(values[name]))
+	}
+	return nil
+}
+
+// Set the given variable in the given inputs. If the variable is already set to a map, and the new value is a map too,
+// they're merged, with the keys of the new value winning, as Terraform does.
+func setInput(inputs map[string]interface{}, name string, value interface{}) {
+	existingMap, existingIsMap := inputs[name].(map[string]interface{})
+	newMap, newIsMap := value.(map[string]interface{})
+	if !existingIsMap || !newIsMap {
+		inputs[name] = value
+		return
+	}
+
+	merged := map[string]interface{}{}
+	for key, value := range existingMap {
+		merged[key] = value
+	}
+	for key, value := range newMap {
+		merged[key] = value
+	}
+	inputs[name] = merged
+}
+
+// Parse the given value of a -var argument or TF_VAR_xxx env var. Like Terraform, a value that looks like a list or
+// map literal is parsed as HCL, and any other value is a string.
+func parseVarValue(value string) interface{} {
+	trimmed := strings.TrimSpace(value)
+	if !strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "{") {
+		return value
+	}
+
+	parsed := map[string]interface{}{}
+	if err := hcl.Decode(&parsed, fmt.Sprintf("value = %s", trimmed)); err != nil {
+		return value
+	}
+	return normalizeHclValue(parsed["value"])
+}
+
+// HCL decodes a map, such as { a = "b" }, into a list with a single map in it, and a map set in several blocks into a
+// list with several maps. Convert such lists, at any depth, into a single map, so the value has the shape Terraform
+// gives it.
+func normalizeHclValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case []map[string]interface{}:
+		merged := map[string]interface{}{}
+		for _, item := range value {
+			for key, itemValue := range item {
+				merged[key] = normalizeHclValue(itemValue)
+			}
+		}
+		return merged
+	case map[string]interface{}:
+		normalized := map[string]interface{}{}
+		for key, itemValue := range value {
+			normalized[key] = normalizeHclValue(itemValue)
+		}
+		return normalized
+	case []interface{}:
+		normalized := []interface{}{}
+		for _, item := range value {
+			normalized = append(normalized, normalizeHclValue(item))
+		}
+		return normalized
+	default:
+		return value
+	}
+}
+
+// Split the given argument into the name of the flag it sets, without leading dashes, and its value, if it's passed in
+// the same argument, as in -var-file=foo.tfvars. Returns an empty name if the argument isn't a flag.
+func splitVarFlag(arg string) (string, string, bool) {
+	if !strings.HasPrefix(arg, "-") {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1], true
+	}
+	return parts[0], "", false
+}
+
+func sortedInterfaceKeys(values map[string]interface{}) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Custom error types
+
+type InvalidVarArgument string
+
+func (err InvalidVarArgument) Error() string {
+	return fmt.Sprintf("Invalid -var argument '%s' in extra_arguments. Expected the form NAME=VALUE.", string(err))
+}
+
+type ErrorParsingVarFile struct {
+	Path       string
+	Underlying error
+}
+
+func (err ErrorParsingVarFile) Error() string {
+	return fmt.Sprintf("Unable to parse the variable file %s: %v", err.Path, err.Underlying)
+}
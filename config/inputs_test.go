@@ -0,0 +1,197 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A stack with interpolations, includes, and env-derived values, along with the inputs expected for its modules
+const RENDER_INPUTS_FIXTURE = "../test/fixture-render-inputs"
+
+// The env the fixture is rendered with
+var RENDER_INPUTS_ENV = map[string]string{
+	"TG_RENDER_INPUTS_REGION": "eu-west-1",
+	"TF_VAR_owner":            "alice",
+	"TF_VAR_name":             "from-env",
+}
+
+func TestResolvedInputs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		module       string
+		command      string
+		expectedFile string
+	}{
+		{"live/app", "plan", "app-plan.json"},
+		// extra_arguments only apply to the commands they list, so none of them apply to output
+		{"live/app", "output", "app-output.json"},
+		{"live/db", "apply", "db-apply.json"},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions := terragruntOptionsForTestWithEnv(t, util.JoinPath(RENDER_INPUTS_FIXTURE, testCase.module, DefaultTerragruntConfigPath), RENDER_INPUTS_ENV)
+		terragruntOptions.TerraformCliArgs = []string{testCase.command}
+
+		terragruntConfig, err := ReadTerragruntConfig(terragruntOptions)
+		require.NoError(t, err, "For %s %s", testCase.module, testCase.command)
+
+		inputs, err := ResolvedInputs(terragruntOptions, terragruntConfig)
+		require.NoError(t, err, "For %s %s", testCase.module, testCase.command)
+
+		actual, err := json.Marshal(inputs)
+		require.NoError(t, err)
+
+		expected, err := util.ReadFileAsString(util.JoinPath(RENDER_INPUTS_FIXTURE, "expected", testCase.expectedFile))
+		require.NoError(t, err)
+
+		assert.JSONEq(t, expected, string(actual), "For %s %s", testCase.module, testCase.command)
+	}
+}
+
+func TestResolvedInputsInvalidVarArgument(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, util.JoinPath(RENDER_INPUTS_FIXTURE, "live/db", DefaultTerragruntConfigPath))
+	terragruntOptions.TerraformCliArgs = []string{"plan"}
+
+	terragruntConfig := &TerragruntConfig{Terraform: &TerraformConfig{ExtraArgs: []TerraformExtraArguments{
+		{Name: "invalid", Commands: []string{"plan"}, Arguments: []string{"-var", "no-equals-sign"}},
+	}}}
+
+	_, err := ResolvedInputs(terragruntOptions, terragruntConfig)
+	if assert.Error(t, err) {
+		assert.IsType(t, InvalidVarArgument(""), errors.Unwrap(err))
+	}
+}
+
+func TestRedactSensitiveInputs(t *testing.T) {
+	t.Parallel()
+
+	inputs := map[string]interface{}{
+		"name":        "app",
+		"db_password": "hunter2",
+		"api_token":   "abc123",
+		"tags":        map[string]interface{}{"Team": "platform"},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"name":        "app",
+		"db_password": RedactedValue,
+		"api_token":   RedactedValue,
+		"tags":        map[string]interface{}{"Team": "platform"},
+	}, RedactSensitiveInputs(inputs, nil))
+
+	// Variables that match one of the given patterns are redacted too, on top of those that look like secrets
+	assert.Equal(t, map[string]interface{}{
+		"name":        RedactedValue,
+		"db_password": RedactedValue,
+		"api_token":   RedactedValue,
+		"tags":        map[string]interface{}{"Team": "platform"},
+	}, RedactSensitiveInputs(inputs, []*regexp.Regexp{regexp.MustCompile("^na"), regexp.MustCompile("^does_not_match$")}))
+
+	// The inputs themselves are left alone
+	assert.Equal(t, "hunter2", inputs["db_password"])
+}
+
+func TestParseVarValue(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    string
+		expected interface{}
+	}{
+		{"foo", "foo"},
+		{"3", "3"},
+		{"true", "true"},
+		{"", ""},
+		{`["a", "b"]`, []interface{}{"a", "b"}},
+		{`{ a = "b" }`, map[string]interface{}{"a": "b"}},
+		{`{ a = { b = "c" } }`, map[string]interface{}{"a": map[string]interface{}{"b": "c"}}},
+		{"[not valid", "[not valid"},
+		{"[1, /* two */ 2, 3]", []interface{}{1, 2, 3}},
+		{"{ a = /* inline */ \"b\" }", map[string]interface{}{"a": "b"}},
+		{"[1, /* not terminated 2, 3]", "[1, /* not terminated 2, 3]"},
+		// Like Terraform 0.11, HCL has no null, so it's just a string, and a list or map with null in it isn't valid
+		{"null", "null"},
+		{`["a", null]`, `["a", null]`},
+		{`{ a = null }`, `{ a = null }`},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, parseVarValue(testCase.value), "For value %s", testCase.value)
+	}
+}
+
+func TestSetInputsFromVarFileWithBlockComments(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "terragrunt-var-file-block-comments")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	varFile := filepath.Join(tmpDir, "terraform.tfvars")
+	contents := `
+/*
+ * Shared by all envs.
+ * Nested comments are not supported, as in Terraform.
+ */
+foo = [1, /* two */ 2, 3]
+
+tags = {
+  /* The team that owns
+     the module */
+  owner = "platform"
+  env   = /* overridden per env */ "dev"
+}
+`
+	require.NoError(t, ioutil.WriteFile(varFile, []byte(contents), 0644))
+
+	inputs := map[string]interface{}{}
+	require.NoError(t, setInputsFromVarFile(inputs, varFile))
+	assert.Equal(t, map[string]interface{}{
+		"foo":  []interface{}{1, 2, 3},
+		"tags": map[string]interface{}{"owner": "platform", "env": "dev"},
+	}, inputs)
+
+	unterminatedVarFile := filepath.Join(tmpDir, "unterminated.tfvars")
+	require.NoError(t, ioutil.WriteFile(unterminatedVarFile, []byte("foo = [1, /* two 2, 3]\n"), 0644))
+
+	err = setInputsFromVarFile(map[string]interface{}{}, unterminatedVarFile)
+	if assert.Error(t, err) {
+		assert.IsType(t, ErrorParsingVarFile{}, errors.Unwrap(err))
+	}
+}
+
+func TestSplitVarFlag(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		arg              string
+		expectedFlag     string
+		expectedValue    string
+		expectedHasValue bool
+	}{
+		{"-var", "var", "", false},
+		{"-var=name=value", "var", "name=value", true},
+		{"--var-file=foo.tfvars", "var-file", "foo.tfvars", true},
+		{"-no-color", "no-color", "", false},
+		{"name=value", "", "", false},
+	}
+
+	for _, testCase := range testCases {
+		flag, value, hasValue := splitVarFlag(testCase.arg)
+		assert.Equal(t, testCase.expectedFlag, flag, "For arg %s", testCase.arg)
+		assert.Equal(t, testCase.expectedValue, value, "For arg %s", testCase.arg)
+		assert.Equal(t, testCase.expectedHasValue, hasValue, "For arg %s", testCase.arg)
+	}
+}
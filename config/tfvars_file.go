@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl"
+)
+
+// The values of the tfvars files read by read_tfvars_file, keyed by the canonical path of the file, with the calls to
+// helper functions in them resolved, so each file is only read once, even if it's read by several configs of an
+// xxx-all run
+var tfVarsFileCache = map[string]map[string]interface{}{}
+var tfVarsFileCacheLock sync.Mutex
+
+// Return the value of the given key in the given tfvars file, such as the region in
+// ${read_tfvars_file("../common.tfvars", "region")}. A relative path is relative to the directory of the current
+// config. Calls to helper functions in the tfvars file are resolved as if it were a config itself, so they may read
+// other tfvars files too, as long as no file ends up reading itself. Both parameters may be calls to helper functions.
+func readTfVarsFile(parameters string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (interface{}, error) {
+	params, err := parseQuotedParams(parameters)
+	if err != nil || len(params) != 2 {
+		return "", errors.WithStackTrace(InvalidReadTfVarsFileParams(parameters))
+	}
+
+	resolvedParams := []string{}
+	for _, param := range params {
+		value, err := resolveDeferredParam(param, include, terragruntOptions)
+		if err != nil {
+			return "", err
+		}
+		resolvedParams = append(resolvedParams, fmt.Sprintf("%v", value))
+	}
+	path, key := resolvedParams[0], resolvedParams[1]
+
+	path, err = util.CanonicalPath(path, filepath.Dir(terragruntOptions.TerragruntConfigPath))
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	values, err := parseTfVarsFile(path, terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+
+	value, hasKey := values[key]
+	if !hasKey {
+		return "", errors.WithStackTrace(TfVarsKeyNotFound{Path: path, Key: key})
+	}
+
+	return helperValueFromTfVars(value, path, key)
+}
+
+// Read the tfvars file at the given canonical path, resolve the calls to helper functions in it, and return its top-level
+// values, as decoded by HCL. The file is read with options of its own, as if it were the config, so calls such as
+// get_tfvars_dir() are relative to the file, rather than to the config that reads it.
+func parseTfVarsFile(path string, terragruntOptions *options.TerragruntOptions) (map[string]interface{}, error) {
+	configPath, err := util.CanonicalPath(terragruntOptions.TerragruntConfigPath, ".")
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	filesBeingRead := append(util.CloneStringList(terragruntOptions.TfVarsFilesBeingRead), configPath)
+	if util.ListContainsElement(filesBeingRead, path) {
+		return nil, errors.WithStackTrace(TfVarsFileCycle(append(filesBeingRead, path)))
+	}
+
+	tfVarsFileCacheLock.Lock()
+	values, isCached := tfVarsFileCache[path]
+	tfVarsFileCacheLock.Unlock()
+	if isCached {
+		return values, nil
+	}
+
+	contents, err := util.ReadFileAsString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fileOptions := terragruntOptions.Clone(path)
+	fileOptions.TfVarsFilesBeingRead = filesBeingRead
+
+	// The lock isn't held while the calls in the file are resolved, as they may read other tfvars files
+	resolvedContents, err := ResolveTerragruntConfigString(contents, nil, fileOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	values = map[string]interface{}{}
+	if err := hcl.Decode(&values, resolvedContents); err != nil {
+		return nil, errors.WithStackTrace(ErrorParsingTfVarsFile{Path: path, Underlying: err})
+	}
+
+	tfVarsFileCacheLock.Lock()
+	defer tfVarsFileCacheLock.Unlock()
+	tfVarsFileCache[path] = values
+
+	return values, nil
+}
+
+// Convert the given value of a tfvars file, as decoded by HCL, into one that can be interpolated into the config:
+// strings, numbers, and booleans as is, lists of strings as a []string, lists of whole numbers as an []int, and maps
+// whose values are all strings, numbers, or booleans as a map[string]string. Other values, such as a list of maps, can't
+// be represented in the config.
+func helperValueFromTfVars(value interface{}, path string, key string) (interface{}, error) {
+	switch value := value.(type) {
+	case string, bool, int, float64:
+		return value, nil
+	case []interface{}:
+		return listFromTfVars(value, path, key)
+	case []map[string]interface{}:
+		// HCL decodes a map, such as tags = { Team = "infra" }, as a list with a single map in it
+		if len(value) != 1 {
+			return "", errors.WithStackTrace(UnsupportedTfVarsValue{Path: path, Key: key, Value: value})
+		}
+		stringMap := map[string]string{}
+		for mapKey, element := range value[0] {
+			switch element.(type) {
+			case string, bool, int, float64:
+				stringMap[mapKey] = fmt.Sprintf("%v", element)
+			default:
+				return "", errors.WithStackTrace(UnsupportedTfVarsValue{Path: path, Key: key, Value: value})
+			}
+		}
+		return stringMap, nil
+	default:
+		return "", errors.WithStackTrace(UnsupportedTfVarsValue{Path: path, Key: key, Value: value})
+	}
+}
+
+// Convert the given list from a tfvars file into an []int if all its elements are whole numbers, or into a []string if
+// all its elements are strings
+func listFromTfVars(value []interface{}, path string, key string) (interface{}, error) {
+	ints := []int{}
+	strs := []string{}
+	for _, element := range value {
+		switch element := element.(type) {
+		case int:
+			ints = append(ints, element)
+		case string:
+			strs = append(strs, element)
+		default:
+			return "", errors.WithStackTrace(UnsupportedTfVarsValue{Path: path, Key: key, Value: value})
+		}
+	}
+
+	switch {
+	case len(ints) == 0:
+		return strs, nil
+	case len(strs) == 0:
+		return ints, nil
+	default:
+		return "", errors.WithStackTrace(UnsupportedTfVarsValue{Path: path, Key: key, Value: value})
+	}
+}
+
+// Custom error types
+
+type InvalidReadTfVarsFileParams string
+
+func (err InvalidReadTfVarsFileParams) Error() string {
+	return fmt.Sprintf("Invalid parameters. Expected syntax of the form '${read_tfvars_file(\"path\", \"key\")}', but got '%s'", string(err))
+}
+
+type TfVarsKeyNotFound struct {
+	Path string
+	Key  string
+}
+
+func (err TfVarsKeyNotFound) Error() string {
+	return fmt.Sprintf("The tfvars file %s doesn't set %s.", err.Path, err.Key)
+}
+
+type TfVarsFileCycle []string
+
+func (err TfVarsFileCycle) Error() string {
+	return fmt.Sprintf("Found a cycle in the files read by read_tfvars_file: %s", strings.Join(err, " -> "))
+}
+
+type ErrorParsingTfVarsFile struct {
+	Path       string
+	Underlying error
+}
+
+func (err ErrorParsingTfVarsFile) Error() string {
+	return fmt.Sprintf("Error parsing the tfvars file %s: %v", err.Path, err.Underlying)
+}
+
+type UnsupportedTfVarsValue struct {
+	Path  string
+	Key   string
+	Value interface{}
+}
+
+func (err UnsupportedTfVarsValue) Error() string {
+	return fmt.Sprintf("The value of %s in the tfvars file %s is %v, which read_tfvars_file can't return. Only strings, numbers, booleans, lists of strings or of whole numbers, and maps of strings are supported.", err.Key, err.Path, err.Value)
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTfVarsFile(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "../test/fixture-read-tfvars-file/app/"+DefaultTerragruntConfigPath)
+
+	commonDir, err := util.CanonicalPath("../test/fixture-read-tfvars-file", ".")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		params      string
+		expected    interface{}
+		expectedErr error
+	}{
+		{`"../common.tfvars", "region"`, "us-east-1", nil},
+		{`"../common.tfvars", "availability_zones"`, []string{"us-east-1a", "us-east-1b"}, nil},
+		{`"../common.tfvars", "instance_counts"`, []int{1, 2, 3}, nil},
+		{`"../common.tfvars", "tags"`, map[string]string{"Team": "platform", "CostCenter": "1234"}, nil},
+		{`"../common.tfvars", "common_dir"`, commonDir, nil},
+		{`"../common.tfvars", "name"`, "app-default", nil},
+		{`"${get_parent_tfvars_dir()}/common.tfvars", "${string("region")}"`, "us-east-1", nil},
+		{`"../common.tfvars", "subnets"`, nil, UnsupportedTfVarsValue{}},
+		{`"../common.tfvars", "not_a_key"`, nil, TfVarsKeyNotFound{}},
+		{`"terraform.tfvars", "region"`, nil, TfVarsFileCycle{}},
+		{`"../cycle/a.tfvars", "value"`, nil, TfVarsFileCycle{}},
+		{`"../invalid/common.tfvars", "region"`, nil, ErrorParsingTfVarsFile{}},
+		{`"../common.tfvars"`, nil, InvalidReadTfVarsFileParams("")},
+		{``, nil, InvalidReadTfVarsFileParams("")},
+	}
+
+	for _, testCase := range testCases {
+		actual, actualErr := readTfVarsFile(testCase.params, nil, terragruntOptions)
+		if testCase.expectedErr != nil {
+			if assert.Error(t, actualErr, "For params %s", testCase.params) {
+				assert.IsType(t, testCase.expectedErr, errors.Unwrap(actualErr), "For params %s", testCase.params)
+			}
+		} else {
+			assert.Nil(t, actualErr, "For params %s, unexpected error: %v", testCase.params, actualErr)
+			assert.Equal(t, testCase.expected, actual, "For params %s", testCase.params)
+		}
+	}
+}
+
+func TestReadTfVarsFileCycleThroughConfig(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "../test/fixture-read-tfvars-file/cycle/"+DefaultTerragruntConfigPath)
+
+	_, err := ReadTerragruntConfig(terragruntOptions)
+	if assert.Error(t, err) {
+		cycle, isCycle := errors.Unwrap(err).(TfVarsFileCycle)
+		if assert.True(t, isCycle, "Unexpected error %v", err) {
+			assert.Len(t, cycle, 4)
+			assert.Equal(t, cycle[1], cycle[3])
+		}
+	}
+}
+
+func TestResolveReadTfVarsFileConfigString(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "../test/fixture-read-tfvars-file/app/"+DefaultTerragruntConfigPath)
+
+	actual, err := ResolveTerragruntConfigString(`tags = "${read_tfvars_file("../common.tfvars", "tags")}" zones = ["${read_tfvars_file("../common.tfvars", "availability_zones")}"]`, nil, terragruntOptions)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `tags = {"CostCenter" = "1234", "Team" = "platform"} zones = ["us-east-1a", "us-east-1b"]`, actual)
+	}
+}
@@ -29,6 +29,22 @@ type TerraformModule struct {
 }
 
 // Render this module as a human-readable string
+// Return the concurrency group of this module, or an empty string if it's not in one
+func (module *TerraformModule) ConcurrencyGroup() string {
+	if module.Config.Terraform == nil {
+		return ""
+	}
+	return module.Config.Terraform.ConcurrencyGroup
+}
+
+// Return the exit codes, other than 0, that don't count as a failure of this module during xxx-all commands
+func (module *TerraformModule) AllowedExitCodes() []int {
+	if module.Config.Terraform == nil {
+		return nil
+	}
+	return module.Config.Terraform.AllowedExitCodes
+}
+
 func (module *TerraformModule) String() string {
 	dependencies := []string{}
 	for _, dependency := range module.Dependencies {
@@ -205,24 +221,75 @@ func findModuleinPath(module *TerraformModule, targetDirs []string) bool {
 }
 
 // Go through each of the given Terragrunt configuration files and resolve the module that configuration file represents
-// into a TerraformModule struct. Note that this method will NOT fill in the Dependencies field of the TerraformModule
-// struct (see the crosslinkDependencies method for that). Return a map from module path to TerraformModule struct.
+// into a TerraformModule struct, leaving out the configurations that aren't runnable modules (see skipReason). Note
+// that this method will NOT fill in the Dependencies field of the TerraformModule struct (see the crosslinkDependencies
+// method for that). Return a map from module path to TerraformModule struct.
 func resolveModules(canonicalTerragruntConfigPaths []string, terragruntOptions *options.TerragruntOptions, howTheseModulesWereFound string) (map[string]*TerraformModule, error) {
 	moduleMap := map[string]*TerraformModule{}
 
+	modules := []*TerraformModule{}
+	includedConfigPaths := map[string]bool{}
 	for _, terragruntConfigPath := range canonicalTerragruntConfigPaths {
 		module, err := resolveTerraformModule(terragruntConfigPath, terragruntOptions, howTheseModulesWereFound)
 		if err != nil {
 			return moduleMap, err
 		}
-		if module != nil {
-			moduleMap[module.Path] = module
+		modules = append(modules, module)
+
+		if module.Config.IncludedConfigPath != "" {
+			includedConfigPath, err := util.CanonicalPath(module.Config.IncludedConfigPath, ".")
+			if err != nil {
+				return moduleMap, err
+			}
+			includedConfigPaths[includedConfigPath] = true
 		}
 	}
 
+	for _, module := range modules {
+		reason, err := skipReason(module, includedConfigPaths)
+		if err != nil {
+			return moduleMap, err
+		}
+		if reason != "" {
+			terragruntOptions.Logger.Printf("Module %s %s and will be skipped.", module.Path, reason)
+			continue
+		}
+		moduleMap[module.Path] = module
+	}
+
 	return moduleMap, nil
 }
 
+// Return why the given module is not a runnable module, or an empty string if it is one. In order of precedence:
+//
+// 1. A module whose config sets skip = true is never run.
+// 2. A module with Terraform code, a terraform source or .tf files, is run, even if other configs include its config.
+// 3. A module without Terraform code whose config other configs include is only a parent config, so it isn't run.
+// 4. Any other module without Terraform code isn't run either, as there's nothing to run.
+//
+// The given included config paths are the canonical paths of the configs that the configs found alongside this one
+// include.
+func skipReason(module *TerraformModule, includedConfigPaths map[string]bool) (string, error) {
+	if module.Config.Skip {
+		return "sets skip = true", nil
+	}
+
+	// Fix for https://github.com/gruntwork-io/terragrunt/issues/208
+	terragruntConfigPath := module.TerragruntOptions.TerragruntConfigPath
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(terragruntConfigPath), "*.tf"))
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	if (module.Config.Terraform != nil && module.Config.Terraform.Source != "") || matches != nil {
+		return "", nil
+	}
+
+	if includedConfigPaths[terragruntConfigPath] {
+		return "is included by other modules and does not have an associated terraform configuration of its own", nil
+	}
+	return "does not have an associated terraform configuration", nil
+}
+
 // Create a TerraformModule struct for the Terraform module specified by the given Terragrunt configuration file path.
 // Note that this method will NOT fill in the Dependencies field of the TerraformModule struct (see the
 // crosslinkDependencies method for that).
@@ -260,16 +327,6 @@ func resolveTerraformModule(terragruntConfigPath string, terragruntOptions *opti
 		opts.DownloadDir = downloadDir
 	}
 
-	// Fix for https://github.com/gruntwork-io/terragrunt/issues/208
-	matches, err := filepath.Glob(filepath.Join(filepath.Dir(terragruntConfigPath), "*.tf"))
-	if err != nil {
-		return nil, err
-	}
-	if (terragruntConfig.Terraform == nil || terragruntConfig.Terraform.Source == "") && matches == nil {
-		terragruntOptions.Logger.Printf("Module %s does not have an associated terraform configuration and will be skipped.", filepath.Dir(terragruntConfigPath))
-		return nil, nil
-	}
-
 	return &TerraformModule{Path: modulePath, Config: *terragruntConfig, TerragruntOptions: opts}, nil
 }
 
@@ -492,6 +549,10 @@ func (err UnrecognizedDependency) Error() string {
 	return fmt.Sprintf("Module %s specifies %s as a dependency, but that dependency was not one of the ones found while scanning subfolders: %v", err.ModulePath, err.DependencyPath, err.TerragruntConfigPaths)
 }
 
+func (err UnrecognizedDependency) Class() errors.ErrorClass {
+	return errors.ErrorClassDependencyGraph
+}
+
 type ErrorProcessingModule struct {
 	UnderlyingError       error
 	ModulePath            string
@@ -502,6 +563,10 @@ func (err ErrorProcessingModule) Error() string {
 	return fmt.Sprintf("Error processing module at '%s'. How this module was found: %s. Underlying error: %v", err.ModulePath, err.HowThisModuleWasFound, err.UnderlyingError)
 }
 
+func (err ErrorProcessingModule) Class() errors.ErrorClass {
+	return errors.ErrorClassConfig
+}
+
 type InvalidSourceUrl struct {
 	ModulePath       string
 	ModuleSourceUrl  string
@@ -528,3 +593,7 @@ type InfiniteRecursion struct {
 func (err InfiniteRecursion) Error() string {
 	return fmt.Sprintf("Hit what seems to be an infinite recursion after going %d levels deep. Please check for a circular dependency! Modules involved: %v", err.RecursionLevel, err.Modules)
 }
+
+func (err InfiniteRecursion) Class() errors.ErrorClass {
+	return errors.ErrorClassDependencyGraph
+}
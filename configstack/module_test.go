@@ -49,8 +49,9 @@ func TestResolveTerraformModulesOneModuleWithIncludesNoDependencies(t *testing.T
 		Path:         canonical(t, "../test/fixture-modules/module-b/module-b-child"),
 		Dependencies: []*TerraformModule{},
 		Config: config.TerragruntConfig{
-			RemoteState: state(t, "bucket", "module-b-child/terraform.tfstate"),
-			Terraform:   &config.TerraformConfig{Source: "..."},
+			RemoteState:        state(t, "bucket", "module-b-child/terraform.tfstate"),
+			Terraform:          &config.TerraformConfig{Source: "..."},
+			IncludedConfigPath: canonical(t, "../test/fixture-modules/module-b/"+config.DefaultTerragruntConfigPath),
 		},
 		TerragruntOptions: mockOptions.Clone(canonical(t, "../test/fixture-modules/module-b/module-b-child/"+config.DefaultTerragruntConfigPath)),
 	}
@@ -306,8 +307,9 @@ func TestResolveTerraformModulesMultipleModulesWithDependencies(t *testing.T) {
 		Path:         canonical(t, "../test/fixture-modules/module-b/module-b-child"),
 		Dependencies: []*TerraformModule{},
 		Config: config.TerragruntConfig{
-			RemoteState: state(t, "bucket", "module-b-child/terraform.tfstate"),
-			Terraform:   &config.TerraformConfig{Source: "..."},
+			RemoteState:        state(t, "bucket", "module-b-child/terraform.tfstate"),
+			Terraform:          &config.TerraformConfig{Source: "..."},
+			IncludedConfigPath: canonical(t, "../test/fixture-modules/module-b/"+config.DefaultTerragruntConfigPath),
 		},
 		TerragruntOptions: mockOptions.Clone(canonical(t, "../test/fixture-modules/module-b/module-b-child/"+config.DefaultTerragruntConfigPath)),
 	}
@@ -353,8 +355,9 @@ func TestResolveTerraformModulesMultipleModulesWithDependenciesWithIncludes(t *t
 		Path:         canonical(t, "../test/fixture-modules/module-b/module-b-child"),
 		Dependencies: []*TerraformModule{},
 		Config: config.TerragruntConfig{
-			RemoteState: state(t, "bucket", "module-b-child/terraform.tfstate"),
-			Terraform:   &config.TerraformConfig{Source: "..."},
+			RemoteState:        state(t, "bucket", "module-b-child/terraform.tfstate"),
+			Terraform:          &config.TerraformConfig{Source: "..."},
+			IncludedConfigPath: canonical(t, "../test/fixture-modules/module-b/"+config.DefaultTerragruntConfigPath),
 		},
 		TerragruntOptions: mockOptions.Clone(canonical(t, "../test/fixture-modules/module-b/module-b-child/"+config.DefaultTerragruntConfigPath)),
 	}
@@ -363,9 +366,10 @@ func TestResolveTerraformModulesMultipleModulesWithDependenciesWithIncludes(t *t
 		Path:         canonical(t, "../test/fixture-modules/module-e/module-e-child"),
 		Dependencies: []*TerraformModule{moduleA, moduleB},
 		Config: config.TerragruntConfig{
-			RemoteState:  state(t, "bucket", "module-e-child/terraform.tfstate"),
-			Dependencies: &config.ModuleDependencies{Paths: []string{"../../module-a", "../../module-b/module-b-child"}},
-			Terraform:    &config.TerraformConfig{Source: "test"},
+			RemoteState:        state(t, "bucket", "module-e-child/terraform.tfstate"),
+			Dependencies:       &config.ModuleDependencies{Paths: []string{"../../module-a", "../../module-b/module-b-child"}},
+			Terraform:          &config.TerraformConfig{Source: "test"},
+			IncludedConfigPath: canonical(t, "../test/fixture-modules/module-e/"+config.DefaultTerragruntConfigPath),
 		},
 		TerragruntOptions: mockOptions.Clone(canonical(t, "../test/fixture-modules/module-e/module-e-child/"+config.DefaultTerragruntConfigPath)),
 	}
@@ -0,0 +1,420 @@
+package configstack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/go-getter"
+)
+
+// The folder, within the download dir, where apply-all records the result of each module, so a failed run can be
+// resumed. Like config snapshots, these files are kept in the download dir, rather than in the working dir, so they
+// don't show up as untracked files in the repo the modules are in.
+const RESUME_STATE_DIR = "resume-states"
+
+// The value of --terragrunt-resume that resumes the last run recorded for the working dir (see ResumeStatePath)
+const RESUME_LAST = "last"
+
+// The version of the format of the resume state file. Bump this whenever the format of ResumeState changes in a
+// backwards incompatible way.
+const ResumeStateVersion = 1
+
+// ResumeState is the record of an apply-all run that --terragrunt-resume reads to figure out which modules it can skip
+type ResumeState struct {
+	// The version of the file format
+	Version int `json:"version"`
+
+	// The ID of the run these results are from (see TerragruntOptions.StackRunId)
+	RunId string `json:"run_id"`
+
+	// The ID of the run this run resumed, if any
+	ResumedRunId string `json:"resumed_run_id,omitempty"`
+
+	// The result of each module of the run, keyed by module path
+	Modules map[string]ResumeModuleResult `json:"modules"`
+}
+
+// The result of a single module in an apply-all run
+type ResumeModuleResult struct {
+	State ModuleState `json:"state"`
+
+	// A hash of everything that determines what applying the module does: its resolved config and inputs, and the
+	// contents of its folder and of its Terraform code, if that's in a local folder. Empty if it couldn't be computed.
+	Hash string `json:"hash"`
+
+	// The source of the Terraform code of the module, if any
+	Source string `json:"source"`
+
+	// The exit code the module finished with, if it's known and not 0. A module that succeeded may have a non-zero exit
+	// code, if its allowed_exit_codes setting allows it.
+	ExitCode int `json:"exit_code,omitempty"`
+}
+
+// resumeTracker records the result of each module of an apply-all run in the resume state file as the modules finish,
+// and, with --terragrunt-resume, decides which modules can be skipped because they succeeded in the run being resumed.
+// A nil resumeTracker records and skips nothing. It's safe for concurrent use.
+type resumeTracker struct {
+	mutex    sync.Mutex
+	path     string
+	current  ResumeState
+	previous *ResumeState
+	modules  map[string]ResumeModuleResult
+	resumed  map[string]bool
+}
+
+// Create a resumeTracker for applying the given stack. Each module's fingerprint is computed now, before anything is
+// applied, so that it reflects the config the module is applied with. If --terragrunt-resume is set, read the state of
+// the run to resume and figure out which modules to skip.
+func newResumeTracker(stack *Stack, terragruntOptions *options.TerragruntOptions) (*resumeTracker, error) {
+	path, err := ResumeStatePath(terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := &resumeTracker{
+		path: path,
+		current: ResumeState{
+			Version: ResumeStateVersion,
+			RunId:   terragruntOptions.StackRunId,
+			Modules: map[string]ResumeModuleResult{},
+		},
+		modules: map[string]ResumeModuleResult{},
+		resumed: map[string]bool{},
+	}
+
+	for _, module := range stack.Modules {
+		if module.FlagExcluded {
+			continue
+		}
+		hash, source, err := moduleFingerprint(module)
+		if err != nil {
+			terragruntOptions.Logger.Printf("WARNING: Unable to compute the fingerprint of module %s, so it will always be applied, even with --terragrunt-resume: %v", module.Path, err)
+		}
+		tracker.modules[module.Path] = ResumeModuleResult{Hash: hash, Source: source}
+	}
+
+	if terragruntOptions.Resume == "" {
+		return tracker, nil
+	}
+
+	resumePath := terragruntOptions.Resume
+	if resumePath == RESUME_LAST {
+		resumePath = tracker.path
+	} else if !filepath.IsAbs(resumePath) {
+		resumePath = util.JoinPath(terragruntOptions.WorkingDir, resumePath)
+	}
+
+	previous, err := ReadResumeState(resumePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker.previous = previous
+	tracker.current.ResumedRunId = previous.RunId
+	tracker.resumed = resumableModules(stack.Modules, previous, tracker.modules)
+
+	return tracker, nil
+}
+
+// Return the path of the file apply-all records the results of a run in the working dir of the given options in. The
+// download dir may be shared by the runs of several working dirs, so the file name is derived from the canonical path
+// of the working dir.
+func ResumeStatePath(terragruntOptions *options.TerragruntOptions) (string, error) {
+	canonicalWorkingDir, err := util.CanonicalPath(terragruntOptions.WorkingDir, "")
+	if err != nil {
+		return "", err
+	}
+
+	fileName := fmt.Sprintf("%s.json", util.EncodeBase64Sha1(canonicalWorkingDir))
+	return util.JoinPath(filepath.ToSlash(terragruntOptions.DownloadDir), RESUME_STATE_DIR, fileName), nil
+}
+
+// Return the paths of the given modules that can be skipped when resuming the given run. A module can only be skipped
+// if it succeeded in that run, its fingerprint (see moduleFingerprint) is unchanged, and none of its dependencies will
+// be applied again in this run, as that might change their outputs.
+func resumableModules(modules []*TerraformModule, previous *ResumeState, fingerprints map[string]ResumeModuleResult) map[string]bool {
+	resumable := map[string]bool{}
+	visited := map[string]bool{}
+
+	var canSkip func(module *TerraformModule) bool
+	canSkip = func(module *TerraformModule) bool {
+		if visited[module.Path] {
+			return resumable[module.Path]
+		}
+		visited[module.Path] = true
+
+		previousResult, hasPreviousResult := previous.Modules[module.Path]
+		fingerprint := fingerprints[module.Path]
+		if !hasPreviousResult || previousResult.State != ModuleSucceeded || fingerprint.Hash == "" || previousResult.Hash != fingerprint.Hash || previousResult.Source != fingerprint.Source {
+			return false
+		}
+
+		for _, dependency := range module.Dependencies {
+			// Modules that aren't applied as part of this run don't change in the meantime
+			if dependency.AssumeAlreadyApplied || dependency.FlagExcluded {
+				continue
+			}
+			if !canSkip(dependency) {
+				return false
+			}
+		}
+
+		resumable[module.Path] = true
+		return true
+	}
+
+	for _, module := range modules {
+		if !module.AssumeAlreadyApplied && !module.FlagExcluded {
+			canSkip(module)
+		}
+	}
+
+	return resumable
+}
+
+// Return true if the given module succeeded in the run being resumed and nothing that affects it has changed since, so
+// it doesn't need to be applied again
+func (tracker *resumeTracker) skips(module *TerraformModule) bool {
+	if tracker == nil {
+		return false
+	}
+	return tracker.resumed[module.Path]
+}
+
+// Record that the given module finished in the given state, and write the results of the run so far to the resume
+// state file. Modules that were skipped because they succeeded in the run being resumed are recorded as succeeded, so
+// the run can be resumed again. Failing to write the file is only logged, as it shouldn't fail the run itself.
+func (tracker *resumeTracker) recordResult(module *TerraformModule, state ModuleState, exitCode int) {
+	if tracker == nil {
+		return
+	}
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	result := tracker.modules[module.Path]
+	result.State = state
+	result.ExitCode = exitCode
+	if tracker.resumed[module.Path] {
+		result.State = ModuleSucceeded
+	}
+	tracker.current.Modules[module.Path] = result
+
+	if err := WriteResumeState(&tracker.current, tracker.path); err != nil {
+		module.TerragruntOptions.Logger.Printf("WARNING: Unable to record the result of module %s in %s, so this run may not be resumable: %v", module.Path, tracker.path, err)
+	}
+}
+
+// Return the sorted paths of the modules that were skipped because they succeeded in the run being resumed
+func (tracker *resumeTracker) resumedModules() []string {
+	if tracker == nil {
+		return []string{}
+	}
+
+	paths := []string{}
+	for path := range tracker.resumed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Log which modules were skipped because of --terragrunt-resume, and, if the run failed, how to resume it
+func (tracker *resumeTracker) logSummary(terragruntOptions *options.TerragruntOptions, runErr error) {
+	if tracker == nil {
+		return
+	}
+
+	if tracker.previous != nil {
+		resumedModules := tracker.resumedModules()
+		if len(resumedModules) == 0 {
+			terragruntOptions.Logger.Printf("Resumed run %s, but no modules could be skipped, as none of them succeeded in that run with the same config, source, and dependencies", tracker.previous.RunId)
+		} else {
+			terragruntOptions.Logger.Printf("Resumed run %s. Skipped %d module(s) that succeeded in that run and whose config, source, and dependencies are unchanged:\n  %s", tracker.previous.RunId, len(resumedModules), strings.Join(resumedModules, "\n  "))
+		}
+	}
+
+	if runErr != nil {
+		terragruntOptions.Logger.Printf("The result of each module was recorded in %s. To apply only the modules that didn't succeed, or that changed since, run apply-all again with --terragrunt-resume %s.", tracker.path, RESUME_LAST)
+	}
+}
+
+// Return a hash of everything that determines what applying the given module does, along with the source of its
+// Terraform code. The hash covers the resolved config, the variables Terraform receives, and the contents of the
+// module's folder and of the folder its Terraform code is in, if that's local. Remote sources are only compared by
+// URL, so they should be pinned to a specific version for --terragrunt-resume to be safe.
+func moduleFingerprint(module *TerraformModule) (string, string, error) {
+	source := module.TerragruntOptions.Source
+	if source == "" && module.Config.Terraform != nil {
+		source = module.Config.Terraform.Source
+	}
+
+	snapshot, err := config.NewConfigSnapshot(&module.Config, module.TerragruntOptions.SensitiveVarPatterns)
+	if err != nil {
+		return "", source, err
+	}
+
+	inputs, err := config.ResolvedInputs(module.TerragruntOptions, &module.Config)
+	if err != nil {
+		return "", source, err
+	}
+	inputsJson, err := json.Marshal(inputs)
+	if err != nil {
+		return "", source, errors.WithStackTrace(err)
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "config=%s\ninputs=%s\nsource=%s\n", snapshot.Hash, inputsJson, source)
+
+	folders := []string{module.Path}
+	if localSource := localSourceFolder(source, module.Path); localSource != "" {
+		folders = append(folders, localSource)
+	}
+	for _, folder := range folders {
+		if err := hashFolderContents(hash, folder); err != nil {
+			return "", source, err
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), source, nil
+}
+
+// Return the folder the given Terraform source is in, if it's a local path, without any subdirectory (e.g.
+// ../modules//app is in ../modules), as modules may refer to other modules in the same folder. Otherwise, return an
+// empty string.
+func localSourceFolder(source string, modulePath string) string {
+	if source == "" {
+		return ""
+	}
+
+	detected, err := getter.Detect(source, modulePath, getter.Detectors)
+	if err != nil || !strings.HasPrefix(detected, "file://") {
+		return ""
+	}
+
+	folder, _ := getter.SourceDirSubdir(strings.TrimPrefix(detected, "file://"))
+	if !util.IsDir(folder) {
+		return ""
+	}
+	return folder
+}
+
+// Write the relative path and contents of each file in the given folder and its subfolders to the given hash. Hidden
+// files and folders, such as the Terragrunt and Terraform caches, and local Terraform state files are left out, as
+// they change whenever the module is applied.
+func hashFolderContents(hash io.Writer, folder string) error {
+	return filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+
+		if path != folder && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || strings.HasSuffix(info.Name(), ".tfstate") || strings.HasSuffix(info.Name(), ".tfstate.backup") {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(folder, path)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+
+		fileHash := sha256.Sum256(contents)
+		fmt.Fprintf(hash, "%s=%s\n", filepath.ToSlash(relativePath), hex.EncodeToString(fileHash[:]))
+		return nil
+	})
+}
+
+// Read the resume state stored at the given path
+func ReadResumeState(path string) (*ResumeState, error) {
+	if !util.FileExists(path) {
+		return nil, errors.WithStackTrace(ResumeStateNotFound(path))
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	state := &ResumeState{}
+	if err := json.Unmarshal(contents, state); err != nil {
+		return nil, errors.WithStackTrace(CorruptResumeState{Path: path, Underlying: err})
+	}
+
+	if state.Version != ResumeStateVersion {
+		return nil, errors.WithStackTrace(UnsupportedResumeStateVersion{Path: path, Version: state.Version})
+	}
+
+	if state.Modules == nil {
+		state.Modules = map[string]ResumeModuleResult{}
+	}
+
+	return state, nil
+}
+
+// Write the given resume state to the given path. The state is written to a temp file that is then renamed, so the
+// file is never left half written if Terragrunt is interrupted.
+func WriteResumeState(state *ResumeState, path string) error {
+	contents, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, contents, 0644); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	return errors.WithStackTrace(os.Rename(tmpPath, path))
+}
+
+// Custom error types
+
+type ResumeStateNotFound string
+
+func (path ResumeStateNotFound) Error() string {
+	return fmt.Sprintf("Cannot resume the run recorded in %s, as that file does not exist. Note that only apply-all records its runs.", string(path))
+}
+
+type CorruptResumeState struct {
+	Path       string
+	Underlying error
+}
+
+func (err CorruptResumeState) Error() string {
+	return fmt.Sprintf("Unable to parse the resume state at %s: %v", err.Path, err.Underlying)
+}
+
+type UnsupportedResumeStateVersion struct {
+	Path    string
+	Version int
+}
+
+func (err UnsupportedResumeStateVersion) Error() string {
+	return fmt.Sprintf("Resume state at %s uses format version %d, but this version of Terragrunt only supports version %d", err.Path, err.Version, ResumeStateVersion)
+}
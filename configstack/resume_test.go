@@ -0,0 +1,269 @@
+package configstack
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyResumesFailedRun(t *testing.T) {
+	t.Parallel()
+
+	rootDir, err := ioutil.TempDir("", "terragrunt-resume-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	// vpc <- app <- frontend, and db, which fails in the first run
+	for _, name := range []string{"vpc", "app", "frontend", "db"} {
+		writeResumeTestFile(t, util.JoinPath(rootDir, name, "terraform.tfvars"), fmt.Sprintf("name = \"%s\"\n", name))
+	}
+
+	// The first run fails in db, and records the result of every module
+	ran := newModulesRan()
+	err = newResumeTestStack(t, rootDir, ran, "db").Apply(resumeTestOptions(t, rootDir, "run-1", ""))
+	assert.Error(t, err)
+	assert.Equal(t, []string{"app", "db", "frontend", "vpc"}, ran.names(rootDir))
+
+	state, err := ReadResumeState(resumeTestStatePath(t, rootDir))
+	require.NoError(t, err)
+	assert.Equal(t, "run-1", state.RunId)
+	assert.Equal(t, ModuleSucceeded, state.Modules[util.JoinPath(rootDir, "app")].State)
+	assert.Equal(t, ModuleFailed, state.Modules[util.JoinPath(rootDir, "db")].State)
+
+	// The result is recorded in the download dir, not in the working dir, so it doesn't end up in the repo
+	assert.False(t, util.FileExists(util.JoinPath(rootDir, ".terragrunt-resume.json")))
+	assert.True(t, strings.HasPrefix(resumeTestStatePath(t, rootDir), util.JoinPath(rootDir, options.TerragruntCacheDir)+"/"))
+
+	// Before resuming, the config of app changes, so it and frontend, which depends on it, have to run again, as does db,
+	// which failed. Only vpc can be skipped.
+	writeResumeTestFile(t, util.JoinPath(rootDir, "app", "terraform.tfvars"), "name = \"app\"\ninstance_type = \"t3.large\"\n")
+
+	ran = newModulesRan()
+	err = newResumeTestStack(t, rootDir, ran).Apply(resumeTestOptions(t, rootDir, "run-2", RESUME_LAST))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app", "db", "frontend"}, ran.names(rootDir))
+
+	state, err = ReadResumeState(resumeTestStatePath(t, rootDir))
+	require.NoError(t, err)
+	assert.Equal(t, "run-2", state.RunId)
+	assert.Equal(t, "run-1", state.ResumedRunId)
+	for _, name := range []string{"vpc", "app", "frontend", "db"} {
+		assert.Equal(t, ModuleSucceeded, state.Modules[util.JoinPath(rootDir, name)].State, "For module %s", name)
+	}
+
+	// Nothing changed since the second run, so resuming it skips every module
+	ran = newModulesRan()
+	err = newResumeTestStack(t, rootDir, ran).Apply(resumeTestOptions(t, rootDir, "run-3", RESUME_LAST))
+	assert.NoError(t, err)
+	assert.Empty(t, ran.names(rootDir))
+}
+
+func TestApplyRecordsAllowedExitCode(t *testing.T) {
+	t.Parallel()
+
+	rootDir, err := ioutil.TempDir("", "terragrunt-resume-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	writeResumeTestFile(t, util.JoinPath(rootDir, "script", "terraform.tfvars"), "name = \"script\"\n")
+
+	ran := newModulesRan()
+	script := newResumeTestModule(t, rootDir, "script", ran, fakeCommandError(2))
+	script.Config.Terraform = &config.TerraformConfig{AllowedExitCodes: []int{2}}
+	stack := &Stack{Path: rootDir, Modules: []*TerraformModule{script}}
+
+	require.NoError(t, stack.Apply(resumeTestOptions(t, rootDir, "run-1", "")))
+
+	state, err := ReadResumeState(resumeTestStatePath(t, rootDir))
+	require.NoError(t, err)
+	assert.Equal(t, ModuleSucceeded, state.Modules[util.JoinPath(rootDir, "script")].State)
+	assert.Equal(t, 2, state.Modules[util.JoinPath(rootDir, "script")].ExitCode)
+}
+
+func TestApplyResumeStateNotFound(t *testing.T) {
+	t.Parallel()
+
+	rootDir, err := ioutil.TempDir("", "terragrunt-resume-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	writeResumeTestFile(t, util.JoinPath(rootDir, "vpc", "terraform.tfvars"), "name = \"vpc\"\n")
+
+	ran := newModulesRan()
+	stack := &Stack{Path: rootDir, Modules: []*TerraformModule{newResumeTestModule(t, rootDir, "vpc", ran, nil)}}
+
+	err = stack.Apply(resumeTestOptions(t, rootDir, "run-1", "does-not-exist.json"))
+	if assert.Error(t, err) {
+		assert.IsType(t, ResumeStateNotFound(""), errors.Unwrap(err))
+	}
+	assert.Empty(t, ran.names(rootDir))
+}
+
+func TestResumableModules(t *testing.T) {
+	t.Parallel()
+
+	vpc := &TerraformModule{Path: "vpc"}
+	app := &TerraformModule{Path: "app", Dependencies: []*TerraformModule{vpc}}
+	excluded := &TerraformModule{Path: "excluded", FlagExcluded: true}
+	frontend := &TerraformModule{Path: "frontend", Dependencies: []*TerraformModule{app, excluded}}
+	modules := []*TerraformModule{vpc, app, frontend, excluded}
+
+	succeeded := func(hash string) ResumeModuleResult {
+		return ResumeModuleResult{State: ModuleSucceeded, Hash: hash, Source: "../modules//app"}
+	}
+	fingerprints := map[string]ResumeModuleResult{
+		"vpc":      {Hash: "vpc-hash", Source: "../modules//app"},
+		"app":      {Hash: "app-hash", Source: "../modules//app"},
+		"frontend": {Hash: "frontend-hash", Source: "../modules//app"},
+	}
+
+	testCases := []struct {
+		name     string
+		previous map[string]ResumeModuleResult
+		expected []string
+	}{
+		{"all succeeded", map[string]ResumeModuleResult{"vpc": succeeded("vpc-hash"), "app": succeeded("app-hash"), "frontend": succeeded("frontend-hash")}, []string{"app", "frontend", "vpc"}},
+		{"dependency changed", map[string]ResumeModuleResult{"vpc": succeeded("old-hash"), "app": succeeded("app-hash"), "frontend": succeeded("frontend-hash")}, []string{}},
+		{"dependent changed", map[string]ResumeModuleResult{"vpc": succeeded("vpc-hash"), "app": succeeded("app-hash"), "frontend": succeeded("old-hash")}, []string{"app", "vpc"}},
+		{"dependency failed", map[string]ResumeModuleResult{"vpc": succeeded("vpc-hash"), "app": {State: ModuleFailed, Hash: "app-hash", Source: "../modules//app"}, "frontend": succeeded("frontend-hash")}, []string{"vpc"}},
+		{"source changed", map[string]ResumeModuleResult{"vpc": succeeded("vpc-hash"), "app": {State: ModuleSucceeded, Hash: "app-hash", Source: "../modules//old"}, "frontend": succeeded("frontend-hash")}, []string{"vpc"}},
+		{"module not in previous run", map[string]ResumeModuleResult{"vpc": succeeded("vpc-hash"), "app": succeeded("app-hash")}, []string{"app", "vpc"}},
+	}
+
+	for _, testCase := range testCases {
+		actual := resumableModules(modules, &ResumeState{Modules: testCase.previous}, fingerprints)
+
+		actualPaths := []string{}
+		for path := range actual {
+			actualPaths = append(actualPaths, path)
+		}
+		sort.Strings(actualPaths)
+
+		assert.Equal(t, testCase.expected, actualPaths, "For %s", testCase.name)
+	}
+}
+
+func TestReadResumeStateInvalid(t *testing.T) {
+	t.Parallel()
+
+	tmpFile, err := ioutil.TempFile("", "terragrunt-resume-test")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	testCases := []struct {
+		contents      string
+		expectedError error
+	}{
+		{"not json", CorruptResumeState{}},
+		{`{"version": 99, "modules": {}}`, UnsupportedResumeStateVersion{}},
+	}
+
+	for _, testCase := range testCases {
+		require.NoError(t, ioutil.WriteFile(tmpFile.Name(), []byte(testCase.contents), 0644))
+
+		_, err := ReadResumeState(tmpFile.Name())
+		if assert.Error(t, err, "For contents %s", testCase.contents) {
+			assert.IsType(t, testCase.expectedError, errors.Unwrap(err), "For contents %s", testCase.contents)
+		}
+	}
+}
+
+// Records the config paths of the modules that ran, as RunTerragrunt is called concurrently
+type modulesRan struct {
+	mutex sync.Mutex
+	paths []string
+}
+
+func newModulesRan() *modulesRan {
+	return &modulesRan{paths: []string{}}
+}
+
+func (ran *modulesRan) add(path string) {
+	ran.mutex.Lock()
+	defer ran.mutex.Unlock()
+	ran.paths = append(ran.paths, path)
+}
+
+// Return the paths of the modules that ran, in the order they started in
+func (ran *modulesRan) ordered() []string {
+	ran.mutex.Lock()
+	defer ran.mutex.Unlock()
+	return append([]string{}, ran.paths...)
+}
+
+// Return the sorted names of the module folders in the given folder that ran
+func (ran *modulesRan) names(rootDir string) []string {
+	ran.mutex.Lock()
+	defer ran.mutex.Unlock()
+
+	names := []string{}
+	for _, path := range ran.paths {
+		name, err := util.GetPathRelativeTo(path, rootDir)
+		if err != nil {
+			name = path
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Create a stack with modules vpc, app (which depends on vpc), frontend (which depends on app), and db in the given
+// folder, whose RunTerragrunt records that they ran in the given modulesRan. The modules with the given names fail.
+func newResumeTestStack(t *testing.T, rootDir string, ran *modulesRan, failing ...string) *Stack {
+	moduleErrors := map[string]error{}
+	for _, name := range failing {
+		moduleErrors[name] = fmt.Errorf("Failed to apply %s", name)
+	}
+
+	vpc := newResumeTestModule(t, rootDir, "vpc", ran, moduleErrors["vpc"])
+	app := newResumeTestModule(t, rootDir, "app", ran, moduleErrors["app"], vpc)
+	frontend := newResumeTestModule(t, rootDir, "frontend", ran, moduleErrors["frontend"], app)
+	db := newResumeTestModule(t, rootDir, "db", ran, moduleErrors["db"])
+
+	return &Stack{Path: rootDir, Modules: []*TerraformModule{vpc, app, frontend, db}}
+}
+
+func newResumeTestModule(t *testing.T, rootDir string, name string, ran *modulesRan, toReturn error, dependencies ...*TerraformModule) *TerraformModule {
+	path := util.JoinPath(rootDir, name)
+
+	opts, err := options.NewTerragruntOptionsForTest(util.JoinPath(path, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	opts.RunTerragrunt = func(_ *options.TerragruntOptions) error {
+		ran.add(path)
+		return toReturn
+	}
+
+	return &TerraformModule{Path: path, Dependencies: dependencies, Config: config.TerragruntConfig{}, TerragruntOptions: opts}
+}
+
+func resumeTestOptions(t *testing.T, rootDir string, runId string, resume string) *options.TerragruntOptions {
+	opts, err := options.NewTerragruntOptionsForTest(util.JoinPath(rootDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	opts.StackRunId = runId
+	opts.Resume = resume
+	return opts
+}
+
+// Return the path of the resume state file of the runs of the stack in the given folder
+func resumeTestStatePath(t *testing.T, rootDir string) string {
+	path, err := ResumeStatePath(resumeTestOptions(t, rootDir, "", ""))
+	require.NoError(t, err)
+	return path
+}
+
+func writeResumeTestFile(t *testing.T, path string, contents string) {
+	require.NoError(t, os.MkdirAll(util.JoinPath(path, ".."), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+}
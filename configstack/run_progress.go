@@ -0,0 +1,262 @@
+package configstack
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The state of a single module during an xxx-all run, as reported by RunProgress
+type ModuleState string
+
+const (
+	ModulePending   ModuleState = "pending"
+	ModuleRunning   ModuleState = "running"
+	ModuleSucceeded ModuleState = "succeeded"
+	ModuleFailed    ModuleState = "failed"
+	ModuleSkipped   ModuleState = "skipped"
+)
+
+// How many of the most recent lines of output to keep for each module
+const MAX_LOG_LINES_PER_MODULE = 20
+
+// RunProgress tracks the state of each module during an xxx-all run. It's the single source of truth for both the
+// progress messages in the log and the status endpoint (see StatusServer), so the two can never disagree. It's safe
+// for concurrent use.
+type RunProgress struct {
+	mutex           sync.Mutex
+	startedAt       time.Time
+	now             func() time.Time
+	captureOutput   bool
+	dependencyOrder DependencyOrder
+	modules         map[string]*moduleProgress
+}
+
+type moduleProgress struct {
+	state       ModuleState
+	group       int
+	exitCode    int
+	logLines    []string
+	partialLine string
+}
+
+// A point in time report of a RunProgress. This is what the status endpoint returns as JSON.
+type RunProgressReport struct {
+	// The order the modules run in: "normal", where each module runs after the modules it depends on, or "reverse",
+	// as in destroy-all, where each module runs after the modules that depend on it
+	DependencyOrder string `json:"dependency_order"`
+
+	// The lowest group that still has modules that are pending or running, or -1 if all modules are done. Modules in
+	// group 0 don't wait for any other module, modules in group 1 only wait for modules in group 0, and so on. In
+	// reverse order, group 0 is the modules no other module depends on.
+	CurrentGroup   int                    `json:"current_group"`
+	ElapsedSeconds float64                `json:"elapsed_seconds"`
+	Counts         map[ModuleState]int    `json:"counts"`
+	Modules        []ModuleProgressReport `json:"modules"`
+}
+
+type ModuleProgressReport struct {
+	Path         string      `json:"path"`
+	State        ModuleState `json:"state"`
+	Group        int         `json:"group"`
+	LastLogLines []string    `json:"last_log_lines,omitempty"`
+
+	// The exit code the module finished with, if it's known and not 0. A module that succeeded may have a non-zero exit
+	// code, if its allowed_exit_codes setting allows it.
+	ExitCode int `json:"exit_code,omitempty"`
+}
+
+// Create a RunProgress for the given modules, with all of them pending. The group of each module is computed from the
+// modules it waits for, in the order they were cross-linked in (see crossLinkDependencies), so the groups match the
+// order the modules actually run in. This has to happen before the run starts, as each module removes the modules it
+// waits for from its Dependencies as they finish.
+func newRunProgress(modules map[string]*runningModule) *RunProgress {
+	progress := &RunProgress{
+		startedAt: time.Now(),
+		now:       time.Now,
+		modules:   map[string]*moduleProgress{},
+	}
+
+	groups := map[string]int{}
+	for path, module := range modules {
+		progress.modules[path] = &moduleProgress{state: ModulePending, group: dependencyGroup(module, groups, map[string]bool{})}
+		progress.dependencyOrder = module.DependencyOrder
+	}
+
+	return progress
+}
+
+// Return the group of the given module: 0 if it doesn't wait for any other module, or one more than the highest group
+// of the modules it waits for otherwise
+func dependencyGroup(module *runningModule, groups map[string]int, visiting map[string]bool) int {
+	path := module.Module.Path
+	if group, alreadyComputed := groups[path]; alreadyComputed {
+		return group
+	}
+	// Cycles are detected and reported when the stack is created, so this should never happen, but don't recurse
+	// forever if it does
+	if visiting[path] {
+		return 0
+	}
+	visiting[path] = true
+
+	group := 0
+	for _, dependency := range module.Dependencies {
+		if dependencyGroup := dependencyGroup(dependency, groups, visiting) + 1; dependencyGroup > group {
+			group = dependencyGroup
+		}
+	}
+
+	groups[path] = group
+	return group
+}
+
+// Record the new state of the module at the given path
+func (progress *RunProgress) setState(path string, state ModuleState) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	if module, exists := progress.modules[path]; exists {
+		module.state = state
+	}
+}
+
+// Record the exit code the module at the given path finished with
+func (progress *RunProgress) setExitCode(path string, exitCode int) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	if module, exists := progress.modules[path]; exists {
+		module.exitCode = exitCode
+	}
+}
+
+// Record output written by the module at the given path, keeping only the last MAX_LOG_LINES_PER_MODULE lines
+func (progress *RunProgress) appendOutput(path string, output []byte) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	module, exists := progress.modules[path]
+	if !exists {
+		return
+	}
+
+	lines := strings.Split(module.partialLine+string(output), "\n")
+	module.partialLine = lines[len(lines)-1]
+	module.logLines = append(module.logLines, lines[:len(lines)-1]...)
+	if len(module.logLines) > MAX_LOG_LINES_PER_MODULE {
+		module.logLines = module.logLines[len(module.logLines)-MAX_LOG_LINES_PER_MODULE:]
+	}
+}
+
+// Return the options to run the module at the given path with. If output capturing is enabled, the output of the module,
+// including log messages, is recorded in addition to being written to where it would normally go.
+func (progress *RunProgress) optionsForModule(path string, terragruntOptions *options.TerragruntOptions) *options.TerragruntOptions {
+	if !progress.captureOutput {
+		return terragruntOptions
+	}
+
+	captureOptions := terragruntOptions.Clone(terragruntOptions.TerragruntConfigPath)
+	captureOptions.WorkingDir = terragruntOptions.WorkingDir
+	captureOptions.Writer = &moduleOutputWriter{progress: progress, path: path, writer: terragruntOptions.Writer}
+	captureOptions.ErrWriter = &moduleOutputWriter{progress: progress, path: path, writer: terragruntOptions.ErrWriter}
+	captureOptions.Logger = util.CreateLoggerWithWriter(captureOptions.ErrWriter, captureOptions.WorkingDir)
+	return captureOptions
+}
+
+// Return a report of the current state of all modules
+func (progress *RunProgress) Report() RunProgressReport {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	report := RunProgressReport{
+		DependencyOrder: progress.dependencyOrder.String(),
+		CurrentGroup:    -1,
+		ElapsedSeconds:  progress.now().Sub(progress.startedAt).Seconds(),
+		Counts:          map[ModuleState]int{ModulePending: 0, ModuleRunning: 0, ModuleSucceeded: 0, ModuleFailed: 0, ModuleSkipped: 0},
+		Modules:         []ModuleProgressReport{},
+	}
+
+	for path, module := range progress.modules {
+		moduleReport := ModuleProgressReport{Path: path, State: module.state, Group: module.group, ExitCode: module.exitCode}
+		if module.state == ModuleRunning {
+			moduleReport.LastLogLines = append([]string{}, module.logLines...)
+		}
+		report.Modules = append(report.Modules, moduleReport)
+
+		report.Counts[module.state]++
+		if (module.state == ModulePending || module.state == ModuleRunning) && (report.CurrentGroup == -1 || module.group < report.CurrentGroup) {
+			report.CurrentGroup = module.group
+		}
+	}
+
+	sort.Slice(report.Modules, func(i, j int) bool { return report.Modules[i].Path < report.Modules[j].Path })
+
+	return report
+}
+
+// Return the paths of the modules in each group, in the order the groups run in, with the paths in each group sorted
+func (progress *RunProgress) Groups() [][]string {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	groups := [][]string{}
+	for path, module := range progress.modules {
+		for len(groups) <= module.group {
+			groups = append(groups, []string{})
+		}
+		groups[module.group] = append(groups[module.group], path)
+	}
+
+	for _, group := range groups {
+		sort.Strings(group)
+	}
+
+	return groups
+}
+
+// Return a description of the order the modules run in, with a line listing the modules of each group, so the log shows
+// the same groups as the status endpoint, in the direction the modules actually run in
+func (progress *RunProgress) GroupsSummary() string {
+	groups := progress.Groups()
+
+	explanation := "each module runs once the modules it depends on are done"
+	if progress.dependencyOrder == ReverseOrder {
+		explanation = "each module runs once the modules that depend on it are done"
+	}
+
+	lines := []string{fmt.Sprintf("Running %d modules in %d groups, in %s dependency order, so %s:", len(progress.modules), len(groups), progress.dependencyOrder, explanation)}
+	for index, group := range groups {
+		lines = append(lines, fmt.Sprintf("  Group %d: %s", index, strings.Join(group, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Return a one line summary of the report, such as "3 of 5 modules done (2 succeeded, 1 failed, 0 skipped), 1 running,
+// 1 pending"
+func (report RunProgressReport) Summary() string {
+	done := report.Counts[ModuleSucceeded] + report.Counts[ModuleFailed] + report.Counts[ModuleSkipped]
+	return fmt.Sprintf("%d of %d modules done (%d succeeded, %d failed, %d skipped), %d running, %d pending", done, len(report.Modules), report.Counts[ModuleSucceeded], report.Counts[ModuleFailed], report.Counts[ModuleSkipped], report.Counts[ModuleRunning], report.Counts[ModulePending])
+}
+
+// An io.Writer that records everything written to it as the output of a module, before passing it on to the given
+// writer
+type moduleOutputWriter struct {
+	progress *RunProgress
+	path     string
+	writer   io.Writer
+}
+
+func (writer *moduleOutputWriter) Write(p []byte) (int, error) {
+	writer.progress.appendOutput(writer.path, p)
+	if writer.writer == nil {
+		return len(p), nil
+	}
+	return writer.writer.Write(p)
+}
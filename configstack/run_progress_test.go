@@ -0,0 +1,171 @@
+package configstack
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunProgressGroupsAndStates(t *testing.T) {
+	t.Parallel()
+
+	aRan, bRan, cRan, dRan := false, false, false, false
+	moduleA := &TerraformModule{Path: "a", Config: config.TerragruntConfig{}, TerragruntOptions: optionsWithMockTerragruntCommand(t, "a", nil, &aRan)}
+	moduleB := &TerraformModule{Path: "b", Dependencies: []*TerraformModule{moduleA}, Config: config.TerragruntConfig{}, TerragruntOptions: optionsWithMockTerragruntCommand(t, "b", nil, &bRan)}
+	moduleC := &TerraformModule{Path: "c", Dependencies: []*TerraformModule{moduleA, moduleB}, Config: config.TerragruntConfig{}, TerragruntOptions: optionsWithMockTerragruntCommand(t, "c", nil, &cRan)}
+	moduleD := &TerraformModule{Path: "d", Config: config.TerragruntConfig{}, TerragruntOptions: optionsWithMockTerragruntCommand(t, "d", nil, &dRan)}
+
+	runningModules, err := toRunningModules([]*TerraformModule{moduleA, moduleB, moduleC, moduleD}, NormalOrder)
+	require.NoError(t, err)
+
+	startedAt := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	progress := newRunProgress(runningModules)
+	progress.startedAt = startedAt
+	progress.now = func() time.Time { return startedAt.Add(90 * time.Second) }
+
+	report := progress.Report()
+	assert.Equal(t, 0, report.CurrentGroup)
+	assert.Equal(t, 90.0, report.ElapsedSeconds)
+	assert.Equal(t, []ModuleProgressReport{
+		{Path: "a", State: ModulePending, Group: 0},
+		{Path: "b", State: ModulePending, Group: 1},
+		{Path: "c", State: ModulePending, Group: 2},
+		{Path: "d", State: ModulePending, Group: 0},
+	}, report.Modules)
+	assert.Equal(t, "0 of 4 modules done (0 succeeded, 0 failed, 0 skipped), 0 running, 4 pending", report.Summary())
+
+	progress.setState("a", ModuleSucceeded)
+	progress.setState("d", ModuleFailed)
+	progress.setState("b", ModuleRunning)
+
+	report = progress.Report()
+	assert.Equal(t, 1, report.CurrentGroup)
+	assert.Equal(t, "2 of 4 modules done (1 succeeded, 1 failed, 0 skipped), 1 running, 1 pending", report.Summary())
+
+	progress.setState("b", ModuleSucceeded)
+	progress.setState("c", ModuleSkipped)
+
+	report = progress.Report()
+	assert.Equal(t, -1, report.CurrentGroup)
+	assert.Equal(t, "4 of 4 modules done (2 succeeded, 1 failed, 1 skipped), 0 running, 0 pending", report.Summary())
+}
+
+func TestRunProgressGroupsFollowDependencyOrder(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		dependencyOrder DependencyOrder
+		expectedGroups  [][]string
+		expectedSummary string
+	}{
+		{
+			NormalOrder,
+			[][]string{{"vpc"}, {"app", "db"}, {"frontend"}},
+			"Running 4 modules in 3 groups, in normal dependency order, so each module runs once the modules it depends on are done:\n  Group 0: vpc\n  Group 1: app, db\n  Group 2: frontend",
+		},
+		{
+			ReverseOrder,
+			[][]string{{"frontend"}, {"app", "db"}, {"vpc"}},
+			"Running 4 modules in 3 groups, in reverse dependency order, so each module runs once the modules that depend on it are done:\n  Group 0: frontend\n  Group 1: app, db\n  Group 2: vpc",
+		},
+	}
+
+	for _, testCase := range testCases {
+		runningModules, err := toRunningModules(diamondModules(t, newModulesRan(), ""), testCase.dependencyOrder)
+		require.NoError(t, err)
+
+		progress := newRunProgress(runningModules)
+		report := progress.Report()
+
+		assert.Equal(t, testCase.dependencyOrder.String(), report.DependencyOrder)
+		assert.Equal(t, 0, report.CurrentGroup, "For order %s", testCase.dependencyOrder)
+		assert.Equal(t, testCase.expectedGroups, progress.Groups(), "For order %s", testCase.dependencyOrder)
+		assert.Equal(t, testCase.expectedSummary, progress.GroupsSummary(), "For order %s", testCase.dependencyOrder)
+
+		// Once the modules of the first group are done, the modules in the middle of the diamond are next
+		progress.setState(testCase.expectedGroups[0][0], ModuleSucceeded)
+		assert.Equal(t, 1, progress.Report().CurrentGroup, "For order %s", testCase.dependencyOrder)
+	}
+}
+
+func TestRunProgressAppendOutput(t *testing.T) {
+	t.Parallel()
+
+	aRan := false
+	moduleA := &TerraformModule{Path: "a", Config: config.TerragruntConfig{}, TerragruntOptions: optionsWithMockTerragruntCommand(t, "a", nil, &aRan)}
+
+	runningModules, err := toRunningModules([]*TerraformModule{moduleA}, NormalOrder)
+	require.NoError(t, err)
+
+	progress := newRunProgress(runningModules)
+	progress.setState("a", ModuleRunning)
+
+	progress.appendOutput("a", []byte("first line\nsecond "))
+	progress.appendOutput("a", []byte("line\nthird"))
+	progress.appendOutput("unknown", []byte("ignored\n"))
+	assert.Equal(t, []string{"first line", "second line"}, progress.Report().Modules[0].LastLogLines)
+
+	for i := 0; i < MAX_LOG_LINES_PER_MODULE; i++ {
+		progress.appendOutput("a", []byte(fmt.Sprintf(" %d\n", i)))
+	}
+
+	lastLogLines := progress.Report().Modules[0].LastLogLines
+	assert.Len(t, lastLogLines, MAX_LOG_LINES_PER_MODULE)
+	assert.Equal(t, "third 0", lastLogLines[0])
+	assert.Equal(t, fmt.Sprintf(" %d", MAX_LOG_LINES_PER_MODULE-1), lastLogLines[MAX_LOG_LINES_PER_MODULE-1])
+
+	// Log lines are only reported while a module is running
+	progress.setState("a", ModuleSucceeded)
+	assert.Empty(t, progress.Report().Modules[0].LastLogLines)
+}
+
+func TestModuleStateWhenFinished(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		assumeAlreadyApplied bool
+		moduleErr            error
+		expected             ModuleState
+	}{
+		{false, nil, ModuleSucceeded},
+		{true, nil, ModuleSkipped},
+		{false, fmt.Errorf("boom"), ModuleFailed},
+		{false, DependencyFinishedWithError{Module: &TerraformModule{Path: "a"}, Dependency: &TerraformModule{Path: "b"}, Err: fmt.Errorf("boom")}, ModuleSkipped},
+	}
+
+	for _, testCase := range testCases {
+		module := &TerraformModule{Path: "a", AssumeAlreadyApplied: testCase.assumeAlreadyApplied}
+		actual := moduleStateWhenFinished(module, testCase.moduleErr)
+		assert.Equal(t, testCase.expected, actual, "For assumeAlreadyApplied %v and error %v", testCase.assumeAlreadyApplied, testCase.moduleErr)
+	}
+}
+
+// Create the modules of a diamond shaped graph in the given folder: vpc, app and db, which both depend on vpc, and
+// frontend, which depends on both app and db. Their RunTerragrunt records that they ran in the given modulesRan.
+func diamondModules(t *testing.T, ran *modulesRan, rootDir string) []*TerraformModule {
+	newModule := func(name string, dependencies ...*TerraformModule) *TerraformModule {
+		path := util.JoinPath(rootDir, name)
+
+		opts, err := options.NewTerragruntOptionsForTest(util.JoinPath(path, config.DefaultTerragruntConfigPath))
+		require.NoError(t, err)
+		opts.RunTerragrunt = func(_ *options.TerragruntOptions) error {
+			ran.add(path)
+			return nil
+		}
+
+		return &TerraformModule{Path: path, Dependencies: dependencies, Config: config.TerragruntConfig{}, TerragruntOptions: opts}
+	}
+
+	vpc := newModule("vpc")
+	app := newModule("app", vpc)
+	db := newModule("db", vpc)
+	frontend := newModule("frontend", app, db)
+
+	return []*TerraformModule{vpc, app, db, frontend}
+}
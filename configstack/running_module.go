@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/shell"
@@ -27,6 +28,25 @@ type runningModule struct {
 	Dependencies   map[string]*runningModule
 	NotifyWhenDone []*runningModule
 	FlagExcluded   bool
+	Progress       *RunProgress
+
+	// The exit code the module finished with, if it ran and the exit code is known. This is the real exit code, even if
+	// the module counts as succeeded because its config allows that exit code.
+	ExitCode int
+
+	// Held while the module runs, if it's in a concurrency group, so that no two modules in the same group run at once
+	ConcurrencyGroupLock *sync.Mutex
+
+	// Limits how quickly modules are started, shared by all modules in the run. Nil if there are no limits.
+	StartLimiter *startLimiter
+
+	// Records the result of the module so the run can be resumed, and tells whether the module can be skipped because
+	// it succeeded in the run being resumed. Nil unless the run is an apply-all.
+	Resume *resumeTracker
+
+	// The order dependencies are enforced in. With ReverseOrder, such as for destroy-all, Dependencies holds the modules
+	// that depend on this module rather than the ones it depends on.
+	DependencyOrder DependencyOrder
 }
 
 // This controls in what order dependencies should be enforced between modules
@@ -37,6 +57,22 @@ const (
 	ReverseOrder
 )
 
+func (dependencyOrder DependencyOrder) String() string {
+	if dependencyOrder == ReverseOrder {
+		return "reverse"
+	}
+	return "normal"
+}
+
+// Return what the modules a module waits for are called in log messages, in singular and plural form: its dependencies
+// or, in reverse order, its dependents, i.e. the modules that depend on it
+func (dependencyOrder DependencyOrder) waitsFor() (string, string) {
+	if dependencyOrder == ReverseOrder {
+		return "dependent", "dependents"
+	}
+	return "dependency", "dependencies"
+}
+
 // Create a new RunningModule struct for the given module. This will initialize all fields to reasonable defaults,
 // except for the Dependencies and NotifyWhenDone, both of which will be empty. You should fill these using a
 // function such as crossLinkDependencies.
@@ -80,6 +116,7 @@ func toRunningModules(modules []*TerraformModule, dependencyOrder DependencyOrde
 	runningModules := map[string]*runningModule{}
 	for _, module := range modules {
 		runningModules[module.Path] = newRunningModule(module)
+		runningModules[module.Path].DependencyOrder = dependencyOrder
 	}
 
 	crossLinkedModules, err := crossLinkDependencies(runningModules, dependencyOrder)
@@ -124,12 +161,13 @@ func removeFlagExcluded(modules map[string]*runningModule) map[string]*runningMo
 		// Only add modules that should not be excluded
 		if !module.FlagExcluded {
 			finalModules[key] = &runningModule{
-				Module:         module.Module,
-				Dependencies:   make(map[string]*runningModule),
-				DependencyDone: module.DependencyDone,
-				Err:            module.Err,
-				NotifyWhenDone: module.NotifyWhenDone,
-				Status:         module.Status,
+				Module:          module.Module,
+				Dependencies:    make(map[string]*runningModule),
+				DependencyDone:  module.DependencyDone,
+				Err:             module.Err,
+				NotifyWhenDone:  module.NotifyWhenDone,
+				Status:          module.Status,
+				DependencyOrder: module.DependencyOrder,
 			}
 
 			// Only add dependencies that should not be excluded
@@ -148,8 +186,27 @@ func removeFlagExcluded(modules map[string]*runningModule) map[string]*runningMo
 // TerragruntOptions object. The modules will be executed in an order determined by their inter-dependencies, using
 // as much concurrency as possible.
 func runModules(modules map[string]*runningModule) error {
+	return runModulesWithProgress(modules, newRunProgress(modules), nil)
+}
+
+// Run the given map of module path to runningModule, just like runModules, recording the state of each module in the
+// given RunProgress as it runs. If limiter is not nil, it limits how quickly the modules are started.
+func runModulesWithProgress(modules map[string]*runningModule, progress *RunProgress, limiter *startLimiter) error {
 	var waitGroup sync.WaitGroup
 
+	concurrencyGroupLocks := map[string]*sync.Mutex{}
+	for _, module := range modules {
+		module.Progress = progress
+		module.StartLimiter = limiter
+
+		if group := module.Module.ConcurrencyGroup(); group != "" {
+			if _, exists := concurrencyGroupLocks[group]; !exists {
+				concurrencyGroupLocks[group] = &sync.Mutex{}
+			}
+			module.ConcurrencyGroupLock = concurrencyGroupLocks[group]
+		}
+	}
+
 	for _, module := range modules {
 		waitGroup.Add(1)
 		go func(module *runningModule) {
@@ -184,28 +241,31 @@ func collectErrors(modules map[string]*runningModule) error {
 func (module *runningModule) runModuleWhenReady() {
 	err := module.waitForDependencies()
 	if err == nil {
-		err = module.runNow()
+		err = module.allowExitCode(module.runNow())
 	}
 	module.moduleFinished(err)
 }
 
 // Wait for all of this modules dependencies to finish executing. Return an error if any of those dependencies complete
 // with an error. Return immediately if this module has no dependencies.
+// In reverse order, the messages logged while waiting refer to the modules that depend on this one as its dependents.
 func (module *runningModule) waitForDependencies() error {
-	module.Module.TerragruntOptions.Logger.Printf("Module %s must wait for %d dependencies to finish", module.Module.Path, len(module.Dependencies))
+	singular, plural := module.DependencyOrder.waitsFor()
+
+	module.Module.TerragruntOptions.Logger.Printf("Module %s must wait for %d %s to finish", module.Module.Path, len(module.Dependencies), plural)
 	for len(module.Dependencies) > 0 {
 		doneDependency := <-module.DependencyDone
 		delete(module.Dependencies, doneDependency.Module.Path)
 
 		if doneDependency.Err != nil {
 			if module.Module.TerragruntOptions.IgnoreDependencyErrors {
-				module.Module.TerragruntOptions.Logger.Printf("Dependency %s of module %s just finished with an error. Module %s will have to return an error too. However, because of --terragrunt-ignore-dependency-errors, module %s will run anyway.", doneDependency.Module.Path, module.Module.Path, module.Module.Path, module.Module.Path)
+				module.Module.TerragruntOptions.Logger.Printf("The %s %s of module %s just finished with an error. Module %s will have to return an error too. However, because of --terragrunt-ignore-dependency-errors, module %s will run anyway.", singular, doneDependency.Module.Path, module.Module.Path, module.Module.Path, module.Module.Path)
 			} else {
-				module.Module.TerragruntOptions.Logger.Printf("Dependency %s of module %s just finished with an error. Module %s will have to return an error too.", doneDependency.Module.Path, module.Module.Path, module.Module.Path)
+				module.Module.TerragruntOptions.Logger.Printf("The %s %s of module %s just finished with an error. Module %s will have to return an error too.", singular, doneDependency.Module.Path, module.Module.Path, module.Module.Path)
 				return DependencyFinishedWithError{module.Module, doneDependency.Module, doneDependency.Err}
 			}
 		} else {
-			module.Module.TerragruntOptions.Logger.Printf("Dependency %s of module %s just finished successfully. Module %s must wait on %d more dependencies.", doneDependency.Module.Path, module.Module.Path, module.Module.Path, len(module.Dependencies))
+			module.Module.TerragruntOptions.Logger.Printf("The %s %s of module %s just finished successfully. Module %s must wait on %d more %s.", singular, doneDependency.Module.Path, module.Module.Path, module.Module.Path, len(module.Dependencies), plural)
 		}
 	}
 
@@ -219,12 +279,51 @@ func (module *runningModule) runNow() error {
 	if module.Module.AssumeAlreadyApplied {
 		module.Module.TerragruntOptions.Logger.Printf("Assuming module %s has already been applied and skipping it", module.Module.Path)
 		return nil
+	} else if module.Resume.skips(module.Module) {
+		module.Module.TerragruntOptions.Logger.Printf("Module %s succeeded in the run being resumed and neither its config, source, nor dependencies changed since, so skipping it", module.Module.Path)
+		return nil
 	} else {
+		if module.ConcurrencyGroupLock != nil {
+			module.Module.TerragruntOptions.Logger.Printf("Module %s is waiting for the other modules in concurrency group %s to finish", module.Module.Path, module.Module.ConcurrencyGroup())
+			module.ConcurrencyGroupLock.Lock()
+			defer module.ConcurrencyGroupLock.Unlock()
+		}
+
+		module.StartLimiter.wait(func(delay time.Duration) {
+			module.Module.TerragruntOptions.Logger.Printf("Throttling the start of module %s by %v to stay within --terragrunt-stagger and --terragrunt-max-starts-per-minute", module.Module.Path, delay)
+		})
+
 		module.Module.TerragruntOptions.Logger.Printf("Running module %s now", module.Module.Path)
-		return module.Module.TerragruntOptions.RunTerragrunt(module.Module.TerragruntOptions)
+		module.Progress.setState(module.Module.Path, ModuleRunning)
+		terragruntOptions := module.Progress.optionsForModule(module.Module.Path, module.Module.TerragruntOptions)
+		return terragruntOptions.RunTerragrunt(terragruntOptions)
 	}
 }
 
+// Record the exit code of the given error, returned by running the module, if it's known. If the exit code is one of
+// those in the allowed_exit_codes setting of the module, log a warning and return nil, so the module counts as
+// succeeded and the modules that wait for it still run. Otherwise, return the given error.
+func (module *runningModule) allowExitCode(moduleErr error) error {
+	if moduleErr == nil {
+		return nil
+	}
+
+	exitCode, err := shell.GetExitCode(moduleErr)
+	if err != nil {
+		return moduleErr
+	}
+	module.ExitCode = exitCode
+
+	for _, allowedExitCode := range module.Module.AllowedExitCodes() {
+		if exitCode != 0 && exitCode == allowedExitCode {
+			module.Module.TerragruntOptions.Logger.Printf("WARNING: Module %s exited with code %d, which its allowed_exit_codes setting allows, so it counts as succeeded: %v", module.Module.Path, exitCode, moduleErr)
+			return nil
+		}
+	}
+
+	return moduleErr
+}
+
 // Record that a module has finished executing and notify all of this module's dependencies
 func (module *runningModule) moduleFinished(moduleErr error) {
 	if moduleErr == nil {
@@ -236,11 +335,36 @@ func (module *runningModule) moduleFinished(moduleErr error) {
 	module.Status = Finished
 	module.Err = moduleErr
 
+	state := moduleStateWhenFinished(module.Module, moduleErr)
+	if moduleErr == nil && module.Resume.skips(module.Module) {
+		state = ModuleSkipped
+	}
+	module.Resume.recordResult(module.Module, state, module.ExitCode)
+
+	module.Progress.setState(module.Module.Path, state)
+	module.Progress.setExitCode(module.Module.Path, module.ExitCode)
+	module.Module.TerragruntOptions.Logger.Printf("Progress: %s", module.Progress.Report().Summary())
+
 	for _, toNotify := range module.NotifyWhenDone {
 		toNotify.DependencyDone <- module
 	}
 }
 
+// Return the state of a module that just finished running with the given error. A module is skipped if it was assumed
+// to be applied already, or if it never ran because one of its dependencies failed.
+func moduleStateWhenFinished(module *TerraformModule, moduleErr error) ModuleState {
+	if _, isDependencyErr := moduleErr.(DependencyFinishedWithError); isDependencyErr {
+		return ModuleSkipped
+	}
+	if moduleErr != nil {
+		return ModuleFailed
+	}
+	if module.AssumeAlreadyApplied {
+		return ModuleSkipped
+	}
+	return ModuleSucceeded
+}
+
 // Custom error types
 
 type DependencyFinishedWithError struct {
@@ -292,3 +416,7 @@ type DependencyNotFoundWhileCrossLinking struct {
 func (err DependencyNotFoundWhileCrossLinking) Error() string {
 	return fmt.Sprintf("Module %v specifies a dependency on module %v, but could not find that module while cross-linking dependencies. This is most likely a bug in Terragrunt. Please report it.", err.Module, err.Dependency)
 }
+
+func (err DependencyNotFoundWhileCrossLinking) Class() errors.ErrorClass {
+	return errors.ErrorClassDependencyGraph
+}
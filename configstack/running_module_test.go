@@ -2,11 +2,17 @@ package configstack
 
 import (
 	"fmt"
+	"os/exec"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var mockOptions, _ = options.NewTerragruntOptionsForTest("running_module_test")
@@ -1073,3 +1079,147 @@ func TestRunModulesReverseOrderMultipleModulesWithDependenciesLargeGraphPartialF
 	assert.True(t, eRan)
 	assert.True(t, fRan)
 }
+
+func TestRunModulesConcurrencyGroupRunsModulesSerially(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	running := map[string]int{}
+	maxRunning := map[string]int{}
+
+	moduleInGroup := func(path string, group string) *TerraformModule {
+		opts, err := options.NewTerragruntOptionsForTest(path)
+		if err != nil {
+			t.Fatalf("Error creating terragrunt options for test %v", err)
+		}
+		opts.RunTerragrunt = func(_ *options.TerragruntOptions) error {
+			lock.Lock()
+			running[group]++
+			if running[group] > maxRunning[group] {
+				maxRunning[group] = running[group]
+			}
+			lock.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			lock.Lock()
+			running[group]--
+			lock.Unlock()
+			return nil
+		}
+
+		return &TerraformModule{
+			Path:              path,
+			Dependencies:      []*TerraformModule{},
+			Config:            config.TerragruntConfig{Terraform: &config.TerraformConfig{ConcurrencyGroup: group}},
+			TerragruntOptions: opts,
+		}
+	}
+
+	modules := []*TerraformModule{
+		moduleInGroup("a", "pagerduty"),
+		moduleInGroup("b", "pagerduty"),
+		moduleInGroup("c", "pagerduty"),
+		moduleInGroup("d", "github"),
+		moduleInGroup("e", "github"),
+	}
+
+	err := RunModules(modules)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, maxRunning["pagerduty"])
+	assert.Equal(t, 1, maxRunning["github"])
+}
+
+func TestRunModulesSharesOneLockPerConcurrencyGroup(t *testing.T) {
+	t.Parallel()
+
+	moduleWithGroup := func(path string, group string) *TerraformModule {
+		executed := false
+		return &TerraformModule{
+			Path:              path,
+			Dependencies:      []*TerraformModule{},
+			Config:            config.TerragruntConfig{Terraform: &config.TerraformConfig{ConcurrencyGroup: group}},
+			TerragruntOptions: optionsWithMockTerragruntCommand(t, path, nil, &executed),
+		}
+	}
+
+	modules := []*TerraformModule{
+		moduleWithGroup("a", "pagerduty"),
+		moduleWithGroup("b", "pagerduty"),
+		moduleWithGroup("c", "github"),
+		moduleWithGroup("d", ""),
+	}
+
+	runningModules, err := toRunningModules(modules, NormalOrder)
+	assert.Nil(t, err)
+	assert.Nil(t, runModules(runningModules))
+
+	assert.NotNil(t, runningModules["a"].ConcurrencyGroupLock)
+	assert.True(t, runningModules["a"].ConcurrencyGroupLock == runningModules["b"].ConcurrencyGroupLock)
+	assert.NotNil(t, runningModules["c"].ConcurrencyGroupLock)
+	assert.False(t, runningModules["a"].ConcurrencyGroupLock == runningModules["c"].ConcurrencyGroupLock)
+	assert.Nil(t, runningModules["d"].ConcurrencyGroupLock)
+}
+
+func TestRunModulesAllowedExitCodes(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		exitCode         int
+		allowedExitCodes []int
+		expectedState    ModuleState
+	}{
+		{"success", 0, []int{0, 2}, ModuleSucceeded},
+		{"allowed exit code", 2, []int{0, 2}, ModuleSucceeded},
+		{"other exit code", 1, []int{0, 2}, ModuleFailed},
+		// Without allowed_exit_codes, exit code 2, such as from plan -detailed-exitcode, is still a failure
+		{"no allowed exit codes", 2, nil, ModuleFailed},
+	}
+
+	for _, testCase := range testCases {
+		aRan := false
+		moduleA := &TerraformModule{
+			Path:              "a",
+			Dependencies:      []*TerraformModule{},
+			Config:            config.TerragruntConfig{Terraform: &config.TerraformConfig{AllowedExitCodes: testCase.allowedExitCodes}},
+			TerragruntOptions: optionsWithMockTerragruntCommand(t, "a", fakeCommandError(testCase.exitCode), &aRan),
+		}
+
+		bRan := false
+		moduleB := &TerraformModule{
+			Path:              "b",
+			Dependencies:      []*TerraformModule{moduleA},
+			Config:            config.TerragruntConfig{},
+			TerragruntOptions: optionsWithMockTerragruntCommand(t, "b", nil, &bRan),
+		}
+
+		runningModules, err := toRunningModules([]*TerraformModule{moduleA, moduleB}, NormalOrder)
+		require.NoError(t, err, "For %s", testCase.name)
+		progress := newRunProgress(runningModules)
+
+		err = runModulesWithProgress(runningModules, progress, nil)
+		if testCase.expectedState == ModuleSucceeded {
+			assert.NoError(t, err, "For %s", testCase.name)
+			assert.True(t, bRan, "For %s", testCase.name)
+		} else if assert.Error(t, err, "For %s", testCase.name) {
+			exitCode, exitCodeErr := shell.GetExitCode(err)
+			assert.NoError(t, exitCodeErr, "For %s", testCase.name)
+			assert.Equal(t, testCase.exitCode, exitCode, "For %s", testCase.name)
+			assert.False(t, bRan, "For %s", testCase.name)
+		}
+
+		assert.True(t, aRan, "For %s", testCase.name)
+		assert.Equal(t, testCase.exitCode, runningModules["a"].ExitCode, "For %s", testCase.name)
+
+		report := progress.Report()
+		assert.Equal(t, testCase.expectedState, report.Modules[0].State, "For %s", testCase.name)
+		assert.Equal(t, testCase.exitCode, report.Modules[0].ExitCode, "For %s", testCase.name)
+	}
+}
+
+// Run a fake command that exits with the given exit code, and return the error it fails with, or nil for exit code 0
+func fakeCommandError(exitCode int) error {
+	return errors.WithStackTrace(exec.Command("sh", "-c", fmt.Sprintf("exit %d", exitCode)).Run())
+}
@@ -3,6 +3,7 @@ package configstack
 import (
 	"bytes"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/gruntwork-io/terragrunt/config"
@@ -39,7 +40,7 @@ func (stack *Stack) Plan(terragruntOptions *options.TerragruntOptions) error {
 		module.TerragruntOptions.ErrWriter = &errorStreams[n]
 	}
 	defer stack.summarizePlanAllErrors(terragruntOptions, errorStreams)
-	return RunModules(stack.Modules)
+	return stack.run(terragruntOptions, NormalOrder, nil)
 }
 
 // We inspect the error streams to give an explicit message if the plan failed because there were references to
@@ -71,26 +72,66 @@ func (stack *Stack) summarizePlanAllErrors(terragruntOptions *options.Terragrunt
 // proper order.
 func (stack *Stack) Apply(terragruntOptions *options.TerragruntOptions) error {
 	stack.setTerraformCommand([]string{"apply", "-input=false", "-auto-approve"})
-	return RunModules(stack.Modules)
+
+	resume, err := newResumeTracker(stack, terragruntOptions)
+	if err != nil {
+		return err
+	}
+	return stack.run(terragruntOptions, NormalOrder, resume)
 }
 
 // Destroy all the modules in the given stack, making sure to destroy the dependencies of each module in the stack in
 // the proper order.
 func (stack *Stack) Destroy(terragruntOptions *options.TerragruntOptions) error {
 	stack.setTerraformCommand([]string{"destroy", "-force", "-input=false"})
-	return RunModulesReverseOrder(stack.Modules)
+	return stack.run(terragruntOptions, ReverseOrder, nil)
 }
 
 // Output prints the outputs of all the modules in the given stack in their specified order.
 func (stack *Stack) Output(terragruntOptions *options.TerragruntOptions) error {
 	stack.setTerraformCommand([]string{"output"})
-	return RunModules(stack.Modules)
+	return stack.run(terragruntOptions, NormalOrder, nil)
 }
 
 // Validate runs terraform validate on each module
 func (stack *Stack) Validate(terragruntOptions *options.TerragruntOptions) error {
 	stack.setTerraformCommand([]string{"validate"})
-	return RunModules(stack.Modules)
+	return stack.run(terragruntOptions, NormalOrder, nil)
+}
+
+// Run the modules in this stack in the given dependency order. If a status port is configured, serve the progress of
+// the run on that port until the run is done. If resume is not nil, record the result of each module in it, and skip
+// the modules it says succeeded in the run being resumed.
+func (stack *Stack) run(terragruntOptions *options.TerragruntOptions, dependencyOrder DependencyOrder, resume *resumeTracker) error {
+	runningModules, err := toRunningModules(stack.Modules, dependencyOrder)
+	if err != nil {
+		return err
+	}
+
+	for _, module := range runningModules {
+		module.Resume = resume
+	}
+
+	progress := newRunProgress(runningModules)
+	terragruntOptions.Logger.Printf("%s", progress.GroupsSummary())
+
+	if terragruntOptions.StatusPort > 0 {
+		progress.captureOutput = true
+
+		statusServer, err := StartStatusServer(terragruntOptions, progress)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := statusServer.Shutdown(); err != nil {
+				terragruntOptions.Logger.Printf("WARNING: Error shutting down the status server: %v", err)
+			}
+		}()
+	}
+
+	runErr := runModulesWithProgress(runningModules, progress, newStartLimiter(terragruntOptions))
+	resume.logSummary(terragruntOptions, runErr)
+	return runErr
 }
 
 // Return an error if there is a dependency cycle in the modules of this stack.
@@ -110,6 +151,83 @@ func FindStackInSubfolders(terragruntOptions *options.TerragruntOptions) (*Stack
 	return createStackForTerragruntConfigPaths(terragruntOptions.WorkingDir, terragruntConfigFiles, terragruntOptions, howThesePathsWereFound)
 }
 
+// Find the module of the Terragrunt config file in the given TerragruntOptions and the modules it depends on, directly
+// or through other dependencies, and assemble them into a Stack object, so a command can be run in the module and its
+// dependencies in the right order. Only the dependencies up to maxDepth levels away from the module are run, such as
+// just the direct dependencies for a maxDepth of 1, or all of them if maxDepth is 0. The others are flagged as
+// excluded, like those left out by --terragrunt-exclude-dir.
+func FindStackForModuleWithDependencies(terragruntOptions *options.TerragruntOptions, maxDepth int) (*Stack, error) {
+	terragruntConfigPath, err := util.CanonicalPath(terragruntOptions.TerragruntConfigPath, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	moduleMap := map[string]*TerraformModule{}
+	depths := map[string]int{}
+	terragruntConfigPaths := []string{}
+
+	// Resolve the modules one level of dependencies at a time, so the depth of each module is the length of the shortest
+	// chain of dependencies from the module the command was run in to it
+	pathsToResolve := []string{terragruntConfigPath}
+	howThesePathsWereFound := fmt.Sprintf("module Terragrunt was run in, at '%s'", terragruntOptions.WorkingDir)
+	for depth := 0; len(pathsToResolve) > 0; depth++ {
+		terragruntConfigPaths = append(terragruntConfigPaths, pathsToResolve...)
+
+		modules, err := resolveModules(pathsToResolve, terragruntOptions, howThesePathsWereFound)
+		if err != nil {
+			return nil, err
+		}
+
+		pathsToResolve = []string{}
+		for path, module := range modules {
+			moduleMap[path] = module
+			depths[path] = depth
+		}
+		for _, module := range modules {
+			if module.Config.Dependencies == nil {
+				continue
+			}
+			for _, dependency := range module.Config.Dependencies.Paths {
+				dependencyPath, err := util.CanonicalPath(dependency, module.Path)
+				if err != nil {
+					return nil, err
+				}
+				if _, alreadyResolved := moduleMap[dependencyPath]; alreadyResolved {
+					continue
+				}
+				dependencyConfigPath := config.DefaultConfigPath(dependencyPath)
+				if !util.ListContainsElement(terragruntConfigPaths, dependencyConfigPath) && !util.ListContainsElement(pathsToResolve, dependencyConfigPath) {
+					pathsToResolve = append(pathsToResolve, dependencyConfigPath)
+				}
+			}
+		}
+		howThesePathsWereFound = fmt.Sprintf("dependency of the module Terragrunt was run in, at '%s'", terragruntOptions.WorkingDir)
+	}
+
+	// The module itself may not be runnable, such as if it sets skip = true, in which case resolveModules logged why
+	if _, found := moduleMap[filepath.Dir(terragruntConfigPath)]; !found {
+		return nil, errors.WithStackTrace(ModuleNotRunnable(filepath.Dir(terragruntConfigPath)))
+	}
+
+	modules, err := crosslinkDependencies(moduleMap, terragruntConfigPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxDepth > 0 {
+		for _, module := range modules {
+			module.FlagExcluded = depths[module.Path] > maxDepth
+		}
+	}
+
+	stack := &Stack{Path: terragruntOptions.WorkingDir, Modules: modules}
+	if err := stack.CheckForCycles(); err != nil {
+		return nil, err
+	}
+
+	return stack, nil
+}
+
 // Set the command in the TerragruntOptions object of each module in this stack to the given command.
 func (stack *Stack) setTerraformCommand(command []string) {
 	for _, module := range stack.Modules {
@@ -142,8 +260,18 @@ func createStackForTerragruntConfigPaths(path string, terragruntConfigPaths []st
 
 var NoTerraformModulesFound = fmt.Errorf("Could not find any subfolders with Terragrunt configuration files")
 
+type ModuleNotRunnable string
+
+func (path ModuleNotRunnable) Error() string {
+	return fmt.Sprintf("The module at %s can't be run, so neither can its dependencies", string(path))
+}
+
 type DependencyCycle []string
 
 func (err DependencyCycle) Error() string {
 	return fmt.Sprintf("Found a dependency cycle between modules: %s", strings.Join([]string(err), " -> "))
 }
+
+func (err DependencyCycle) Class() errors.ErrorClass {
+	return errors.ErrorClassDependencyGraph
+}
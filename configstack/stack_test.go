@@ -1,14 +1,18 @@
 package configstack
 
 import (
+	"bytes"
 	"github.com/gruntwork-io/terragrunt/config"
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/gruntwork-io/terragrunt/util"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -55,6 +59,146 @@ func TestFindStackInSubfolders(t *testing.T) {
 
 }
 
+func TestFindStackInSubfoldersRootModule(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		fixture         string
+		expectedModules []string
+		expectedLog     string
+	}{
+		{"root-as-module", []string{".", "app"}, ""},
+		{"root-as-parent", []string{"app"}, "is included by other modules and does not have an associated terraform configuration of its own and will be skipped"},
+		{"both", []string{".", "app"}, ""},
+		{"skip", []string{"app"}, "sets skip = true and will be skipped"},
+	}
+
+	for _, testCase := range testCases {
+		rootPath := canonical(t, filepath.Join("../test/fixture-stack-root", testCase.fixture))
+
+		terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(rootPath, config.DefaultTerragruntConfigPath))
+		require.NoError(t, err, "For fixture %s", testCase.fixture)
+		terragruntOptions.WorkingDir = rootPath
+
+		var logs bytes.Buffer
+		terragruntOptions.Logger = util.CreateLoggerWithWriter(&logs, "")
+
+		stack, err := FindStackInSubfolders(terragruntOptions)
+		require.NoError(t, err, "For fixture %s", testCase.fixture)
+
+		modulePaths := []string{}
+		for _, module := range stack.Modules {
+			relPath, err := util.GetPathRelativeTo(module.Path, rootPath)
+			require.NoError(t, err, "For fixture %s", testCase.fixture)
+			modulePaths = append(modulePaths, relPath)
+		}
+		sort.Strings(modulePaths)
+
+		assert.Equal(t, testCase.expectedModules, modulePaths, "For fixture %s", testCase.fixture)
+		if testCase.expectedLog != "" {
+			assert.Contains(t, logs.String(), testCase.expectedLog, "For fixture %s", testCase.fixture)
+		}
+	}
+}
+
+func TestFindStackForModuleWithDependencies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		command         string
+		maxDepth        int
+		expectedModules []string
+		expectedArgs    []string
+	}{
+		{"plan", 1, []string{"app", "cache", "db"}, []string{"plan", "-lock=false"}},
+		{"plan", 2, []string{"app", "cache", "db", "vpc"}, []string{"plan", "-lock=false"}},
+		{"apply", 0, []string{"app", "cache", "db", "vpc"}, []string{"apply", "-input=false", "-auto-approve", "-lock=false"}},
+	}
+
+	for _, testCase := range testCases {
+		// Apply records its results in the working dir, so run in a copy of the fixture
+		rootPath := createTempFolder(t)
+		defer os.RemoveAll(rootPath)
+		require.NoError(t, util.CopyFolderContents("../test/fixture-run-with-dependencies", rootPath))
+		rootPath = canonical(t, rootPath)
+		appPath := util.JoinPath(rootPath, "app")
+
+		terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(appPath, config.DefaultTerragruntConfigPath))
+		require.NoError(t, err)
+		terragruntOptions.TerraformCliArgs = []string{"-lock=false"}
+
+		ran := newModulesRan()
+		args := map[string][]string{}
+		var argsMutex sync.Mutex
+		terragruntOptions.RunTerragrunt = func(opts *options.TerragruntOptions) error {
+			ran.add(opts.WorkingDir)
+			argsMutex.Lock()
+			defer argsMutex.Unlock()
+			args[opts.WorkingDir] = opts.TerraformCliArgs
+			return nil
+		}
+
+		stack, err := FindStackForModuleWithDependencies(terragruntOptions, testCase.maxDepth)
+		require.NoError(t, err, "For %s with max depth %d", testCase.command, testCase.maxDepth)
+		if testCase.command == "plan" {
+			require.NoError(t, stack.Plan(terragruntOptions), "For %s with max depth %d", testCase.command, testCase.maxDepth)
+		} else {
+			require.NoError(t, stack.Apply(terragruntOptions), "For %s with max depth %d", testCase.command, testCase.maxDepth)
+		}
+
+		// frontend depends on app, but isn't a dependency of it, so it never runs
+		assert.Equal(t, testCase.expectedModules, ran.names(rootPath), "For %s with max depth %d", testCase.command, testCase.maxDepth)
+		for path, actualArgs := range args {
+			assert.Equal(t, testCase.expectedArgs, actualArgs, "For %s with max depth %d in %s", testCase.command, testCase.maxDepth, path)
+		}
+
+		// app depends on db and cache, and db on vpc, so app has to run last, and vpc, if it runs, before db
+		order := ran.ordered()
+		if assert.NotEmpty(t, order, "For %s with max depth %d", testCase.command, testCase.maxDepth) {
+			assert.Equal(t, appPath, order[len(order)-1], "For %s with max depth %d", testCase.command, testCase.maxDepth)
+		}
+		if util.ListContainsElement(order, util.JoinPath(rootPath, "vpc")) {
+			assert.True(t, indexOf(order, util.JoinPath(rootPath, "vpc")) < indexOf(order, util.JoinPath(rootPath, "db")), "For %s with max depth %d, vpc ran after db: %v", testCase.command, testCase.maxDepth, order)
+		}
+	}
+}
+
+func TestDestroyRunsDiamondInReverseOrder(t *testing.T) {
+	t.Parallel()
+
+	ran := newModulesRan()
+	stack := &Stack{Path: "/stage", Modules: diamondModules(t, ran, "/stage")}
+
+	var logs bytes.Buffer
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("/stage/" + config.DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+	terragruntOptions.Logger = util.CreateLoggerWithWriter(&logs, "")
+
+	require.NoError(t, stack.Destroy(terragruntOptions))
+
+	// frontend depends on app and db, which both depend on vpc, so destroying them has to start with frontend, and end
+	// with vpc
+	order := ran.ordered()
+	if assert.Len(t, order, 4) {
+		assert.Equal(t, "/stage/frontend", order[0])
+		assert.ElementsMatch(t, []string{"/stage/app", "/stage/db"}, order[1:3])
+		assert.Equal(t, "/stage/vpc", order[3])
+	}
+
+	assert.Contains(t, logs.String(), "in reverse dependency order")
+	assert.Contains(t, logs.String(), "Group 0: /stage/frontend\n  Group 1: /stage/app, /stage/db\n  Group 2: /stage/vpc")
+}
+
+// Return the index of the given element in the given list, or -1 if it's not in it
+func indexOf(list []string, element string) int {
+	for index, item := range list {
+		if item == element {
+			return index
+		}
+	}
+	return -1
+}
+
 func createTempFolder(t *testing.T) string {
 	tmpFolder, err := ioutil.TempDir("", "")
 	if err != nil {
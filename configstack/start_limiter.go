@@ -0,0 +1,92 @@
+package configstack
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// startLimiter limits how quickly xxx-all commands start the Terraform processes of their modules, independent of how
+// many modules may run at the same time. Even modules that don't depend on each other all refresh their state at
+// startup, and starting dozens of them at once can overwhelm the APIs of the providers they use. There are two
+// independent limits: stagger is the minimum time between two starts, and maxStartsPerMinute is the size of a token
+// bucket that allows bursts of up to that many starts and is refilled at a rate of that many starts per minute.
+//
+// It's safe for concurrent use. Each call to wait reserves the earliest start time that satisfies both limits, so
+// modules start in the order they called wait.
+type startLimiter struct {
+	mutex              sync.Mutex
+	stagger            time.Duration
+	maxStartsPerMinute int
+	now                func() time.Time
+	sleep              func(time.Duration)
+
+	// The time of the most recent start reserved by wait
+	lastStart time.Time
+
+	// The token bucket is tracked as the time at which it would be full again (the "theoretical arrival time" of the
+	// generic cell rate algorithm), which avoids having to refill it on a timer
+	bucketFullAt time.Time
+}
+
+// Create a startLimiter using the limits in the given options, or return nil if neither limit is set
+func newStartLimiter(terragruntOptions *options.TerragruntOptions) *startLimiter {
+	if terragruntOptions.Stagger <= 0 && terragruntOptions.MaxStartsPerMinute <= 0 {
+		return nil
+	}
+
+	return &startLimiter{
+		stagger:            terragruntOptions.Stagger,
+		maxStartsPerMinute: terragruntOptions.MaxStartsPerMinute,
+		now:                time.Now,
+		sleep:              time.Sleep,
+	}
+}
+
+// Block until it's OK to start another module, and return how long that took. If it has to wait at all, call
+// onThrottle with the delay before waiting. A nil startLimiter never blocks.
+func (limiter *startLimiter) wait(onThrottle func(delay time.Duration)) time.Duration {
+	if limiter == nil {
+		return 0
+	}
+
+	delay := limiter.reserve()
+	if delay > 0 {
+		onThrottle(delay)
+		limiter.sleep(delay)
+	}
+	return delay
+}
+
+// Reserve the earliest start time that satisfies both limits and return how long from now that is
+func (limiter *startLimiter) reserve() time.Duration {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	now := limiter.now()
+	start := now
+
+	if limiter.stagger > 0 && !limiter.lastStart.IsZero() {
+		if earliest := limiter.lastStart.Add(limiter.stagger); earliest.After(start) {
+			start = earliest
+		}
+	}
+
+	if limiter.maxStartsPerMinute > 0 {
+		interval := time.Minute / time.Duration(limiter.maxStartsPerMinute)
+		burst := interval * time.Duration(limiter.maxStartsPerMinute-1)
+
+		if limiter.bucketFullAt.Before(start) {
+			limiter.bucketFullAt = start
+		}
+		if earliest := limiter.bucketFullAt.Add(-burst); earliest.After(start) {
+			start = earliest
+		}
+		limiter.bucketFullAt = limiter.bucketFullAt.Add(interval)
+	}
+
+	limiter.lastStart = start
+
+	return start.Sub(now)
+}
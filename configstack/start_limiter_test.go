@@ -0,0 +1,158 @@
+package configstack
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStartLimiter(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("start_limiter_test")
+	assert.Nil(t, err)
+	assert.Nil(t, newStartLimiter(terragruntOptions))
+
+	terragruntOptions.Stagger = 3 * time.Second
+	limiter := newStartLimiter(terragruntOptions)
+	if assert.NotNil(t, limiter) {
+		assert.Equal(t, 3*time.Second, limiter.stagger)
+		assert.Equal(t, 0, limiter.maxStartsPerMinute)
+	}
+
+	terragruntOptions.Stagger = 0
+	terragruntOptions.MaxStartsPerMinute = 10
+	limiter = newStartLimiter(terragruntOptions)
+	if assert.NotNil(t, limiter) {
+		assert.Equal(t, time.Duration(0), limiter.stagger)
+		assert.Equal(t, 10, limiter.maxStartsPerMinute)
+	}
+}
+
+func TestStartLimiterReserve(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		stagger            time.Duration
+		maxStartsPerMinute int
+		// The times, relative to the start of the test, at which each start is requested
+		requestedAt []time.Duration
+		// The expected delay of each start
+		expectedDelays []time.Duration
+	}{
+		{"stagger-all-at-once", 3 * time.Second, 0, []time.Duration{0, 0, 0}, []time.Duration{0, 3 * time.Second, 6 * time.Second}},
+		{"stagger-spread-out", 3 * time.Second, 0, []time.Duration{0, 10 * time.Second, 11 * time.Second}, []time.Duration{0, 0, 2 * time.Second}},
+		{"rate-burst-then-throttle", 0, 2, []time.Duration{0, 0, 0, 0}, []time.Duration{0, 0, 30 * time.Second, 60 * time.Second}},
+		{"rate-refills-when-idle", 0, 2, []time.Duration{0, 0, 2 * time.Minute, 2 * time.Minute, 2 * time.Minute}, []time.Duration{0, 0, 0, 0, 30 * time.Second}},
+		{"rate-one-per-minute", 0, 1, []time.Duration{0, 0, 30 * time.Second}, []time.Duration{0, time.Minute, 90 * time.Second}},
+		{"stagger-and-rate", 10 * time.Second, 2, []time.Duration{0, 0, 0}, []time.Duration{0, 10 * time.Second, 30 * time.Second}},
+	}
+
+	for _, testCase := range testCases {
+		startedAt := time.Date(2018, 3, 14, 12, 0, 0, 0, time.UTC)
+		clock := startedAt
+
+		limiter := &startLimiter{
+			stagger:            testCase.stagger,
+			maxStartsPerMinute: testCase.maxStartsPerMinute,
+			now:                func() time.Time { return clock },
+			sleep:              func(time.Duration) {},
+		}
+
+		actualDelays := []time.Duration{}
+		for _, requestedAt := range testCase.requestedAt {
+			clock = startedAt.Add(requestedAt)
+			actualDelays = append(actualDelays, limiter.reserve())
+		}
+
+		assert.Equal(t, testCase.expectedDelays, actualDelays, "For case %s", testCase.name)
+	}
+}
+
+func TestStartLimiterWait(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2018, 3, 14, 12, 0, 0, 0, time.UTC)
+	slept := []time.Duration{}
+	throttled := []time.Duration{}
+
+	limiter := &startLimiter{
+		stagger: 3 * time.Second,
+		now:     func() time.Time { return now },
+		sleep:   func(delay time.Duration) { slept = append(slept, delay) },
+	}
+	onThrottle := func(delay time.Duration) { throttled = append(throttled, delay) }
+
+	assert.Equal(t, time.Duration(0), limiter.wait(onThrottle))
+	assert.Equal(t, 3*time.Second, limiter.wait(onThrottle))
+
+	assert.Equal(t, []time.Duration{3 * time.Second}, slept)
+	assert.Equal(t, []time.Duration{3 * time.Second}, throttled)
+}
+
+func TestStartLimiterWaitNil(t *testing.T) {
+	t.Parallel()
+
+	var limiter *startLimiter
+	assert.Equal(t, time.Duration(0), limiter.wait(func(time.Duration) { t.Fatal("A nil startLimiter should never throttle") }))
+}
+
+func TestRunModulesWithStartLimiter(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	now := time.Date(2018, 3, 14, 12, 0, 0, 0, time.UTC)
+	slept := []time.Duration{}
+
+	limiter := &startLimiter{
+		stagger: 3 * time.Second,
+		now:     func() time.Time { return now },
+		sleep: func(delay time.Duration) {
+			lock.Lock()
+			defer lock.Unlock()
+			slept = append(slept, delay)
+		},
+	}
+
+	aRan := false
+	moduleA := &TerraformModule{
+		Path:              "a",
+		Dependencies:      []*TerraformModule{},
+		Config:            config.TerragruntConfig{},
+		TerragruntOptions: optionsWithMockTerragruntCommand(t, "a", nil, &aRan),
+	}
+
+	bRan := false
+	moduleB := &TerraformModule{
+		Path:              "b",
+		Dependencies:      []*TerraformModule{},
+		Config:            config.TerragruntConfig{},
+		TerragruntOptions: optionsWithMockTerragruntCommand(t, "b", nil, &bRan),
+	}
+
+	cRan := false
+	moduleC := &TerraformModule{
+		Path:              "c",
+		Dependencies:      []*TerraformModule{moduleA},
+		Config:            config.TerragruntConfig{},
+		TerragruntOptions: optionsWithMockTerragruntCommand(t, "c", nil, &cRan),
+	}
+
+	runningModules, err := toRunningModules([]*TerraformModule{moduleA, moduleB, moduleC}, NormalOrder)
+	assert.Nil(t, err)
+
+	err = runModulesWithProgress(runningModules, newRunProgress(runningModules), limiter)
+	assert.Nil(t, err)
+
+	assert.True(t, aRan)
+	assert.True(t, bRan)
+	assert.True(t, cRan)
+
+	// The fake clock never moves, so every start after the first one has to wait for the ones before it
+	assert.ElementsMatch(t, []time.Duration{3 * time.Second, 6 * time.Second}, slept)
+}
@@ -0,0 +1,101 @@
+package configstack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// The address the status server binds to, unless another one is specified. Only processes on the same machine can
+// connect to it.
+const DEFAULT_STATUS_BIND_ADDRESS = "127.0.0.1"
+
+// How long to wait for in-flight requests to the status server to finish when shutting it down
+const STATUS_SERVER_SHUTDOWN_TIMEOUT = 5 * time.Second
+
+// StatusServer is a tiny HTTP server that reports the progress of an xxx-all run as JSON, so that CI systems and other
+// tools can show something useful during long runs
+type StatusServer struct {
+	listener net.Listener
+	server   *http.Server
+	done     chan struct{}
+}
+
+// Start serving the given progress on the address configured in the given options
+func StartStatusServer(terragruntOptions *options.TerragruntOptions, progress *RunProgress) (*StatusServer, error) {
+	bindAddress := terragruntOptions.StatusBindAddress
+	if bindAddress == "" {
+		bindAddress = DEFAULT_STATUS_BIND_ADDRESS
+	}
+	address := net.JoinHostPort(bindAddress, fmt.Sprintf("%d", terragruntOptions.StatusPort))
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, errors.WithStackTrace(StatusServerFailedToStart{Address: address, Underlying: err})
+	}
+
+	statusServer := &StatusServer{
+		listener: listener,
+		server:   &http.Server{Handler: newStatusHandler(progress)},
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(statusServer.done)
+		if err := statusServer.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			terragruntOptions.Logger.Printf("WARNING: Status server on %s stopped unexpectedly: %v", address, err)
+		}
+	}()
+
+	terragruntOptions.Logger.Printf("Serving the status of this run at http://%s/status", listener.Addr().String())
+	return statusServer, nil
+}
+
+// Return the address the server is listening on, such as 127.0.0.1:8123
+func (statusServer *StatusServer) Address() string {
+	return statusServer.listener.Addr().String()
+}
+
+// Stop the server, waiting for in-flight requests to finish
+func (statusServer *StatusServer) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), STATUS_SERVER_SHUTDOWN_TIMEOUT)
+	defer cancel()
+
+	err := statusServer.server.Shutdown(ctx)
+	<-statusServer.done
+	return errors.WithStackTrace(err)
+}
+
+// Return an HTTP handler that serves a report of the given progress as JSON
+func newStatusHandler(progress *RunProgress) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodGet && request.Method != http.MethodHead {
+			http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(progress.Report())
+	})
+	return mux
+}
+
+// Custom error types
+
+type StatusServerFailedToStart struct {
+	Address    string
+	Underlying error
+}
+
+func (err StatusServerFailedToStart) Error() string {
+	return fmt.Sprintf("Unable to start the status server on %s: %v", err.Address, err.Underlying)
+}
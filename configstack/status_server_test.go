@@ -0,0 +1,128 @@
+package configstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusHandlerDuringRun(t *testing.T) {
+	t.Parallel()
+
+	aStarted := make(chan struct{})
+	releaseA := make(chan struct{})
+
+	optionsA, err := options.NewTerragruntOptionsForTest("a")
+	require.NoError(t, err)
+	optionsA.RunTerragrunt = func(terragruntOptions *options.TerragruntOptions) error {
+		fmt.Fprintln(terragruntOptions.Writer, "Refreshing state...")
+		close(aStarted)
+		<-releaseA
+		return nil
+	}
+
+	bRan := false
+	moduleA := &TerraformModule{Path: "a", Config: config.TerragruntConfig{}, TerragruntOptions: optionsA}
+	moduleB := &TerraformModule{Path: "b", Dependencies: []*TerraformModule{moduleA}, Config: config.TerragruntConfig{}, TerragruntOptions: optionsWithMockTerragruntCommand(t, "b", nil, &bRan)}
+
+	runningModules, err := toRunningModules([]*TerraformModule{moduleA, moduleB}, NormalOrder)
+	require.NoError(t, err)
+
+	progress := newRunProgress(runningModules)
+	progress.captureOutput = true
+
+	server := httptest.NewServer(newStatusHandler(progress))
+	defer server.Close()
+
+	runErr := make(chan error)
+	go func() {
+		runErr <- runModulesWithProgress(runningModules, progress, nil)
+	}()
+
+	<-aStarted
+	report := getStatusReport(t, server.URL)
+	assert.Equal(t, 0, report.CurrentGroup)
+	assert.Equal(t, []ModuleProgressReport{
+		{Path: "a", State: ModuleRunning, Group: 0, LastLogLines: []string{"Refreshing state..."}},
+		{Path: "b", State: ModulePending, Group: 1},
+	}, report.Modules)
+
+	close(releaseA)
+	require.NoError(t, <-runErr)
+	assert.True(t, bRan)
+
+	report = getStatusReport(t, server.URL)
+	assert.Equal(t, -1, report.CurrentGroup)
+	assert.Equal(t, 2, report.Counts[ModuleSucceeded])
+	assert.Equal(t, []ModuleProgressReport{
+		{Path: "a", State: ModuleSucceeded, Group: 0},
+		{Path: "b", State: ModuleSucceeded, Group: 1},
+	}, report.Modules)
+}
+
+func TestStatusHandlerRejectsOtherMethods(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(newStatusHandler(newRunProgress(map[string]*runningModule{})))
+	defer server.Close()
+
+	response, err := http.Post(server.URL+"/status", "application/json", nil)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+}
+
+func TestStartStatusServer(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-for-test.tfvars")
+	require.NoError(t, err)
+	terragruntOptions.StatusPort = freePort(t)
+
+	statusServer, err := StartStatusServer(terragruntOptions, newRunProgress(map[string]*runningModule{}))
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%s:%d", DEFAULT_STATUS_BIND_ADDRESS, terragruntOptions.StatusPort), statusServer.Address())
+
+	report := getStatusReport(t, "http://"+statusServer.Address())
+	assert.Equal(t, -1, report.CurrentGroup)
+	assert.Empty(t, report.Modules)
+
+	// A second server can't use the same port while the first one is still running
+	_, err = StartStatusServer(terragruntOptions, newRunProgress(map[string]*runningModule{}))
+	if assert.Error(t, err) {
+		assert.IsType(t, StatusServerFailedToStart{}, errors.Unwrap(err))
+	}
+
+	assert.NoError(t, statusServer.Shutdown())
+}
+
+func getStatusReport(t *testing.T, url string) RunProgressReport {
+	response, err := http.Get(url + "/status")
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+
+	report := RunProgressReport{}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&report))
+	return report
+}
+
+// Return a port that's free on the loopback interface at the time of the call
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", net.JoinHostPort(DEFAULT_STATUS_BIND_ADDRESS, "0"))
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/gruntwork-io/terragrunt/aws_helper"
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
@@ -37,7 +38,7 @@ func CreateDynamoDbClient(config *aws_helper.AwsSessionConfig, terragruntOptions
 }
 
 // Create the lock table in DynamoDB if it doesn't already exist
-func CreateLockTableIfNecessary(tableName string, tags map[string]string, client *dynamodb.DynamoDB, terragruntOptions *options.TerragruntOptions) error {
+func CreateLockTableIfNecessary(tableName string, tags map[string]string, client dynamodbiface.DynamoDBAPI, terragruntOptions *options.TerragruntOptions) error {
 	tableExists, err := LockTableExistsAndIsActive(tableName, client)
 	if err != nil {
 		return err
@@ -52,7 +53,7 @@ func CreateLockTableIfNecessary(tableName string, tags map[string]string, client
 }
 
 // Return true if the lock table exists in DynamoDB and is in "active" state
-func LockTableExistsAndIsActive(tableName string, client *dynamodb.DynamoDB) (bool, error) {
+func LockTableExistsAndIsActive(tableName string, client dynamodbiface.DynamoDBAPI) (bool, error) {
 	output, err := client.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
 	if err != nil {
 		if awsErr, isAwsErr := err.(awserr.Error); isAwsErr && awsErr.Code() == "ResourceNotFoundException" {
@@ -67,7 +68,7 @@ func LockTableExistsAndIsActive(tableName string, client *dynamodb.DynamoDB) (bo
 
 // Create a lock table in DynamoDB and wait until it is in "active" state. If the table already exists, merely wait
 // until it is in "active" state.
-func CreateLockTable(tableName string, tags map[string]string, readCapacityUnits int, writeCapacityUnits int, client *dynamodb.DynamoDB, terragruntOptions *options.TerragruntOptions) error {
+func CreateLockTable(tableName string, tags map[string]string, readCapacityUnits int, writeCapacityUnits int, client dynamodbiface.DynamoDBAPI, terragruntOptions *options.TerragruntOptions) error {
 	tableCreateDeleteSemaphore.Acquire()
 	defer tableCreateDeleteSemaphore.Release()
 
@@ -118,7 +119,7 @@ func CreateLockTable(tableName string, tags map[string]string, readCapacityUnits
 	return nil
 }
 
-func tagTableIfTagsGiven(tags map[string]string, tableArn *string, client *dynamodb.DynamoDB, terragruntOptions *options.TerragruntOptions) error {
+func tagTableIfTagsGiven(tags map[string]string, tableArn *string, client dynamodbiface.DynamoDBAPI, terragruntOptions *options.TerragruntOptions) error {
 
 	if tags == nil || len(tags) == 0 {
 		terragruntOptions.Logger.Printf("No tags for lock table given.")
@@ -144,7 +145,7 @@ func tagTableIfTagsGiven(tags map[string]string, tableArn *string, client *dynam
 }
 
 // Delete the given table in DynamoDB
-func DeleteTable(tableName string, client *dynamodb.DynamoDB) error {
+func DeleteTable(tableName string, client dynamodbiface.DynamoDBAPI) error {
 	tableCreateDeleteSemaphore.Acquire()
 	defer tableCreateDeleteSemaphore.Release()
 
@@ -160,14 +161,14 @@ func isTableAlreadyBeingCreatedError(err error) bool {
 
 // Wait for the given DynamoDB table to be in the "active" state. If it's not in "active" state, sleep for the
 // specified amount of time, and try again, up to a maximum of maxRetries retries.
-func waitForTableToBeActive(tableName string, client *dynamodb.DynamoDB, maxRetries int, sleepBetweenRetries time.Duration, terragruntOptions *options.TerragruntOptions) error {
+func waitForTableToBeActive(tableName string, client dynamodbiface.DynamoDBAPI, maxRetries int, sleepBetweenRetries time.Duration, terragruntOptions *options.TerragruntOptions) error {
 	return waitForTableToBeActiveWithRandomSleep(tableName, client, maxRetries, sleepBetweenRetries, sleepBetweenRetries, terragruntOptions)
 }
 
 // Waits for the given table as described above, but sleeps a random amount of time greater than sleepBetweenRetriesMin
 // and less than sleepBetweenRetriesMax between tries. This is to avoid an AWS issue where all waiting requests fire at
 // the same time, which continually triggered AWS's "subscriber limit exceeded" API error.
-func waitForTableToBeActiveWithRandomSleep(tableName string, client *dynamodb.DynamoDB, maxRetries int, sleepBetweenRetriesMin time.Duration, sleepBetweenRetriesMax time.Duration, terragruntOptions *options.TerragruntOptions) error {
+func waitForTableToBeActiveWithRandomSleep(tableName string, client dynamodbiface.DynamoDBAPI, maxRetries int, sleepBetweenRetriesMin time.Duration, sleepBetweenRetriesMax time.Duration, terragruntOptions *options.TerragruntOptions) error {
 	for i := 0; i < maxRetries; i++ {
 		tableReady, err := LockTableExistsAndIsActive(tableName, client)
 		if err != nil {
@@ -196,6 +197,10 @@ func (err TableActiveRetriesExceeded) Error() string {
 	return fmt.Sprintf("Table %s is still not in active state after %d retries.", err.TableName, err.Retries)
 }
 
+func (err TableActiveRetriesExceeded) Class() errors.ErrorClass {
+	return errors.ErrorClassLock
+}
+
 type TableDoesNotExist struct {
 	TableName  string
 	Underlying error
@@ -0,0 +1,79 @@
+package errors
+
+import (
+	goerrors "github.com/go-errors/errors"
+)
+
+// The class of failure an error belongs to. Terragrunt exits with a different code for each class (see cli.ExitCode),
+// so scripts and CI can tell, for example, an invalid config from a failed Terraform command.
+type ErrorClass int
+
+const (
+	// Any failure that doesn't belong to one of the other classes, such as a failed Terraform command
+	ErrorClassUnknown ErrorClass = iota
+
+	// The Terragrunt config couldn't be parsed, or isn't valid
+	ErrorClassConfig
+
+	// The dependencies between modules are invalid, such as when they form a cycle
+	ErrorClassDependencyGraph
+
+	// The remote state couldn't be initialized, such as when the S3 bucket couldn't be created
+	ErrorClassRemoteState
+
+	// Terragrunt gave up waiting for a lock, or for a resource it created to become ready
+	ErrorClassLock
+)
+
+// Interface for errors that always belong to the same class of failure, such as a dependency cycle
+type IErrorClass interface {
+	Class() ErrorClass
+}
+
+// An error that was given a class of failure where it was returned, for errors whose type doesn't say what the failure
+// was, such as those of the AWS SDK
+type ErrorWithClass struct {
+	Underlying error
+	ErrorClass ErrorClass
+}
+
+func (err ErrorWithClass) Error() string {
+	return err.Underlying.Error()
+}
+
+// Give the given error the given class of failure, unless it already has a class of its own (see ClassOf), which is
+// more specific, in which case it's returned unchanged. Unwrap and PrintErrorWithStackTrace see through the returned
+// error, so it can be returned in place of the given error. If the given error is nil, return nil.
+func WithClass(err error, class ErrorClass) error {
+	if err == nil || ClassOf(err) != ErrorClassUnknown {
+		return err
+	}
+
+	return ErrorWithClass{Underlying: err, ErrorClass: class}
+}
+
+// Return the class of failure of the given error: the class of the first error it wraps that has one, looking through
+// stack traces, errors given a class with WithClass, and MultiErrors, or ErrorClassUnknown if none of them do
+func ClassOf(err error) ErrorClass {
+	if goError, isGoError := err.(*goerrors.Error); isGoError {
+		return ClassOf(goError.Err)
+	}
+
+	switch underlyingErr := err.(type) {
+	case ErrorWithClass:
+		if class := ClassOf(underlyingErr.Underlying); class != ErrorClassUnknown {
+			return class
+		}
+		return underlyingErr.ErrorClass
+	case IErrorClass:
+		return underlyingErr.Class()
+	case MultiError:
+		for _, err := range underlyingErr.Errors {
+			if class := ClassOf(err); class != ErrorClassUnknown {
+				return class
+			}
+		}
+	}
+
+	return ErrorClassUnknown
+}
@@ -29,18 +29,25 @@ func WithStackTraceAndPrefix(err error, message string, args ...interface{}) err
 // Returns true if actual is the same type of error as expected. This method unwraps the given error objects (if they
 // are wrapped in objects with a stacktrace) and then does a simple equality check on them.
 func IsError(actual error, expected error) bool {
-	return goerrors.Is(actual, expected)
+	return goerrors.Is(Unwrap(actual), Unwrap(expected))
 }
 
-// If the given error is a wrapper that contains a stacktrace, unwrap it and return the original, underlying error.
-// In all other cases, return the error unchanged
+// If the given error is a wrapper that contains a stacktrace, or that gives an error a class (see WithClass), unwrap it
+// and return the original, underlying error. In all other cases, return the error unchanged
 func Unwrap(err error) error {
 	if err == nil {
 		return nil
 	}
 
+	if classified, isClassified := err.(ErrorWithClass); isClassified {
+		return Unwrap(classified.Underlying)
+	}
+
 	goError, isGoError := err.(*goerrors.Error)
 	if isGoError {
+		if classified, isClassified := goError.Err.(ErrorWithClass); isClassified {
+			return Unwrap(classified)
+		}
 		return goError.Err
 	}
 
@@ -54,6 +61,8 @@ func PrintErrorWithStackTrace(err error) string {
 	}
 
 	switch underlyingErr := err.(type) {
+	case ErrorWithClass:
+		return PrintErrorWithStackTrace(underlyingErr.Underlying)
 	case *goerrors.Error:
 		return underlyingErr.ErrorStack()
 	default:
@@ -3,7 +3,6 @@ package main
 import (
 	"github.com/gruntwork-io/terragrunt/cli"
 	"github.com/gruntwork-io/terragrunt/errors"
-	"github.com/gruntwork-io/terragrunt/shell"
 	"github.com/gruntwork-io/terragrunt/util"
 	"os"
 )
@@ -33,13 +32,8 @@ func checkForErrorsAndExit(err error) {
 		} else {
 			logger.Println(err)
 		}
-		// exit with the underlying error code
-		exitCode, exitCodeErr := shell.GetExitCode(err)
-		if exitCodeErr != nil {
-			exitCode = 1
-			logger.Println("Unable to determine underlying exit code, so Terragrunt will exit with error code 1")
-		}
-		os.Exit(exitCode)
+		// exit with the code for the class of the error, or the underlying error code
+		os.Exit(cli.ExitCode(err))
 	}
 
 }
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"time"
 
 	"github.com/gruntwork-io/terragrunt/errors"
@@ -21,6 +22,9 @@ var TERRAFORM_COMMANDS_WITH_SUBCOMMAND = []string{
 
 const DEFAULT_MAX_FOLDERS_TO_CHECK = 100
 
+// How long helpers that make HTTP requests, such as http_get_json, wait for a response by default
+const DEFAULT_HTTP_TIMEOUT = 10 * time.Second
+
 const TerragruntCacheDir = ".terragrunt-cache"
 
 // TerragruntOptions represents options that configure the behavior of the Terragrunt program
@@ -37,6 +41,9 @@ type TerragruntOptions struct {
 	// Version of terraform (obtained by running 'terraform version')
 	TerraformVersion *version.Version
 
+	// Version of Terragrunt itself, or nil if it's unknown, such as in a build from source without a version
+	TerragruntVersion *version.Version
+
 	// Whether we should prompt the user for confirmation or always assume "yes"
 	NonInteractive bool
 
@@ -46,9 +53,14 @@ type TerragruntOptions struct {
 	// CLI args that are intended for Terraform (i.e. all the CLI args except the --terragrunt ones)
 	TerraformCliArgs []string
 
-	// The working directory in which to run Terraform
+	// The working directory in which to run Terraform. When the Terraform code is downloaded from a source, this is
+	// changed to the folder it's downloaded into.
 	WorkingDir string
 
+	// The working directory of the module before any Terraform code was downloaded, i.e. the folder the user ran
+	// Terragrunt in, or the module's folder for the modules of an xxx-all command. Unlike WorkingDir, it never changes.
+	OriginalWorkingDir string
+
 	// The logger to use for all logging
 	Logger *log.Logger
 
@@ -71,6 +83,9 @@ type TerragruntOptions struct {
 	// If set to true, continue running *-all commands even if a dependency has errors. This is mostly useful for 'output-all <some_variable>'. See https://github.com/gruntwork-io/terragrunt/issues/193
 	IgnoreDependencyErrors bool
 
+	// If you want prompts to read the user's answers from somewhere other than os.stdin
+	Reader io.Reader
+
 	// If you want stdout to go somewhere other than os.stdout
 	Writer io.Writer
 
@@ -99,6 +114,103 @@ type TerragruntOptions struct {
 	// Unix-style glob of directories to include when running *-all commands
 	IncludeDirs []string
 
+	// If set to true, store a snapshot of the resolved config after each run and report which settings changed since
+	// the previous run
+	TrackConfigChanges bool
+
+	// If set to true, print the full diff between the resolved config of the previous run and the current one
+	ShowConfigDiff bool
+
+	// If set, commands that produce structured data (e.g. terragrunt-info) also write that data as JSON to this path.
+	// If set to "-", they write only the JSON, to stdout.
+	JsonOut string
+
+	// If set to a port number, serve the progress of xxx-all commands as JSON on this port while they run
+	StatusPort int
+
+	// The address the status server binds to. Defaults to localhost only.
+	StatusBindAddress string
+
+	// If set to true, check at most once a day whether a newer version of Terragrunt has been released
+	CheckForUpdates bool
+
+	// The URL to query for the latest Terragrunt release. Defaults to the GitHub releases API.
+	VersionCheckUrl string
+
+	// The minimum time between starting the Terraform processes of two modules in xxx-all commands. Zero means no
+	// minimum.
+	Stagger time.Duration
+
+	// The maximum number of modules xxx-all commands start per minute. Zero means no limit.
+	MaxStartsPerMinute int
+
+	// The working directory of the Terragrunt command the user ran. Unlike WorkingDir, it's the same for all modules of
+	// an xxx-all command, and doesn't change when Terraform code is downloaded into a temporary folder.
+	RootWorkingDir string
+
+	// A unique ID for the Terragrunt command the user ran, shared by all modules of an xxx-all command
+	StackRunId string
+
+	// If set to true, configuration that is almost always a mistake, such as a child config using a different remote
+	// state backend than its parent, is an error rather than a warning, and interpolations that aren't calls to helper
+	// functions, such as ${var.region}, are an error rather than left as is
+	Strict bool
+
+	// If set to true, a call to a helper function in the config that returns an empty value, such as an env var that's
+	// set, but blank, is an error, unless the call is wrapped in allow_empty
+	FailOnEmpty bool
+
+	// If set to true, render-inputs shows the values of variables that look like secrets instead of masking them
+	IncludeSensitive bool
+
+	// If set, apply-all skips the modules that succeeded in the run recorded in this resume state file, unless anything
+	// that affects them changed since. "last" means the file apply-all writes for the working dir (see
+	// configstack.ResumeStatePath).
+	Resume string
+
+	// How long helpers that make HTTP requests, such as http_get_json, wait for the response before giving up
+	HttpTimeout time.Duration
+
+	// Variables whose names match one of these are treated as secrets, on top of those whose names look like secrets,
+	// so their values are masked wherever Terragrunt shows them: in render-inputs, in the -var args of the commands it
+	// logs, and in config snapshots
+	SensitiveVarPatterns []*regexp.Regexp
+
+	// If set to true, plan and apply also run in the dependencies of the module, in dependency order, using the same
+	// machinery as the xxx-all commands
+	IncludeDependencies bool
+
+	// How many levels of dependencies plan runs in with IncludeDependencies, such as 1 for only the direct
+	// dependencies. Zero means the default. Apply always runs in all of them.
+	IncludeDependenciesDepth int
+
+	// How many times each helper function was called while parsing configs, and how long the calls took. It's shared by
+	// all the modules of an xxx-all command, so it covers the whole run.
+	HelperMetrics *util.CallMetrics
+
+	// If set to true, HelperMetrics is logged each time a config has been parsed
+	LogHelperMetrics bool
+
+	// If set to true, none of the extra_arguments blocks of the config are used for this run
+	DisableExtraArgs bool
+
+	// The names of the extra_arguments blocks of the config that aren't used for this run
+	DisabledExtraArgs []string
+
+	// If set to true, none of the before_hook and after_hook blocks of the config are run
+	DisableHooks bool
+
+	// The names of the before_hook and after_hook blocks of the config that aren't run
+	DisabledHooks []string
+
+	// The configs and tfvars files that are being read by calls to read_tfvars_file, outermost first, which leads up to
+	// the file in TerragruntConfigPath, so a file that ends up reading itself can be reported instead of read forever
+	TfVarsFilesBeingRead []string
+
+	// The warnings that have been logged so far, such as about deprecated config settings, so each one is only logged
+	// once. It's shared by all the modules of an xxx-all command, so it covers the whole run.
+	LoggedWarnings *util.OnceSet
+
 	// A command that can be used to run Terragrunt with the given options. This is useful for running Terragrunt
 	// multiple times (e.g. when spinning up a stack of Terraform modules). The actual command is normally defined
 	// in the cli package, which depends on almost all other packages, so we declare it here so that other
@@ -124,12 +236,14 @@ func NewTerragruntOptions(terragruntConfigPath string) (*TerragruntOptions, erro
 		NonInteractive:         false,
 		TerraformCliArgs:       []string{},
 		WorkingDir:             workingDir,
+		OriginalWorkingDir:     workingDir,
 		Logger:                 logger,
 		Env:                    map[string]string{},
 		Source:                 "",
 		SourceUpdate:           false,
 		DownloadDir:            downloadDir,
 		IgnoreDependencyErrors: false,
+		Reader:                 os.Stdin,
 		Writer:                 os.Stdout,
 		ErrWriter:              os.Stderr,
 		MaxFoldersToCheck:      DEFAULT_MAX_FOLDERS_TO_CHECK,
@@ -139,6 +253,12 @@ func NewTerragruntOptions(terragruntConfigPath string) (*TerragruntOptions, erro
 		RetryableErrors:        util.CloneStringList(RETRYABLE_ERRORS),
 		ExcludeDirs:            []string{},
 		IncludeDirs:            []string{},
+		DisabledExtraArgs:      []string{},
+		DisabledHooks:          []string{},
+		HttpTimeout:            DEFAULT_HTTP_TIMEOUT,
+		SensitiveVarPatterns:   []*regexp.Regexp{},
+		HelperMetrics:          util.NewCallMetrics(),
+		LoggedWarnings:         util.NewOnceSet(),
 		RunTerragrunt: func(terragruntOptions *TerragruntOptions) error {
 			return errors.WithStackTrace(RunTerragruntCommandNotSet)
 		},
@@ -157,6 +277,18 @@ func DefaultWorkingAndDownloadDirs(terragruntConfigPath string) (string, string,
 	return workingDir, downloadDir, nil
 }
 
+// Get the download directory to use for the given Terragrunt config path if the default download directory can't be
+// written to, such as when running in a container with a read-only file system. It's a folder in the system temp dir
+// that is unique to the config's working directory.
+func ScratchDownloadDir(terragruntConfigPath string) (string, error) {
+	workingDir, err := filepath.Abs(filepath.Dir(terragruntConfigPath))
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	return filepath.Join(os.TempDir(), "terragrunt-cache", util.EncodeBase64Sha1(filepath.ToSlash(workingDir))), nil
+}
+
 // Create a new TerragruntOptions object with reasonable defaults for test usage
 func NewTerragruntOptionsForTest(terragruntConfigPath string) (*TerragruntOptions, error) {
 	opts, err := NewTerragruntOptions(terragruntConfigPath)
@@ -181,31 +313,61 @@ func (terragruntOptions *TerragruntOptions) Clone(terragruntConfigPath string) *
 	// during xxx-all commands (e.g., apply-all, plan-all). See https://github.com/gruntwork-io/terragrunt/issues/367
 	// for more info.
 	return &TerragruntOptions{
-		TerragruntConfigPath:   terragruntConfigPath,
-		TerraformPath:          terragruntOptions.TerraformPath,
-		TerraformCommand:       terragruntOptions.TerraformCommand,
-		TerraformVersion:       terragruntOptions.TerraformVersion,
-		AutoInit:               terragruntOptions.AutoInit,
-		NonInteractive:         terragruntOptions.NonInteractive,
-		TerraformCliArgs:       util.CloneStringList(terragruntOptions.TerraformCliArgs),
-		WorkingDir:             workingDir,
-		Logger:                 util.CreateLoggerWithWriter(terragruntOptions.ErrWriter, workingDir),
-		Env:                    util.CloneStringMap(terragruntOptions.Env),
-		Source:                 terragruntOptions.Source,
-		SourceUpdate:           terragruntOptions.SourceUpdate,
-		DownloadDir:            terragruntOptions.DownloadDir,
-		IamRole:                terragruntOptions.IamRole,
-		IgnoreDependencyErrors: terragruntOptions.IgnoreDependencyErrors,
-		Writer:                 terragruntOptions.Writer,
-		ErrWriter:              terragruntOptions.ErrWriter,
-		MaxFoldersToCheck:      terragruntOptions.MaxFoldersToCheck,
-		AutoRetry:              terragruntOptions.AutoRetry,
-		MaxRetryAttempts:       terragruntOptions.MaxRetryAttempts,
-		Sleep:                  terragruntOptions.Sleep,
-		RetryableErrors:        util.CloneStringList(terragruntOptions.RetryableErrors),
-		ExcludeDirs:            terragruntOptions.ExcludeDirs,
-		IncludeDirs:            terragruntOptions.IncludeDirs,
-		RunTerragrunt:          terragruntOptions.RunTerragrunt,
+		TerragruntConfigPath:     terragruntConfigPath,
+		TerraformPath:            terragruntOptions.TerraformPath,
+		TerraformCommand:         terragruntOptions.TerraformCommand,
+		TerraformVersion:         terragruntOptions.TerraformVersion,
+		TerragruntVersion:        terragruntOptions.TerragruntVersion,
+		AutoInit:                 terragruntOptions.AutoInit,
+		NonInteractive:           terragruntOptions.NonInteractive,
+		TerraformCliArgs:         util.CloneStringList(terragruntOptions.TerraformCliArgs),
+		WorkingDir:               workingDir,
+		OriginalWorkingDir:       workingDir,
+		Logger:                   util.CreateLoggerWithWriter(terragruntOptions.ErrWriter, workingDir),
+		Env:                      util.CloneStringMap(terragruntOptions.Env),
+		Source:                   terragruntOptions.Source,
+		SourceUpdate:             terragruntOptions.SourceUpdate,
+		DownloadDir:              terragruntOptions.DownloadDir,
+		IamRole:                  terragruntOptions.IamRole,
+		IgnoreDependencyErrors:   terragruntOptions.IgnoreDependencyErrors,
+		Reader:                   terragruntOptions.Reader,
+		Writer:                   terragruntOptions.Writer,
+		ErrWriter:                terragruntOptions.ErrWriter,
+		MaxFoldersToCheck:        terragruntOptions.MaxFoldersToCheck,
+		AutoRetry:                terragruntOptions.AutoRetry,
+		MaxRetryAttempts:         terragruntOptions.MaxRetryAttempts,
+		Sleep:                    terragruntOptions.Sleep,
+		RetryableErrors:          util.CloneStringList(terragruntOptions.RetryableErrors),
+		ExcludeDirs:              terragruntOptions.ExcludeDirs,
+		IncludeDirs:              terragruntOptions.IncludeDirs,
+		TrackConfigChanges:       terragruntOptions.TrackConfigChanges,
+		ShowConfigDiff:           terragruntOptions.ShowConfigDiff,
+		JsonOut:                  terragruntOptions.JsonOut,
+		StatusPort:               terragruntOptions.StatusPort,
+		StatusBindAddress:        terragruntOptions.StatusBindAddress,
+		CheckForUpdates:          terragruntOptions.CheckForUpdates,
+		VersionCheckUrl:          terragruntOptions.VersionCheckUrl,
+		Stagger:                  terragruntOptions.Stagger,
+		MaxStartsPerMinute:       terragruntOptions.MaxStartsPerMinute,
+		RootWorkingDir:           terragruntOptions.RootWorkingDir,
+		StackRunId:               terragruntOptions.StackRunId,
+		Strict:                   terragruntOptions.Strict,
+		FailOnEmpty:              terragruntOptions.FailOnEmpty,
+		IncludeSensitive:         terragruntOptions.IncludeSensitive,
+		Resume:                   terragruntOptions.Resume,
+		HttpTimeout:              terragruntOptions.HttpTimeout,
+		SensitiveVarPatterns:     terragruntOptions.SensitiveVarPatterns,
+		IncludeDependencies:      terragruntOptions.IncludeDependencies,
+		IncludeDependenciesDepth: terragruntOptions.IncludeDependenciesDepth,
+		HelperMetrics:            terragruntOptions.HelperMetrics,
+		LogHelperMetrics:         terragruntOptions.LogHelperMetrics,
+		DisableExtraArgs:         terragruntOptions.DisableExtraArgs,
+		DisabledExtraArgs:        util.CloneStringList(terragruntOptions.DisabledExtraArgs),
+		DisableHooks:             terragruntOptions.DisableHooks,
+		DisabledHooks:            util.CloneStringList(terragruntOptions.DisabledHooks),
+		TfVarsFilesBeingRead:     util.CloneStringList(terragruntOptions.TfVarsFilesBeingRead),
+		LoggedWarnings:           terragruntOptions.LoggedWarnings,
+		RunTerragrunt:            terragruntOptions.RunTerragrunt,
 	}
 }
 
@@ -228,6 +390,16 @@ func (terragruntOptions *TerragruntOptions) InsertTerraformCliArgs(argsToInsert
 	terragruntOptions.TerraformCliArgs = args
 }
 
+// Return true if the extra_arguments block of the given name is not to be used for this run
+func (terragruntOptions *TerragruntOptions) IsExtraArgsDisabled(name string) bool {
+	return terragruntOptions.DisableExtraArgs || util.ListContainsElement(terragruntOptions.DisabledExtraArgs, name)
+}
+
+// Return true if the hook of the given name is not to be run
+func (terragruntOptions *TerragruntOptions) IsHookDisabled(name string) bool {
+	return terragruntOptions.DisableHooks || util.ListContainsElement(terragruntOptions.DisabledHooks, name)
+}
+
 // Appends the given argsToAppend after the current TerraformCliArgs
 func (terragruntOptions *TerragruntOptions) AppendTerraformCliArgs(argsToAppend ...string) {
 	terragruntOptions.TerraformCliArgs = append(terragruntOptions.TerraformCliArgs, argsToAppend...)
@@ -4,15 +4,31 @@ import (
 	"fmt"
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
 	"reflect"
+	"sort"
 )
 
 // Configuration for Terraform remote state
 type RemoteState struct {
 	Backend string                 `hcl:"backend"`
 	Config  map[string]interface{} `hcl:"config"`
+
+	// Set to true in a child config to acknowledge that it deliberately uses a different backend than the parent config
+	// it includes, which is otherwise reported as a likely mistake
+	OverrideParentBackend bool `hcl:"override_parent_backend"`
+
+	// Set to true to back up the current state before each apply or destroy. Only the s3 backend supports this.
+	BackupBeforeApply bool `hcl:"backup_before_apply"`
+
+	// The number of state backups to keep when BackupBeforeApply is set. Older backups beyond it are deleted. 0 keeps
+	// all of them.
+	BackupRetention int `hcl:"backup_retention"`
 }
 
+// The Terraform commands that change the state, before which the state is backed up if backup_before_apply is set
+var TERRAFORM_COMMANDS_THAT_NEED_STATE_BACKUP = []string{"apply", "destroy"}
+
 func (remoteState *RemoteState) String() string {
 	return fmt.Sprintf("RemoteState{Backend = %v, Config = %v}", remoteState.Backend, remoteState.Config)
 }
@@ -45,9 +61,27 @@ func (remoteState *RemoteState) Validate() error {
 		return errors.WithStackTrace(RemoteBackendMissing)
 	}
 
+	if remoteState.BackupBeforeApply && remoteState.Backend != "s3" {
+		return errors.WithStackTrace(StateBackupNotSupported(remoteState.Backend))
+	}
+
+	if remoteState.BackupRetention < 0 {
+		return errors.WithStackTrace(InvalidBackupRetention(remoteState.BackupRetention))
+	}
+
 	return nil
 }
 
+// Back up the current state before running the given Terraform command, if backup_before_apply is set and the command
+// changes the state, such as apply or destroy
+func (remoteState *RemoteState) BackupBeforeCommand(command string, terragruntOptions *options.TerragruntOptions) error {
+	if !remoteState.BackupBeforeApply || !util.ListContainsElement(TERRAFORM_COMMANDS_THAT_NEED_STATE_BACKUP, command) {
+		return nil
+	}
+
+	return BackupS3State(remoteState.Config, remoteState.BackupRetention, terragruntOptions)
+}
+
 // Perform any actions necessary to initialize the remote state before it's used for storage. For example, if you're
 // using S3 for remote state storage, this may create the S3 bucket if it doesn't exist already.
 func (remoteState *RemoteState) Initialize(terragruntOptions *options.TerragruntOptions) error {
@@ -114,10 +148,17 @@ func (remoteState RemoteState) ToTerraformInitArgs() []string {
 		config = initializer.GetTerraformInitArgs(remoteState.Config)
 	}
 
+	// Sort the keys, so the same config always results in exactly the same command line
+	keys := []string{}
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
 	var backendConfigArgs []string = nil
 
-	for key, value := range config {
-		arg := fmt.Sprintf("-backend-config=%s=%v", key, value)
+	for _, key := range keys {
+		arg := fmt.Sprintf("-backend-config=%s=%v", key, config[key])
 		backendConfigArgs = append(backendConfigArgs, arg)
 	}
 
@@ -125,3 +166,15 @@ func (remoteState RemoteState) ToTerraformInitArgs() []string {
 }
 
 var RemoteBackendMissing = fmt.Errorf("The remote_state.backend field cannot be empty")
+
+type StateBackupNotSupported string
+
+func (backend StateBackupNotSupported) Error() string {
+	return fmt.Sprintf("backup_before_apply is only supported by the s3 backend, but remote_state uses the %s backend", string(backend))
+}
+
+type InvalidBackupRetention int
+
+func (retention InvalidBackupRetention) Error() string {
+	return fmt.Sprintf("backup_retention must be 0, to keep all state backups, or more, but got %d", int(retention))
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/gruntwork-io/terragrunt/aws_helper"
 	"github.com/gruntwork-io/terragrunt/dynamodb"
 	"github.com/gruntwork-io/terragrunt/errors"
@@ -12,8 +14,11 @@ import (
 	"github.com/gruntwork-io/terragrunt/shell"
 	"github.com/gruntwork-io/terragrunt/util"
 	"github.com/mitchellh/mapstructure"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -25,9 +30,24 @@ import (
 type ExtendedRemoteStateConfigS3 struct {
 	remoteStateConfigS3 RemoteStateConfigS3
 
-	S3BucketTags         []map[string]string `mapstructure:"s3_bucket_tags"`
-	DynamotableTags      []map[string]string `mapstructure:"dynamodb_table_tags"`
-	SkipBucketVersioning bool                `mapstructure:"skip_bucket_versioning"`
+	S3BucketTags              []map[string]string `mapstructure:"s3_bucket_tags"`
+	DynamotableTags           []map[string]string `mapstructure:"dynamodb_table_tags"`
+	SkipBucketVersioning      bool                `mapstructure:"skip_bucket_versioning"`
+	SkipLockTableCreation     bool                `mapstructure:"skip_lock_table_creation"`
+	SkipLockTableVerification bool                `mapstructure:"skip_lock_table_verification"`
+	LockTableAssumeRole       string              `mapstructure:"lock_table_assume_role"`
+	LockTableRegion           string              `mapstructure:"lock_table_region"`
+}
+
+// The keys of the S3 remote state config that are only used by Terragrunt, and not passed on to Terraform
+var TERRAGRUNT_ONLY_S3_CONFIG_KEYS = []string{
+	"s3_bucket_tags",
+	"dynamodb_table_tags",
+	"skip_bucket_versioning",
+	"skip_lock_table_creation",
+	"skip_lock_table_verification",
+	"lock_table_assume_role",
+	"lock_table_region",
 }
 
 // A representation of the configuration options available for S3 remote state
@@ -57,6 +77,20 @@ func (c *RemoteStateConfigS3) GetAwsSessionConfig() *aws_helper.AwsSessionConfig
 	}
 }
 
+// Builds a session config for the requests Terragrunt makes to the DynamoDB lock table. This is the same as the session
+// config for the S3 bucket, except that lock_table_region and lock_table_assume_role, if set, override the region and
+// IAM role, for lock tables that live in another account or region.
+func (extendedConfig *ExtendedRemoteStateConfigS3) GetLockTableSessionConfig() *aws_helper.AwsSessionConfig {
+	sessionConfig := extendedConfig.remoteStateConfigS3.GetAwsSessionConfig()
+	if extendedConfig.LockTableRegion != "" {
+		sessionConfig.Region = extendedConfig.LockTableRegion
+	}
+	if extendedConfig.LockTableAssumeRole != "" {
+		sessionConfig.RoleArn = extendedConfig.LockTableAssumeRole
+	}
+	return sessionConfig
+}
+
 // The DynamoDB lock table name used to be called lock_table, but has since been renamed to dynamodb_table, and the old
 // name deprecated. To maintain backwards compatibility, we support both names.
 func (s3Config *RemoteStateConfigS3) GetLockTableName() string {
@@ -66,6 +100,9 @@ func (s3Config *RemoteStateConfigS3) GetLockTableName() string {
 	return s3Config.LockTable
 }
 
+// The format of the timestamp in the key of a state backup. Backups of the same state sort in the order they were made.
+const STATE_BACKUP_TIMESTAMP_FORMAT = "20060102T150405Z"
+
 const MAX_RETRIES_WAITING_FOR_S3_BUCKET = 12
 const SLEEP_BETWEEN_RETRIES_WAITING_FOR_S3_BUCKET = 5 * time.Second
 
@@ -80,14 +117,14 @@ func (s3Initializer S3Initializer) NeedsInitialization(config map[string]interfa
 		return true, nil
 	}
 
-	s3Config, err := parseS3Config(config)
+	s3ConfigExtended, err := parseExtendedS3Config(config)
 	if err != nil {
 		return false, err
 	}
 
-	sessionConfig := s3Config.GetAwsSessionConfig()
+	s3Config := &s3ConfigExtended.remoteStateConfigS3
 
-	s3Client, err := CreateS3Client(sessionConfig, terragruntOptions)
+	s3Client, err := CreateS3Client(s3Config.GetAwsSessionConfig(), terragruntOptions)
 	if err != nil {
 		return false, err
 	}
@@ -96,13 +133,13 @@ func (s3Initializer S3Initializer) NeedsInitialization(config map[string]interfa
 		return true, nil
 	}
 
-	if s3Config.GetLockTableName() != "" {
-		dynamodbClient, err := dynamodb.CreateDynamoDbClient(sessionConfig, terragruntOptions)
+	if s3Config.GetLockTableName() != "" && !s3ConfigExtended.SkipLockTableVerification {
+		dynamodbClient, err := dynamodb.CreateDynamoDbClient(s3ConfigExtended.GetLockTableSessionConfig(), terragruntOptions)
 		if err != nil {
 			return false, err
 		}
 
-		tableExists, err := dynamodb.LockTableExistsAndIsActive(s3Config.GetLockTableName(), dynamodbClient)
+		tableExists, err := lockTableExists(s3ConfigExtended, dynamodbClient, terragruntOptions)
 		if err != nil {
 			return false, err
 		}
@@ -151,10 +188,11 @@ func configValuesEqual(config map[string]interface{}, existingBackend *Terraform
 		}
 	}
 
-	// Delete S3 and DynamoDB and Bucket Versioning tags, as these are only stored in Terragrunt config and not in Terraform's backend
-	delete(config, "s3_bucket_tags")
-	delete(config, "dynamodb_table_tags")
-	delete(config, "skip_bucket_versioning")
+	// Delete the settings that are only used by Terragrunt, such as the S3 and DynamoDB tags, as these are only stored in
+	// Terragrunt config and not in Terraform's backend
+	for _, key := range TERRAGRUNT_ONLY_S3_CONFIG_KEYS {
+		delete(config, key)
+	}
 
 	if !reflect.DeepEqual(existingBackend.Config, config) {
 		terragruntOptions.Logger.Printf("Backend config has changed from %s to %s", existingBackend.Config, config)
@@ -191,7 +229,7 @@ func (s3Initializer S3Initializer) Initialize(config map[string]interface{}, ter
 		return err
 	}
 
-	if err := createLockTableIfNecessary(&s3Config, s3ConfigExtended.DynamotableTags, terragruntOptions); err != nil {
+	if err := createLockTableIfNecessary(s3ConfigExtended, terragruntOptions); err != nil {
 		return err
 	}
 
@@ -203,7 +241,7 @@ func (s3Initializer S3Initializer) GetTerraformInitArgs(config map[string]interf
 
 	for key, val := range config {
 
-		if key == "s3_bucket_tags" || key == "dynamodb_table_tags" || key == "skip_bucket_versioning" {
+		if util.ListContainsElement(TERRAGRUNT_ONLY_S3_CONFIG_KEYS, key) {
 			continue
 		}
 
@@ -278,8 +316,12 @@ func validateS3Config(extendedConfig *ExtendedRemoteStateConfigS3, terragruntOpt
 // confirms, create the bucket and enable versioning for it.
 func createS3BucketIfNecessary(s3Client *s3.S3, config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
 	if !DoesS3BucketExist(s3Client, &config.remoteStateConfigS3) {
-		prompt := fmt.Sprintf("Remote state S3 bucket %s does not exist or you don't have permissions to access it. Would you like Terragrunt to create it?", config.remoteStateConfigS3.Bucket)
-		shouldCreateBucket, err := shell.PromptUserForYesNo(prompt, terragruntOptions)
+		terragruntOptions.Logger.Printf("Remote state S3 bucket %s does not exist or you don't have permissions to access it.", config.remoteStateConfigS3.Bucket)
+		changes := []string{fmt.Sprintf("Create S3 bucket %s", config.remoteStateConfigS3.Bucket)}
+		if !config.SkipBucketVersioning {
+			changes = append(changes, fmt.Sprintf("Enable versioning for S3 bucket %s", config.remoteStateConfigS3.Bucket))
+		}
+		shouldCreateBucket, err := shell.ConfirmOrDryRun("Would you like Terragrunt to create it?", changes, false, terragruntOptions)
 		if err != nil {
 			return err
 		}
@@ -430,24 +472,182 @@ func DoesS3BucketExist(s3Client *s3.S3, config *RemoteStateConfigS3) bool {
 	return err == nil
 }
 
-// Create a table for locks in DynamoDB if the user has configured a lock table and the table doesn't already exist
-func createLockTableIfNecessary(s3Config *RemoteStateConfigS3, tagsDeclarations []map[string]string, terragruntOptions *options.TerragruntOptions) error {
+// Create a table for locks in DynamoDB if the user has configured a lock table and the table doesn't already exist,
+// unless skip_lock_table_creation or skip_lock_table_verification is set
+func createLockTableIfNecessary(extendedConfig *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
+	tableName := extendedConfig.remoteStateConfigS3.GetLockTableName()
+	if tableName == "" {
+		return nil
+	}
 
-	if s3Config.GetLockTableName() == "" {
+	if extendedConfig.SkipLockTableVerification {
+		terragruntOptions.Logger.Printf("Not checking the DynamoDB lock table %s, as 'skip_lock_table_verification' is set.", tableName)
 		return nil
 	}
 
-	dynamodbClient, err := dynamodb.CreateDynamoDbClient(s3Config.GetAwsSessionConfig(), terragruntOptions)
+	dynamodbClient, err := dynamodb.CreateDynamoDbClient(extendedConfig.GetLockTableSessionConfig(), terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	return createLockTableIfNecessaryWithClient(extendedConfig, dynamodbClient, terragruntOptions)
+}
+
+// Create the lock table in the given config using the given client, if it doesn't already exist. If
+// skip_lock_table_creation is set, only check that the table exists.
+func createLockTableIfNecessaryWithClient(extendedConfig *ExtendedRemoteStateConfigS3, dynamodbClient dynamodbiface.DynamoDBAPI, terragruntOptions *options.TerragruntOptions) error {
+	tableName := extendedConfig.remoteStateConfigS3.GetLockTableName()
+
+	if !extendedConfig.SkipLockTableCreation {
+		var tags map[string]string = nil
+		if len(extendedConfig.DynamotableTags) == 1 {
+			tags = extendedConfig.DynamotableTags[0]
+		}
+
+		return dynamodb.CreateLockTableIfNecessary(tableName, tags, dynamodbClient, terragruntOptions)
+	}
+
+	tableExists, err := lockTableExists(extendedConfig, dynamodbClient, terragruntOptions)
 	if err != nil {
 		return err
 	}
+	if !tableExists {
+		return errors.WithStackTrace(LockTableDoesNotExist(tableName))
+	}
+	return nil
+}
+
+// Return true if the lock table in the given config exists and is active. If skip_lock_table_creation is set, the
+// table is managed elsewhere, so if the credentials Terragrunt uses aren't allowed to look at it, that's only noted in
+// the debug logs, and the table is assumed to exist: Terraform may still be able to lock via the backend's own role.
+func lockTableExists(extendedConfig *ExtendedRemoteStateConfigS3, dynamodbClient dynamodbiface.DynamoDBAPI, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	tableName := extendedConfig.remoteStateConfigS3.GetLockTableName()
 
-	var tags map[string]string = nil
-	if len(tagsDeclarations) == 1 {
-		tags = tagsDeclarations[0]
+	tableExists, err := dynamodb.LockTableExistsAndIsActive(tableName, dynamodbClient)
+	if err != nil && extendedConfig.SkipLockTableCreation && isAccessDeniedError(err) {
+		if terragruntOptions.Env["TERRAGRUNT_DEBUG"] != "" {
+			terragruntOptions.Logger.Printf("Not allowed to check the DynamoDB lock table %s, so assuming it exists, as 'skip_lock_table_creation' is set: %v", tableName, err)
+		}
+		return true, nil
 	}
+	return tableExists, err
+}
 
-	return dynamodb.CreateLockTableIfNecessary(s3Config.GetLockTableName(), tags, dynamodbClient, terragruntOptions)
+// Return true if the given error is the error returned by AWS when the credentials aren't allowed to make a request
+func isAccessDeniedError(err error) bool {
+	awsErr, isAwsErr := errors.Unwrap(err).(awserr.Error)
+	return isAwsErr && (awsErr.Code() == "AccessDeniedException" || awsErr.Code() == "AccessDenied")
+}
+
+// Back up the current state of the S3 backend in the given config by copying it to <key>.backup/<timestamp> in the same
+// bucket. If retention is more than 0, the oldest backups beyond that many are deleted afterwards.
+func BackupS3State(config map[string]interface{}, retention int, terragruntOptions *options.TerragruntOptions) error {
+	s3Config, err := parseS3Config(config)
+	if err != nil {
+		return err
+	}
+
+	if s3Config.Bucket == "" {
+		return errors.WithStackTrace(MissingRequiredS3RemoteStateConfig("bucket"))
+	}
+
+	if s3Config.Key == "" {
+		return errors.WithStackTrace(MissingRequiredS3RemoteStateConfig("key"))
+	}
+
+	s3Client, err := CreateS3Client(s3Config.GetAwsSessionConfig(), terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	return backupS3StateWithClient(s3Client, s3Config, retention, time.Now(), terragruntOptions)
+}
+
+// Back up the current state of the given S3 config using the given client, naming the backup after the given time. The
+// state is copied within S3, so it's never downloaded. Nothing is backed up if there's no state yet.
+func backupS3StateWithClient(s3Client s3iface.S3API, s3Config *RemoteStateConfigS3, retention int, now time.Time, terragruntOptions *options.TerragruntOptions) error {
+	_, err := s3Client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(s3Config.Bucket), Key: aws.String(s3Config.Key)})
+	if err != nil {
+		if isS3ObjectNotFoundError(err) {
+			terragruntOptions.Logger.Printf("Not backing up the state at s3://%s/%s, as it doesn't exist yet", s3Config.Bucket, s3Config.Key)
+			return nil
+		}
+		return errors.WithStackTrace(err)
+	}
+
+	backupKey := stateBackupPrefix(s3Config.Key) + now.UTC().Format(STATE_BACKUP_TIMESTAMP_FORMAT)
+	copyInput := &s3.CopyObjectInput{
+		Bucket:     aws.String(s3Config.Bucket),
+		Key:        aws.String(backupKey),
+		CopySource: aws.String(s3CopySource(s3Config.Bucket, s3Config.Key)),
+	}
+	if s3Config.Encrypt {
+		copyInput.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAes256)
+	}
+	if _, err := s3Client.CopyObject(copyInput); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	terragruntOptions.Logger.Printf("Backed up the state at s3://%s/%s to s3://%s/%s", s3Config.Bucket, s3Config.Key, s3Config.Bucket, backupKey)
+
+	if retention == 0 {
+		return nil
+	}
+	return pruneS3StateBackups(s3Client, s3Config, retention, terragruntOptions)
+}
+
+// Delete the oldest backups of the state of the given S3 config, so only the given number of backups is left
+func pruneS3StateBackups(s3Client s3iface.S3API, s3Config *RemoteStateConfigS3, retention int, terragruntOptions *options.TerragruntOptions) error {
+	backupKeys := []string{}
+
+	listInput := &s3.ListObjectsV2Input{Bucket: aws.String(s3Config.Bucket), Prefix: aws.String(stateBackupPrefix(s3Config.Key))}
+	for {
+		output, err := s3Client.ListObjectsV2(listInput)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+		for _, object := range output.Contents {
+			backupKeys = append(backupKeys, aws.StringValue(object.Key))
+		}
+		if !aws.BoolValue(output.IsTruncated) {
+			break
+		}
+		listInput.ContinuationToken = output.NextContinuationToken
+	}
+
+	if len(backupKeys) <= retention {
+		return nil
+	}
+
+	sort.Strings(backupKeys)
+	for _, backupKey := range backupKeys[:len(backupKeys)-retention] {
+		if _, err := s3Client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(s3Config.Bucket), Key: aws.String(backupKey)}); err != nil {
+			return errors.WithStackTrace(err)
+		}
+		terragruntOptions.Logger.Printf("Deleted the old state backup s3://%s/%s, as backup_retention is %d", s3Config.Bucket, backupKey, retention)
+	}
+
+	return nil
+}
+
+// Return the prefix of the keys of the backups of the state at the given key
+func stateBackupPrefix(key string) string {
+	return key + ".backup/"
+}
+
+// Return the URL-encoded source of a copy of the given object, as CopyObject expects it
+func s3CopySource(bucket string, key string) string {
+	segments := strings.Split(bucket+"/"+key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Return true if the given error is the error returned by S3 for an object that doesn't exist. HeadObject returns a
+// NotFound error, as the response has no body to tell why, while other requests return NoSuchKey.
+func isS3ObjectNotFoundError(err error) bool {
+	awsErr, isAwsErr := errors.Unwrap(err).(awserr.Error)
+	return isAwsErr && (awsErr.Code() == "NotFound" || awsErr.Code() == s3.ErrCodeNoSuchKey)
 }
 
 // Create an authenticated client for DynamoDB
@@ -474,8 +674,18 @@ func (target MultipleTagsDeclarations) Error() string {
 	return fmt.Sprintf("Tags for %s got declared multiple times. Please do only declare in one block.", string(target))
 }
 
+type LockTableDoesNotExist string
+
+func (tableName LockTableDoesNotExist) Error() string {
+	return fmt.Sprintf("The DynamoDB lock table %s does not exist. Terragrunt did not create it, as 'skip_lock_table_creation' is set. Create the table, or set 'skip_lock_table_verification' to skip this check.", string(tableName))
+}
+
 type MaxRetriesWaitingForS3BucketExceeded string
 
 func (err MaxRetriesWaitingForS3BucketExceeded) Error() string {
 	return fmt.Sprintf("Exceeded max retries (%d) waiting for bucket S3 bucket %s", MAX_RETRIES_WAITING_FOR_S3_BUCKET, string(err))
 }
+
+func (err MaxRetriesWaitingForS3BucketExceeded) Class() errors.ErrorClass {
+	return errors.ErrorClassLock
+}
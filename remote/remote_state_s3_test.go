@@ -2,11 +2,21 @@ package remote
 
 import (
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awsdynamodb "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/gruntwork-io/terragrunt/aws_helper"
+	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestConfigValuesEqual(t *testing.T) {
@@ -75,6 +85,12 @@ func TestConfigValuesEqual(t *testing.T) {
 			&TerraformBackend{Type: "s3", Config: map[string]interface{}{}},
 			true,
 		},
+		{
+			"equal-ignore-lock-table-settings",
+			map[string]interface{}{"skip_lock_table_creation": true, "skip_lock_table_verification": true, "lock_table_assume_role": "arn:aws:iam::123456789012:role/lock-table", "lock_table_region": "us-west-2"},
+			&TerraformBackend{Type: "s3", Config: map[string]interface{}{}},
+			true,
+		},
 		{
 			"unequal-wrong-backend",
 			map[string]interface{}{"foo": "bar"},
@@ -203,3 +219,376 @@ func TestGetAwsSessionConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestGetLockTableSessionConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		config   map[string]interface{}
+		expected *aws_helper.AwsSessionConfig
+	}{
+		{
+			"same-account-and-region",
+			map[string]interface{}{"region": "us-east-1", "profile": "dev", "role_arn": "arn:aws:iam::111111111111:role/state"},
+			&aws_helper.AwsSessionConfig{Region: "us-east-1", Profile: "dev", RoleArn: "arn:aws:iam::111111111111:role/state"},
+		},
+		{
+			"other-region",
+			map[string]interface{}{"region": "us-east-1", "lock_table_region": "eu-west-1"},
+			&aws_helper.AwsSessionConfig{Region: "eu-west-1"},
+		},
+		{
+			"other-account-and-region",
+			map[string]interface{}{"region": "us-east-1", "profile": "dev", "role_arn": "arn:aws:iam::111111111111:role/state", "lock_table_region": "eu-west-1", "lock_table_assume_role": "arn:aws:iam::222222222222:role/lock-table"},
+			&aws_helper.AwsSessionConfig{Region: "eu-west-1", Profile: "dev", RoleArn: "arn:aws:iam::222222222222:role/lock-table"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			extendedConfig, err := parseExtendedS3Config(testCase.config)
+			require.Nil(t, err, "Unexpected error parsing config for test: %v", err)
+
+			assert.Equal(t, testCase.expected, extendedConfig.GetLockTableSessionConfig())
+		})
+	}
+}
+
+func TestCreateLockTableIfNecessaryWithClient(t *testing.T) {
+	t.Parallel()
+
+	accessDenied := awserr.New("AccessDeniedException", "User is not authorized to perform: dynamodb:DescribeTable", nil)
+
+	testCases := []struct {
+		name            string
+		config          map[string]interface{}
+		client          *mockDynamoDbClient
+		expectedErr     error
+		expectedCreated bool
+	}{
+		{
+			"create-missing-table",
+			map[string]interface{}{"dynamodb_table": "locks"},
+			&mockDynamoDbClient{},
+			nil,
+			true,
+		},
+		{
+			"existing-table",
+			map[string]interface{}{"dynamodb_table": "locks"},
+			&mockDynamoDbClient{tableStatus: awsdynamodb.TableStatusActive},
+			nil,
+			false,
+		},
+		{
+			"access-denied",
+			map[string]interface{}{"dynamodb_table": "locks"},
+			&mockDynamoDbClient{describeErr: accessDenied},
+			accessDenied,
+			false,
+		},
+		{
+			"skip-creation-existing-table",
+			map[string]interface{}{"dynamodb_table": "locks", "skip_lock_table_creation": true},
+			&mockDynamoDbClient{tableStatus: awsdynamodb.TableStatusActive},
+			nil,
+			false,
+		},
+		{
+			"skip-creation-missing-table",
+			map[string]interface{}{"dynamodb_table": "locks", "skip_lock_table_creation": true},
+			&mockDynamoDbClient{},
+			LockTableDoesNotExist("locks"),
+			false,
+		},
+		{
+			"skip-creation-access-denied",
+			map[string]interface{}{"dynamodb_table": "locks", "skip_lock_table_creation": true},
+			&mockDynamoDbClient{describeErr: accessDenied},
+			nil,
+			false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+			require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+			extendedConfig, err := parseExtendedS3Config(testCase.config)
+			require.Nil(t, err, "Unexpected error parsing config for test: %v", err)
+
+			err = createLockTableIfNecessaryWithClient(extendedConfig, testCase.client, terragruntOptions)
+			if testCase.expectedErr != nil {
+				if assert.Error(t, err) {
+					assert.Equal(t, testCase.expectedErr, errors.Unwrap(err))
+				}
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, testCase.expectedCreated, testCase.client.created)
+		})
+	}
+}
+
+// A DynamoDB client with a single table, which doesn't exist until it's created unless tableStatus is set. The methods
+// Terragrunt doesn't call panic, as they're left to the nil embedded interface.
+type mockDynamoDbClient struct {
+	dynamodbiface.DynamoDBAPI
+
+	tableStatus string
+	describeErr error
+	created     bool
+}
+
+func (client *mockDynamoDbClient) DescribeTable(input *awsdynamodb.DescribeTableInput) (*awsdynamodb.DescribeTableOutput, error) {
+	if client.describeErr != nil {
+		return nil, client.describeErr
+	}
+	if client.tableStatus == "" {
+		return nil, awserr.New("ResourceNotFoundException", "Requested resource not found", nil)
+	}
+	return &awsdynamodb.DescribeTableOutput{Table: &awsdynamodb.TableDescription{TableName: input.TableName, TableStatus: aws.String(client.tableStatus)}}, nil
+}
+
+func (client *mockDynamoDbClient) CreateTable(input *awsdynamodb.CreateTableInput) (*awsdynamodb.CreateTableOutput, error) {
+	client.created = true
+	client.tableStatus = awsdynamodb.TableStatusActive
+	return &awsdynamodb.CreateTableOutput{TableDescription: &awsdynamodb.TableDescription{TableName: input.TableName}}, nil
+}
+
+func TestBackupS3StateWithClient(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testCases := []struct {
+		name                 string
+		config               map[string]interface{}
+		objects              []string
+		expectedCopySource   string
+		expectedBackupKey    string
+		expectedEncryption   *string
+		expectedObjectsAfter []string
+	}{
+		{
+			"copy",
+			map[string]interface{}{"bucket": "my-bucket", "key": "live/app/terraform.tfstate"},
+			[]string{"live/app/terraform.tfstate"},
+			"my-bucket/live/app/terraform.tfstate",
+			"live/app/terraform.tfstate.backup/20240102T030405Z",
+			nil,
+			[]string{"live/app/terraform.tfstate", "live/app/terraform.tfstate.backup/20240102T030405Z"},
+		},
+		{
+			"copy-encrypted",
+			map[string]interface{}{"bucket": "my-bucket", "key": "terraform.tfstate", "encrypt": true},
+			[]string{"terraform.tfstate"},
+			"my-bucket/terraform.tfstate",
+			"terraform.tfstate.backup/20240102T030405Z",
+			aws.String(s3.ServerSideEncryptionAes256),
+			[]string{"terraform.tfstate", "terraform.tfstate.backup/20240102T030405Z"},
+		},
+		{
+			"copy-escapes-source",
+			map[string]interface{}{"bucket": "my-bucket", "key": "my app/état+1.tfstate"},
+			[]string{"my app/état+1.tfstate"},
+			"my-bucket/my%20app/%C3%A9tat+1.tfstate",
+			"my app/état+1.tfstate.backup/20240102T030405Z",
+			nil,
+			[]string{"my app/état+1.tfstate", "my app/état+1.tfstate.backup/20240102T030405Z"},
+		},
+		{
+			"missing-state",
+			map[string]interface{}{"bucket": "my-bucket", "key": "live/app/terraform.tfstate"},
+			[]string{"live/db/terraform.tfstate"},
+			"",
+			"",
+			nil,
+			[]string{"live/db/terraform.tfstate"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+			require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+			s3Config, err := parseS3Config(testCase.config)
+			require.Nil(t, err, "Unexpected error parsing config for test: %v", err)
+
+			client := newMockS3Client(testCase.objects...)
+			err = backupS3StateWithClient(client, s3Config, 0, now, terragruntOptions)
+			require.Nil(t, err, "Unexpected error backing up state: %v", err)
+
+			if testCase.expectedBackupKey == "" {
+				assert.Empty(t, client.copies)
+			} else if assert.Len(t, client.copies, 1) {
+				assert.Equal(t, "my-bucket", aws.StringValue(client.copies[0].Bucket))
+				assert.Equal(t, testCase.expectedBackupKey, aws.StringValue(client.copies[0].Key))
+				assert.Equal(t, testCase.expectedCopySource, aws.StringValue(client.copies[0].CopySource))
+				assert.Equal(t, testCase.expectedEncryption, client.copies[0].ServerSideEncryption)
+			}
+			assert.Equal(t, testCase.expectedObjectsAfter, client.keys())
+			assert.Empty(t, client.deleted)
+		})
+	}
+}
+
+func TestBackupS3StateWithClientPrunesOldBackups(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	s3Config, err := parseS3Config(map[string]interface{}{"bucket": "my-bucket", "key": "app/terraform.tfstate"})
+	require.Nil(t, err, "Unexpected error parsing config for test: %v", err)
+
+	testCases := []struct {
+		retention       int
+		expectedDeleted []string
+	}{
+		{0, []string{}},
+		{5, []string{}},
+		{6, []string{}},
+		{3, []string{"app/terraform.tfstate.backup/20231101T000000Z", "app/terraform.tfstate.backup/20231201T000000Z"}},
+		{1, []string{"app/terraform.tfstate.backup/20231101T000000Z", "app/terraform.tfstate.backup/20231201T000000Z", "app/terraform.tfstate.backup/20231231T235959Z", "app/terraform.tfstate.backup/20240101T000000Z"}},
+	}
+
+	for _, testCase := range testCases {
+		// The backups of other states that share the prefix of the key, such as app/terraform.tfstate.old, aren't
+		// touched, and the listing takes several pages
+		client := newMockS3Client(
+			"app/terraform.tfstate",
+			"app/terraform.tfstate.backup/20240101T000000Z",
+			"app/terraform.tfstate.backup/20231101T000000Z",
+			"app/terraform.tfstate.backup/20231231T235959Z",
+			"app/terraform.tfstate.backup/20231201T000000Z",
+			"app/terraform.tfstate.old",
+			"app/terraform.tfstate.old.backup/20200101T000000Z",
+		)
+		client.pageSize = 2
+
+		err := backupS3StateWithClient(client, s3Config, testCase.retention, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), terragruntOptions)
+		require.Nil(t, err, "For retention %d, unexpected error backing up state: %v", testCase.retention, err)
+
+		assert.Equal(t, testCase.expectedDeleted, client.deleted, "For retention %d", testCase.retention)
+		assert.Contains(t, client.keys(), "app/terraform.tfstate.backup/20240102T030405Z", "For retention %d", testCase.retention)
+		assert.Contains(t, client.keys(), "app/terraform.tfstate.old.backup/20200101T000000Z", "For retention %d", testCase.retention)
+	}
+}
+
+func TestBackupS3StateWithClientError(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	s3Config, err := parseS3Config(map[string]interface{}{"bucket": "my-bucket", "key": "terraform.tfstate"})
+	require.Nil(t, err, "Unexpected error parsing config for test: %v", err)
+
+	accessDenied := awserr.New("AccessDenied", "Access Denied", nil)
+	client := newMockS3Client("terraform.tfstate")
+	client.headErr = accessDenied
+
+	err = backupS3StateWithClient(client, s3Config, 0, time.Now(), terragruntOptions)
+	if assert.Error(t, err) {
+		assert.Equal(t, accessDenied, errors.Unwrap(err))
+	}
+	assert.Empty(t, client.copies)
+}
+
+// An S3 client with a single bucket, which holds the given objects. ListObjectsV2 returns pageSize objects at a time, if
+// it's set. The methods Terragrunt doesn't call panic, as they're left to the nil embedded interface.
+type mockS3Client struct {
+	s3iface.S3API
+
+	objects  map[string]bool
+	pageSize int
+	headErr  error
+	copies   []*s3.CopyObjectInput
+	deleted  []string
+}
+
+func newMockS3Client(keys ...string) *mockS3Client {
+	client := &mockS3Client{objects: map[string]bool{}, deleted: []string{}}
+	for _, key := range keys {
+		client.objects[key] = true
+	}
+	return client
+}
+
+// Return the keys of the objects in the bucket, sorted
+func (client *mockS3Client) keys() []string {
+	keys := []string{}
+	for key := range client.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (client *mockS3Client) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	if client.headErr != nil {
+		return nil, client.headErr
+	}
+	if !client.objects[aws.StringValue(input.Key)] {
+		return nil, awserr.New("NotFound", "Not Found", nil)
+	}
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func (client *mockS3Client) CopyObject(input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	client.copies = append(client.copies, input)
+	client.objects[aws.StringValue(input.Key)] = true
+	return &s3.CopyObjectOutput{}, nil
+}
+
+// List the objects with the given prefix a page at a time, newest first, so the caller can't rely on S3 listing them in
+// lexicographical order. The continuation token is the number of objects listed so far.
+func (client *mockS3Client) ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	keys := []string{}
+	for key := range client.objects {
+		if strings.HasPrefix(key, aws.StringValue(input.Prefix)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	start := 0
+	if input.ContinuationToken != nil {
+		start, _ = strconv.Atoi(aws.StringValue(input.ContinuationToken))
+	}
+	end := len(keys)
+	if client.pageSize > 0 && start+client.pageSize < end {
+		end = start + client.pageSize
+	}
+
+	output := &s3.ListObjectsV2Output{IsTruncated: aws.Bool(end < len(keys))}
+	for _, key := range keys[start:end] {
+		output.Contents = append(output.Contents, &s3.Object{Key: aws.String(key)})
+	}
+	if end < len(keys) {
+		output.NextContinuationToken = aws.String(strconv.Itoa(end))
+	}
+	return output, nil
+}
+
+func (client *mockS3Client) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	client.deleted = append(client.deleted, aws.StringValue(input.Key))
+	delete(client.objects, aws.StringValue(input.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
@@ -4,8 +4,10 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 /**
@@ -32,7 +34,11 @@ func TestToTerraformInitArgs(t *testing.T) {
 				"name":    "Terraform state storage",
 				"service": "Terraform"},
 
-			"skip_bucket_versioning": true,
+			"skip_bucket_versioning":       true,
+			"skip_lock_table_creation":     true,
+			"skip_lock_table_verification": false,
+			"lock_table_assume_role":       "arn:aws:iam::123456789012:role/lock-table",
+			"lock_table_region":            "us-west-2",
 
 			"shared_credentials_file": "my-file",
 			"force_path_style":        true,
@@ -40,10 +46,44 @@ func TestToTerraformInitArgs(t *testing.T) {
 	}
 	args := remoteState.ToTerraformInitArgs()
 
-	// must not contain s3_bucket_tags or dynamodb_table_tags or skip_bucket_versioning
+	// must not contain s3_bucket_tags, dynamodb_table_tags, skip_bucket_versioning, or the lock table settings
 	assertTerraformInitArgsEqual(t, args, "-backend-config=encrypt=true -backend-config=bucket=my-bucket -backend-config=key=terraform.tfstate -backend-config=region=us-east-1 -backend-config=force_path_style=true -backend-config=shared_credentials_file=my-file")
 }
 
+func TestToTerraformInitArgsIsDeterministic(t *testing.T) {
+	t.Parallel()
+
+	newRemoteState := func() RemoteState {
+		return RemoteState{
+			Backend: "s3",
+			Config: map[string]interface{}{
+				"encrypt":        true,
+				"bucket":         "my-bucket",
+				"key":            "terraform.tfstate",
+				"region":         "us-east-1",
+				"dynamodb_table": "my-lock-table",
+				"profile":        "prod",
+				"role_arn":       "arn:aws:iam::123456789012:role/terraform",
+			},
+		}
+	}
+
+	expected := []string{
+		"-backend-config=bucket=my-bucket",
+		"-backend-config=dynamodb_table=my-lock-table",
+		"-backend-config=encrypt=true",
+		"-backend-config=key=terraform.tfstate",
+		"-backend-config=profile=prod",
+		"-backend-config=region=us-east-1",
+		"-backend-config=role_arn=arn:aws:iam::123456789012:role/terraform",
+	}
+
+	// Go randomizes map iteration order, so a single run could pass by chance
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, expected, newRemoteState().ToTerraformInitArgs())
+	}
+}
+
 func TestToTerraformInitArgsUnknownBackend(t *testing.T) {
 	t.Parallel()
 
@@ -151,3 +191,47 @@ func assertTerraformInitArgsEqual(t *testing.T, actualArgs []string, expectedArg
 		assert.Contains(t, actualArgs, expectedArg)
 	}
 }
+
+func TestValidateStateBackup(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		remoteState RemoteState
+		expectedErr error
+	}{
+		{RemoteState{Backend: "s3"}, nil},
+		{RemoteState{Backend: "s3", BackupBeforeApply: true}, nil},
+		{RemoteState{Backend: "s3", BackupBeforeApply: true, BackupRetention: 10}, nil},
+		{RemoteState{Backend: "gcs", BackupBeforeApply: true}, StateBackupNotSupported("gcs")},
+		{RemoteState{Backend: "s3", BackupBeforeApply: true, BackupRetention: -1}, InvalidBackupRetention(-1)},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.remoteState.Validate()
+		if testCase.expectedErr != nil {
+			if assert.Error(t, err, "For remote state %v", testCase.remoteState) {
+				assert.Equal(t, testCase.expectedErr, errors.Unwrap(err), "For remote state %v", testCase.remoteState)
+			}
+		} else {
+			assert.Nil(t, err, "For remote state %v, unexpected error: %v", testCase.remoteState, err)
+		}
+	}
+}
+
+func TestBackupBeforeCommandSkipsOtherCommands(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	// The config has no bucket, so trying to back up the state would fail
+	withBackup := RemoteState{Backend: "s3", Config: map[string]interface{}{}, BackupBeforeApply: true}
+	for _, command := range []string{"plan", "output", "init", ""} {
+		assert.Nil(t, withBackup.BackupBeforeCommand(command, terragruntOptions), "For command %s", command)
+	}
+
+	withoutBackup := RemoteState{Backend: "s3", Config: map[string]interface{}{}}
+	for _, command := range TERRAFORM_COMMANDS_THAT_NEED_STATE_BACKUP {
+		assert.Nil(t, withoutBackup.BackupBeforeCommand(command, terragruntOptions), "For command %s", command)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
+	"io"
 	"os"
 	"strings"
 )
@@ -22,7 +23,11 @@ func PromptUserForInput(prompt string, terragruntOptions *options.TerragruntOpti
 		return "yes", nil
 	}
 
-	reader := bufio.NewReader(os.Stdin)
+	var input io.Reader = os.Stdin
+	if terragruntOptions.Reader != nil {
+		input = terragruntOptions.Reader
+	}
+	reader := bufio.NewReader(input)
 
 	text, err := reader.ReadString('\n')
 	if err != nil {
@@ -47,3 +52,21 @@ func PromptUserForYesNo(prompt string, terragruntOptions *options.TerragruntOpti
 		return false, nil
 	}
 }
+
+// Every command that changes or deletes something, other than the Terraform commands themselves, should ask for
+// confirmation through this function, so they all behave the same way. It logs the given changes, one per line, and
+// then, in a dry run, returns false without prompting, so the caller makes none of them. Otherwise it asks the user to
+// confirm the changes with the given prompt and returns true if they did. With --terragrunt-non-interactive, it
+// returns true without prompting: non-interactive always means go ahead, never skip.
+func ConfirmOrDryRun(prompt string, changes []string, dryRun bool, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	for _, change := range changes {
+		terragruntOptions.Logger.Printf("  %s", change)
+	}
+
+	if dryRun {
+		terragruntOptions.Logger.Printf("This is a dry run, so none of the changes above were made")
+		return false, nil
+	}
+
+	return PromptUserForYesNo(prompt, terragruntOptions)
+}
@@ -0,0 +1,69 @@
+package shell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmOrDryRun(t *testing.T) {
+	t.Parallel()
+
+	changes := []string{"Remove cache entry /tmp/a", "Remove cache entry /tmp/b"}
+
+	testCases := []struct {
+		name           string
+		input          string
+		dryRun         bool
+		nonInteractive bool
+		expected       bool
+		expectedPrompt bool
+	}{
+		{"yes", "yes\n", false, false, true, true},
+		{"y", "y\n", false, false, true, true},
+		{"upper-case-yes", "YES\n", false, false, true, true},
+		{"no", "n\n", false, false, false, true},
+		{"anything-else", "sure\n", false, false, false, true},
+		{"non-interactive", "", false, true, true, true},
+		{"dry-run", "yes\n", true, false, false, false},
+		{"dry-run-non-interactive", "", true, true, false, false},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest("")
+		require.NoError(t, err)
+		terragruntOptions.NonInteractive = testCase.nonInteractive
+		terragruntOptions.Reader = strings.NewReader(testCase.input)
+
+		var logs bytes.Buffer
+		terragruntOptions.Logger = util.CreateLoggerWithWriter(&logs, "")
+
+		confirmed, err := ConfirmOrDryRun("Remove them?", changes, testCase.dryRun, terragruntOptions)
+		assert.NoError(t, err, "For case %s", testCase.name)
+		assert.Equal(t, testCase.expected, confirmed, "For case %s", testCase.name)
+
+		for _, change := range changes {
+			assert.Contains(t, logs.String(), change, "For case %s", testCase.name)
+		}
+		assert.Equal(t, testCase.expectedPrompt, strings.Contains(logs.String(), "Remove them? (y/n)"), "For case %s", testCase.name)
+	}
+}
+
+func TestConfirmOrDryRunNoAnswer(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("")
+	require.NoError(t, err)
+	terragruntOptions.NonInteractive = false
+	terragruntOptions.Reader = strings.NewReader("")
+	terragruntOptions.Logger = util.CreateLoggerWithWriter(&bytes.Buffer{}, "")
+
+	confirmed, err := ConfirmOrDryRun("Remove them?", nil, false, terragruntOptions)
+	assert.Error(t, err)
+	assert.False(t, confirmed)
+}
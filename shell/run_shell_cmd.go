@@ -4,16 +4,19 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
 	"reflect"
+	"sort"
 	"strings"
 	"syscall"
 
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
 )
 
 // Run the given Terraform command
@@ -37,7 +40,30 @@ func RunTerraformCommandWithOutput(terragruntOptions *options.TerragruntOptions,
 // Run the specified shell command with the specified arguments. Connect the command's stdin, stdout, and stderr to
 // the currently running app.
 func RunShellCommandWithOutput(terragruntOptions *options.TerragruntOptions, command string, args ...string) (*CmdOutput, error) {
-	terragruntOptions.Logger.Printf("Running command: %s %s", command, strings.Join(args, " "))
+	var outWriter = terragruntOptions.Writer
+	// Terragrunt can run some commands (such as terraform remote config) before running the actual terraform
+	// command requested by the user. The output of these other commands should not end up on stdout as this
+	// breaks scripts relying on terraform's output, such as ones that parse the JSON of terraform output -json. That
+	// includes commands that happen to have the same args, such as a hook that runs echo output -json.
+	if command != terragruntOptions.TerraformPath || !reflect.DeepEqual(terragruntOptions.TerraformCliArgs, args) {
+		outWriter = terragruntOptions.ErrWriter
+	}
+
+	return runShellCommand(terragruntOptions, outWriter, command, args...)
+}
+
+// Run the specified shell command with the specified arguments, the same way as RunShellCommandWithOutput, but only
+// return its stdout to this method's caller instead of also writing it to the terminal. This is for commands whose
+// output is a value Terragrunt uses, which may be a secret. Stderr is still written to the terminal.
+func RunShellCommandAndCaptureOutput(terragruntOptions *options.TerragruntOptions, command string, args ...string) (*CmdOutput, error) {
+	return runShellCommand(terragruntOptions, ioutil.Discard, command, args...)
+}
+
+// Run the specified shell command with the specified arguments, writing its stdout to the given writer and its stderr
+// to the ErrWriter in the given options, and returning both to this method's caller
+func runShellCommand(terragruntOptions *options.TerragruntOptions, outWriter io.Writer, command string, args ...string) (*CmdOutput, error) {
+	// The args may set variables that hold secrets, such as -var db_password=..., so mask those before logging them
+	terragruntOptions.Logger.Printf("Running command: %s %s", command, strings.Join(util.RedactSensitiveVarArgs(args, terragruntOptions.SensitiveVarPatterns), " "))
 
 	var stdoutBuf bytes.Buffer
 	var stderrBuf bytes.Buffer
@@ -49,13 +75,6 @@ func RunShellCommandWithOutput(terragruntOptions *options.TerragruntOptions, com
 	cmd.Env = toEnvVarsList(terragruntOptions.Env)
 
 	var errWriter = terragruntOptions.ErrWriter
-	var outWriter = terragruntOptions.Writer
-	// Terragrunt can run some commands (such as terraform remote config) before running the actual terraform
-	// command requested by the user. The output of these other commands should not end up on stdout as this
-	// breaks scripts relying on terraform's output.
-	if !reflect.DeepEqual(terragruntOptions.TerraformCliArgs, args) {
-		outWriter = terragruntOptions.ErrWriter
-	}
 
 	cmd.Dir = terragruntOptions.WorkingDir
 	// Inspired by https://blog.kowalczyk.info/article/wOYk/advanced-command-execution-in-go-with-osexec.html
@@ -82,10 +101,18 @@ func RunShellCommandWithOutput(terragruntOptions *options.TerragruntOptions, com
 	return &cmdOutput, errors.WithStackTrace(err)
 }
 
+// Convert the given env vars to the KEY=VALUE format exec.Cmd expects, sorted by name, so the same env vars always
+// result in exactly the same command
 func toEnvVarsList(envVarsAsMap map[string]string) []string {
+	keys := []string{}
+	for key := range envVarsAsMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
 	envVarsAsList := []string{}
-	for key, value := range envVarsAsMap {
-		envVarsAsList = append(envVarsAsList, fmt.Sprintf("%s=%s", key, value))
+	for _, key := range keys {
+		envVarsAsList = append(envVarsAsList, fmt.Sprintf("%s=%s", key, envVarsAsMap[key]))
 	}
 	return envVarsAsList
 }
@@ -2,10 +2,12 @@ package shell
 
 import (
 	"bytes"
+	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,3 +55,46 @@ func TestRunShellOutputToStderrAndStdout(t *testing.T) {
 	assert.True(t, strings.Contains(stderr.String(), "Terraform"), "Output directed to stderr")
 	assert.True(t, len(stdout.String()) == 0, "No output to stdout")
 }
+
+func TestRunShellCommandRedactsSensitiveVarsInLogs(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("")
+	assert.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	var logs bytes.Buffer
+	terragruntOptions.Logger = util.CreateLoggerWithWriter(&logs, "")
+	terragruntOptions.SensitiveVarPatterns = []*regexp.Regexp{regexp.MustCompile(`^license_key$`)}
+
+	stdout := new(bytes.Buffer)
+	terragruntOptions.Writer = stdout
+	terragruntOptions.ErrWriter = stdout
+
+	cmd := RunShellCommand(terragruntOptions, "echo", "apply", "-var", "secret=hunter2", "-var=license_key=abc123", "-var", "name=app")
+	assert.Nil(t, cmd)
+
+	assert.Contains(t, logs.String(), "Running command: echo apply -var secret=<redacted> -var=license_key=<redacted> -var name=app")
+	assert.NotContains(t, logs.String(), "hunter2")
+	assert.NotContains(t, logs.String(), "abc123")
+	// Only the log is masked, not the args the command is run with
+	assert.Contains(t, stdout.String(), "secret=hunter2")
+}
+
+func TestToEnvVarsListIsSorted(t *testing.T) {
+	t.Parallel()
+
+	envVars := map[string]string{
+		"TF_VAR_region": "us-east-1",
+		"TF_VAR_env":    "prod",
+		"AWS_PROFILE":   "prod",
+		"TF_VAR_name":   "vpc",
+		"PATH":          "/usr/bin",
+	}
+
+	expected := []string{"AWS_PROFILE=prod", "PATH=/usr/bin", "TF_VAR_env=prod", "TF_VAR_name=vpc", "TF_VAR_region=us-east-1"}
+
+	// Go randomizes map iteration order, so a single run could pass by chance
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, expected, toEnvVarsList(envVars))
+	}
+}
@@ -3,6 +3,7 @@
 package shell
 
 import (
+	"bytes"
 	goerrors "errors"
 	"os"
 	"os/exec"
@@ -39,6 +40,26 @@ func TestExitCodeUnix(t *testing.T) {
 	assert.Equal(t, 0, retCode)
 }
 
+func TestRunShellCommandAndCaptureOutputUnix(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("")
+	assert.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	terragruntOptions.Writer = stdout
+	terragruntOptions.ErrWriter = stderr
+
+	output, err := RunShellCommandAndCaptureOutput(terragruntOptions, "sh", "-c", "echo secret; echo warning 1>&2")
+	assert.Nil(t, err)
+
+	assert.Equal(t, "secret\n", output.Stdout)
+	assert.Equal(t, "warning\n", output.Stderr)
+	assert.Equal(t, 0, stdout.Len(), "Stdout is only captured")
+	assert.Equal(t, "warning\n", stderr.String(), "Stderr is written to stderr")
+}
+
 func TestNewSignalsForwarderWaitUnix(t *testing.T) {
 	t.Parallel()
 
@@ -118,3 +139,33 @@ func TestNewSignalsForwarderMultipleUnix(t *testing.T) {
 	assert.True(t, retCode <= interrupts, "Subprocess received wrong number of signals")
 	assert.Equal(t, retCode, expectedInterrupts, "Subprocess didn't receive multiple signals")
 }
+
+func TestRunShellCommandOnlyWritesTerraformCommandToStdout(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		terraformPath  string
+		expectedStdout string
+		expectedStderr string
+	}{
+		// A command with the same args as the Terraform command, such as a hook, still writes to stderr
+		{"terraform", "", "output -json\n"},
+		{"echo", "output -json\n", ""},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest("")
+		assert.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		terragruntOptions.TerraformPath = testCase.terraformPath
+		terragruntOptions.TerraformCliArgs = []string{"output", "-json"}
+		terragruntOptions.Writer = stdout
+		terragruntOptions.ErrWriter = stderr
+
+		assert.Nil(t, RunShellCommand(terragruntOptions, "echo", "output", "-json"))
+		assert.Equal(t, testCase.expectedStdout, stdout.String(), "For terraform path %s", testCase.terraformPath)
+		assert.Equal(t, testCase.expectedStderr, stderr.String(), "For terraform path %s", testCase.terraformPath)
+	}
+}
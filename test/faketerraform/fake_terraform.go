@@ -0,0 +1,236 @@
+// Package faketerraform lets tests run Terragrunt against a fake terraform binary, so they don't need Terraform or cloud
+// credentials. The fake records how Terragrunt called it, and responds to each command the way the test scripted it
+// to, or the way a successful run of Terraform would, with no resources to change. See the terraform folder for the
+// fake itself.
+//
+// Build the fake once, in TestMain, and then give each test its own copy with New:
+//
+//	fake := faketerraform.New(t, fakeTerraformBinary)
+//	defer fake.Close()
+//
+//	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, dir))
+//
+//	fake.AssertCalls(t, faketerraform.ExpectedCall{Command: "init", Dir: dir}, faketerraform.ExpectedCall{Command: "apply", Dir: dir})
+package faketerraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The names of the script file and of the folder the calls are recorded in, in the folder of the fake terraform binary.
+// These must match the ones in terraform/main.go.
+const SCRIPT_FILE = "fake-terraform-script.json"
+const CALLS_DIR = "fake-terraform-calls"
+
+// The arguments Terragrunt calls terraform with to check its version, before running any command
+var VERSION_CHECK_ARGS = []string{"--version"}
+
+// A copy of the fake terraform binary, in a folder of its own, for a single test. The script and the calls of the test
+// are kept in the same folder, so tests can run in parallel.
+type FakeTerraform struct {
+	// The path of the fake terraform binary. Pass it to Terragrunt via --terragrunt-tfpath.
+	Path string
+
+	dir    string
+	script map[string][]Response
+}
+
+// The response of the fake to a call to a command
+type Response struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// A call to the fake, as it recorded it
+type Call struct {
+	Args []string          `json:"args"`
+	Dir  string            `json:"dir"`
+	Env  map[string]string `json:"env"`
+}
+
+// A call a test expects the fake to have received
+type ExpectedCall struct {
+	// The terraform command, such as init or plan
+	Command string
+
+	// The arguments after the command. Not checked if nil.
+	Args []string
+
+	// The folder terraform was called in. Not checked if empty.
+	Dir string
+}
+
+// Return the terraform command of the call, such as init or plan, which is its first argument
+func (call Call) Command() string {
+	if len(call.Args) == 0 {
+		return ""
+	}
+	return call.Args[0]
+}
+
+// Compile the fake terraform binary into a new temp folder and return its path. This is meant to be called once, from
+// TestMain, as compiling takes a while. The caller should remove the folder of the binary once all tests are done.
+func Build() (string, error) {
+	_, thisFile, _, ok := runtime.Caller(0)
+	if !ok {
+		return "", fmt.Errorf("Unable to find the source of the fake terraform binary")
+	}
+	sourceDir := filepath.Join(filepath.Dir(thisFile), "terraform")
+
+	binaryDir, err := ioutil.TempDir("", "fake-terraform")
+	if err != nil {
+		return "", err
+	}
+	binaryPath := filepath.Join(binaryDir, binaryName())
+
+	goBinary, err := exec.LookPath("go")
+	if err != nil {
+		goBinary = filepath.Join(runtime.GOROOT(), "bin", "go")
+	}
+
+	cmd := exec.Command(goBinary, "build", "-o", binaryPath, ".")
+	cmd.Dir = sourceDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(binaryDir)
+		return "", fmt.Errorf("Unable to build the fake terraform binary in %s: %v\n%s", sourceDir, err, output)
+	}
+
+	return binaryPath, nil
+}
+
+// Create a copy of the fake terraform binary at the given path, as returned by Build, in a new temp folder for the
+// current test. Call Close when the test is done.
+func New(t *testing.T, binaryPath string) *FakeTerraform {
+	dir, err := ioutil.TempDir("", "fake-terraform-test")
+	require.NoError(t, err)
+
+	fake := &FakeTerraform{Path: filepath.Join(dir, binaryName()), dir: dir, script: map[string][]Response{}}
+
+	// A hard link is cheaper than a copy, but may not be possible, such as across file systems
+	if err := os.Link(binaryPath, fake.Path); err != nil {
+		contents, err := ioutil.ReadFile(binaryPath)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(fake.Path, contents, 0755))
+	}
+
+	return fake
+}
+
+// Remove the folder of the fake, along with its script and the calls it recorded
+func (fake *FakeTerraform) Close() {
+	os.RemoveAll(fake.dir)
+}
+
+// Script the responses of the fake to the given command. The first call to the command gets the first response, the
+// second call the second response, and so on, with the last response repeated once they run out. Commands without a
+// script succeed, with the output Terraform has when there's nothing to change.
+func (fake *FakeTerraform) Script(t *testing.T, command string, responses ...Response) {
+	fake.script[command] = responses
+
+	contents, err := json.Marshal(fake.script)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(fake.dir, SCRIPT_FILE), contents, 0644))
+}
+
+// Return the calls the fake received, in the order they were made in. The calls Terragrunt makes to check the version
+// of terraform, which it makes every time it runs, are left out.
+func (fake *FakeTerraform) Calls(t *testing.T) []Call {
+	callsDir := filepath.Join(fake.dir, CALLS_DIR)
+	if _, err := os.Stat(callsDir); os.IsNotExist(err) {
+		return []Call{}
+	}
+
+	files, err := ioutil.ReadDir(callsDir)
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, file := range files {
+		if filepath.Ext(file.Name()) == ".json" {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	calls := []Call{}
+	for _, name := range names {
+		contents, err := ioutil.ReadFile(filepath.Join(callsDir, name))
+		require.NoError(t, err)
+
+		var call Call
+		require.NoError(t, json.Unmarshal(contents, &call))
+		if len(call.Args) == len(VERSION_CHECK_ARGS) && call.Command() == VERSION_CHECK_ARGS[0] {
+			continue
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+// Return the calls the fake received for the given command, in the order they were made in
+func (fake *FakeTerraform) CallsTo(t *testing.T, command string) []Call {
+	calls := []Call{}
+	for _, call := range fake.Calls(t) {
+		if call.Command() == command {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Assert the fake received exactly the given calls, in the given order, leaving out version checks. Return true if it
+// did.
+func (fake *FakeTerraform) AssertCalls(t *testing.T, expected ...ExpectedCall) bool {
+	actual := fake.Calls(t)
+
+	commands := []string{}
+	for _, call := range actual {
+		commands = append(commands, call.Command())
+	}
+	expectedCommands := []string{}
+	for _, call := range expected {
+		expectedCommands = append(expectedCommands, call.Command)
+	}
+	if !assert.Equal(t, expectedCommands, commands, "Unexpected terraform commands") {
+		return false
+	}
+
+	matches := true
+	for index, expectedCall := range expected {
+		actualCall := actual[index]
+		if expectedCall.Args != nil {
+			matches = assert.Equal(t, expectedCall.Args, actualCall.Args[1:], "Unexpected arguments for call %d, to %s", index, expectedCall.Command) && matches
+		}
+		if expectedCall.Dir != "" {
+			matches = assert.Equal(t, canonicalDir(expectedCall.Dir), canonicalDir(actualCall.Dir), "Unexpected working dir for call %d, to %s", index, expectedCall.Command) && matches
+		}
+	}
+	return matches
+}
+
+// Return the given folder with symlinks resolved, such as /var to /private/var on macOS, so it can be compared to the
+// working dir the fake recorded
+func canonicalDir(dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		return resolved
+	}
+	return filepath.Clean(dir)
+}
+
+func binaryName() string {
+	if runtime.GOOS == "windows" {
+		return "terraform.exe"
+	}
+	return "terraform"
+}
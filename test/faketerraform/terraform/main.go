@@ -0,0 +1,273 @@
+// A fake terraform binary for testing Terragrunt without Terraform or cloud credentials. Every time it runs, it records
+// its arguments, env vars, and working dir in a JSON file, and then responds the way the test scripted it to, or the
+// way a successful run of Terraform would, with no resources to change, if the test didn't script a response. See the
+// faketerraform package, which builds this binary and reads what it recorded.
+//
+// The fake reads its script from, and records its calls next to, the path it was run as, so each test can have its own
+// copy of the binary in its own folder, without any env vars, which are shared by tests running in parallel.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The version the fake reports. It has to meet the version constraint of Terragrunt.
+const VERSION = "v0.11.14"
+
+// The names of the script file and of the folder the calls are recorded in, in the folder of the binary. These must
+// match the ones in the faketerraform package.
+const SCRIPT_FILE = "fake-terraform-script.json"
+const CALLS_DIR = "fake-terraform-calls"
+
+// What the fake outputs for commands the test didn't script a response for
+var DEFAULT_OUTPUTS = map[string]string{
+	"apply":   "Apply complete! Resources: 0 added, 0 changed, 0 destroyed.\n",
+	"destroy": "Destroy complete! Resources: 0 destroyed.\n",
+	"plan":    "No changes. Infrastructure is up-to-date.\n",
+}
+
+type call struct {
+	Args []string          `json:"args"`
+	Dir  string            `json:"dir"`
+	Env  map[string]string `json:"env"`
+}
+
+type response struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+}
+
+func main() {
+	os.Exit(run(os.Args[0], os.Args[1:]))
+}
+
+func run(binaryPath string, args []string) int {
+	fakeDir := filepath.Dir(binaryPath)
+
+	previousCalls, err := recordCall(fakeDir, args)
+	if err != nil {
+		return fail("Unable to record the call: %v", err)
+	}
+
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	if command == "--version" || command == "-version" || command == "version" {
+		fmt.Printf("Terraform %s\n", VERSION)
+		return 0
+	}
+
+	if scripted, isScripted, err := scriptedResponse(fakeDir, command, previousCalls); err != nil {
+		return fail("Unable to read the script: %v", err)
+	} else if isScripted {
+		io.WriteString(os.Stdout, scripted.Stdout)
+		io.WriteString(os.Stderr, scripted.Stderr)
+		return scripted.ExitCode
+	}
+
+	if command == "init" {
+		if err := initialize(args[1:]); err != nil {
+			return fail("%v", err)
+		}
+		fmt.Println("Terraform has been successfully initialized!")
+		return 0
+	}
+
+	io.WriteString(os.Stdout, DEFAULT_OUTPUTS[command])
+	return 0
+}
+
+// Record the given call in a file of its own in the calls folder, so calls made at the same time don't overwrite each
+// other, and return how many calls were recorded before it with the same command
+func recordCall(fakeDir string, args []string) (int, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return 0, err
+	}
+
+	env := map[string]string{}
+	for _, keyValue := range os.Environ() {
+		parts := strings.SplitN(keyValue, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	callsDir := filepath.Join(fakeDir, CALLS_DIR)
+	if err := os.MkdirAll(callsDir, 0755); err != nil {
+		return 0, err
+	}
+
+	previousCalls, err := countCalls(callsDir, args)
+	if err != nil {
+		return 0, err
+	}
+
+	contents, err := json.Marshal(call{Args: args, Dir: dir, Env: env})
+	if err != nil {
+		return 0, err
+	}
+
+	// The names sort in the order the calls were made in. The call is written to a temp file first, and then renamed, so
+	// fakes running at the same time, such as for the modules of apply-all, never read a call that's half written.
+	name := fmt.Sprintf("%020d-%010d", time.Now().UnixNano(), os.Getpid())
+	tmpPath := filepath.Join(callsDir, name+".tmp")
+	if err := ioutil.WriteFile(tmpPath, contents, 0644); err != nil {
+		return 0, err
+	}
+	return previousCalls, os.Rename(tmpPath, filepath.Join(callsDir, name+".json"))
+}
+
+// Return how many of the calls in the given folder have the same command as the given args
+func countCalls(callsDir string, args []string) (int, error) {
+	files, err := ioutil.ReadDir(callsDir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, file := range files {
+		if filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		contents, err := ioutil.ReadFile(filepath.Join(callsDir, file.Name()))
+		if err != nil {
+			return 0, err
+		}
+		var previous call
+		if err := json.Unmarshal(contents, &previous); err != nil {
+			return 0, err
+		}
+		if len(args) > 0 && len(previous.Args) > 0 && previous.Args[0] == args[0] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Return the response the script has for the given call to the given command: the response at the index of the call
+// among the calls to that command, or the last response once they run out. Return false if there's no script, or it
+// has no responses for the command.
+func scriptedResponse(fakeDir string, command string, previousCalls int) (response, bool, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(fakeDir, SCRIPT_FILE))
+	if os.IsNotExist(err) {
+		return response{}, false, nil
+	}
+	if err != nil {
+		return response{}, false, err
+	}
+
+	script := map[string][]response{}
+	if err := json.Unmarshal(contents, &script); err != nil {
+		return response{}, false, err
+	}
+
+	responses := script[command]
+	if len(responses) == 0 {
+		return response{}, false, nil
+	}
+	if previousCalls >= len(responses) {
+		return responses[len(responses)-1], true, nil
+	}
+	return responses[previousCalls], true, nil
+}
+
+// Do what terraform init does, as far as Terragrunt can tell: copy the module passed via -from-module, if any, to the
+// given dir, and create the .terraform folder with the plugins and modules folders Terragrunt checks for. Only modules
+// in local folders are supported.
+func initialize(args []string) error {
+	fromModule := ""
+	positional := []string{}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-from-module=") {
+			fromModule = strings.TrimPrefix(arg, "-from-module=")
+		} else if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+		}
+	}
+
+	targetDir := "."
+	if len(positional) > 0 {
+		targetDir = positional[len(positional)-1]
+	}
+
+	if fromModule != "" {
+		if err := copyModule(fromModule, targetDir); err != nil {
+			return err
+		}
+		// Like Terraform, -from-module only copies the module, as Terragrunt runs init again in the module folder
+		return nil
+	}
+
+	for _, dir := range []string{"plugins", "modules"} {
+		if err := os.MkdirAll(filepath.Join(targetDir, ".terraform", dir), 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Copy the files of the module at the given source, which must be a local path or a file:// URL, to the given folder.
+// As with go-getter, a // in the source separates the folder to copy from a subfolder of it, and the whole folder is
+// copied.
+func copyModule(source string, targetDir string) error {
+	if strings.Contains(source, "://") && !strings.HasPrefix(source, "file://") {
+		return fmt.Errorf("The fake terraform only supports modules in local folders, not %s", source)
+	}
+
+	sourceDir := strings.TrimPrefix(source, "file://")
+	// Skip the first character, which is the slash of an absolute path rather than a separator
+	if len(sourceDir) > 1 {
+		if index := strings.Index(sourceDir[1:], "//"); index >= 0 {
+			sourceDir = sourceDir[:index+1]
+		}
+	}
+
+	paths := []string{}
+	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(targetDir, relPath)
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(destPath, contents, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fail(format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "fake terraform: "+format+"\n", args...)
+	return 1
+}
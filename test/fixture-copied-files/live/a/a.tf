@@ -0,0 +1,3 @@
+output "module" {
+  value = "a"
+}
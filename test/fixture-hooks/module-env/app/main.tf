@@ -0,0 +1,7 @@
+data "template_file" "example" {
+  template = "hello, world"  
+}
+
+output "example" {
+  value = "${data.template_file.example.rendered}"
+}
@@ -0,0 +1,12 @@
+provider "aws" {
+  region  = "us-east-1"
+  version = "~> 1.30"
+}
+
+terraform {
+  required_providers {
+    random = {
+      version = "~> 2.0"
+    }
+  }
+}
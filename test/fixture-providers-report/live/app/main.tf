@@ -0,0 +1,8 @@
+provider "aws" {
+  region  = "us-east-1"
+  version = "~> 1.30"
+}
+
+provider "template" {
+  version = "~> 1.0"
+}
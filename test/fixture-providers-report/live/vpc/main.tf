@@ -0,0 +1,12 @@
+provider "aws" {
+  region  = "us-east-1"
+  version = "~> 1.30"
+}
+
+provider "aws" {
+  alias   = "east"
+  region  = "us-east-2"
+  version = "~> 1.30"
+}
+
+provider "null" {}
@@ -0,0 +1,10 @@
+provider "aws" {
+  region  = "us-east-1"
+  version = "~> 1.40"
+}
+
+terraform {
+  required_providers {
+    random = "~> 2.0"
+  }
+}
@@ -0,0 +1,16 @@
+variable "name" {}
+variable "owner" {}
+variable "team" {}
+variable "environment" {}
+variable "region" {}
+variable "api_token" {}
+variable "instance_type" {}
+variable "instance_count" {}
+
+variable "zones" {
+  type = "list"
+}
+
+variable "tags" {
+  type = "map"
+}
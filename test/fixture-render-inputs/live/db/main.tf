@@ -0,0 +1,11 @@
+variable "name" {}
+variable "owner" {}
+variable "team" {}
+variable "environment" {}
+variable "region" {}
+variable "db_name" {}
+variable "db_password" {}
+
+variable "tags" {
+  type = "map"
+}
@@ -0,0 +1,3 @@
+Team        = "platform"
+ManagedBy   = "terragrunt"
+Environment = "prod"
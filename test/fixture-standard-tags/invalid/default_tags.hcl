@@ -0,0 +1,2 @@
+Team   = "platform"
+Owners = ["alice", "bob"]
@@ -2,6 +2,7 @@ package test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -24,8 +25,10 @@ import (
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/gruntwork-io/terragrunt/remote"
 	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/test/faketerraform"
 	"github.com/gruntwork-io/terragrunt/util"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // hard-code this to match the test fixture for now
@@ -68,28 +71,265 @@ const (
 	TEST_FIXTURE_HOOKS_EMPTY_STRING_COMMAND_PATH            = "fixture-hooks/bad-arg-action/empty-string-command"
 	TEST_FIXTURE_HOOKS_EMPTY_COMMAND_LIST_PATH              = "fixture-hooks/bad-arg-action/empty-command-list"
 	TEST_FIXTURE_HOOKS_INTERPOLATIONS_PATH                  = "fixture-hooks/interpolations"
+	TEST_FIXTURE_HOOKS_MODULE_ENV_PATH                      = "fixture-hooks/module-env"
 	TEST_FIXTURE_HOOKS_INIT_ONCE_NO_SOURCE_NO_BACKEND       = "fixture-hooks/init-once/no-source-no-backend"
 	TEST_FIXTURE_HOOKS_INIT_ONCE_NO_SOURCE_WITH_BACKEND     = "fixture-hooks/init-once/no-source-with-backend"
 	TEST_FIXTURE_HOOKS_INIT_ONCE_WITH_SOURCE_NO_BACKEND     = "fixture-hooks/init-once/with-source-no-backend"
 	TEST_FIXTURE_HOOKS_INIT_ONCE_WITH_SOURCE_WITH_BACKEND   = "fixture-hooks/init-once/with-source-with-backend"
+	TEST_FIXTURE_AUTO_INIT                                  = "fixture-auto-init"
+	TEST_FIXTURE_RUN_WITH_DEPENDENCIES                      = "fixture-run-with-dependencies"
 	TEST_FIXTURE_FAILED_TERRAFORM                           = "fixture-failure"
 	TEST_FIXTURE_EXIT_CODE                                  = "fixture-exit-code"
 	TEST_FIXTURE_AUTO_RETRY_RERUN                           = "fixture-auto-retry/re-run"
 	TEST_FIXTURE_AUTO_RETRY_EXHAUST                         = "fixture-auto-retry/exhaust"
 	TEST_FIXTURE_AUTO_RETRY_APPLY_ALL_RETRIES               = "fixture-auto-retry/apply-all"
+	TEST_FIXTURE_OUTPUT_JSON                                = "fixture-output-json"
 	TERRAFORM_FOLDER                                        = ".terraform"
 	TERRAFORM_STATE                                         = "terraform.tfstate"
 	TERRAFORM_STATE_BACKUP                                  = "terraform.tfstate.backup"
 	TERRAGRUNT_CACHE                                        = ".terragrunt-cache"
 )
 
+// The fake terraform binary tests that don't need a real Terraform use. See the faketerraform package.
+var fakeTerraformBinary string
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
+func TestMain(m *testing.M) {
+	binaryPath, err := faketerraform.Build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fakeTerraformBinary = binaryPath
+
+	exitCode := m.Run()
+
+	os.RemoveAll(filepath.Dir(binaryPath))
+	os.Exit(exitCode)
+}
+
+func TestAutoInitRunsInitOnlyWhenNeeded(t *testing.T) {
+	t.Parallel()
+
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_AUTO_INIT)
+	defer os.RemoveAll(tmpEnvPath)
+	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_AUTO_INIT)
+
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath))
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath))
+
+	// The first apply finds no .terraform folder, so it runs init, with the extra arguments for init, and the second
+	// one doesn't
+	fake.AssertCalls(t,
+		faketerraform.ExpectedCall{Command: "init", Args: []string{"-lock-timeout=20m"}, Dir: rootPath},
+		faketerraform.ExpectedCall{Command: "apply", Args: []string{"-lock-timeout=20m"}, Dir: rootPath},
+		faketerraform.ExpectedCall{Command: "apply", Args: []string{"-lock-timeout=20m"}, Dir: rootPath},
+	)
+}
+
+func TestAutoInitDisabled(t *testing.T) {
+	t.Parallel()
+
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_AUTO_INIT)
+	defer os.RemoveAll(tmpEnvPath)
+	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_AUTO_INIT)
+
+	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-no-auto-init --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath), os.Stdout, os.Stderr)
+	if assert.Error(t, err) {
+		assert.IsType(t, cli.InitNeededButDisabled(""), errors.Unwrap(err))
+	}
+
+	fake.AssertCalls(t)
+}
+
+func TestAutoInitFailureStopsCommand(t *testing.T) {
+	t.Parallel()
+
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+	fake.Script(t, "init", faketerraform.Response{Stderr: "Error: Failed to install provider\n", ExitCode: 1})
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_AUTO_INIT)
+	defer os.RemoveAll(tmpEnvPath)
+	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_AUTO_INIT)
+
+	stderr := new(bytes.Buffer)
+	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath), os.Stdout, stderr)
+	if assert.Error(t, err) {
+		exitCode, exitCodeErr := shell.GetExitCode(err)
+		assert.NoError(t, exitCodeErr)
+		assert.Equal(t, 1, exitCode)
+	}
+	assert.Contains(t, stderr.String(), "Failed to install provider")
+
+	fake.AssertCalls(t, faketerraform.ExpectedCall{Command: "init", Dir: rootPath})
+}
+
+func TestIncludeDependenciesPlan(t *testing.T) {
+	t.Parallel()
+
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_RUN_WITH_DEPENDENCIES)
+	defer os.RemoveAll(tmpEnvPath)
+	appPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_RUN_WITH_DEPENDENCIES, "app")
+
+	runTerragrunt(t, fmt.Sprintf("terragrunt plan --terragrunt-include-dependencies --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, appPath))
+
+	// app depends on db and cache, and db on vpc, so by default, plan only runs in app and its direct dependencies,
+	// with app last
+	modules := callDirNames(fake.CallsTo(t, "plan"))
+	assert.ElementsMatch(t, []string{"app", "cache", "db"}, modules)
+	if assert.NotEmpty(t, modules) {
+		assert.Equal(t, "app", modules[len(modules)-1])
+	}
+}
+
+func TestIncludeDependenciesApply(t *testing.T) {
+	t.Parallel()
+
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_RUN_WITH_DEPENDENCIES)
+	defer os.RemoveAll(tmpEnvPath)
+	appPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_RUN_WITH_DEPENDENCIES, "app")
+
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-include-dependencies --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, appPath))
+
+	// Apply runs in all the dependencies of app, with vpc before db, and app last
+	calls := fake.CallsTo(t, "apply")
+	for _, call := range calls {
+		assert.Equal(t, []string{"apply", "-input=false", "-auto-approve"}, call.Args)
+	}
+
+	modules := callDirNames(calls)
+	assert.ElementsMatch(t, []string{"app", "cache", "db", "vpc"}, modules)
+	if assert.Len(t, modules, 4) {
+		assert.Equal(t, "app", modules[3])
+	}
+	for index, module := range modules {
+		if module == "db" {
+			assert.Contains(t, modules[:index], "vpc")
+		}
+	}
+}
+
+func TestIncludeDependenciesWithTarget(t *testing.T) {
+	t.Parallel()
+
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_RUN_WITH_DEPENDENCIES)
+	defer os.RemoveAll(tmpEnvPath)
+	appPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_RUN_WITH_DEPENDENCIES, "app")
+
+	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply -target=aws_instance.app --terragrunt-include-dependencies --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, appPath), os.Stdout, os.Stderr)
+	if assert.Error(t, err) {
+		assert.IsType(t, cli.IncludeDependenciesWithTarget(""), errors.Unwrap(err))
+	}
+
+	fake.AssertCalls(t)
+}
+
+// What the fake terraform outputs for output -json in the tests that check only that ends up on stdout
+const FAKE_OUTPUT_JSON = `{"name": {"sensitive": false, "type": "string", "value": "app"}}`
+
+func TestOutputJsonOnlyWritesJsonToStdout(t *testing.T) {
+	t.Parallel()
+
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+	fake.Script(t, "init", faketerraform.Response{Stdout: "Terraform has been successfully initialized!\n"})
+	fake.Script(t, "output", faketerraform.Response{Stdout: FAKE_OUTPUT_JSON + "\n"})
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_OUTPUT_JSON)
+	defer os.RemoveAll(tmpEnvPath)
+	appPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_OUTPUT_JSON, "app1")
+
+	var (
+		stdout bytes.Buffer
+		stderr bytes.Buffer
+	)
+	runTerragruntRedirectOutput(t, fmt.Sprintf("terragrunt output -json --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, appPath), &stdout, &stderr)
+
+	// Auto init, the hooks, and Terragrunt's own log all go to stderr
+	assert.Equal(t, []interface{}{decodeJson(t, FAKE_OUTPUT_JSON)}, decodeJsonStream(t, stdout.String()), "Unexpected stdout:\n%s", stdout.String())
+	assert.Contains(t, stderr.String(), "Terraform has been successfully initialized!")
+	assert.Contains(t, stderr.String(), "output -json\n")
+	assert.Contains(t, stderr.String(), "after hook\n")
+	assert.Contains(t, stderr.String(), "[terragrunt]")
+}
+
+func TestOutputAllJsonOnlyWritesJsonToStdout(t *testing.T) {
+	t.Parallel()
+
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+	fake.Script(t, "output", faketerraform.Response{Stdout: FAKE_OUTPUT_JSON + "\n"})
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_OUTPUT_JSON)
+	defer os.RemoveAll(tmpEnvPath)
+	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_OUTPUT_JSON)
+
+	var (
+		stdout bytes.Buffer
+		stderr bytes.Buffer
+	)
+	runTerragruntRedirectOutput(t, fmt.Sprintf("terragrunt output-all -json --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath), &stdout, &stderr)
+
+	// The output of each module is written to stdout as is, so it's a stream of JSON documents, one per module
+	expected := []interface{}{decodeJson(t, FAKE_OUTPUT_JSON), decodeJson(t, FAKE_OUTPUT_JSON)}
+	assert.Equal(t, expected, decodeJsonStream(t, stdout.String()), "Unexpected stdout:\n%s", stdout.String())
+	assert.Contains(t, stderr.String(), "after hook\n")
+	assert.ElementsMatch(t, []string{"app1", "app2"}, callDirNames(fake.CallsTo(t, "output")))
+}
+
+// Decode the given JSON document, failing the test if it isn't valid JSON
+func decodeJson(t *testing.T, str string) interface{} {
+	var value interface{}
+	require.NoError(t, json.Unmarshal([]byte(str), &value), "Invalid JSON: %s", str)
+	return value
+}
+
+// Decode the given stream of JSON documents, failing the test if anything in it isn't valid JSON
+func decodeJsonStream(t *testing.T, str string) []interface{} {
+	values := []interface{}{}
+	decoder := json.NewDecoder(strings.NewReader(str))
+	for decoder.More() {
+		var value interface{}
+		require.NoError(t, decoder.Decode(&value), "Invalid JSON in: %s", str)
+		values = append(values, value)
+	}
+	return values
+}
+
+// Return the names of the module folders of the given calls, in the order of the calls
+func callDirNames(calls []faketerraform.Call) []string {
+	names := []string{}
+	for _, call := range calls {
+		names = append(names, filepath.Base(call.Dir))
+	}
+	return names
+}
+
 func TestTerragruntInitHookNoSourceNoBackend(t *testing.T) {
 	t.Parallel()
 
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
 	cleanupTerraformFolder(t, TEST_FIXTURE_HOOKS_INIT_ONCE_NO_SOURCE_NO_BACKEND)
 	tmpEnvPath := copyEnvironment(t, "fixture-hooks/init-once")
 	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_HOOKS_INIT_ONCE_NO_SOURCE_NO_BACKEND)
@@ -99,7 +339,7 @@ func TestTerragruntInitHookNoSourceNoBackend(t *testing.T) {
 		stderr bytes.Buffer
 	)
 
-	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", rootPath), &stdout, &stderr)
+	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath), &stdout, &stderr)
 	output := stderr.String()
 
 	if err != nil {
@@ -110,6 +350,8 @@ func TestTerragruntInitHookNoSourceNoBackend(t *testing.T) {
 	assert.Equal(t, 2, strings.Count(output, "AFTER_INIT_ONLY_ONCE"), "Hooks on init command executed more than once")
 	// With no source, `init-from-module` should not execute
 	assert.NotContains(t, output, "AFTER_INIT_FROM_MODULE_ONLY_ONCE", "Hooks on init-from-module command executed when no source was specified")
+
+	fake.AssertCalls(t, faketerraform.ExpectedCall{Command: "init", Dir: rootPath}, faketerraform.ExpectedCall{Command: "apply", Dir: rootPath})
 }
 
 func TestTerragruntInitHookNoSourceWithBackend(t *testing.T) {
@@ -147,6 +389,9 @@ func TestTerragruntInitHookNoSourceWithBackend(t *testing.T) {
 func TestTerragruntInitHookWithSourceNoBackend(t *testing.T) {
 	t.Parallel()
 
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
 	cleanupTerraformFolder(t, TEST_FIXTURE_HOOKS_INIT_ONCE_WITH_SOURCE_NO_BACKEND)
 	tmpEnvPath := copyEnvironment(t, "fixture-hooks/init-once")
 	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_HOOKS_INIT_ONCE_WITH_SOURCE_NO_BACKEND)
@@ -156,7 +401,7 @@ func TestTerragruntInitHookWithSourceNoBackend(t *testing.T) {
 		stderr bytes.Buffer
 	)
 
-	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", rootPath), &stdout, &stderr)
+	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath), &stdout, &stderr)
 	output := stderr.String()
 
 	if err != nil {
@@ -167,6 +412,14 @@ func TestTerragruntInitHookWithSourceNoBackend(t *testing.T) {
 	assert.Equal(t, 2, strings.Count(output, "AFTER_INIT_ONLY_ONCE"), "Hooks on init command executed more than once")
 	// `init-from-module` hook should execute only once (2 occurrences due to the echo and its output)
 	assert.Equal(t, 2, strings.Count(output, "AFTER_INIT_FROM_MODULE_ONLY_ONCE"), "Hooks on init-from-module command executed more than once")
+
+	// The source is downloaded with init -from-module, and then the downloaded code is initialized and applied
+	if fake.AssertCalls(t, faketerraform.ExpectedCall{Command: "init"}, faketerraform.ExpectedCall{Command: "init", Args: []string{}}, faketerraform.ExpectedCall{Command: "apply"}) {
+		calls := fake.Calls(t)
+		assert.Contains(t, calls[0].Args, "-from-module=file://"+filepath.ToSlash(util.JoinPath(tmpEnvPath, "fixture-hooks/init-once/base-module")))
+		assert.Equal(t, calls[1].Dir, calls[2].Dir)
+		assert.True(t, util.FileExists(util.JoinPath(calls[2].Dir, "main.tf")), "Expected the source to be downloaded to %s", calls[2].Dir)
+	}
 }
 
 func TestTerragruntInitHookWithSourceWithBackend(t *testing.T) {
@@ -204,45 +457,103 @@ func TestTerragruntInitHookWithSourceWithBackend(t *testing.T) {
 func TestTerragruntBeforeHook(t *testing.T) {
 	t.Parallel()
 
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
 	cleanupTerraformFolder(t, TEST_FIXTURE_HOOKS_BEFORE_ONLY_PATH)
 	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_HOOKS_BEFORE_ONLY_PATH)
 	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_HOOKS_BEFORE_ONLY_PATH)
 
-	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", rootPath))
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath))
 
 	_, exception := ioutil.ReadFile(rootPath + "/file.out")
 
 	assert.NoError(t, exception)
+
+	fake.AssertCalls(t, faketerraform.ExpectedCall{Command: "init", Dir: rootPath}, faketerraform.ExpectedCall{Command: "apply", Dir: rootPath})
 }
 
 func TestTerragruntAfterHook(t *testing.T) {
 	t.Parallel()
 
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
 	cleanupTerraformFolder(t, TEST_FIXTURE_HOOKS_AFTER_ONLY_PATH)
 	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_HOOKS_AFTER_ONLY_PATH)
 	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_HOOKS_AFTER_ONLY_PATH)
 
-	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", rootPath))
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath))
 
 	_, exception := ioutil.ReadFile(rootPath + "/file.out")
 
 	assert.NoError(t, exception)
+
+	fake.AssertCalls(t, faketerraform.ExpectedCall{Command: "init", Dir: rootPath}, faketerraform.ExpectedCall{Command: "apply", Dir: rootPath})
+}
+
+func TestTerragruntHooksGetModuleEnvVars(t *testing.T) {
+	t.Parallel()
+
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_HOOKS_MODULE_ENV_PATH)
+	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_HOOKS_MODULE_ENV_PATH)
+	appPath := util.JoinPath(rootPath, "app")
+	dbPath := util.JoinPath(rootPath, "data", "db")
+
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply-all --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath))
+
+	appEnv := readModuleEnvFile(t, util.JoinPath(appPath, "module.env"))
+	dbEnv := readModuleEnvFile(t, util.JoinPath(dbPath, "module.env"))
+
+	assert.Equal(t, "app", appEnv["TERRAGRUNT_MODULE_PATH"])
+	assert.Equal(t, "data/db", dbEnv["TERRAGRUNT_MODULE_PATH"])
+	assert.Equal(t, util.JoinPath(appPath, config.DefaultTerragruntConfigPath), appEnv["TERRAGRUNT_CONFIG_PATH"])
+	assert.Equal(t, util.JoinPath(dbPath, config.DefaultTerragruntConfigPath), dbEnv["TERRAGRUNT_CONFIG_PATH"])
+	assert.Equal(t, "apply", appEnv["TERRAGRUNT_COMMAND"])
+	assert.Equal(t, "apply", dbEnv["TERRAGRUNT_COMMAND"])
+	assert.Equal(t, util.JoinPath(appPath, options.TerragruntCacheDir), appEnv["TERRAGRUNT_DOWNLOAD_DIR"])
+	assert.Equal(t, util.JoinPath(dbPath, options.TerragruntCacheDir), dbEnv["TERRAGRUNT_DOWNLOAD_DIR"])
+	assert.NotEmpty(t, appEnv["TERRAGRUNT_STACK_RUN_ID"])
+	assert.Equal(t, appEnv["TERRAGRUNT_STACK_RUN_ID"], dbEnv["TERRAGRUNT_STACK_RUN_ID"])
+
+	// Running a single module sets the same env vars, with a new run ID
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, appPath))
+
+	singleEnv := readModuleEnvFile(t, util.JoinPath(appPath, "module.env"))
+	assert.Equal(t, ".", singleEnv["TERRAGRUNT_MODULE_PATH"])
+	assert.Equal(t, appEnv["TERRAGRUNT_CONFIG_PATH"], singleEnv["TERRAGRUNT_CONFIG_PATH"])
+	assert.Equal(t, "apply", singleEnv["TERRAGRUNT_COMMAND"])
+	assert.Equal(t, appEnv["TERRAGRUNT_DOWNLOAD_DIR"], singleEnv["TERRAGRUNT_DOWNLOAD_DIR"])
+	assert.NotEmpty(t, singleEnv["TERRAGRUNT_STACK_RUN_ID"])
+	assert.NotEqual(t, appEnv["TERRAGRUNT_STACK_RUN_ID"], singleEnv["TERRAGRUNT_STACK_RUN_ID"])
+
+	// Each module is initialized once, the first time it's applied
+	assert.Len(t, fake.CallsTo(t, "init"), 2)
+	assert.Len(t, fake.CallsTo(t, "apply"), 3)
 }
 
 func TestTerragruntBeforeAndAfterHook(t *testing.T) {
 	t.Parallel()
 
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
 	cleanupTerraformFolder(t, TEST_FIXTURE_HOOKS_BEFORE_AND_AFTER_PATH)
 	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_HOOKS_BEFORE_AND_AFTER_PATH)
 	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_HOOKS_BEFORE_AND_AFTER_PATH)
 
-	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", rootPath))
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath))
 
 	_, beforeException := ioutil.ReadFile(rootPath + "/before.out")
 	_, afterException := ioutil.ReadFile(rootPath + "/after.out")
 
 	assert.NoError(t, beforeException)
 	assert.NoError(t, afterException)
+
+	fake.AssertCalls(t, faketerraform.ExpectedCall{Command: "init", Dir: rootPath}, faketerraform.ExpectedCall{Command: "apply", Dir: rootPath})
 }
 
 func TestTerragruntBeforeAndAfterMergeHook(t *testing.T) {
@@ -277,6 +588,9 @@ func TestTerragruntBeforeAndAfterMergeHook(t *testing.T) {
 func TestTerragruntSkipOnError(t *testing.T) {
 	t.Parallel()
 
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
 	cleanupTerraformFolder(t, TEST_FIXTURE_HOOKS_SKIP_ON_ERROR_PATH)
 	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_HOOKS_SKIP_ON_ERROR_PATH)
 	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_HOOKS_SKIP_ON_ERROR_PATH)
@@ -286,7 +600,7 @@ func TestTerragruntSkipOnError(t *testing.T) {
 		stderr bytes.Buffer
 	)
 
-	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", rootPath), &stdout, &stderr)
+	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath), &stdout, &stderr)
 
 	output := stderr.String()
 	if err != nil {
@@ -298,11 +612,17 @@ func TestTerragruntSkipOnError(t *testing.T) {
 	} else {
 		t.Error("Expected NO terragrunt execution due to previous errors but it did run.")
 	}
+
+	// Auto-init still runs, as it happens before the hooks, but apply doesn't
+	fake.AssertCalls(t, faketerraform.ExpectedCall{Command: "init", Dir: rootPath})
 }
 
 func TestTerragruntBeforeOneArgAction(t *testing.T) {
 	t.Parallel()
 
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
 	cleanupTerraformFolder(t, TEST_FIXTURE_HOOKS_ONE_ARG_ACTION_PATH)
 	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_HOOKS_ONE_ARG_ACTION_PATH)
 	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_HOOKS_ONE_ARG_ACTION_PATH)
@@ -312,7 +632,7 @@ func TestTerragruntBeforeOneArgAction(t *testing.T) {
 		stderr bytes.Buffer
 	)
 
-	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", rootPath), &stdout, &stderr)
+	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath), &stdout, &stderr)
 	output := stderr.String()
 
 	if err != nil {
@@ -325,6 +645,9 @@ func TestTerragruntBeforeOneArgAction(t *testing.T) {
 func TestTerragruntEmptyStringCommandHook(t *testing.T) {
 	t.Parallel()
 
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
 	cleanupTerraformFolder(t, TEST_FIXTURE_HOOKS_EMPTY_STRING_COMMAND_PATH)
 	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_HOOKS_EMPTY_STRING_COMMAND_PATH)
 	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_HOOKS_EMPTY_STRING_COMMAND_PATH)
@@ -334,18 +657,24 @@ func TestTerragruntEmptyStringCommandHook(t *testing.T) {
 		stderr bytes.Buffer
 	)
 
-	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", rootPath), &stdout, &stderr)
+	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath), &stdout, &stderr)
 
 	if err != nil {
 		assert.Contains(t, err.Error(), "Need at least one non-empty argument in 'execute'.")
 	} else {
 		t.Error("Expected an Error with message: 'Need at least one argument'")
 	}
+
+	// The config is invalid, so terraform never runs
+	fake.AssertCalls(t)
 }
 
 func TestTerragruntEmptyCommandListHook(t *testing.T) {
 	t.Parallel()
 
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
 	cleanupTerraformFolder(t, TEST_FIXTURE_HOOKS_EMPTY_COMMAND_LIST_PATH)
 	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_HOOKS_EMPTY_COMMAND_LIST_PATH)
 	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_HOOKS_EMPTY_COMMAND_LIST_PATH)
@@ -355,18 +684,24 @@ func TestTerragruntEmptyCommandListHook(t *testing.T) {
 		stderr bytes.Buffer
 	)
 
-	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", rootPath), &stdout, &stderr)
+	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath), &stdout, &stderr)
 
 	if err != nil {
 		assert.Contains(t, err.Error(), "Need at least one non-empty argument in 'execute'.")
 	} else {
 		t.Error("Expected an Error with message: 'Need at least one argument'")
 	}
+
+	// The config is invalid, so terraform never runs
+	fake.AssertCalls(t)
 }
 
 func TestTerragruntHookInterpolation(t *testing.T) {
 	t.Parallel()
 
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
 	cleanupTerraformFolder(t, TEST_FIXTURE_HOOKS_INTERPOLATIONS_PATH)
 	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_HOOKS_INTERPOLATIONS_PATH)
 	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_HOOKS_INTERPOLATIONS_PATH)
@@ -376,7 +711,7 @@ func TestTerragruntHookInterpolation(t *testing.T) {
 		stderr bytes.Buffer
 	)
 
-	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", rootPath), &stdout, &stderr)
+	err := runTerragruntCommand(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath), &stdout, &stderr)
 	erroutput := stderr.String()
 
 	homePath := os.Getenv("HOME")
@@ -641,6 +976,40 @@ func TestLocalDownload(t *testing.T) {
 	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", TEST_FIXTURE_LOCAL_DOWNLOAD_PATH))
 }
 
+func TestLocalDownloadWithReadOnlyConfigDir(t *testing.T) {
+	t.Parallel()
+
+	tmpEnvPath := copyEnvironment(t, "fixture-download")
+	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_LOCAL_DOWNLOAD_PATH)
+
+	require.NoError(t, os.Chmod(rootPath, 0555))
+	defer os.Chmod(rootPath, 0755)
+
+	if probe, err := ioutil.TempFile(rootPath, "probe"); err == nil {
+		probe.Close()
+		os.Remove(probe.Name())
+		t.Skip("Unable to make a folder read-only, probably because the tests are running as root")
+	}
+
+	scratchDownloadDir, err := options.ScratchDownloadDir(util.JoinPath(rootPath, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	defer os.RemoveAll(scratchDownloadDir)
+
+	runTerragrunt(t, fmt.Sprintf("terragrunt plan --terragrunt-non-interactive --terragrunt-track-config-changes --terragrunt-working-dir %s", rootPath))
+
+	assert.False(t, util.FileExists(util.JoinPath(rootPath, TERRAGRUNT_CACHE)), "Nothing should be written next to the read-only config")
+	assert.True(t, util.FileExists(scratchDownloadDir), "The source should be downloaded into the scratch dir")
+	assert.True(t, util.FileExists(util.JoinPath(scratchDownloadDir, cli.CONFIG_SNAPSHOTS_DIR)), "The config snapshot should be stored in the scratch dir")
+
+	// An explicitly configured download dir that is read-only is an error, rather than silently falling back
+	readOnlyDownloadDir := util.JoinPath(tmpEnvPath, "read-only-download-dir")
+	require.NoError(t, os.Mkdir(readOnlyDownloadDir, 0555))
+	err = runTerragruntCommand(t, fmt.Sprintf("terragrunt plan --terragrunt-non-interactive --terragrunt-working-dir %s --terragrunt-download-dir %s", rootPath, util.JoinPath(readOnlyDownloadDir, "cache")), os.Stdout, os.Stderr)
+	if assert.Error(t, err) {
+		assert.IsType(t, cli.PathNotWritable{}, errors.Unwrap(err))
+	}
+}
+
 func TestLocalDownloadWithHiddenFolder(t *testing.T) {
 	t.Parallel()
 
@@ -799,48 +1168,106 @@ func TestExitCode(t *testing.T) {
 
 func TestExtraArguments(t *testing.T) {
 	// Do not use t.Parallel() on this test, it will infers with the other TestExtraArguments.* tests
-	out := new(bytes.Buffer)
-	runTerragruntRedirectOutput(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", TEST_FIXTURE_EXTRA_ARGS_PATH), out, os.Stderr)
-	t.Log(out.String())
-	assert.Contains(t, out.String(), "Hello, World from dev!")
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_EXTRA_ARGS_PATH)
+	defer os.RemoveAll(tmpEnvPath)
+	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_EXTRA_ARGS_PATH)
+
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath))
+
+	fake.AssertCalls(t,
+		faketerraform.ExpectedCall{Command: "init", Dir: rootPath},
+		faketerraform.ExpectedCall{Command: "apply", Args: extraArgumentsVarFiles(rootPath, "dev.tfvars"), Dir: rootPath},
+	)
 }
 
 func TestExtraArgumentsWithEnv(t *testing.T) {
 	// Do not use t.Parallel() on this test, it will infers with the other TestExtraArguments.* tests
-	out := new(bytes.Buffer)
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_EXTRA_ARGS_PATH)
+	defer os.RemoveAll(tmpEnvPath)
+	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_EXTRA_ARGS_PATH)
+
 	os.Setenv("TF_VAR_env", "prod")
 	defer os.Unsetenv("TF_VAR_env")
-	runTerragruntRedirectOutput(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", TEST_FIXTURE_EXTRA_ARGS_PATH), out, os.Stderr)
-	t.Log(out.String())
-	assert.Contains(t, out.String(), "Hello, World!")
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath))
+
+	// There's no prod.tfvars, so only the required var files are passed
+	fake.AssertCalls(t,
+		faketerraform.ExpectedCall{Command: "init", Dir: rootPath},
+		faketerraform.ExpectedCall{Command: "apply", Args: extraArgumentsVarFiles(rootPath), Dir: rootPath},
+	)
 }
 
 func TestExtraArgumentsWithEnvVarBlock(t *testing.T) {
-	out := new(bytes.Buffer)
-	runTerragruntRedirectOutput(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", TEST_FIXTURE_ENV_VARS_BLOCK_PATH), out, os.Stderr)
-	t.Log(out.String())
-	assert.Contains(t, out.String(), "I'm set in extra_arguments env_vars")
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_ENV_VARS_BLOCK_PATH)
+	defer os.RemoveAll(tmpEnvPath)
+	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_ENV_VARS_BLOCK_PATH)
+
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath))
+
+	applyCalls := fake.CallsTo(t, "apply")
+	if assert.Len(t, applyCalls, 1) {
+		assert.Equal(t, "I'm set in extra_arguments env_vars", applyCalls[0].Env["TF_VAR_custom_var"])
+	}
 }
 
 func TestExtraArgumentsWithRegion(t *testing.T) {
 	// Do not use t.Parallel() on this test, it will infers with the other TestExtraArguments.* tests
-	out := new(bytes.Buffer)
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_EXTRA_ARGS_PATH)
+	defer os.RemoveAll(tmpEnvPath)
+	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_EXTRA_ARGS_PATH)
+
 	os.Setenv("TF_VAR_region", "us-west-2")
 	defer os.Unsetenv("TF_VAR_region")
-	runTerragruntRedirectOutput(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-working-dir %s", TEST_FIXTURE_EXTRA_ARGS_PATH), out, os.Stderr)
-	t.Log(out.String())
-	assert.Contains(t, out.String(), "Hello, World from Oregon!")
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", fake.Path, rootPath))
+
+	fake.AssertCalls(t,
+		faketerraform.ExpectedCall{Command: "init", Dir: rootPath},
+		faketerraform.ExpectedCall{Command: "apply", Args: extraArgumentsVarFiles(rootPath, "dev.tfvars", "us-west-2.tfvars"), Dir: rootPath},
+	)
 }
 
 func TestPriorityOrderOfArgument(t *testing.T) {
 	// Do not use t.Parallel() on this test, it will infers with the other TestExtraArguments.* tests
-	out := new(bytes.Buffer)
+	fake := faketerraform.New(t, fakeTerraformBinary)
+	defer fake.Close()
+
+	tmpEnvPath := copyEnvironment(t, TEST_FIXTURE_EXTRA_ARGS_PATH)
+	defer os.RemoveAll(tmpEnvPath)
+	rootPath := util.JoinPath(tmpEnvPath, TEST_FIXTURE_EXTRA_ARGS_PATH)
+
 	injectedValue := "Injected-directly-by-argument"
-	runTerragruntRedirectOutput(t, fmt.Sprintf("terragrunt apply -var extra_var=%s --terragrunt-non-interactive --terragrunt-working-dir %s", injectedValue, TEST_FIXTURE_EXTRA_ARGS_PATH), out, os.Stderr)
-	t.Log(out.String())
-	// And the result value for test should be the injected variable since the injected arguments are injected before the suplied parameters,
-	// so our override of extra_var should be the last argument.
-	assert.Contains(t, out.String(), fmt.Sprintf("test = %s", injectedValue))
+	runTerragrunt(t, fmt.Sprintf("terragrunt apply -var extra_var=%s --terragrunt-non-interactive --terragrunt-tfpath %s --terragrunt-working-dir %s", injectedValue, fake.Path, rootPath))
+
+	// The extra arguments are injected before the supplied arguments, so that our override of extra_var is the last
+	// argument, and wins
+	expectedArgs := append(extraArgumentsVarFiles(rootPath, "dev.tfvars"), "-var", fmt.Sprintf("extra_var=%s", injectedValue))
+	fake.AssertCalls(t,
+		faketerraform.ExpectedCall{Command: "init", Dir: rootPath},
+		faketerraform.ExpectedCall{Command: "apply", Args: expectedArgs, Dir: rootPath},
+	)
+}
+
+// Return the arguments terragrunt passes to apply in the extra args fixture at the given path: the arguments and
+// required var files of its extra_arguments blocks, as written, followed by the given optional var files, which must
+// exist, resolved relative to the fixture
+func extraArgumentsVarFiles(rootPath string, optionalVarFiles ...string) []string {
+	args := []string{"-var-file=terraform.tfvars", "-var-file=extra.tfvars"}
+	for _, varFile := range optionalVarFiles {
+		args = append(args, fmt.Sprintf("-var-file=%s", util.JoinPath(rootPath, varFile)))
+	}
+	return args
 }
 
 func TestAutoRetryBasicRerun(t *testing.T) {
@@ -1617,3 +2044,18 @@ func cleanupTableForTest(t *testing.T, tableName string, awsRegion string) {
 	err := terragruntDynamoDb.DeleteTable(tableName, client)
 	assert.Nil(t, err, "Unexpected error: %v", err)
 }
+
+// Parse a file written by the env command into a map of env var names to values
+func readModuleEnvFile(t *testing.T, path string) map[string]string {
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	env := map[string]string{}
+	for _, line := range strings.Split(string(contents), "\n") {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	return env
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How many times each of a set of named calls, such as the calls to each helper function while parsing configs, was
+// made, and how long the calls took in total. It's safe to use concurrently, and once a name has been seen, recording
+// a call to it only takes two atomic additions, so it's cheap enough to always leave on. A nil CallMetrics records
+// nothing.
+type CallMetrics struct {
+	counters sync.Map
+}
+
+type callCounter struct {
+	calls int64
+	nanos int64
+}
+
+// The calls made to one name, as returned by CallMetrics.Snapshot
+type CallMetric struct {
+	Name     string        `json:"name"`
+	Calls    int64         `json:"calls"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+func NewCallMetrics() *CallMetrics {
+	return &CallMetrics{}
+}
+
+// Record a call to the given name that took the given duration
+func (metrics *CallMetrics) Record(name string, duration time.Duration) {
+	if metrics == nil {
+		return
+	}
+
+	counter, isKnown := metrics.counters.Load(name)
+	if !isKnown {
+		counter, _ = metrics.counters.LoadOrStore(name, &callCounter{})
+	}
+
+	atomic.AddInt64(&counter.(*callCounter).calls, 1)
+	atomic.AddInt64(&counter.(*callCounter).nanos, int64(duration))
+}
+
+// Return the calls recorded so far for each name, sorted by name
+func (metrics *CallMetrics) Snapshot() []CallMetric {
+	snapshot := []CallMetric{}
+	if metrics == nil {
+		return snapshot
+	}
+
+	metrics.counters.Range(func(name interface{}, counter interface{}) bool {
+		snapshot = append(snapshot, CallMetric{
+			Name:     name.(string),
+			Calls:    atomic.LoadInt64(&counter.(*callCounter).calls),
+			Duration: time.Duration(atomic.LoadInt64(&counter.(*callCounter).nanos)),
+		})
+		return true
+	})
+
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].Name < snapshot[j].Name })
+	return snapshot
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallMetrics(t *testing.T) {
+	t.Parallel()
+
+	metrics := NewCallMetrics()
+	assert.Equal(t, []CallMetric{}, metrics.Snapshot())
+
+	metrics.Record("get_env", 2*time.Millisecond)
+	metrics.Record("run_cmd", 50*time.Millisecond)
+	metrics.Record("get_env", 3*time.Millisecond)
+
+	expected := []CallMetric{
+		{Name: "get_env", Calls: 2, Duration: 5 * time.Millisecond},
+		{Name: "run_cmd", Calls: 1, Duration: 50 * time.Millisecond},
+	}
+	assert.Equal(t, expected, metrics.Snapshot())
+}
+
+func TestCallMetricsConcurrent(t *testing.T) {
+	t.Parallel()
+
+	metrics := NewCallMetrics()
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for j := 0; j < 100; j++ {
+				metrics.Record("get_env", time.Microsecond)
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	assert.Equal(t, []CallMetric{{Name: "get_env", Calls: 1000, Duration: 1000 * time.Microsecond}}, metrics.Snapshot())
+}
+
+func TestCallMetricsNil(t *testing.T) {
+	t.Parallel()
+
+	var metrics *CallMetrics
+	metrics.Record("get_env", time.Millisecond)
+	assert.Equal(t, []CallMetric{}, metrics.Snapshot())
+}
@@ -3,6 +3,7 @@ package util
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -74,6 +75,54 @@ func CommaSeparatedStrings(list []string) string {
 	return strings.Join(values, ", ")
 }
 
+// CommaSeparatedInts returns an HCL compliant formatted list of numbers
+func CommaSeparatedInts(list []int) string {
+	values := make([]string, 0, len(list))
+	for _, value := range list {
+		values = append(values, fmt.Sprintf("%d", value))
+	}
+	return strings.Join(values, ", ")
+}
+
+// HclMapOfStrings returns an HCL compliant formatted map of strings, with the keys in sorted order
+func HclMapOfStrings(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	items := make([]string, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, fmt.Sprintf(`"%s" = "%s"`, key, m[key]))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(items, ", "))
+}
+
+// CommaSeparatedMaps returns an HCL compliant formatted list of maps, with the keys of each map in sorted order. String
+// values are quoted, while other values, such as numbers, are not.
+func CommaSeparatedMaps(list []map[string]interface{}) string {
+	maps := make([]string, 0, len(list))
+	for _, m := range list {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		items := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if value, isString := m[key].(string); isString {
+				items = append(items, fmt.Sprintf(`"%s" = "%s"`, key, value))
+			} else {
+				items = append(items, fmt.Sprintf(`"%s" = %v`, key, m[key]))
+			}
+		}
+		maps = append(maps, fmt.Sprintf("{%s}", strings.Join(items, ", ")))
+	}
+	return strings.Join(maps, ", ")
+}
+
 // Make a copy of the given list of strings
 func CloneStringList(listToClone []string) []string {
 	out := []string{}
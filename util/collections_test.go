@@ -125,3 +125,55 @@ func TestCommaSeparatedStrings(t *testing.T) {
 		t.Logf("%v passed", testCase.list)
 	}
 }
+
+func TestCommaSeparatedInts(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		list     []int
+		expected string
+	}{
+		{[]int{}, ``},
+		{[]int{0}, `0`},
+		{[]int{3, -1, 10}, `3, -1, 10`},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, CommaSeparatedInts(testCase.list), testCase.expected, "For list %v", testCase.list)
+	}
+}
+
+func TestHclMapOfStrings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		m        map[string]string
+		expected string
+	}{
+		{map[string]string{}, `{}`},
+		{map[string]string{"Name": "vpc"}, `{"Name" = "vpc"}`},
+		{map[string]string{"Name": "vpc", "Env": "prod"}, `{"Env" = "prod", "Name" = "vpc"}`},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, HclMapOfStrings(testCase.m), "For map %v", testCase.m)
+	}
+}
+
+func TestCommaSeparatedMaps(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		list     []map[string]interface{}
+		expected string
+	}{
+		{[]map[string]interface{}{}, ``},
+		{[]map[string]interface{}{{}}, `{}`},
+		{[]map[string]interface{}{{"value": "vpc", "key": "Name"}}, `{"key" = "Name", "value" = "vpc"}`},
+		{[]map[string]interface{}{{"key": "a", "value": 1}, {"key": "b", "value": true}}, `{"key" = "a", "value" = 1}, {"key" = "b", "value" = true}`},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, CommaSeparatedMaps(testCase.list), "For list %v", testCase.list)
+	}
+}
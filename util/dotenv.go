@@ -0,0 +1,59 @@
+package util
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Parse the contents of a dotenv file (e.g. .env) into a map of keys to values. Blank lines and lines starting with #
+// are ignored, an optional "export " prefix is stripped from each key, and values may be wrapped in single or double
+// quotes. Double quoted values support the usual escape sequences (e.g. \n, \"), while single quoted values are taken
+// literally. Unquoted values end at the first " #", which starts an inline comment.
+func ParseDotEnv(contents string) map[string]string {
+	out := map[string]string{}
+
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		keyAndValue := strings.SplitN(line, "=", 2)
+		if len(keyAndValue) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(keyAndValue[0])
+		if key == "" {
+			continue
+		}
+
+		out[key] = parseDotEnvValue(strings.TrimSpace(keyAndValue[1]))
+	}
+
+	return out
+}
+
+// Parse a single value from a dotenv file, removing surrounding quotes and inline comments
+func parseDotEnvValue(value string) string {
+	if len(value) >= 2 {
+		switch {
+		case value[0] == '\'' && strings.LastIndex(value, "'") > 0:
+			return value[1:strings.LastIndex(value, "'")]
+		case value[0] == '"' && strings.LastIndex(value, `"`) > 0:
+			quoted := value[:strings.LastIndex(value, `"`)+1]
+			if unquoted, err := strconv.Unquote(quoted); err == nil {
+				return unquoted
+			}
+			return quoted[1 : len(quoted)-1]
+		}
+	}
+
+	if commentIndex := strings.Index(value, " #"); commentIndex >= 0 {
+		value = value[:commentIndex]
+	}
+
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		contents string
+		expected map[string]string
+	}{
+		{``, map[string]string{}},
+		{"# just a comment\n\n", map[string]string{}},
+		{`FOO=bar`, map[string]string{"FOO": "bar"}},
+		{"FOO = bar  \r\nBAZ=qux", map[string]string{"FOO": "bar", "BAZ": "qux"}},
+		{`export FOO=bar`, map[string]string{"FOO": "bar"}},
+		{`FOO="bar baz"`, map[string]string{"FOO": "bar baz"}},
+		{`FOO='bar baz'`, map[string]string{"FOO": "bar baz"}},
+		{`FOO="line1\nline2"`, map[string]string{"FOO": "line1\nline2"}},
+		{`FOO='line1\nline2'`, map[string]string{"FOO": `line1\nline2`}},
+		{`FOO="bar" # comment`, map[string]string{"FOO": "bar"}},
+		{`FOO=bar # comment`, map[string]string{"FOO": "bar"}},
+		{`FOO=bar#baz`, map[string]string{"FOO": "bar#baz"}},
+		{`FOO=a=b`, map[string]string{"FOO": "a=b"}},
+		{`FOO=`, map[string]string{"FOO": ""}},
+		{"not a valid line\nFOO=bar", map[string]string{"FOO": "bar"}},
+	}
+
+	for _, testCase := range testCases {
+		actual := ParseDotEnv(testCase.contents)
+		assert.Equal(t, testCase.expected, actual, "For contents %s", testCase.contents)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"syscall"
 
 	"fmt"
 	"github.com/gruntwork-io/terragrunt/errors"
@@ -18,6 +19,25 @@ func FileExists(path string) bool {
 	return err == nil
 }
 
+// Return true if the given error means that a path could not be written to because the path, or the file system it's
+// on, is read-only
+func IsReadOnlyError(err error) bool {
+	err = errors.Unwrap(err)
+	if os.IsPermission(err) {
+		return true
+	}
+
+	switch err := err.(type) {
+	case *os.PathError:
+		return err.Err == syscall.EROFS
+	case *os.LinkError:
+		return err.Err == syscall.EROFS
+	case *os.SyscallError:
+		return err.Err == syscall.EROFS
+	}
+	return err == syscall.EROFS
+}
+
 // Return the canonical version of the given path, relative to the given base path. That is, if the given path is a
 // relative path, assume it is relative to the given base path. A canonical path is an absolute path with all relative
 // components (e.g. "../") fully resolved, which makes it safe to compare paths as strings.
@@ -33,6 +53,34 @@ func CanonicalPath(path string, basePath string) (string, error) {
 	return CleanPath(absPath), nil
 }
 
+// Return the canonical version of the given path, relative to the given base path, as CanonicalPath does, but with any
+// symlinks in it resolved, so that two paths to the same folder, such as one through a symlinked checkout, are equal.
+// If the end of the path doesn't exist yet, the part of it that does is resolved and the rest is kept as it is.
+func CanonicalPathWithSymlinks(path string, basePath string) (string, error) {
+	canonicalPath, err := CanonicalPath(path, basePath)
+	if err != nil {
+		return "", err
+	}
+
+	existingPath, remainingPath := filepath.FromSlash(canonicalPath), ""
+	for {
+		resolvedPath, err := filepath.EvalSymlinks(existingPath)
+		if err == nil {
+			return CleanPath(filepath.Join(resolvedPath, remainingPath)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", errors.WithStackTrace(err)
+		}
+
+		parentPath := filepath.Dir(existingPath)
+		if parentPath == existingPath {
+			return canonicalPath, nil
+		}
+		remainingPath = filepath.Join(filepath.Base(existingPath), remainingPath)
+		existingPath = parentPath
+	}
+}
+
 // Return the canonical version of the given paths, relative to the given base path. That is, if a given path is a
 // relative path, assume it is relative to the given base path. A canonical path is an absolute path with all relative
 // components (e.g. "../") fully resolved, which makes it safe to compare paths as strings.
@@ -127,14 +175,30 @@ func GetPathRelativeTo(path string, basePath string) (string, error) {
 	return filepath.ToSlash(relPath), nil
 }
 
-// Return the contents of the file at the given path as a string
+// Return the contents of the file at the given path as a string, without a leading UTF-8 byte order mark (see
+// StripUTF8BOM)
 func ReadFileAsString(path string) (string, error) {
 	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		return "", errors.WithStackTraceAndPrefix(err, "Error reading file at path %s", path)
 	}
 
-	return string(bytes), nil
+	return StripUTF8BOM(string(bytes)), nil
+}
+
+// The byte order mark that editors on Windows often put at the start of UTF-8 encoded files
+const UTF8_BOM = "\xef\xbb\xbf"
+
+// Remove the UTF-8 byte order mark, if any, from the start of the given file contents. A BOM is meaningless in UTF-8,
+// but if left in place, it ends up in the first key or value read from the file, or makes the file fail to parse.
+func StripUTF8BOM(contents string) string {
+	return strings.TrimPrefix(contents, UTF8_BOM)
+}
+
+// Convert Windows (CRLF) and old Mac (CR) line endings in the given file contents to Unix (LF) line endings, so that
+// none of the values parsed from the contents end up with a stray carriage return in them
+func NormalizeLineEndings(contents string) string {
+	return strings.Replace(strings.Replace(contents, "\r\n", "\n", -1), "\r", "\n", -1)
 }
 
 // Copy the files and folders within the source folder into the destination folder. Note that hidden files and folders
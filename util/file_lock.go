@@ -0,0 +1,170 @@
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// A lock file older than this is assumed to have been left behind by a process that died while holding the lock, so
+// it's removed rather than waited on
+const STALE_LOCK_FILE_AGE = 1 * time.Minute
+
+// How often the modification time of a lock file is updated while the lock is held, so that it never looks stale, no
+// matter how long the lock is held for
+const LOCK_FILE_REFRESH_INTERVAL = STALE_LOCK_FILE_AGE / 4
+
+// How long to wait between attempts to acquire a lock file that is held by someone else
+const LOCK_FILE_RETRY_INTERVAL = 50 * time.Millisecond
+
+// Acquire an exclusive lock by creating a file at the given path. Creating the file only succeeds if it doesn't exist
+// yet, which makes this safe across processes on all platforms. If someone else holds the lock, wait up to the given
+// timeout for them to release it. Returns a function that releases the lock by removing the file.
+//
+// The file holds the PID of this process and a random token, which identify this holder of the lock. While the lock
+// is held, the modification time of the file is updated every LOCK_FILE_REFRESH_INTERVAL, so only the lock file of a
+// process that died is ever older than STALE_LOCK_FILE_AGE. Releasing the lock only removes the file if it still
+// holds the same token, so a holder never removes a lock that has since been acquired by someone else.
+func AcquireLockFile(path string, timeout time.Duration) (func() error, error) {
+	uuid, err := NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	token := fmt.Sprintf("%d %s", os.Getpid(), uuid)
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		lockFile, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, writeErr := lockFile.WriteString(token)
+			closeErr := lockFile.Close()
+			if writeErr != nil || closeErr != nil {
+				os.Remove(path)
+				if writeErr != nil {
+					return nil, errors.WithStackTrace(writeErr)
+				}
+				return nil, errors.WithStackTrace(closeErr)
+			}
+			return holdLockFile(path, token), nil
+		}
+		if !os.IsExist(err) {
+			return nil, errors.WithStackTrace(err)
+		}
+
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > STALE_LOCK_FILE_AGE {
+			breakStaleLockFile(path, info)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.WithStackTrace(LockFileTimeout{Path: path, Timeout: timeout})
+		}
+		time.Sleep(LOCK_FILE_RETRY_INTERVAL)
+	}
+}
+
+// Keep the lock file at the given path, which holds the given token, fresh until the returned function is called. The
+// returned function releases the lock, by removing the file if it still holds the token. If it doesn't, the lock was
+// broken and acquired by someone else in the meantime, so the file is left alone and a LockFileLost error is returned.
+func holdLockFile(path string, token string) func() error {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(LOCK_FILE_REFRESH_INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				refreshLockFile(path, token)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() error {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+
+		if !lockFileHoldsToken(path, token) {
+			return errors.WithStackTrace(LockFileLost(path))
+		}
+		return errors.WithStackTrace(os.Remove(path))
+	}
+}
+
+// Update the modification time of the lock file at the given path to now, if it still holds the given token. Returns
+// true if it did.
+func refreshLockFile(path string, token string) bool {
+	if !lockFileHoldsToken(path, token) {
+		return false
+	}
+	now := time.Now()
+	return os.Chtimes(path, now, now) == nil
+}
+
+// Returns true if the lock file at the given path exists and holds the given token
+func lockFileHoldsToken(path string, token string) bool {
+	contents, err := ioutil.ReadFile(path)
+	return err == nil && string(contents) == token
+}
+
+// Remove the stale lock file at the given path, which was found to be stale when it had the given info. Between
+// looking at the file and removing it, someone else may have broken the same stale lock and acquired a fresh one, so
+// rather than removing the path, which could remove their lock, the file is first renamed to a name of its own. If the
+// renamed file turns out not to be the stale one, it's put back. Returns true if the stale lock file was removed.
+func breakStaleLockFile(path string, staleInfo os.FileInfo) bool {
+	uuid, err := NewUUID()
+	if err != nil {
+		return false
+	}
+	brokenPath := fmt.Sprintf("%s.%s.stale", path, uuid)
+
+	if err := os.Rename(path, brokenPath); err != nil {
+		// Most likely someone else already broke or released the lock
+		return false
+	}
+
+	brokenInfo, err := os.Stat(brokenPath)
+	if err == nil && os.SameFile(staleInfo, brokenInfo) && brokenInfo.ModTime().Equal(staleInfo.ModTime()) {
+		os.Remove(brokenPath)
+		return true
+	}
+
+	// This is a fresh lock, so put it back. Unlike a rename, a hard link never replaces a file that already exists, so
+	// this never removes a lock someone may have acquired while the path was free.
+	os.Link(brokenPath, path)
+	os.Remove(brokenPath)
+	return false
+}
+
+// Custom error types
+
+type LockFileTimeout struct {
+	Path    string
+	Timeout time.Duration
+}
+
+func (err LockFileTimeout) Error() string {
+	return fmt.Sprintf("Timed out after %v waiting for the lock file %s. If no other Terragrunt process is running, delete it and try again.", err.Timeout, err.Path)
+}
+
+func (err LockFileTimeout) Class() errors.ErrorClass {
+	return errors.ErrorClassLock
+}
+
+type LockFileLost string
+
+func (err LockFileLost) Error() string {
+	return fmt.Sprintf("The lock file %s no longer belongs to this process, as it was taken over by someone else while the lock was held, so it was left alone.", string(err))
+}
@@ -0,0 +1,137 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireLockFile(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "file-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	lockPath := filepath.Join(tmpDir, "test.lock")
+
+	release, err := AcquireLockFile(lockPath, time.Second)
+	require.NoError(t, err)
+	assert.True(t, FileExists(lockPath))
+
+	_, err = AcquireLockFile(lockPath, 100*time.Millisecond)
+	if assert.Error(t, err) {
+		assert.IsType(t, LockFileTimeout{}, errors.Unwrap(err))
+	}
+
+	require.NoError(t, release())
+	assert.False(t, FileExists(lockPath))
+
+	release, err = AcquireLockFile(lockPath, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, release())
+}
+
+func TestAcquireLockFileRemovesStaleLock(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "file-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	lockPath := filepath.Join(tmpDir, "test.lock")
+	require.NoError(t, ioutil.WriteFile(lockPath, []byte("12345"), 0600))
+
+	staleTime := time.Now().Add(-2 * STALE_LOCK_FILE_AGE)
+	require.NoError(t, os.Chtimes(lockPath, staleTime, staleTime))
+
+	release, err := AcquireLockFile(lockPath, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, release())
+}
+
+func TestAcquireLockFileReleaseLeavesLockOfSomeoneElse(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "file-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	lockPath := filepath.Join(tmpDir, "test.lock")
+
+	release, err := AcquireLockFile(lockPath, time.Second)
+	require.NoError(t, err)
+
+	// Someone else broke the lock and acquired it in the meantime
+	require.NoError(t, ioutil.WriteFile(lockPath, []byte("12345 someone-else"), 0600))
+
+	err = release()
+	if assert.Error(t, err) {
+		assert.IsType(t, LockFileLost(""), errors.Unwrap(err))
+	}
+	assert.True(t, FileExists(lockPath))
+}
+
+func TestBreakStaleLockFileLeavesFreshLock(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "file-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	lockPath := filepath.Join(tmpDir, "test.lock")
+	require.NoError(t, ioutil.WriteFile(lockPath, []byte("12345 dead"), 0600))
+
+	staleTime := time.Now().Add(-2 * STALE_LOCK_FILE_AGE)
+	require.NoError(t, os.Chtimes(lockPath, staleTime, staleTime))
+	staleInfo, err := os.Stat(lockPath)
+	require.NoError(t, err)
+
+	// Someone else breaks the stale lock and acquires a fresh one before we get to break it
+	require.NoError(t, os.Remove(lockPath))
+	require.NoError(t, ioutil.WriteFile(lockPath, []byte("67890 alive"), 0600))
+
+	assert.False(t, breakStaleLockFile(lockPath, staleInfo))
+	assert.True(t, lockFileHoldsToken(lockPath, "67890 alive"))
+
+	files, err := ioutil.ReadDir(tmpDir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "The renamed lock file should have been cleaned up")
+
+	// The lock that is actually stale is removed
+	require.NoError(t, os.Chtimes(lockPath, staleTime, staleTime))
+	staleInfo, err = os.Stat(lockPath)
+	require.NoError(t, err)
+	assert.True(t, breakStaleLockFile(lockPath, staleInfo))
+	assert.False(t, FileExists(lockPath))
+}
+
+func TestRefreshLockFile(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "file-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	lockPath := filepath.Join(tmpDir, "test.lock")
+	require.NoError(t, ioutil.WriteFile(lockPath, []byte("12345 token"), 0600))
+
+	oldTime := time.Now().Add(-STALE_LOCK_FILE_AGE / 2)
+	require.NoError(t, os.Chtimes(lockPath, oldTime, oldTime))
+
+	assert.False(t, refreshLockFile(lockPath, "12345 other-token"))
+	info, err := os.Stat(lockPath)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Before(time.Now().Add(-STALE_LOCK_FILE_AGE/4)), "A lock file of someone else shouldn't be refreshed")
+
+	assert.True(t, refreshLockFile(lockPath, "12345 token"))
+	info, err = os.Stat(lockPath)
+	require.NoError(t, err)
+	assert.True(t, time.Since(info.ModTime()) < STALE_LOCK_FILE_AGE/4, "The lock file should have been refreshed")
+}
@@ -1,12 +1,17 @@
 package util
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 
 	"fmt"
+	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/test/helpers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetPathRelativeTo(t *testing.T) {
@@ -63,6 +68,41 @@ func TestCanonicalPath(t *testing.T) {
 	}
 }
 
+func TestCanonicalPathWithSymlinks(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "terragrunt-canonical-path-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	// The temp folder may itself be behind a symlink, such as /tmp on macOS
+	tmpDir, err = filepath.EvalSymlinks(tmpDir)
+	require.NoError(t, err)
+	tmpDir = filepath.ToSlash(tmpDir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "live", "app"), 0700))
+	require.NoError(t, os.Symlink(filepath.Join(tmpDir, "live"), filepath.Join(tmpDir, "link")))
+
+	testCases := []struct {
+		path     string
+		basePath string
+		expected string
+	}{
+		{"live/app", tmpDir, tmpDir + "/live/app"},
+		{"link/app", tmpDir, tmpDir + "/live/app"},
+		{"app", tmpDir + "/link", tmpDir + "/live/app"},
+		{"link/../live/app", tmpDir, tmpDir + "/live/app"},
+		{"link/app/not-yet-created/sub", tmpDir, tmpDir + "/live/app/not-yet-created/sub"},
+		{helpers.RootFolder + "does-not-exist/foo", tmpDir, helpers.RootFolder + "does-not-exist/foo"},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := CanonicalPathWithSymlinks(testCase.path, testCase.basePath)
+		assert.Nil(t, err, "Unexpected error for path %s and basePath %s: %v", testCase.path, testCase.basePath, err)
+		assert.Equal(t, testCase.expected, actual, "For path %s and basePath %s", testCase.path, testCase.basePath)
+	}
+}
+
 func TestPathContainsHiddenFileOrFolder(t *testing.T) {
 	t.Parallel()
 
@@ -118,3 +158,83 @@ func TestJoinTerraformModulePath(t *testing.T) {
 		})
 	}
 }
+
+func TestIsReadOnlyError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{&os.PathError{Op: "mkdir", Path: "/foo", Err: syscall.EROFS}, true},
+		{&os.PathError{Op: "open", Path: "/foo", Err: syscall.EACCES}, true},
+		{&os.PathError{Op: "open", Path: "/foo", Err: syscall.EPERM}, true},
+		{&os.LinkError{Op: "symlink", Old: "/foo", New: "/bar", Err: syscall.EROFS}, true},
+		{errors.WithStackTrace(&os.PathError{Op: "mkdir", Path: "/foo", Err: syscall.EROFS}), true},
+		{&os.PathError{Op: "open", Path: "/foo", Err: syscall.ENOENT}, false},
+		{fmt.Errorf("read-only"), false},
+		{nil, false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, IsReadOnlyError(testCase.err), "For error %v", testCase.err)
+	}
+}
+
+func TestStripUTF8BOM(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		contents string
+		expected string
+	}{
+		{"", ""},
+		{"foo = bar", "foo = bar"},
+		{UTF8_BOM + "foo = bar", "foo = bar"},
+		{UTF8_BOM, ""},
+		{"foo = " + UTF8_BOM + "bar", "foo = " + UTF8_BOM + "bar"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, StripUTF8BOM(testCase.contents), "For contents %q", testCase.contents)
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		contents string
+		expected string
+	}{
+		{"", ""},
+		{"foo = bar\n", "foo = bar\n"},
+		{"foo = bar\r\nbaz = blah\r\n", "foo = bar\nbaz = blah\n"},
+		{"foo = bar\rbaz = blah\r", "foo = bar\nbaz = blah\n"},
+		{"foo = bar\r\nbaz = blah\nqux = quux\r", "foo = bar\nbaz = blah\nqux = quux\n"},
+		{"\r\n\r\n", "\n\n"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, NormalizeLineEndings(testCase.contents), "For contents %q", testCase.contents)
+	}
+}
+
+func TestReadFileAsStringStripsUTF8BOM(t *testing.T) {
+	t.Parallel()
+
+	tmpFile, err := ioutil.TempFile("", "bom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(UTF8_BOM + `{"name": "vpc"}`); err != nil {
+		t.Fatal(err)
+	}
+	tmpFile.Close()
+
+	contents, err := ReadFileAsString(tmpFile.Name())
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	assert.Equal(t, `{"name": "vpc"}`, contents)
+}
@@ -0,0 +1,124 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The maximum length of a file name returned by EncodeModulePath. Most file systems allow 255 bytes, which leaves room
+// for a prefix or an extension such as .json.
+const MAX_ENCODED_MODULE_PATH_LENGTH = 200
+
+// The separator between the truncated encoding of a long module path and the hash of the full path. EncodeModulePath
+// escapes it everywhere else, so a name that contains it is always one that was truncated.
+const ENCODED_MODULE_PATH_HASH_SEPARATOR = "~"
+
+// The number of hex characters of the sha256 hash of the full module path kept at the end of a truncated name
+const ENCODED_MODULE_PATH_HASH_LENGTH = 16
+
+// Turn the given module path, such as envs/prod/us-east-1/vpc, into a name that is safe to use as a single file or
+// folder name on every OS, so that features that store a file per module all name it the same way. The encoding is:
+//
+//  1. ASCII letters, digits, '-', '_' and '.' are kept as they are, except a '.' at the start, so that the name is never
+//     hidden, or the special "." or ".." folder.
+//  2. '/' becomes '+'. Use filepath.ToSlash first if the path may contain OS-specific separators.
+//  3. Every other byte, including '+', '%', '~', spaces, and each byte of a non-ASCII character, becomes '%' followed
+//     by its value as two uppercase hex digits, as in URLs.
+//
+// For example, envs/prod/us-east-1/vpc becomes envs+prod+us-east-1+vpc. DecodeModulePath reverses this. If the
+// encoding is longer than MAX_ENCODED_MODULE_PATH_LENGTH, it's cut short and ends with '~' and a hash of the full path
+// instead, which keeps names of different paths different, but can't be decoded. Note that the encoding keeps the case
+// of letters, so paths that only differ in case get names that collide on a case-insensitive file system.
+func EncodeModulePath(path string) string {
+	var encoded strings.Builder
+	for i := 0; i < len(path); i++ {
+		char := path[i]
+		switch {
+		case char == '/':
+			encoded.WriteByte('+')
+		case char == '.' && i == 0:
+			encoded.WriteString("%2E")
+		case isModulePathSafeChar(char):
+			encoded.WriteByte(char)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", char)
+		}
+	}
+
+	if encoded.Len() <= MAX_ENCODED_MODULE_PATH_LENGTH {
+		return encoded.String()
+	}
+
+	hash := sha256.Sum256([]byte(path))
+	suffix := ENCODED_MODULE_PATH_HASH_SEPARATOR + hex.EncodeToString(hash[:])[:ENCODED_MODULE_PATH_HASH_LENGTH]
+
+	prefix := encoded.String()[:MAX_ENCODED_MODULE_PATH_LENGTH-len(suffix)]
+	// Don't cut an escape sequence in half
+	if index := strings.LastIndex(prefix, "%"); index >= 0 && index > len(prefix)-3 {
+		prefix = prefix[:index]
+	}
+
+	return prefix + suffix
+}
+
+// Return the module path the given name was encoded from by EncodeModulePath. Names that were cut short because the
+// path was too long can't be decoded, and return a ModulePathTruncated error.
+func DecodeModulePath(name string) (string, error) {
+	if strings.Contains(name, ENCODED_MODULE_PATH_HASH_SEPARATOR) {
+		return "", errors.WithStackTrace(ModulePathTruncated(name))
+	}
+
+	var decoded strings.Builder
+	for i := 0; i < len(name); i++ {
+		char := name[i]
+		switch {
+		case char == '+':
+			decoded.WriteByte('/')
+		case char == '%':
+			if i+2 >= len(name) || !isUppercaseHexDigit(name[i+1]) || !isUppercaseHexDigit(name[i+2]) {
+				return "", errors.WithStackTrace(InvalidEncodedModulePath(name))
+			}
+			value, err := hex.DecodeString(name[i+1 : i+3])
+			if err != nil {
+				return "", errors.WithStackTrace(InvalidEncodedModulePath(name))
+			}
+			decoded.Write(value)
+			i += 2
+		case char == '.' && i == 0:
+			return "", errors.WithStackTrace(InvalidEncodedModulePath(name))
+		case isModulePathSafeChar(char):
+			decoded.WriteByte(char)
+		default:
+			return "", errors.WithStackTrace(InvalidEncodedModulePath(name))
+		}
+	}
+
+	return decoded.String(), nil
+}
+
+// Returns true if the given byte is kept as it is by EncodeModulePath
+func isModulePathSafeChar(char byte) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') || char == '-' || char == '_' || char == '.'
+}
+
+func isUppercaseHexDigit(char byte) bool {
+	return (char >= '0' && char <= '9') || (char >= 'A' && char <= 'F')
+}
+
+// Custom error types
+
+type ModulePathTruncated string
+
+func (err ModulePathTruncated) Error() string {
+	return fmt.Sprintf("%s is the encoding of a module path that was too long, and was cut short, so the path can't be decoded from it", string(err))
+}
+
+type InvalidEncodedModulePath string
+
+func (err InvalidEncodedModulePath) Error() string {
+	return fmt.Sprintf("%s is not a module path encoded by EncodeModulePath", string(err))
+}
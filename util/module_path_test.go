@@ -0,0 +1,162 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeModulePath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"", ""},
+		{"vpc", "vpc"},
+		{"envs/prod/us-east-1/vpc", "envs+prod+us-east-1+vpc"},
+		{"envs/prod/my_app.v2", "envs+prod+my_app.v2"},
+		{".", "%2E"},
+		{"..", "%2E."},
+		{".hidden/app", "%2Ehidden+app"},
+		{"/root/live/vpc", "+root+live+vpc"},
+		{"my app/vpc", "my%20app+vpc"},
+		{"a+b/c%d~e", "a%2Bb+c%25d%7Ee"},
+		{`envs\prod`, "envs%5Cprod"},
+		{"envs/prod:1/*?", "envs+prod%3A1+%2A%3F"},
+		{"régions/東京", "r%C3%A9gions+%E6%9D%B1%E4%BA%AC"},
+	}
+
+	for _, testCase := range testCases {
+		actual := EncodeModulePath(testCase.path)
+		assert.Equal(t, testCase.expected, actual, "For path %s", testCase.path)
+	}
+}
+
+func TestEncodeModulePathLongPaths(t *testing.T) {
+	t.Parallel()
+
+	longPath := strings.Repeat("envs/prod/", 30) + "vpc"
+	encoded := EncodeModulePath(longPath)
+	assert.Len(t, encoded, MAX_ENCODED_MODULE_PATH_LENGTH)
+	assert.True(t, strings.HasPrefix(encoded, "envs+prod+envs+prod+"), "Unexpected encoding %s", encoded)
+	assert.Contains(t, encoded, ENCODED_MODULE_PATH_HASH_SEPARATOR)
+
+	// Paths that only differ after the cut still get different names
+	assert.NotEqual(t, encoded, EncodeModulePath(strings.Repeat("envs/prod/", 30)+"db"))
+
+	// An escape sequence is never cut in half
+	for padding := 0; padding < 3; padding++ {
+		unicodePath := strings.Repeat("a", padding) + strings.Repeat("東", 100)
+		encoded := EncodeModulePath(unicodePath)
+		escapes := strings.TrimLeft(encoded[:strings.Index(encoded, ENCODED_MODULE_PATH_HASH_SEPARATOR)], "a")
+		assert.Equal(t, len(escapes), strings.Count(escapes, "%")*3, "Escape sequence cut in %s", encoded)
+		assert.True(t, len(encoded) <= MAX_ENCODED_MODULE_PATH_LENGTH, "Encoding %s is too long", encoded)
+	}
+
+	_, err := DecodeModulePath(encoded)
+	if assert.Error(t, err) {
+		assert.IsType(t, ModulePathTruncated(""), errors.Unwrap(err))
+	}
+}
+
+func TestDecodeModulePathInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []string{
+		"envs/prod",
+		"my app",
+		".hidden",
+		"a%2",
+		"a%zz",
+		"a%2e",
+		"東京",
+	}
+
+	for _, testCase := range testCases {
+		_, err := DecodeModulePath(testCase)
+		if assert.Error(t, err, "For name %s", testCase) {
+			assert.IsType(t, InvalidEncodedModulePath(""), errors.Unwrap(err), "For name %s", testCase)
+		}
+	}
+}
+
+func TestEncodeModulePathRoundTrips(t *testing.T) {
+	t.Parallel()
+
+	nastyPaths := []string{
+		"",
+		".",
+		"..",
+		"../..",
+		"/",
+		"//",
+		"envs/prod/us-east-1/vpc",
+		"with space/and\ttab/and\nnewline",
+		"+%~/%2F/%%",
+		"C:\\Users\\me\\live",
+		"régions/東京/😀",
+		"\x00\xff invalid utf8 \xc3",
+		strings.Repeat("a/", 60),
+		strings.Repeat("東", 20),
+	}
+
+	for _, path := range nastyPaths {
+		assert.True(t, encodesAndDecodes(path), "Path %q doesn't round-trip: encoded as %s", path, EncodeModulePath(path))
+	}
+
+	if err := quick.Check(encodesAndDecodes, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeModulePathLongPathsAreDistinctAndSafe(t *testing.T) {
+	t.Parallel()
+
+	longPath := func(path string) bool {
+		path = strings.Repeat("envs/prod/", 20) + path
+		encoded := EncodeModulePath(path)
+		return len(encoded) <= MAX_ENCODED_MODULE_PATH_LENGTH &&
+			isSafeEncoding(strings.Replace(encoded, ENCODED_MODULE_PATH_HASH_SEPARATOR, "", 1)) &&
+			(encoded == EncodeModulePath(path)) &&
+			(encoded != EncodeModulePath(path+"x"))
+	}
+
+	if err := quick.Check(longPath, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Returns true if the given path is encoded into a safe file name and decoded back into the same path, or, if it's too
+// long to encode in full, into a safe file name of the maximum length that can't be decoded
+func encodesAndDecodes(path string) bool {
+	encoded := EncodeModulePath(path)
+	if len(encoded) > MAX_ENCODED_MODULE_PATH_LENGTH {
+		return false
+	}
+
+	decoded, err := DecodeModulePath(encoded)
+	if strings.Contains(encoded, ENCODED_MODULE_PATH_HASH_SEPARATOR) {
+		_, isTruncated := errors.Unwrap(err).(ModulePathTruncated)
+		return isTruncated
+	}
+
+	return err == nil && decoded == path && isSafeEncoding(encoded)
+}
+
+// Returns true if the given name only contains characters EncodeModulePath may write, and is never hidden or special
+func isSafeEncoding(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isModulePathSafeChar(name[i]) && name[i] != '+' && name[i] != '%' {
+			return false
+		}
+	}
+	return true
+}
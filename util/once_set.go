@@ -0,0 +1,31 @@
+package util
+
+import "sync"
+
+// A set of keys that tells whether a key is seen for the first time, such as to log a warning only once per run, no
+// matter how many modules of an xxx-all command run into it. It's safe to use concurrently. A nil OnceSet treats every
+// key as seen for the first time.
+type OnceSet struct {
+	lock sync.Mutex
+	seen map[string]bool
+}
+
+func NewOnceSet() *OnceSet {
+	return &OnceSet{seen: map[string]bool{}}
+}
+
+// Add the given key to the set, and return true if it wasn't in it yet
+func (set *OnceSet) Add(key string) bool {
+	if set == nil {
+		return true
+	}
+
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	if set.seen[key] {
+		return false
+	}
+	set.seen[key] = true
+	return true
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnceSet(t *testing.T) {
+	t.Parallel()
+
+	set := NewOnceSet()
+	assert.True(t, set.Add("a"))
+	assert.False(t, set.Add("a"))
+	assert.True(t, set.Add("b"))
+	assert.False(t, set.Add("b"))
+	assert.False(t, set.Add("a"))
+}
+
+func TestOnceSetNil(t *testing.T) {
+	t.Parallel()
+
+	var set *OnceSet
+	assert.True(t, set.Add("a"))
+	assert.True(t, set.Add("a"))
+}
+
+func TestOnceSetConcurrent(t *testing.T) {
+	t.Parallel()
+
+	set := NewOnceSet()
+	firsts := int64(0)
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		waitGroup.Add(1)
+		go func(i int) {
+			defer waitGroup.Done()
+			if set.Add(fmt.Sprintf("key-%d", i%5)) {
+				atomic.AddInt64(&firsts, 1)
+			}
+		}(i)
+	}
+	waitGroup.Wait()
+
+	assert.Equal(t, int64(5), firsts)
+}
@@ -1,8 +1,12 @@
 package util
 
 import (
+	cryptorand "crypto/rand"
+	"fmt"
 	"math/rand"
 	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
 )
 
 // Get a random time duration between the lower bound and upper bound. This is useful because some of our automated tests
@@ -40,3 +44,16 @@ func random(min int, max int) int {
 	rand.Seed(time.Now().UnixNano())
 	return rand.Intn(max-min) + min
 }
+
+// Generate a random (version 4) UUID, such as 0b5c8c4e-9e0c-4f4e-8d6a-2f1c3e7a9b10
+func NewUUID() (string, error) {
+	uuid := make([]byte, 16)
+	if _, err := cryptorand.Read(uuid); err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x40 // version 4
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16]), nil
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"regexp"
 	"testing"
 	"time"
 )
@@ -38,3 +39,24 @@ func TestGetRandomTime(t *testing.T) {
 		}
 	}
 }
+
+func TestNewUUID(t *testing.T) {
+	t.Parallel()
+
+	uuidRegex := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		uuid, err := NewUUID()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !uuidRegex.MatchString(uuid) {
+			t.Fatalf("%s is not a valid version 4 UUID", uuid)
+		}
+		if seen[uuid] {
+			t.Fatalf("Got the same UUID %s twice", uuid)
+		}
+		seen[uuid] = true
+	}
+}
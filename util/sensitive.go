@@ -0,0 +1,58 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The value shown in place of the value of any variable or setting that looks like it contains a secret
+const RedactedValue = "<redacted>"
+
+// Variables and settings whose name matches this regex are considered secrets, so their values are never shown or
+// written to disk
+var SENSITIVE_NAME_REGEX = regexp.MustCompile(`(?i)(secret|password|passwd|token|credential|private_key|access_key)`)
+
+// Returns true if the given name of a variable or setting looks like it refers to a secret, or matches one of the given
+// patterns (see TerragruntOptions.SensitiveVarPatterns)
+func IsSensitiveName(name string, sensitiveNamePatterns []*regexp.Regexp) bool {
+	if SENSITIVE_NAME_REGEX.MatchString(name) {
+		return true
+	}
+	for _, pattern := range sensitiveNamePatterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return a copy of the given Terraform args in which the value of each -var argument that sets a sensitive variable
+// (see IsSensitiveName) is replaced by RedactedValue, so the args can be logged. For example:
+//
+// ["apply", "-var", "db_password=hunter2", "-var=name=app"] -> ["apply", "-var", "db_password=<redacted>", "-var=name=app"]
+func RedactSensitiveVarArgs(args []string, sensitiveNamePatterns []*regexp.Regexp) []string {
+	redacted := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case (arg == "-var" || arg == "--var") && i+1 < len(args):
+			redacted = append(redacted, arg, redactSensitiveVar(args[i+1], sensitiveNamePatterns))
+			i++
+		case strings.HasPrefix(arg, "-var=") || strings.HasPrefix(arg, "--var="):
+			parts := strings.SplitN(arg, "=", 2)
+			redacted = append(redacted, parts[0]+"="+redactSensitiveVar(parts[1], sensitiveNamePatterns))
+		default:
+			redacted = append(redacted, arg)
+		}
+	}
+	return redacted
+}
+
+// Redact the value of the given NAME=VALUE assignment of a variable if the name of the variable is sensitive
+func redactSensitiveVar(assignment string, sensitiveNamePatterns []*regexp.Regexp) string {
+	parts := strings.SplitN(assignment, "=", 2)
+	if len(parts) != 2 || !IsSensitiveName(parts[0], sensitiveNamePatterns) {
+		return assignment
+	}
+	return parts[0] + "=" + RedactedValue
+}
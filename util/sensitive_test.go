@@ -0,0 +1,35 @@
+package util
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactSensitiveVarArgs(t *testing.T) {
+	t.Parallel()
+
+	patterns := []*regexp.Regexp{regexp.MustCompile(`^license_key$`)}
+
+	testCases := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{}, []string{}},
+		{[]string{"apply", "-input=false"}, []string{"apply", "-input=false"}},
+		{[]string{"apply", "-var", "name=app"}, []string{"apply", "-var", "name=app"}},
+		{[]string{"apply", "-var", "secret=hunter2"}, []string{"apply", "-var", "secret=<redacted>"}},
+		{[]string{"apply", "-var=db_password=hunter2=="}, []string{"apply", "-var=db_password=<redacted>"}},
+		{[]string{"apply", "--var", "api_token=abc"}, []string{"apply", "--var", "api_token=<redacted>"}},
+		{[]string{"apply", "-var", "license_key=abc", "-var", "name=app"}, []string{"apply", "-var", "license_key=<redacted>", "-var", "name=app"}},
+		{[]string{"apply", "-var-file", "secrets.tfvars"}, []string{"apply", "-var-file", "secrets.tfvars"}},
+		{[]string{"apply", "-var", "no_value"}, []string{"apply", "-var", "no_value"}},
+		{[]string{"apply", "-var"}, []string{"apply", "-var"}},
+		{[]string{"echo", "secret=hunter2"}, []string{"echo", "secret=hunter2"}},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, RedactSensitiveVarArgs(testCase.args, patterns), "For args %v", testCase.args)
+	}
+}